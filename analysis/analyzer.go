@@ -0,0 +1,211 @@
+package analysis
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+type (
+	// Analyzer is a named check or computation that is run over each module of a module set by
+	// RunAnalyzers. Analyzers are composed using Requires so that third party checks can build on
+	// the results and facts of other analyzers.
+	Analyzer struct {
+		// Name is the unique name of the analyzer
+		Name string
+
+		// Doc describes what the analyzer reports or computes
+		Doc string
+
+		// Requires are the analyzers that must be run on a module before this analyzer. Their
+		// results are available in Pass.ResultOf and their facts can be imported.
+		Requires []*Analyzer
+
+		// FactTypes are the types of the facts that the analyzer exports. Each element is a pointer
+		// to a zero value of a fact type.
+		FactTypes []Fact
+
+		// Run performs the analysis of one module and returns a result that is made available to
+		// the analyzers that require this analyzer
+		Run func(pass *Pass) (interface{}, error)
+	}
+
+	// Fact is information about a named definition, e.g. a class or a function, that an analyzer
+	// exports when analyzing the module that contains the definition, and that is imported when
+	// analyzing the modules that use it. Facts must be pointers to structs.
+	Fact interface {
+		// AFact is a marker method that distinguishes facts from other values
+		AFact()
+	}
+
+	// Module is a named set of parsed files, typically the manifests of a Puppet module
+	Module struct {
+		Name     string
+		Programs []*parser.Program
+	}
+
+	// Pass provides an analyzer with the module to analyze and the means to report issues and to
+	// exchange facts with other analyzers
+	Pass struct {
+		// Analyzer is the analyzer that is run
+		Analyzer *Analyzer
+
+		// Module is the module to analyze
+		Module *Module
+
+		// ResultOf contains the results that the required analyzers produced for the module
+		ResultOf map[*Analyzer]interface{}
+
+		driver *driver
+	}
+
+	factKey struct {
+		analyzer *Analyzer
+		name     string
+		factType reflect.Type
+	}
+
+	driver struct {
+		facts  map[factKey]Fact
+		issues []issue.Reported
+		roots  map[*Analyzer]bool
+	}
+)
+
+// Report reports an issue with the given code for the given expression. Issues with a soft code
+// are reported as warnings and issues with a hard code as errors. Issues reported by analyzers that
+// are only run because other analyzers require them are dropped.
+func (p *Pass) Report(code issue.Code, e parser.Expression, args issue.H) {
+	severity := issue.SEVERITY_ERROR
+	if issue.IssueForCode(code).IsDemotable() {
+		severity = issue.SEVERITY_WARNING
+	}
+	p.ReportIssue(issue.NewReported(code, severity, args, e))
+}
+
+// ReportIssue reports an issue that has already been created, e.g. by a validator
+func (p *Pass) ReportIssue(reported issue.Reported) {
+	if p.driver.roots[p.Analyzer] {
+		p.driver.issues = append(p.driver.issues, reported)
+	}
+}
+
+// ExportFact associates the given fact with the definition that has the given name. The type of the
+// fact must be one of the FactTypes of the analyzer.
+func (p *Pass) ExportFact(name string, fact Fact) {
+	ft := reflect.TypeOf(fact)
+	declared := false
+	for _, t := range p.Analyzer.FactTypes {
+		if reflect.TypeOf(t) == ft {
+			declared = true
+			break
+		}
+	}
+	if !declared {
+		panic(fmt.Sprintf(`analyzer %s exports a fact of undeclared type %s`, p.Analyzer.Name, ft))
+	}
+	p.driver.facts[factKey{p.Analyzer, parser.ResolveReference(name), ft}] = fact
+}
+
+// ImportFact copies the fact of the same type as the given fact that has been associated with the
+// definition that has the given name into the given fact, and returns true. It returns false when no
+// such fact has been exported by the analyzer of the pass or by any of the analyzers that it requires,
+// directly or indirectly, when analyzing this module or the modules that were analyzed before it.
+func (p *Pass) ImportFact(name string, fact Fact) bool {
+	name = parser.ResolveReference(name)
+	ft := reflect.TypeOf(fact)
+	found := false
+	visitAnalyzers([]*Analyzer{p.Analyzer}, make(map[*Analyzer]bool), func(a *Analyzer) {
+		if stored, ok := p.driver.facts[factKey{a, name, ft}]; ok && !found {
+			reflect.ValueOf(fact).Elem().Set(reflect.ValueOf(stored).Elem())
+			found = true
+		}
+	})
+	return found
+}
+
+// RunAnalyzers runs the given analyzers, and the analyzers that they require, over each of the given
+// modules and returns the issues that the given analyzers report. Modules are analyzed in the order that
+// they are given so a module should be given after the modules that it depends on. An analyzer is always
+// run after the analyzers that it requires.
+//
+// An error is returned when the analyzers have a cyclic dependency or when an analyzer fails.
+func RunAnalyzers(analyzers []*Analyzer, modules []*Module) ([]issue.Reported, error) {
+	order, err := analyzerOrder(analyzers)
+	if err != nil {
+		return nil, err
+	}
+	d := &driver{facts: make(map[factKey]Fact), issues: make([]issue.Reported, 0), roots: make(map[*Analyzer]bool, len(analyzers))}
+	for _, a := range analyzers {
+		d.roots[a] = true
+	}
+	for _, m := range modules {
+		results := make(map[*Analyzer]interface{}, len(order))
+		for _, a := range order {
+			resultOf := make(map[*Analyzer]interface{}, len(a.Requires))
+			for _, r := range a.Requires {
+				resultOf[r] = results[r]
+			}
+			result, err := a.Run(&Pass{Analyzer: a, Module: m, ResultOf: resultOf, driver: d})
+			if err != nil {
+				return nil, fmt.Errorf(`analyzer %s failed on module %s: %w`, a.Name, m.Name, err)
+			}
+			results[a] = result
+		}
+	}
+	return d.issues, nil
+}
+
+// analyzerOrder returns the given analyzers and all analyzers that they require, directly or indirectly,
+// ordered so that each analyzer comes after the analyzers that it requires
+func analyzerOrder(analyzers []*Analyzer) ([]*Analyzer, error) {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[*Analyzer]int)
+	names := make(map[string]*Analyzer)
+	order := make([]*Analyzer, 0, len(analyzers))
+	var visit func(a *Analyzer) error
+	visit = func(a *Analyzer) error {
+		switch state[a] {
+		case visiting:
+			return fmt.Errorf(`analyzer %s has a cyclic dependency`, a.Name)
+		case visited:
+			return nil
+		}
+		if other, ok := names[a.Name]; ok && other != a {
+			return fmt.Errorf(`analyzer name %s is not unique`, a.Name)
+		}
+		names[a.Name] = a
+		state[a] = visiting
+		for _, r := range a.Requires {
+			if err := visit(r); err != nil {
+				return err
+			}
+		}
+		state[a] = visited
+		order = append(order, a)
+		return nil
+	}
+	for _, a := range analyzers {
+		if err := visit(a); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// visitAnalyzers calls the given function with the given analyzers and, recursively, with the analyzers
+// that they require. Each analyzer is visited once.
+func visitAnalyzers(analyzers []*Analyzer, seen map[*Analyzer]bool, f func(a *Analyzer)) {
+	for _, a := range analyzers {
+		if !seen[a] {
+			seen[a] = true
+			f(a)
+			visitAnalyzers(a.Requires, seen, f)
+		}
+	}
+}
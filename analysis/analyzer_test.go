@@ -0,0 +1,98 @@
+package analysis
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-parser/literal"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+const testUnknownClass = `TEST_UNKNOWN_CLASS`
+
+func init() {
+	issue.Soft(testUnknownClass, `unknown class '%{name}'`)
+}
+
+type classFact struct {
+	Parameters int
+}
+
+func (*classFact) AFact() {}
+
+var classes = &Analyzer{
+	Name:      `classes`,
+	Doc:       `exports the number of parameters of each class and returns the number of classes`,
+	FactTypes: []Fact{(*classFact)(nil)},
+	Run: func(pass *Pass) (interface{}, error) {
+		count := 0
+		for _, program := range pass.Module.Programs {
+			for _, c := range program.Classes() {
+				pass.ExportFact(c.Name(), &classFact{len(c.Parameters())})
+				count++
+			}
+		}
+		return count, nil
+	},
+}
+
+var includes = &Analyzer{
+	Name:     `includes`,
+	Doc:      `reports included classes that are unknown`,
+	Requires: []*Analyzer{classes},
+	Run: func(pass *Pass) (interface{}, error) {
+		if pass.ResultOf[classes] == nil {
+			return nil, errors.New(`no result from classes`)
+		}
+		for _, program := range pass.Module.Programs {
+			for _, call := range parser.FindAll[*parser.CallNamedFunctionExpression](program) {
+				if qn, ok := call.Functor().(*parser.QualifiedName); !ok || qn.Name() != `include` {
+					continue
+				}
+				for _, arg := range call.Arguments() {
+					name, _ := literal.ToLiteral(arg)
+					var fact classFact
+					if s, ok := name.(string); ok && !pass.ImportFact(s, &fact) {
+						pass.Report(testUnknownClass, arg, issue.H{`name`: s})
+					}
+				}
+			}
+		}
+		return nil, nil
+	},
+}
+
+func TestRunAnalyzers(t *testing.T) {
+	a := &Module{`a`, []*parser.Program{parse(t, `class a::one($x) {}`).(*parser.Program)}}
+	b := &Module{`b`, []*parser.Program{parse(t, `class b { include 'a::one', '::B', 'c' }`).(*parser.Program)}}
+
+	issues, err := RunAnalyzers([]*Analyzer{includes}, []*Module{a, b})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 1 || issues[0].Argument(`name`) != `c` || issues[0].Severity() != issue.SEVERITY_WARNING {
+		t.Errorf("expected one unknown class 'c', got %v", issues)
+	}
+
+	// Facts of modules that are analyzed later are not visible
+	issues, _ = RunAnalyzers([]*Analyzer{includes}, []*Module{b, a})
+	if len(issues) != 2 {
+		t.Errorf("expected two unknown classes, got %d", len(issues))
+	}
+}
+
+func TestRunAnalyzersErrors(t *testing.T) {
+	cyclic := &Analyzer{Name: `cyclic`}
+	cyclic.Requires = []*Analyzer{{Name: `other`, Requires: []*Analyzer{cyclic}}}
+	if _, err := RunAnalyzers([]*Analyzer{cyclic}, nil); err == nil || !strings.Contains(err.Error(), `cyclic dependency`) {
+		t.Errorf("expected cyclic dependency error, got %v", err)
+	}
+
+	failing := &Analyzer{Name: `failing`, Run: func(pass *Pass) (interface{}, error) { return nil, errors.New(`boom`) }}
+	_, err := RunAnalyzers([]*Analyzer{failing}, []*Module{{Name: `m`}})
+	if err == nil || err.Error() != `analyzer failing failed on module m: boom` {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,195 @@
+package analysis
+
+import (
+	"math"
+
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+// MayBeInstance returns false if the value that the given value expression evaluates to can never be an
+// instance of the type that the given type expression evaluates to. The result is true when that can't be
+// determined statically, e.g. when the value isn't literal or the type is an alias, so false is only
+// returned when the mismatch is certain.
+func MayBeInstance(typeExpr, value parser.Expression) bool {
+	return mayBeInstance(typeExpr, infer(value))
+}
+
+func mayBeInstance(typeExpr parser.Expression, t *inferredType) bool {
+	var name string
+	var params []parser.Expression
+	switch te := typeExpr.(type) {
+	case *parser.QualifiedReference:
+		name = te.Name()
+	case *parser.AccessExpression:
+		qr, ok := te.Operand().(*parser.QualifiedReference)
+		if !ok {
+			return true
+		}
+		name = qr.Name()
+		params = te.Keys()
+	default:
+		return true
+	}
+
+	switch t.name {
+	case `Any`:
+		return true
+	case `Optional`:
+		return mayBeInstance(typeExpr, &inferredType{name: `Undef`}) || mayBeInstance(typeExpr, t.element)
+	}
+
+	switch name {
+	case `Undef`:
+		return t.name == `Undef`
+	case `Default`:
+		return t.name == `Default`
+	case `Optional`:
+		return t.name == `Undef` || len(params) == 0 || mayBeInstance(params[0], t)
+	case `NotUndef`:
+		return t.name != `Undef` && (len(params) == 0 || mayBeInstance(params[0], t))
+	case `Variant`:
+		for _, p := range params {
+			if mayBeInstance(p, t) {
+				return true
+			}
+		}
+		return false
+	case `Boolean`:
+		return isAbstractScalar(t) || t.name == `Boolean`
+	case `Integer`:
+		switch t.name {
+		case `Integer`:
+			lo, hi := intRange(params)
+			return t.intMax >= lo && t.intMin <= hi
+		case `Numeric`, `ScalarData`, `Scalar`:
+			return true
+		}
+		return false
+	case `Float`:
+		switch t.name {
+		case `Float`:
+			lo, hi := floatRange(params)
+			return t.floatMax >= lo && t.floatMin <= hi
+		case `Numeric`, `ScalarData`, `Scalar`:
+			return true
+		}
+		return false
+	case `Numeric`:
+		return isAbstractScalar(t) || t.name == `Integer` || t.name == `Float`
+	case `String`, `Enum`, `Pattern`:
+		return isAbstractScalar(t) || t.name == `String`
+	case `Regexp`:
+		return t.name == `Regexp` || t.name == `Scalar`
+	case `ScalarData`:
+		return isAbstractScalar(t) || scalarData[t.name]
+	case `Scalar`:
+		return isAbstractScalar(t) || scalarData[t.name] || t.name == `Regexp`
+	case `Type`:
+		return t.name == `Type`
+	case `Collection`:
+		return t.name == `Array` || t.name == `Hash`
+	case `Array`, `Tuple`:
+		if t.name != `Array` {
+			return false
+		}
+		if name == `Tuple` {
+			return true
+		}
+		elemType, lo, hi := collectionParams(params)
+		if t.maxSize < lo || int64(t.minSize) > hi {
+			return false
+		}
+		return elemType == nil || t.element == nil || mayBeInstance(elemType, t.element)
+	case `Hash`, `Struct`:
+		if t.name != `Hash` {
+			return false
+		}
+		if name == `Struct` || len(params) < 2 {
+			return true
+		}
+		_, lo, hi := collectionParams(params[1:])
+		if t.maxSize < lo || int64(t.minSize) > hi {
+			return false
+		}
+		return t.key == nil || mayBeInstance(params[0], t.key) && mayBeInstance(params[1], t.element)
+	case `Data`:
+		switch t.name {
+		case `Default`, `Regexp`, `Type`:
+			return false
+		case `Array`:
+			return t.element == nil || mayBeInstance(typeExpr, t.element)
+		case `Hash`:
+			return t.key == nil || mayBeInstance(typeExpr, t.element)
+		}
+		return true
+	}
+	return true
+}
+
+// isAbstractScalar returns true if the given type is the common type of different scalar types and
+// hence may have instances of any of them
+func isAbstractScalar(t *inferredType) bool {
+	return t.name == `Numeric` || t.name == `ScalarData` || t.name == `Scalar`
+}
+
+// intRange returns the range given by the parameters of an Integer type
+func intRange(params []parser.Expression) (int64, int64) {
+	lo, hi := int64(math.MinInt64), int64(math.MaxInt64)
+	if len(params) > 0 {
+		if t := infer(params[0]); t.name == `Integer` {
+			lo = t.intMin
+		}
+	}
+	if len(params) > 1 {
+		if t := infer(params[1]); t.name == `Integer` {
+			hi = t.intMin
+		}
+	}
+	return lo, hi
+}
+
+// floatRange returns the range given by the parameters of a Float type
+func floatRange(params []parser.Expression) (float64, float64) {
+	lo, hi := math.Inf(-1), math.Inf(1)
+	if len(params) > 0 {
+		if t := infer(params[0]); t.name == `Float` || t.name == `Integer` {
+			lo = t.floatMin
+			if t.name == `Integer` {
+				lo = float64(t.intMin)
+			}
+		}
+	}
+	if len(params) > 1 {
+		if t := infer(params[1]); t.name == `Float` || t.name == `Integer` {
+			hi = t.floatMin
+			if t.name == `Integer` {
+				hi = float64(t.intMin)
+			}
+		}
+	}
+	return lo, hi
+}
+
+// collectionParams returns the type and the size range given by the parameters of an Array type. The
+// returned type is nil when the parameters don't start with a type.
+func collectionParams(params []parser.Expression) (parser.Expression, int, int64) {
+	var elemType parser.Expression
+	if len(params) > 0 && isTypeReference(params[0]) {
+		elemType = params[0]
+		params = params[1:]
+	}
+	lo, hi := intRange(params)
+	if lo < 0 {
+		lo = 0
+	}
+	return elemType, int(lo), hi
+}
+
+// isTypeReference returns true if the given expression is a reference to a type, which may be parameterized
+func isTypeReference(e parser.Expression) bool {
+	if ae, ok := e.(*parser.AccessExpression); ok {
+		e = ae.Operand()
+	}
+	_, ok := e.(*parser.QualifiedReference)
+	return ok
+}
@@ -0,0 +1,53 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+func TestMayBeInstance(t *testing.T) {
+	tests := []struct {
+		typeExpr string
+		value    string
+		expected bool
+	}{
+		{`Integer`, `1`, true},
+		{`Integer[2]`, `1`, false},
+		{`Integer[default, 0]`, `-1`, true},
+		{`Integer`, `1.0`, false},
+		{`Float[0.0, 1]`, `0.5`, true},
+		{`Float[0.0, 1]`, `1.5`, false},
+		{`Numeric`, `'1'`, false},
+		{`String[1]`, `"x${y}"`, true},
+		{`Optional[Integer]`, `undef`, true},
+		{`Integer`, `undef`, false},
+		{`NotUndef`, `undef`, false},
+		{`Variant[String, Boolean]`, `true`, true},
+		{`Variant[String, Boolean]`, `1`, false},
+		{`Array[String]`, `['a', 'b']`, true},
+		{`Array[String]`, `[1, 2]`, false},
+		{`Array[String, 0, 1]`, `['a', 'b']`, false},
+		{`Array`, `{}`, false},
+		{`Tuple[String]`, `[1]`, true},
+		{`Hash[String, Integer]`, `{'a' => 1}`, true},
+		{`Hash[String, Integer, 2]`, `{'a' => 1}`, false},
+		{`Struct[{a => Integer}]`, `{'a' => 1}`, true},
+		{`Data`, `[1, 'a', { 'b' => undef }]`, true},
+		{`Data`, `[/x/]`, false},
+		{`Type[String]`, `String`, true},
+		{`Type`, `'String'`, false},
+		{`Regexp`, `/x/`, true},
+		{`Scalar`, `[]`, false},
+		{`Integer`, `$x ? { 'a' => 1, default => 'b' }`, true},
+		{`Integer`, `$x ? { 'a' => 'a', default => 'b' }`, false},
+		{`Integer`, `$x`, true},
+		{`My::Alias`, `1`, true},
+	}
+	for _, test := range tests {
+		typeExpr := parse(t, test.typeExpr).(*parser.Program).Body().(*parser.BlockExpression).Statements()[0]
+		if actual := MayBeInstance(typeExpr, parse(t, test.value)); actual != test.expected {
+			t.Errorf("%s = %s: expected %t, got %t", test.typeExpr, test.value, test.expected, actual)
+		}
+	}
+}
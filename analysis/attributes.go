@@ -0,0 +1,128 @@
+package analysis
+
+import (
+	"sort"
+
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+type (
+	// ResourceTypeUsage describes how the resources of a type are declared across a set of manifests
+	ResourceTypeUsage struct {
+		// Type is the name of the resource type in lower case, e.g. "file" or "mymod::vhost"
+		Type string
+
+		// Declarations is the number of declared resource bodies of the type
+		Declarations int
+
+		// Splats is the number of declared resource bodies that set attributes from a hash using
+		// '* =>'. The attributes set that way are unknown.
+		Splats int
+
+		// Attributes are the attributes given to the resources, sorted by name
+		Attributes []*AttributeUsage
+	}
+
+	// AttributeUsage describes the values given to an attribute of a resource type
+	AttributeUsage struct {
+		// Name is the name of the attribute
+		Name string
+
+		// Count is the number of declared resource bodies that give the attribute a value. The attribute
+		// is given in all declarations when the count is equal to the number of declarations of the
+		// resource type.
+		Count int
+
+		// Types maps the names of the types of the given values, e.g. "String" or "Array", to the number
+		// of values of that type. The type of a value that isn't literal is "Any", see InferType.
+		Types map[string]int
+	}
+)
+
+// AttributeUsages returns the usage of each resource type that is declared in the trees rooted at the
+// given expressions, sorted by type name. Only resource declarations are considered, i.e. resource
+// defaults and overrides are not.
+func AttributeUsages(roots ...parser.Expression) []*ResourceTypeUsage {
+	usages := make(map[string]*ResourceTypeUsage)
+	attributes := make(map[*ResourceTypeUsage]map[string]*AttributeUsage)
+	visit := func(path []parser.Expression, e parser.Expression) {
+		re, ok := e.(*parser.ResourceExpression)
+		if !ok {
+			return
+		}
+		tn, ok := re.TypeName().(*parser.QualifiedName)
+		if !ok {
+			return
+		}
+		name := parser.ResolveReference(tn.Name())
+		u, ok := usages[name]
+		if !ok {
+			u = &ResourceTypeUsage{Type: name}
+			usages[name] = u
+			attributes[u] = make(map[string]*AttributeUsage)
+		}
+		for _, b := range re.Bodies() {
+			u.Declarations++
+			splat := false
+			for _, op := range b.(*parser.ResourceBody).Operations() {
+				switch op := op.(type) {
+				case *parser.AttributeOperation:
+					a, ok := attributes[u][op.Name()]
+					if !ok {
+						a = &AttributeUsage{Name: op.Name(), Types: make(map[string]int)}
+						attributes[u][op.Name()] = a
+					}
+					a.Count++
+					a.Types[valueType(op.Value())]++
+				case *parser.AttributesOperation:
+					splat = true
+				}
+			}
+			if splat {
+				u.Splats++
+			}
+		}
+	}
+	for _, root := range roots {
+		visit(nil, root)
+		root.AllContents(nil, visit)
+	}
+
+	result := make([]*ResourceTypeUsage, 0, len(usages))
+	for _, u := range usages {
+		u.Attributes = make([]*AttributeUsage, 0, len(attributes[u]))
+		for _, a := range attributes[u] {
+			u.Attributes = append(u.Attributes, a)
+		}
+		sort.Slice(u.Attributes, func(i, j int) bool { return u.Attributes[i].Name < u.Attributes[j].Name })
+		result = append(result, u)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Type < result[j].Type })
+	return result
+}
+
+// valueType returns the name of the type of the value of the given expression. A bare word is a string.
+func valueType(e parser.Expression) string {
+	if _, ok := e.(*parser.QualifiedName); ok {
+		return `String`
+	}
+	return infer(e).name
+}
+
+// ToData returns the usage as a hash that can be serialized to JSON
+func (u *ResourceTypeUsage) ToData() map[string]interface{} {
+	attrs := make([]interface{}, len(u.Attributes))
+	for i, a := range u.Attributes {
+		types := make(map[string]interface{}, len(a.Types))
+		for t, n := range a.Types {
+			types[t] = int64(n)
+		}
+		attrs[i] = map[string]interface{}{`name`: a.Name, `count`: int64(a.Count), `types`: types}
+	}
+	return map[string]interface{}{
+		`type`:         u.Type,
+		`declarations`: int64(u.Declarations),
+		`splats`:       int64(u.Splats),
+		`attributes`:   attrs,
+	}
+}
@@ -0,0 +1,30 @@
+package analysis
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/lyraproj/puppet-parser/json"
+)
+
+func TestAttributeUsages(t *testing.T) {
+	a := parse(t, `file { '/a': ensure => file, mode => '0644'; '/b': ensure => directory, mode => 0755 }`)
+	b := parse(t, `class b { File { owner => 'root' } file { '/c': ensure => $ensure, * => $attrs } File['/a'] { mode => '0600' } }`)
+
+	usages := AttributeUsages(a, b)
+	if len(usages) != 1 {
+		t.Fatalf("expected 1 resource type, got %d", len(usages))
+	}
+	buf := bytes.NewBufferString(``)
+	json.ToJson(usages[0].ToData(), buf)
+	expected := `{"attributes":[{"count":3,"name":"ensure","types":{"Any":1,"String":2}},{"count":2,"name":"mode","types":{"Integer":1,"String":1}}],` +
+		`"declarations":3,"splats":1,"type":"file"}` + "\n"
+	if buf.String() != expected {
+		t.Errorf("expected %s, got %s", expected, buf.String())
+	}
+
+	usages = AttributeUsages(parse(t, `mymod::vhost { 'x': port => 80 } ::mymod::vhost { 'y': }`))
+	if len(usages) != 1 || usages[0].Type != `mymod::vhost` || usages[0].Declarations != 2 || usages[0].Attributes[0].Count != 1 {
+		t.Errorf("unexpected usages %v", usages)
+	}
+}
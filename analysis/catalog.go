@@ -0,0 +1,378 @@
+package analysis
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/lyraproj/puppet-parser/literal"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+type (
+	// Catalog is the shape of the catalog that a manifest produces, computed without evaluating the
+	// manifest. It only contains the resources with literal titles.
+	Catalog struct {
+		// Resources are the resources in the order that they are declared
+		Resources []*CatalogResource
+
+		// Edges are the containment edges and the relationships between the resources, in the order
+		// that they are declared
+		Edges []*CatalogEdge
+	}
+
+	// CatalogResource is a resource of a Catalog
+	CatalogResource struct {
+		// Expression is the resource body, or the class definition or call that declares a class. It
+		// provides the position.
+		Expression parser.Expression
+
+		// Type is the capitalized name of the resource type, e.g. "File" or "Mymod::Vhost"
+		Type string
+
+		// Title is the title of the resource. The title of a class is its capitalized name.
+		Title string
+
+		// Virtual is true for a virtual resource and for an exported resource
+		Virtual bool
+
+		// Exported is true for an exported resource
+		Exported bool
+
+		// Parameters are the attributes that have literal values or values that are literal resource
+		// references. A reference is represented by a string such as "File[/tmp/x]".
+		Parameters map[string]interface{}
+
+		// Unresolved are the names of the attributes that have values that are not literal, sorted by
+		// name
+		Unresolved []string
+	}
+
+	// CatalogEdge is a containment edge or a relationship between two resources of a Catalog. Both ends
+	// are references such as "Class[Ntp]" or "File[/tmp/x]".
+	CatalogEdge struct {
+		Source string
+		Target string
+
+		// Kind is "contains" for a containment edge, "before" for an ordering relationship, and
+		// "notify" for a notifying relationship
+		Kind string
+	}
+)
+
+// relationshipParams are the metaparameters that declare relationships, the kind of relationship that
+// they declare, and whether the declaring resource is the source of the relationship
+var relationshipParams = []struct {
+	name   string
+	kind   string
+	source bool
+}{
+	{`before`, `before`, true},
+	{`notify`, `notify`, true},
+	{`require`, `before`, false},
+	{`subscribe`, `notify`, false},
+}
+
+// StaticCatalog returns the shape of the catalog that the tree rooted at the given expression produces,
+// computed without evaluating it. The catalog contains the resources that have literal titles and the
+// class resources of the classes that are defined or declared with literal names. Resources declared at
+// top scope are contained in Class[Main] and resources declared in a class are contained in the class.
+// Resources in defined types, functions, plans, lambdas, and node definitions are skipped since their
+// number and titles depend on how they are called. Relationships are taken from the before, notify,
+// require, and subscribe metaparameters and from relationship expressions with literal operands.
+func StaticCatalog(root parser.Expression) *Catalog {
+	c := &Catalog{Resources: make([]*CatalogResource, 0), Edges: make([]*CatalogEdge, 0)}
+	refs := make(map[string]bool)
+	var addResource func(r *CatalogResource, container string)
+	addClass := func(e parser.Expression, name string, container string) {
+		addResource(&CatalogResource{Expression: e, Type: `Class`, Title: CapitalizedName(name), Parameters: map[string]interface{}{}}, container)
+	}
+	addResource = func(r *CatalogResource, container string) {
+		ref := r.Reference()
+		if refs[ref] {
+			if r.Type == `Class` && container != `` {
+				// A class that is defined before it is contained
+				c.Edges = append(c.Edges, &CatalogEdge{container, ref, `contains`})
+			}
+			return
+		}
+		if container == `Class[Main]` && !refs[container] {
+			addClass(root, `main`, ``)
+		}
+		refs[ref] = true
+		c.Resources = append(c.Resources, r)
+		if container != `` {
+			c.Edges = append(c.Edges, &CatalogEdge{container, ref, `contains`})
+		}
+	}
+
+	visit := func(path []parser.Expression, e parser.Expression) {
+		container := `Class[Main]`
+		for _, p := range path {
+			switch p := p.(type) {
+			case *parser.HostClassDefinition:
+				container = `Class[` + CapitalizedName(p.Name()) + `]`
+			case *parser.ResourceTypeDefinition, *parser.FunctionDefinition, *parser.PlanDefinition, *parser.LambdaExpression, *parser.NodeDefinition:
+				return
+			}
+		}
+		switch e := e.(type) {
+		case *parser.HostClassDefinition:
+			addClass(e, e.Name(), ``)
+		case *parser.CallNamedFunctionExpression:
+			if fn, ok := e.Functor().(*parser.QualifiedName); ok && classFunctions[fn.Name()] {
+				for _, name := range literalNames(e.Arguments()) {
+					if fn.Name() == `contain` {
+						addClass(e, name, container)
+					} else {
+						addClass(e, name, ``)
+					}
+				}
+			}
+		case *parser.ResourceExpression:
+			for _, r := range resourcesOf(e) {
+				addResource(r, container)
+				for _, rp := range relationshipParams {
+					if targets, ok := r.Parameters[rp.name]; ok {
+						for _, target := range referenceList(targets) {
+							if rp.source {
+								c.Edges = append(c.Edges, &CatalogEdge{r.Reference(), target, rp.kind})
+							} else {
+								c.Edges = append(c.Edges, &CatalogEdge{target, r.Reference(), rp.kind})
+							}
+						}
+					}
+				}
+			}
+		case *parser.RelationshipExpression:
+			sources, targets := relationshipOperand(e.Lhs()), relationshipOperand(e.Rhs())
+			kind := `before`
+			switch e.Operator() {
+			case `<-`:
+				sources, targets = targets, sources
+			case `~>`:
+				kind = `notify`
+			case `<~`:
+				sources, targets, kind = targets, sources, `notify`
+			}
+			for _, s := range sources {
+				for _, t := range targets {
+					c.Edges = append(c.Edges, &CatalogEdge{s, t, kind})
+				}
+			}
+		}
+	}
+	visit(nil, root)
+	root.AllContents(nil, visit)
+	return c
+}
+
+// Reference returns the reference to the resource, e.g. "File[/tmp/x]"
+func (r *CatalogResource) Reference() string {
+	return r.Type + `[` + r.Title + `]`
+}
+
+// ToData returns the catalog in the form of the JSON representation of a Puppet catalog, i.e. a hash with
+// the resources and the edges
+func (c *Catalog) ToData() map[string]interface{} {
+	resources := make([]interface{}, len(c.Resources))
+	for i, r := range c.Resources {
+		rd := map[string]interface{}{
+			`type`:       r.Type,
+			`title`:      r.Title,
+			`exported`:   r.Exported,
+			`parameters`: r.Parameters,
+			`file`:       r.Expression.File(),
+			`line`:       int64(r.Expression.Line()),
+		}
+		if r.Virtual {
+			rd[`virtual`] = true
+		}
+		if len(r.Unresolved) > 0 {
+			unresolved := make([]interface{}, len(r.Unresolved))
+			for j, name := range r.Unresolved {
+				unresolved[j] = name
+			}
+			rd[`unresolved`] = unresolved
+		}
+		resources[i] = rd
+	}
+	edges := make([]interface{}, len(c.Edges))
+	for i, e := range c.Edges {
+		edges[i] = map[string]interface{}{`source`: e.Source, `target`: e.Target, `kind`: e.Kind}
+	}
+	return map[string]interface{}{`resources`: resources, `edges`: edges}
+}
+
+// CapitalizedName returns the given name with each segment capitalized and without a leading '::', e.g.
+// "Mymod::Vhost" for "::mymod::vhost"
+func CapitalizedName(name string) string {
+	segments := strings.Split(strings.TrimPrefix(name, `::`), `::`)
+	for i, s := range segments {
+		if s != `` {
+			segments[i] = strings.ToUpper(s[:1]) + s[1:]
+		}
+	}
+	return strings.Join(segments, `::`)
+}
+
+// resourcesOf returns the resources of the bodies of the given resource expression that have literal
+// titles. A body with an array of titles declares one resource per title.
+func resourcesOf(e *parser.ResourceExpression) []*CatalogResource {
+	tn, ok := e.TypeName().(*parser.QualifiedName)
+	if !ok {
+		return nil
+	}
+	typeName := CapitalizedName(tn.Name())
+	isClass := typeName == `Class`
+	resources := make([]*CatalogResource, 0, len(e.Bodies()))
+	for _, b := range e.Bodies() {
+		body := b.(*parser.ResourceBody)
+		titles, ok := literal.ToLiteral(body.Title())
+		if !ok {
+			continue
+		}
+		params := make(map[string]interface{})
+		unresolved := make([]string, 0)
+		for _, op := range body.Operations() {
+			ao, ok := op.(*parser.AttributeOperation)
+			if !ok {
+				continue
+			}
+			if v, ok := attributeValue(ao.Value()); ok {
+				params[ao.Name()] = v
+			} else {
+				unresolved = append(unresolved, ao.Name())
+			}
+		}
+		sort.Strings(unresolved)
+		titleList, isList := titles.([]interface{})
+		if !isList {
+			titleList = []interface{}{titles}
+		}
+		for _, t := range titleList {
+			title, ok := t.(string)
+			if !ok || title == `` {
+				continue
+			}
+			if isClass {
+				title = CapitalizedName(title)
+			}
+			resources = append(resources, &CatalogResource{
+				Expression: body,
+				Type:       typeName,
+				Title:      title,
+				Virtual:    e.Form() != parser.REGULAR,
+				Exported:   e.Form() == parser.EXPORTED,
+				Parameters: params,
+				Unresolved: unresolved,
+			})
+		}
+	}
+	return resources
+}
+
+// attributeValue returns the JSON compatible value of the given attribute value expression. Literal
+// resource references are returned as strings.
+func attributeValue(e parser.Expression) (interface{}, bool) {
+	if refs, ok := literalReferences(e); ok {
+		if _, isList := e.(*parser.LiteralList); !isList && len(refs) == 1 {
+			return refs[0], true
+		}
+		values := make([]interface{}, len(refs))
+		for i, ref := range refs {
+			values[i] = ref
+		}
+		return values, true
+	}
+	return jsonValue(e)
+}
+
+// literalReferences returns the resource references that the given expression consists of, e.g.
+// "File[/a]" and "File[/b]" for File['/a', '/b'] or [File['/a'], File['/b']]
+func literalReferences(e parser.Expression) ([]string, bool) {
+	switch e := e.(type) {
+	case *parser.AccessExpression:
+		qr, ok := e.Operand().(*parser.QualifiedReference)
+		if !ok || len(e.Keys()) == 0 {
+			return nil, false
+		}
+		typeName := CapitalizedName(qr.DowncasedName())
+		refs := make([]string, 0, len(e.Keys()))
+		for _, key := range e.Keys() {
+			title, ok := literal.ToLiteral(key)
+			s, isString := title.(string)
+			if !ok || !isString {
+				return nil, false
+			}
+			if typeName == `Class` {
+				s = CapitalizedName(s)
+			}
+			refs = append(refs, typeName+`[`+s+`]`)
+		}
+		return refs, true
+	case *parser.LiteralList:
+		refs := make([]string, 0, len(e.Elements()))
+		for _, elem := range e.Elements() {
+			r, ok := literalReferences(elem)
+			if !ok {
+				return nil, false
+			}
+			refs = append(refs, r...)
+		}
+		return refs, len(refs) > 0
+	}
+	return nil, false
+}
+
+// referenceList returns the references in the given parameter value, which is a reference or a list of
+// references
+func referenceList(v interface{}) []string {
+	switch v := v.(type) {
+	case string:
+		if strings.HasSuffix(v, `]`) && strings.Contains(v, `[`) {
+			return []string{v}
+		}
+	case []interface{}:
+		refs := make([]string, 0, len(v))
+		for _, e := range v {
+			refs = append(refs, referenceList(e)...)
+		}
+		return refs
+	}
+	return nil
+}
+
+// relationshipOperand returns the references to the resources of the given operand of a relationship
+// expression. The operand of a chain such as a -> b -> c is the rightmost operand of the chain.
+func relationshipOperand(e parser.Expression) []string {
+	switch e := e.(type) {
+	case *parser.RelationshipExpression:
+		return relationshipOperand(e.Rhs())
+	case *parser.ResourceExpression:
+		resources := resourcesOf(e)
+		refs := make([]string, len(resources))
+		for i, r := range resources {
+			refs[i] = r.Reference()
+		}
+		return refs
+	}
+	refs, _ := literalReferences(e)
+	return refs
+}
+
+// literalNames returns the literal names and strings among the given expressions and the elements of
+// arrays among them
+func literalNames(exprs []parser.Expression) []string {
+	names := make([]string, 0, len(exprs))
+	for _, e := range exprs {
+		switch e := e.(type) {
+		case *parser.QualifiedName:
+			names = append(names, e.Name())
+		case *parser.LiteralString:
+			names = append(names, e.StringValue())
+		case *parser.LiteralList:
+			names = append(names, literalNames(e.Elements())...)
+		}
+	}
+	return names
+}
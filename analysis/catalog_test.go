@@ -0,0 +1,79 @@
+package analysis
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-parser/json"
+)
+
+func TestStaticCatalog(t *testing.T) {
+	program := parse(t, issue.Unindent(`
+    class ntp::config {
+      file { ['/etc/ntp.conf', '/etc/ntp/step-tickers']:
+        ensure  => file,
+        mode    => '0644',
+        content => template('ntp/ntp.conf.erb'),
+        notify  => Service['ntpd'],
+      }
+      @user { 'ntp': uid => 38 }
+      file { "/etc/${x}": ensure => file }
+    }
+
+    class ntp {
+      contain ntp::config
+      include ntp::service
+      service { 'ntpd': ensure => running, require => [Package['ntp'], Class['ntp::config']] }
+    }
+
+    define ntp::peer() {
+      file { '/etc/ntp/peer': }
+    }
+
+    package { 'ntp': ensure => installed }
+    -> exec { 'restart': command => 'ntpd -q' }
+    Package['ntp'] ~> Service['ntpd']`))
+
+	c := StaticCatalog(program)
+	b := bytes.NewBufferString(``)
+	for _, r := range c.Resources {
+		b.WriteString(r.Reference())
+		b.WriteByte(' ')
+	}
+	if b.String() != `Class[Ntp::Config] File[/etc/ntp.conf] File[/etc/ntp/step-tickers] User[ntp] Class[Ntp] Class[Ntp::Service] Service[ntpd] Class[Main] Package[ntp] Exec[restart] ` {
+		t.Errorf("unexpected resources %s", b.String())
+	}
+
+	b.Reset()
+	for _, e := range c.Edges {
+		b.WriteString(e.Source + ` ` + e.Kind + ` ` + e.Target + "\n")
+	}
+	expected := issue.Unindent(`
+    Class[Ntp::Config] contains File[/etc/ntp.conf]
+    File[/etc/ntp.conf] notify Service[ntpd]
+    Class[Ntp::Config] contains File[/etc/ntp/step-tickers]
+    File[/etc/ntp/step-tickers] notify Service[ntpd]
+    Class[Ntp::Config] contains User[ntp]
+    Class[Ntp] contains Class[Ntp::Config]
+    Class[Ntp] contains Service[ntpd]
+    Package[ntp] before Service[ntpd]
+    Class[Ntp::Config] before Service[ntpd]
+    Package[ntp] before Exec[restart]
+    Class[Main] contains Package[ntp]
+    Class[Main] contains Exec[restart]
+    Package[ntp] notify Service[ntpd]
+    `)
+	if b.String() != expected {
+		t.Errorf("expected %s, got %s", expected, b.String())
+	}
+
+	b.Reset()
+	json.ToJson(StaticCatalog(parse(t, `@@file { '/x': mode => '0644', owner => $owner, before => File['/y'] }`)).ToData(), b)
+	expected = `{"edges":[{"kind":"contains","source":"Class[Main]","target":"File[/x]"},{"kind":"before","source":"File[/x]","target":"File[/y]"}],` +
+		`"resources":[{"exported":false,"file":"test.pp","line":1,"parameters":{},"title":"Main","type":"Class"},` +
+		`{"exported":true,"file":"test.pp","line":1,"parameters":{"before":"File[/y]","mode":"0644"},"title":"/x","type":"File","unresolved":["owner"],"virtual":true}]}` + "\n"
+	if b.String() != expected {
+		t.Errorf("expected %s, got %s", expected, b.String())
+	}
+}
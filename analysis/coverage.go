@@ -0,0 +1,238 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/lyraproj/puppet-parser/literal"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+type (
+	// FactCoverage lists the facts that the conditions of a class or a defined type depend on, i.e. the
+	// facts that tests of the definition must vary to cover all of its branches
+	FactCoverage struct {
+		// Definition is the class or defined type. It provides the position.
+		Definition parser.Expression
+
+		// Name is the name of the class or defined type
+		Name string
+
+		// Facts are the facts that conditions depend on, sorted by path
+		Facts []*ConditionalFact
+	}
+
+	// ConditionalFact is a fact that the conditions of a definition depend on
+	ConditionalFact struct {
+		// Path is the path of the fact reference in the form returned by FactReference.String, e.g.
+		// "facts.os.family"
+		Path string
+
+		// Values are the distinct literal values that the conditions compare the fact with, in the order
+		// that they appear. A regular expression is represented by its source, e.g. "/^RedHat/", and a
+		// fact that is tested for truthiness has the values true and false. Values is empty when the
+		// fact is used in a condition in some other way.
+		Values []interface{}
+
+		// Conditions are the if, unless, case, and selector expressions that depend on the fact
+		Conditions []parser.Expression
+	}
+)
+
+// ConditionalFactCoverage returns the facts that the if, unless, case, and selector conditions of each
+// class and defined type in the tree rooted at the given expression depend on, together with the values
+// that the facts are compared with. The definitions are returned in the order that they appear. Those
+// without conditions that depend on facts are omitted.
+func ConditionalFactCoverage(root parser.Expression) []*FactCoverage {
+	found := make([]*FactCoverage, 0)
+	visit := func(path []parser.Expression, e parser.Expression) {
+		var name string
+		var body parser.Expression
+		switch d := e.(type) {
+		case *parser.HostClassDefinition:
+			name, body = d.Name(), d.Body()
+		case *parser.ResourceTypeDefinition:
+			name, body = d.Name(), d.Body()
+		default:
+			return
+		}
+		if body == nil {
+			return
+		}
+		c := &coverageCollector{facts: make(map[string]*ConditionalFact), values: make(map[*ConditionalFact]map[string]bool)}
+		body.AllContents([]parser.Expression{}, func(p []parser.Expression, e parser.Expression) {
+			for _, a := range p {
+				switch a.(type) {
+				case *parser.HostClassDefinition, *parser.ResourceTypeDefinition, *parser.FunctionDefinition:
+					return
+				}
+			}
+			c.condition(e)
+		})
+		if len(c.facts) == 0 {
+			return
+		}
+		fc := &FactCoverage{Definition: e, Name: name, Facts: make([]*ConditionalFact, 0, len(c.facts))}
+		for _, f := range c.facts {
+			fc.Facts = append(fc.Facts, f)
+		}
+		sort.Slice(fc.Facts, func(i, j int) bool { return fc.Facts[i].Path < fc.Facts[j].Path })
+		found = append(found, fc)
+	}
+	visit(nil, root)
+	root.AllContents(nil, visit)
+	return found
+}
+
+// ToData returns the coverage as a hash that maps the name of the definition to a hash of fact paths
+// and the values that they are compared with
+func (fc *FactCoverage) ToData() map[string]interface{} {
+	facts := make(map[string]interface{}, len(fc.Facts))
+	for _, f := range fc.Facts {
+		values := make([]interface{}, len(f.Values))
+		copy(values, f.Values)
+		facts[f.Path] = values
+	}
+	return map[string]interface{}{`name`: fc.Name, `facts`: facts}
+}
+
+type coverageCollector struct {
+	facts  map[string]*ConditionalFact
+	values map[*ConditionalFact]map[string]bool
+}
+
+// condition collects the facts that the given expression depends on if it is a conditional expression
+func (c *coverageCollector) condition(e parser.Expression) {
+	switch e := e.(type) {
+	case *parser.UnlessExpression:
+		c.test(e, e.Test())
+	case *parser.IfExpression:
+		c.test(e, e.Test())
+	case *parser.CaseExpression:
+		values := make([]parser.Expression, 0)
+		for _, o := range e.Options() {
+			values = append(values, o.(*parser.CaseOption).Values()...)
+		}
+		if !c.compare(e, e.Test(), values) {
+			c.uses(e, e.Test())
+		}
+	case *parser.SelectorExpression:
+		values := make([]parser.Expression, 0, len(e.Selectors()))
+		for _, s := range e.Selectors() {
+			values = append(values, s.(*parser.SelectorEntry).Matching())
+		}
+		if !c.compare(e, e.Lhs(), values) {
+			c.uses(e, e.Lhs())
+		}
+	}
+}
+
+// test collects the facts of the given test of an if or unless expression
+func (c *coverageCollector) test(cond parser.Expression, test parser.Expression) {
+	switch t := test.(type) {
+	case *parser.AndExpression:
+		c.test(cond, t.Lhs())
+		c.test(cond, t.Rhs())
+	case *parser.OrExpression:
+		c.test(cond, t.Lhs())
+		c.test(cond, t.Rhs())
+	case *parser.NotExpression:
+		c.test(cond, t.Expr())
+	case *parser.ParenthesizedExpression:
+		c.test(cond, t.Expr())
+	case *parser.ComparisonExpression, *parser.MatchExpression:
+		be := t.(parser.BinaryExpression)
+		if c.compare(cond, be.Lhs(), []parser.Expression{be.Rhs()}) || c.compare(cond, be.Rhs(), []parser.Expression{be.Lhs()}) {
+			return
+		}
+		c.uses(cond, test)
+	case *parser.InExpression:
+		// $facts['x'] in ['a', 'b'] compares with each element while 'a' in $facts['x'] tests membership
+		if list, ok := t.Rhs().(*parser.LiteralList); ok && c.compare(cond, t.Lhs(), list.Elements()) {
+			return
+		}
+		if c.compare(cond, t.Rhs(), []parser.Expression{t.Lhs()}) {
+			return
+		}
+		c.uses(cond, test)
+	default:
+		if ref, ok := factReference(test); ok {
+			c.add(cond, ref, []interface{}{true, false})
+			return
+		}
+		c.uses(cond, test)
+	}
+}
+
+// compare adds the values that the given expression is compared with if the expression is a fact
+// reference and returns true, or returns false when it isn't
+func (c *coverageCollector) compare(cond parser.Expression, e parser.Expression, values []parser.Expression) bool {
+	ref, ok := factReference(e)
+	if !ok {
+		return false
+	}
+	lvs := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		if lv, ok := conditionValue(v); ok {
+			lvs = append(lvs, lv)
+		}
+	}
+	c.add(cond, ref, lvs)
+	return true
+}
+
+// uses adds the facts that are referenced by the given expression without adding any values
+func (c *coverageCollector) uses(cond parser.Expression, e parser.Expression) {
+	for _, ref := range FindFactReferences(e) {
+		c.add(cond, ref, nil)
+	}
+}
+
+func (c *coverageCollector) add(cond parser.Expression, ref *FactReference, values []interface{}) {
+	path := ref.String()
+	f, ok := c.facts[path]
+	if !ok {
+		f = &ConditionalFact{Path: path, Values: make([]interface{}, 0), Conditions: make([]parser.Expression, 0)}
+		c.facts[path] = f
+		c.values[f] = make(map[string]bool)
+	}
+	if n := len(f.Conditions); n == 0 || f.Conditions[n-1] != cond {
+		f.Conditions = append(f.Conditions, cond)
+	}
+	for _, v := range values {
+		key := fmt.Sprintf(`%T %v`, v, v)
+		if !c.values[f][key] {
+			c.values[f][key] = true
+			f.Values = append(f.Values, v)
+		}
+	}
+}
+
+// factReference returns the fact reference that the given expression consists of
+func factReference(e parser.Expression) (*FactReference, bool) {
+	if pe, ok := e.(*parser.ParenthesizedExpression); ok {
+		return factReference(pe.Expr())
+	}
+	refs := FindFactReferences(e)
+	if len(refs) == 1 && refs[0].Expression == e {
+		return refs[0], true
+	}
+	return nil, false
+}
+
+// conditionValue returns the JSON compatible value of the given expression that a fact is compared with.
+// A regular expression is returned as its source and a bare word as a string.
+func conditionValue(e parser.Expression) (interface{}, bool) {
+	switch e := e.(type) {
+	case *parser.LiteralDefault:
+		return nil, false
+	case *parser.RegexpExpression:
+		return `/` + e.PatternString() + `/`, true
+	case *parser.QualifiedName:
+		return e.Name(), true
+	}
+	if v, ok := literal.ToLiteral(e); ok {
+		return toJSON(v)
+	}
+	return nil, false
+}
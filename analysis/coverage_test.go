@@ -0,0 +1,49 @@
+package analysis
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-parser/json"
+)
+
+func TestConditionalFactCoverage(t *testing.T) {
+	program := parse(t, issue.Unindent(`
+    class ntp {
+      case $facts['os']['family'] {
+        'RedHat', 'Suse': { $pkg = 'ntp' }
+        /^Deb/: { $pkg = 'ntpsec' }
+        default: { fail('unsupported') }
+      }
+      if $facts['is_virtual'] and $facts['os']['release']['major'] == '8' {
+        $x = 1
+      }
+      unless $::osfamily in ['RedHat', 'Debian'] {
+        $y = 2
+      }
+      $z = $facts['os']['family'] ? { windows => 'c:/', default => '/' }
+      if versioncmp($facts['puppetversion'], '7') > 0 {
+        $w = 3
+      }
+    }
+
+    define ntp::peer() {
+      notice($facts['os']['name'])
+    }`))
+
+	coverage := ConditionalFactCoverage(program)
+	if len(coverage) != 1 || coverage[0].Name != `ntp` {
+		t.Fatalf("expected coverage of class ntp")
+	}
+	b := bytes.NewBufferString(``)
+	json.ToJson(coverage[0].ToData(), b)
+	expected := `{"facts":{"::osfamily":["RedHat","Debian"],"facts.is_virtual":[true,false],"facts.os.family":["RedHat","Suse","/^Deb/","windows"],` +
+		`"facts.os.release.major":["8"],"facts.puppetversion":[]},"name":"ntp"}` + "\n"
+	if b.String() != expected {
+		t.Errorf("expected %s, got %s", expected, b.String())
+	}
+	if f := coverage[0].Facts[2]; f.Path != `facts.os.family` || len(f.Conditions) != 2 || f.Conditions[0].Line() != 2 {
+		t.Errorf("unexpected conditions of %s", f.Path)
+	}
+}
@@ -0,0 +1,124 @@
+// Package analysis contains analyses that extract information from parsed Puppet code, such as the
+// constructs that are evaluated on the agent rather than during compilation.
+package analysis
+
+import (
+	"github.com/lyraproj/puppet-parser/literal"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+type (
+	// DeferredCall is a construction of a Deferred value, i.e. Deferred('f', [args]) or
+	// Deferred.new('f', [args]). The deferred function is called on the agent when the catalog is
+	// applied.
+	DeferredCall struct {
+		// Call is the expression that constructs the Deferred value. It provides the position.
+		Call parser.Expression
+
+		// Function is the name of the deferred function, or an empty string when the name is not
+		// a literal string
+		Function string
+
+		// Arguments is the expression that produces the arguments of the deferred function, or nil
+		// when no arguments are given
+		Arguments parser.Expression
+
+		// Literals are the arguments of the deferred function when they are all literal. It is nil
+		// when the arguments are not literal.
+		Literals []interface{}
+
+		// Sensitive is true when the Deferred value is wrapped in a Sensitive value
+		Sensitive bool
+	}
+
+	// SensitiveWrapper is a construction of a Sensitive value, i.e. Sensitive(x) or Sensitive.new(x)
+	SensitiveWrapper struct {
+		// Call is the expression that constructs the Sensitive value. It provides the position.
+		Call parser.Expression
+
+		// Value is the expression that produces the wrapped value
+		Value parser.Expression
+
+		// Deferred is true when the wrapped value is a Deferred value
+		Deferred bool
+	}
+)
+
+// FindDeferred returns all constructions of Deferred values in the tree rooted at the given expression in
+// the order that they appear
+func FindDeferred(root parser.Expression) []*DeferredCall {
+	found := make([]*DeferredCall, 0)
+	visit := func(path []parser.Expression, e parser.Expression) {
+		args, ok := typeConstruction(e, `Deferred`)
+		if !ok {
+			return
+		}
+		dc := &DeferredCall{Call: e}
+		if len(args) > 0 {
+			if name, ok := literal.ToLiteral(args[0]); ok {
+				dc.Function, _ = name.(string)
+			}
+		}
+		if len(args) > 1 {
+			dc.Arguments = args[1]
+			if values, ok := literal.ToLiteral(args[1]); ok {
+				dc.Literals, _ = values.([]interface{})
+			}
+		}
+		if len(path) > 0 {
+			if _, ok := typeConstruction(path[len(path)-1], `Sensitive`); ok {
+				dc.Sensitive = true
+			}
+		}
+		found = append(found, dc)
+	}
+	visit(nil, root)
+	root.AllContents(nil, visit)
+	return found
+}
+
+// FindSensitive returns all constructions of Sensitive values in the tree rooted at the given expression
+// in the order that they appear
+func FindSensitive(root parser.Expression) []*SensitiveWrapper {
+	found := make([]*SensitiveWrapper, 0)
+	visit := func(path []parser.Expression, e parser.Expression) {
+		args, ok := typeConstruction(e, `Sensitive`)
+		if !ok || len(args) == 0 {
+			return
+		}
+		sw := &SensitiveWrapper{Call: e, Value: args[0]}
+		_, sw.Deferred = typeConstruction(args[0], `Deferred`)
+		found = append(found, sw)
+	}
+	visit(nil, root)
+	root.AllContents(nil, visit)
+	return found
+}
+
+// typeConstruction returns the arguments and true if the given expression is a call to the type with the
+// given name, e.g. T(args) or T.new(args). The type may be parameterized.
+func typeConstruction(e parser.Expression, typeName string) ([]parser.Expression, bool) {
+	switch e := e.(type) {
+	case *parser.CallNamedFunctionExpression:
+		if isType(e.Functor(), typeName) {
+			return e.Arguments(), true
+		}
+	case *parser.CallMethodExpression:
+		if na, ok := e.Functor().(*parser.NamedAccessExpression); ok && isType(na.Lhs(), typeName) {
+			if qn, ok := na.Rhs().(*parser.QualifiedName); ok && qn.Name() == `new` {
+				return e.Arguments(), true
+			}
+		}
+	}
+	return nil, false
+}
+
+// isType returns true if the given expression is a reference to the type with the given name,
+// optionally parameterized
+func isType(e parser.Expression, typeName string) bool {
+	if ae, ok := e.(*parser.AccessExpression); ok {
+		e = ae.Operand()
+	}
+	qr, ok := e.(*parser.QualifiedReference)
+	return ok && qr.Name() == typeName
+}
@@ -0,0 +1,68 @@
+package analysis
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+func TestFindDeferred(t *testing.T) {
+	program := parse(t, issue.Unindent(`
+    $a = Deferred('vault_lookup::lookup', ['secret/db', 'https://vault:8200'])
+    $b = Sensitive(Deferred('new_password'))
+    $c = Deferred.new($fn, [$x])
+    $d = Sensitive.new('plain')
+    notice(Deferred)`))
+
+	deferred := FindDeferred(program)
+	if len(deferred) != 3 {
+		t.Fatalf("expected 3 deferred calls, got %d", len(deferred))
+	}
+
+	d := deferred[0]
+	if d.Function != `vault_lookup::lookup` || d.Sensitive || d.Call.Line() != 1 || d.Call.Pos() != 6 {
+		t.Errorf("unexpected first deferred call %s", d.Call)
+	}
+	if !reflect.DeepEqual(d.Literals, []interface{}{`secret/db`, `https://vault:8200`}) {
+		t.Errorf("unexpected literals %v", d.Literals)
+	}
+
+	d = deferred[1]
+	if d.Function != `new_password` || !d.Sensitive || d.Arguments != nil || d.Literals != nil {
+		t.Errorf("unexpected second deferred call %s", d.Call)
+	}
+
+	d = deferred[2]
+	if d.Function != `` || d.Sensitive || d.Arguments == nil || d.Literals != nil || d.Call.Line() != 3 {
+		t.Errorf("unexpected third deferred call %s", d.Call)
+	}
+}
+
+func TestFindSensitive(t *testing.T) {
+	program := parse(t, issue.Unindent(`
+    $b = Sensitive(Deferred('new_password'))
+    $d = Sensitive.new('plain')
+    $e = Sensitive`))
+
+	sensitive := FindSensitive(program)
+	if len(sensitive) != 2 {
+		t.Fatalf("expected 2 sensitive values, got %d", len(sensitive))
+	}
+	if !sensitive[0].Deferred || sensitive[0].Call.Line() != 1 {
+		t.Errorf("unexpected first sensitive value %s", sensitive[0].Call)
+	}
+	if sensitive[1].Deferred || sensitive[1].Value.String() != `'plain'` {
+		t.Errorf("unexpected second sensitive value %s", sensitive[1].Call)
+	}
+}
+
+func parse(t *testing.T, source string) parser.Expression {
+	t.Helper()
+	program, err := parser.CreateParser().Parse(`test.pp`, source, false)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	return program
+}
@@ -0,0 +1,177 @@
+package analysis
+
+import (
+	"strings"
+
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+type (
+	// DocString is a documentation comment in the format used by Puppet Strings, i.e. a description
+	// followed by tags such as @summary, @param, and @example
+	DocString struct {
+		// Text is the description, i.e. the text before the first tag
+		Text string
+
+		// Tags are the tags in the order that they appear
+		Tags []*DocTag
+	}
+
+	// DocTag is a tag of a DocString
+	DocTag struct {
+		// Tag is the name of the tag without the leading '@', e.g. "param"
+		Tag string
+
+		// Name is the name of the parameter of a @param or an @option tag, without a leading '$', or the
+		// title of an @example tag. It's empty for other tags.
+		Name string
+
+		// Text is the text of the tag. The lines of an @example are kept as is, apart from the common
+		// indentation, while the lines of other tags are joined with a space.
+		Text string
+	}
+)
+
+// namedTags are the tags that start with a name
+var namedTags = map[string]bool{`param`: true, `option`: true}
+
+// DocComment returns the text of the comment lines that immediately precede the line where the given
+// expression starts, with the leading '#' and one space removed from each line
+func DocComment(e parser.Expression) string {
+	src := e.Locator().String()
+	start := strings.LastIndexByte(src[:e.ByteOffset()-e.Locator().HostOffset(0)], '\n') + 1
+	lines := make([]string, 0)
+	for start > 0 {
+		end := start - 1
+		start = strings.LastIndexByte(src[:end], '\n') + 1
+		line := strings.TrimSpace(src[start:end])
+		if !strings.HasPrefix(line, `#`) {
+			break
+		}
+		line = strings.TrimPrefix(line[1:], ` `)
+		lines = append(lines, line)
+	}
+	for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+		lines[i], lines[j] = lines[j], lines[i]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ParseDocString parses the given comment, e.g. a comment returned by DocComment. Lines that follow a tag
+// and are indented continue the text of that tag. The text of an @example tag also continues over empty
+// lines. Other lines that follow a tag are ignored.
+func ParseDocString(comment string) *DocString {
+	doc := &DocString{Tags: make([]*DocTag, 0)}
+	desc := make([]string, 0)
+	var tag *DocTag
+	var lines []string
+	endTag := func() {
+		if tag == nil {
+			return
+		}
+		if tag.Tag == `example` {
+			tag.Text = strings.TrimRight(unindent(lines), "\n ")
+		} else {
+			tag.Text = strings.Join(lines, ` `)
+		}
+		tag = nil
+	}
+	for _, line := range strings.Split(comment, "\n") {
+		if strings.HasPrefix(line, `@`) {
+			endTag()
+			fields := strings.Fields(line)
+			tag = &DocTag{Tag: fields[0][1:]}
+			fields = fields[1:]
+			switch {
+			case tag.Tag == `example`:
+				tag.Name = strings.Join(fields, ` `)
+				fields = nil
+			case namedTags[tag.Tag] && len(fields) > 0:
+				tag.Name = strings.TrimPrefix(fields[0], `$`)
+				fields = fields[1:]
+			}
+			lines = make([]string, 0)
+			if len(fields) > 0 {
+				lines = append(lines, strings.Join(fields, ` `))
+			}
+			doc.Tags = append(doc.Tags, tag)
+			continue
+		}
+		indented := line != `` && (line[0] == ' ' || line[0] == '\t')
+		switch {
+		case len(doc.Tags) == 0:
+			desc = append(desc, line)
+		case tag == nil:
+		case tag.Tag == `example` && (indented || strings.TrimSpace(line) == ``):
+			lines = append(lines, line)
+		case indented && strings.TrimSpace(line) != ``:
+			lines = append(lines, strings.TrimSpace(line))
+		default:
+			endTag()
+		}
+	}
+	endTag()
+	doc.Text = strings.TrimSpace(strings.Join(desc, "\n"))
+	return doc
+}
+
+// Tag returns the first tag with the given name, or nil when there is no such tag
+func (d *DocString) Tag(name string) *DocTag {
+	for _, t := range d.Tags {
+		if t.Tag == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// TagsNamed returns the tags with the given name in the order that they appear
+func (d *DocString) TagsNamed(name string) []*DocTag {
+	tags := make([]*DocTag, 0)
+	for _, t := range d.Tags {
+		if t.Tag == name {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
+
+// TagText returns the text of the first tag with the given name, or an empty string when there is no
+// such tag
+func (d *DocString) TagText(name string) string {
+	if t := d.Tag(name); t != nil {
+		return t.Text
+	}
+	return ``
+}
+
+// Param returns the text of the @param tag of the parameter with the given name, or an empty string when
+// there is no such tag
+func (d *DocString) Param(name string) string {
+	for _, t := range d.Tags {
+		if t.Tag == `param` && t.Name == name {
+			return t.Text
+		}
+	}
+	return ``
+}
+
+// unindent joins the given lines after removing the indentation that all non-empty lines have in common
+func unindent(lines []string) string {
+	indent := -1
+	for _, line := range lines {
+		if trimmed := strings.TrimLeft(line, " \t"); trimmed != `` {
+			if n := len(line) - len(trimmed); indent < 0 || n < indent {
+				indent = n
+			}
+		}
+	}
+	for i, line := range lines {
+		if len(line) >= indent && indent > 0 {
+			lines[i] = line[indent:]
+		} else {
+			lines[i] = strings.TrimLeft(line, " \t")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,48 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+)
+
+func TestParseDocString(t *testing.T) {
+	doc := ParseDocString(issue.Unindent(`
+    Manages NTP
+
+    Installs and configures the service.
+    @summary NTP
+    @param servers
+      The servers to use,
+      in order of preference
+    @option $opts :retries
+      The number of retries
+    @api private
+    @example Basic usage
+      class { 'ntp':
+        servers => ['a'],
+      }
+
+      include ntp::peer
+    not part of the example`))
+
+	if doc.Text != "Manages NTP\n\nInstalls and configures the service." {
+		t.Errorf("unexpected text %q", doc.Text)
+	}
+	if len(doc.Tags) != 5 {
+		t.Fatalf("expected 5 tags, got %d", len(doc.Tags))
+	}
+	if doc.TagText(`summary`) != `NTP` || doc.TagText(`api`) != `private` || doc.TagText(`see`) != `` {
+		t.Error(`unexpected text of tag`)
+	}
+	if doc.Param(`servers`) != `The servers to use, in order of preference` || doc.Param(`opts`) != `` {
+		t.Errorf("unexpected param text %q", doc.Param(`servers`))
+	}
+	if o := doc.TagsNamed(`option`); len(o) != 1 || o[0].Name != `opts` || o[0].Text != `:retries The number of retries` {
+		t.Error(`unexpected option tag`)
+	}
+	ex := doc.Tag(`example`)
+	if ex.Name != `Basic usage` || ex.Text != "class { 'ntp':\n  servers => ['a'],\n}\n\ninclude ntp::peer" {
+		t.Errorf("unexpected example %q", ex.Text)
+	}
+}
@@ -0,0 +1,149 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lyraproj/puppet-parser/literal"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+// FactSource tells where the data of a FactReference comes from
+type FactSource string
+
+const (
+	// FACTS is a reference to the $facts hash
+	FACTS = FactSource(`facts`)
+
+	// TRUSTED is a reference to the $trusted hash
+	TRUSTED = FactSource(`trusted`)
+
+	// SERVER_FACTS is a reference to the $server_facts hash
+	SERVER_FACTS = FactSource(`server_facts`)
+
+	// TOP_SCOPE_FACT is a reference to a legacy fact in the form of a top scope variable, e.g. $::osfamily
+	TOP_SCOPE_FACT = FactSource(`top scope`)
+)
+
+// FactReference is a reference to facts or trusted data
+type FactReference struct {
+	// Expression is the outermost expression of the reference. It provides the position.
+	Expression parser.Expression
+
+	// Source tells which data that is referenced
+	Source FactSource
+
+	// Path is the access path into the referenced data, e.g. ['os', 'family'] for $facts['os']['family'].
+	// The path of a top scope fact starts with the name of the variable. The path ends at the first
+	// key that isn't a literal string or integer. An empty path is a reference to the whole hash.
+	Path []interface{}
+}
+
+// String returns the reference in the form of the source followed by the path in dot notation, e.g.
+// "facts.os.family"
+func (r *FactReference) String() string {
+	b := strings.Builder{}
+	if r.Source == TOP_SCOPE_FACT {
+		b.WriteString(`::`)
+	} else {
+		b.WriteString(string(r.Source))
+	}
+	for i, key := range r.Path {
+		if !(i == 0 && r.Source == TOP_SCOPE_FACT) {
+			b.WriteByte('.')
+		}
+		b.WriteString(fmt.Sprint(key))
+	}
+	return b.String()
+}
+
+var factSources = map[string]FactSource{
+	`facts`:          FACTS,
+	`::facts`:        FACTS,
+	`trusted`:        TRUSTED,
+	`::trusted`:      TRUSTED,
+	`server_facts`:   SERVER_FACTS,
+	`::server_facts`: SERVER_FACTS,
+}
+
+// FindFactReferences returns all references to $facts, $trusted, $server_facts, and top scope variables
+// in the tree rooted at the given expression in the order that they appear. A top scope variable is a
+// variable with a name that starts with '::' and has no further namespace segments, e.g. $::osfamily.
+// Such variables are assumed to be legacy facts.
+//
+// Access using the [] operator and the dig function with literal keys extend the path of a reference.
+func FindFactReferences(root parser.Expression) []*FactReference {
+	found := make([]*FactReference, 0)
+	visit := func(path []parser.Expression, e parser.Expression) {
+		ve, ok := e.(*parser.VariableExpression)
+		if !ok {
+			return
+		}
+		name, ok := ve.Name()
+		if !ok {
+			return
+		}
+		ref := &FactReference{Expression: e}
+		if src, ok := factSources[name]; ok {
+			ref.Source = src
+			ref.Path = []interface{}{}
+		} else if strings.HasPrefix(name, `::`) && !strings.Contains(name[2:], `::`) {
+			ref.Source = TOP_SCOPE_FACT
+			ref.Path = []interface{}{name[2:]}
+		} else {
+			return
+		}
+		extendPath(ref, path)
+		found = append(found, ref)
+	}
+	visit(nil, root)
+	root.AllContents(nil, visit)
+	return found
+}
+
+// extendPath extends the path of the given reference with the literal keys of the access expressions
+// and dig calls that are applied to it. The parents of the reference are given in path.
+func extendPath(ref *FactReference, path []parser.Expression) {
+	for i := len(path) - 1; i >= 0; i-- {
+		var keys []parser.Expression
+		var next parser.Expression
+		switch p := path[i].(type) {
+		case *parser.AccessExpression:
+			if p.Operand() != ref.Expression || len(p.Keys()) != 1 {
+				return
+			}
+			keys = p.Keys()
+			next = p
+		case *parser.NamedAccessExpression:
+			// The dig call is the parent of the named access
+			if i == 0 || p.Lhs() != ref.Expression {
+				return
+			}
+			if qn, ok := p.Rhs().(*parser.QualifiedName); !ok || qn.Name() != `dig` {
+				return
+			}
+			call, ok := path[i-1].(*parser.CallMethodExpression)
+			if !ok || call.Functor() != p {
+				return
+			}
+			keys = call.Arguments()
+			next = call
+			i--
+		default:
+			return
+		}
+		for _, key := range keys {
+			k, ok := literal.ToLiteral(key)
+			if !ok {
+				return
+			}
+			switch k.(type) {
+			case string, int64:
+				ref.Path = append(ref.Path, k)
+			default:
+				return
+			}
+		}
+		ref.Expression = next
+	}
+}
@@ -0,0 +1,52 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+func TestFindFactReferences(t *testing.T) {
+	program := parse(t, issue.Unindent(`
+    $a = $facts['os']['family']
+    $b = $trusted['certname']
+    $c = $facts.dig('os', 'release', 'major')
+    $d = "${::osfamily} ${facts['networking']['interfaces'][$x]['ip']}"
+    $e = $::facts
+    $f = $server_facts[0]
+    $g = $::foo::bar
+    $h = $osfamily`))
+
+	expected := []string{
+		`facts.os.family`,
+		`trusted.certname`,
+		`facts.os.release.major`,
+		`::osfamily`,
+		`facts.networking.interfaces`,
+		`facts`,
+		`server_facts.0`,
+	}
+	refs := FindFactReferences(program)
+	if len(refs) != len(expected) {
+		t.Fatalf("expected %d references, got %d", len(expected), len(refs))
+	}
+	for i, ref := range refs {
+		if ref.String() != expected[i] {
+			t.Errorf("expected reference %s, got %s", expected[i], ref)
+		}
+	}
+
+	if refs[0].Source != FACTS || refs[0].Expression.Line() != 1 || refs[0].Expression.Pos() != 6 || refs[0].Expression.String() != `$facts['os']['family']` {
+		t.Errorf("unexpected expression %s for %s", refs[0].Expression, refs[0])
+	}
+	if refs[1].Source != TRUSTED || refs[3].Source != TOP_SCOPE_FACT || refs[6].Source != SERVER_FACTS {
+		t.Errorf("unexpected sources")
+	}
+	if refs[2].Expression.String() != `$facts.dig('os', 'release', 'major')` {
+		t.Errorf("unexpected expression %s for %s", refs[2].Expression, refs[2])
+	}
+	if _, ok := refs[4].Expression.(*parser.AccessExpression); !ok {
+		t.Errorf("unexpected expression %s for %s", refs[4].Expression, refs[4])
+	}
+}
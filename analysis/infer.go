@@ -0,0 +1,262 @@
+package analysis
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/lyraproj/puppet-parser/parser"
+	"github.com/lyraproj/puppet-parser/printer"
+)
+
+// inferredType is the type of a value as computed by InferType
+type inferredType struct {
+	// name is the name of the Pcore type, e.g. Integer or Array
+	name string
+
+	// intMin and intMax are the range of an Integer
+	intMin, intMax int64
+
+	// floatMin and floatMax are the range of a Float
+	floatMin, floatMax float64
+
+	// param is the parameter of a Regexp or a Type
+	param string
+
+	// element is the element type of an Array or the type of an Optional. key and element are the key
+	// and value types of a Hash. Both are nil when the collection is empty
+	key, element *inferredType
+
+	// minSize and maxSize are the size range of an Array or a Hash
+	minSize, maxSize int
+}
+
+var anyType = &inferredType{name: `Any`}
+
+// scalarData are the names of the types that are assignable to ScalarData
+var scalarData = map[string]bool{
+	`Boolean`:    true,
+	`Float`:      true,
+	`Integer`:    true,
+	`Numeric`:    true,
+	`ScalarData`: true,
+	`String`:     true,
+}
+
+// InferType returns the Pcore type of the value that the given expression evaluates to. The type can be
+// inferred for literal values, arrays and hashes of such values, and selectors that select such values.
+// The type is Any for all other expressions.
+//
+// Integers and floats have a type that is restricted to the value, e.g. Integer[5, 5]. Arrays and hashes
+// have a type that is restricted to their size and to the common type of their elements, e.g.
+// Array[Integer[1, 3], 3, 3] for the array [1, 2, 3]. The common type of types that differ only in
+// range is the type with a range that spans both.
+func InferType(e parser.Expression) string {
+	return infer(e).String()
+}
+
+func infer(e parser.Expression) *inferredType {
+	switch e := e.(type) {
+	case *parser.Program:
+		return infer(e.Body())
+	case *parser.BlockExpression:
+		// The value of a block is the value of its last statement
+		if n := len(e.Statements()); n > 0 {
+			return infer(e.Statements()[n-1])
+		}
+		return &inferredType{name: `Undef`}
+	case *parser.LiteralUndef:
+		return &inferredType{name: `Undef`}
+	case *parser.LiteralDefault:
+		return &inferredType{name: `Default`}
+	case *parser.LiteralBoolean:
+		return &inferredType{name: `Boolean`}
+	case *parser.LiteralInteger:
+		return &inferredType{name: `Integer`, intMin: e.Int(), intMax: e.Int()}
+	case *parser.LiteralFloat:
+		return &inferredType{name: `Float`, floatMin: e.Float(), floatMax: e.Float()}
+	case *parser.LiteralString, *parser.ConcatenatedString, *parser.HeredocExpression:
+		return &inferredType{name: `String`}
+	case *parser.RegexpExpression:
+		return &inferredType{name: `Regexp`, param: `/` + e.PatternString() + `/`}
+	case *parser.QualifiedReference, *parser.AccessExpression:
+		if s, ok := typeString(e); ok {
+			return &inferredType{name: `Type`, param: s}
+		}
+	case *parser.ParenthesizedExpression:
+		return infer(e.Expr())
+	case *parser.UnaryMinusExpression:
+		switch t := infer(e.Expr()); t.name {
+		case `Integer`:
+			// The negation of the least Integer is out of range
+			if t.intMin != math.MinInt64 {
+				return &inferredType{name: `Integer`, intMin: -t.intMax, intMax: -t.intMin}
+			}
+		case `Float`:
+			return &inferredType{name: `Float`, floatMin: -t.floatMax, floatMax: -t.floatMin}
+		}
+	case *parser.LiteralList:
+		t := &inferredType{name: `Array`, minSize: len(e.Elements()), maxSize: len(e.Elements())}
+		for _, elem := range e.Elements() {
+			t.element = commonType(t.element, infer(elem))
+		}
+		return t
+	case *parser.LiteralHash:
+		t := &inferredType{name: `Hash`, minSize: len(e.Entries()), maxSize: len(e.Entries())}
+		for _, entry := range e.Entries() {
+			if ke, ok := entry.(*parser.KeyedEntry); ok {
+				t.key = commonType(t.key, infer(ke.Key()))
+				t.element = commonType(t.element, infer(ke.Value()))
+			}
+		}
+		return t
+	case *parser.SelectorExpression:
+		var t *inferredType
+		for _, s := range e.Selectors() {
+			if se, ok := s.(*parser.SelectorEntry); ok {
+				t = commonType(t, infer(se.Value()))
+			}
+		}
+		if t != nil {
+			return t
+		}
+	}
+	return anyType
+}
+
+// commonType returns the most specific type that both of the given types are assignable to. A nil type
+// is assignable to all types.
+func commonType(a, b *inferredType) *inferredType {
+	switch {
+	case a == nil:
+		return b
+	case b == nil:
+		return a
+	case a.name == `Undef` && b.name == `Undef`:
+		return a
+	case a.name == `Undef`:
+		return optional(b)
+	case b.name == `Undef`:
+		return optional(a)
+	case a.name == `Optional` || b.name == `Optional`:
+		return optional(commonType(unwrapOptional(a), unwrapOptional(b)))
+	case a.name == b.name:
+		switch a.name {
+		case `Integer`:
+			return &inferredType{name: a.name, intMin: min(a.intMin, b.intMin), intMax: max(a.intMax, b.intMax)}
+		case `Float`:
+			return &inferredType{name: a.name, floatMin: min(a.floatMin, b.floatMin), floatMax: max(a.floatMax, b.floatMax)}
+		case `Array`, `Hash`:
+			return &inferredType{
+				name:    a.name,
+				key:     commonType(a.key, b.key),
+				element: commonType(a.element, b.element),
+				minSize: min(a.minSize, b.minSize),
+				maxSize: max(a.maxSize, b.maxSize)}
+		case `Regexp`, `Type`:
+			if a.param != b.param {
+				return &inferredType{name: a.name}
+			}
+		}
+		return a
+	case (a.name == `Integer` || a.name == `Float` || a.name == `Numeric`) && (b.name == `Integer` || b.name == `Float` || b.name == `Numeric`):
+		return &inferredType{name: `Numeric`}
+	case scalarData[a.name] && scalarData[b.name]:
+		return &inferredType{name: `ScalarData`}
+	case (scalarData[a.name] || a.name == `Regexp` || a.name == `Scalar`) && (scalarData[b.name] || b.name == `Regexp` || b.name == `Scalar`):
+		return &inferredType{name: `Scalar`}
+	}
+	return anyType
+}
+
+func optional(t *inferredType) *inferredType {
+	if t.name == `Optional` || t.name == `Any` {
+		return t
+	}
+	return &inferredType{name: `Optional`, element: t}
+}
+
+func unwrapOptional(t *inferredType) *inferredType {
+	if t.name == `Optional` {
+		return t.element
+	}
+	return t
+}
+
+// typeString returns the string form of the given type reference, which may be parameterized with
+// literal integers, strings, and other type references, and true, or an empty string and false when
+// the expression is not such a reference
+func typeString(e parser.Expression) (string, bool) {
+	switch e := e.(type) {
+	case *parser.QualifiedReference:
+		return e.Name(), true
+	case *parser.LiteralInteger:
+		return strconv.FormatInt(e.Int(), 10), true
+	case *parser.LiteralString:
+		return printer.SingleQuote(e.StringValue()), true
+	case *parser.LiteralDefault:
+		return `default`, true
+	case *parser.AccessExpression:
+		b := bytes.NewBufferString(``)
+		s, ok := typeString(e.Operand())
+		if !ok {
+			return ``, false
+		}
+		b.WriteString(s)
+		b.WriteByte('[')
+		for i, key := range e.Keys() {
+			if i > 0 {
+				b.WriteString(`, `)
+			}
+			if s, ok = typeString(key); !ok {
+				return ``, false
+			}
+			b.WriteString(s)
+		}
+		b.WriteByte(']')
+		return b.String(), true
+	}
+	return ``, false
+}
+
+func (t *inferredType) String() string {
+	switch t.name {
+	case `Integer`:
+		return fmt.Sprintf(`Integer[%d, %d]`, t.intMin, t.intMax)
+	case `Float`:
+		return fmt.Sprintf(`Float[%s, %s]`, formatFloat(t.floatMin), formatFloat(t.floatMax))
+	case `Regexp`, `Type`:
+		if t.param != `` {
+			return t.name + `[` + t.param + `]`
+		}
+	case `Optional`:
+		return `Optional[` + t.element.String() + `]`
+	case `Array`:
+		if t.element == nil {
+			return fmt.Sprintf(`Array[%d, %d]`, t.minSize, t.maxSize)
+		}
+		return fmt.Sprintf(`Array[%s, %d, %d]`, t.element, t.minSize, t.maxSize)
+	case `Hash`:
+		if t.element == nil {
+			return fmt.Sprintf(`Hash[%d, %d]`, t.minSize, t.maxSize)
+		}
+		return fmt.Sprintf(`Hash[%s, %s, %d, %d]`, t.key, t.element, t.minSize, t.maxSize)
+	}
+	return t.name
+}
+
+// formatFloat formats the given float so that it always has a decimal point or an exponent, and hence
+// can't be mistaken for an Integer. Floats of 1e21 and above are formatted with an exponent.
+func formatFloat(f float64) string {
+	format := byte('f')
+	if math.Abs(f) >= 1e21 {
+		format = 'g'
+	}
+	s := strconv.FormatFloat(f, format, -1, 64)
+	if !strings.ContainsAny(s, `.e`) {
+		s += `.0`
+	}
+	return s
+}
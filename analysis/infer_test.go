@@ -0,0 +1,54 @@
+package analysis
+
+import (
+	"math"
+	"testing"
+
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+func TestInferType(t *testing.T) {
+	tests := map[string]string{
+		`undef`:                                 `Undef`,
+		`default`:                               `Default`,
+		`true`:                                  `Boolean`,
+		`42`:                                    `Integer[42, 42]`,
+		`-42`:                                   `Integer[-42, -42]`,
+		`-(42)`:                                 `Integer[-42, -42]`,
+		`3.0`:                                   `Float[3.0, 3.0]`,
+		`1e19`:                                  `Float[10000000000000000000.0, 10000000000000000000.0]`,
+		`-1.5e300`:                              `Float[-1.5e+300, -1.5e+300]`,
+		`Enum['it\'s']`:                         `Type[Enum['it\'s']]`,
+		`'abc'`:                                 `String`,
+		`"abc${x}"`:                             `String`,
+		`/^a.*b$/`:                              `Regexp[/^a.*b$/]`,
+		`String`:                                `Type[String]`,
+		`Enum['a', 'b']`:                        `Type[Enum['a', 'b']]`,
+		`[]`:                                    `Array[0, 0]`,
+		`[1, 2, 3]`:                             `Array[Integer[1, 3], 3, 3]`,
+		`[1, 2.5]`:                              `Array[Numeric, 2, 2]`,
+		`[1, 'a', true]`:                        `Array[ScalarData, 3, 3]`,
+		`[1, /a/]`:                              `Array[Scalar, 2, 2]`,
+		`[1, undef]`:                            `Array[Optional[Integer[1, 1]], 2, 2]`,
+		`[[1], [2, 3]]`:                         `Array[Array[Integer[1, 3], 1, 2], 2, 2]`,
+		`[1, [2]]`:                              `Array[Any, 2, 2]`,
+		`{}`:                                    `Hash[0, 0]`,
+		`{'a' => 1, 'b' => 2.0}`:                `Hash[String, Numeric, 2, 2]`,
+		`$x ? { 'a' => 1, default => 5 }`:       `Integer[1, 5]`,
+		`$x ? { 'a' => 'x', default => undef }`: `Optional[String]`,
+		`$x`:                                    `Any`,
+		`$x ? { 'a' => $y, default => 1 }`:      `Any`,
+		`Integer[1, $x]`:                        `Any`,
+	}
+	for source, expected := range tests {
+		if actual := InferType(parse(t, source)); actual != expected {
+			t.Errorf("%s: expected %s, got %s", source, expected, actual)
+		}
+	}
+	// The least Integer can't be negated
+	f := parser.DefaultFactory()
+	l := parser.NewLocator(`test.pp`, `-(-9223372036854775808)`)
+	if actual := InferType(f.Negate(f.Integer(math.MinInt64, 10, l, 1, 22), l, 0, 23)); actual != `Any` {
+		t.Errorf("expected the negation of the least Integer to be Any, got %s", actual)
+	}
+}
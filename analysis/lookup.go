@@ -0,0 +1,214 @@
+package analysis
+
+import (
+	"sort"
+
+	"github.com/lyraproj/puppet-parser/literal"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+// LookupCall is a call to the lookup function or to one of the deprecated hiera functions
+type LookupCall struct {
+	// Call is the call expression. It provides the position.
+	Call parser.Expression
+
+	// Function is the name of the called function, e.g. "lookup" or "hiera_array"
+	Function string
+
+	// Keys are the looked up keys. A lookup of an array of keys has more than one key. Keys is nil
+	// when the keys are not literal.
+	Keys []string
+
+	// ValueType is the expected type of the value, e.g. "Array[String]", or an empty string when no
+	// type is given or when the type is not a literal type reference
+	ValueType string
+
+	// Merge is the name of the merge strategy, e.g. "deep", or an empty string when no strategy is
+	// given or when the strategy is not literal
+	Merge string
+
+	// Default is the expression that produces the default value, or nil when no default is given.
+	// It's the lambda when the default is produced by a lambda.
+	Default parser.Expression
+
+	// DefaultType is the type inferred for the default value using InferType, or an empty string
+	// when no default is given
+	DefaultType string
+}
+
+// LookupFunctions are the names of the functions that perform a lookup of keys in Hiera data
+var LookupFunctions = map[string]bool{
+	`lookup`:        true,
+	`hiera`:         true,
+	`hiera_array`:   true,
+	`hiera_hash`:    true,
+	`hiera_include`: true,
+}
+
+// FindLookups returns all calls to the lookup function and the hiera functions in the tree rooted at
+// the given expression, in the order that they appear. Both function calls and method calls, e.g.
+// 'ntp::servers'.lookup, are found.
+//
+// The lookup function is recognized in all its forms, i.e. with positional arguments, with an options
+// hash, and with a lambda that produces the default value.
+func FindLookups(root parser.Expression) []*LookupCall {
+	found := make([]*LookupCall, 0)
+	visit := func(path []parser.Expression, e parser.Expression) {
+		name, args, lambda, ok := lookupCall(e)
+		if !ok {
+			return
+		}
+		lc := &LookupCall{Call: e, Function: name}
+		if name == `lookup` {
+			lc.lookupArguments(args)
+		} else {
+			if len(args) > 0 {
+				lc.Keys = literalKeys(args[0])
+			}
+			if len(args) > 1 {
+				lc.Default = args[1]
+			}
+		}
+		if lambda != nil {
+			lc.Default = lambda
+		}
+		if lc.Default != nil {
+			if l, ok := lc.Default.(*parser.LambdaExpression); ok {
+				lc.DefaultType = InferType(l.Body())
+			} else {
+				lc.DefaultType = InferType(lc.Default)
+			}
+		}
+		found = append(found, lc)
+	}
+	visit(nil, root)
+	root.AllContents(nil, visit)
+	return found
+}
+
+// LookupKeys returns the sorted and unique keys of the given lookup calls. Calls with keys that are not
+// literal are ignored.
+func LookupKeys(calls []*LookupCall) []string {
+	seen := make(map[string]bool)
+	keys := make([]string, 0)
+	for _, lc := range calls {
+		for _, key := range lc.Keys {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// lookupCall returns the name, arguments, and lambda of the given expression when it's a call to a
+// lookup function. The receiver of a method call is the first argument.
+func lookupCall(e parser.Expression) (string, []parser.Expression, parser.Expression, bool) {
+	switch e := e.(type) {
+	case *parser.CallNamedFunctionExpression:
+		if qn, ok := e.Functor().(*parser.QualifiedName); ok && LookupFunctions[qn.Name()] {
+			return qn.Name(), e.Arguments(), e.Lambda(), true
+		}
+	case *parser.CallMethodExpression:
+		if na, ok := e.Functor().(*parser.NamedAccessExpression); ok {
+			if qn, ok := na.Rhs().(*parser.QualifiedName); ok && LookupFunctions[qn.Name()] {
+				return qn.Name(), append([]parser.Expression{na.Lhs()}, e.Arguments()...), e.Lambda(), true
+			}
+		}
+	}
+	return ``, nil, nil, false
+}
+
+// lookupArguments assigns the keys, value type, merge strategy, and default value from the given
+// arguments of the lookup function
+func (lc *LookupCall) lookupArguments(args []parser.Expression) {
+	if len(args) == 0 {
+		return
+	}
+	if _, ok := args[0].(*parser.LiteralHash); ok {
+		// lookup(options_hash)
+		lc.lookupOptions(args[0])
+		return
+	}
+	lc.Keys = literalKeys(args[0])
+	if len(args) == 2 {
+		if _, ok := args[1].(*parser.LiteralHash); ok {
+			// lookup(name, options_hash)
+			lc.lookupOptions(args[1])
+			return
+		}
+	}
+	if len(args) > 1 {
+		lc.ValueType, _ = typeString(args[1])
+	}
+	if len(args) > 2 {
+		lc.Merge = mergeStrategy(args[2])
+	}
+	if len(args) > 3 {
+		lc.Default = args[3]
+	}
+}
+
+// lookupOptions assigns the keys, value type, merge strategy, and default value from the given
+// options hash
+func (lc *LookupCall) lookupOptions(options parser.Expression) {
+	for _, entry := range options.(*parser.LiteralHash).Entries() {
+		ke, ok := entry.(*parser.KeyedEntry)
+		if !ok {
+			continue
+		}
+		key, _ := literal.ToLiteral(ke.Key())
+		switch key {
+		case `name`:
+			lc.Keys = literalKeys(ke.Value())
+		case `value_type`:
+			lc.ValueType, _ = typeString(ke.Value())
+		case `merge`:
+			lc.Merge = mergeStrategy(ke.Value())
+		case `default_value`:
+			lc.Default = ke.Value()
+		}
+	}
+}
+
+// literalKeys returns the key or keys that the given expression evaluates to, or nil when they are not
+// literal strings
+func literalKeys(e parser.Expression) []string {
+	value, ok := literal.ToLiteral(e)
+	if !ok {
+		return nil
+	}
+	switch value := value.(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		keys := make([]string, len(value))
+		for i, v := range value {
+			if keys[i], ok = v.(string); !ok {
+				return nil
+			}
+		}
+		return keys
+	}
+	return nil
+}
+
+// mergeStrategy returns the name of the merge strategy that the given expression evaluates to, which
+// is either a strategy name or a hash with a strategy key, or an empty string when it isn't literal
+func mergeStrategy(e parser.Expression) string {
+	if h, ok := e.(*parser.LiteralHash); ok {
+		for _, entry := range h.Entries() {
+			if ke, ok := entry.(*parser.KeyedEntry); ok {
+				if key, _ := literal.ToLiteral(ke.Key()); key == `strategy` {
+					return mergeStrategy(ke.Value())
+				}
+			}
+		}
+		return ``
+	}
+	s, _ := literal.ToLiteral(e)
+	strategy, _ := s.(string)
+	return strategy
+}
@@ -0,0 +1,48 @@
+package analysis
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+)
+
+func TestFindLookups(t *testing.T) {
+	program := parse(t, issue.Unindent(`
+    $a = lookup('ntp::servers', Array[String], 'unique', ['pool.ntp.org'])
+    $b = lookup('ntp::enabled', { value_type => Boolean, default_value => true, merge => { strategy => 'deep' } })
+    $c = lookup(['x::a', 'x::b']) |$k| { 42 }
+    $d = 'ntp::port'.lookup(Integer)
+    $e = lookup($name)
+    $f = hiera_array('ntp::peers', [])
+    $g = lookup({ name => 'x::c' })`))
+
+	expected := []string{
+		`lookup [ntp::servers] Array[String] unique Array[String, 1, 1]`,
+		`lookup [ntp::enabled] Boolean deep Boolean`,
+		`lookup [x::a x::b]   Integer[42, 42]`,
+		`lookup [ntp::port] Integer  `,
+		`lookup []   `,
+		`hiera_array [ntp::peers]   Array[0, 0]`,
+		`lookup [x::c]   `,
+	}
+	lookups := FindLookups(program)
+	if len(lookups) != len(expected) {
+		t.Fatalf("expected %d lookups, got %d", len(expected), len(lookups))
+	}
+	for i, lc := range lookups {
+		actual := fmt.Sprintf(`%s %v %s %s %s`, lc.Function, lc.Keys, lc.ValueType, lc.Merge, lc.DefaultType)
+		if actual != expected[i] {
+			t.Errorf("expected %s, got %s", expected[i], actual)
+		}
+	}
+	if lookups[0].Call.Line() != 1 || lookups[3].Call.Line() != 4 || lookups[4].Keys != nil {
+		t.Errorf("unexpected lookups")
+	}
+
+	keys := LookupKeys(lookups)
+	if !reflect.DeepEqual(keys, []string{`ntp::enabled`, `ntp::peers`, `ntp::port`, `ntp::servers`, `x::a`, `x::b`, `x::c`}) {
+		t.Errorf("unexpected keys %v", keys)
+	}
+}
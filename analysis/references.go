@@ -0,0 +1,249 @@
+package analysis
+
+import (
+	"strings"
+
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+// ReferenceKind tells what kind of definition a Reference refers to
+type ReferenceKind string
+
+const (
+	// CLASS_REFERENCE is a reference to a class, e.g. the argument of an include call
+	CLASS_REFERENCE = ReferenceKind(`class`)
+
+	// DEFINED_TYPE_REFERENCE is a reference to a defined type, e.g. the type of a resource declaration
+	DEFINED_TYPE_REFERENCE = ReferenceKind(`defined type`)
+)
+
+// Reference is a reference by name to a class or a defined type
+type Reference struct {
+	// Expression is the expression that contains the name. It provides the position.
+	Expression parser.Expression
+
+	// Kind tells if the reference is to a class or to a defined type
+	Kind ReferenceKind
+
+	// Name is the referenced name in lower case and without a leading '::'
+	Name string
+}
+
+// BUILTIN_CLASSES are the classes that Puppet defines without a manifest
+var BUILTIN_CLASSES = map[string]bool{
+	`main`:     true,
+	`settings`: true,
+}
+
+// FindReferences returns the references to classes and defined types in the tree rooted at the given
+// expression in the order that they appear. References to classes are the literal arguments of calls
+// to include, contain, and require, the titles of class resources, the keys of Class references, and the
+// inherits clauses of classes. References to defined types are the types of resource declarations,
+// defaults, and overrides, and resource references such as Mymod::Vhost['x']. Names that aren't literal
+// are not references.
+func FindReferences(root parser.Expression) []*Reference {
+	found := make([]*Reference, 0)
+	add := func(e parser.Expression, kind ReferenceKind, name string) {
+		if name = parser.ResolveReference(name); name != `` {
+			found = append(found, &Reference{Expression: e, Kind: kind, Name: name})
+		}
+	}
+	var addClasses func(exprs []parser.Expression)
+	addClasses = func(exprs []parser.Expression) {
+		for _, e := range exprs {
+			switch e := e.(type) {
+			case *parser.QualifiedName:
+				add(e, CLASS_REFERENCE, e.Name())
+			case *parser.LiteralString:
+				add(e, CLASS_REFERENCE, e.StringValue())
+			case *parser.LiteralList:
+				addClasses(e.Elements())
+			}
+		}
+	}
+	visit := func(path []parser.Expression, e parser.Expression) {
+		switch e := e.(type) {
+		case *parser.CallNamedFunctionExpression:
+			if fn, ok := e.Functor().(*parser.QualifiedName); ok && classFunctions[fn.Name()] {
+				addClasses(e.Arguments())
+			}
+		case *parser.ResourceExpression:
+			if tn, ok := e.TypeName().(*parser.QualifiedName); ok {
+				if tn.Name() == `class` {
+					for _, b := range e.Bodies() {
+						addClasses([]parser.Expression{b.(*parser.ResourceBody).Title()})
+					}
+				} else {
+					add(tn, DEFINED_TYPE_REFERENCE, tn.Name())
+				}
+			}
+		case *parser.ResourceDefaultsExpression:
+			if qr, ok := e.TypeRef().(*parser.QualifiedReference); ok && qr.DowncasedName() != `class` {
+				add(qr, DEFINED_TYPE_REFERENCE, qr.Name())
+			}
+		case *parser.AccessExpression:
+			if qr, ok := e.Operand().(*parser.QualifiedReference); ok {
+				if qr.DowncasedName() == `class` {
+					addClasses(e.Keys())
+				} else if strings.Contains(qr.Name(), `::`) {
+					// Data types are not namespaced, so this is a resource reference
+					add(qr, DEFINED_TYPE_REFERENCE, qr.Name())
+				}
+			}
+		case *parser.HostClassDefinition:
+			if parent := e.ParentClass(); parent != `` && parent != `default` {
+				add(e, CLASS_REFERENCE, parent)
+			}
+		}
+	}
+	visit(nil, root)
+	root.AllContents(nil, visit)
+	return found
+}
+
+// classFunctions are the functions that declare the classes named by their arguments
+var classFunctions = map[string]bool{`include`: true, `contain`: true, `require`: true}
+
+// PrivateMarker is the line in the doc comment of a class or defined type that makes it private to its
+// module. It can be changed to support other conventions than the one of Puppet Strings.
+var PrivateMarker = `@api private`
+
+// DefinitionIndex is an index of the classes and defined types of a set of modules, e.g. of all modules
+// on a module path
+type DefinitionIndex struct {
+	classes      map[string]*parser.HostClassDefinition
+	definedTypes map[string]*parser.ResourceTypeDefinition
+	modules      map[parser.Definition]string
+	private      map[parser.Definition]bool
+}
+
+// NewDefinitionIndex creates an index of the classes and defined types of the given modules. The first
+// definition of a name wins when a name is defined more than once.
+func NewDefinitionIndex(modules []*Module) *DefinitionIndex {
+	ix := &DefinitionIndex{
+		make(map[string]*parser.HostClassDefinition),
+		make(map[string]*parser.ResourceTypeDefinition),
+		make(map[parser.Definition]string),
+		make(map[parser.Definition]bool)}
+	for _, m := range modules {
+		for _, p := range m.Programs {
+			for _, c := range p.Classes() {
+				if name := parser.ResolveReference(c.Name()); ix.classes[name] == nil {
+					ix.classes[name] = c
+					ix.add(m, c, c.Body())
+				}
+			}
+			for _, d := range p.DefinedTypes() {
+				if name := parser.ResolveReference(d.Name()); ix.definedTypes[name] == nil {
+					ix.definedTypes[name] = d
+					ix.add(m, d, d.Body())
+				}
+			}
+		}
+	}
+	return ix
+}
+
+func (ix *DefinitionIndex) add(m *Module, def parser.Definition, body parser.Expression) {
+	ix.modules[def] = m.Name
+	ix.private[def] = isPrivate(def, body)
+}
+
+// isPrivate returns true if the doc comment of the given definition contains the PrivateMarker or if its
+// body calls assert_private
+func isPrivate(def parser.Definition, body parser.Expression) bool {
+	for _, line := range strings.Split(DocComment(def), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), PrivateMarker) {
+			return true
+		}
+	}
+	if block, ok := body.(*parser.BlockExpression); ok {
+		for _, s := range block.Statements() {
+			if call, ok := s.(*parser.CallNamedFunctionExpression); ok {
+				if fn, ok := call.Functor().(*parser.QualifiedName); ok && fn.Name() == `assert_private` {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// Class returns the class with the given name
+func (ix *DefinitionIndex) Class(name string) (*parser.HostClassDefinition, bool) {
+	c, ok := ix.classes[parser.ResolveReference(name)]
+	return c, ok
+}
+
+// DefinedType returns the defined type with the given name
+func (ix *DefinitionIndex) DefinedType(name string) (*parser.ResourceTypeDefinition, bool) {
+	d, ok := ix.definedTypes[parser.ResolveReference(name)]
+	return d, ok
+}
+
+// Module returns the name of the module that contains the given definition, or an empty string when
+// the definition isn't in the index
+func (ix *DefinitionIndex) Module(def parser.Definition) string {
+	return ix.modules[def]
+}
+
+// IsPrivate returns true if the given definition is private to its module, i.e. if its doc comment has
+// a line that starts with the PrivateMarker or if its body calls assert_private
+func (ix *DefinitionIndex) IsPrivate(def parser.Definition) bool {
+	return ix.private[def]
+}
+
+// Resolve returns the definition that the given reference refers to. A reference to a builtin class
+// or to a resource type without a namespace, such as file or a type that a module implements in Ruby,
+// resolves to nil and true since the type cannot be found in a manifest.
+func (ix *DefinitionIndex) Resolve(ref *Reference) (parser.Definition, bool) {
+	switch ref.Kind {
+	case CLASS_REFERENCE:
+		if c, ok := ix.classes[ref.Name]; ok {
+			return c, true
+		}
+		return nil, BUILTIN_CLASSES[ref.Name]
+	default:
+		if d, ok := ix.definedTypes[ref.Name]; ok {
+			return d, true
+		}
+		return nil, !strings.Contains(ref.Name, `::`)
+	}
+}
+
+// UnresolvedReferences returns the references of the given modules to classes and defined types that
+// none of the modules define, in the order of the modules and the order that the references appear in
+// each module, see FindReferences and DefinitionIndex.Resolve. All modules that the modules depend on
+// must be given.
+func UnresolvedReferences(modules []*Module) []*Reference {
+	ix := NewDefinitionIndex(modules)
+	unresolved := make([]*Reference, 0)
+	for _, m := range modules {
+		for _, p := range m.Programs {
+			for _, ref := range FindReferences(p) {
+				if _, ok := ix.Resolve(ref); !ok {
+					unresolved = append(unresolved, ref)
+				}
+			}
+		}
+	}
+	return unresolved
+}
+
+// PrivateReferences returns the references of the given modules to classes and defined types that are
+// private to another module, see DefinitionIndex.IsPrivate, in the order of the modules and the order
+// that the references appear in each module
+func PrivateReferences(modules []*Module) []*Reference {
+	ix := NewDefinitionIndex(modules)
+	private := make([]*Reference, 0)
+	for _, m := range modules {
+		for _, p := range m.Programs {
+			for _, ref := range FindReferences(p) {
+				if def, ok := ix.Resolve(ref); ok && def != nil && ix.IsPrivate(def) && ix.Module(def) != m.Name {
+					private = append(private, ref)
+				}
+			}
+		}
+	}
+	return private
+}
@@ -0,0 +1,115 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+func TestFindReferences(t *testing.T) {
+	program := parse(t, issue.Unindent(`
+    class a::b inherits a {
+      include a::c, '::A::D'
+      contain ['a::e']
+      class { 'a::f': }
+      a::g { 'x': }
+      file { '/tmp/x': require => [Class['a::h'], A::I['y']] }
+      A::J { ensure => present }
+      include "a::${x}"
+      notice(String[1])
+    }`))
+
+	expected := []string{
+		`class a`,
+		`class a::c`,
+		`class a::d`,
+		`class a::e`,
+		`class a::f`,
+		`defined type a::g`,
+		`defined type file`,
+		`class a::h`,
+		`defined type a::i`,
+		`defined type a::j`,
+	}
+	refs := FindReferences(program)
+	if len(refs) != len(expected) {
+		t.Fatalf("expected %d references, got %d", len(expected), len(refs))
+	}
+	for i, ref := range refs {
+		if s := string(ref.Kind) + ` ` + ref.Name; s != expected[i] {
+			t.Errorf("expected %s, got %s", expected[i], s)
+		}
+	}
+}
+
+func TestUnresolvedReferences(t *testing.T) {
+	a := &Module{`a`, []*parser.Program{
+		parse(t, `class a { include a::missing, b, settings }`).(*parser.Program),
+		parse(t, `define a::vhost() { file { '/x': } mysql_user { 'x': } b::missing { 'x': } }`).(*parser.Program),
+	}}
+	b := &Module{`b`, []*parser.Program{parse(t, `class b inherits c { a::vhost { 'x': } Class['a'] -> Class['a::other'] }`).(*parser.Program)}}
+
+	refs := UnresolvedReferences([]*Module{a, b})
+	expected := []string{`class a::missing`, `defined type b::missing`, `class c`, `class a::other`}
+	if len(refs) != len(expected) {
+		t.Fatalf("expected %d unresolved references, got %d", len(expected), len(refs))
+	}
+	for i, ref := range refs {
+		if s := string(ref.Kind) + ` ` + ref.Name; s != expected[i] {
+			t.Errorf("expected %s, got %s", expected[i], s)
+		}
+	}
+	if refs[0].Expression.Line() != 1 || refs[0].Expression.Pos() != 19 {
+		t.Errorf("unexpected position %d:%d", refs[0].Expression.Line(), refs[0].Expression.Pos())
+	}
+
+	ix := NewDefinitionIndex([]*Module{a, b})
+	if c, ok := ix.Class(`::A`); !ok || c.Name() != `a` {
+		t.Errorf("expected class a")
+	}
+	if _, ok := ix.DefinedType(`A::Vhost`); !ok {
+		t.Errorf("expected defined type a::vhost")
+	}
+}
+
+func TestPrivateReferences(t *testing.T) {
+	a := &Module{`a`, []*parser.Program{parse(t, issue.Unindent(`
+    # Installs the package
+    #
+    # @api private
+    class a::install {}
+
+    define a::config() {
+      assert_private()
+    }
+
+    class a {
+      contain a::install
+      a::config { 'x': }
+    }`)).(*parser.Program)}}
+	b := &Module{`b`, []*parser.Program{parse(t, `class b { include a, a::install a::config { 'y': } }`).(*parser.Program)}}
+
+	refs := PrivateReferences([]*Module{a, b})
+	expected := []string{`class a::install`, `defined type a::config`}
+	if len(refs) != len(expected) {
+		t.Fatalf("expected %d private references, got %d", len(expected), len(refs))
+	}
+	for i, ref := range refs {
+		if s := string(ref.Kind) + ` ` + ref.Name; s != expected[i] {
+			t.Errorf("expected %s, got %s", expected[i], s)
+		}
+	}
+
+	ix := NewDefinitionIndex([]*Module{a, b})
+	c, _ := ix.Class(`a::install`)
+	if !ix.IsPrivate(c) || ix.Module(c) != `a` {
+		t.Errorf("expected a::install to be private to a")
+	}
+
+	PrivateMarker = `@private`
+	defer func() { PrivateMarker = `@api private` }()
+	if refs = PrivateReferences([]*Module{a, b}); len(refs) != 1 {
+		t.Errorf("expected 1 private reference, got %d", len(refs))
+	}
+}
@@ -0,0 +1,160 @@
+package analysis
+
+import (
+	"github.com/lyraproj/puppet-parser/literal"
+	"github.com/lyraproj/puppet-parser/parser"
+	"github.com/lyraproj/puppet-parser/printer"
+)
+
+type (
+	// DefinitionSchema describes the parameters of a class, a defined type, or a plan
+	DefinitionSchema struct {
+		// Definition is the class, defined type, or plan. It provides the position.
+		Definition parser.Expression
+
+		// Kind is "class", "define", or "plan"
+		Kind string
+
+		// Name is the name of the class, defined type, or plan
+		Name string
+
+		// Doc is the description in the comment that precedes the definition, i.e. the comment
+		// text before the first tag
+		Doc string
+
+		// Parameters are the parameters in the order that they are declared
+		Parameters []*ParameterSchema
+	}
+
+	// ParameterSchema describes a parameter of a class, a defined type, or a plan
+	ParameterSchema struct {
+		// Name is the name of the parameter without the leading '$'
+		Name string
+
+		// Type is the declared type, e.g. "Array[String]", or "Any" when no type is declared
+		Type string
+
+		// Default is the expression that produces the default value, or nil when the parameter has
+		// no default value and hence is required
+		Default parser.Expression
+
+		// Doc is the text of the @param tag for the parameter in the comment that precedes the
+		// definition, or an empty string when there is no such tag
+		Doc string
+	}
+)
+
+// ParameterSchemas returns a schema for each class, defined type, and plan in the tree rooted at the
+// given expression, in the order that they appear. The documentation of the definitions and their
+// parameters is taken from the comment lines that immediately precede each definition and that are
+// written in the format used by Puppet Strings.
+func ParameterSchemas(root parser.Expression) []*DefinitionSchema {
+	found := make([]*DefinitionSchema, 0)
+	visit := func(path []parser.Expression, e parser.Expression) {
+		var kind string
+		switch e.(type) {
+		case *parser.HostClassDefinition:
+			kind = `class`
+		case *parser.ResourceTypeDefinition:
+			kind = `define`
+		case *parser.PlanDefinition:
+			kind = `plan`
+		default:
+			return
+		}
+		nd := e.(parser.NamedDefinition)
+		doc := ParseDocString(DocComment(e))
+		ds := &DefinitionSchema{Definition: e, Kind: kind, Name: nd.Name(), Doc: doc.Text}
+		ds.Parameters = make([]*ParameterSchema, 0, len(nd.Parameters()))
+		for _, p := range nd.Parameters() {
+			param := p.(*parser.Parameter)
+			ps := &ParameterSchema{Name: param.Name(), Type: `Any`, Default: param.Value(), Doc: doc.Param(param.Name())}
+			if param.Type() != nil {
+				if s, ok := typeString(param.Type()); ok {
+					ps.Type = s
+				} else {
+					ps.Type = printer.String(param.Type())
+				}
+			}
+			ds.Parameters = append(ds.Parameters, ps)
+		}
+		found = append(found, ds)
+	}
+	visit(nil, root)
+	root.AllContents(nil, visit)
+	return found
+}
+
+// ToData returns the schema as a JSON compatible value. It's a hash with the keys "name", "kind",
+// "parameters", and "required", and with a "description" when the definition is documented. The
+// parameters are described by an array of hashes with the keys "name" and "type", a "description"
+// when the parameter is documented, and a "default" when the parameter has a literal default value
+// or a "default_expression" with the source of the default when it's not literal. The "required" key
+// is an array with the names of the parameters that have no default.
+func (s *DefinitionSchema) ToData() map[string]interface{} {
+	data := map[string]interface{}{`name`: s.Name, `kind`: s.Kind}
+	if s.Doc != `` {
+		data[`description`] = s.Doc
+	}
+	params := make([]interface{}, len(s.Parameters))
+	required := make([]interface{}, 0)
+	for i, p := range s.Parameters {
+		pd := map[string]interface{}{`name`: p.Name, `type`: p.Type}
+		if p.Doc != `` {
+			pd[`description`] = p.Doc
+		}
+		if p.Default == nil {
+			required = append(required, p.Name)
+		} else if v, ok := jsonValue(p.Default); ok {
+			pd[`default`] = v
+		} else {
+			pd[`default_expression`] = printer.String(p.Default)
+		}
+		params[i] = pd
+	}
+	data[`parameters`] = params
+	data[`required`] = required
+	return data
+}
+
+// jsonValue returns the JSON compatible value of the given expression and true, or nil and false when
+// the expression is not literal or its value has no JSON representation
+func jsonValue(e parser.Expression) (interface{}, bool) {
+	if _, ok := e.(*parser.LiteralDefault); ok {
+		return nil, false
+	}
+	v, ok := literal.ToLiteral(e)
+	if !ok {
+		return nil, false
+	}
+	return toJSON(v)
+}
+
+func toJSON(v interface{}) (interface{}, bool) {
+	switch v := v.(type) {
+	case nil, string, bool, int64, float64:
+		return v, true
+	case []interface{}:
+		a := make([]interface{}, len(v))
+		for i, e := range v {
+			var ok bool
+			if a[i], ok = toJSON(e); !ok {
+				return nil, false
+			}
+		}
+		return a, true
+	case map[interface{}]interface{}:
+		h := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			ks, ok := k.(string)
+			if !ok {
+				return nil, false
+			}
+			if h[ks], ok = toJSON(e); !ok {
+				return nil, false
+			}
+		}
+		return h, true
+	}
+	return nil, false
+}
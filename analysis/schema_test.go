@@ -0,0 +1,52 @@
+package analysis
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-parser/json"
+)
+
+func TestParameterSchemas(t *testing.T) {
+	program := parse(t, issue.Unindent(`
+    # Manages NTP
+    #
+    # @summary NTP
+    # @param servers
+    #   The servers to use,
+    #   in order of preference
+    # @param $enabled Whether the service is enabled
+    class ntp(
+      Array[String] $servers = ['pool.ntp.org'],
+      Boolean $enabled = true,
+      $config,
+      Optional[Hash[String, Integer]] $opts = { 'a' => 1 },
+      String $file = "${name}.conf",
+    ) {
+    }
+
+    define ntp::peer(Variant[String, Integer] $host) {}`))
+
+	schemas := ParameterSchemas(program)
+	if len(schemas) != 2 {
+		t.Fatalf("expected 2 schemas, got %d", len(schemas))
+	}
+	b := bytes.NewBufferString(``)
+	json.ToJson(schemas[0].ToData(), b)
+	expected := `{"description":"Manages NTP","kind":"class","name":"ntp","parameters":[` +
+		`{"default":["pool.ntp.org"],"description":"The servers to use, in order of preference","name":"servers","type":"Array[String]"},` +
+		`{"default":true,"description":"Whether the service is enabled","name":"enabled","type":"Boolean"},` +
+		`{"name":"config","type":"Any"},` +
+		`{"default":{"a":1},"name":"opts","type":"Optional[Hash[String, Integer]]"},` +
+		`{"default_expression":"\"${name}.conf\"","name":"file","type":"String"}],` +
+		`"required":["config"]}` + "\n"
+	if b.String() != expected {
+		t.Errorf("expected %s, got %s", expected, b.String())
+	}
+
+	s := schemas[1]
+	if s.Kind != `define` || s.Name != `ntp::peer` || s.Doc != `` || s.Parameters[0].Type != `Variant[String, Integer]` || s.Definition.Line() != 17 {
+		t.Errorf("unexpected schema %v", s.ToData())
+	}
+}
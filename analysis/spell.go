@@ -0,0 +1,307 @@
+package analysis
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+// SPELL_UNKNOWN_WORD is reported by CheckSpelling for a word that the dictionary doesn't contain
+const SPELL_UNKNOWN_WORD = `SPELL_UNKNOWN_WORD`
+
+func init() {
+	issue.Soft2(SPELL_UNKNOWN_WORD, `'%{word}' is not a known word%{suggestions}`, issue.HF{`suggestions`: didYouMean})
+}
+
+// didYouMean formats the suggestions of a SPELL_UNKNOWN_WORD issue
+func didYouMean(value interface{}) string {
+	suggestions, _ := value.([]string)
+	if len(suggestions) == 0 {
+		return ``
+	}
+	quoted := make([]string, len(suggestions))
+	for i, s := range suggestions {
+		quoted[i] = `'` + s + `'`
+	}
+	if len(quoted) == 1 {
+		return `. Did you mean ` + quoted[0] + `?`
+	}
+	return `. Did you mean ` + strings.Join(quoted[:len(quoted)-1], `, `) + ` or ` + quoted[len(quoted)-1] + `?`
+}
+
+// Dictionary is the set of known words that CheckSpelling checks against. Implementations can wrap a
+// spell checker, a terminology list, or both.
+type Dictionary interface {
+	// Contains returns true if the given word is known. The word is given as it's written.
+	Contains(word string) bool
+
+	// Suggest returns the known words that the given unknown word may be a misspelling of, best first
+	Suggest(word string) []string
+}
+
+// WordList is a Dictionary of a list of words. Words are matched without regard to case, so a list of
+// lower case words accepts capitalized words too.
+type WordList struct {
+	words map[string]bool
+}
+
+// NewWordList returns a word list of the given words
+func NewWordList(words ...string) *WordList {
+	wl := &WordList{words: make(map[string]bool, len(words))}
+	wl.Add(words...)
+	return wl
+}
+
+// ReadWordList reads a word list with one word per line, such as /usr/share/dict/words. Blank lines and
+// lines that start with '#' are ignored.
+func ReadWordList(r io.Reader) (*WordList, error) {
+	wl := NewWordList()
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if word := strings.TrimSpace(scanner.Text()); word != `` && !strings.HasPrefix(word, `#`) {
+			wl.Add(word)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return wl, nil
+}
+
+// Add adds the given words to the list
+func (wl *WordList) Add(words ...string) {
+	for _, word := range words {
+		wl.words[strings.ToLower(word)] = true
+	}
+}
+
+// Contains returns true if the list contains the given word
+func (wl *WordList) Contains(word string) bool {
+	return wl.words[strings.ToLower(word)]
+}
+
+// Suggest returns at most three words of the list that are at the smallest edit distance from the given
+// word, or nil when no word is similar enough
+func (wl *WordList) Suggest(word string) []string {
+	word = strings.ToLower(word)
+	limit := 1
+	if len(word) > 5 {
+		limit = 2
+	}
+	best := limit + 1
+	suggestions := make([]string, 0)
+	for w := range wl.words {
+		if d := len(w) - len(word); d > limit || d < -limit {
+			continue
+		}
+		d := editDistance(word, w)
+		switch {
+		case d < best:
+			best = d
+			suggestions = append(suggestions[:0], w)
+		case d == best:
+			suggestions = append(suggestions, w)
+		}
+	}
+	if len(suggestions) == 0 {
+		return nil
+	}
+	sort.Strings(suggestions)
+	if len(suggestions) > 3 {
+		suggestions = suggestions[:3]
+	}
+	return suggestions
+}
+
+// Word is a word of the text of a comment or a literal string. It's the location of the issues that are
+// reported for it.
+type Word struct {
+	// Text is the word as it's written in the source
+	Text string
+
+	// Offset is the byte offset of the word in the source
+	Offset int
+
+	// Comment is true when the word is in a comment and false when it's in a literal string
+	Comment bool
+
+	locator *parser.Locator
+}
+
+// String returns the word and its position
+func (w *Word) String() string {
+	return fmt.Sprintf(`%s %s`, w.Text, issue.LocationString(w))
+}
+
+func (w *Word) File() string {
+	return w.locator.File()
+}
+
+func (w *Word) Line() int {
+	return w.locator.LineForOffset(w.Offset)
+}
+
+func (w *Word) Pos() int {
+	return w.locator.PosOnLine(w.Offset)
+}
+
+// Words returns the words of the comments and the literal strings of the given program in the order
+// that they appear in the source.
+//
+// Only the literal parts of interpolated strings and heredocs are included, and only strings that contain
+// whitespace since other strings are typically names, modes, or values that aren't prose. Within the text,
+// anything that looks like a path, a URL, a file or host name, a variable, a qualified name, or code
+// between backticks is skipped, and so are words that contain digits or underscores, words written in
+// all capitals or in camel case, and single letters. The comments of EPP templates and their text outside
+// of tags are not included.
+func Words(program *parser.Program) []*Word {
+	locator := program.Locator()
+	if locator == nil || locator.IsSynthetic() {
+		return nil
+	}
+	words := make([]*Word, 0)
+	add := func(text string, offset int, comment bool) {
+		scanWords(text, func(word string, at int) {
+			words = append(words, &Word{Text: word, Offset: offset + at, Comment: comment, locator: locator})
+		})
+	}
+
+	// The body of a program that was parsed in EPP mode is a lambda
+	if _, epp := program.Body().(*parser.LambdaExpression); !epp {
+		l := parser.NewLexer(locator.File(), locator.String(), parser.LEXER_EMIT_COMMENTS, parser.LEXER_ERROR_RECOVERY)
+		for l.NextToken() != parser.TOKEN_END {
+			if l.CurrentToken() == parser.TOKEN_COMMENT {
+				add(l.TokenString(), l.TokenStartPos(), true)
+			}
+		}
+	}
+
+	program.AllContents(nil, func(path []parser.Expression, e parser.Expression) {
+		ls, ok := e.(*parser.LiteralString)
+		if !ok || ls.Locator() != locator || ls.ByteLength() == 0 || !strings.ContainsAny(ls.StringValue(), " \t\n") {
+			return
+		}
+		if len(path) > 0 {
+			if _, ok := path[len(path)-1].(*parser.RenderStringExpression); ok {
+				return
+			}
+		}
+		add(ls.String(), ls.ByteOffset(), false)
+	})
+
+	sort.SliceStable(words, func(i, j int) bool { return words[i].Offset < words[j].Offset })
+	return words
+}
+
+// CheckSpelling returns a SPELL_UNKNOWN_WORD warning for each word of the comments and the literal
+// strings of the given program that the given dictionary doesn't contain, see Words
+func CheckSpelling(program *parser.Program, dictionary Dictionary) []issue.Reported {
+	reported := make([]issue.Reported, 0)
+	for _, w := range Words(program) {
+		if !dictionary.Contains(w.Text) {
+			reported = append(reported, issue.NewReported(SPELL_UNKNOWN_WORD, issue.SEVERITY_WARNING,
+				issue.H{`word`: w.Text, `suggestions`: dictionary.Suggest(w.Text)}, w))
+		}
+	}
+	return reported
+}
+
+// scanWords calls the given function with each word of the given source text and its byte offset in the
+// text. The text is split into fields at whitespace and fields that aren't prose are skipped.
+func scanWords(text string, word func(string, int)) {
+	start := -1
+	for i := 0; i <= len(text); i++ {
+		if i < len(text) && !isSpace(text[i]) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			if field := text[start:i]; !isCode(field) {
+				scanField(field, start, word)
+			}
+			start = -1
+		}
+	}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// isCode returns true if the given field looks like a path, a URL, a name, or code rather than prose
+func isCode(field string) bool {
+	if strings.ContainsAny(field, "/_$@`=<>{}|~") || strings.Contains(field, `::`) || strings.Contains(field, `:\`) {
+		return true
+	}
+	// File names, host names, and versions, but not a period that ends a sentence
+	for i := 1; i+1 < len(field); i++ {
+		if field[i] == '.' && isAlnum(field[i-1]) && isAlnum(field[i+1]) {
+			return true
+		}
+	}
+	return false
+}
+
+func isAlnum(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= utf8.RuneSelf
+}
+
+// scanField calls the given function with each word of the given field. A backslash and the character
+// that follows it are an escape sequence that separates words. An apostrophe is part of a word when it's
+// between two letters.
+func scanField(field string, offset int, word func(string, int)) {
+	start := -1
+	emit := func(end int) {
+		if start >= 0 {
+			if w := field[start:end]; isWord(w) {
+				word(w, offset+start)
+			}
+			start = -1
+		}
+	}
+	for i := 0; i < len(field); {
+		r, sz := utf8.DecodeRuneInString(field[i:])
+		switch {
+		case r == '\\':
+			emit(i)
+			i += sz
+			if i < len(field) {
+				_, sz = utf8.DecodeRuneInString(field[i:])
+			}
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_':
+			if start < 0 {
+				start = i
+			}
+		case r == '\'' && start >= 0 && i+sz < len(field):
+			if next, _ := utf8.DecodeRuneInString(field[i+sz:]); !unicode.IsLetter(next) {
+				emit(i)
+			}
+		default:
+			emit(i)
+		}
+		i += sz
+	}
+	emit(len(field))
+}
+
+// isWord returns true if the given run of letters and digits should be spell checked
+func isWord(w string) bool {
+	if utf8.RuneCountInString(w) < 2 {
+		return false
+	}
+	for i, r := range w {
+		if unicode.IsDigit(r) || r == '_' || i > 0 && unicode.IsUpper(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,116 @@
+package analysis
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+func TestWords(t *testing.T) {
+	program := parse(t, issue.Unindent(`
+    # Manages the ntp servce, see https://ntp.org and /etc/ntp.conf
+    class ntp(String $servers) {
+      /* Block comment
+         spans lines */
+      notice("Configured ${servers} as the timeserver\n")
+      $mode = 'ntp_mode'
+      $motd = @(END)
+        Don't edit this file, it's managed by Puppet
+        | END
+      warning('Uses NTPv4 and x86 and CamelCase and `+"`ntp::config`"+` of ntp.conf')
+    }`)).(*parser.Program)
+
+	texts := make([]string, 0)
+	for _, w := range Words(program) {
+		texts = append(texts, w.String())
+	}
+	expected := []string{
+		`Manages (file: test.pp, line: 1, column: 3)`,
+		`the (file: test.pp, line: 1, column: 11)`,
+		`ntp (file: test.pp, line: 1, column: 15)`,
+		`servce (file: test.pp, line: 1, column: 19)`,
+		`see (file: test.pp, line: 1, column: 27)`,
+		`and (file: test.pp, line: 1, column: 47)`,
+		`Block (file: test.pp, line: 3, column: 6)`,
+		`comment (file: test.pp, line: 3, column: 12)`,
+		`spans (file: test.pp, line: 4, column: 6)`,
+		`lines (file: test.pp, line: 4, column: 12)`,
+		`Configured (file: test.pp, line: 5, column: 11)`,
+		`as (file: test.pp, line: 5, column: 33)`,
+		`the (file: test.pp, line: 5, column: 36)`,
+		`timeserver (file: test.pp, line: 5, column: 40)`,
+		`Don't (file: test.pp, line: 8, column: 5)`,
+		`edit (file: test.pp, line: 8, column: 11)`,
+		`this (file: test.pp, line: 8, column: 16)`,
+		`file (file: test.pp, line: 8, column: 21)`,
+		`it's (file: test.pp, line: 8, column: 27)`,
+		`managed (file: test.pp, line: 8, column: 32)`,
+		`by (file: test.pp, line: 8, column: 40)`,
+		`Puppet (file: test.pp, line: 8, column: 43)`,
+		`Uses (file: test.pp, line: 10, column: 12)`,
+		`and (file: test.pp, line: 10, column: 23)`,
+		`and (file: test.pp, line: 10, column: 31)`,
+		`and (file: test.pp, line: 10, column: 45)`,
+		`of (file: test.pp, line: 10, column: 63)`,
+	}
+	if actual := strings.Join(texts, "\n"); actual != strings.Join(expected, "\n") {
+		t.Errorf("expected\n%s\ngot\n%s", strings.Join(expected, "\n"), actual)
+	}
+}
+
+func TestWordsOfTemplate(t *testing.T) {
+	template, err := parser.CreateParser(parser.PARSER_EPP_MODE).Parse(`motd.epp`,
+		"<%# a coment %>Welcom to <%= $facts['fqdn'] %>\n<% notice('Rendring the motd') %>", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	texts := make([]string, 0)
+	for _, w := range Words(template.(*parser.Program)) {
+		texts = append(texts, w.Text)
+	}
+	if actual := strings.Join(texts, ` `); actual != `Rendring the motd` {
+		t.Errorf(`expected 'Rendring the motd', got '%s'`, actual)
+	}
+}
+
+func TestCheckSpelling(t *testing.T) {
+	program := parse(t, issue.Unindent(`
+    # Installs the pakage
+    notice('Instals the package')`)).(*parser.Program)
+
+	dictionary := NewWordList(`installs`, `the`, `package`, `instant`)
+	reported := CheckSpelling(program, dictionary)
+	expected := []string{
+		`'pakage' is not a known word. Did you mean 'package'? (file: test.pp, line: 1, column: 16)`,
+		`'Instals' is not a known word. Did you mean 'installs'? (file: test.pp, line: 2, column: 9)`,
+	}
+	if len(reported) != len(expected) {
+		t.Fatalf(`expected %d issues, got %d`, len(expected), len(reported))
+	}
+	for i, ri := range reported {
+		if ri.Code() != SPELL_UNKNOWN_WORD || ri.Severity() != issue.SEVERITY_WARNING {
+			t.Errorf(`unexpected issue %s`, ri.Error())
+		}
+		if actual := ri.Error(); actual != expected[i] {
+			t.Errorf(`expected '%s', got '%s'`, expected[i], actual)
+		}
+	}
+}
+
+func TestWordList(t *testing.T) {
+	wl, err := ReadWordList(strings.NewReader("# words\nservice\n\nserve\nServer\nsurface\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !wl.Contains(`Service`) || !wl.Contains(`server`) || wl.Contains(`servce`) {
+		t.Error(`expected the word list to match words without regard to case`)
+	}
+	if actual := strings.Join(wl.Suggest(`servce`), ` `); actual != `serve service` {
+		t.Errorf(`expected 'serve service', got '%s'`, actual)
+	}
+	if suggestions := wl.Suggest(`xyzzy`); suggestions != nil {
+		t.Errorf(`expected no suggestions, got %v`, suggestions)
+	}
+}
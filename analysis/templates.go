@@ -0,0 +1,214 @@
+package analysis
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+// TemplateMismatchKind tells how the arguments of an epp call fail to match the parameters of the template
+type TemplateMismatchKind string
+
+const (
+	// MISSING_TEMPLATE_PARAMETER is a required parameter of the template that the call doesn't give
+	MISSING_TEMPLATE_PARAMETER = TemplateMismatchKind(`missing`)
+
+	// EXTRA_TEMPLATE_PARAMETER is an argument of the call that the template doesn't declare
+	EXTRA_TEMPLATE_PARAMETER = TemplateMismatchKind(`extra`)
+
+	// MISNAMED_TEMPLATE_PARAMETER is an argument of the call that the template doesn't declare but that
+	// is close to the name of a parameter that the call doesn't give, e.g. "sever" for "server"
+	MISNAMED_TEMPLATE_PARAMETER = TemplateMismatchKind(`misnamed`)
+)
+
+// TemplateMismatch is a difference between the arguments that an epp call passes to a template and the
+// parameters that the template declares
+type TemplateMismatch struct {
+	// Call is the epp call
+	Call *parser.CallNamedFunctionExpression
+
+	// Expression is the key of the argument hash for an extra or a misnamed argument, and the call for
+	// a missing parameter. It provides the position.
+	Expression parser.Expression
+
+	// Template is the name of the template, e.g. "mymod/config.epp"
+	Template string
+
+	// Kind tells if the parameter is missing, extra, or misnamed
+	Kind TemplateMismatchKind
+
+	// Name is the name of the missing parameter or of the extra or misnamed argument
+	Name string
+
+	// Suggestion is the name of the parameter that a misnamed argument probably was meant to be
+	Suggestion string
+}
+
+// TemplateName returns the name that the given program is loaded by with the epp function, e.g.
+// "mymod/config.epp" for the file "mymod/templates/config.epp" of the module mymod, and true. It returns
+// false when the file of the program isn't in the templates directory of a module.
+func TemplateName(module *Module, program *parser.Program) (string, bool) {
+	path := filepath.ToSlash(program.File())
+	i := strings.LastIndex(`/`+path, `/templates/`)
+	if i < 0 || !strings.HasSuffix(path, `.epp`) {
+		return ``, false
+	}
+	return module.Name + `/` + path[i+len(`templates/`):], true
+}
+
+// TemplateParameters returns the parameters that the given template declares and true, or nil and false
+// when the template has no parameter declaration and hence accepts any arguments
+func TemplateParameters(template *parser.Program) ([]*parser.Parameter, bool) {
+	lambda, ok := template.Body().(*parser.LambdaExpression)
+	if !ok {
+		return nil, false
+	}
+	if epp, ok := lambda.Body().(*parser.EppExpression); !ok || !epp.ParametersSpecified() {
+		return nil, false
+	}
+	params := make([]*parser.Parameter, len(lambda.Parameters()))
+	for i, p := range lambda.Parameters() {
+		params[i] = p.(*parser.Parameter)
+	}
+	return params, true
+}
+
+// TemplateMismatches returns the differences between the arguments of the epp calls of the given modules
+// and the parameters of the templates that they render, in the order of the modules and of the calls.
+// Only calls with a literal template name and a literal hash of arguments, or no arguments, are checked,
+// and only against templates that declare their parameters. The templates are the programs of the
+// modules that were parsed in EPP mode from the templates directory of a module, see TemplateName.
+func TemplateMismatches(modules []*Module) []*TemplateMismatch {
+	templates := make(map[string]*parser.Program)
+	for _, m := range modules {
+		for _, p := range m.Programs {
+			if name, ok := TemplateName(m, p); ok {
+				templates[name] = p
+			}
+		}
+	}
+
+	mismatches := make([]*TemplateMismatch, 0)
+	for _, m := range modules {
+		for _, p := range m.Programs {
+			p.AllContents(nil, func(path []parser.Expression, e parser.Expression) {
+				call, ok := e.(*parser.CallNamedFunctionExpression)
+				if !ok {
+					return
+				}
+				if fn, ok := call.Functor().(*parser.QualifiedName); !ok || fn.Name() != `epp` || len(call.Arguments()) == 0 {
+					return
+				}
+				ls, ok := call.Arguments()[0].(*parser.LiteralString)
+				if !ok {
+					return
+				}
+				if template, ok := templates[ls.StringValue()]; ok {
+					if params, ok := TemplateParameters(template); ok {
+						mismatches = append(mismatches, templateMismatches(call, ls.StringValue(), params)...)
+					}
+				}
+			})
+		}
+	}
+	return mismatches
+}
+
+func templateMismatches(call *parser.CallNamedFunctionExpression, template string, params []*parser.Parameter) []*TemplateMismatch {
+	args := make(map[string]bool)
+	keys := make([]parser.Expression, 0)
+	if len(call.Arguments()) > 1 {
+		hash, ok := call.Arguments()[1].(*parser.LiteralHash)
+		if !ok {
+			return nil
+		}
+		for _, entry := range hash.Entries() {
+			key := entry.(*parser.KeyedEntry).Key()
+			name, ok := keyName(key)
+			if !ok {
+				return nil
+			}
+			args[name] = true
+			keys = append(keys, key)
+		}
+	}
+
+	declared := make(map[string]bool, len(params))
+	missing := make([]string, 0)
+	for _, p := range params {
+		declared[p.Name()] = true
+		if p.Value() == nil && !args[p.Name()] {
+			missing = append(missing, p.Name())
+		}
+	}
+
+	mismatches := make([]*TemplateMismatch, 0)
+	suggested := make(map[string]bool)
+	for _, key := range keys {
+		name, _ := keyName(key)
+		if declared[name] {
+			continue
+		}
+		mm := &TemplateMismatch{Call: call, Expression: key, Template: template, Kind: EXTRA_TEMPLATE_PARAMETER, Name: name}
+		for _, p := range params {
+			if !args[p.Name()] && !suggested[p.Name()] && similarNames(name, p.Name()) {
+				mm.Kind = MISNAMED_TEMPLATE_PARAMETER
+				mm.Suggestion = p.Name()
+				suggested[p.Name()] = true
+				break
+			}
+		}
+		mismatches = append(mismatches, mm)
+	}
+	for _, name := range missing {
+		if !suggested[name] {
+			mismatches = append(mismatches, &TemplateMismatch{Call: call, Expression: call, Template: template, Kind: MISSING_TEMPLATE_PARAMETER, Name: name})
+		}
+	}
+	return mismatches
+}
+
+// keyName returns the name of the given literal hash key
+func keyName(key parser.Expression) (string, bool) {
+	switch key := key.(type) {
+	case *parser.LiteralString:
+		return key.StringValue(), true
+	case *parser.QualifiedName:
+		return key.Name(), true
+	}
+	return ``, false
+}
+
+// similarNames returns true if the given names differ only in case, or by at most one edit for short
+// names and two edits for names that are longer than five characters
+func similarNames(a, b string) bool {
+	a, b = strings.ToLower(a), strings.ToLower(b)
+	limit := 1
+	if len(b) > 5 {
+		limit = 2
+	}
+	return editDistance(a, b) <= limit
+}
+
+// editDistance returns the Levenshtein distance between the given strings
+func editDistance(a, b string) int {
+	ar, br := []rune(a), []rune(b)
+	prev := make([]int, len(br)+1)
+	curr := make([]int, len(br)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ar); i++ {
+		curr[0] = i
+		for j := 1; j <= len(br); j++ {
+			cost := 1
+			if ar[i-1] == br[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(br)]
+}
@@ -0,0 +1,51 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+func TestTemplateMismatches(t *testing.T) {
+	template, err := parser.CreateParser(parser.PARSER_EPP_MODE).Parse(`ntp/templates/ntp.conf.epp`,
+		"<%- | Array[String] $servers, String $driftfile, Boolean $iburst = true | -%>\n<%= $servers %>", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	untyped, err := parser.CreateParser(parser.PARSER_EPP_MODE).Parse(`ntp/templates/free.epp`, `<%= $x %>`, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest := parse(t, issue.Unindent(`
+    class ntp {
+      $a = epp('ntp/ntp.conf.epp', { 'servers' => [], 'driftfile' => '/d' })
+      $b = epp('ntp/ntp.conf.epp', { 'severs' => [], driftfile => '/d', 'extra' => 1 })
+      $c = epp('ntp/ntp.conf.epp')
+      $d = epp('ntp/ntp.conf.epp', $params)
+      $e = epp('ntp/free.epp', { 'y' => 1 })
+      $f = epp('other/x.epp', { 'y' => 1 })
+    }`)).(*parser.Program)
+
+	mismatches := TemplateMismatches([]*Module{{Name: `ntp`, Programs: []*parser.Program{manifest, template.(*parser.Program), untyped.(*parser.Program)}}})
+	expected := []struct {
+		kind       TemplateMismatchKind
+		name       string
+		suggestion string
+		line       int
+	}{
+		{MISNAMED_TEMPLATE_PARAMETER, `severs`, `servers`, 3},
+		{EXTRA_TEMPLATE_PARAMETER, `extra`, ``, 3},
+		{MISSING_TEMPLATE_PARAMETER, `servers`, ``, 4},
+		{MISSING_TEMPLATE_PARAMETER, `driftfile`, ``, 4},
+	}
+	if len(mismatches) != len(expected) {
+		t.Fatalf("expected %d mismatches, got %d", len(expected), len(mismatches))
+	}
+	for i, e := range expected {
+		m := mismatches[i]
+		if m.Kind != e.kind || m.Name != e.name || m.Suggestion != e.suggestion || m.Expression.Line() != e.line || m.Template != `ntp/ntp.conf.epp` {
+			t.Errorf("unexpected mismatch %d: %s %s %s at line %d", i, m.Kind, m.Name, m.Suggestion, m.Expression.Line())
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package ast_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lyraproj/puppet-parser/ast"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+func TestAliases(t *testing.T) {
+	expr, err := parser.CreateParser().Parse(``, "file { '/tmp/a': }\n@user { 'bob': }", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resources := ast.FindAll[*ast.ResourceExpression](expr)
+	if len(resources) != 2 || resources[0].Form() != ast.REGULAR || resources[1].Form() != ast.VIRTUAL {
+		t.Errorf(`expected a regular and a virtual resource`)
+	}
+	if _, ok := ast.First[*parser.ResourceExpression](ast.Expression(expr)); !ok {
+		t.Errorf(`expected ast and parser types to be interchangeable`)
+	}
+}
+
+func TestAssignedVariables(t *testing.T) {
+	expr, err := parser.CreateParser().Parse(``, `[$a, [$b, $c]] = [1, [2, 3]]`, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0)
+	for _, v := range expr.(*ast.AssignmentExpression).AssignedVariables() {
+		name, _ := v.Name()
+		names = append(names, name)
+	}
+	if strings.Join(names, ` `) != `a b c` {
+		t.Errorf("expected the variables a b c, got %v", names)
+	}
+}
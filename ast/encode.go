@@ -0,0 +1,16 @@
+package ast
+
+import (
+	"io"
+
+	"github.com/lyraproj/puppet-parser/pn"
+)
+
+// EncodePN writes the PN representation of the given expression as JSON to the given writer. The
+// output is the same as when encoding the result of calling ToData on the PN but the JSON is
+// streamed to the writer while the expression tree is walked. The PN of each contained expression
+// is produced when the encoder reaches it and is dropped once it has been written, so the PN of
+// the whole tree is never built.
+func EncodePN(w io.Writer, e Expression, opts ...pn.Option) error {
+	return pn.EncodeJSON(w, e.ToPN(), opts...)
+}
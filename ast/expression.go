@@ -1,8 +1,23 @@
-package parser
+// Package ast declares the abstract syntax tree that the parser package produces: the expression types,
+// the locators that map them to their source, and the functions that find, walk, visit, and transform
+// expressions. The parser package declares aliases of the types, so an ast.Expression is a
+// parser.Expression and code that uses either import path can be mixed freely.
+//
+// A typical client parses with the parser package and inspects the result with this package:
+//
+//	expr, err := parser.CreateParser().Parse(`site.pp`, source, false)
+//	if err == nil {
+//	  for _, r := range ast.FindAll[*ast.ResourceExpression](expr) {
+//	    ...
+//	  }
+//	}
+package ast
 
 import (
+	"fmt"
 	"sort"
 	"strings"
+	"sync"
 	"unicode/utf8"
 
 	"github.com/lyraproj/issue/issue"
@@ -63,11 +78,29 @@ type (
 
 		ByteLength() int
 
+		// Returns the byte offset of this expression in the source, or in the host document when
+		// the source is a snippet of a larger document
 		ByteOffset() int
 
 		Locator() *Locator
 
+		// Annotate associates the given value with the given key in this expression. An existing
+		// value for the key is replaced. A nil value removes the annotation. The key must be a valid
+		// PN key since annotations can be included in the PN output using the pn.WITH_ANNOTATIONS
+		// option.
+		Annotate(key string, value interface{})
+
+		// Annotation returns the value of the annotation with the given key and true, or nil and
+		// false when no such annotation exists
+		Annotation(key string) (interface{}, bool)
+
+		// AnnotationKeys returns the keys of all annotations in the order they were first added
+		AnnotationKeys() []string
+
 		updateOffsetAndLength(offset int, length int)
+
+		// Returns the byte offset of this expression in the source that it was parsed from
+		byteOffset() int
 	}
 
 	ResourceForm string
@@ -256,8 +289,9 @@ type (
 
 	HeredocExpression struct {
 		Positioned
-		syntax string
-		text   Expression
+		syntax   string
+		text     Expression
+		template Expression
 	}
 
 	HostClassDefinition struct {
@@ -272,6 +306,13 @@ type (
 		elseExpr Expression
 	}
 
+	// ImportExpression is a legacy Puppet 3 import statement. It is produced only when the
+	// PARSER_IMPORT_COMPAT option is given to the parser.
+	ImportExpression struct {
+		Positioned
+		patterns []Expression
+	}
+
 	InExpression struct {
 		binaryExpression
 	}
@@ -325,9 +366,32 @@ type (
 	}
 
 	Locator struct {
-		string    string
-		file      string
-		lineIndex []int
+		string string
+		file   string
+
+		// The line index is computed when first needed. The once makes that safe when the expressions
+		// of the locator are read by several goroutines.
+		lineIndex     []int
+		lineIndexOnce sync.Once
+
+		// The source when the locator was created for a Source that hasn't been flattened yet. The lock
+		// guards the flattening.
+		source Source
+		lock   sync.Mutex
+
+		// Position of the source in a host document when the source is a snippet of that document
+		line   int
+		column int
+		offset int
+
+		// The number of bytes that were stripped from the start of each line of the source and of the
+		// lines before it, when the source is the text of a heredoc with a margin
+		margins []int
+
+		// Description and origin of synthetic expressions, i.e. expressions that were created by a tool
+		// rather than parsed from a source
+		provenance string
+		origin     Expression
 	}
 
 	MatchExpression struct {
@@ -537,9 +601,15 @@ type (
 	}
 
 	Positioned struct {
-		locator *Locator
-		offset  int
-		length  int
+		locator     *Locator
+		offset      int
+		length      int
+		annotations []annotation
+	}
+
+	annotation struct {
+		key   string
+		value interface{}
 	}
 
 	queryExpression struct {
@@ -563,35 +633,193 @@ func NewLocator(file, content string) *Locator {
 	return &Locator{string: content, file: file}
 }
 
+// NewSourceLocator creates a locator for the given source. The source is flattened into one string only
+// when String is called. The String of a located expression is read from the source.
+func NewSourceLocator(file string, source Source) *Locator {
+	if s, ok := source.(stringSource); ok {
+		return NewLocator(file, string(s))
+	}
+	return &Locator{file: file, source: source}
+}
+
+// NewSnippetLocator creates a locator for a source that is a snippet of a larger host document.
+// The snippet starts at the given line and column (both 1-based) and byte offset in the host document.
+// Lines, positions on lines, and byte offsets of expressions located by the created locator will be
+// relative to the host document.
+func NewSnippetLocator(file, content string, line, column, offset int) *Locator {
+	if line < 1 || column < 1 || offset < 0 {
+		panic(fmt.Sprintf(`Invalid snippet start %d:%d offset %d`, line, column, offset))
+	}
+	return &Locator{string: content, file: file, line: line - 1, column: column - 1, offset: offset}
+}
+
+// NewHeredocLocator creates a locator for the text of a heredoc that is parsed on its own. The text
+// starts at the given line (0-based) and byte offset in the host document. The margins are the number
+// of bytes that were stripped from the start of each line of the text and of the lines before it, or
+// nil when no margin was stripped.
+func NewHeredocLocator(file, text string, line, offset int, margins []int) *Locator {
+	return &Locator{string: text, file: file, line: line, offset: offset, margins: margins}
+}
+
+// NewSyntheticLocator creates a locator for expressions that are created by a tool using a Factory
+// rather than parsed from a source. The label describes what the expressions were generated from,
+// e.g. "template 'motd.epp'". The origin is the expression that they were generated from, or nil.
+//
+// The source of a synthetic locator is empty. The file, line, and position reported for located
+// expressions are those of the origin. Without an origin, the file is the Provenance and the line
+// and position are zero so that issues are reported as "(file: generated from template 'motd.epp')"
+// rather than with a position in an empty file.
+func NewSyntheticLocator(label string, origin Expression) *Locator {
+	return &Locator{file: `generated from ` + label, provenance: `generated from ` + label, origin: origin}
+}
+
 func (e *Locator) String() string {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if e.source != nil {
+		e.string = SourceText(e.source)
+		e.source = nil
+	}
 	return e.string
 }
 
+// Text returns the flattened source, or the source when it hasn't been flattened yet
+func (e *Locator) Text() (string, Source) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return e.string, e.source
+}
+
+// Len returns the length of the source in bytes
+func (e *Locator) Len() int {
+	s, source := e.Text()
+	if source != nil {
+		return source.Len()
+	}
+	return len(s)
+}
+
+// Slice returns the source text between the given offsets. The text is empty when the end offset isn't
+// after the start offset, as is the case for the empty text of a heredoc with a '|-' end marker
+func (e *Locator) Slice(start, end int) string {
+	s, source := e.Text()
+	if source != nil {
+		return SourceSlice(source, start, end)
+	}
+	if start >= end {
+		return ``
+	}
+	return s[start:end]
+}
+
 func (e *Locator) File() string {
+	if e.origin != nil {
+		return e.origin.File()
+	}
 	return e.file
 }
 
-// Return the line in the source for the given byte offset
+// IsSynthetic returns true if this locator was created using NewSyntheticLocator
+func (e *Locator) IsSynthetic() bool {
+	return e.provenance != ``
+}
+
+// Provenance returns "generated from <label>" when this locator is synthetic, or an empty string
+// when it is not
+func (e *Locator) Provenance() string {
+	return e.provenance
+}
+
+// Origin returns the expression that the expressions of a synthetic locator were generated from, or
+// nil when the locator is not synthetic or has no origin
+func (e *Locator) Origin() Expression {
+	return e.origin
+}
+
+// Return the line in the source for the given byte offset. The line is relative to the
+// host document when the source is a snippet.
 func (e *Locator) LineForOffset(offset int) int {
-	return sort.SearchInts(e.getLineIndex(), offset+1)
+	if e.IsSynthetic() {
+		if e.origin != nil {
+			return e.origin.Line()
+		}
+		return 0
+	}
+	return sort.SearchInts(e.getLineIndex(), offset+1) + e.line
 }
 
-// Return the position on a line in the source for the given byte offset
+// Return the position on a line in the source for the given byte offset. The position is
+// relative to the host document when the source is a snippet.
 func (e *Locator) PosOnLine(offset int) int {
-	return e.offsetOnLine(offset) + 1
+	if e.IsSynthetic() {
+		if e.origin != nil {
+			return e.origin.Pos()
+		}
+		return 0
+	}
+	pos := e.offsetOnLine(offset) + 1
+	if offset < e.firstLineEnd() {
+		pos += e.column
+	}
+	if e.margins != nil {
+		line := e.lineIndexForOffset(offset)
+		pos += e.margins[line]
+		if line > 0 {
+			pos -= e.margins[line-1]
+		}
+	}
+	return pos
+}
+
+// Return the byte offset in the host document for the given byte offset in the source
+func (e *Locator) HostOffset(offset int) int {
+	if e == nil {
+		return offset
+	}
+	if e.origin != nil {
+		return e.origin.ByteOffset()
+	}
+	if e.margins != nil {
+		offset += e.margins[e.lineIndexForOffset(offset)]
+	}
+	return offset + e.offset
+}
+
+// lineIndexForOffset returns the zero based index of the line of the given offset in the source
+func (e *Locator) lineIndexForOffset(offset int) int {
+	line := sort.SearchInts(e.getLineIndex(), offset+1) - 1
+	if line >= len(e.margins) {
+		line = len(e.margins) - 1
+	}
+	return line
+}
+
+func (e *Locator) firstLineEnd() int {
+	li := e.getLineIndex()
+	if len(li) > 1 {
+		return li[1]
+	}
+	return e.Len() + 1
 }
 
 func (e *Locator) getLineIndex() []int {
-	if e.lineIndex == nil {
+	e.lineIndexOnce.Do(func() {
 		li := append(make([]int, 0, 32), 0)
-		rdr := NewStringReader(e.string)
-		for c, _ := rdr.Next(); c != 0; c, _ = rdr.Next() {
-			if c == '\n' {
-				li = append(li, rdr.Pos())
+		s, source := e.Text()
+		if source == nil {
+			source = stringSource(s)
+		}
+		for pos := 0; pos < source.Len(); {
+			start, chunk := source.Chunk(pos)
+			for i := pos - start; i < len(chunk); i++ {
+				if chunk[i] == '\n' {
+					li = append(li, start+i+1)
+				}
 			}
+			pos = start + len(chunk)
 		}
 		e.lineIndex = li
-	}
+	})
 	return e.lineIndex
 }
 
@@ -602,10 +830,10 @@ func (e *Locator) offsetOnLine(offset int) int {
 	if offset == lineStart {
 		return 0
 	}
-	if offset > len(e.string) {
-		offset = len(e.string)
+	if offset > e.Len() {
+		offset = e.Len()
 	}
-	return utf8.RuneCountInString(e.string[lineStart:offset])
+	return utf8.RuneCountInString(e.Slice(lineStart, offset))
 }
 
 func (e *Positioned) Init(locator *Locator, offset, len int) {
@@ -615,7 +843,7 @@ func (e *Positioned) Init(locator *Locator, offset, len int) {
 }
 
 func (e *Positioned) String() string {
-	return e.locator.String()[e.offset : e.offset+e.length]
+	return e.locator.Slice(e.offset, e.offset+e.length)
 }
 
 func (e *Positioned) File() string {
@@ -637,6 +865,10 @@ func (e *Positioned) ByteLength() int {
 }
 
 func (e *Positioned) ByteOffset() int {
+	return e.locator.HostOffset(e.offset)
+}
+
+func (e *Positioned) byteOffset() int {
 	return e.offset
 }
 
@@ -653,6 +885,73 @@ func (e *Positioned) updateOffsetAndLength(offset int, length int) {
 	e.length = length
 }
 
+// SourceOffset returns the byte offset of the given expression in the source of its locator. It differs
+// from the ByteOffset when the source is a snippet of a host document.
+func SourceOffset(e Expression) int {
+	return e.byteOffset()
+}
+
+// Range is a range in the source of a locator
+type Range struct {
+	// Offset and Length are the byte offset and the length in bytes of the range in the source of the
+	// locator
+	Offset, Length int
+
+	// Line and Column is the start of the range and EndLine and EndColumn the position just after its
+	// end. All are 1-based and relative to the host document when the source is a snippet.
+	Line, Column, EndLine, EndColumn int
+}
+
+// RangeOf returns the range of the given expression in the source of its locator
+func RangeOf(e Expression) Range {
+	l := e.Locator()
+	start := e.byteOffset()
+	end := start + e.ByteLength()
+	return Range{start, end - start, e.Line(), e.Pos(), l.LineForOffset(end), l.PosOnLine(end)}
+}
+
+// UpdateOffsetAndLength changes the range of the source of the given expression, e.g. when the parser
+// finds that a number starts at a preceding sign
+func UpdateOffsetAndLength(e Expression, offset int, length int) {
+	e.updateOffsetAndLength(offset, length)
+}
+
+func (e *Positioned) Annotate(key string, value interface{}) {
+	if !pn.ValidKey(key) {
+		panic(fmt.Sprintf(`Invalid annotation key '%s'`, key))
+	}
+	for idx, a := range e.annotations {
+		if a.key == key {
+			if value == nil {
+				e.annotations = append(e.annotations[:idx], e.annotations[idx+1:]...)
+			} else {
+				e.annotations[idx].value = value
+			}
+			return
+		}
+	}
+	if value != nil {
+		e.annotations = append(e.annotations, annotation{key, value})
+	}
+}
+
+func (e *Positioned) Annotation(key string) (interface{}, bool) {
+	for _, a := range e.annotations {
+		if a.key == key {
+			return a.value, true
+		}
+	}
+	return nil, false
+}
+
+func (e *Positioned) AnnotationKeys() []string {
+	keys := make([]string, len(e.annotations))
+	for idx, a := range e.annotations {
+		keys[idx] = a.key
+	}
+	return keys
+}
+
 func DeepVisit(e Expression, path []Expression, visitor PathVisitor, children ...interface{}) {
 	if len(children) == 0 {
 		return
@@ -757,6 +1056,26 @@ func (e *AssignmentExpression) Operator() string {
 	return e.operator
 }
 
+// AssignedVariables returns the variables that the assignment assigns, i.e. its left hand side when that
+// is a variable, or the variables of the list on its left hand side, including those in nested lists
+func (e *AssignmentExpression) AssignedVariables() []*VariableExpression {
+	return assignedVariables(e.Lhs())
+}
+
+func assignedVariables(lhs Expression) []*VariableExpression {
+	switch lhs := lhs.(type) {
+	case *VariableExpression:
+		return []*VariableExpression{lhs}
+	case *LiteralList:
+		vars := make([]*VariableExpression, 0, len(lhs.Elements()))
+		for _, elem := range lhs.Elements() {
+			vars = append(vars, assignedVariables(elem)...)
+		}
+		return vars
+	}
+	return nil
+}
+
 func (e *AssignmentExpression) AllContents(path []Expression, visitor PathVisitor) {
 	DeepVisit(e, path, visitor, e.lhs, e.rhs)
 }
@@ -788,7 +1107,7 @@ func (e *AttributeOperation) Contents(path []Expression, visitor PathVisitor) {
 }
 
 func (e *AttributeOperation) ToPN() pn.PN {
-	return pn.Call(e.Operator(), pn.Literal(e.Name()), e.Value().ToPN())
+	return pn.Call(e.Operator(), pn.Literal(e.Name()), pnOf(e.Value()))
 }
 
 func (e *AttributesOperation) Expr() Expression {
@@ -831,6 +1150,12 @@ func (e *callExpression) RvalRequired() bool {
 	return e.rvalRequired
 }
 
+// SetRvalRequired sets whether the call must produce a value. The parser uses it when it finds out
+// whether a call without parentheses is a statement or the value of one.
+func (e *callExpression) SetRvalRequired(rvalRequired bool) {
+	e.rvalRequired = rvalRequired
+}
+
 func (e *callExpression) Functor() Expression {
 	return e.functor
 }
@@ -856,9 +1181,9 @@ func (e *CallFunctionExpression) ToPN() pn.PN {
 	if e.RvalRequired() {
 		s = `call-lambda`
 	}
-	entries := []pn.Entry{e.Functor().ToPN().WithName(`functor`), pnList(e.Arguments()).WithName(`args`)}
+	entries := []pn.Entry{pnOf(e.Functor()).WithName(`functor`), pnList(e.Arguments()).WithName(`args`)}
 	if e.Lambda() != nil {
-		entries = append(entries, e.Lambda().ToPN().WithName(`block`))
+		entries = append(entries, pnOf(e.Lambda()).WithName(`block`))
 	}
 	return pn.Map(entries).AsCall(s)
 }
@@ -876,9 +1201,9 @@ func (e *CallMethodExpression) ToPN() pn.PN {
 	if e.RvalRequired() {
 		s = `call-method`
 	}
-	entries := []pn.Entry{e.Functor().ToPN().WithName(`functor`), pnList(e.Arguments()).WithName(`args`)}
+	entries := []pn.Entry{pnOf(e.Functor()).WithName(`functor`), pnList(e.Arguments()).WithName(`args`)}
 	if e.Lambda() != nil {
-		entries = append(entries, e.Lambda().ToPN().WithName(`block`))
+		entries = append(entries, pnOf(e.Lambda()).WithName(`block`))
 	}
 	return pn.Map(entries).AsCall(s)
 }
@@ -903,9 +1228,9 @@ func (e *CallNamedFunctionExpression) ToPN() pn.PN {
 	if e.RvalRequired() {
 		s = `call`
 	}
-	entries := []pn.Entry{e.Functor().ToPN().WithName(`functor`), pnList(e.Arguments()).WithName(`args`)}
+	entries := []pn.Entry{pnOf(e.Functor()).WithName(`functor`), pnList(e.Arguments()).WithName(`args`)}
 	if e.Lambda() != nil {
-		entries = append(entries, e.Lambda().ToPN().WithName(`block`))
+		entries = append(entries, pnOf(e.Lambda()).WithName(`block`))
 	}
 	return pn.Map(entries).AsCall(s)
 }
@@ -939,7 +1264,7 @@ func (e *CapabilityMapping) ToDefinition() Definition {
 }
 
 func (e *CapabilityMapping) ToPN() pn.PN {
-	return pn.Call(e.Kind(), e.Component().ToPN(), pn.List(append([]pn.PN{pn.Literal(e.Capability())}, pnMap(e.Mappings())...)))
+	return pn.Call(e.Kind(), pnOf(e.Component()), pn.List(append([]pn.PN{pn.Literal(e.Capability())}, pnMap(e.Mappings())...)))
 }
 
 func (e *CaseExpression) Test() Expression {
@@ -1002,7 +1327,7 @@ func (e *CollectExpression) Contents(path []Expression, visitor PathVisitor) {
 
 func (e *CollectExpression) ToPN() pn.PN {
 	entries := make([]pn.Entry, 0, 3)
-	entries = append(entries, e.ResourceType().ToPN().WithName(`type`), e.Query().ToPN().WithName(`query`))
+	entries = append(entries, pnOf(e.ResourceType()).WithName(`type`), pnOf(e.Query()).WithName(`query`))
 	if len(e.Operations()) > 0 {
 		entries = append(entries, pnList(e.Operations()).WithName(`ops`))
 	}
@@ -1077,7 +1402,7 @@ func (e *ExportedQuery) ToPN() pn.PN {
 	if e.Expr().IsNop() {
 		return pn.Call(`exported-query`)
 	}
-	return pn.Call(`exported-query`, e.Expr().ToPN())
+	return pn.Call(`exported-query`, pnOf(e.Expr()))
 }
 
 func (e *FunctionDefinition) ReturnType() Expression {
@@ -1108,6 +1433,22 @@ func (e *HeredocExpression) Text() Expression {
 	return e.text
 }
 
+// Template returns the EPP template that the text of a heredoc with the syntax "epp" was parsed to, i.e.
+// the same LambdaExpression that parsing the text as an .epp file produces. The positions of the template
+// are positions in the host document. Nil is returned when the syntax isn't "epp", when the text is
+// subject to interpolation, or when the template has syntax errors.
+//
+// The template is not visited by AllContents and Contents since it's not evaluated as part of the host
+// document.
+func (e *HeredocExpression) Template() Expression {
+	return e.template
+}
+
+// SetTemplate sets the EPP template that the text of the heredoc was parsed to
+func (e *HeredocExpression) SetTemplate(template Expression) {
+	e.template = template
+}
+
 func (e *HeredocExpression) AllContents(path []Expression, visitor PathVisitor) {
 	DeepVisit(e, path, visitor, e.text)
 }
@@ -1121,7 +1462,7 @@ func (e *HeredocExpression) ToPN() pn.PN {
 	if e.Syntax() != `` {
 		entries = append(entries, pn.Literal(e.Syntax()).WithName(`syntax`))
 	}
-	entries = append(entries, e.Text().ToPN().WithName(`text`))
+	entries = append(entries, pnOf(e.Text()).WithName(`text`))
 	return pn.Map(entries).AsCall(`heredoc`)
 }
 
@@ -1167,6 +1508,21 @@ func (e *IfExpression) Contents(path []Expression, visitor PathVisitor) {
 
 func (e *IfExpression) ToPN() pn.PN { return e.pnIf(`if`) }
 
+// Patterns returns the file names or glob patterns of the files that the import statement imports
+func (e *ImportExpression) Patterns() []Expression {
+	return e.patterns
+}
+
+func (e *ImportExpression) AllContents(path []Expression, visitor PathVisitor) {
+	DeepVisit(e, path, visitor, e.patterns)
+}
+
+func (e *ImportExpression) Contents(path []Expression, visitor PathVisitor) {
+	ShallowVisit(e, path, visitor, e.patterns)
+}
+
+func (e *ImportExpression) ToPN() pn.PN { return pn.Call(`import`, pnMap(e.patterns)...) }
+
 func (e *InExpression) AllContents(path []Expression, visitor PathVisitor) {
 	DeepVisit(e, path, visitor, e.lhs, e.rhs)
 }
@@ -1221,7 +1577,7 @@ func (e *LambdaExpression) ToPN() pn.PN {
 		entries = append(entries, parametersEntry(e.Parameters()))
 	}
 	if e.ReturnType() != nil {
-		entries = append(entries, e.ReturnType().ToPN().WithName(`returns`))
+		entries = append(entries, pnOf(e.ReturnType()).WithName(`returns`))
 	}
 	if e.Body() != nil {
 		entries = append(entries, pnBlockAsEntry(`body`, e.Body()))
@@ -1249,6 +1605,10 @@ func (e *LiteralBoolean) ToLiteralValue() LiteralValue {
 	return e
 }
 
+var DEFAULT_INSTANCE = Default{}
+
+type Default struct{}
+
 func (e *LiteralDefault) Value() interface{} {
 	return DEFAULT_INSTANCE
 }
@@ -1473,7 +1833,7 @@ func (e *NodeDefinition) ToPN() pn.PN {
 	entries := make([]pn.Entry, 0, 4)
 	entries = append(entries, pnList(e.HostMatches()).WithName(`matches`))
 	if e.Parent() != nil {
-		entries = append(entries, e.Parent().ToPN().WithName(`parent`))
+		entries = append(entries, pnOf(e.Parent()).WithName(`parent`))
 	}
 	if e.Body() != nil {
 		entries = append(entries, pnBlockAsEntry(`body`, e.Body()))
@@ -1539,13 +1899,13 @@ func (e *Parameter) ToPN() pn.PN {
 	entries := make([]pn.Entry, 0, 3)
 	entries = append(entries, pn.Literal(e.Name()).WithName(`name`))
 	if e.Type() != nil {
-		entries = append(entries, e.Type().ToPN().WithName(`type`))
+		entries = append(entries, pnOf(e.Type()).WithName(`type`))
 	}
 	if e.CapturesRest() {
 		entries = append(entries, pn.Literal(true).WithName(`splat`))
 	}
 	if e.Value() != nil {
-		entries = append(entries, e.Value().ToPN().WithName(`value`))
+		entries = append(entries, pnOf(e.Value()).WithName(`value`))
 	}
 	return pn.Map(entries).AsCall(`param`)
 }
@@ -1592,7 +1952,7 @@ func (e *Program) Contents(path []Expression, visitor PathVisitor) {
 	ShallowVisit(e, path, visitor, e.body)
 }
 
-func (e *Program) ToPN() pn.PN { return e.Body().ToPN() }
+func (e *Program) ToPN() pn.PN { return pnOf(e.Body()) }
 
 func (e *qRefDefinition) Name() string {
 	return e.name
@@ -1742,7 +2102,7 @@ func (e *ResourceBody) Contents(path []Expression, visitor PathVisitor) {
 
 func (e *ResourceBody) ToPN() pn.PN {
 	return pn.Map([]pn.Entry{
-		e.Title().ToPN().WithName(`title`),
+		pnOf(e.Title()).WithName(`title`),
 		pnList(e.Operations()).WithName(`ops`)}).AsCall(`resource-body`)
 }
 
@@ -1764,7 +2124,7 @@ func (e *ResourceDefaultsExpression) Contents(path []Expression, visitor PathVis
 
 func (e *ResourceDefaultsExpression) ToPN() pn.PN {
 	entries := make([]pn.Entry, 0, 3)
-	entries = append(entries, e.TypeRef().ToPN().WithName(`type`), pnList(e.Operations()).WithName(`ops`))
+	entries = append(entries, pnOf(e.TypeRef()).WithName(`type`), pnList(e.Operations()).WithName(`ops`))
 	if e.Form() != REGULAR {
 		entries = append(entries, pn.Literal(string(e.Form())).WithName(`form`))
 	}
@@ -1789,10 +2149,13 @@ func (e *ResourceExpression) Contents(path []Expression, visitor PathVisitor) {
 
 func (e *ResourceExpression) ToPN() pn.PN {
 	entries := make([]pn.Entry, 0, 3)
-	entries = append(entries, e.TypeName().ToPN().WithName(`type`))
+	entries = append(entries, pnOf(e.TypeName()).WithName(`type`))
 	bodies := make([]pn.PN, 0, len(e.Bodies()))
 	for _, body := range e.bodies {
-		bodies = append(bodies, body.ToPN().AsParameters()...)
+		// The body is represented by its parameters but their position is the position of the body
+		for _, bp := range body.ToPN().AsParameters() {
+			bodies = append(bodies, pn.Locate(bp, body))
+		}
 	}
 	entries = append(entries, pn.List(bodies).WithName(`bodies`))
 	if e.Form() != REGULAR {
@@ -1819,7 +2182,7 @@ func (e *ResourceOverrideExpression) Contents(path []Expression, visitor PathVis
 
 func (e *ResourceOverrideExpression) ToPN() pn.PN {
 	entries := make([]pn.Entry, 0, 3)
-	entries = append(entries, e.Resources().ToPN().WithName(`resources`), pnList(e.Operations()).WithName(`ops`))
+	entries = append(entries, pnOf(e.Resources()).WithName(`resources`), pnList(e.Operations()).WithName(`ops`))
 	if e.Form() != REGULAR {
 		entries = append(entries, pn.Literal(string(e.Form())).WithName(`form`))
 	}
@@ -1875,7 +2238,7 @@ func (e *SelectorExpression) Selectors() []Expression {
 }
 
 func (e *SelectorExpression) ToPN() pn.PN {
-	return pn.Call(`?`, e.Lhs().ToPN(), pnList(e.Selectors()))
+	return pn.Call(`?`, pnOf(e.Lhs()), pnList(e.Selectors()))
 }
 
 func (e *SiteDefinition) AllContents(path []Expression, visitor PathVisitor) {
@@ -1925,7 +2288,7 @@ func (e *TypeAlias) ToDefinition() Definition {
 }
 
 func (e *TypeAlias) ToPN() pn.PN {
-	return pn.Call(`type-alias`, pn.Literal(e.Name()), e.Type().ToPN())
+	return pn.Call(`type-alias`, pn.Literal(e.Name()), pnOf(e.Type()))
 }
 
 func (e *TypeAlias) Type() Expression {
@@ -1953,7 +2316,7 @@ func (e *TypeDefinition) ToDefinition() Definition {
 }
 
 func (e *TypeDefinition) ToPN() pn.PN {
-	return pn.Call(`type-definition`, pn.Literal(e.Name()), pn.Literal(e.Parent()), e.Body().ToPN())
+	return pn.Call(`type-definition`, pn.Literal(e.Name()), pn.Literal(e.Parent()), pnOf(e.Body()))
 }
 
 func (e *TypeMapping) Type() Expression {
@@ -1977,7 +2340,7 @@ func (e *TypeMapping) ToDefinition() Definition {
 }
 
 func (e *TypeMapping) ToPN() pn.PN {
-	return pn.Call(`type-mapping`, e.Type().ToPN(), e.Mapping().ToPN())
+	return pn.Call(`type-mapping`, pnOf(e.Type()), pnOf(e.Mapping()))
 }
 
 func (e *unaryExpression) Expr() Expression {
@@ -2075,7 +2438,7 @@ func (e *VirtualQuery) ToPN() pn.PN {
 	if e.Expr().IsNop() {
 		return pn.Call(`virtual-query`)
 	}
-	return pn.Call(`virtual-query`, e.Expr().ToPN())
+	return pn.Call(`virtual-query`, pnOf(e.Expr()))
 }
 
 func (e *VirtualQuery) ToQueryExpression() QueryExpression {
@@ -2084,7 +2447,7 @@ func (e *VirtualQuery) ToQueryExpression() QueryExpression {
 
 func (e *IfExpression) pnIf(name string) pn.PN {
 	entries := make([]pn.Entry, 0, 3)
-	entries = append(entries, e.Test().ToPN().WithName(`test`))
+	entries = append(entries, pnOf(e.Test()).WithName(`test`))
 	if !e.Then().IsNop() {
 		entries = append(entries, pnBlockAsEntry(`then`, e.Then()))
 	}
@@ -2107,7 +2470,7 @@ func (e *namedDefinition) definitionPN(typeName string, parent string, returnTyp
 		entries = append(entries, pnBlockAsEntry(`body`, e.Body()))
 	}
 	if returnType != nil {
-		entries = append(entries, returnType.ToPN().WithName(`returns`))
+		entries = append(entries, pnOf(returnType).WithName(`returns`))
 	}
 	return pn.Map(entries).AsCall(typeName)
 }
@@ -2118,21 +2481,29 @@ func parametersEntry(parameters []Expression) pn.Entry {
 		p, _ := param.(*Parameter)
 		entries := make([]pn.Entry, 0, 3)
 		if p.Type() != nil {
-			entries = append(entries, p.Type().ToPN().WithName(`type`))
+			entries = append(entries, pnOf(p.Type()).WithName(`type`))
 		}
 		if p.CapturesRest() {
 			entries = append(entries, pn.Literal(true).WithName(`splat`))
 		}
 		if p.Value() != nil {
-			entries = append(entries, p.Value().ToPN().WithName(`value`))
+			entries = append(entries, pnOf(p.Value()).WithName(`value`))
 		}
-		params[idx] = pn.Map(entries).WithName(p.Name())
+		params[idx] = pn.Locate(pn.Map(entries), p).WithName(p.Name())
 	}
 	return pn.Map(params).WithName(`params`)
 }
 
 func (e *binaryExpression) binaryOp(op string) pn.PN {
-	return pn.Call(op, e.Lhs().ToPN(), e.Rhs().ToPN())
+	return pn.Call(op, pnOf(e.Lhs()), pnOf(e.Rhs()))
+}
+
+// pnOf returns the PN of a contained expression. The PN is located so that the position of the
+// expression can be included when the PN is rendered using the pn.WITH_POSITIONS option, and it is lazy
+// so that the PN of an expression only holds the nodes of its own level and the rest is produced while
+// it is rendered.
+func pnOf(e Expression) pn.PN {
+	return pn.Locate(pn.Lazy(e.ToPN), e)
 }
 
 func pnList(elements []Expression) pn.PN {
@@ -2146,7 +2517,7 @@ func pnMap(elements []Expression) []pn.PN {
 func pnMapArgs(elements ...Expression) []pn.PN {
 	result := make([]pn.PN, len(elements))
 	for idx, element := range elements {
-		result[idx] = element.ToPN()
+		result[idx] = pnOf(element)
 	}
 	return result
 }
@@ -0,0 +1,34 @@
+package ast
+
+// FindAll returns all expressions of type T in the tree rooted at the given expression, including
+// the root itself, in the order that they are visited by AllContents. For example,
+//
+//	resources := parser.FindAll[*parser.ResourceExpression](program)
+//
+// returns all resource expressions of a program. T can also be an interface such as Definition.
+func FindAll[T Expression](root Expression) []T {
+	found := make([]T, 0)
+	collect := func(path []Expression, e Expression) {
+		if t, ok := e.(T); ok {
+			found = append(found, t)
+		}
+	}
+	collect(nil, root)
+	root.AllContents(nil, collect)
+	return found
+}
+
+// First returns the first expression of type T in the tree rooted at the given expression, including
+// the root itself, in the order that they are visited by AllContents. The second return value is
+// false when no such expression exists.
+func First[T Expression](root Expression) (first T, found bool) {
+	if first, found = root.(T); found {
+		return
+	}
+	root.AllContents(nil, func(path []Expression, e Expression) {
+		if !found {
+			first, found = e.(T)
+		}
+	})
+	return
+}
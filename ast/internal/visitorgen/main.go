@@ -0,0 +1,120 @@
+// The visitorgen program generates the Visitor interface of the ast package and the functions
+// that dispatch expressions to it. The kinds of expressions are the exported struct types of the
+// package that embed Positioned, directly or by embedding another such struct. Run it from the
+// directory of the ast package using
+//
+//	go generate
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+func main() {
+	dir := flag.String(`d`, `.`, `directory of the ast package`)
+	out := flag.String(`o`, `visitor_gen.go`, `name of the generated file`)
+	flag.Parse()
+
+	source, err := Generate(*dir, *out)
+	if err == nil {
+		err = ioutil.WriteFile(filepath.Join(*dir, *out), source, 0644)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+}
+
+// Generate returns the source of the generated file with the given name for the package in the
+// given directory
+func Generate(dir, out string) ([]byte, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), `_test.go`) && fi.Name() != out
+	}, 0)
+	if err != nil {
+		return nil, err
+	}
+	pkg, ok := pkgs[`ast`]
+	if !ok {
+		return nil, fmt.Errorf(`no ast package found in %s`, dir)
+	}
+	return generate(nodeKinds(pkg))
+}
+
+// nodeKinds returns the sorted names of the exported struct types of the given package that embed
+// Positioned
+func nodeKinds(pkg *ast.Package) []string {
+	embeds := make(map[string][]string)
+	for _, file := range pkg.Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			if ts, ok := n.(*ast.TypeSpec); ok {
+				if st, ok := ts.Type.(*ast.StructType); ok {
+					names := make([]string, 0)
+					for _, field := range st.Fields.List {
+						if id, ok := field.Type.(*ast.Ident); ok && len(field.Names) == 0 {
+							names = append(names, id.Name)
+						}
+					}
+					embeds[ts.Name.Name] = names
+				}
+				return false
+			}
+			return true
+		})
+	}
+
+	var isNode func(name string) bool
+	isNode = func(name string) bool {
+		for _, embedded := range embeds[name] {
+			if embedded == `Positioned` || isNode(embedded) {
+				return true
+			}
+		}
+		return false
+	}
+
+	kinds := make([]string, 0, len(embeds))
+	for name := range embeds {
+		if ast.IsExported(name) && isNode(name) {
+			kinds = append(kinds, name)
+		}
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+func generate(kinds []string) ([]byte, error) {
+	b := bytes.NewBufferString("// Code generated by visitorgen. DO NOT EDIT.\n\npackage ast\n\n")
+
+	b.WriteString("// Visitor is implemented by types that handle each kind of expression using a method of its own.\n")
+	b.WriteString("// Embed DefaultVisitor in an implementation to only implement the methods of interest.\n")
+	b.WriteString("type Visitor interface {\n")
+	for _, kind := range kinds {
+		fmt.Fprintf(b, "\tVisit%s(e *%s)\n", kind, kind)
+	}
+	b.WriteString("}\n\n")
+
+	for _, kind := range kinds {
+		fmt.Fprintf(b, "func (v DefaultVisitor) Visit%s(e *%s) {\n\tv.fallback(e)\n}\n\n", kind, kind)
+	}
+
+	b.WriteString("// Visit calls the method of the given visitor that corresponds to the kind of the given expression\n")
+	b.WriteString("func Visit(v Visitor, e Expression) {\n\tswitch e := e.(type) {\n")
+	for _, kind := range kinds {
+		fmt.Fprintf(b, "\tcase *%s:\n\t\tv.Visit%s(e)\n", kind, kind)
+	}
+	b.WriteString("\t}\n}\n")
+	return format.Source(b.Bytes())
+}
@@ -1,4 +1,4 @@
-package parser
+package ast
 
 import (
 	"fmt"
@@ -31,6 +31,7 @@ func (e *FunctionDefinition) Label() string          { return "Function Definiti
 func (e *HeredocExpression) Label() string           { return "Heredoc" }
 func (e *HostClassDefinition) Label() string         { return "Host Class Definition" }
 func (e *IfExpression) Label() string                { return "'if' statement" }
+func (e *ImportExpression) Label() string            { return "'import' statement" }
 func (e *InExpression) Label() string                { return "'in' expression" }
 func (e *KeyedEntry) Label() string                  { return "Hash Entry" }
 func (e *LiteralBoolean) Label() string              { return "Literal Boolean" }
@@ -49,6 +50,7 @@ func (e *Nop) Label() string                         { return "Nop" }
 func (e *NotExpression) Label() string               { return "'!' expression" }
 func (e *OrExpression) Label() string                { return "'or' expression" }
 func (e *Parameter) Label() string                   { return "Parameter Definition" }
+func (e *PlanDefinition) Label() string              { return "Plan Definition" }
 func (e *Program) Label() string                     { return "Program" }
 func (e *QualifiedName) Label() string               { return "Name" }
 func (e *QualifiedReference) Label() string          { return "Type-Name" }
@@ -0,0 +1,33 @@
+package ast
+
+import "strings"
+
+// ResolveRelativeName returns the qualified name that a definition with the given name gets when it's
+// declared inside the class or workflow with the given qualified name. The current name is empty at
+// top level. This is the qualification that the parser applies to nested definitions, e.g. class b
+// declared inside class a is named a::b. A leading '::' of the given name is ignored.
+//
+// References are not resolved this way. Use ResolveReference to resolve a reference such as the name
+// given to include.
+func ResolveRelativeName(current, name string) string {
+	name = strings.TrimPrefix(name, `::`)
+	if current == `` {
+		return name
+	}
+	return current + `::` + name
+}
+
+// ResolveReference returns the qualified name of the definition that the given reference refers to.
+// References are resolved from top scope regardless of where they appear, so `include foo` inside
+// class bar refers to the class foo and not to bar::foo. The returned name is in lower case and has
+// no leading '::', e.g. both 'Foo::Bar' and '::foo::bar' resolve to 'foo::bar'.
+func ResolveReference(ref string) string {
+	return strings.ToLower(strings.TrimPrefix(ref, `::`))
+}
+
+// IsAbsoluteName returns true if the given name starts with '::', which explicitly makes it a
+// reference to a top scope name. Names that are not absolute are relative. A relative reference is
+// still resolved from top scope but a relative variable name is resolved in the scope where it's used.
+func IsAbsoluteName(name string) bool {
+	return strings.HasPrefix(name, `::`)
+}
@@ -0,0 +1,120 @@
+package ast
+
+import "sort"
+
+// NormalizeOption selects a transformation performed by Normalize
+type NormalizeOption int
+
+// NORMALIZE_SORT_ATTRIBUTES sorts the attribute operations of resource bodies, resource defaults,
+// resource overrides, and collectors by attribute name. An attribute splat (* => $h) is sorted using
+// the name '*'.
+const NORMALIZE_SORT_ATTRIBUTES = NormalizeOption(1)
+
+// NORMALIZE_EXPAND_CALLS replaces the call sugar with plain function calls. A method call such as
+// $x.f(1) becomes f($x, 1) and a statement call such as `notice 'x'` becomes a call that produces
+// a value, i.e. `notice('x')`.
+const NORMALIZE_EXPAND_CALLS = NormalizeOption(2)
+
+// NORMALIZE_SELECTORS replaces a selector that has a default entry only with the value of that entry.
+const NORMALIZE_SELECTORS = NormalizeOption(3)
+
+// NORMALIZE_STRIP_PARENTHESES replaces parenthesized expressions with the expression that they
+// enclose. The grouping is retained by the structure of the tree.
+const NORMALIZE_STRIP_PARENTHESES = NormalizeOption(4)
+
+// Normalize returns the given tree transformed into a canonical form by the transformations
+// selected by the given options. Trees that differ only in ways that are removed by the selected
+// transformations have equal PN after normalization, which makes the result suitable for semantic
+// diffing. The given tree is not modified.
+//
+// Expressions of the result retain the positions of the expressions that they were created from.
+func Normalize(e Expression, options ...NormalizeOption) Expression {
+	var sortAttributes, expandCalls, selectors, stripParentheses bool
+	for _, option := range options {
+		switch option {
+		case NORMALIZE_SORT_ATTRIBUTES:
+			sortAttributes = true
+		case NORMALIZE_EXPAND_CALLS:
+			expandCalls = true
+		case NORMALIZE_SELECTORS:
+			selectors = true
+		case NORMALIZE_STRIP_PARENTHESES:
+			stripParentheses = true
+		}
+	}
+
+	return Transform(e, func(e Expression) Expression {
+		switch e := e.(type) {
+		case *ResourceBody:
+			if sortAttributes {
+				c := *e
+				c.operations = sortedAttributes(e.operations)
+				return &c
+			}
+		case *ResourceDefaultsExpression:
+			if sortAttributes {
+				c := *e
+				c.operations = sortedAttributes(e.operations)
+				return &c
+			}
+		case *ResourceOverrideExpression:
+			if sortAttributes {
+				c := *e
+				c.operations = sortedAttributes(e.operations)
+				return &c
+			}
+		case *CollectExpression:
+			if sortAttributes {
+				c := *e
+				c.operations = sortedAttributes(e.operations)
+				return &c
+			}
+		case *CallMethodExpression:
+			if expandCalls {
+				if na, ok := e.functor.(*NamedAccessExpression); ok {
+					if _, ok := na.rhs.(*QualifiedName); ok {
+						args := make([]Expression, 0, len(e.arguments)+1)
+						args = append(append(args, na.lhs), e.arguments...)
+						return &CallNamedFunctionExpression{callExpression{e.Positioned, true, na.rhs, args, e.lambda}}
+					}
+				}
+			}
+		case *CallNamedFunctionExpression:
+			if expandCalls && !e.rvalRequired {
+				c := *e
+				c.rvalRequired = true
+				return &c
+			}
+		case *SelectorExpression:
+			if selectors && len(e.selectors) == 1 {
+				if se, ok := e.selectors[0].(*SelectorEntry); ok {
+					if _, ok := se.matching.(*LiteralDefault); ok {
+						return se.value
+					}
+				}
+			}
+		case *ParenthesizedExpression:
+			if stripParentheses {
+				return e.expr
+			}
+		}
+		return e
+	})
+}
+
+// sortedAttributes returns a sorted copy of the given attribute operations
+func sortedAttributes(operations []Expression) []Expression {
+	sorted := make([]Expression, len(operations))
+	copy(sorted, operations)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return attributeName(sorted[i]) < attributeName(sorted[j])
+	})
+	return sorted
+}
+
+func attributeName(e Expression) string {
+	if ao, ok := e.(*AttributeOperation); ok {
+		return ao.name
+	}
+	return `*`
+}
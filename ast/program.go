@@ -0,0 +1,130 @@
+package ast
+
+import "strings"
+
+// ComposePrograms creates a Program that consists of all the given programs, typically parsed
+// from different files. Each program is retained as a statement of the body of the created program
+// so every expression keeps the Locator of the file that it was parsed from. The Definitions of
+// the created program are the definitions of all the given programs.
+//
+// A composed program is neither associated with a file nor a source text. Composed programs given
+// to this function are flattened so that the result always consists of programs parsed from files.
+func ComposePrograms(programs ...*Program) *Program {
+	locator := NewLocator(``, ``)
+	files := make([]Expression, 0, len(programs))
+	definitions := make([]Definition, 0)
+	for _, program := range programs {
+		for _, p := range program.Programs() {
+			files = append(files, p)
+			definitions = append(definitions, p.Definitions()...)
+		}
+	}
+	body := DefaultFactory().Block(files, locator, 0, 0)
+	return DefaultFactory().Program(body, definitions, locator, 0, 0).(*Program)
+}
+
+// Programs returns the programs that this program is composed of or, if this program
+// isn't composed, a slice containing only this program.
+func (e *Program) Programs() []*Program {
+	if b, ok := e.body.(*BlockExpression); ok && len(b.statements) > 0 {
+		programs := make([]*Program, 0, len(b.statements))
+		for _, s := range b.statements {
+			p, ok := s.(*Program)
+			if !ok {
+				break
+			}
+			programs = append(programs, p)
+		}
+		if len(programs) == len(b.statements) {
+			return programs
+		}
+	}
+	return []*Program{e}
+}
+
+// Program returns the program that was parsed from the given file and true, or nil and false
+// if no such program is found
+func (e *Program) Program(file string) (*Program, bool) {
+	for _, p := range e.Programs() {
+		if p.File() == file {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+// Classes returns the class definitions of this program in the same order as in Definitions
+func (e *Program) Classes() []*HostClassDefinition {
+	return definitionsOf[*HostClassDefinition](e)
+}
+
+// DefinedTypes returns the defined type definitions of this program in the same order as in Definitions
+func (e *Program) DefinedTypes() []*ResourceTypeDefinition {
+	return definitionsOf[*ResourceTypeDefinition](e)
+}
+
+// Functions returns the function definitions of this program in the same order as in Definitions. Plans
+// are not included.
+func (e *Program) Functions() []*FunctionDefinition {
+	return definitionsOf[*FunctionDefinition](e)
+}
+
+// Plans returns the plan definitions of this program in the same order as in Definitions
+func (e *Program) Plans() []*PlanDefinition {
+	return definitionsOf[*PlanDefinition](e)
+}
+
+// TypeAliases returns the type aliases of this program in the same order as in Definitions
+func (e *Program) TypeAliases() []*TypeAlias {
+	return definitionsOf[*TypeAlias](e)
+}
+
+// Nodes returns the node definitions of this program in the same order as in Definitions
+func (e *Program) Nodes() []*NodeDefinition {
+	return definitionsOf[*NodeDefinition](e)
+}
+
+// DefinitionByName returns the definition with the given qualified name and true, or nil and false
+// if no such definition is found. The name is matched case insensitively and a leading '::' is
+// ignored, so both 'mymod::config' and 'Mymod::Config' find the type alias Mymod::Config. The first
+// definition found is returned when different kinds of definitions have the same name.
+func (e *Program) DefinitionByName(name string) (Definition, bool) {
+	name = strings.TrimPrefix(name, `::`)
+	for _, d := range e.definitions {
+		if nd, ok := d.(interface{ Name() string }); ok && strings.EqualFold(nd.Name(), name) {
+			return d, true
+		}
+	}
+	return nil, false
+}
+
+// SourceOf returns the original source text of the given definition and its range in the source of the
+// program that it was parsed from. The text is exactly what was parsed, including comments and line
+// breaks within the definition, so it can be copied to another file or used as a cache key that changes
+// only when the definition changes. An empty string and a zero Range is returned when the definition
+// was not parsed from this program or from one of the programs that it is composed of.
+func (e *Program) SourceOf(def Definition) (string, Range) {
+	l := def.Locator()
+	if l.IsSynthetic() {
+		return ``, Range{}
+	}
+	for _, p := range e.Programs() {
+		if p.Locator() == l {
+			r := RangeOf(def)
+			return l.Slice(r.Offset, r.Offset+r.Length), r
+		}
+	}
+	return ``, Range{}
+}
+
+// definitionsOf returns the definitions of type T of the given program in the order that they
+// appear in its Definitions
+func definitionsOf[T Definition](e *Program) []T {
+	found := make([]T, 0)
+	for _, d := range e.definitions {
+		if t, ok := d.(T); ok {
+			found = append(found, t)
+		}
+	}
+	return found
+}
@@ -0,0 +1,62 @@
+package ast
+
+import (
+	"regexp"
+
+	"github.com/lyraproj/puppet-parser/pn"
+)
+
+// RedactedPN returns the PN of the given expression where sensitive string values have been
+// replaced by pn.REDACTED. A string is considered sensitive when it flows into a Sensitive value,
+// i.e. when it is an argument to Sensitive() or Sensitive.new(), or when it is the default value
+// of a parameter of type Sensitive. Strings that match any of the given patterns are also redacted.
+func RedactedPN(e Expression, patterns ...*regexp.Regexp) pn.PN {
+	sensitive := make(map[pn.Located]bool)
+	mark := func(path []Expression, e Expression) {
+		sensitive[e] = true
+	}
+	markAll := func(e Expression) {
+		mark(nil, e)
+		e.AllContents(nil, mark)
+	}
+
+	visit := func(path []Expression, e Expression) {
+		switch e := e.(type) {
+		case *CallNamedFunctionExpression:
+			if isSensitiveType(e.Functor()) {
+				for _, arg := range e.Arguments() {
+					markAll(arg)
+				}
+			}
+		case *CallMethodExpression:
+			if na, ok := e.Functor().(*NamedAccessExpression); ok && isSensitiveType(na.Lhs()) {
+				if qn, ok := na.Rhs().(*QualifiedName); ok && qn.Name() == `new` {
+					for _, arg := range e.Arguments() {
+						markAll(arg)
+					}
+				}
+			}
+		case *Parameter:
+			if e.Value() != nil && isSensitiveType(e.Type()) {
+				markAll(e.Value())
+			}
+		}
+	}
+	visit(nil, e)
+	e.AllContents(nil, visit)
+
+	matches := pn.RedactMatching(patterns...)
+	return pn.Redact(e.ToPN(), func(source pn.Located, value string) bool {
+		return sensitive[source] || matches(source, value)
+	})
+}
+
+// isSensitiveType returns true if the given expression is a reference to the Sensitive type, optionally
+// parameterized
+func isSensitiveType(e Expression) bool {
+	if ae, ok := e.(*AccessExpression); ok {
+		e = ae.Operand()
+	}
+	qr, ok := e.(*QualifiedReference)
+	return ok && qr.Name() == `Sensitive`
+}
@@ -0,0 +1,91 @@
+package ast
+
+import (
+	"sort"
+	"strings"
+)
+
+// A Source is a text that is stored in pieces rather than in one string, e.g. the rope or piece table of an
+// editor buffer. A lexer or parser that reads a Source reads it one chunk at a time, so a buffer can be lexed
+// and parsed after each change without first being flattened into one string. The text is flattened only
+// when it is needed as a whole, i.e. when the source is EPP, when a lexer recovers from an error, or when
+// the String of the Locator is requested. The Chunk method must be safe for concurrent use when the parsed
+// expressions are read by several goroutines.
+type Source interface {
+	// Len returns the length of the text in bytes
+	Len() int
+
+	// Chunk returns the chunk of the text that contains the given byte offset, which is less than Len,
+	// together with the offset where the chunk starts. Chunks must not be empty.
+	Chunk(offset int) (start int, chunk string)
+}
+
+type stringSource string
+
+// StringSource returns a Source that consists of one chunk, the given string
+func StringSource(s string) Source {
+	return stringSource(s)
+}
+
+func (s stringSource) Len() int {
+	return len(s)
+}
+
+func (s stringSource) Chunk(offset int) (int, string) {
+	return 0, string(s)
+}
+
+type chunkedSource struct {
+	chunks []string
+	starts []int
+	length int
+}
+
+// NewChunkedSource returns a Source that consists of the given chunks. Empty chunks are ignored.
+func NewChunkedSource(chunks ...string) Source {
+	cs := &chunkedSource{chunks: make([]string, 0, len(chunks)), starts: make([]int, 0, len(chunks))}
+	for _, chunk := range chunks {
+		if chunk != `` {
+			cs.chunks = append(cs.chunks, chunk)
+			cs.starts = append(cs.starts, cs.length)
+			cs.length += len(chunk)
+		}
+	}
+	return cs
+}
+
+func (cs *chunkedSource) Len() int {
+	return cs.length
+}
+
+func (cs *chunkedSource) Chunk(offset int) (int, string) {
+	i := sort.SearchInts(cs.starts, offset+1) - 1
+	return cs.starts[i], cs.chunks[i]
+}
+
+// SourceSlice returns the text of the given source between the given offsets
+func SourceSlice(source Source, start, end int) string {
+	if start >= end {
+		return ``
+	}
+	first, chunk := source.Chunk(start)
+	if end <= first+len(chunk) {
+		return chunk[start-first : end-first]
+	}
+	b := strings.Builder{}
+	b.Grow(end - start)
+	b.WriteString(chunk[start-first:])
+	for pos := first + len(chunk); pos < end; pos += len(chunk) {
+		_, chunk = source.Chunk(pos)
+		b.WriteString(chunk[:min(len(chunk), end-pos)])
+	}
+	return b.String()
+}
+
+// SourceText returns the whole text of the given source
+func SourceText(source Source) string {
+	if s, ok := source.(stringSource); ok {
+		return string(s)
+	}
+	return SourceSlice(source, 0, source.Len())
+}
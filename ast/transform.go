@@ -0,0 +1,498 @@
+package ast
+
+// Transform returns the result of applying the given function to every expression of the given
+// tree, bottom up. The function is called with an expression whose contained expressions have already
+// been transformed and returns the expression to use in its place, which may be the given expression.
+//
+// The given tree is never modified. An expression that contains a replaced expression is copied and the
+// copy refers to the replacements. Expressions that are unaffected by the transformation are shared between
+// the given tree and the result. The Definitions of a transformed Program are updated to reflect the
+// transformed definitions.
+func Transform(e Expression, f func(Expression) Expression) Expression {
+	t := &transformer{f: f, definitions: make(map[Definition]Expression)}
+	return t.transform(e)
+}
+
+type transformer struct {
+	f           func(Expression) Expression
+	definitions map[Definition]Expression
+}
+
+func (t *transformer) transform(e Expression) Expression {
+	var r Expression = e
+	switch e := e.(type) {
+	case *AccessExpression:
+		operand, c1 := t.expr(e.operand)
+		keys, c2 := t.exprs(e.keys)
+		if c1 || c2 {
+			c := *e
+			c.operand, c.keys = operand, keys
+			r = &c
+		}
+	case *ActivityExpression:
+		properties, c1 := t.expr(e.properties)
+		definition, c2 := t.expr(e.definition)
+		if c1 || c2 {
+			c := *e
+			c.properties, c.definition = properties, definition
+			r = &c
+		}
+	case *AndExpression:
+		if b, changed := t.binary(&e.binaryExpression); changed {
+			c := *e
+			c.binaryExpression = b
+			r = &c
+		}
+	case *Application:
+		if d, changed := t.namedDefinition(&e.namedDefinition); changed {
+			c := *e
+			c.namedDefinition = d
+			r = &c
+		}
+	case *ArithmeticExpression:
+		if b, changed := t.binary(&e.binaryExpression); changed {
+			c := *e
+			c.binaryExpression = b
+			r = &c
+		}
+	case *AssignmentExpression:
+		if b, changed := t.binary(&e.binaryExpression); changed {
+			c := *e
+			c.binaryExpression = b
+			r = &c
+		}
+	case *AttributeOperation:
+		if value, changed := t.expr(e.value); changed {
+			c := *e
+			c.value = value
+			r = &c
+		}
+	case *AttributesOperation:
+		if expr, changed := t.expr(e.expr); changed {
+			c := *e
+			c.expr = expr
+			r = &c
+		}
+	case *BlockExpression:
+		if statements, changed := t.exprs(e.statements); changed {
+			c := *e
+			c.statements = statements
+			r = &c
+		}
+	case *CallFunctionExpression:
+		if ce, changed := t.call(&e.callExpression); changed {
+			c := *e
+			c.callExpression = ce
+			r = &c
+		}
+	case *CallMethodExpression:
+		if ce, changed := t.call(&e.callExpression); changed {
+			c := *e
+			c.callExpression = ce
+			r = &c
+		}
+	case *CallNamedFunctionExpression:
+		if ce, changed := t.call(&e.callExpression); changed {
+			c := *e
+			c.callExpression = ce
+			r = &c
+		}
+	case *CapabilityMapping:
+		component, c1 := t.expr(e.component)
+		mappings, c2 := t.exprs(e.mappings)
+		if c1 || c2 {
+			c := *e
+			c.component, c.mappings = component, mappings
+			r = &c
+		}
+	case *CaseExpression:
+		test, c1 := t.expr(e.test)
+		options, c2 := t.exprs(e.options)
+		if c1 || c2 {
+			c := *e
+			c.test, c.options = test, options
+			r = &c
+		}
+	case *CaseOption:
+		values, c1 := t.exprs(e.values)
+		then, c2 := t.expr(e.then)
+		if c1 || c2 {
+			c := *e
+			c.values, c.then = values, then
+			r = &c
+		}
+	case *CollectExpression:
+		resourceType, c1 := t.expr(e.resourceType)
+		query, c2 := t.expr(e.query)
+		operations, c3 := t.exprs(e.operations)
+		if c1 || c2 || c3 {
+			c := *e
+			c.resourceType, c.query, c.operations = resourceType, query, operations
+			r = &c
+		}
+	case *ComparisonExpression:
+		if b, changed := t.binary(&e.binaryExpression); changed {
+			c := *e
+			c.binaryExpression = b
+			r = &c
+		}
+	case *ConcatenatedString:
+		if segments, changed := t.exprs(e.segments); changed {
+			c := *e
+			c.segments = segments
+			r = &c
+		}
+	case *EppExpression:
+		if body, changed := t.expr(e.body); changed {
+			c := *e
+			c.body = body
+			r = &c
+		}
+	case *ExportedQuery:
+		if expr, changed := t.expr(e.expr); changed {
+			c := *e
+			c.expr = expr
+			r = &c
+		}
+	case *FunctionDefinition:
+		if fd, changed := t.functionDefinition(e); changed {
+			r = &fd
+		}
+	case *HeredocExpression:
+		if text, changed := t.expr(e.text); changed {
+			c := *e
+			c.text = text
+			r = &c
+		}
+	case *HostClassDefinition:
+		if d, changed := t.namedDefinition(&e.namedDefinition); changed {
+			c := *e
+			c.namedDefinition = d
+			r = &c
+		}
+	case *IfExpression:
+		if ie, changed := t.ifExpression(e); changed {
+			r = &ie
+		}
+	case *ImportExpression:
+		if patterns, changed := t.exprs(e.patterns); changed {
+			c := *e
+			c.patterns = patterns
+			r = &c
+		}
+	case *InExpression:
+		if b, changed := t.binary(&e.binaryExpression); changed {
+			c := *e
+			c.binaryExpression = b
+			r = &c
+		}
+	case *KeyedEntry:
+		key, c1 := t.expr(e.key)
+		value, c2 := t.expr(e.value)
+		if c1 || c2 {
+			c := *e
+			c.key, c.value = key, value
+			r = &c
+		}
+	case *LambdaExpression:
+		parameters, c1 := t.exprs(e.parameters)
+		body, c2 := t.expr(e.body)
+		returnType, c3 := t.expr(e.returnType)
+		if c1 || c2 || c3 {
+			c := *e
+			c.parameters, c.body, c.returnType = parameters, body, returnType
+			r = &c
+		}
+	case *LiteralHash:
+		if entries, changed := t.exprs(e.entries); changed {
+			c := *e
+			c.entries = entries
+			r = &c
+		}
+	case *LiteralList:
+		if elements, changed := t.exprs(e.elements); changed {
+			c := *e
+			c.elements = elements
+			r = &c
+		}
+	case *MatchExpression:
+		if b, changed := t.binary(&e.binaryExpression); changed {
+			c := *e
+			c.binaryExpression = b
+			r = &c
+		}
+	case *NamedAccessExpression:
+		if b, changed := t.binary(&e.binaryExpression); changed {
+			c := *e
+			c.binaryExpression = b
+			r = &c
+		}
+	case *NodeDefinition:
+		parent, c1 := t.expr(e.parent)
+		hostMatches, c2 := t.exprs(e.hostMatches)
+		body, c3 := t.expr(e.body)
+		if c1 || c2 || c3 {
+			c := *e
+			c.parent, c.hostMatches, c.body = parent, hostMatches, body
+			r = &c
+		}
+	case *NotExpression:
+		if u, changed := t.unary(&e.unaryExpression); changed {
+			c := *e
+			c.unaryExpression = u
+			r = &c
+		}
+	case *OrExpression:
+		if b, changed := t.binary(&e.binaryExpression); changed {
+			c := *e
+			c.binaryExpression = b
+			r = &c
+		}
+	case *Parameter:
+		value, c1 := t.expr(e.value)
+		typeExpr, c2 := t.expr(e.typeExpr)
+		if c1 || c2 {
+			c := *e
+			c.value, c.typeExpr = value, typeExpr
+			r = &c
+		}
+	case *ParenthesizedExpression:
+		if u, changed := t.unary(&e.unaryExpression); changed {
+			c := *e
+			c.unaryExpression = u
+			r = &c
+		}
+	case *PlanDefinition:
+		if fd, changed := t.functionDefinition(&e.FunctionDefinition); changed {
+			c := *e
+			c.FunctionDefinition = fd
+			r = &c
+		}
+	case *Program:
+		if body, changed := t.expr(e.body); changed {
+			c := *e
+			c.body = body
+			c.definitions = make([]Definition, 0, len(e.definitions))
+			for _, d := range e.definitions {
+				if n, ok := t.definitions[d]; ok {
+					if nd, ok := n.(Definition); ok {
+						c.definitions = append(c.definitions, nd)
+					}
+				} else {
+					c.definitions = append(c.definitions, d)
+				}
+			}
+			r = &c
+		}
+	case *RelationshipExpression:
+		if b, changed := t.binary(&e.binaryExpression); changed {
+			c := *e
+			c.binaryExpression = b
+			r = &c
+		}
+	case *RenderExpression:
+		if u, changed := t.unary(&e.unaryExpression); changed {
+			c := *e
+			c.unaryExpression = u
+			r = &c
+		}
+	case *ResourceBody:
+		title, c1 := t.expr(e.title)
+		operations, c2 := t.exprs(e.operations)
+		if c1 || c2 {
+			c := *e
+			c.title, c.operations = title, operations
+			r = &c
+		}
+	case *ResourceDefaultsExpression:
+		typeRef, c1 := t.expr(e.typeRef)
+		operations, c2 := t.exprs(e.operations)
+		if c1 || c2 {
+			c := *e
+			c.typeRef, c.operations = typeRef, operations
+			r = &c
+		}
+	case *ResourceExpression:
+		typeName, c1 := t.expr(e.typeName)
+		bodies, c2 := t.exprs(e.bodies)
+		if c1 || c2 {
+			c := *e
+			c.typeName, c.bodies = typeName, bodies
+			r = &c
+		}
+	case *ResourceOverrideExpression:
+		resources, c1 := t.expr(e.resources)
+		operations, c2 := t.exprs(e.operations)
+		if c1 || c2 {
+			c := *e
+			c.resources, c.operations = resources, operations
+			r = &c
+		}
+	case *ResourceTypeDefinition:
+		if d, changed := t.namedDefinition(&e.namedDefinition); changed {
+			c := *e
+			c.namedDefinition = d
+			r = &c
+		}
+	case *SelectorEntry:
+		matching, c1 := t.expr(e.matching)
+		value, c2 := t.expr(e.value)
+		if c1 || c2 {
+			c := *e
+			c.matching, c.value = matching, value
+			r = &c
+		}
+	case *SelectorExpression:
+		lhs, c1 := t.expr(e.lhs)
+		selectors, c2 := t.exprs(e.selectors)
+		if c1 || c2 {
+			c := *e
+			c.lhs, c.selectors = lhs, selectors
+			r = &c
+		}
+	case *SiteDefinition:
+		if body, changed := t.expr(e.body); changed {
+			c := *e
+			c.body = body
+			r = &c
+		}
+	case *TextExpression:
+		if u, changed := t.unary(&e.unaryExpression); changed {
+			c := *e
+			c.unaryExpression = u
+			r = &c
+		}
+	case *TypeAlias:
+		if typeExpr, changed := t.expr(e.typeExpr); changed {
+			c := *e
+			c.typeExpr = typeExpr
+			r = &c
+		}
+	case *TypeDefinition:
+		if body, changed := t.expr(e.body); changed {
+			c := *e
+			c.body = body
+			r = &c
+		}
+	case *TypeMapping:
+		typeExpr, c1 := t.expr(e.typeExpr)
+		mappingExpr, c2 := t.expr(e.mappingExpr)
+		if c1 || c2 {
+			c := *e
+			c.typeExpr, c.mappingExpr = typeExpr, mappingExpr
+			r = &c
+		}
+	case *UnaryMinusExpression:
+		if u, changed := t.unary(&e.unaryExpression); changed {
+			c := *e
+			c.unaryExpression = u
+			r = &c
+		}
+	case *UnfoldExpression:
+		if u, changed := t.unary(&e.unaryExpression); changed {
+			c := *e
+			c.unaryExpression = u
+			r = &c
+		}
+	case *UnlessExpression:
+		if ie, changed := t.ifExpression(&e.IfExpression); changed {
+			c := *e
+			c.IfExpression = ie
+			r = &c
+		}
+	case *VariableExpression:
+		if u, changed := t.unary(&e.unaryExpression); changed {
+			c := *e
+			c.unaryExpression = u
+			r = &c
+		}
+	case *VirtualQuery:
+		if expr, changed := t.expr(e.expr); changed {
+			c := *e
+			c.expr = expr
+			r = &c
+		}
+	}
+	r = t.f(r)
+	if d, ok := e.(Definition); ok && r != e {
+		t.definitions[d] = r
+	}
+	return r
+}
+
+// expr transforms the given expression, which may be nil, and returns the result together with a
+// boolean that is true if the result differs from the given expression
+func (t *transformer) expr(e Expression) (Expression, bool) {
+	if e == nil {
+		return nil, false
+	}
+	r := t.transform(e)
+	return r, r != e
+}
+
+// exprs transforms the given expressions. The given slice is returned unless an expression was replaced.
+func (t *transformer) exprs(es []Expression) ([]Expression, bool) {
+	var r []Expression
+	for i, e := range es {
+		if n, changed := t.expr(e); changed {
+			if r == nil {
+				r = make([]Expression, len(es))
+				copy(r, es)
+			}
+			r[i] = n
+		}
+	}
+	if r == nil {
+		return es, false
+	}
+	return r, true
+}
+
+func (t *transformer) binary(e *binaryExpression) (binaryExpression, bool) {
+	lhs, c1 := t.expr(e.lhs)
+	rhs, c2 := t.expr(e.rhs)
+	c := *e
+	c.lhs, c.rhs = lhs, rhs
+	return c, c1 || c2
+}
+
+func (t *transformer) unary(e *unaryExpression) (unaryExpression, bool) {
+	expr, changed := t.expr(e.expr)
+	c := *e
+	c.expr = expr
+	return c, changed
+}
+
+func (t *transformer) call(e *callExpression) (callExpression, bool) {
+	functor, c1 := t.expr(e.functor)
+	arguments, c2 := t.exprs(e.arguments)
+	lambda, c3 := t.expr(e.lambda)
+	c := *e
+	c.functor, c.arguments, c.lambda = functor, arguments, lambda
+	return c, c1 || c2 || c3
+}
+
+func (t *transformer) namedDefinition(e *namedDefinition) (namedDefinition, bool) {
+	parameters, c1 := t.exprs(e.parameters)
+	body, c2 := t.expr(e.body)
+	c := *e
+	c.parameters, c.body = parameters, body
+	return c, c1 || c2
+}
+
+func (t *transformer) functionDefinition(e *FunctionDefinition) (FunctionDefinition, bool) {
+	d, c1 := t.namedDefinition(&e.namedDefinition)
+	returnType, c2 := t.expr(e.returnType)
+	c := *e
+	c.namedDefinition, c.returnType = d, returnType
+	return c, c1 || c2
+}
+
+func (t *transformer) ifExpression(e *IfExpression) (IfExpression, bool) {
+	test, c1 := t.expr(e.test)
+	then, c2 := t.expr(e.then)
+	elseExpr, c3 := t.expr(e.elseExpr)
+	c := *e
+	c.test, c.then, c.elseExpr = test, then, elseExpr
+	return c, c1 || c2 || c3
+}
@@ -0,0 +1,24 @@
+package ast
+
+//go:generate go run ./internal/visitorgen
+
+// DefaultVisitor implements all methods of Visitor by calling Fallback with the visited expression.
+// Nothing happens when Fallback is nil.
+type DefaultVisitor struct {
+	Fallback func(e Expression)
+}
+
+// Walk calls Visit for the given expression and then for all its contents, in the order that they
+// are visited by AllContents
+func Walk(v Visitor, e Expression) {
+	Visit(v, e)
+	e.AllContents(nil, func(path []Expression, e Expression) {
+		Visit(v, e)
+	})
+}
+
+func (v DefaultVisitor) fallback(e Expression) {
+	if v.Fallback != nil {
+		v.Fallback(e)
+	}
+}
@@ -0,0 +1,504 @@
+// Code generated by visitorgen. DO NOT EDIT.
+
+package ast
+
+// Visitor is implemented by types that handle each kind of expression using a method of its own.
+// Embed DefaultVisitor in an implementation to only implement the methods of interest.
+type Visitor interface {
+	VisitAccessExpression(e *AccessExpression)
+	VisitActivityExpression(e *ActivityExpression)
+	VisitAndExpression(e *AndExpression)
+	VisitApplication(e *Application)
+	VisitArithmeticExpression(e *ArithmeticExpression)
+	VisitAssignmentExpression(e *AssignmentExpression)
+	VisitAttributeOperation(e *AttributeOperation)
+	VisitAttributesOperation(e *AttributesOperation)
+	VisitBlockExpression(e *BlockExpression)
+	VisitCallFunctionExpression(e *CallFunctionExpression)
+	VisitCallMethodExpression(e *CallMethodExpression)
+	VisitCallNamedFunctionExpression(e *CallNamedFunctionExpression)
+	VisitCapabilityMapping(e *CapabilityMapping)
+	VisitCaseExpression(e *CaseExpression)
+	VisitCaseOption(e *CaseOption)
+	VisitCollectExpression(e *CollectExpression)
+	VisitComparisonExpression(e *ComparisonExpression)
+	VisitConcatenatedString(e *ConcatenatedString)
+	VisitEppExpression(e *EppExpression)
+	VisitExportedQuery(e *ExportedQuery)
+	VisitFunctionDefinition(e *FunctionDefinition)
+	VisitHeredocExpression(e *HeredocExpression)
+	VisitHostClassDefinition(e *HostClassDefinition)
+	VisitIfExpression(e *IfExpression)
+	VisitImportExpression(e *ImportExpression)
+	VisitInExpression(e *InExpression)
+	VisitKeyedEntry(e *KeyedEntry)
+	VisitLambdaExpression(e *LambdaExpression)
+	VisitLiteralBoolean(e *LiteralBoolean)
+	VisitLiteralDefault(e *LiteralDefault)
+	VisitLiteralFloat(e *LiteralFloat)
+	VisitLiteralHash(e *LiteralHash)
+	VisitLiteralInteger(e *LiteralInteger)
+	VisitLiteralList(e *LiteralList)
+	VisitLiteralString(e *LiteralString)
+	VisitLiteralUndef(e *LiteralUndef)
+	VisitMatchExpression(e *MatchExpression)
+	VisitNamedAccessExpression(e *NamedAccessExpression)
+	VisitNodeDefinition(e *NodeDefinition)
+	VisitNop(e *Nop)
+	VisitNotExpression(e *NotExpression)
+	VisitOrExpression(e *OrExpression)
+	VisitParameter(e *Parameter)
+	VisitParenthesizedExpression(e *ParenthesizedExpression)
+	VisitPlanDefinition(e *PlanDefinition)
+	VisitProgram(e *Program)
+	VisitQualifiedName(e *QualifiedName)
+	VisitQualifiedReference(e *QualifiedReference)
+	VisitRegexpExpression(e *RegexpExpression)
+	VisitRelationshipExpression(e *RelationshipExpression)
+	VisitRenderExpression(e *RenderExpression)
+	VisitRenderStringExpression(e *RenderStringExpression)
+	VisitReservedWord(e *ReservedWord)
+	VisitResourceBody(e *ResourceBody)
+	VisitResourceDefaultsExpression(e *ResourceDefaultsExpression)
+	VisitResourceExpression(e *ResourceExpression)
+	VisitResourceOverrideExpression(e *ResourceOverrideExpression)
+	VisitResourceTypeDefinition(e *ResourceTypeDefinition)
+	VisitSelectorEntry(e *SelectorEntry)
+	VisitSelectorExpression(e *SelectorExpression)
+	VisitSiteDefinition(e *SiteDefinition)
+	VisitTextExpression(e *TextExpression)
+	VisitTypeAlias(e *TypeAlias)
+	VisitTypeDefinition(e *TypeDefinition)
+	VisitTypeMapping(e *TypeMapping)
+	VisitUnaryMinusExpression(e *UnaryMinusExpression)
+	VisitUnfoldExpression(e *UnfoldExpression)
+	VisitUnlessExpression(e *UnlessExpression)
+	VisitVariableExpression(e *VariableExpression)
+	VisitVirtualQuery(e *VirtualQuery)
+}
+
+func (v DefaultVisitor) VisitAccessExpression(e *AccessExpression) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitActivityExpression(e *ActivityExpression) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitAndExpression(e *AndExpression) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitApplication(e *Application) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitArithmeticExpression(e *ArithmeticExpression) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitAssignmentExpression(e *AssignmentExpression) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitAttributeOperation(e *AttributeOperation) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitAttributesOperation(e *AttributesOperation) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitBlockExpression(e *BlockExpression) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitCallFunctionExpression(e *CallFunctionExpression) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitCallMethodExpression(e *CallMethodExpression) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitCallNamedFunctionExpression(e *CallNamedFunctionExpression) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitCapabilityMapping(e *CapabilityMapping) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitCaseExpression(e *CaseExpression) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitCaseOption(e *CaseOption) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitCollectExpression(e *CollectExpression) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitComparisonExpression(e *ComparisonExpression) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitConcatenatedString(e *ConcatenatedString) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitEppExpression(e *EppExpression) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitExportedQuery(e *ExportedQuery) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitFunctionDefinition(e *FunctionDefinition) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitHeredocExpression(e *HeredocExpression) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitHostClassDefinition(e *HostClassDefinition) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitIfExpression(e *IfExpression) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitImportExpression(e *ImportExpression) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitInExpression(e *InExpression) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitKeyedEntry(e *KeyedEntry) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitLambdaExpression(e *LambdaExpression) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitLiteralBoolean(e *LiteralBoolean) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitLiteralDefault(e *LiteralDefault) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitLiteralFloat(e *LiteralFloat) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitLiteralHash(e *LiteralHash) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitLiteralInteger(e *LiteralInteger) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitLiteralList(e *LiteralList) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitLiteralString(e *LiteralString) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitLiteralUndef(e *LiteralUndef) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitMatchExpression(e *MatchExpression) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitNamedAccessExpression(e *NamedAccessExpression) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitNodeDefinition(e *NodeDefinition) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitNop(e *Nop) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitNotExpression(e *NotExpression) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitOrExpression(e *OrExpression) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitParameter(e *Parameter) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitParenthesizedExpression(e *ParenthesizedExpression) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitPlanDefinition(e *PlanDefinition) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitProgram(e *Program) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitQualifiedName(e *QualifiedName) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitQualifiedReference(e *QualifiedReference) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitRegexpExpression(e *RegexpExpression) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitRelationshipExpression(e *RelationshipExpression) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitRenderExpression(e *RenderExpression) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitRenderStringExpression(e *RenderStringExpression) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitReservedWord(e *ReservedWord) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitResourceBody(e *ResourceBody) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitResourceDefaultsExpression(e *ResourceDefaultsExpression) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitResourceExpression(e *ResourceExpression) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitResourceOverrideExpression(e *ResourceOverrideExpression) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitResourceTypeDefinition(e *ResourceTypeDefinition) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitSelectorEntry(e *SelectorEntry) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitSelectorExpression(e *SelectorExpression) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitSiteDefinition(e *SiteDefinition) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitTextExpression(e *TextExpression) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitTypeAlias(e *TypeAlias) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitTypeDefinition(e *TypeDefinition) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitTypeMapping(e *TypeMapping) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitUnaryMinusExpression(e *UnaryMinusExpression) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitUnfoldExpression(e *UnfoldExpression) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitUnlessExpression(e *UnlessExpression) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitVariableExpression(e *VariableExpression) {
+	v.fallback(e)
+}
+
+func (v DefaultVisitor) VisitVirtualQuery(e *VirtualQuery) {
+	v.fallback(e)
+}
+
+// Visit calls the method of the given visitor that corresponds to the kind of the given expression
+func Visit(v Visitor, e Expression) {
+	switch e := e.(type) {
+	case *AccessExpression:
+		v.VisitAccessExpression(e)
+	case *ActivityExpression:
+		v.VisitActivityExpression(e)
+	case *AndExpression:
+		v.VisitAndExpression(e)
+	case *Application:
+		v.VisitApplication(e)
+	case *ArithmeticExpression:
+		v.VisitArithmeticExpression(e)
+	case *AssignmentExpression:
+		v.VisitAssignmentExpression(e)
+	case *AttributeOperation:
+		v.VisitAttributeOperation(e)
+	case *AttributesOperation:
+		v.VisitAttributesOperation(e)
+	case *BlockExpression:
+		v.VisitBlockExpression(e)
+	case *CallFunctionExpression:
+		v.VisitCallFunctionExpression(e)
+	case *CallMethodExpression:
+		v.VisitCallMethodExpression(e)
+	case *CallNamedFunctionExpression:
+		v.VisitCallNamedFunctionExpression(e)
+	case *CapabilityMapping:
+		v.VisitCapabilityMapping(e)
+	case *CaseExpression:
+		v.VisitCaseExpression(e)
+	case *CaseOption:
+		v.VisitCaseOption(e)
+	case *CollectExpression:
+		v.VisitCollectExpression(e)
+	case *ComparisonExpression:
+		v.VisitComparisonExpression(e)
+	case *ConcatenatedString:
+		v.VisitConcatenatedString(e)
+	case *EppExpression:
+		v.VisitEppExpression(e)
+	case *ExportedQuery:
+		v.VisitExportedQuery(e)
+	case *FunctionDefinition:
+		v.VisitFunctionDefinition(e)
+	case *HeredocExpression:
+		v.VisitHeredocExpression(e)
+	case *HostClassDefinition:
+		v.VisitHostClassDefinition(e)
+	case *IfExpression:
+		v.VisitIfExpression(e)
+	case *ImportExpression:
+		v.VisitImportExpression(e)
+	case *InExpression:
+		v.VisitInExpression(e)
+	case *KeyedEntry:
+		v.VisitKeyedEntry(e)
+	case *LambdaExpression:
+		v.VisitLambdaExpression(e)
+	case *LiteralBoolean:
+		v.VisitLiteralBoolean(e)
+	case *LiteralDefault:
+		v.VisitLiteralDefault(e)
+	case *LiteralFloat:
+		v.VisitLiteralFloat(e)
+	case *LiteralHash:
+		v.VisitLiteralHash(e)
+	case *LiteralInteger:
+		v.VisitLiteralInteger(e)
+	case *LiteralList:
+		v.VisitLiteralList(e)
+	case *LiteralString:
+		v.VisitLiteralString(e)
+	case *LiteralUndef:
+		v.VisitLiteralUndef(e)
+	case *MatchExpression:
+		v.VisitMatchExpression(e)
+	case *NamedAccessExpression:
+		v.VisitNamedAccessExpression(e)
+	case *NodeDefinition:
+		v.VisitNodeDefinition(e)
+	case *Nop:
+		v.VisitNop(e)
+	case *NotExpression:
+		v.VisitNotExpression(e)
+	case *OrExpression:
+		v.VisitOrExpression(e)
+	case *Parameter:
+		v.VisitParameter(e)
+	case *ParenthesizedExpression:
+		v.VisitParenthesizedExpression(e)
+	case *PlanDefinition:
+		v.VisitPlanDefinition(e)
+	case *Program:
+		v.VisitProgram(e)
+	case *QualifiedName:
+		v.VisitQualifiedName(e)
+	case *QualifiedReference:
+		v.VisitQualifiedReference(e)
+	case *RegexpExpression:
+		v.VisitRegexpExpression(e)
+	case *RelationshipExpression:
+		v.VisitRelationshipExpression(e)
+	case *RenderExpression:
+		v.VisitRenderExpression(e)
+	case *RenderStringExpression:
+		v.VisitRenderStringExpression(e)
+	case *ReservedWord:
+		v.VisitReservedWord(e)
+	case *ResourceBody:
+		v.VisitResourceBody(e)
+	case *ResourceDefaultsExpression:
+		v.VisitResourceDefaultsExpression(e)
+	case *ResourceExpression:
+		v.VisitResourceExpression(e)
+	case *ResourceOverrideExpression:
+		v.VisitResourceOverrideExpression(e)
+	case *ResourceTypeDefinition:
+		v.VisitResourceTypeDefinition(e)
+	case *SelectorEntry:
+		v.VisitSelectorEntry(e)
+	case *SelectorExpression:
+		v.VisitSelectorExpression(e)
+	case *SiteDefinition:
+		v.VisitSiteDefinition(e)
+	case *TextExpression:
+		v.VisitTextExpression(e)
+	case *TypeAlias:
+		v.VisitTypeAlias(e)
+	case *TypeDefinition:
+		v.VisitTypeDefinition(e)
+	case *TypeMapping:
+		v.VisitTypeMapping(e)
+	case *UnaryMinusExpression:
+		v.VisitUnaryMinusExpression(e)
+	case *UnfoldExpression:
+		v.VisitUnfoldExpression(e)
+	case *UnlessExpression:
+		v.VisitUnlessExpression(e)
+	case *VariableExpression:
+		v.VisitVariableExpression(e)
+	case *VirtualQuery:
+		v.VisitVirtualQuery(e)
+	}
+}
@@ -1,4 +1,4 @@
-package parser
+package ast
 
 import "github.com/lyraproj/puppet-parser/pn"
 
@@ -51,10 +51,10 @@ func (e *ActivityExpression) ToPN() pn.PN {
 		pn.Literal(string(e.style)).WithName(`style`)}
 
 	if e.properties != nil {
-		entries = append(entries, e.properties.ToPN().WithName(`properties`))
+		entries = append(entries, pnOf(e.properties).WithName(`properties`))
 	}
 	if e.definition != nil {
-		entries = append(entries, e.definition.ToPN().WithName(`definition`))
+		entries = append(entries, pnOf(e.definition).WithName(`definition`))
 	}
 	return pn.Map(entries).AsCall(`activity`)
 }
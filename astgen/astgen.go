@@ -0,0 +1,430 @@
+// Package astgen generates random ASTs for property based testing of code that consumes or produces
+// Puppet ASTs. The generated trees are valid in the sense that the printer produces source for them
+// that parses into equal trees, which RoundTrip verifies.
+//
+// A typical property test generates trees from a range of seeds so that failures are reproducible:
+//
+//	for seed := int64(0); seed < 1000; seed++ {
+//	  program := astgen.NewGenerator(rand.New(rand.NewSource(seed)), astgen.Config{}).Program()
+//	  if err := astgen.RoundTrip(program); err != nil {
+//	    t.Fatalf("seed %d: %s", seed, err)
+//	  }
+//	}
+package astgen
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"github.com/lyraproj/puppet-parser/parser"
+	"github.com/lyraproj/puppet-parser/printer"
+)
+
+// Kind is a kind of expression that the generator can produce
+type Kind int
+
+const (
+	INTEGER = Kind(iota)
+	FLOAT
+	STRING
+	INTERPOLATED_STRING
+	BOOLEAN
+	UNDEF
+	VARIABLE
+	TYPE_REFERENCE
+	ARRAY
+	HASH
+	ACCESS
+	ARITHMETIC
+	COMPARISON
+	LOGICAL
+	NOT
+	NEGATE
+	MATCH
+	IN
+	SELECTOR
+	CALL
+	METHOD_CALL
+
+	// DEFAULT is only produced as a selector or case option since the lexer treats a '/' that follows
+	// 'default' as the start of a regular expression
+	DEFAULT
+
+	// Kinds that are only produced as statements
+	ASSIGNMENT
+	IF
+	UNLESS
+	CASE
+	RESOURCE_DEFAULTS
+	RESOURCE_OVERRIDE
+
+	// CLASS is only produced as a statement of the program or of the body of another class
+	CLASS
+)
+
+var leafKinds = []Kind{INTEGER, FLOAT, STRING, BOOLEAN, UNDEF, VARIABLE, TYPE_REFERENCE}
+
+var expressionKinds = []Kind{
+	INTEGER, FLOAT, STRING, INTERPOLATED_STRING, BOOLEAN, UNDEF, VARIABLE, TYPE_REFERENCE,
+	ARRAY, HASH, ACCESS, ARITHMETIC, COMPARISON, LOGICAL, NOT, NEGATE, MATCH, IN, SELECTOR, CALL, METHOD_CALL,
+}
+
+var statementKinds = []Kind{ASSIGNMENT, CALL, METHOD_CALL, IF, UNLESS, CASE, RESOURCE_DEFAULTS, RESOURCE_OVERRIDE}
+
+var leafStatementKinds = []Kind{ASSIGNMENT, CALL, METHOD_CALL, RESOURCE_DEFAULTS, RESOURCE_OVERRIDE}
+
+// DefaultWeights are the weights used when Config.Weights is nil
+var DefaultWeights = map[Kind]int{
+	INTEGER:             4,
+	FLOAT:               1,
+	STRING:              4,
+	INTERPOLATED_STRING: 1,
+	BOOLEAN:             1,
+	UNDEF:               1,
+	DEFAULT:             1,
+	VARIABLE:            4,
+	TYPE_REFERENCE:      1,
+	ARRAY:               2,
+	HASH:                2,
+	ACCESS:              2,
+	ARITHMETIC:          3,
+	COMPARISON:          2,
+	LOGICAL:             2,
+	NOT:                 1,
+	NEGATE:              1,
+	MATCH:               1,
+	IN:                  1,
+	SELECTOR:            1,
+	CALL:                2,
+	METHOD_CALL:         1,
+	ASSIGNMENT:          4,
+	IF:                  1,
+	UNLESS:              1,
+	CASE:                1,
+	RESOURCE_DEFAULTS:   1,
+	RESOURCE_OVERRIDE:   1,
+	CLASS:               1,
+}
+
+// Config controls the shape of the generated trees
+type Config struct {
+	// MaxDepth is the maximum nesting depth of expressions. Only literals and variables are produced at
+	// the maximum depth. The default is 5.
+	MaxDepth int
+
+	// MaxStatements is the maximum number of statements in a program. Nested blocks have at most two
+	// statements. The default is 5.
+	MaxStatements int
+
+	// Weights are the relative frequencies of the kinds of expressions. Kinds that are missing have
+	// weight zero and are only produced when all alternatives have weight zero. DefaultWeights are
+	// used when Weights is nil.
+	Weights map[Kind]int
+}
+
+// Generator produces random expressions
+type Generator struct {
+	rnd     *rand.Rand
+	config  Config
+	factory parser.ExpressionFactory
+	locator *parser.Locator
+
+	// classes are the names of the classes that enclose the statement being generated
+	classes []string
+}
+
+var names = []string{`a`, `b`, `x`, `y`, `foo`, `bar::baz`}
+var functions = []string{`notice`, `size`, `join`, `f`, `mymod::g`}
+var types = []string{`Integer`, `String`, `Array`, `Hash`, `Foo::Bar`}
+var classNames = []string{`a`, `b`, `mymod::c`}
+var attributes = []string{`ensure`, `mode`, `owner`, `require`}
+var forms = []parser.ResourceForm{parser.REGULAR, parser.VIRTUAL, parser.EXPORTED}
+var stringChars = []rune("abc xyz-'\"\\$%{}\n\tå")
+
+// NewGenerator creates a generator that uses the given source of randomness. Generators created with
+// sources that have the same seed produce the same trees.
+func NewGenerator(rnd *rand.Rand, config Config) *Generator {
+	if config.MaxDepth <= 0 {
+		config.MaxDepth = 5
+	}
+	if config.MaxStatements <= 0 {
+		config.MaxStatements = 5
+	}
+	return &Generator{rnd: rnd, config: config, factory: parser.DefaultFactory(), locator: parser.NewSyntheticLocator(`astgen`, nil)}
+}
+
+// Program returns a random program with at least one statement
+func (g *Generator) Program() *parser.Program {
+	return g.factory.Program(g.statements(0, true), nil, g.locator, 0, 0).(*parser.Program)
+}
+
+// Expression returns a random expression that produces a value
+func (g *Generator) Expression() parser.Expression {
+	return g.expression(0)
+}
+
+// RoundTrip prints the given expression, parses the printed source using the given parser options,
+// and returns an error unless the parsed tree is equal to the given tree. Parenthesized expressions
+// are ignored in the comparison since the printer adds them where needed to retain the structure.
+func RoundTrip(e parser.Expression, parserOptions ...parser.Option) error {
+	source := printer.String(e)
+	parsed, err := parser.CreateParser(parserOptions...).Parse(``, source, true)
+	if _, ok := e.(*parser.Program); ok {
+		parsed, err = parser.CreateParser(parserOptions...).Parse(``, source, false)
+	}
+	if err != nil {
+		return fmt.Errorf("printed source does not parse: %s\nsource:\n%s", err.Error(), source)
+	}
+	expected := parser.Normalize(e, parser.NORMALIZE_STRIP_PARENTHESES).ToPN().String()
+	actual := parser.Normalize(parsed, parser.NORMALIZE_STRIP_PARENTHESES).ToPN().String()
+	if expected != actual {
+		return errors.New(fmt.Sprintf("parsed tree differs from printed tree\nsource:\n%s\nexpected:\n%s\ngot:\n%s", source, expected, actual))
+	}
+	return nil
+}
+
+func (g *Generator) block(depth int) parser.Expression {
+	return g.statements(depth, false)
+}
+
+// statements returns a block of random statements. Classes are only produced when definitions is true.
+func (g *Generator) statements(depth int, definitions bool) parser.Expression {
+	// Nested blocks are kept small to bound the size of the tree
+	max := g.config.MaxStatements
+	if depth > 0 {
+		max = min(max, 2)
+	}
+	n := 1 + g.rnd.Intn(max)
+	stmts := make([]parser.Expression, n)
+	for i := range stmts {
+		stmts[i] = g.statement(depth, definitions)
+	}
+	return g.factory.Block(stmts, g.locator, 0, 0)
+}
+
+func (g *Generator) statement(depth int, definitions bool) parser.Expression {
+	f, l := g.factory, g.locator
+	kinds := statementKinds
+	if depth+1 >= g.config.MaxDepth {
+		kinds = leafStatementKinds
+	} else if definitions {
+		kinds = append(kinds[:len(kinds):len(kinds)], CLASS)
+	}
+	switch g.pick(kinds) {
+	case CALL:
+		return g.call(depth, false)
+	case METHOD_CALL:
+		return g.methodCall(depth)
+	case IF:
+		return f.If(g.expression(depth+1), g.block(depth+1), g.elsePart(depth), l, 0, 0)
+	case UNLESS:
+		return f.Unless(g.expression(depth+1), g.block(depth+1), g.elsePart(depth), l, 0, 0)
+	case CASE:
+		options := make([]parser.Expression, 1+g.rnd.Intn(3))
+		for i := range options {
+			values := g.expressions(depth+1, 1, 3)
+			values[0] = g.option(depth + 1)
+			options[i] = f.When(values, g.block(depth+1), l, 0, 0)
+		}
+		return f.Case(g.expression(depth+1), options, l, 0, 0)
+	case RESOURCE_DEFAULTS:
+		typeRef := f.QualifiedReference(types[g.rnd.Intn(len(types))], l, 0, 0)
+		return f.ResourceDefaults(forms[g.rnd.Intn(len(forms))], typeRef, g.attributeOps(depth, `=>`), l, 0, 0)
+	case RESOURCE_OVERRIDE:
+		ref := f.Access(f.QualifiedReference(types[g.rnd.Intn(len(types))], l, 0, 0), []parser.Expression{f.String(g.text(), l, 0, 0)}, l, 0, 0)
+		return f.ResourceOverride(forms[g.rnd.Intn(len(forms))], ref, g.attributeOps(depth, `=>`, `+>`), l, 0, 0)
+	case CLASS:
+		return g.class(depth)
+	default:
+		return f.Assignment(`=`, g.variable(), g.expression(depth+1), l, 0, 0)
+	}
+}
+
+// class returns a class with a name that is qualified with the names of the enclosing classes in the
+// same way as the parser qualifies the name of a nested class
+func (g *Generator) class(depth int) parser.Expression {
+	name := classNames[g.rnd.Intn(len(classNames))]
+	qualified := parser.ResolveRelativeName(strings.Join(g.classes, `::`), name)
+	g.classes = append(g.classes, name)
+	body := g.statements(depth+1, true)
+	g.classes = g.classes[:len(g.classes)-1]
+	return g.factory.Class(qualified, []parser.Expression{}, ``, body, g.locator, 0, 0)
+}
+
+func (g *Generator) attributeOps(depth int, ops ...string) []parser.Expression {
+	result := make([]parser.Expression, 1+g.rnd.Intn(2))
+	for i := range result {
+		result[i] = g.factory.AttributeOp(g.operator(ops...), attributes[g.rnd.Intn(len(attributes))], g.expression(depth+1), g.locator, 0, 0)
+	}
+	return result
+}
+
+func (g *Generator) elsePart(depth int) parser.Expression {
+	if depth+1 < g.config.MaxDepth && g.rnd.Intn(2) == 0 {
+		return g.block(depth + 1)
+	}
+	return g.factory.Nop(g.locator, 0, 0)
+}
+
+func (g *Generator) expression(depth int) parser.Expression {
+	f, l := g.factory, g.locator
+	kinds := expressionKinds
+	if depth >= g.config.MaxDepth {
+		kinds = leafKinds
+	}
+	switch g.pick(kinds) {
+	case INTEGER:
+		return f.Integer(int64(g.rnd.Intn(1000)), 10, l, 0, 0)
+	case FLOAT:
+		return f.Float(float64(g.rnd.Intn(400))/4+0.25, l, 0, 0)
+	case STRING:
+		return f.String(g.text(), l, 0, 0)
+	case INTERPOLATED_STRING:
+		return g.interpolatedString(depth)
+	case BOOLEAN:
+		return f.Boolean(g.rnd.Intn(2) == 0, l, 0, 0)
+	case UNDEF:
+		return f.Undef(l, 0, 0)
+	case TYPE_REFERENCE:
+		return f.QualifiedReference(types[g.rnd.Intn(len(types))], l, 0, 0)
+	case ARRAY:
+		return f.Array(g.expressions(depth+1, 0, 4), l, 0, 0)
+	case HASH:
+		entries := make([]parser.Expression, g.rnd.Intn(4))
+		for i := range entries {
+			entries[i] = f.KeyedEntry(f.String(g.text(), l, 0, 0), g.expression(depth+1), l, 0, 0)
+		}
+		return f.Hash(entries, l, 0, 0)
+	case ACCESS:
+		var operand parser.Expression
+		if g.rnd.Intn(2) == 0 {
+			operand = g.variable()
+		} else {
+			operand = f.QualifiedReference(types[g.rnd.Intn(len(types))], l, 0, 0)
+		}
+		return f.Access(operand, g.expressions(depth+1, 1, 2), l, 0, 0)
+	case ARITHMETIC:
+		return f.Arithmetic(g.operator(`+`, `-`, `*`, `/`, `%`, `<<`, `>>`), g.expression(depth+1), g.expression(depth+1), l, 0, 0)
+	case COMPARISON:
+		return f.Comparison(g.operator(`==`, `!=`, `<`, `>`, `<=`, `>=`), g.expression(depth+1), g.expression(depth+1), l, 0, 0)
+	case LOGICAL:
+		if g.rnd.Intn(2) == 0 {
+			return f.And(g.expression(depth+1), g.expression(depth+1), l, 0, 0)
+		}
+		return f.Or(g.expression(depth+1), g.expression(depth+1), l, 0, 0)
+	case NOT:
+		return f.Not(g.expression(depth+1), l, 0, 0)
+	case NEGATE:
+		return f.Negate(g.variable(), l, 0, 0)
+	case MATCH:
+		return f.Match(g.operator(`=~`, `!~`), g.expression(depth+1), f.Regexp(`^a.*b$`, l, 0, 0), l, 0, 0)
+	case IN:
+		return f.In(g.expression(depth+1), g.expression(depth+1), l, 0, 0)
+	case SELECTOR:
+		entries := make([]parser.Expression, 1+g.rnd.Intn(3))
+		for i := range entries {
+			entries[i] = f.Selector(g.option(depth+1), g.expression(depth+1), l, 0, 0)
+		}
+		return f.Select(g.variable(), entries, l, 0, 0)
+	case CALL:
+		return g.call(depth, true)
+	case METHOD_CALL:
+		return g.methodCall(depth)
+	default:
+		return g.variable()
+	}
+}
+
+// option returns an expression or, based on the weight of DEFAULT, a default
+func (g *Generator) option(depth int) parser.Expression {
+	if w := g.weight(DEFAULT); w > 0 && g.rnd.Intn(w+g.weight(INTEGER)+g.weight(STRING)+g.weight(VARIABLE)) < w {
+		return g.factory.Default(g.locator, 0, 0)
+	}
+	return g.expression(depth)
+}
+
+func (g *Generator) expressions(depth, min, max int) []parser.Expression {
+	exprs := make([]parser.Expression, min+g.rnd.Intn(max-min+1))
+	for i := range exprs {
+		exprs[i] = g.expression(depth)
+	}
+	return exprs
+}
+
+// call returns a call to a named function. A call that is a statement doesn't require a value.
+func (g *Generator) call(depth int, rvalRequired bool) parser.Expression {
+	f, l := g.factory, g.locator
+	name := f.QualifiedName(functions[g.rnd.Intn(len(functions))], l, 0, 0)
+	return f.CallNamed(name, rvalRequired, g.expressions(depth+1, 0, 3), nil, l, 0, 0)
+}
+
+func (g *Generator) methodCall(depth int) parser.Expression {
+	f, l := g.factory, g.locator
+	name := f.QualifiedName(functions[g.rnd.Intn(len(functions)-1)], l, 0, 0)
+	return f.CallMethod(f.NamedAccess(g.variable(), name, l, 0, 0), g.expressions(depth+1, 0, 2), nil, l, 0, 0)
+}
+
+func (g *Generator) interpolatedString(depth int) parser.Expression {
+	f, l := g.factory, g.locator
+	n := 1 + g.rnd.Intn(3)
+	segments := make([]parser.Expression, 0, 2*n+1)
+	for i := 0; i < n; i++ {
+		segments = append(segments, f.String(g.nonEmptyText(), l, 0, 0), f.Text(g.variable(), l, 0, 0))
+	}
+	if g.rnd.Intn(2) == 0 {
+		segments = append(segments, f.String(g.nonEmptyText(), l, 0, 0))
+	}
+	return f.ConcatenatedString(segments, l, 0, 0)
+}
+
+func (g *Generator) variable() parser.Expression {
+	f, l := g.factory, g.locator
+	return f.Variable(f.QualifiedName(names[g.rnd.Intn(len(names))], l, 0, 0), l, 0, 0)
+}
+
+func (g *Generator) text() string {
+	b := strings.Builder{}
+	for n := g.rnd.Intn(8); n > 0; n-- {
+		b.WriteRune(stringChars[g.rnd.Intn(len(stringChars))])
+	}
+	return b.String()
+}
+
+func (g *Generator) nonEmptyText() string {
+	for {
+		if s := g.text(); s != `` {
+			return s
+		}
+	}
+}
+
+func (g *Generator) operator(ops ...string) string {
+	return ops[g.rnd.Intn(len(ops))]
+}
+
+// pick returns a random kind among the given kinds using the configured weights
+func (g *Generator) pick(kinds []Kind) Kind {
+	total := 0
+	for _, k := range kinds {
+		total += g.weight(k)
+	}
+	if total == 0 {
+		return kinds[0]
+	}
+	n := g.rnd.Intn(total)
+	for _, k := range kinds {
+		if n -= g.weight(k); n < 0 {
+			return k
+		}
+	}
+	return kinds[len(kinds)-1]
+}
+
+func (g *Generator) weight(k Kind) int {
+	if g.config.Weights == nil {
+		return DefaultWeights[k]
+	}
+	return g.config.Weights[k]
+}
@@ -0,0 +1,49 @@
+package astgen
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/lyraproj/puppet-parser/pn"
+	"github.com/lyraproj/puppet-parser/printer"
+)
+
+func TestRoundTrip(t *testing.T) {
+	for seed := int64(0); seed < 500; seed++ {
+		program := NewGenerator(rand.New(rand.NewSource(seed)), Config{}).Program()
+		if err := RoundTrip(program); err != nil {
+			t.Fatalf("seed %d: %s", seed, err)
+		}
+	}
+}
+
+func TestPNConformsToSchema(t *testing.T) {
+	for seed := int64(0); seed < 100; seed++ {
+		program := NewGenerator(rand.New(rand.NewSource(seed)), Config{}).Program()
+		if err := pn.ValidateData(pn.ToDataWith(program.ToPN())); err != nil {
+			t.Fatalf("seed %d: %s", seed, err)
+		}
+	}
+}
+
+func TestGeneratorIsDeterministic(t *testing.T) {
+	a := printer.String(NewGenerator(rand.New(rand.NewSource(42)), Config{}).Program())
+	b := printer.String(NewGenerator(rand.New(rand.NewSource(42)), Config{}).Program())
+	if a != b {
+		t.Errorf("expected equal programs, got:\n%s\nand:\n%s", a, b)
+	}
+}
+
+func TestWeights(t *testing.T) {
+	only := map[Kind]int{ASSIGNMENT: 1, INTEGER: 1}
+	source := printer.String(NewGenerator(rand.New(rand.NewSource(1)), Config{MaxStatements: 1, Weights: only}).Program())
+	if len(source) < 5 || source[0] != '$' {
+		t.Errorf("expected an assignment of an integer, got %s", source)
+	}
+	for _, c := range source[:len(source)-1] {
+		if !(c == '$' || c == ' ' || c == '=' || c >= 'a' && c <= 'z' || c == ':' || c >= '0' && c <= '9') {
+			t.Errorf("expected an assignment of an integer, got %s", source)
+			break
+		}
+	}
+}
@@ -0,0 +1,490 @@
+// Package builder provides a fluent API for creating an AST programmatically, e.g.
+//
+//	b := builder.New()
+//	class := b.Class(`nginx`).Param(`port`, b.Int(80)).Body(
+//	  b.Resource(`package`, `nginx`, b.Attr(`ensure`, b.Name(`installed`))))
+//
+// The expressions are created by the parser.ExpressionFactory of the Builder. Since they aren't
+// parsed from source, they are given synthetic positions. All expressions created by the same
+// Builder share a Locator that has the file name given to the Builder and an empty source. The
+// printer package is used to produce Puppet source from the created expressions.
+package builder
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+type (
+	// Builder creates expressions with synthetic positions
+	Builder struct {
+		factory parser.ExpressionFactory
+		locator *parser.Locator
+	}
+
+	// DefinitionBuilder collects the parts of a class, define, function, plan, or lambda. The
+	// definition is created by a call to Body.
+	DefinitionBuilder struct {
+		b          *Builder
+		keyword    string
+		name       string
+		params     []parser.Expression
+		parent     string
+		returnType parser.Expression
+	}
+)
+
+// New creates a Builder that uses the default expression factory and a Locator with an empty
+// file name
+func New() *Builder {
+	return NewWithFactory(parser.DefaultFactory(), ``)
+}
+
+// NewWithFactory creates a Builder that uses the given expression factory. The given file name
+// is used by the Locator that is shared by all created expressions.
+func NewWithFactory(factory parser.ExpressionFactory, file string) *Builder {
+	return &Builder{factory: factory, locator: parser.NewLocator(file, ``)}
+}
+
+// Locator returns the Locator that is shared by all expressions created by this Builder
+func (b *Builder) Locator() *parser.Locator {
+	return b.locator
+}
+
+// Program creates a Program with the given statements. The definitions of the program are the
+// definitions found among the statements and the expressions that they contain.
+func (b *Builder) Program(statements ...parser.Expression) *parser.Program {
+	definitions := make([]parser.Definition, 0)
+	collect := func(path []parser.Expression, e parser.Expression) {
+		if d, ok := e.(parser.Definition); ok {
+			definitions = append(definitions, d)
+		}
+	}
+	for _, s := range statements {
+		collect(nil, s)
+		s.AllContents(nil, collect)
+	}
+	return b.factory.Program(b.Block(statements...), definitions, b.locator, 0, 0).(*parser.Program)
+}
+
+// Block creates a block with the given statements. As in a parsed block, calls that are
+// statements don't require a return value.
+func (b *Builder) Block(statements ...parser.Expression) parser.Expression {
+	stmts := make([]parser.Expression, len(statements))
+	for i, s := range statements {
+		if c, ok := s.(*parser.CallNamedFunctionExpression); ok && c.RvalRequired() {
+			s = b.factory.CallNamed(c.Functor(), false, c.Arguments(), c.Lambda(), b.locator, 0, 0)
+		}
+		stmts[i] = s
+	}
+	return b.factory.Block(stmts, b.locator, 0, 0)
+}
+
+// Str creates a string literal
+func (b *Builder) Str(value string) parser.Expression {
+	return b.factory.String(value, b.locator, 0, 0)
+}
+
+// Int creates an integer literal
+func (b *Builder) Int(value int64) parser.Expression {
+	return b.factory.Integer(value, 10, b.locator, 0, 0)
+}
+
+// Float creates a float literal
+func (b *Builder) Float(value float64) parser.Expression {
+	return b.factory.Float(value, b.locator, 0, 0)
+}
+
+// Bool creates a boolean literal
+func (b *Builder) Bool(value bool) parser.Expression {
+	return b.factory.Boolean(value, b.locator, 0, 0)
+}
+
+// Undef creates the undef literal
+func (b *Builder) Undef() parser.Expression {
+	return b.factory.Undef(b.locator, 0, 0)
+}
+
+// Default creates the default literal
+func (b *Builder) Default() parser.Expression {
+	return b.factory.Default(b.locator, 0, 0)
+}
+
+// Regexp creates a regular expression literal
+func (b *Builder) Regexp(value string) parser.Expression {
+	return b.factory.Regexp(value, b.locator, 0, 0)
+}
+
+// Name creates a bare word, e.g. a resource type or an attribute value such as 'present'
+func (b *Builder) Name(name string) parser.Expression {
+	return b.factory.QualifiedName(name, b.locator, 0, 0)
+}
+
+// Type creates a reference to the type with the given name. When parameters are given, the
+// reference is parameterized, i.e. Type(`Integer`, b.Int(1)) becomes Integer[1]
+func (b *Builder) Type(name string, params ...parser.Expression) parser.Expression {
+	t := b.factory.QualifiedReference(name, b.locator, 0, 0)
+	if len(params) > 0 {
+		t = b.Access(t, params...)
+	}
+	return t
+}
+
+// Var creates a variable reference
+func (b *Builder) Var(name string) parser.Expression {
+	return b.factory.Variable(b.Name(name), b.locator, 0, 0)
+}
+
+// Array creates an array literal
+func (b *Builder) Array(elements ...parser.Expression) parser.Expression {
+	return b.factory.Array(elements, b.locator, 0, 0)
+}
+
+// Hash creates a hash literal with entries created using Entry
+func (b *Builder) Hash(entries ...parser.Expression) parser.Expression {
+	return b.factory.Hash(entries, b.locator, 0, 0)
+}
+
+// Entry creates a hash entry or a selector entry
+func (b *Builder) Entry(key, value parser.Expression) parser.Expression {
+	return b.factory.KeyedEntry(key, value, b.locator, 0, 0)
+}
+
+// Concat creates a double quoted string with interpolation. Segments that are not string
+// literals are interpolated.
+func (b *Builder) Concat(segments ...parser.Expression) parser.Expression {
+	s := make([]parser.Expression, len(segments))
+	for i, seg := range segments {
+		if _, ok := seg.(*parser.LiteralString); ok {
+			s[i] = seg
+		} else {
+			s[i] = b.factory.Text(seg, b.locator, 0, 0)
+		}
+	}
+	return b.factory.ConcatenatedString(s, b.locator, 0, 0)
+}
+
+// Heredoc creates a heredoc with the given text and syntax. The syntax may be empty.
+func (b *Builder) Heredoc(text string, syntax string) parser.Expression {
+	return b.factory.Heredoc(b.Str(text), syntax, b.locator, 0, 0)
+}
+
+// Value creates an expression from a Go value. Strings, booleans, integer and floating point
+// numbers, nil, slices and maps with string keys of such values are accepted. Expressions are
+// returned unchanged. The entries of a map are sorted by key.
+func (b *Builder) Value(v interface{}) parser.Expression {
+	switch v := v.(type) {
+	case nil:
+		return b.Undef()
+	case parser.Expression:
+		return v
+	case string:
+		return b.Str(v)
+	case bool:
+		return b.Bool(v)
+	case int:
+		return b.Int(int64(v))
+	case int32:
+		return b.Int(int64(v))
+	case int64:
+		return b.Int(v)
+	case float32:
+		return b.Float(float64(v))
+	case float64:
+		return b.Float(v)
+	case []string:
+		es := make([]parser.Expression, len(v))
+		for i, e := range v {
+			es[i] = b.Str(e)
+		}
+		return b.Array(es...)
+	case []interface{}:
+		es := make([]parser.Expression, len(v))
+		for i, e := range v {
+			es[i] = b.Value(e)
+		}
+		return b.Array(es...)
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		es := make([]parser.Expression, len(keys))
+		for i, k := range keys {
+			es[i] = b.Entry(b.Str(k), b.Value(v[k]))
+		}
+		return b.Hash(es...)
+	default:
+		panic(fmt.Sprintf("Unable to create an expression from a value of type %T", v))
+	}
+}
+
+// Access creates an access expression, e.g. $x[1] or Integer[1, 2]
+func (b *Builder) Access(operand parser.Expression, keys ...parser.Expression) parser.Expression {
+	return b.factory.Access(operand, keys, b.locator, 0, 0)
+}
+
+// Call creates a call to the function with the given name. A lambda given as the last argument
+// becomes the lambda of the call.
+func (b *Builder) Call(name string, args ...parser.Expression) parser.Expression {
+	args, lambda := splitLambda(args)
+	return b.factory.CallNamed(b.Name(name), true, args, lambda, b.locator, 0, 0)
+}
+
+// Method creates a call to the function with the given name using the given receiver as the
+// first argument, e.g. $x.each |$v| { ... }. A lambda given as the last argument becomes the
+// lambda of the call.
+func (b *Builder) Method(receiver parser.Expression, name string, args ...parser.Expression) parser.Expression {
+	args, lambda := splitLambda(args)
+	functor := b.factory.NamedAccess(receiver, b.Name(name), b.locator, 0, 0)
+	return b.factory.CallMethod(functor, args, lambda, b.locator, 0, 0)
+}
+
+func splitLambda(args []parser.Expression) ([]parser.Expression, parser.Expression) {
+	if n := len(args) - 1; n >= 0 {
+		if _, ok := args[n].(*parser.LambdaExpression); ok {
+			return args[:n], args[n]
+		}
+	}
+	return args, nil
+}
+
+// Assign creates an assignment of the given value to the variable with the given name
+func (b *Builder) Assign(name string, value parser.Expression) parser.Expression {
+	return b.factory.Assignment(`=`, b.Var(name), value, b.locator, 0, 0)
+}
+
+// Binary creates an expression with the given binary operator, e.g. '+', '==', 'and', or '->'
+func (b *Builder) Binary(op string, lhs, rhs parser.Expression) parser.Expression {
+	f := b.factory
+	l := b.locator
+	switch op {
+	case `and`:
+		return f.And(lhs, rhs, l, 0, 0)
+	case `or`:
+		return f.Or(lhs, rhs, l, 0, 0)
+	case `in`:
+		return f.In(lhs, rhs, l, 0, 0)
+	case `+`, `-`, `*`, `/`, `%`, `<<`, `>>`:
+		return f.Arithmetic(op, lhs, rhs, l, 0, 0)
+	case `==`, `!=`, `<`, `<=`, `>`, `>=`:
+		return f.Comparison(op, lhs, rhs, l, 0, 0)
+	case `=~`, `!~`:
+		return f.Match(op, lhs, rhs, l, 0, 0)
+	case `=`, `+=`, `-=`:
+		return f.Assignment(op, lhs, rhs, l, 0, 0)
+	case `->`, `~>`, `<-`, `<~`:
+		return f.RelOp(op, lhs, rhs, l, 0, 0)
+	default:
+		panic(fmt.Sprintf("Unknown binary operator '%s'", op))
+	}
+}
+
+// Not creates a logical negation
+func (b *Builder) Not(e parser.Expression) parser.Expression {
+	return b.factory.Not(e, b.locator, 0, 0)
+}
+
+// Negate creates an arithmetic negation
+func (b *Builder) Negate(e parser.Expression) parser.Expression {
+	return b.factory.Negate(e, b.locator, 0, 0)
+}
+
+// Unfold creates a splat, i.e. *e
+func (b *Builder) Unfold(e parser.Expression) parser.Expression {
+	return b.factory.Unfold(e, b.locator, 0, 0)
+}
+
+// Paren encloses the given expression in parentheses
+func (b *Builder) Paren(e parser.Expression) parser.Expression {
+	return b.factory.Parenthesized(e, b.locator, 0, 0)
+}
+
+// If creates an if expression. The then and else parts are statements or blocks and the else
+// part may be nil. An if expression given as the else part is printed as elsif.
+func (b *Builder) If(test, then, elseExpr parser.Expression) parser.Expression {
+	return b.factory.If(test, b.asBlock(then), b.elsePart(elseExpr), b.locator, 0, 0)
+}
+
+// Unless creates an unless expression. The then and else parts are statements or blocks and the
+// else part may be nil.
+func (b *Builder) Unless(test, then, elseExpr parser.Expression) parser.Expression {
+	return b.factory.Unless(test, b.asBlock(then), b.elsePart(elseExpr), b.locator, 0, 0)
+}
+
+func (b *Builder) asBlock(e parser.Expression) parser.Expression {
+	switch e.(type) {
+	case nil:
+		return b.Block()
+	case *parser.BlockExpression:
+		return e
+	default:
+		return b.Block(e)
+	}
+}
+
+func (b *Builder) elsePart(e parser.Expression) parser.Expression {
+	switch e.(type) {
+	case nil:
+		return b.factory.Nop(b.locator, 0, 0)
+	case *parser.IfExpression:
+		return e
+	default:
+		return b.asBlock(e)
+	}
+}
+
+// Case creates a case expression with options created using When
+func (b *Builder) Case(test parser.Expression, options ...parser.Expression) parser.Expression {
+	return b.factory.Case(test, options, b.locator, 0, 0)
+}
+
+// When creates a case option
+func (b *Builder) When(values []parser.Expression, statements ...parser.Expression) parser.Expression {
+	return b.factory.When(values, b.Block(statements...), b.locator, 0, 0)
+}
+
+// Select creates a selector expression with entries created using Entry
+func (b *Builder) Select(lhs parser.Expression, entries ...parser.Expression) parser.Expression {
+	selectors := make([]parser.Expression, len(entries))
+	for i, e := range entries {
+		ke := e.(*parser.KeyedEntry)
+		selectors[i] = b.factory.Selector(ke.Key(), ke.Value(), b.locator, 0, 0)
+	}
+	return b.factory.Select(lhs, selectors, b.locator, 0, 0)
+}
+
+// Attr creates an attribute operation, i.e. name => value
+func (b *Builder) Attr(name string, value parser.Expression) parser.Expression {
+	return b.factory.AttributeOp(`=>`, name, value, b.locator, 0, 0)
+}
+
+// AppendAttr creates an attribute operation that appends to the value, i.e. name +> value
+func (b *Builder) AppendAttr(name string, value parser.Expression) parser.Expression {
+	return b.factory.AttributeOp(`+>`, name, value, b.locator, 0, 0)
+}
+
+// Splat creates an attributes operation, i.e. * => value
+func (b *Builder) Splat(value parser.Expression) parser.Expression {
+	return b.factory.AttributesOp(value, b.locator, 0, 0)
+}
+
+// Resource creates a resource expression with one body that has the given title and attribute
+// operations
+func (b *Builder) Resource(typeName string, title string, ops ...parser.Expression) parser.Expression {
+	return b.Resources(parser.REGULAR, typeName, b.ResourceBody(b.Str(title), ops...))
+}
+
+// Resources creates a resource expression of the given form with bodies created using ResourceBody
+func (b *Builder) Resources(form parser.ResourceForm, typeName string, bodies ...parser.Expression) parser.Expression {
+	return b.factory.Resource(form, b.Name(typeName), bodies, b.locator, 0, 0)
+}
+
+// ResourceBody creates a resource body with the given title and attribute operations
+func (b *Builder) ResourceBody(title parser.Expression, ops ...parser.Expression) parser.Expression {
+	return b.factory.ResourceBody(title, ops, b.locator, 0, 0)
+}
+
+// ResourceDefaults creates resource defaults for the type with the given name, e.g. File { ... }
+func (b *Builder) ResourceDefaults(typeName string, ops ...parser.Expression) parser.Expression {
+	return b.factory.ResourceDefaults(parser.REGULAR, b.Type(typeName), ops, b.locator, 0, 0)
+}
+
+// ResourceOverride creates an override of the given resources, e.g. File['x'] { ... }
+func (b *Builder) ResourceOverride(resources parser.Expression, ops ...parser.Expression) parser.Expression {
+	return b.factory.ResourceOverride(parser.REGULAR, resources, ops, b.locator, 0, 0)
+}
+
+// TypeAlias creates a type alias
+func (b *Builder) TypeAlias(name string, typeExpr parser.Expression) parser.Expression {
+	return b.factory.TypeAlias(name, typeExpr, b.locator, 0, 0)
+}
+
+// Node creates a node definition
+func (b *Builder) Node(hostMatches []parser.Expression, statements ...parser.Expression) parser.Expression {
+	return b.factory.Node(hostMatches, nil, b.Block(statements...), b.locator, 0, 0)
+}
+
+// Class starts the creation of a class
+func (b *Builder) Class(name string) *DefinitionBuilder {
+	return &DefinitionBuilder{b: b, keyword: `class`, name: name}
+}
+
+// Define starts the creation of a defined resource type
+func (b *Builder) Define(name string) *DefinitionBuilder {
+	return &DefinitionBuilder{b: b, keyword: `define`, name: name}
+}
+
+// Function starts the creation of a function
+func (b *Builder) Function(name string) *DefinitionBuilder {
+	return &DefinitionBuilder{b: b, keyword: `function`, name: name}
+}
+
+// Plan starts the creation of a plan
+func (b *Builder) Plan(name string) *DefinitionBuilder {
+	return &DefinitionBuilder{b: b, keyword: `plan`, name: name}
+}
+
+// Lambda starts the creation of a lambda
+func (b *Builder) Lambda() *DefinitionBuilder {
+	return &DefinitionBuilder{b: b, keyword: `lambda`}
+}
+
+// Param adds an untyped parameter. The value is the default value of the parameter and may be nil.
+func (d *DefinitionBuilder) Param(name string, value parser.Expression) *DefinitionBuilder {
+	return d.TypedParam(nil, name, value)
+}
+
+// TypedParam adds a parameter with the given type. The type and the value may be nil.
+func (d *DefinitionBuilder) TypedParam(typeExpr parser.Expression, name string, value parser.Expression) *DefinitionBuilder {
+	d.params = append(d.params, d.b.factory.Parameter(name, value, typeExpr, false, d.b.locator, 0, 0))
+	return d
+}
+
+// RestParam adds a parameter that captures the rest of the arguments. The type may be nil.
+func (d *DefinitionBuilder) RestParam(typeExpr parser.Expression, name string) *DefinitionBuilder {
+	d.params = append(d.params, d.b.factory.Parameter(name, nil, typeExpr, true, d.b.locator, 0, 0))
+	return d
+}
+
+// Inherits sets the parent of a class
+func (d *DefinitionBuilder) Inherits(parent string) *DefinitionBuilder {
+	if d.keyword != `class` {
+		panic(fmt.Sprintf("A %s cannot inherit", d.keyword))
+	}
+	d.parent = parent
+	return d
+}
+
+// Returns sets the return type of a function, plan, or lambda
+func (d *DefinitionBuilder) Returns(returnType parser.Expression) *DefinitionBuilder {
+	switch d.keyword {
+	case `function`, `plan`, `lambda`:
+		d.returnType = returnType
+		return d
+	default:
+		panic(fmt.Sprintf("A %s cannot have a return type", d.keyword))
+	}
+}
+
+// Body creates the definition with the given statements as its body
+func (d *DefinitionBuilder) Body(statements ...parser.Expression) parser.Expression {
+	f := d.b.factory
+	l := d.b.locator
+	body := d.b.Block(statements...)
+	switch d.keyword {
+	case `class`:
+		return f.Class(d.name, d.params, d.parent, body, l, 0, 0)
+	case `define`:
+		return f.Definition(d.name, d.params, body, l, 0, 0)
+	case `function`:
+		return f.Function(d.name, d.params, body, d.returnType, l, 0, 0)
+	case `plan`:
+		return f.Plan(d.name, d.params, body, d.returnType, l, 0, 0)
+	default:
+		return f.Lambda(d.params, body, d.returnType, l, 0, 0)
+	}
+}
@@ -0,0 +1,70 @@
+package builder
+
+import (
+	"testing"
+
+	"github.com/lyraproj/puppet-parser/parser"
+	"github.com/lyraproj/puppet-parser/printer"
+)
+
+func TestClass(t *testing.T) {
+	b := New()
+	program := b.Program(
+		b.Class(`nginx`).
+			TypedParam(b.Type(`Integer`, b.Int(1)), `port`, b.Int(80)).
+			Param(`options`, b.Value(map[string]interface{}{`worker_processes`: 4, `sendfile`: true})).
+			Body(
+				b.Resource(`package`, `nginx`, b.Attr(`ensure`, b.Name(`installed`))),
+				b.Resource(`service`, `nginx`,
+					b.Attr(`ensure`, b.Name(`running`)),
+					b.Attr(`require`, b.Access(b.Type(`Package`), b.Str(`nginx`)))),
+				b.Method(b.Var(`options`), `each`, b.Lambda().Param(`k`, nil).Param(`v`, nil).Body(
+					b.Call(`notice`, b.Concat(b.Str(`option `), b.Var(`k`), b.Str(` = `), b.Var(`v`))))),
+				b.If(b.Binary(`>`, b.Var(`port`), b.Int(1024)), b.Call(`notice`, b.Str(`unprivileged`)), nil)))
+
+	expected := `class nginx(
+  Integer[1] $port = 80,
+  $options = {'sendfile' => true, 'worker_processes' => 4},
+) {
+  package { 'nginx':
+    ensure => installed,
+  }
+  service { 'nginx':
+    ensure  => running,
+    require => Package['nginx'],
+  }
+  $options.each |$k, $v| {
+    notice("option ${k} = ${v}")
+  }
+  if $port > 1024 {
+    notice('unprivileged')
+  }
+}
+`
+	source := printer.String(program)
+	if source != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, source)
+	}
+	if len(program.Definitions()) != 1 {
+		t.Errorf("expected one definition, got %d", len(program.Definitions()))
+	}
+
+	parsed, err := parser.CreateParser().Parse(`test.pp`, source, false)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if parsed.ToPN().String() != program.ToPN().String() {
+		t.Errorf("expected:\n%s\ngot:\n%s", program.ToPN(), parsed.ToPN())
+	}
+}
+
+func TestSyntheticPositions(t *testing.T) {
+	b := NewWithFactory(parser.DefaultFactory(), `generated.pp`)
+	e := b.Assign(`x`, b.Int(1))
+	if e.File() != `generated.pp` || e.Line() != 1 || e.Pos() != 1 || e.ByteOffset() != 0 || e.ByteLength() != 0 {
+		t.Errorf("unexpected position %s:%d:%d", e.File(), e.Line(), e.Pos())
+	}
+	if e.Locator() != b.Locator() {
+		t.Error(`expected expression to use the locator of the builder`)
+	}
+}
@@ -0,0 +1,412 @@
+// Package config loads the tooling defaults of a project from a .puppet-parser.yaml file.
+//
+// The file is discovered by searching upward from the files that are processed, so a file in the root of
+// a control repository or a module applies to all of its manifests. A file sets parser options, the
+// validator strictness, the targeted language version, lint and the severity of individual issues, and
+// the defaults of the parse command, e.g.
+//
+//	parser:
+//	  tasks: true
+//	  lenient: true
+//	validator:
+//	  strict: warning
+//	  language_version: 6
+//	  lint: true
+//	  storeconfigs: false
+//	  warnings_as_errors: [VALIDATE_FUTURE_RESERVED_WORD]
+//	  header: '\A# Copyright \d{4} Example Inc\.'
+//	  max_line_length: 140
+//	  max_string_length: 80
+//	  rules:
+//	    VALIDATE_UNQUALIFIED_TOP_SCOPE_VARIABLE: ignore
+//	    VALIDATE_EMPTY_BODY: error
+//	output: checkstyle
+//	exclude:
+//	  - spec/fixtures/
+//
+// Only the subset of YAML that such files need is understood: block mappings and sequences, flow
+// sequences, scalars, and comments.
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-parser/parser"
+	"github.com/lyraproj/puppet-parser/validator"
+)
+
+// FILE_NAME is the name of the configuration file
+const FILE_NAME = `.puppet-parser.yaml`
+
+// Config holds the settings of a configuration file. The zero Config changes nothing.
+type Config struct {
+	// Path is the path of the file that the configuration was loaded from, or empty when no file was
+	// found. It's absolute when the file was found using Find.
+	Path string
+
+	// Tasks, Workflow, and Lenient enable the parser options PARSER_TASKS_ENABLED,
+	// PARSER_WORKFLOW_ENABLED, and PARSER_LENIENT_COMMAS
+	Tasks, Workflow, Lenient bool
+
+	// Strict is the strictness of the validator, or zero when it isn't set
+	Strict validator.Strictness
+
+	// LanguageVersion is the targeted Puppet language version, or zero when it isn't set
+	LanguageVersion validator.LanguageVersion
+
+	// Lint enables the lint issues, see validator.EnableLint
+	Lint bool
+
+	// NoStoreconfigs tells that storeconfigs is disabled, see validator.ApplyStoreconfigs
+	NoStoreconfigs bool
+
+	// Header is the pattern that the leading comments of each manifest must match, or nil when no header
+	// is required, see validator.RequireHeader
+	Header *regexp.Regexp
+
+	// MaxLineLength and MaxStringLength are the maximum lengths of lines and quoted strings, or zero when
+	// they aren't checked, see validator.ApplyMaxLineLength and validator.ApplyMaxStringLength
+	MaxLineLength, MaxStringLength int
+
+	// AllWarningsAsErrors reports all warnings as errors, and WarningsAsErrors the warnings with the
+	// given codes, see validator.WarningsAsErrors
+	AllWarningsAsErrors bool
+	WarningsAsErrors    []issue.Code
+
+	// Rules are the severities of individual issues. They are applied after Strict and Lint so a rule
+	// can turn off a lint issue or report it as an error.
+	Rules map[issue.Code]issue.Severity
+
+	// Output is the default format of the diagnostics of the parse command, or empty when it isn't set
+	Output string
+
+	// Exclude are .gitignore style patterns of files to skip, relative to the directory of the file
+	Exclude []string
+}
+
+// Find returns the configuration in the closest configuration file in the directory of the given path,
+// or in one of its parent directories. The path is a file or a directory. A zero Config is returned when
+// no file is found.
+func Find(path string) (*Config, error) {
+	dir, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+		dir = filepath.Dir(dir)
+	}
+	for {
+		fileName := filepath.Join(dir, FILE_NAME)
+		if _, err := os.Stat(fileName); err == nil {
+			return Load(fileName)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return &Config{}, nil
+		}
+		dir = parent
+	}
+}
+
+// Load returns the configuration in the file with the given path
+func Load(fileName string) (*Config, error) {
+	content, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(fileName, string(content))
+}
+
+// Parse returns the configuration in the given content of the file with the given path. An error is
+// returned for keys and issue codes that are unknown and for values of the wrong type.
+func Parse(fileName string, content string) (*Config, error) {
+	data, err := parseYAML(content)
+	if err != nil {
+		return nil, fmt.Errorf(`%s: %s`, fileName, err.Error())
+	}
+	c := &Config{Path: fileName}
+	d := &decoder{}
+	d.decode(data, c)
+	if d.err != nil {
+		return nil, fmt.Errorf(`%s: %s`, fileName, d.err.Error())
+	}
+	return c, nil
+}
+
+// ParserOptions returns the parser options that the configuration enables
+func (c *Config) ParserOptions() []parser.Option {
+	opts := []parser.Option{}
+	if c.Tasks {
+		opts = append(opts, parser.PARSER_TASKS_ENABLED)
+	}
+	if c.Workflow {
+		opts = append(opts, parser.PARSER_WORKFLOW_ENABLED)
+	}
+	if c.Lenient {
+		opts = append(opts, parser.PARSER_LENIENT_COMMAS)
+	}
+	return opts
+}
+
+// Apply configures the given validator according to the configuration
+func (c *Config) Apply(v validator.Validator) {
+	if c.Strict != 0 {
+		validator.ApplyStrictness(v, c.Strict)
+	}
+	if c.LanguageVersion != 0 {
+		validator.ApplyLanguageVersion(v, c.LanguageVersion)
+	}
+	if c.NoStoreconfigs {
+		validator.ApplyStoreconfigs(v, false)
+	}
+	if c.Lint {
+		validator.EnableLint(v)
+	}
+	if c.Header != nil {
+		validator.RequireHeader(v, c.Header)
+	}
+	if c.MaxLineLength > 0 {
+		validator.ApplyMaxLineLength(v, c.MaxLineLength)
+	}
+	if c.MaxStringLength > 0 {
+		validator.ApplyMaxStringLength(v, c.MaxStringLength)
+	}
+	for code, severity := range c.Rules {
+		v.Demote(code, severity)
+	}
+	if c.AllWarningsAsErrors {
+		validator.WarningsAsErrors(v)
+	} else if len(c.WarningsAsErrors) > 0 {
+		validator.WarningsAsErrors(v, c.WarningsAsErrors...)
+	}
+}
+
+// ParserValidatorFor returns the ParserValidator that validator.ParserValidatorWith returns for the given
+// path, with the parser options of the configuration and a validator that the configuration is applied to
+func (c *Config) ParserValidatorFor(path string) validator.ParserValidator {
+	return validator.ParserValidatorWith(path, c.Apply, c.ParserOptions()...)
+}
+
+// BatchOptions returns the options that make validator.ValidateAll use the configuration
+func (c *Config) BatchOptions() validator.BatchOptions {
+	return validator.BatchOptions{ParserOptions: c.ParserOptions(), Configure: c.Apply}
+}
+
+// SetWarningsAsErrors parses a value of the warnings_as_errors setting, "all" or a comma separated list
+// of issue codes, into the configuration
+func (c *Config) SetWarningsAsErrors(value string) error {
+	c.AllWarningsAsErrors, c.WarningsAsErrors = false, nil
+	switch strings.TrimSpace(value) {
+	case ``:
+		return nil
+	case `all`:
+		c.AllWarningsAsErrors = true
+		return nil
+	}
+	for _, code := range strings.Split(value, `,`) {
+		code = strings.TrimSpace(code)
+		if _, ok := issue.IssueForCode2(issue.Code(code)); !ok {
+			return fmt.Errorf(`unknown issue code '%s'`, code)
+		}
+		c.WarningsAsErrors = append(c.WarningsAsErrors, issue.Code(code))
+	}
+	return nil
+}
+
+// decoder decodes the data of a configuration file. It records the first error and ignores the rest.
+type decoder struct {
+	err error
+}
+
+func (d *decoder) fail(format string, args ...interface{}) {
+	if d.err == nil {
+		d.err = fmt.Errorf(format, args...)
+	}
+}
+
+func (d *decoder) decode(data interface{}, c *Config) {
+	m := d.mapping(data, ``)
+	for _, key := range sortedKeys(m) {
+		value := m[key]
+		switch key {
+		case `parser`:
+			pm := d.mapping(value, `parser`)
+			for _, key := range sortedKeys(pm) {
+				value := pm[key]
+				switch key {
+				case `tasks`:
+					c.Tasks = d.boolean(value, `parser.tasks`)
+				case `workflow`:
+					c.Workflow = d.boolean(value, `parser.workflow`)
+				case `lenient`:
+					c.Lenient = d.boolean(value, `parser.lenient`)
+				default:
+					d.fail(`unknown key 'parser.%s'`, key)
+				}
+			}
+		case `validator`:
+			d.decodeValidator(value, c)
+		case `output`:
+			c.Output = d.str(value, `output`)
+			switch c.Output {
+			case `text`, `jsonl`, `checkstyle`:
+			default:
+				d.fail(`output must be text, jsonl, or checkstyle, got '%s'`, c.Output)
+			}
+		case `exclude`:
+			c.Exclude = d.strings(value, `exclude`)
+		default:
+			d.fail(`unknown key '%s'`, key)
+		}
+	}
+}
+
+func (d *decoder) decodeValidator(data interface{}, c *Config) {
+	m := d.mapping(data, `validator`)
+	for _, key := range sortedKeys(m) {
+		value := m[key]
+		switch key {
+		case `strict`:
+			switch s := d.str(value, `validator.strict`); s {
+			case `off`, `warning`, `error`:
+				c.Strict = validator.Strict(s)
+			default:
+				d.fail(`validator.strict must be off, warning, or error, got '%s'`, s)
+			}
+		case `language_version`:
+			switch v := fmt.Sprint(value); v {
+			case `5`, `6`, `7`:
+				c.LanguageVersion = validator.ParseLanguageVersion(v)
+			default:
+				d.fail(`validator.language_version must be 5, 6, or 7, got '%s'`, v)
+			}
+		case `lint`:
+			c.Lint = d.boolean(value, `validator.lint`)
+		case `storeconfigs`:
+			c.NoStoreconfigs = !d.boolean(value, `validator.storeconfigs`)
+		case `header`:
+			var err error
+			if c.Header, err = regexp.Compile(d.str(value, `validator.header`)); err != nil {
+				d.fail(`validator.header: %s`, err.Error())
+			}
+		case `max_line_length`:
+			c.MaxLineLength = d.positive(value, `validator.max_line_length`)
+		case `max_string_length`:
+			c.MaxStringLength = d.positive(value, `validator.max_string_length`)
+		case `warnings_as_errors`:
+			var err error
+			if s, ok := value.(string); ok {
+				err = c.SetWarningsAsErrors(s)
+			} else {
+				err = c.SetWarningsAsErrors(strings.Join(d.strings(value, `validator.warnings_as_errors`), `,`))
+			}
+			if err != nil {
+				d.fail(`validator.warnings_as_errors: %s`, err.Error())
+			}
+		case `rules`:
+			rules := d.mapping(value, `validator.rules`)
+			c.Rules = make(map[issue.Code]issue.Severity, len(rules))
+			for _, code := range sortedKeys(rules) {
+				c.Rules[issue.Code(code)] = d.rule(issue.Code(code), rules[code])
+			}
+		default:
+			d.fail(`unknown key 'validator.%s'`, key)
+		}
+	}
+}
+
+// rule returns the severity of the rule for the issue with the given code
+func (d *decoder) rule(code issue.Code, value interface{}) issue.Severity {
+	i, ok := issue.IssueForCode2(code)
+	if !ok {
+		d.fail(`unknown issue code '%s' in validator.rules`, code)
+		return issue.SEVERITY_IGNORE
+	}
+	if !i.IsDemotable() {
+		d.fail(`the severity of the issue '%s' in validator.rules cannot be changed`, code)
+		return issue.SEVERITY_IGNORE
+	}
+	switch s := d.str(value, `validator.rules.`+string(code)); s {
+	case `ignore`, `off`:
+		return issue.SEVERITY_IGNORE
+	case `deprecation`:
+		return issue.SEVERITY_DEPRECATION
+	case `warning`:
+		return issue.SEVERITY_WARNING
+	case `error`:
+		return issue.SEVERITY_ERROR
+	default:
+		d.fail(`validator.rules.%s must be ignore, deprecation, warning, or error, got '%s'`, code, s)
+		return issue.SEVERITY_IGNORE
+	}
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (d *decoder) mapping(value interface{}, key string) map[string]interface{} {
+	if m, ok := value.(map[string]interface{}); ok {
+		return m
+	}
+	if value != nil {
+		if key == `` {
+			d.fail(`expected a mapping`)
+		} else {
+			d.fail(`%s must be a mapping`, key)
+		}
+	}
+	return nil
+}
+
+func (d *decoder) boolean(value interface{}, key string) bool {
+	b, ok := value.(bool)
+	if !ok {
+		d.fail(`%s must be true or false`, key)
+	}
+	return b
+}
+
+func (d *decoder) positive(value interface{}, key string) int {
+	i, ok := value.(int64)
+	if !ok || i <= 0 {
+		d.fail(`%s must be a positive integer`, key)
+	}
+	return int(i)
+}
+
+func (d *decoder) str(value interface{}, key string) string {
+	s, ok := value.(string)
+	if !ok {
+		d.fail(`%s must be a string`, key)
+	}
+	return s
+}
+
+func (d *decoder) strings(value interface{}, key string) []string {
+	list, ok := value.([]interface{})
+	if !ok {
+		if s, ok := value.(string); ok {
+			return []string{s}
+		}
+		d.fail(`%s must be a list of strings`, key)
+		return nil
+	}
+	result := make([]string, len(list))
+	for i, v := range list {
+		result[i] = d.str(v, key)
+	}
+	return result
+}
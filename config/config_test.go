@@ -0,0 +1,178 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-parser/validator"
+)
+
+func TestParseYAML(t *testing.T) {
+	data, err := parseYAML(issue.Unindent(`
+		# comment
+		---
+		a: 1
+		b:
+		  c: 'it''s' # trailing comment
+		  d: "tab\tand # hash"
+		e:
+		- x
+		-   - 1
+		    - 2
+		- f: true
+		  g: ~
+		h: [a, 'b, c', "d"]
+		i: {}
+		j:
+		`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		`a`: int64(1),
+		`b`: map[string]interface{}{`c`: `it's`, `d`: "tab\tand # hash"},
+		`e`: []interface{}{`x`, []interface{}{int64(1), int64(2)}, map[string]interface{}{`f`: true, `g`: nil}},
+		`h`: []interface{}{`a`, `b, c`, `d`},
+		`i`: map[string]interface{}{},
+		`j`: nil,
+	}
+	if !reflect.DeepEqual(expected, data) {
+		t.Errorf("expected %v, got %v", expected, data)
+	}
+}
+
+func TestParseYAMLErrors(t *testing.T) {
+	for src, expected := range map[string]string{
+		"a: 1\n  b: 2\n":   `line 2: unexpected indentation`,
+		"a: 1\na: 2\n":     `line 2: duplicate key 'a'`,
+		"a: 1\nb\n":        `line 2: expected "key: value", got 'b'`,
+		"a: [1, 2\n":       `line 1: unterminated flow sequence [1, 2`,
+		"a: {b: 1}\n":      `line 1: flow mappings are not supported`,
+		"a:\n\t- b\n":      `line 2: tabs cannot be used for indentation`,
+		"a: 1\n- b\n":      `line 2: expected a key`,
+		"a: 'unterminated": `line 1: malformed single quoted string 'unterminated`,
+	} {
+		if _, err := parseYAML(src); err == nil || err.Error() != expected {
+			t.Errorf("expected %q to fail with %s, got %v", src, expected, err)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	c, err := Parse(FILE_NAME, issue.Unindent(`
+		parser:
+		  tasks: true
+		  lenient: true
+		validator:
+		  strict: warning
+		  language_version: 6
+		  lint: true
+		  storeconfigs: false
+		  warnings_as_errors: [VALIDATE_FUTURE_RESERVED_WORD]
+		  header: '\A# Copyright'
+		  max_line_length: 140
+		  rules:
+		    VALIDATE_UNQUALIFIED_TOP_SCOPE_VARIABLE: ignore
+		    VALIDATE_EMPTY_BODY: error
+		output: checkstyle
+		exclude:
+		  - spec/fixtures/
+		`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &Config{
+		Path:             FILE_NAME,
+		Tasks:            true,
+		Lenient:          true,
+		Strict:           validator.STRICT_WARNING,
+		LanguageVersion:  validator.PUPPET_6,
+		Lint:             true,
+		NoStoreconfigs:   true,
+		Header:           regexp.MustCompile(`\A# Copyright`),
+		MaxLineLength:    140,
+		WarningsAsErrors: []issue.Code{validator.VALIDATE_FUTURE_RESERVED_WORD},
+		Rules: map[issue.Code]issue.Severity{
+			validator.VALIDATE_UNQUALIFIED_TOP_SCOPE_VARIABLE: issue.SEVERITY_IGNORE,
+			validator.VALIDATE_EMPTY_BODY:                     issue.SEVERITY_ERROR,
+		},
+		Output:  `checkstyle`,
+		Exclude: []string{`spec/fixtures/`},
+	}
+	if !reflect.DeepEqual(expected, c) {
+		t.Errorf("expected %+v, got %+v", expected, c)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for src, expected := range map[string]string{
+		"formatter: {}\n":                                             `unknown key 'formatter'`,
+		"parser:\n  tasks: yes\n":                                     `parser.tasks must be true or false`,
+		"validator:\n  strict: high\n":                                `validator.strict must be off, warning, or error, got 'high'`,
+		"validator:\n  rules:\n    NO_SUCH: error\n":                  `unknown issue code 'NO_SUCH' in validator.rules`,
+		"validator:\n  rules:\n    VALIDATE_EMPTY_BODY: fatal\n":      `validator.rules.VALIDATE_EMPTY_BODY must be ignore, deprecation, warning, or error, got 'fatal'`,
+		"validator:\n  rules:\n    VALIDATE_NOT_TOP_LEVEL: warning\n": `the severity of the issue 'VALIDATE_NOT_TOP_LEVEL' in validator.rules cannot be changed`,
+		"validator:\n  header: '('\n":                                 `validator.header: error parsing regexp: missing closing ): ` + "`(`",
+		"validator:\n  max_string_length: long\n":                     `validator.max_string_length must be a positive integer`,
+		"output: xml\n":                                               `output must be text, jsonl, or checkstyle, got 'xml'`,
+		"- a\n":                                                       `expected a mapping`,
+	} {
+		if _, err := Parse(`x.yaml`, src); err == nil || err.Error() != `x.yaml: `+expected {
+			t.Errorf("expected %q to fail with %s, got %v", src, expected, err)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	dir, err := ioutil.TempDir(``, `config`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	manifests := filepath.Join(dir, `mymod`, `manifests`)
+	if err = os.MkdirAll(manifests, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(filepath.Join(dir, FILE_NAME), []byte("validator:\n  lint: true\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := Find(filepath.Join(manifests, `init.pp`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.Lint || c.Path != filepath.Join(dir, FILE_NAME) {
+		t.Errorf("expected the configuration of the parent directory, got %+v", c)
+	}
+}
+
+func TestParserValidatorFor(t *testing.T) {
+	c, err := Parse(FILE_NAME, issue.Unindent(`
+		parser:
+		  lenient: true
+		validator:
+		  lint: true
+		  rules:
+		    VALIDATE_EMPTY_BODY: ignore
+		    VALIDATE_EMPTY_RESOURCE_BODY: error
+		`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, result := c.ParserValidatorFor(`mymod/manifests/init.pp`).Parse(`mymod/manifests/init.pp`, "class mymod {\n  $x = 1,\n  notify { hello: }\n}\nclass mymod::empty {}\n")
+	if result == nil {
+		t.Fatal(`expected issues`)
+	}
+	codes := make([]string, 0)
+	for _, ri := range result.Issues() {
+		codes = append(codes, string(ri.Code())+` `+ri.Severity().String())
+	}
+	expected := []string{`PARSE_EXTRANEOUS_COMMA warning`, `VALIDATE_EMPTY_RESOURCE_BODY error`}
+	if !reflect.DeepEqual(expected, codes) {
+		t.Errorf("expected %v, got %v", expected, codes)
+	}
+}
@@ -0,0 +1,297 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// yamlLine is a line of a YAML document that isn't blank or a comment
+type yamlLine struct {
+	indent int
+	text   string
+	number int
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+// yamlError is a syntax error at a line of a YAML document
+type yamlError struct {
+	line    int
+	message string
+}
+
+func (e *yamlError) Error() string {
+	return fmt.Sprintf(`line %d: %s`, e.line, e.message)
+}
+
+// parseYAML parses the subset of YAML that configuration files use: block mappings and block sequences
+// nested by indentation, flow sequences such as [a, b], plain, single quoted, and double quoted scalars,
+// and comments. Mappings are returned as map[string]interface{}, sequences as []interface{}, and scalars
+// as string, int64, bool, or nil. An empty document is returned as an empty mapping.
+func parseYAML(content string) (interface{}, error) {
+	p := &yamlParser{}
+	for i, text := range strings.Split(content, "\n") {
+		text = strings.TrimRight(stripComment(text), " \t\r")
+		if text == `` || text == `---` {
+			continue
+		}
+		trimmed := strings.TrimLeft(text, ` `)
+		if strings.HasPrefix(trimmed, "\t") {
+			return nil, &yamlError{i + 1, `tabs cannot be used for indentation`}
+		}
+		p.lines = append(p.lines, yamlLine{len(text) - len(trimmed), trimmed, i + 1})
+	}
+	if len(p.lines) == 0 {
+		return map[string]interface{}{}, nil
+	}
+	value, err := p.parseBlock()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, &yamlError{p.lines[p.pos].number, `unexpected indentation`}
+	}
+	return value, nil
+}
+
+// stripComment returns the given line without a comment that starts with a # at the start of the line
+// or after a space, outside of quotes
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+func isSequenceItem(text string) bool {
+	return text == `-` || strings.HasPrefix(text, `- `)
+}
+
+// parseBlock parses the mapping or sequence that starts at the current line
+func (p *yamlParser) parseBlock() (interface{}, error) {
+	l := p.lines[p.pos]
+	if isSequenceItem(l.text) {
+		return p.parseSequence(l.indent)
+	}
+	return p.parseMapping(l.indent)
+}
+
+func (p *yamlParser) parseMapping(indent int) (map[string]interface{}, error) {
+	m := make(map[string]interface{})
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent {
+		l := p.lines[p.pos]
+		if isSequenceItem(l.text) {
+			return nil, &yamlError{l.number, `expected a key`}
+		}
+		key, rest, ok := splitKey(l.text)
+		if !ok {
+			return nil, &yamlError{l.number, fmt.Sprintf(`expected "key: value", got '%s'`, l.text)}
+		}
+		if _, ok := m[key]; ok {
+			return nil, &yamlError{l.number, fmt.Sprintf(`duplicate key '%s'`, key)}
+		}
+		p.pos++
+		var value interface{}
+		var err error
+		if rest != `` {
+			value, err = parseScalar(rest, l.number)
+		} else if p.pos < len(p.lines) {
+			next := p.lines[p.pos]
+			if next.indent > indent || next.indent == indent && isSequenceItem(next.text) {
+				value, err = p.parseBlock()
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		m[key] = value
+	}
+	return m, nil
+}
+
+func (p *yamlParser) parseSequence(indent int) ([]interface{}, error) {
+	s := make([]interface{}, 0)
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent && isSequenceItem(p.lines[p.pos].text) {
+		l := p.lines[p.pos]
+		rest := strings.TrimLeft(l.text[1:], ` `)
+		var value interface{}
+		var err error
+		switch {
+		case rest == ``:
+			p.pos++
+			if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+				value, err = p.parseBlock()
+			}
+		case isSequenceItem(rest):
+			// A nested sequence that starts on the line of the item
+			p.lines[p.pos] = yamlLine{indent + len(l.text) - len(rest), rest, l.number}
+			value, err = p.parseBlock()
+		default:
+			if _, _, ok := splitKey(rest); ok {
+				// A mapping that starts on the line of the item
+				p.lines[p.pos] = yamlLine{indent + len(l.text) - len(rest), rest, l.number}
+				value, err = p.parseBlock()
+			} else {
+				p.pos++
+				value, err = parseScalar(rest, l.number)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		s = append(s, value)
+	}
+	return s, nil
+}
+
+// splitKey splits the given text into the key and the value of a mapping entry
+func splitKey(text string) (string, string, bool) {
+	var key string
+	if text[0] == '"' || text[0] == '\'' {
+		end := closingQuote(text)
+		if end < 0 {
+			return ``, ``, false
+		}
+		k, err := parseScalar(text[:end+1], 0)
+		if err != nil {
+			return ``, ``, false
+		}
+		key, text = k.(string), text[end+1:]
+		if !strings.HasPrefix(text, `:`) {
+			return ``, ``, false
+		}
+		text = text[1:]
+	} else {
+		i := strings.Index(text, `: `)
+		if i < 0 {
+			if !strings.HasSuffix(text, `:`) {
+				return ``, ``, false
+			}
+			i = len(text) - 1
+		}
+		key, text = text[:i], text[i+1:]
+		if key == `` || strings.ContainsAny(key[:1], `[{`) {
+			return ``, ``, false
+		}
+	}
+	if text != `` && text[0] != ' ' {
+		return ``, ``, false
+	}
+	return key, strings.TrimSpace(text), true
+}
+
+// closingQuote returns the index of the quote that ends the quoted string at the start of the given text
+func closingQuote(text string) int {
+	quote := text[0]
+	for i := 1; i < len(text); i++ {
+		switch {
+		case text[i] == '\\' && quote == '"':
+			i++
+		case text[i] == quote:
+			if quote == '\'' && i+1 < len(text) && text[i+1] == '\'' {
+				i++
+				continue
+			}
+			return i
+		}
+	}
+	return -1
+}
+
+// parseScalar parses a scalar or a flow sequence
+func parseScalar(text string, line int) (interface{}, error) {
+	switch text[0] {
+	case '"':
+		if closingQuote(text) != len(text)-1 {
+			return nil, &yamlError{line, fmt.Sprintf(`malformed double quoted string %s`, text)}
+		}
+		s, err := strconv.Unquote(text)
+		if err != nil {
+			return nil, &yamlError{line, fmt.Sprintf(`malformed double quoted string %s`, text)}
+		}
+		return s, nil
+	case '\'':
+		if closingQuote(text) != len(text)-1 {
+			return nil, &yamlError{line, fmt.Sprintf(`malformed single quoted string %s`, text)}
+		}
+		return strings.ReplaceAll(text[1:len(text)-1], `''`, `'`), nil
+	case '[':
+		if !strings.HasSuffix(text, `]`) {
+			return nil, &yamlError{line, fmt.Sprintf(`unterminated flow sequence %s`, text)}
+		}
+		return parseFlowSequence(text[1:len(text)-1], line)
+	case '{':
+		if text == `{}` {
+			return map[string]interface{}{}, nil
+		}
+		return nil, &yamlError{line, `flow mappings are not supported`}
+	case '&', '*', '!', '|', '>':
+		return nil, &yamlError{line, fmt.Sprintf(`'%c' is not supported`, text[0])}
+	}
+	switch text {
+	case `~`, `null`:
+		return nil, nil
+	case `true`:
+		return true, nil
+	case `false`:
+		return false, nil
+	}
+	if i, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return i, nil
+	}
+	return text, nil
+}
+
+func parseFlowSequence(text string, line int) ([]interface{}, error) {
+	s := make([]interface{}, 0)
+	text = strings.TrimSpace(text)
+	for text != `` {
+		end := strings.IndexByte(text, ',')
+		if text[0] == '"' || text[0] == '\'' {
+			q := closingQuote(text)
+			if q < 0 {
+				return nil, &yamlError{line, `unterminated quoted string in flow sequence`}
+			}
+			end = strings.IndexByte(text[q:], ',')
+			if end >= 0 {
+				end += q
+			}
+		}
+		item := text
+		if end >= 0 {
+			item, text = text[:end], strings.TrimSpace(text[end+1:])
+		} else {
+			text = ``
+		}
+		item = strings.TrimSpace(item)
+		if item == `` {
+			return nil, &yamlError{line, `empty item in flow sequence`}
+		}
+		if item[0] == '[' || item[0] == '{' {
+			return nil, &yamlError{line, `nested flow collections are not supported`}
+		}
+		value, err := parseScalar(item, line)
+		if err != nil {
+			return nil, err
+		}
+		s = append(s, value)
+	}
+	return s, nil
+}
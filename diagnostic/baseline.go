@@ -0,0 +1,122 @@
+package diagnostic
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+
+	"github.com/lyraproj/issue/issue"
+)
+
+// BASELINE_VERSION is the version of the format of a baseline file
+const BASELINE_VERSION = 1
+
+// Baseline is a record of known issues. Issues that are in the baseline are suppressed so that a large
+// legacy code base can adopt the validator and the lint checks incrementally and only new issues are
+// reported.
+//
+// An issue is identified by its file, code, and message, but not by its position, so the issues of a
+// file remain suppressed when lines are added or removed above them. The baseline records how many times
+// each issue occurs, so adding another occurrence of a known issue to a file is reported.
+type Baseline struct {
+	counts map[baselineKey]int
+}
+
+type baselineKey struct {
+	file    string
+	code    issue.Code
+	message string
+}
+
+type baselineEntry struct {
+	File    string `json:"file"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Count   int    `json:"count"`
+}
+
+type baselineFile struct {
+	Version int              `json:"version"`
+	Issues  []*baselineEntry `json:"issues"`
+}
+
+// NewBaseline returns a baseline of the given issues
+func NewBaseline(reported []issue.Reported) *Baseline {
+	b := &Baseline{counts: make(map[baselineKey]int)}
+	for _, ri := range reported {
+		b.counts[keyOf(ri)]++
+	}
+	return b
+}
+
+// ReadBaseline reads a baseline that was written using Write
+func ReadBaseline(r io.Reader) (*Baseline, error) {
+	bf := &baselineFile{}
+	if err := json.NewDecoder(r).Decode(bf); err != nil {
+		return nil, fmt.Errorf(`malformed baseline: %s`, err.Error())
+	}
+	if bf.Version != BASELINE_VERSION {
+		return nil, fmt.Errorf(`unsupported baseline version %d`, bf.Version)
+	}
+	b := &Baseline{counts: make(map[baselineKey]int, len(bf.Issues))}
+	for _, e := range bf.Issues {
+		b.counts[baselineKey{filepath.ToSlash(e.File), issue.Code(e.Code), e.Message}] += e.Count
+	}
+	return b, nil
+}
+
+// Write writes the baseline as indented JSON with the issues sorted by file, code, and message, so that
+// changes of the baseline are easy to review
+func (b *Baseline) Write(w io.Writer) error {
+	bf := &baselineFile{Version: BASELINE_VERSION, Issues: make([]*baselineEntry, 0, len(b.counts))}
+	for k, count := range b.counts {
+		bf.Issues = append(bf.Issues, &baselineEntry{k.file, string(k.code), k.message, count})
+	}
+	sort.Slice(bf.Issues, func(i, j int) bool {
+		a, b := bf.Issues[i], bf.Issues[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Code != b.Code {
+			return a.Code < b.Code
+		}
+		return a.Message < b.Message
+	})
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent(``, `  `)
+	return enc.Encode(bf)
+}
+
+// Len returns the number of issues in the baseline
+func (b *Baseline) Len() int {
+	n := 0
+	for _, count := range b.counts {
+		n += count
+	}
+	return n
+}
+
+// Filter returns the given issues that are not in the baseline, in the order that they are given. When
+// an issue occurs more times than the baseline records, the first occurrences are suppressed and the rest
+// are returned. The baseline isn't modified, so Filter can be called by several goroutines at once.
+func (b *Baseline) Filter(reported []issue.Reported) []issue.Reported {
+	used := make(map[baselineKey]int)
+	result := make([]issue.Reported, 0, len(reported))
+	for _, ri := range reported {
+		k := keyOf(ri)
+		if used[k] < b.counts[k] {
+			used[k]++
+			continue
+		}
+		result = append(result, ri)
+	}
+	return result
+}
+
+func keyOf(ri issue.Reported) baselineKey {
+	d := FromReported(ri)
+	return baselineKey{filepath.ToSlash(d.File), d.Code, d.Message}
+}
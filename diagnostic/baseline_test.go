@@ -0,0 +1,56 @@
+package diagnostic
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+)
+
+func TestBaseline(t *testing.T) {
+	legacy := reportedFor(t, "if $x {\n  class a {}\n}\n")
+	b := bytes.NewBufferString(``)
+	if err := NewBaseline(legacy).Write(b); err != nil {
+		t.Fatal(err)
+	}
+	expected := issue.Unindent(`
+		{
+		  "version": 1,
+		  "issues": [
+		    {
+		      "file": "site.pp",
+		      "code": "VALIDATE_NOT_TOP_LEVEL",
+		      "message": "Classes, definitions, and nodes may only appear at top level or inside other classes",
+		      "count": 1
+		    }
+		  ]
+		}
+		`)
+	if b.String() != expected {
+		t.Fatalf("expected %s, got %s", expected, b.String())
+	}
+
+	baseline, err := ReadBaseline(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if baseline.Len() != 1 {
+		t.Errorf("expected one issue in the baseline, got %d", baseline.Len())
+	}
+
+	// The known issue moves down and another occurrence of it is added
+	current := reportedFor(t, "$y = 1\nif $x {\n  class a {}\n}\nif $x {\n  class b {}\n}\n")
+	found := baseline.Filter(current)
+	if len(found) != 1 || found[0].Location().Line() != 6 {
+		t.Errorf("expected only the new issue at line 6, got %v", found)
+	}
+}
+
+func TestReadBaselineErrors(t *testing.T) {
+	if _, err := ReadBaseline(bytes.NewBufferString(`{"version":2,"issues":[]}`)); err == nil || err.Error() != `unsupported baseline version 2` {
+		t.Errorf("expected version error, got %v", err)
+	}
+	if _, err := ReadBaseline(bytes.NewBufferString(`[`)); err == nil {
+		t.Errorf("expected malformed baseline error")
+	}
+}
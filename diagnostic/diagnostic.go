@@ -0,0 +1,166 @@
+// Package diagnostic converts reported issues into diagnostics with a file and a source range and writes
+// them in formats that CI tools can ingest, such as line oriented JSON and checkstyle XML.
+package diagnostic
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-parser/ast"
+	"github.com/lyraproj/puppet-parser/json"
+	"github.com/lyraproj/puppet-parser/parser"
+	"github.com/lyraproj/puppet-parser/pn"
+)
+
+// Diagnostic is an issue together with the range of the source that it was reported for
+type Diagnostic struct {
+	// File is the file that the issue was reported for
+	File string
+
+	// Range is the range of the source that the issue was reported for. The end is equal to the start
+	// and the length is zero when the issue was reported for a position rather than for an expression.
+	// All are zero when the issue has no location.
+	parser.Range
+
+	// Code is the issue code
+	Code issue.Code
+
+	// Severity is the severity of the issue
+	Severity issue.Severity
+
+	// Message is the message of the issue without the location
+	Message string
+
+	// URL is the documentation of the issue, see pn.MapDocURLs. It is empty when no documentation is
+	// mapped.
+	URL string
+}
+
+// sourceRange is a location other than an expression that has a length, such as a part of a line
+type sourceRange interface {
+	ByteOffset() int
+	ByteLength() int
+	Locator() *parser.Locator
+}
+
+// FromReported returns the diagnostic of the given issue
+func FromReported(ri issue.Reported) *Diagnostic {
+	d := &Diagnostic{Code: ri.Code(), Severity: ri.Severity(), Message: ri.Error(), URL: pn.DocURL(ri.Code())}
+	loc := ri.Location()
+	if loc == nil {
+		return d
+	}
+	d.Message = strings.TrimSuffix(d.Message, ` `+issue.LocationString(loc))
+	d.File = loc.File()
+	d.Line, d.Column = loc.Line(), loc.Pos()
+	d.EndLine, d.EndColumn = d.Line, d.Column
+	if e, ok := loc.(parser.Expression); ok {
+		d.Range = ast.RangeOf(e)
+	} else if r, ok := loc.(sourceRange); ok && r.ByteLength() > 0 {
+		end := r.ByteOffset() + r.ByteLength()
+		d.Offset, d.Length = r.ByteOffset(), r.ByteLength()
+		d.EndLine, d.EndColumn = r.Locator().LineForOffset(end), r.Locator().PosOnLine(end)
+	}
+	return d
+}
+
+// FromReportedList returns the diagnostics of the given issues
+func FromReportedList(reported []issue.Reported) []*Diagnostic {
+	ds := make([]*Diagnostic, len(reported))
+	for i, ri := range reported {
+		ds[i] = FromReported(ri)
+	}
+	return ds
+}
+
+// ToData returns the diagnostic as a hash with the keys file, range, code, severity, message, and url.
+// The range is a hash with the keys line, column, end_line, and end_column. The file and the range are
+// omitted when the issue has no location and the url when the issue has no documentation.
+func (d *Diagnostic) ToData() map[string]interface{} {
+	data := map[string]interface{}{
+		`code`:     string(d.Code),
+		`severity`: d.Severity.String(),
+		`message`:  d.Message,
+	}
+	if d.File != `` {
+		data[`file`] = d.File
+	}
+	if d.Line > 0 {
+		data[`range`] = map[string]interface{}{
+			`line`:       int64(d.Line),
+			`column`:     int64(d.Column),
+			`end_line`:   int64(d.EndLine),
+			`end_column`: int64(d.EndColumn),
+		}
+	}
+	if d.URL != `` {
+		data[`url`] = d.URL
+	}
+	return data
+}
+
+// WriteJSONLines writes each of the given diagnostics as a JSON object on a line of its own, see ToData
+func WriteJSONLines(w io.Writer, ds []*Diagnostic) error {
+	for _, d := range ds {
+		b := bytes.NewBufferString(``)
+		json.ToJson(d.ToData(), b)
+		if _, err := w.Write(b.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteCheckstyle writes the given diagnostics as a checkstyle XML report. The diagnostics are grouped
+// by file in the order that the files first appear. Each diagnostic is an error element with the issue
+// code as its source. Diagnostics without a file are reported under the given default file name.
+func WriteCheckstyle(w io.Writer, defaultFile string, ds []*Diagnostic) error {
+	files := make([]string, 0)
+	byFile := make(map[string][]*Diagnostic)
+	for _, d := range ds {
+		file := d.File
+		if file == `` {
+			file = defaultFile
+		}
+		if _, ok := byFile[file]; !ok {
+			files = append(files, file)
+		}
+		byFile[file] = append(byFile[file], d)
+	}
+
+	b := bytes.NewBufferString(xml.Header)
+	b.WriteString("<checkstyle version=\"4.3\">\n")
+	for _, file := range files {
+		fmt.Fprintf(b, "  <file name=\"%s\">\n", escape(file))
+		for _, d := range byFile[file] {
+			fmt.Fprintf(b, "    <error line=\"%d\" column=\"%d\" severity=\"%s\" message=\"%s\" source=\"%s\"/>\n",
+				d.Line, d.Column, checkstyleSeverity(d.Severity), escape(d.Message), escape(string(d.Code)))
+		}
+		b.WriteString("  </file>\n")
+	}
+	b.WriteString("</checkstyle>\n")
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// checkstyleSeverity returns the checkstyle name of the given severity
+func checkstyleSeverity(severity issue.Severity) string {
+	switch severity {
+	case issue.SEVERITY_ERROR:
+		return `error`
+	case issue.SEVERITY_WARNING, issue.SEVERITY_DEPRECATION:
+		return `warning`
+	default:
+		return `info`
+	}
+}
+
+func escape(s string) string {
+	b := bytes.NewBufferString(``)
+	xml.EscapeText(b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,90 @@
+package diagnostic
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-parser/parser"
+	"github.com/lyraproj/puppet-parser/validator"
+)
+
+func reportedFor(t *testing.T, src string) []issue.Reported {
+	t.Helper()
+	p := parser.CreateParser()
+	expr, err := p.Parse(`site.pp`, src, false)
+	if err != nil {
+		if ri, ok := err.(issue.Reported); ok {
+			return []issue.Reported{ri}
+		}
+		t.Fatal(err)
+	}
+	v := validator.NewChecker(validator.STRICT_ERROR)
+	validator.Validate(v, expr)
+	return v.Issues()
+}
+
+func TestJSONLines(t *testing.T) {
+	ds := FromReportedList(reportedFor(t, "if $x {\n  class b {\n  }\n}\n"))
+	b := bytes.NewBufferString(``)
+	if err := WriteJSONLines(b, ds); err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"code":"VALIDATE_NOT_TOP_LEVEL","file":"site.pp","message":"Classes, definitions, and nodes may only appear at top level or inside other classes","range":{"column":3,"end_column":4,"end_line":3,"line":2},"severity":"error"}` + "\n"
+	if b.String() != expected {
+		t.Errorf("expected %s, got %s", expected, b.String())
+	}
+	if d := ds[0]; d.Offset != 10 || d.Length != 13 {
+		t.Errorf("expected the class at offset 10 with length 13, got %d and %d", d.Offset, d.Length)
+	}
+}
+
+func TestParseErrorRange(t *testing.T) {
+	ds := FromReportedList(reportedFor(t, "$x = \n'a"))
+	if len(ds) != 1 {
+		t.Fatalf("expected one diagnostic, got %d", len(ds))
+	}
+	d := ds[0]
+	if d.Message != `unterminated single quoted string` || d.Line != 2 || d.Column != 1 || d.EndLine != 2 || d.EndColumn != 1 {
+		t.Errorf("unexpected diagnostic %v", d.ToData())
+	}
+}
+
+func TestLineRange(t *testing.T) {
+	expr, err := parser.CreateParser().Parse(`site.pp`, "$x = 1\n$long_name = 'long value'\n", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := validator.NewChecker(validator.STRICT_ERROR)
+	validator.ApplyMaxLineLength(v, 20)
+	validator.Validate(v, expr)
+	ds := FromReportedList(v.Issues())
+	if len(ds) != 1 {
+		t.Fatalf("expected one diagnostic, got %d", len(ds))
+	}
+	if d := ds[0]; d.Line != 2 || d.Column != 21 || d.EndLine != 2 || d.EndColumn != 26 || d.Offset != 27 || d.Length != 5 {
+		t.Errorf("unexpected diagnostic %v", d.ToData())
+	}
+}
+
+func TestCheckstyle(t *testing.T) {
+	ds := FromReportedList(reportedFor(t, "if $x {\n  class b {\n  }\n}\n"))
+	ds = append(ds, &Diagnostic{Code: `PARSE_EXTRANEOUS_COMMA`, Severity: issue.SEVERITY_WARNING, Message: `Extraneous "," & more`})
+	b := bytes.NewBufferString(``)
+	if err := WriteCheckstyle(b, `other.pp`, ds); err != nil {
+		t.Fatal(err)
+	}
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<checkstyle version="4.3">
+  <file name="site.pp">
+    <error line="2" column="3" severity="error" message="Classes, definitions, and nodes may only appear at top level or inside other classes" source="VALIDATE_NOT_TOP_LEVEL"/>
+  </file>
+  <file name="other.pp">
+    <error line="0" column="0" severity="warning" message="Extraneous &#34;,&#34; &amp; more" source="PARSE_EXTRANEOUS_COMMA"/>
+  </file>
+</checkstyle>
+`
+	if b.String() != expected {
+		t.Errorf("expected %s, got %s", expected, b.String())
+	}
+}
@@ -0,0 +1,173 @@
+package diagnostic
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+)
+
+// LineRange is a range of lines in a file. Both lines are 1-based and inclusive.
+type LineRange struct {
+	Start, End int
+}
+
+// Changes are the changed lines of the new versions of a set of files, keyed by file path in slash form.
+// The ranges of a file are sorted and don't overlap. A Changes is typically computed from a unified diff
+// using ParseUnifiedDiff, so that a CI pipeline can fail only on the issues that a change introduces.
+type Changes map[string][]LineRange
+
+var hunkHeader = regexp.MustCompile(`^@@ -\d+(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// ParseUnifiedDiff returns the lines that the given unified diff, e.g. the output of "git diff", adds or
+// changes in the new versions of the files. A line that precedes a removal is also considered changed
+// since the removal may affect it. The "b/" prefix that git adds to the new file names is stripped.
+// Deleted files are not included.
+func ParseUnifiedDiff(r io.Reader) (Changes, error) {
+	changes := make(Changes)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	file := ``
+	inFile := false
+	line := 0
+	oldRemaining, newRemaining := 0, 0
+	lineNo := 0
+	for scanner.Scan() {
+		text := scanner.Text()
+		lineNo++
+		if (oldRemaining > 0 || newRemaining > 0) && text != `` {
+			switch text[0] {
+			case '+':
+				changes.add(file, line)
+				line++
+				newRemaining--
+				continue
+			case ' ':
+				line++
+				oldRemaining--
+				newRemaining--
+				continue
+			case '-':
+				changes.add(file, max(line-1, 1))
+				oldRemaining--
+				continue
+			case '\\':
+				continue
+			}
+		}
+		switch {
+		case strings.HasPrefix(text, `+++ `):
+			file = diffFileName(text[4:])
+			inFile = true
+			oldRemaining, newRemaining = 0, 0
+		case strings.HasPrefix(text, `@@ `):
+			m := hunkHeader.FindStringSubmatch(text)
+			if m == nil || !inFile {
+				return nil, fmt.Errorf(`line %d: malformed hunk header '%s'`, lineNo, text)
+			}
+			line, _ = strconv.Atoi(m[2])
+			oldRemaining, newRemaining = hunkCount(m[1]), hunkCount(m[3])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// hunkCount returns the given line count of a hunk header, which is 1 when it's omitted
+func hunkCount(count string) int {
+	if count == `` {
+		return 1
+	}
+	n, _ := strconv.Atoi(count)
+	return n
+}
+
+// diffFileName returns the name of a file in a "+++" line of a diff, or an empty string for /dev/null
+func diffFileName(name string) string {
+	if tab := strings.IndexByte(name, '\t'); tab >= 0 {
+		name = name[:tab]
+	}
+	if strings.HasPrefix(name, `"`) {
+		if unquoted, err := strconv.Unquote(name); err == nil {
+			name = unquoted
+		}
+	}
+	if name == `/dev/null` {
+		return ``
+	}
+	return path.Clean(strings.TrimPrefix(name, `b/`))
+}
+
+// Add adds the given range of changed lines of the given file
+func (c Changes) Add(file string, r LineRange) {
+	file = path.Clean(filepath.ToSlash(file))
+	ranges := append(c[file], r)
+	// Keep the ranges sorted and merge those that overlap or are adjacent
+	for i := len(ranges) - 1; i > 0 && ranges[i].Start < ranges[i-1].Start; i-- {
+		ranges[i], ranges[i-1] = ranges[i-1], ranges[i]
+	}
+	merged := ranges[:1]
+	for _, r := range ranges[1:] {
+		last := &merged[len(merged)-1]
+		if r.Start <= last.End+1 {
+			last.End = max(last.End, r.End)
+		} else {
+			merged = append(merged, r)
+		}
+	}
+	c[file] = merged
+}
+
+func (c Changes) add(file string, line int) {
+	if file != `` {
+		c.Add(file, LineRange{line, line})
+	}
+}
+
+// Ranges returns the changed lines of the given file. A file that is named with a path that has more
+// leading directories than the path in the changes, e.g. an absolute path, matches too.
+func (c Changes) Ranges(file string) []LineRange {
+	file = path.Clean(filepath.ToSlash(file))
+	if ranges, ok := c[file]; ok {
+		return ranges
+	}
+	for f, ranges := range c {
+		if strings.HasSuffix(file, `/`+f) {
+			return ranges
+		}
+	}
+	return nil
+}
+
+// Intersects returns true if the range of the given diagnostic intersects with a changed range of its
+// file. A diagnostic without a location is considered to intersect.
+func (c Changes) Intersects(d *Diagnostic) bool {
+	if d.Line <= 0 {
+		return true
+	}
+	for _, r := range c.Ranges(d.File) {
+		if d.Line <= r.End && max(d.EndLine, d.Line) >= r.Start {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter returns the given issues that intersect with the changes, in the order that they are given
+func (c Changes) Filter(reported []issue.Reported) []issue.Reported {
+	result := make([]issue.Reported, 0, len(reported))
+	for _, ri := range reported {
+		if c.Intersects(FromReported(ri)) {
+			result = append(result, ri)
+		}
+	}
+	return result
+}
@@ -0,0 +1,67 @@
+package diagnostic
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+)
+
+func TestParseUnifiedDiff(t *testing.T) {
+	diff := issue.Unindent(`
+		diff --git a/site.pp b/site.pp
+		index 3b18e51..a9d8f1c 100644
+		--- a/site.pp
+		+++ b/site.pp
+		@@ -1,3 +1,4 @@
+		 $a = 1
+		+$b = 2
+		+$c = 3
+		 $d = 4
+		-$e = 5
+		@@ -20,2 +21,2 @@ class x {
+		 $f = 6
+		-$g = 7
+		+$g = 8
+		diff --git a/old.pp b/old.pp
+		deleted file mode 100644
+		--- a/old.pp
+		+++ /dev/null
+		@@ -1 +0,0 @@
+		-$h = 1
+		`)
+	changes, err := ParseUnifiedDiff(bytes.NewBufferString(diff))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Changes{`site.pp`: []LineRange{{2, 4}, {21, 22}}}
+	if !reflect.DeepEqual(expected, changes) {
+		t.Errorf("expected %v, got %v", expected, changes)
+	}
+
+	if _, err = ParseUnifiedDiff(bytes.NewBufferString("+++ b/x.pp\n@@ bad @@\n")); err == nil || err.Error() != `line 2: malformed hunk header '@@ bad @@'` {
+		t.Errorf("expected malformed hunk header error, got %v", err)
+	}
+}
+
+func TestChangesFilter(t *testing.T) {
+	reported := reportedFor(t, "if $x {\n  class a {\n  }\n}\nif $x {\n  class b {}\n}\n")
+	changes := Changes{}
+	changes.Add(`/work/site.pp`, LineRange{3, 3})
+	if found := changes.Filter(reported); len(found) != 0 {
+		t.Errorf("expected no issues since the path doesn't match, got %v", found)
+	}
+
+	changes = Changes{}
+	changes.Add(`site.pp`, LineRange{3, 3})
+	changes.Add(`site.pp`, LineRange{1, 1})
+	changes.Add(`site.pp`, LineRange{2, 2})
+	if !reflect.DeepEqual([]LineRange{{1, 3}}, changes[`site.pp`]) {
+		t.Errorf("expected adjacent ranges to be merged, got %v", changes[`site.pp`])
+	}
+	found := changes.Filter(reported)
+	if len(found) != 1 || found[0].Location().Line() != 2 {
+		t.Errorf("expected only the issue that spans the changed line, got %v", found)
+	}
+}
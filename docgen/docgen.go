@@ -0,0 +1,405 @@
+// Package docgen extracts the documentation of classes, defined types, functions, type aliases, and
+// plans from parsed manifests and renders it as a REFERENCE.md document in the style of Puppet Strings,
+// or exports it as JSON for documentation sites and editors.
+//
+// The documentation is taken from the comments that precede each definition, see analysis.DocComment
+// and analysis.ParseDocString. The parameter types and default values are taken from the definitions.
+package docgen
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/lyraproj/puppet-parser/analysis"
+	"github.com/lyraproj/puppet-parser/parser"
+	"github.com/lyraproj/puppet-parser/printer"
+)
+
+type (
+	// Reference is the documentation of a set of manifests, e.g. of the manifests of a module
+	Reference struct {
+		Classes      []*Entry
+		DefinedTypes []*Entry
+		Functions    []*Entry
+		DataTypes    []*Entry
+		Plans        []*Entry
+	}
+
+	// Entry is the documentation of a class, defined type, function, type alias, or plan
+	Entry struct {
+		// Expression is the definition. It provides the position.
+		Expression parser.Expression
+
+		// Kind is "class", "define", "function", "type_alias", or "plan"
+		Kind string
+
+		// Name is the name of the definition
+		Name string
+
+		// Summary is the text of the @summary tag
+		Summary string
+
+		// Description is the text before the first tag
+		Description string
+
+		// Private is true when the definition has an "@api private" tag
+		Private bool
+
+		// Params are the parameters in the order that they are declared
+		Params []*Param
+
+		// ReturnType is the declared return type of a function, or an empty string when no type is
+		// declared
+		ReturnType string
+
+		// Returns is the text of the @return tag of a function
+		Returns string
+
+		// AliasOf is the type that a type alias is an alias of
+		AliasOf string
+
+		// Examples are the @example tags in the order that they appear
+		Examples []*Example
+
+		// Doc is the parsed documentation comment
+		Doc *analysis.DocString
+	}
+
+	// Param is the documentation of a parameter
+	Param struct {
+		// Name is the name of the parameter without the leading '$'
+		Name string
+
+		// Type is the declared type, or "Any" when no type is declared
+		Type string
+
+		// Default is the source of the default value, or an empty string when the parameter is required
+		Default string
+
+		// Description is the text of the @param tag of the parameter
+		Description string
+
+		// Options are the texts of the @option tags of the parameter
+		Options []string
+	}
+
+	// Example is an @example tag
+	Example struct {
+		Title  string
+		Source string
+	}
+)
+
+// Extract returns the documentation of the definitions in the trees rooted at the given expressions. The
+// entries of each kind are sorted by name.
+func Extract(roots ...parser.Expression) *Reference {
+	r := &Reference{
+		Classes:      make([]*Entry, 0),
+		DefinedTypes: make([]*Entry, 0),
+		Functions:    make([]*Entry, 0),
+		DataTypes:    make([]*Entry, 0),
+		Plans:        make([]*Entry, 0)}
+	visit := func(path []parser.Expression, e parser.Expression) {
+		switch d := e.(type) {
+		case *parser.HostClassDefinition:
+			r.Classes = append(r.Classes, newEntry(d, `class`, d.Name(), d.Parameters()))
+		case *parser.ResourceTypeDefinition:
+			r.DefinedTypes = append(r.DefinedTypes, newEntry(d, `define`, d.Name(), d.Parameters()))
+		case *parser.FunctionDefinition:
+			entry := newEntry(d, `function`, d.Name(), d.Parameters())
+			if d.ReturnType() != nil {
+				entry.ReturnType = printer.String(d.ReturnType())
+			}
+			entry.Returns = entry.Doc.TagText(`return`)
+			r.Functions = append(r.Functions, entry)
+		case *parser.TypeAlias:
+			entry := newEntry(d, `type_alias`, d.Name(), nil)
+			entry.AliasOf = printer.String(d.Type())
+			r.DataTypes = append(r.DataTypes, entry)
+		case *parser.PlanDefinition:
+			r.Plans = append(r.Plans, newEntry(d, `plan`, d.Name(), d.Parameters()))
+		}
+	}
+	for _, root := range roots {
+		visit(nil, root)
+		root.AllContents(nil, visit)
+	}
+	for _, entries := range r.sections() {
+		sort.SliceStable(entries.entries, func(i, j int) bool { return entries.entries[i].Name < entries.entries[j].Name })
+	}
+	return r
+}
+
+func newEntry(e parser.Expression, kind string, name string, params []parser.Expression) *Entry {
+	doc := analysis.ParseDocString(analysis.DocComment(e))
+	entry := &Entry{
+		Expression:  e,
+		Kind:        kind,
+		Name:        name,
+		Summary:     doc.TagText(`summary`),
+		Description: doc.Text,
+		Private:     doc.TagText(`api`) == `private`,
+		Params:      make([]*Param, 0, len(params)),
+		Examples:    make([]*Example, 0),
+		Doc:         doc}
+	for _, p := range params {
+		param := p.(*parser.Parameter)
+		pd := &Param{Name: param.Name(), Type: `Any`, Description: doc.Param(param.Name()), Options: make([]string, 0)}
+		if param.Type() != nil {
+			pd.Type = printer.String(param.Type())
+		}
+		if param.Value() != nil {
+			pd.Default = printer.String(param.Value())
+		}
+		for _, o := range doc.TagsNamed(`option`) {
+			if o.Name == param.Name() {
+				pd.Options = append(pd.Options, o.Text)
+			}
+		}
+		entry.Params = append(entry.Params, pd)
+	}
+	for _, ex := range doc.TagsNamed(`example`) {
+		entry.Examples = append(entry.Examples, &Example{Title: ex.Name, Source: ex.Text})
+	}
+	return entry
+}
+
+type section struct {
+	title   string
+	noun    string
+	entries []*Entry
+}
+
+func (r *Reference) sections() []*section {
+	return []*section{
+		{`Classes`, `class`, r.Classes},
+		{`Defined types`, `defined type`, r.DefinedTypes},
+		{`Functions`, `function`, r.Functions},
+		{`Data types`, `data type`, r.DataTypes},
+		{`Plans`, `plan`, r.Plans},
+	}
+}
+
+// Markdown writes the reference as a REFERENCE.md document with a table of contents followed by a section
+// for each kind of definition. Private definitions are listed in the table of contents but not described.
+func (r *Reference) Markdown(w io.Writer) {
+	fmt.Fprint(w, "# Reference\n\n<!-- DO NOT EDIT: This document was generated by docgen -->\n\n## Table of Contents\n")
+	for _, s := range r.sections() {
+		if len(s.entries) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n### %s\n\n", s.title)
+		public, private := split(s.entries)
+		if len(private) == 0 {
+			writeTOC(w, public, true)
+			continue
+		}
+		fmt.Fprintf(w, "#### Public %s\n\n", s.title)
+		writeTOC(w, public, true)
+		fmt.Fprintf(w, "\n#### Private %s\n\n", s.title)
+		writeTOC(w, private, false)
+	}
+	for _, s := range r.sections() {
+		public, _ := split(s.entries)
+		if len(public) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n## %s\n", s.title)
+		for _, e := range public {
+			writeEntry(w, e, s.noun)
+		}
+	}
+}
+
+func split(entries []*Entry) ([]*Entry, []*Entry) {
+	public := make([]*Entry, 0, len(entries))
+	private := make([]*Entry, 0)
+	for _, e := range entries {
+		if e.Private {
+			private = append(private, e)
+		} else {
+			public = append(public, e)
+		}
+	}
+	return public, private
+}
+
+func writeTOC(w io.Writer, entries []*Entry, link bool) {
+	for _, e := range entries {
+		if link {
+			fmt.Fprintf(w, "* [`%s`](#%s)", e.Name, anchor(e.Name))
+		} else {
+			fmt.Fprintf(w, "* `%s`", e.Name)
+		}
+		if e.Summary != `` {
+			fmt.Fprintf(w, ": %s", e.Summary)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+func writeEntry(w io.Writer, e *Entry, noun string) {
+	fmt.Fprintf(w, "\n### <a name=\"%s\"></a>`%s`\n", anchor(e.Name), e.Name)
+	if e.Kind == `function` {
+		fmt.Fprint(w, "\nType: Puppet Language\n")
+	}
+	switch {
+	case e.Description != ``:
+		fmt.Fprintf(w, "\n%s\n", e.Description)
+	case e.Summary != ``:
+		fmt.Fprintf(w, "\n%s\n", e.Summary)
+	}
+	if e.Kind == `type_alias` {
+		fmt.Fprintf(w, "\nAlias of `%s`\n", e.AliasOf)
+	}
+	if len(e.Examples) > 0 {
+		fmt.Fprint(w, "\n#### Examples\n")
+		for _, ex := range e.Examples {
+			if ex.Title != `` {
+				fmt.Fprintf(w, "\n##### %s\n", ex.Title)
+			}
+			fmt.Fprintf(w, "\n```puppet\n%s\n```\n", ex.Source)
+		}
+	}
+	if e.Kind == `function` {
+		writeSignature(w, e)
+		return
+	}
+	if len(e.Params) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\n#### Parameters\n\nThe following parameters are available in the `%s` %s:\n\n", e.Name, noun)
+	for _, p := range e.Params {
+		fmt.Fprintf(w, "* [`%s`](#%s)\n", p.Name, paramAnchor(e, p))
+	}
+	for _, p := range e.Params {
+		writeParam(w, e, p, `#####`)
+	}
+}
+
+func writeSignature(w io.Writer, e *Entry) {
+	params := make([]string, len(e.Params))
+	for i, p := range e.Params {
+		params[i] = p.Type + ` $` + p.Name
+	}
+	fmt.Fprintf(w, "\n#### `%s(%s)`\n", e.Name, strings.Join(params, `, `))
+	if e.ReturnType != `` || e.Returns != `` {
+		returnType := e.ReturnType
+		if returnType == `` {
+			returnType = `Any`
+		}
+		fmt.Fprintf(w, "\nReturns: `%s`", returnType)
+		if e.Returns != `` {
+			fmt.Fprintf(w, " %s", e.Returns)
+		}
+		fmt.Fprintln(w)
+	}
+	for _, p := range e.Params {
+		writeParam(w, e, p, `#####`)
+	}
+}
+
+func writeParam(w io.Writer, e *Entry, p *Param, heading string) {
+	fmt.Fprintf(w, "\n%s <a name=\"%s\"></a>`%s`\n\nData type: `%s`\n", heading, paramAnchor(e, p), p.Name, p.Type)
+	if p.Description != `` {
+		fmt.Fprintf(w, "\n%s\n", p.Description)
+	}
+	if len(p.Options) > 0 {
+		fmt.Fprint(w, "\nOptions:\n\n")
+		for _, o := range p.Options {
+			fmt.Fprintf(w, "* %s\n", o)
+		}
+	}
+	if p.Default != `` {
+		fmt.Fprintf(w, "\nDefault value: `%s`\n", p.Default)
+	}
+}
+
+// anchor returns the anchor of the definition with the given name, e.g. "ntp--peer" for ntp::peer
+func anchor(name string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimPrefix(name, `::`), `::`, `--`))
+}
+
+func paramAnchor(e *Entry, p *Param) string {
+	return `-` + anchor(e.Name) + `--` + p.Name
+}
+
+// ToData returns the reference as a JSON compatible hash with the keys "classes", "defined_types",
+// "functions", "data_types", and "plans". Each key maps to an array with the data of the entries of that
+// kind, see Entry.ToData.
+func (r *Reference) ToData() map[string]interface{} {
+	data := make(map[string]interface{}, 5)
+	for _, s := range []struct {
+		key     string
+		entries []*Entry
+	}{
+		{`classes`, r.Classes},
+		{`defined_types`, r.DefinedTypes},
+		{`functions`, r.Functions},
+		{`data_types`, r.DataTypes},
+		{`plans`, r.Plans},
+	} {
+		entries := make([]interface{}, len(s.entries))
+		for i, e := range s.entries {
+			entries[i] = e.ToData()
+		}
+		data[s.key] = entries
+	}
+	return data
+}
+
+// ToData returns the entry as a JSON compatible hash. The hash has the keys "name", "kind", "file",
+// "line", "column", "private", and "docstring", a "parameters" array unless the entry is a type alias,
+// "return_type" when a function declares its return type, and "alias_of" for a type alias. The
+// "docstring" is a hash with the description in "text" and the tags in "tags", where each tag is a hash
+// with the keys "tag_name" and "text" and, for @param, @option, and @example tags, "name". A parameter
+// is a hash with the keys "name" and "type", a "description" when it's documented, "options" when it has
+// @option tags, and "default" with the source of the default value when it has one.
+func (e *Entry) ToData() map[string]interface{} {
+	data := map[string]interface{}{
+		`name`:    e.Name,
+		`kind`:    e.Kind,
+		`file`:    e.Expression.File(),
+		`line`:    int64(e.Expression.Line()),
+		`column`:  int64(e.Expression.Pos()),
+		`private`: e.Private,
+	}
+	tags := make([]interface{}, len(e.Doc.Tags))
+	for i, t := range e.Doc.Tags {
+		td := map[string]interface{}{`tag_name`: t.Tag, `text`: t.Text}
+		if t.Name != `` {
+			td[`name`] = t.Name
+		}
+		tags[i] = td
+	}
+	data[`docstring`] = map[string]interface{}{`text`: e.Description, `tags`: tags}
+	if e.Kind == `type_alias` {
+		data[`alias_of`] = e.AliasOf
+		return data
+	}
+	params := make([]interface{}, len(e.Params))
+	for i, p := range e.Params {
+		pd := map[string]interface{}{`name`: p.Name, `type`: p.Type}
+		if p.Description != `` {
+			pd[`description`] = p.Description
+		}
+		if len(p.Options) > 0 {
+			options := make([]interface{}, len(p.Options))
+			for j, o := range p.Options {
+				options[j] = o
+			}
+			pd[`options`] = options
+		}
+		if p.Default != `` {
+			pd[`default`] = p.Default
+		}
+		params[i] = pd
+	}
+	data[`parameters`] = params
+	if e.ReturnType != `` {
+		data[`return_type`] = e.ReturnType
+	}
+	return data
+}
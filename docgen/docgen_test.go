@@ -0,0 +1,201 @@
+package docgen
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-parser/json"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+const manifest = `
+# Manages NTP
+#
+# @summary Installs and configures NTP
+# @param servers The servers to use
+# @param opts
+#   Options for the service
+# @option opts :retries The number of retries
+# @example Basic usage
+#   include ntp
+class ntp(
+  Array[String] $servers = ['pool.ntp.org'],
+  $opts,
+) {
+}
+
+# @summary Configures NTP
+# @api private
+class ntp::config {}
+
+# @summary A peer
+define ntp::peer(String $host) {}
+
+# Formats a server
+# @param name The name of the server
+# @return The formatted name
+function ntp::format(String $name, Integer $port = 123) >> String {
+  "${name}:${port}"
+}
+
+# A port number
+type Ntp::Port = Integer[1, 65535]
+`
+
+func TestMarkdown(t *testing.T) {
+	expr, err := parser.CreateParser(parser.PARSER_TASKS_ENABLED).Parse(`init.pp`, manifest, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref := Extract(expr)
+	b := bytes.NewBufferString(``)
+	ref.Markdown(b)
+	// Backticks are written as ~ in the expected document
+	expected := strings.ReplaceAll(issue.Unindent(`
+    # Reference
+
+    <!-- DO NOT EDIT: This document was generated by docgen -->
+
+    ## Table of Contents
+
+    ### Classes
+
+    #### Public Classes
+
+    * [~ntp~](#ntp): Installs and configures NTP
+
+    #### Private Classes
+
+    * ~ntp::config~: Configures NTP
+
+    ### Defined types
+
+    * [~ntp::peer~](#ntp--peer): A peer
+
+    ### Functions
+
+    * [~ntp::format~](#ntp--format)
+
+    ### Data types
+
+    * [~Ntp::Port~](#ntp--port)
+
+    ## Classes
+
+    ### <a name="ntp"></a>~ntp~
+
+    Manages NTP
+
+    #### Examples
+
+    ##### Basic usage
+
+    ~~~puppet
+    include ntp
+    ~~~
+
+    #### Parameters
+
+    The following parameters are available in the ~ntp~ class:
+
+    * [~servers~](#-ntp--servers)
+    * [~opts~](#-ntp--opts)
+
+    ##### <a name="-ntp--servers"></a>~servers~
+
+    Data type: ~Array[String]~
+
+    The servers to use
+
+    Default value: ~['pool.ntp.org']~
+
+    ##### <a name="-ntp--opts"></a>~opts~
+
+    Data type: ~Any~
+
+    Options for the service
+
+    Options:
+
+    * :retries The number of retries
+
+    ## Defined types
+
+    ### <a name="ntp--peer"></a>~ntp::peer~
+
+    A peer
+
+    #### Parameters
+
+    The following parameters are available in the ~ntp::peer~ defined type:
+
+    * [~host~](#-ntp--peer--host)
+
+    ##### <a name="-ntp--peer--host"></a>~host~
+
+    Data type: ~String~
+
+    ## Functions
+
+    ### <a name="ntp--format"></a>~ntp::format~
+
+    Type: Puppet Language
+
+    Formats a server
+
+    #### ~ntp::format(String $name, Integer $port)~
+
+    Returns: ~String~ The formatted name
+
+    ##### <a name="-ntp--format--name"></a>~name~
+
+    Data type: ~String~
+
+    The name of the server
+
+    ##### <a name="-ntp--format--port"></a>~port~
+
+    Data type: ~Integer~
+
+    Default value: ~123~
+
+    ## Data types
+
+    ### <a name="ntp--port"></a>~Ntp::Port~
+
+    A port number
+
+    Alias of ~Integer[1, 65535]~
+`), `~`, "`")
+	if b.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, b.String())
+	}
+}
+
+func TestToData(t *testing.T) {
+	expr, err := parser.CreateParser().Parse(`init.pp`, manifest, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref := Extract(expr)
+	b := bytes.NewBufferString(``)
+	json.ToJson(ref.Functions[0].ToData(), b)
+	expected := `{"column":1,"docstring":{"tags":[{"name":"name","tag_name":"param","text":"The name of the server"},` +
+		`{"tag_name":"return","text":"The formatted name"}],"text":"Formats a server"},"file":"init.pp","kind":"function","line":27,` +
+		`"name":"ntp::format","parameters":[{"description":"The name of the server","name":"name","type":"String"},` +
+		`{"default":"123","name":"port","type":"Integer"}],"private":false,"return_type":"String"}` + "\n"
+	if b.String() != expected {
+		t.Errorf("expected %s, got %s", expected, b.String())
+	}
+
+	data := ref.ToData()
+	if len(data[`classes`].([]interface{})) != 2 || len(data[`plans`].([]interface{})) != 0 {
+		t.Errorf("unexpected reference %v", data)
+	}
+	alias := data[`data_types`].([]interface{})[0].(map[string]interface{})
+	if alias[`alias_of`] != `Integer[1, 65535]` || alias[`parameters`] != nil {
+		t.Errorf("unexpected type alias %v", alias)
+	}
+}
@@ -1,3 +1,5 @@
 module github.com/lyraproj/puppet-parser
 
+go 1.21
+
 require github.com/lyraproj/issue v0.0.0-20181204205859-7ed1f9741f4a
@@ -0,0 +1,246 @@
+// Package hiera parses the interpolation strings used in hiera.yaml files and Hiera data files, e.g.
+// "%{facts.os.family}" and "%{lookup('common::domain')}", so that validators of such files can report
+// issues with positions in the same way as the validators of Puppet manifests.
+package hiera
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+type (
+	// Segment is a part of a parsed interpolation string. It's a *Text, a *Variable, or a *Method
+	Segment interface {
+		issue.Location
+
+		// Offset returns the byte offset of the segment in the source that it was parsed from
+		Offset() int
+
+		// Length returns the length in bytes of the segment source
+		Length() int
+
+		// String returns the segment source
+		String() string
+	}
+
+	// Text is literal text
+	Text struct {
+		position
+
+		// Value is the text. It's empty for the empty interpolations %{} and %{::}
+		Value string
+	}
+
+	// Variable is an interpolation of a scope variable, e.g. %{facts.os.family}
+	Variable struct {
+		position
+
+		// Key is the variable name followed by the keys used to dig into its value. The variable name
+		// starts with '::' when it's explicitly a top scope variable. Quotes around segments are
+		// removed so the key of %{facts."a.b"} is ["facts", "a.b"].
+		Key []string
+	}
+
+	// Method is an interpolation using a method, e.g. %{lookup('common::domain')}
+	Method struct {
+		position
+
+		// Name is one of alias, hiera, literal, lookup, or scope
+		Name string
+
+		// Argument is the unquoted argument
+		Argument string
+
+		// Key is the argument split into key segments. It's nil for the literal method.
+		Key []string
+	}
+
+	position struct {
+		locator *parser.Locator
+		offset  int
+		length  int
+	}
+)
+
+// Methods are the names of the interpolation methods
+var Methods = map[string]bool{
+	`alias`:   true,
+	`hiera`:   true,
+	`literal`: true,
+	`lookup`:  true,
+	`scope`:   true,
+}
+
+var methodPattern = regexp.MustCompile(`\A(\w+)\((.*)\)\z`)
+
+// Parse parses the given interpolation string. The returned segments are in the order that they appear
+// in the string. Adjacent text is always contained in one segment.
+//
+// The returned error is an issue.Reported when the string is malformed.
+func Parse(filename, str string) ([]Segment, error) {
+	return parse(parser.NewLocator(filename, str))
+}
+
+// ParseSnippet is like Parse but the string is a snippet that starts at the given line and column
+// (both 1-based) and byte offset of a host document, typically the YAML file that contains the string.
+// All positions of the parsed segments and of reported issues are relative to the host document.
+func ParseSnippet(filename, str string, line, column, offset int) ([]Segment, error) {
+	return parse(parser.NewSnippetLocator(filename, str, line, column, offset))
+}
+
+func parse(locator *parser.Locator) ([]Segment, error) {
+	s := locator.String()
+	segments := make([]Segment, 0)
+	for pos := 0; pos < len(s); {
+		i := strings.Index(s[pos:], `%{`)
+		if i < 0 {
+			segments = append(segments, &Text{position{locator, pos, len(s) - pos}, s[pos:]})
+			break
+		}
+		start := pos + i
+		if i > 0 {
+			segments = append(segments, &Text{position{locator, pos, i}, s[pos:start]})
+		}
+		end := interpolationEnd(s, start+2)
+		if end < 0 {
+			return nil, parseIssue(locator, start, HIERA_UNTERMINATED_INTERPOLATION, issue.NO_ARGS)
+		}
+		segment, err := parseInterpolation(locator, start, end)
+		if err != nil {
+			return nil, err
+		}
+		if m, ok := segment.(*Method); ok && m.Name == `alias` && (start > 0 || end < len(s)) {
+			return nil, parseIssue(locator, start, HIERA_ALIAS_NOT_ALONE, issue.NO_ARGS)
+		}
+		segments = append(segments, segment)
+		pos = end
+	}
+	return segments, nil
+}
+
+// interpolationEnd returns the position after the '}' that ends the interpolation with content that
+// starts at the given position, or -1 if there is no such '}'. A '}' in a quoted string doesn't end
+// the interpolation.
+func interpolationEnd(s string, pos int) int {
+	var quote byte
+	for ; pos < len(s); pos++ {
+		c := s[pos]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '}':
+			return pos + 1
+		}
+	}
+	return -1
+}
+
+// parseInterpolation parses the interpolation between the given positions, i.e. from the start of
+// '%{' to the end of '}'
+func parseInterpolation(locator *parser.Locator, start, end int) (Segment, error) {
+	pos := position{locator, start, end - start}
+	expr := strings.TrimSpace(locator.String()[start+2 : end-1])
+	if expr == `` || expr == `::` {
+		return &Text{pos, ``}, nil
+	}
+
+	if m := methodPattern.FindStringSubmatch(expr); m != nil {
+		name := m[1]
+		if !Methods[name] {
+			return nil, parseIssue(locator, start, HIERA_UNKNOWN_INTERPOLATION_METHOD, issue.H{`name`: name})
+		}
+		arg := strings.TrimSpace(m[2])
+		if len(arg) < 3 || !(arg[0] == '"' || arg[0] == '\'') || arg[len(arg)-1] != arg[0] || strings.IndexByte(arg[1:len(arg)-1], arg[0]) >= 0 {
+			return nil, parseIssue(locator, start, HIERA_ILLEGAL_METHOD_ARGUMENT, issue.H{`name`: name})
+		}
+		arg = arg[1 : len(arg)-1]
+		method := &Method{pos, name, arg, nil}
+		if name != `literal` {
+			key, err := splitKey(locator, start, arg)
+			if err != nil {
+				return nil, err
+			}
+			method.Key = key
+		}
+		return method, nil
+	}
+
+	key, err := splitKey(locator, start, expr)
+	if err != nil {
+		return nil, err
+	}
+	return &Variable{pos, key}, nil
+}
+
+// splitKey splits the given key into its dot separated segments. Segments that are quoted may contain
+// dots. Issues are reported at the given position.
+func splitKey(locator *parser.Locator, at int, key string) ([]string, error) {
+	segments := make([]string, 0, 2)
+	for pos := 0; ; {
+		var segment string
+		if pos < len(key) && (key[pos] == '"' || key[pos] == '\'') {
+			end := strings.IndexByte(key[pos+1:], key[pos])
+			if end < 0 {
+				return nil, parseIssue(locator, at, HIERA_MALFORMED_KEY, issue.H{`key`: key})
+			}
+			segment = key[pos+1 : pos+1+end]
+			pos += end + 2
+			if pos < len(key) && key[pos] != '.' {
+				return nil, parseIssue(locator, at, HIERA_MALFORMED_KEY, issue.H{`key`: key})
+			}
+		} else {
+			end := strings.IndexByte(key[pos:], '.')
+			if end < 0 {
+				end = len(key) - pos
+			}
+			segment = key[pos : pos+end]
+			pos += end
+		}
+		if segment == `` {
+			return nil, parseIssue(locator, at, HIERA_MALFORMED_KEY, issue.H{`key`: key})
+		}
+		segments = append(segments, segment)
+		if pos >= len(key) {
+			return segments, nil
+		}
+		pos++ // skip '.'
+		if pos == len(key) {
+			return nil, parseIssue(locator, at, HIERA_MALFORMED_KEY, issue.H{`key`: key})
+		}
+	}
+}
+
+func parseIssue(locator *parser.Locator, offset int, code issue.Code, args issue.H) issue.Reported {
+	return issue.NewReported(code, issue.SEVERITY_ERROR, args, &position{locator, offset, 0})
+}
+
+func (p *position) File() string {
+	return p.locator.File()
+}
+
+func (p *position) Line() int {
+	return p.locator.LineForOffset(p.offset)
+}
+
+func (p *position) Pos() int {
+	return p.locator.PosOnLine(p.offset)
+}
+
+func (p *position) Offset() int {
+	return p.locator.HostOffset(p.offset)
+}
+
+func (p *position) Length() int {
+	return p.length
+}
+
+func (p *position) String() string {
+	return p.locator.String()[p.offset : p.offset+p.length]
+}
@@ -0,0 +1,94 @@
+package hiera
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+)
+
+func TestParse(t *testing.T) {
+	segments, err := Parse(`common.yaml`, `%{facts.os.family}/%{ lookup('x::y.z') }:%{facts."a.b".c}%{}{%{literal('%')}`)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expected := []string{
+		`Variable %{facts.os.family} [facts os family]`,
+		`Text / /`,
+		`Method %{ lookup('x::y.z') } lookup x::y.z [x::y z]`,
+		`Text : :`,
+		`Variable %{facts."a.b".c} [facts a.b c]`,
+		`Text %{} `,
+		`Text { {`,
+		`Method %{literal('%')} literal % []`,
+	}
+	if len(segments) != len(expected) {
+		t.Fatalf("expected %d segments, got %d", len(expected), len(segments))
+	}
+	for i, s := range segments {
+		var actual string
+		switch s := s.(type) {
+		case *Text:
+			actual = fmt.Sprintf(`Text %s %s`, s, s.Value)
+		case *Variable:
+			actual = fmt.Sprintf(`Variable %s %v`, s, s.Key)
+		case *Method:
+			actual = fmt.Sprintf(`Method %s %s %s %v`, s, s.Name, s.Argument, s.Key)
+		}
+		if actual != expected[i] {
+			t.Errorf("expected %s, got %s", expected[i], actual)
+		}
+	}
+	if segments[2].Offset() != 19 || segments[2].Length() != 21 || segments[2].Pos() != 20 {
+		t.Errorf("unexpected position of %s", segments[2])
+	}
+
+	segments, err = Parse(`common.yaml`, `%{::fqdn}`)
+	if err != nil || !reflect.DeepEqual(segments[0].(*Variable).Key, []string{`::fqdn`}) {
+		t.Errorf("unexpected top scope variable")
+	}
+
+	segments, err = Parse(`common.yaml`, `%{alias("x")}`)
+	if err != nil || segments[0].(*Method).Name != `alias` {
+		t.Errorf("unexpected alias")
+	}
+}
+
+func TestParseSnippet(t *testing.T) {
+	segments, err := ParseSnippet(`common.yaml`, `a %{x}`, 3, 10, 40)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if s := segments[1]; s.File() != `common.yaml` || s.Line() != 3 || s.Pos() != 12 || s.Offset() != 42 {
+		t.Errorf("unexpected position %d:%d of %s", s.Line(), s.Pos(), s)
+	}
+
+	_, err = ParseSnippet(`common.yaml`, `a %{x`, 3, 10, 40)
+	if err == nil || err.Error() != `unterminated interpolation (file: common.yaml, line: 3, column: 12)` {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	expectIssue(t, `%{x`, HIERA_UNTERMINATED_INTERPOLATION)
+	expectIssue(t, `%{'x}`, HIERA_UNTERMINATED_INTERPOLATION)
+	expectIssue(t, `a %{alias('x')}`, HIERA_ALIAS_NOT_ALONE)
+	expectIssue(t, `%{alias('x')}%{y}`, HIERA_ALIAS_NOT_ALONE)
+	expectIssue(t, `%{foo('x')}`, HIERA_UNKNOWN_INTERPOLATION_METHOD)
+	expectIssue(t, `%{lookup(x)}`, HIERA_ILLEGAL_METHOD_ARGUMENT)
+	expectIssue(t, `%{lookup('')}`, HIERA_ILLEGAL_METHOD_ARGUMENT)
+	expectIssue(t, `%{lookup('x', 'y')}`, HIERA_ILLEGAL_METHOD_ARGUMENT)
+	expectIssue(t, `%{a..b}`, HIERA_MALFORMED_KEY)
+	expectIssue(t, `%{a.}`, HIERA_MALFORMED_KEY)
+	expectIssue(t, `%{a."b"c}`, HIERA_MALFORMED_KEY)
+	expectIssue(t, `%{lookup("a.'b")}`, HIERA_MALFORMED_KEY)
+}
+
+func expectIssue(t *testing.T, str string, code issue.Code) {
+	t.Helper()
+	_, err := Parse(`common.yaml`, str)
+	if ri, ok := err.(issue.Reported); !ok || ri.Code() != code {
+		t.Errorf("expected %s for %s, got %v", code, str, err)
+	}
+}
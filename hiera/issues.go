@@ -0,0 +1,19 @@
+package hiera
+
+import "github.com/lyraproj/issue/issue"
+
+const (
+	HIERA_ALIAS_NOT_ALONE              = `HIERA_ALIAS_NOT_ALONE`
+	HIERA_ILLEGAL_METHOD_ARGUMENT      = `HIERA_ILLEGAL_METHOD_ARGUMENT`
+	HIERA_MALFORMED_KEY                = `HIERA_MALFORMED_KEY`
+	HIERA_UNKNOWN_INTERPOLATION_METHOD = `HIERA_UNKNOWN_INTERPOLATION_METHOD`
+	HIERA_UNTERMINATED_INTERPOLATION   = `HIERA_UNTERMINATED_INTERPOLATION`
+)
+
+func init() {
+	issue.Hard(HIERA_ALIAS_NOT_ALONE, `'alias' interpolation is only permitted if the expression is equal to the entire string`)
+	issue.Hard(HIERA_ILLEGAL_METHOD_ARGUMENT, `the argument of interpolation method '%{name}' must be a non empty quoted string`)
+	issue.Hard(HIERA_MALFORMED_KEY, `malformed key '%{key}'`)
+	issue.Hard(HIERA_UNKNOWN_INTERPOLATION_METHOD, `unknown interpolation method '%{name}'`)
+	issue.Hard(HIERA_UNTERMINATED_INTERPOLATION, `unterminated interpolation`)
+}
@@ -0,0 +1,233 @@
+// Package httpapi provides an http.Handler that parses, validates, and formats Puppet source so that the
+// parser can be mounted in an existing Go service.
+//
+// The handler accepts POST requests to paths that end with /parse, /validate, or /format. The body of the
+// request is the source, or a multipart/form-data batch where each file part is a source. The parser and
+// validator options are given as query parameters: the flags epp, tasks, workflow, lenient, and positions,
+// which are enabled by any value other than "false" or "0", and strict, which is "off", "warning", or
+// "error". The epp flag is implied for parts of a batch with a file name that ends with ".epp".
+//
+// The response to a single source is the JSON object that the corresponding function of the playground
+// package returns. The response to a batch is an object with the key "results" that holds one such object
+// per part, extended with the key "name", the file name of the part. Problems with the request itself are
+// reported with a 4xx or 5xx status and a JSON object with the key "error".
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/lyraproj/puppet-parser/json"
+	"github.com/lyraproj/puppet-parser/playground"
+)
+
+// Config contains the limits of a handler. A zero value means that the default is used.
+type Config struct {
+	// MaxSourceSize is the maximum size in bytes of one source. The default is 1 MiB.
+	MaxSourceSize int64
+
+	// MaxRequestSize is the maximum size in bytes of a request body. The default is 10 MiB.
+	MaxRequestSize int64
+
+	// MaxBatchSize is the maximum number of sources in a batch. The default is 100.
+	MaxBatchSize int
+
+	// Timeout is the maximum time spent on a request. The default is 10 seconds.
+	Timeout time.Duration
+}
+
+const (
+	DEFAULT_MAX_SOURCE_SIZE  = 1 << 20
+	DEFAULT_MAX_REQUEST_SIZE = 10 << 20
+	DEFAULT_MAX_BATCH_SIZE   = 100
+	DEFAULT_TIMEOUT          = 10 * time.Second
+)
+
+type handler struct {
+	Config
+}
+
+type operation func(string, playground.Options) map[string]interface{}
+
+var operations = map[string]operation{
+	`parse`:    playground.Parse,
+	`validate`: playground.Validate,
+	`format`:   playground.Format,
+}
+
+// requestError is an error that is reported to the client with the given status
+type requestError struct {
+	status  int
+	message string
+}
+
+// NewHandler returns a handler with the given limits
+func NewHandler(config Config) http.Handler {
+	if config.MaxSourceSize <= 0 {
+		config.MaxSourceSize = DEFAULT_MAX_SOURCE_SIZE
+	}
+	if config.MaxRequestSize <= 0 {
+		config.MaxRequestSize = DEFAULT_MAX_REQUEST_SIZE
+	}
+	if config.MaxBatchSize <= 0 {
+		config.MaxBatchSize = DEFAULT_MAX_BATCH_SIZE
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DEFAULT_TIMEOUT
+	}
+	return &handler{config}
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set(`Allow`, http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, `only POST is supported`)
+		return
+	}
+	op, ok := operations[path.Base(r.URL.Path)]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf(`unknown operation '%s', expected parse, validate, or format`, path.Base(r.URL.Path)))
+		return
+	}
+
+	sources, batch, err := h.readSources(r)
+	if err != nil {
+		writeError(w, err.status, err.message)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.Timeout)
+	defer cancel()
+
+	// The parser cannot be interrupted so the sources are processed in a goroutine that is abandoned when
+	// the timeout expires. Its result is then discarded.
+	done := make(chan []map[string]interface{}, 1)
+	go func() {
+		results := make([]map[string]interface{}, len(sources))
+		for i, src := range sources {
+			results[i] = op(src.text, src.options)
+			if batch {
+				results[i][`name`] = src.name
+			}
+		}
+		done <- results
+	}()
+
+	select {
+	case <-ctx.Done():
+		writeError(w, http.StatusServiceUnavailable, fmt.Sprintf(`request not completed within %s`, h.Timeout))
+	case results := <-done:
+		if batch {
+			writeJSON(w, http.StatusOK, map[string]interface{}{`results`: results})
+		} else {
+			writeJSON(w, http.StatusOK, results[0])
+		}
+	}
+}
+
+type source struct {
+	name    string
+	text    string
+	options playground.Options
+}
+
+// readSources reads the sources of the request. The returned flag is true when the request is a batch.
+func (h *handler) readSources(r *http.Request) ([]*source, bool, *requestError) {
+	options := optionsOf(r)
+	body := http.MaxBytesReader(nil, r.Body, h.MaxRequestSize)
+
+	mediaType, params, err := mime.ParseMediaType(r.Header.Get(`Content-Type`))
+	if err != nil || mediaType != `multipart/form-data` {
+		text, rerr := h.readSource(body)
+		if rerr != nil {
+			return nil, false, rerr
+		}
+		return []*source{{text: text, options: options}}, false, nil
+	}
+
+	sources := make([]*source, 0)
+	mr := multipart.NewReader(body, params[`boundary`])
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return sources, true, nil
+		}
+		if err != nil {
+			return nil, true, readError(err)
+		}
+		if part.FileName() == `` {
+			continue
+		}
+		if len(sources) == h.MaxBatchSize {
+			return nil, true, &requestError{http.StatusRequestEntityTooLarge, fmt.Sprintf(`a batch cannot contain more than %d sources`, h.MaxBatchSize)}
+		}
+		text, rerr := h.readSource(part)
+		if rerr != nil {
+			return nil, true, rerr
+		}
+		partOptions := options
+		partOptions.EPP = options.EPP || strings.HasSuffix(part.FileName(), `.epp`)
+		sources = append(sources, &source{part.FileName(), text, partOptions})
+	}
+}
+
+// readSource reads a source from the given reader and returns an error if it exceeds the maximum size
+func (h *handler) readSource(r io.Reader) (string, *requestError) {
+	content, err := ioutil.ReadAll(io.LimitReader(r, h.MaxSourceSize+1))
+	if err != nil {
+		return ``, readError(err)
+	}
+	if int64(len(content)) > h.MaxSourceSize {
+		return ``, &requestError{http.StatusRequestEntityTooLarge, fmt.Sprintf(`a source cannot be larger than %d bytes`, h.MaxSourceSize)}
+	}
+	return string(content), nil
+}
+
+func readError(err error) *requestError {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return &requestError{http.StatusRequestEntityTooLarge, err.Error()}
+	}
+	return &requestError{http.StatusBadRequest, err.Error()}
+}
+
+func optionsOf(r *http.Request) playground.Options {
+	q := r.URL.Query()
+	flag := func(key string) bool {
+		if _, ok := q[key]; !ok {
+			return false
+		}
+		v := q.Get(key)
+		return v != `false` && v != `0`
+	}
+	return playground.Options{
+		EPP:       flag(`epp`),
+		Tasks:     flag(`tasks`),
+		Workflow:  flag(`workflow`),
+		Lenient:   flag(`lenient`),
+		Positions: flag(`positions`),
+		Strict:    q.Get(`strict`),
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]interface{}{`error`: message})
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	b := bytes.NewBufferString(``)
+	json.ToJson(value, b)
+	w.Header().Set(`Content-Type`, `application/json`)
+	w.WriteHeader(status)
+	w.Write(b.Bytes())
+}
@@ -0,0 +1,110 @@
+package httpapi
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func post(t *testing.T, h http.Handler, url, contentType, body string) (int, string) {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	if contentType != `` {
+		r.Header.Set(`Content-Type`, contentType)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if ct := w.Header().Get(`Content-Type`); ct != `application/json` {
+		t.Errorf("unexpected content type %q", ct)
+	}
+	return w.Code, strings.TrimSpace(w.Body.String())
+}
+
+func batch(t *testing.T, files map[string]string) (string, string) {
+	t.Helper()
+	b := bytes.NewBufferString(``)
+	mw := multipart.NewWriter(b)
+	for _, name := range []string{`a.pp`, `b.epp`, `c.pp`} {
+		if content, ok := files[name]; ok {
+			fw, err := mw.CreateFormFile(`source`, name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fw.Write([]byte(content))
+		}
+	}
+	mw.Close()
+	return mw.FormDataContentType(), b.String()
+}
+
+func TestSingleSource(t *testing.T) {
+	h := NewHandler(Config{})
+	status, body := post(t, h, `/api/parse`, `text/plain`, `$x = 1`)
+	if status != http.StatusOK || body != `{"ast":{"^":["block",{"^":["=",{"^":["var","x"]},1]}],"pn":"1.1"},"valid":true}` {
+		t.Errorf("unexpected response %d %s", status, body)
+	}
+
+	status, body = post(t, h, `/api/validate?strict=error`, ``, `$a::b = 1`)
+	if status != http.StatusOK || !strings.Contains(body, `"code":"VALIDATE_CROSS_SCOPE_ASSIGNMENT"`) || !strings.Contains(body, `"valid":false`) {
+		t.Errorf("unexpected response %d %s", status, body)
+	}
+
+	status, body = post(t, h, `/format`, ``, `notice( 'a' )`)
+	if status != http.StatusOK || body != `{"source":"notice('a')\n","valid":true}` {
+		t.Errorf("unexpected response %d %s", status, body)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	h := NewHandler(Config{})
+	contentType, body := batch(t, map[string]string{`a.pp`: `$x = 1`, `b.epp`: `<%= $x %>`})
+	status, response := post(t, h, `/validate`, contentType, body)
+	if status != http.StatusOK || response != `{"results":[{"name":"a.pp","valid":true},{"name":"b.epp","valid":true}]}` {
+		t.Errorf("unexpected response %d %s", status, response)
+	}
+
+	h = NewHandler(Config{MaxBatchSize: 2})
+	contentType, body = batch(t, map[string]string{`a.pp`: `1`, `b.epp`: `2`, `c.pp`: `3`})
+	status, response = post(t, h, `/validate`, contentType, body)
+	if status != http.StatusRequestEntityTooLarge || response != `{"error":"a batch cannot contain more than 2 sources"}` {
+		t.Errorf("unexpected response %d %s", status, response)
+	}
+}
+
+func TestRequestErrors(t *testing.T) {
+	h := NewHandler(Config{MaxSourceSize: 10})
+
+	r := httptest.NewRequest(http.MethodGet, `/parse`, nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get(`Allow`) != `POST` {
+		t.Errorf("unexpected response %d", w.Code)
+	}
+
+	status, body := post(t, h, `/evaluate`, ``, `1`)
+	if status != http.StatusNotFound || body != `{"error":"unknown operation 'evaluate', expected parse, validate, or format"}` {
+		t.Errorf("unexpected response %d %s", status, body)
+	}
+
+	status, body = post(t, h, `/parse`, ``, `$x = 'a long string'`)
+	if status != http.StatusRequestEntityTooLarge || body != `{"error":"a source cannot be larger than 10 bytes"}` {
+		t.Errorf("unexpected response %d %s", status, body)
+	}
+
+	status, body = post(t, NewHandler(Config{MaxRequestSize: 10}), `/parse`, `multipart/form-data; boundary=x`, strings.Repeat(`x`, 100))
+	if status != http.StatusRequestEntityTooLarge {
+		t.Errorf("unexpected response %d %s", status, body)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	h := NewHandler(Config{Timeout: time.Nanosecond})
+	status, body := post(t, h, `/parse`, ``, strings.Repeat("notice({a => [1, 2, 3]})\n", 20000))
+	if status != http.StatusServiceUnavailable || body != `{"error":"request not completed within 1ns"}` {
+		t.Errorf("unexpected response %d %s", status, body)
+	}
+}
@@ -0,0 +1,140 @@
+// Package jsonrpc serves parse, validate, and format requests using JSON-RPC 2.0 over a pair of streams,
+// typically stdin and stdout, so that editor plugins and build systems can keep one process running
+// instead of starting a new one for each file.
+//
+// Each request and each response is a JSON object on a line of its own. The methods are "parse",
+// "validate", and "format". Their params is an object with the key "source" and the optional keys "epp",
+// "tasks", "workflow", "lenient", "positions", and "strict" that correspond to the fields of
+// playground.Options. The result is the object that the corresponding function of the playground package
+// returns. The method "shutdown" responds with true and makes Serve return. A request without an id is a
+// notification and gets no response, not even when it fails.
+package jsonrpc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/lyraproj/puppet-parser/playground"
+)
+
+// Error codes defined by the JSON-RPC 2.0 specification
+const (
+	PARSE_ERROR      = -32700
+	INVALID_REQUEST  = -32600
+	METHOD_NOT_FOUND = -32601
+	INVALID_PARAMS   = -32602
+)
+
+type request struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Method  string           `json:"method"`
+	Params  json.RawMessage  `json:"params"`
+}
+
+type response struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Result  interface{}      `json:"result,omitempty"`
+	Error   *responseError   `json:"error,omitempty"`
+}
+
+type responseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type params struct {
+	Source    *string `json:"source"`
+	EPP       bool    `json:"epp"`
+	Tasks     bool    `json:"tasks"`
+	Workflow  bool    `json:"workflow"`
+	Lenient   bool    `json:"lenient"`
+	Positions bool    `json:"positions"`
+	Strict    string  `json:"strict"`
+}
+
+var methods = map[string]func(string, playground.Options) map[string]interface{}{
+	`parse`:    playground.Parse,
+	`validate`: playground.Validate,
+	`format`:   playground.Format,
+}
+
+// Serve reads requests from in and writes responses to out until in is exhausted or a shutdown request
+// has been served. The returned error is an error from reading or writing the streams, or nil.
+func Serve(in io.Reader, out io.Writer) error {
+	reader := bufio.NewReader(in)
+	encoder := json.NewEncoder(out)
+	encoder.SetEscapeHTML(false)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			resp, shutdown := serveLine(line)
+			if resp != nil {
+				if werr := encoder.Encode(resp); werr != nil {
+					return werr
+				}
+			}
+			if shutdown {
+				return nil
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// serveLine returns the response to the request on the given line, or nil when the request is a
+// notification, and true if the request is a shutdown request
+func serveLine(line []byte) (*response, bool) {
+	var req request
+	if err := json.Unmarshal(line, &req); err != nil {
+		return errorResponse(nil, PARSE_ERROR, err.Error()), false
+	}
+	resp, shutdown := serve(&req)
+	if req.ID == nil {
+		return nil, shutdown
+	}
+	return resp, shutdown
+}
+
+func serve(req *request) (*response, bool) {
+	if req.JSONRPC != `2.0` || req.Method == `` {
+		return errorResponse(req.ID, INVALID_REQUEST, `expected a JSON-RPC 2.0 request with a method`), false
+	}
+	if req.Method == `shutdown` {
+		return &response{JSONRPC: `2.0`, ID: req.ID, Result: true}, true
+	}
+	method, ok := methods[req.Method]
+	if !ok {
+		return errorResponse(req.ID, METHOD_NOT_FOUND, `unknown method '`+req.Method+`'`), false
+	}
+	var p params
+	if err := json.Unmarshal(req.Params, &p); err != nil || p.Source == nil {
+		return errorResponse(req.ID, INVALID_PARAMS, `expected params with a source string`), false
+	}
+	result := method(*p.Source, playground.Options{
+		EPP:       p.EPP,
+		Tasks:     p.Tasks,
+		Workflow:  p.Workflow,
+		Lenient:   p.Lenient,
+		Positions: p.Positions,
+		Strict:    p.Strict,
+	})
+	return &response{JSONRPC: `2.0`, ID: req.ID, Result: result}, false
+}
+
+func errorResponse(id *json.RawMessage, code int, message string) *response {
+	if id == nil {
+		// The id must be null when it couldn't be determined
+		null := json.RawMessage(`null`)
+		id = &null
+	}
+	return &response{JSONRPC: `2.0`, ID: id, Error: &responseError{code, message}}
+}
@@ -0,0 +1,51 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestServe(t *testing.T) {
+	in := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"parse","params":{"source":"$x = 1"}}`,
+		``,
+		`{"jsonrpc":"2.0","id":"b","method":"validate","params":{"source":"plan foo() {}","tasks":true}}`,
+		`{"jsonrpc":"2.0","method":"format","params":{"source":"notice(1)"}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"format","params":{"source":"notice( 'a' )"}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"evaluate","params":{"source":"1"}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"parse","params":{}}`,
+		`{"id":6,"method":"parse"}`,
+		`{"jsonrpc":`,
+		`{"jsonrpc":"2.0","id":7,"method":"shutdown"}`,
+		`{"jsonrpc":"2.0","id":8,"method":"parse","params":{"source":"1"}}`,
+	}, "\n")
+	out := bytes.NewBufferString(``)
+	if err := Serve(strings.NewReader(in), out); err != nil {
+		t.Fatal(err)
+	}
+	expected := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"result":{"ast":{"^":["block",{"^":["=",{"^":["var","x"]},1]}],"pn":"1.1"},"valid":true}}`,
+		`{"jsonrpc":"2.0","id":"b","result":{"valid":true}}`,
+		`{"jsonrpc":"2.0","id":3,"result":{"source":"notice('a')\n","valid":true}}`,
+		`{"jsonrpc":"2.0","id":4,"error":{"code":-32601,"message":"unknown method 'evaluate'"}}`,
+		`{"jsonrpc":"2.0","id":5,"error":{"code":-32602,"message":"expected params with a source string"}}`,
+		`{"jsonrpc":"2.0","id":6,"error":{"code":-32600,"message":"expected a JSON-RPC 2.0 request with a method"}}`,
+		`{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"unexpected end of JSON input"}}`,
+		`{"jsonrpc":"2.0","id":7,"result":true}`,
+		``,
+	}, "\n")
+	if out.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, out.String())
+	}
+}
+
+func TestServeUntilEOF(t *testing.T) {
+	out := bytes.NewBufferString(``)
+	if err := Serve(strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"validate","params":{"source":"$a::b = 1"}}`), out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), `"code":"VALIDATE_CROSS_SCOPE_ASSIGNMENT"`) {
+		t.Errorf("unexpected response %s", out.String())
+	}
+}
@@ -6,14 +6,25 @@ import (
 	"bytes"
 	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-parser/analysis"
+	"github.com/lyraproj/puppet-parser/config"
+	"github.com/lyraproj/puppet-parser/diagnostic"
 	"github.com/lyraproj/puppet-parser/json"
+	"github.com/lyraproj/puppet-parser/jsonrpc"
 	"github.com/lyraproj/puppet-parser/parser"
 	"github.com/lyraproj/puppet-parser/pn"
+	"github.com/lyraproj/puppet-parser/sources"
 	"github.com/lyraproj/puppet-parser/validator"
 )
 
@@ -23,42 +34,264 @@ var jsonOuput = flag.Bool("j", false, "json output")
 var strict = flag.String("s", `off`, "strict (off, warning, or error)")
 var tasks = flag.Bool("t", false, "tasks")
 var workflow = flag.Bool("w", false, "workflow")
+var lenient = flag.Bool("l", false, "accept extraneous commas between statements with a warning")
+var lint = flag.Bool("L", false, "report lint issues as warnings")
+var noStoreconfigs = flag.Bool("S", false, "warn about exported resources since storeconfigs is disabled")
+var version = flag.String("P", validator.DEFAULT_LANGUAGE_VERSION.String(), "Puppet language version (5, 6, or 7)")
+var positions = flag.Bool("p", false, "include positions in output")
+var redact = flag.Bool("r", false, "redact values that flow into Sensitive")
+var redactPattern = flag.String("R", ``, "redact values that match the given regular expression (implies -r)")
+var warningsAsErrors = flag.String("W", ``, "report warnings as errors (all, or a comma separated list of issue codes)")
+var docURL = flag.String("U", ``, "URL of the documentation of each issue, where %{key} is replaced by the documentation key of the issue")
+var output = flag.String("output", `text`, "diagnostics format (text, jsonl, or checkstyle), where jsonl and checkstyle write only the diagnostics to stdout")
+var jobs = flag.Int("jobs", runtime.NumCPU(), "number of files to parse and validate in parallel")
+var watch = flag.Bool("watch", false, "validate the files again each time they change until interrupted (implies -v)")
+var configFile = flag.String("config", ``, "configuration file (defaults to the closest "+config.FILE_NAME+" of the first path)")
+var baselineFile = flag.String("baseline", ``, "baseline file of known issues that are not reported")
+var updateBaseline = flag.Bool("update-baseline", false, "write the current issues to the baseline file instead of reporting them")
+var diffFile = flag.String("diff", ``, "unified diff, e.g. the output of git diff, outside of whose changed lines issues are not reported")
+var spellFile = flag.String("spell", ``, "word list, one word per line, to check the spelling of comments and literal strings against")
+var daemon = flag.Bool("d", false, "serve JSON-RPC parse, validate, and format requests on stdin and stdout")
+var excludes = patternList{}
+
+func init() {
+	flag.Var(&excludes, "exclude", "exclude files and directories found in directories that match the given .gitignore style pattern (can be repeated)")
+}
+
+// session is the state that the command derives from its flags and arguments before it processes the files
+type session struct {
+	// cfg is the configuration file overridden by the flags that are given explicitly
+	cfg *config.Config
+
+	// redactPatterns are the patterns of the values that are redacted in addition to those that flow into
+	// Sensitive
+	redactPatterns []*regexp.Regexp
+
+	// baseline holds the known issues when a baseline file is given
+	baseline *diagnostic.Baseline
+
+	// changes holds the changed lines when a diff is given
+	changes diagnostic.Changes
+
+	// dictionary holds the known words when a word list is given
+	dictionary analysis.Dictionary
+}
+
+// patternList is a flag value that collects the values of a repeated flag
+type patternList []string
+
+func (pl *patternList) String() string {
+	return strings.Join(*pl, `,`)
+}
+
+func (pl *patternList) Set(value string) error {
+	*pl = append(*pl, value)
+	return nil
+}
 
 func main() {
 	flag.Parse()
 
+	if *daemon {
+		if err := jsonrpc.Serve(os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *docURL != `` {
+		pn.MapDocURLs(pn.DocURLTemplate(*docURL))
+	}
+
+	s := &session{redactPatterns: []*regexp.Regexp{}}
+	if *redactPattern != `` {
+		s.redactPatterns = append(s.redactPatterns, regexp.MustCompile(*redactPattern))
+		*redact = true
+	}
+
 	args := flag.Args()
-	if len(args) != 1 {
-		fmt.Fprintln(os.Stderr, "Usage: parse [options] <pp or epp file to parse>\nValid options are:")
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: parse [options] <pp or epp files, directories, or glob patterns to parse, or - for stdin>\nValid options are:")
 		flag.PrintDefaults()
 		os.Exit(1)
 	}
 
-	fileName := args[0]
-	content, err := ioutil.ReadFile(fileName)
+	var err error
+	if s.cfg, err = loadConfig(args); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+	*output = s.cfg.Output
+	ig, err := ignoreOf(s.cfg)
 	if err != nil {
-		panic(err)
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+
+	switch {
+	case *updateBaseline && *baselineFile == ``:
+		fmt.Fprintln(os.Stderr, `--update-baseline requires --baseline`)
+		os.Exit(1)
+	case *baselineFile != `` && !*updateBaseline:
+		if s.baseline, err = readBaseline(); err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+	}
+
+	if *diffFile != `` {
+		if s.changes, err = readDiff(); err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+	}
+
+	if *spellFile != `` {
+		if s.dictionary, err = readWordList(); err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+	}
+
+	if *watch {
+		*validateOnly = true
+		w := sources.NewWatcher(args, ig)
+		err = w.Watch(time.Second, nil, func(changed []string, removed []string) {
+			for _, fileName := range removed {
+				fmt.Fprintf(os.Stderr, "%s removed\n", fileName)
+			}
+			if len(changed) > 0 {
+				s.run(args[0], changed)
+				fmt.Fprintf(os.Stderr, "%d file(s) validated at %s\n", len(changed), time.Now().Format(`15:04:05`))
+			}
+		})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	fileNames, err := sources.Expand(args, ig)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+	if !s.run(args[0], fileNames) {
+		os.Exit(1)
+	}
+}
+
+// run processes the given files in parallel, writes their output in the order of the files, and returns
+// false if one of them failed
+func (s *session) run(firstArg string, fileNames []string) bool {
+	results := make([]*result, len(fileNames))
+	next := make(chan int)
+	wg := sync.WaitGroup{}
+	for i := 0; i < max(*jobs, 1); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range next {
+				results[idx] = s.process(fileNames[idx], len(fileNames) > 1)
+			}
+		}()
+	}
+	for idx := range fileNames {
+		next <- idx
+	}
+	close(next)
+	wg.Wait()
+
+	failed := false
+	reported := make([]issue.Reported, 0)
+	for _, r := range results {
+		os.Stderr.Write(r.stderr.Bytes())
+		os.Stdout.Write(r.stdout.Bytes())
+		reported = append(reported, r.reported...)
+		failed = failed || r.failed
+	}
+	if *updateBaseline {
+		return writeBaseline(reported) && !failed
+	}
+	if *output != `text` && !emitDiagnostics(firstArg, reported) {
+		failed = true
+	}
+	return !failed
+}
+
+// result is the outcome of processing one file
+type result struct {
+	stdout, stderr bytes.Buffer
+
+	// reported are the issues to emit when the output flag is jsonl or checkstyle
+	reported []issue.Reported
+
+	// failed is true when the file couldn't be read or parsed, or has errors
+	failed bool
+}
+
+// process parses and validates the file with the given name, or stdin when the name is sources.STDIN.
+// The name of the file is included in JSON output when the command processes several files.
+func (s *session) process(fileName string, several bool) *result {
+	r := &result{}
+	var content []byte
+	var err error
+	if fileName == sources.STDIN {
+		fileName = `<stdin>`
+		content, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		content, err = ioutil.ReadFile(fileName)
+	}
+	if err != nil {
+		fmt.Fprintln(&r.stderr, err.Error())
+		r.failed = true
+		return r
 	}
 
 	var result map[string]interface{}
 	if *jsonOuput {
-		result = make(map[string]interface{}, 2)
+		result = make(map[string]interface{}, 3)
+		if several {
+			result[`file`] = fileName
+		}
 	}
 
-	strictness := validator.Strict(*strict)
-
-	parseOpts := []parser.Option{}
+	parseOpts := s.cfg.ParserOptions()
 	if strings.HasSuffix(fileName, `.epp`) {
 		parseOpts = append(parseOpts, parser.PARSER_EPP_MODE)
 	}
-	if *tasks {
-		parseOpts = append(parseOpts, parser.PARSER_TASKS_ENABLED)
+
+	pnOpts := []pn.Option{}
+	if *positions {
+		pnOpts = append(pnOpts, pn.WITH_POSITIONS)
 	}
-	if *workflow {
-		parseOpts = append(parseOpts, parser.PARSER_WORKFLOW_ENABLED)
+
+	toPN := func(e parser.Expression) pn.PN {
+		if *redact {
+			return parser.RedactedPN(e, s.redactPatterns...)
+		}
+		return e.ToPN()
+	}
+
+	p := parser.CreateParser(parseOpts...).(parser.WarningsParser)
+	expr, warnings, err := p.ParseWithWarnings(fileName, string(content), false)
+	if *output != `text` || *updateBaseline {
+		if err != nil {
+			ri, ok := err.(issue.Reported)
+			if !ok || *updateBaseline {
+				fmt.Fprintln(&r.stderr, err.Error())
+				r.failed = true
+				return r
+			}
+			r.reported = []issue.Reported{ri}
+		} else {
+			r.reported = s.diagnostics(expr, warnings, s.validate(expr))
+		}
+		return r
 	}
 
-	expr, err := parser.CreateParser(parseOpts...).Parse(args[0], string(content), false)
 	if *jsonOuput {
 		if err != nil {
 			if issue, ok := err.(issue.Reported); ok {
@@ -66,16 +299,17 @@ func main() {
 			} else {
 				result[`error`] = err.Error()
 			}
-			emitJson(result)
+			emitJson(&r.stdout, result)
 			// Parse error is always SEVERITY_ERROR
-			os.Exit(1)
+			r.failed = true
+			return r
 		}
 
-		v := validator.ValidatePuppet(expr, strictness)
-		if len(v.Issues()) > 0 {
+		reported := s.diagnostics(expr, warnings, s.validate(expr))
+		if len(reported) > 0 {
 			severity := issue.Severity(issue.SEVERITY_IGNORE)
-			issues := make([]interface{}, len(v.Issues()))
-			for idx, issue := range v.Issues() {
+			issues := make([]interface{}, len(reported))
+			for idx, issue := range reported {
 				if issue.Severity() > severity {
 					severity = issue.Severity()
 				}
@@ -83,47 +317,242 @@ func main() {
 			}
 			result[`issues`] = issues
 			if severity == issue.SEVERITY_ERROR {
-				emitJson(result)
-				os.Exit(1)
+				emitJson(&r.stdout, result)
+				r.failed = true
+				return r
 			}
 		}
 
 		if !*validateOnly {
-			result[`ast`] = expr.ToPN().ToData()
+			result[`ast`] = pn.ToDataWith(toPN(expr), append(pnOpts, pn.WITH_SCHEMA_VERSION)...)
 		}
-		emitJson(result)
-		return
+		emitJson(&r.stdout, result)
+		return r
 	}
 
 	if err != nil {
-		fmt.Fprintln(os.Stderr, err.Error())
+		if ri, ok := err.(issue.Reported); ok {
+			fmt.Fprintln(&r.stderr, describe(ri))
+		} else {
+			fmt.Fprintln(&r.stderr, err.Error())
+		}
 		// Parse error is always SEVERITY_ERROR
-		os.Exit(1)
+		r.failed = true
+		return r
 	}
 
-	v := validator.ValidatePuppet(expr, strictness)
-	if len(v.Issues()) > 0 {
+	reported := s.diagnostics(expr, warnings, s.validate(expr))
+	if len(reported) > 0 {
 		severity := issue.Severity(issue.SEVERITY_IGNORE)
-		for _, issue := range v.Issues() {
-			fmt.Fprintln(os.Stderr, issue.String())
+		for _, issue := range reported {
+			fmt.Fprintln(&r.stderr, describe(issue))
 			if issue.Severity() > severity {
 				severity = issue.Severity()
 			}
 		}
 		if severity == issue.SEVERITY_ERROR {
-			os.Exit(1)
+			r.failed = true
+			return r
 		}
 	}
 
 	if !*validateOnly {
 		b := bytes.NewBufferString(``)
-		expr.ToPN().Format(b)
-		fmt.Println(b)
+		pn.FormatWith(toPN(expr), b, pnOpts...)
+		fmt.Fprintln(&r.stdout, b)
+	}
+	return r
+}
+
+func (s *session) validate(expr parser.Expression) validator.Validator {
+	v := validator.NewChecker(s.cfg.Strict)
+	s.cfg.Apply(v)
+	validator.Validate(v, expr)
+	return v
+}
+
+// loadConfig loads the configuration file given by the config flag, or the closest configuration file
+// of the first path argument, and overrides its settings with the flags that are given explicitly
+func loadConfig(args []string) (*config.Config, error) {
+	var c *config.Config
+	var err error
+	if *configFile != `` {
+		c, err = config.Load(*configFile)
+	} else {
+		start := `.`
+		if args[0] != sources.STDIN && !strings.ContainsAny(args[0], `*?[`) {
+			start = args[0]
+		}
+		c, err = config.Find(start)
+	}
+	if err != nil {
+		return nil, err
+	}
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case `t`:
+			c.Tasks = *tasks
+		case `w`:
+			c.Workflow = *workflow
+		case `l`:
+			c.Lenient = *lenient
+		case `s`:
+			c.Strict = validator.Strict(*strict)
+		case `P`:
+			c.LanguageVersion = validator.ParseLanguageVersion(*version)
+		case `L`:
+			c.Lint = *lint
+		case `S`:
+			c.NoStoreconfigs = *noStoreconfigs
+		case `W`:
+			if e := c.SetWarningsAsErrors(*warningsAsErrors); e != nil && err == nil {
+				err = e
+			}
+		case `output`:
+			c.Output = *output
+		}
+	})
+	if c.Strict == 0 {
+		c.Strict = validator.Strict(*strict)
+	}
+	if c.Output == `` {
+		c.Output = *output
+	}
+	return c, err
+}
+
+// ignoreOf returns the exclusion patterns of the exclude flags and of the configuration. The patterns
+// of the configuration are relative to the directory of the configuration file.
+func ignoreOf(c *config.Config) (*sources.Ignore, error) {
+	ig := sources.NewIgnore(excludes...)
+	if c.Path != `` && len(c.Exclude) > 0 {
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		dir, err := filepath.Rel(wd, filepath.Dir(c.Path))
+		if err != nil {
+			return nil, err
+		}
+		ig.Add(dir, strings.Join(c.Exclude, "\n"))
+	}
+	return ig, nil
+}
+
+// diagnostics returns the warnings of the parser followed by the issues of the validator and the unknown
+// words, except for those that are in the baseline or outside of the changed lines. The warnings are
+// reported as errors when the validator says so.
+func (s *session) diagnostics(expr parser.Expression, warnings []issue.Reported, v validator.Validator) []issue.Reported {
+	reported := make([]issue.Reported, 0, len(warnings)+len(v.Issues()))
+	for _, warning := range warnings {
+		reported = append(reported, v.Promoted(warning))
+	}
+	reported = append(reported, v.Issues()...)
+	if program, ok := expr.(*parser.Program); ok && s.dictionary != nil {
+		for _, ri := range analysis.CheckSpelling(program, s.dictionary) {
+			reported = append(reported, v.Promoted(ri))
+		}
+	}
+	if s.baseline != nil {
+		reported = s.baseline.Filter(reported)
+	}
+	if s.changes != nil {
+		reported = s.changes.Filter(reported)
+	}
+	return reported
+}
+
+// readBaseline reads the baseline file given by the baseline flag
+func readBaseline() (*diagnostic.Baseline, error) {
+	f, err := os.Open(*baselineFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	b, err := diagnostic.ReadBaseline(f)
+	if err != nil {
+		return nil, fmt.Errorf(`%s: %s`, *baselineFile, err.Error())
+	}
+	return b, nil
+}
+
+// readWordList reads the word list given by the spell flag
+func readWordList() (analysis.Dictionary, error) {
+	f, err := os.Open(*spellFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return analysis.ReadWordList(f)
+}
+
+// readDiff reads the unified diff given by the diff flag
+func readDiff() (diagnostic.Changes, error) {
+	f, err := os.Open(*diffFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	c, err := diagnostic.ParseUnifiedDiff(f)
+	if err != nil {
+		return nil, fmt.Errorf(`%s: %s`, *diffFile, err.Error())
+	}
+	return c, nil
+}
+
+// writeBaseline writes the given issues to the baseline file given by the baseline flag and returns
+// false if that fails
+func writeBaseline(reported []issue.Reported) bool {
+	b := bytes.NewBufferString(``)
+	baseline := diagnostic.NewBaseline(reported)
+	if err := baseline.Write(b); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return false
+	}
+	if err := ioutil.WriteFile(*baselineFile, b.Bytes(), 0644); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return false
+	}
+	fmt.Fprintf(os.Stderr, "%d issue(s) written to %s\n", baseline.Len(), *baselineFile)
+	return true
+}
+
+// describe returns the message of the given issue followed by the URL of its documentation, if any
+func describe(ri issue.Reported) string {
+	if url := pn.DocURL(ri.Code()); url != `` {
+		return ri.String() + ` See ` + url
+	}
+	return ri.String()
+}
+
+// emitDiagnostics writes the given issues to stdout in the format given by the output flag and returns
+// false if one of them is an error. Issues without a file are attributed to the given file name in
+// checkstyle output.
+func emitDiagnostics(fileName string, reported []issue.Reported) bool {
+	ds := diagnostic.FromReportedList(reported)
+	var err error
+	switch *output {
+	case `jsonl`:
+		err = diagnostic.WriteJSONLines(os.Stdout, ds)
+	case `checkstyle`:
+		err = diagnostic.WriteCheckstyle(os.Stdout, fileName, ds)
+	default:
+		err = fmt.Errorf("unknown output format '%s'", *output)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return false
+	}
+	for _, ri := range reported {
+		if ri.Severity() == issue.SEVERITY_ERROR {
+			return false
+		}
 	}
+	return true
 }
 
-func emitJson(value interface{}) {
+func emitJson(w io.Writer, value interface{}) {
 	b := bytes.NewBufferString(``)
 	json.ToJson(value, b)
-	fmt.Println(b.String())
+	fmt.Fprintln(w, b.String())
 }
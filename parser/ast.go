@@ -0,0 +1,238 @@
+package parser
+
+import (
+	"io"
+	"regexp"
+
+	"github.com/lyraproj/puppet-parser/ast"
+	"github.com/lyraproj/puppet-parser/pn"
+)
+
+// The expressions that the parser produces are declared in the ast package. The aliases and functions
+// below keep the names that they had in this package.
+type (
+	PathVisitor                 = ast.PathVisitor
+	Expression                  = ast.Expression
+	ResourceForm                = ast.ResourceForm
+	AbstractResource            = ast.AbstractResource
+	Definition                  = ast.Definition
+	BinaryExpression            = ast.BinaryExpression
+	BooleanExpression           = ast.BooleanExpression
+	CallExpression              = ast.CallExpression
+	NamedDefinition             = ast.NamedDefinition
+	QueryExpression             = ast.QueryExpression
+	UnaryExpression             = ast.UnaryExpression
+	NameExpression              = ast.NameExpression
+	LiteralValue                = ast.LiteralValue
+	LiteralNumber               = ast.LiteralNumber
+	AccessExpression            = ast.AccessExpression
+	AndExpression               = ast.AndExpression
+	ArithmeticExpression        = ast.ArithmeticExpression
+	Application                 = ast.Application
+	AssignmentExpression        = ast.AssignmentExpression
+	AttributeOperation          = ast.AttributeOperation
+	AttributesOperation         = ast.AttributesOperation
+	BlockExpression             = ast.BlockExpression
+	CallFunctionExpression      = ast.CallFunctionExpression
+	CallMethodExpression        = ast.CallMethodExpression
+	CallNamedFunctionExpression = ast.CallNamedFunctionExpression
+	CapabilityMapping           = ast.CapabilityMapping
+	CaseExpression              = ast.CaseExpression
+	CaseOption                  = ast.CaseOption
+	CollectExpression           = ast.CollectExpression
+	ComparisonExpression        = ast.ComparisonExpression
+	ConcatenatedString          = ast.ConcatenatedString
+	EppExpression               = ast.EppExpression
+	ExportedQuery               = ast.ExportedQuery
+	FunctionDefinition          = ast.FunctionDefinition
+	HeredocExpression           = ast.HeredocExpression
+	HostClassDefinition         = ast.HostClassDefinition
+	IfExpression                = ast.IfExpression
+	ImportExpression            = ast.ImportExpression
+	InExpression                = ast.InExpression
+	KeyedEntry                  = ast.KeyedEntry
+	LambdaExpression            = ast.LambdaExpression
+	LiteralBoolean              = ast.LiteralBoolean
+	LiteralDefault              = ast.LiteralDefault
+	LiteralFloat                = ast.LiteralFloat
+	LiteralHash                 = ast.LiteralHash
+	LiteralInteger              = ast.LiteralInteger
+	LiteralList                 = ast.LiteralList
+	LiteralString               = ast.LiteralString
+	Locator                     = ast.Locator
+	MatchExpression             = ast.MatchExpression
+	NamedAccessExpression       = ast.NamedAccessExpression
+	NodeDefinition              = ast.NodeDefinition
+	Nop                         = ast.Nop
+	NotExpression               = ast.NotExpression
+	OrExpression                = ast.OrExpression
+	PlanDefinition              = ast.PlanDefinition
+	Parameter                   = ast.Parameter
+	ParenthesizedExpression     = ast.ParenthesizedExpression
+	Program                     = ast.Program
+	QualifiedName               = ast.QualifiedName
+	QualifiedReference          = ast.QualifiedReference
+	RegexpExpression            = ast.RegexpExpression
+	RelationshipExpression      = ast.RelationshipExpression
+	RenderExpression            = ast.RenderExpression
+	RenderStringExpression      = ast.RenderStringExpression
+	ReservedWord                = ast.ReservedWord
+	ResourceBody                = ast.ResourceBody
+	ResourceDefaultsExpression  = ast.ResourceDefaultsExpression
+	ResourceExpression          = ast.ResourceExpression
+	ResourceOverrideExpression  = ast.ResourceOverrideExpression
+	ResourceTypeDefinition      = ast.ResourceTypeDefinition
+	SelectorEntry               = ast.SelectorEntry
+	SelectorExpression          = ast.SelectorExpression
+	SiteDefinition              = ast.SiteDefinition
+	TextExpression              = ast.TextExpression
+	TypeAlias                   = ast.TypeAlias
+	TypeDefinition              = ast.TypeDefinition
+	TypeMapping                 = ast.TypeMapping
+	UnaryMinusExpression        = ast.UnaryMinusExpression
+	UnfoldExpression            = ast.UnfoldExpression
+	LiteralUndef                = ast.LiteralUndef
+	UnlessExpression            = ast.UnlessExpression
+	VariableExpression          = ast.VariableExpression
+	VirtualQuery                = ast.VirtualQuery
+	Positioned                  = ast.Positioned
+	Default                     = ast.Default
+	ExpressionFactory           = ast.ExpressionFactory
+	NormalizeOption             = ast.NormalizeOption
+	Range                       = ast.Range
+	Source                      = ast.Source
+	DefaultVisitor              = ast.DefaultVisitor
+	Visitor                     = ast.Visitor
+	ActivityStyle               = ast.ActivityStyle
+	ActivityExpression          = ast.ActivityExpression
+)
+
+// Resource forms
+const (
+	VIRTUAL  = ast.VIRTUAL
+	EXPORTED = ast.EXPORTED
+	REGULAR  = ast.REGULAR
+)
+
+// Activity styles
+const (
+	ActivityStyleWorkflow  = ast.ActivityStyleWorkflow
+	ActivityStyleResource  = ast.ActivityStyleResource
+	ActivityStyleAction    = ast.ActivityStyleAction
+	ActivityStyleStateless = ast.ActivityStyleStateless
+)
+
+// Normalize options
+const (
+	NORMALIZE_SORT_ATTRIBUTES   = ast.NORMALIZE_SORT_ATTRIBUTES
+	NORMALIZE_EXPAND_CALLS      = ast.NORMALIZE_EXPAND_CALLS
+	NORMALIZE_SELECTORS         = ast.NORMALIZE_SELECTORS
+	NORMALIZE_STRIP_PARENTHESES = ast.NORMALIZE_STRIP_PARENTHESES
+)
+
+var DEFAULT_INSTANCE = ast.DEFAULT_INSTANCE
+
+// NewLocator is ast.NewLocator
+func NewLocator(file, content string) *Locator {
+	return ast.NewLocator(file, content)
+}
+
+// NewSourceLocator is ast.NewSourceLocator
+func NewSourceLocator(file string, source Source) *Locator {
+	return ast.NewSourceLocator(file, source)
+}
+
+// NewSnippetLocator is ast.NewSnippetLocator
+func NewSnippetLocator(file, content string, line, column, offset int) *Locator {
+	return ast.NewSnippetLocator(file, content, line, column, offset)
+}
+
+// NewSyntheticLocator is ast.NewSyntheticLocator
+func NewSyntheticLocator(label string, origin Expression) *Locator {
+	return ast.NewSyntheticLocator(label, origin)
+}
+
+// StringSource is ast.StringSource
+func StringSource(s string) Source {
+	return ast.StringSource(s)
+}
+
+// NewChunkedSource is ast.NewChunkedSource
+func NewChunkedSource(chunks ...string) Source {
+	return ast.NewChunkedSource(chunks...)
+}
+
+// DeepVisit is ast.DeepVisit
+func DeepVisit(e Expression, path []Expression, visitor PathVisitor, children ...interface{}) {
+	ast.DeepVisit(e, path, visitor, children...)
+}
+
+// ShallowVisit is ast.ShallowVisit
+func ShallowVisit(e Expression, path []Expression, visitor PathVisitor, children ...interface{}) {
+	ast.ShallowVisit(e, path, visitor, children...)
+}
+
+// DefaultFactory is ast.DefaultFactory
+func DefaultFactory() ExpressionFactory {
+	return ast.DefaultFactory()
+}
+
+// FindAll is ast.FindAll
+func FindAll[T Expression](root Expression) []T {
+	return ast.FindAll[T](root)
+}
+
+// First is ast.First
+func First[T Expression](root Expression) (T, bool) {
+	return ast.First[T](root)
+}
+
+// Walk is ast.Walk
+func Walk(v Visitor, e Expression) {
+	ast.Walk(v, e)
+}
+
+// Visit is ast.Visit
+func Visit(v Visitor, e Expression) {
+	ast.Visit(v, e)
+}
+
+// Transform is ast.Transform
+func Transform(e Expression, f func(Expression) Expression) Expression {
+	return ast.Transform(e, f)
+}
+
+// Normalize is ast.Normalize
+func Normalize(e Expression, options ...NormalizeOption) Expression {
+	return ast.Normalize(e, options...)
+}
+
+// ComposePrograms is ast.ComposePrograms
+func ComposePrograms(programs ...*Program) *Program {
+	return ast.ComposePrograms(programs...)
+}
+
+// ResolveRelativeName is ast.ResolveRelativeName
+func ResolveRelativeName(current, name string) string {
+	return ast.ResolveRelativeName(current, name)
+}
+
+// ResolveReference is ast.ResolveReference
+func ResolveReference(ref string) string {
+	return ast.ResolveReference(ref)
+}
+
+// IsAbsoluteName is ast.IsAbsoluteName
+func IsAbsoluteName(name string) bool {
+	return ast.IsAbsoluteName(name)
+}
+
+// EncodePN is ast.EncodePN
+func EncodePN(w io.Writer, e Expression, opts ...pn.Option) error {
+	return ast.EncodePN(w, e, opts...)
+}
+
+// RedactedPN is ast.RedactedPN
+func RedactedPN(e Expression, patterns ...*regexp.Regexp) pn.PN {
+	return ast.RedactedPN(e, patterns...)
+}
@@ -0,0 +1,133 @@
+// Code generated by dispatchgen. DO NOT EDIT.
+
+package parser
+
+// keywordToken returns the token of the given keyword and true, or 0 and false if the word isn't a keyword.
+// It is generated from keywords.
+func keywordToken(word string) (int, bool) {
+	switch word {
+	case "and":
+		return TOKEN_AND, true
+	case "application":
+		return TOKEN_APPLICATION, true
+	case "attr":
+		return TOKEN_ATTR, true
+	case "case":
+		return TOKEN_CASE, true
+	case "class":
+		return TOKEN_CLASS, true
+	case "consumes":
+		return TOKEN_CONSUMES, true
+	case "default":
+		return TOKEN_DEFAULT, true
+	case "define":
+		return TOKEN_DEFINE, true
+	case "else":
+		return TOKEN_ELSE, true
+	case "elsif":
+		return TOKEN_ELSIF, true
+	case "false":
+		return TOKEN_BOOLEAN, true
+	case "function":
+		return TOKEN_FUNCTION, true
+	case "if":
+		return TOKEN_IF, true
+	case "in":
+		return TOKEN_IN, true
+	case "inherits":
+		return TOKEN_INHERITS, true
+	case "node":
+		return TOKEN_NODE, true
+	case "or":
+		return TOKEN_OR, true
+	case "plan":
+		return TOKEN_PLAN, true
+	case "private":
+		return TOKEN_PRIVATE, true
+	case "produces":
+		return TOKEN_PRODUCES, true
+	case "site":
+		return TOKEN_SITE, true
+	case "true":
+		return TOKEN_BOOLEAN, true
+	case "type":
+		return TOKEN_TYPE, true
+	case "undef":
+		return TOKEN_UNDEF, true
+	case "unless":
+		return TOKEN_UNLESS, true
+	}
+	return 0, false
+}
+
+// isStatementCall returns true if the function with the given name can be called without parentheses.
+// It is generated from statementCalls.
+func isStatementCall(name string) bool {
+	switch name {
+	case "break":
+		return true
+	case "contain":
+		return true
+	case "debug":
+		return true
+	case "err":
+		return true
+	case "fail":
+		return true
+	case "import":
+		return true
+	case "include":
+		return true
+	case "info":
+		return true
+	case "next":
+		return true
+	case "notice":
+		return true
+	case "realize":
+		return true
+	case "require":
+		return true
+	case "return":
+		return true
+	case "tag":
+		return true
+	case "warning":
+		return true
+	}
+	return false
+}
+
+// workflowStyle returns the activity style that the given word declares and true, or "" and false.
+// It is generated from workflowStyles.
+func workflowStyle(word string) (ActivityStyle, bool) {
+	switch word {
+	case "action":
+		return ActivityStyleAction, true
+	case "resource":
+		return ActivityStyleResource, true
+	case "stateless":
+		return ActivityStyleStateless, true
+	case "workflow":
+		return ActivityStyleWorkflow, true
+	}
+	return "", false
+}
+
+// isKeywordTypeName returns true if the given type name is a capitalized keyword that names a type.
+// It is generated from keywordTypeNames.
+func isKeywordTypeName(name string) bool {
+	switch name {
+	case "Application":
+		return true
+	case "Class":
+		return true
+	case "Default":
+		return true
+	case "Type":
+		return true
+	case "Undef":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,139 @@
+package parser
+
+import (
+	"strings"
+
+	"github.com/lyraproj/puppet-parser/ast"
+)
+
+// EppSegmentKind tells what an EppSegment represents
+type EppSegmentKind int
+
+const (
+	// EPP_TEXT is literal text that is rendered as is
+	EPP_TEXT = EppSegmentKind(iota)
+
+	// EPP_EXPRESSION is the code of a <%= %> tag, i.e. code that produces a value that is rendered
+	EPP_EXPRESSION
+
+	// EPP_CODE is the code of a <% %> tag, e.g. a parameter declaration or the start or end of a block
+	EPP_CODE
+)
+
+// EppSegment is a segment of an EPP template as returned by ExtractEpp
+type EppSegment struct {
+	// Kind tells if the segment is text or code
+	Kind EppSegmentKind
+
+	// Text is the text that is rendered when the segment is text, i.e. text where escaped tags have been
+	// unescaped and whitespace trimmed by adjacent tags has been removed. It's the source of the code when
+	// the segment is code.
+	Text string
+
+	// Offset and Length is the range in the template source. The range of a text segment includes the
+	// comment tags that it contains. The range of a code segment excludes the tags and the whitespace
+	// that surrounds the code.
+	Offset, Length int
+}
+
+// ExtractEpp returns the segments of the given EPP template in the order that they appear. Adjacent
+// text is combined into one segment so text and code alternate unless code segments are adjacent.
+// Comment tags are omitted.
+//
+// The code of each tag is tokenized so that strings and comments that contain tag delimiters are
+// handled correctly, but it is not parsed. This makes the function suitable for search and indexing
+// where parsing each template would be too costly. The returned error is a ParseError when the
+// template contains an unbalanced comment tag or the code contains tokens that can't be lexed.
+func ExtractEpp(filename, source string, parserOptions ...Option) (segments []*EppSegment, err error) {
+	ctx := CreateParser(append(parserOptions, PARSER_EPP_MODE)...).(*parser).newContext()
+	ctx.reset(NewLocator(filename, source))
+	defer recoverParseError(&err)
+
+	segments = make([]*EppSegment, 0)
+	for {
+		start := ctx.Pos()
+		text, inTag := ctx.consumeEPPText()
+		if text != `` {
+			segments = append(segments, &EppSegment{EPP_TEXT, text, start, ctx.Pos() - start})
+		}
+		if !inTag {
+			return
+		}
+		segments = append(segments, ctx.extractEppTag())
+	}
+}
+
+// extractEppTag consumes the tag that starts at the current position and returns the segment that
+// represents its code. Tokens are consumed until the end of the tag is found or the input ends.
+func (ctx *context) extractEppTag() *EppSegment {
+	ctx.Advance(2) // <%
+	kind := EPP_CODE
+	switch c, sz := ctx.Peek(); c {
+	case '=':
+		ctx.Advance(sz)
+		kind = EPP_EXPRESSION
+	case '-':
+		ctx.Advance(sz)
+	}
+
+	start := -1
+	end := ctx.Pos()
+	for {
+		c, pos := ctx.skipWhite(false)
+		if start < 0 {
+			start = pos
+		}
+		switch {
+		case c == 0:
+			start = min(start, end)
+		case c == '%' && strings.HasPrefix(ctx.Text()[pos:], `%>`):
+			ctx.SetPos(pos + 2)
+		case c == '-' && strings.HasPrefix(ctx.Text()[pos:], `-%>`):
+			// Trim trailing whitespace and one newline
+			ctx.SetPos(pos + 3)
+			for c, sz := ctx.Peek(); c == ' ' || c == '\t' || c == '\n'; c, sz = ctx.Peek() {
+				ctx.Advance(sz)
+				if c == '\n' {
+					break
+				}
+			}
+		default:
+			ctx.SetPos(pos)
+			ctx.nextToken()
+			end = ctx.Pos()
+			continue
+		}
+		if end < start {
+			// Empty tag
+			end = start
+		}
+		return &EppSegment{kind, ctx.Text()[start:end], start, end - start}
+	}
+}
+
+// EppSourceRange is the range in the source of an EPP template of an expression that produces
+// rendered output
+type EppSourceRange struct {
+	// Expression is a RenderStringExpression or a RenderExpression
+	Expression Expression
+
+	Range
+}
+
+// EppSourceMap returns the source ranges of all expressions in the given parsed EPP template that
+// produce rendered output, in the order that they appear in the template. The range of a
+// RenderStringExpression is the range of the text that it renders, including escaped tags and
+// comment tags. The range of a RenderExpression is the range of the <%= %> tag.
+//
+// Renderers can use the map to translate errors that concern parts of the rendered output back
+// to locations in the template.
+func EppSourceMap(template Expression) []*EppSourceRange {
+	ranges := make([]*EppSourceRange, 0)
+	template.AllContents(nil, func(path []Expression, e Expression) {
+		switch e.(type) {
+		case *RenderStringExpression, *RenderExpression:
+			ranges = append(ranges, &EppSourceRange{e, ast.RangeOf(e)})
+		}
+	})
+	return ranges
+}
@@ -0,0 +1,88 @@
+package parser
+
+import (
+	"sort"
+
+	"github.com/lyraproj/issue/issue"
+)
+
+// A Feature is an optional part of the language that a parser recognizes only when the
+// Option that enables it is given to CreateParser
+type Feature struct {
+	// Option is the parser option that enables the feature
+	Option Option
+
+	// Name is the name of the feature as used in diagnostics
+	Name string
+
+	// Description is a short description of what the feature adds to the language
+	Description string
+}
+
+// featureSet is a set of options that enable features
+type featureSet uint64
+
+func (fs featureSet) has(option Option) bool {
+	return fs&(1<<uint(option)) != 0
+}
+
+func (fs *featureSet) add(option Option) {
+	*fs |= 1 << uint(option)
+}
+
+var featureRegistry = map[Option]*Feature{}
+
+func registerFeature(option Option, name, description string) {
+	featureRegistry[option] = &Feature{option, name, description}
+}
+
+func init() {
+	registerFeature(PARSER_HANDLE_BACKTICK_STRINGS, `backtick strings`, "strings delimited by '`' in which escapes are not processed")
+	registerFeature(PARSER_HANDLE_HEX_ESCAPES, `hex escapes`, `the \xNN escape in double quoted strings`)
+	registerFeature(PARSER_TASKS_ENABLED, `tasks`, `plan definitions`)
+	registerFeature(PARSER_WORKFLOW_ENABLED, `workflow`, `workflow, action, and resource activities`)
+	registerFeature(PARSER_EPP_MODE, `epp`, `text with embedded Puppet expressions`)
+	registerFeature(PARSER_LENIENT_COMMAS, `lenient commas`, `extraneous commas between statements`)
+	registerFeature(PARSER_IMPORT_COMPAT, `import`, `legacy Puppet 3 import statements`)
+}
+
+// Features returns all features ordered by the value of the option that enables them
+func Features() []*Feature {
+	features := make([]*Feature, 0, len(featureRegistry))
+	for _, f := range featureRegistry {
+		features = append(features, f)
+	}
+	sort.Slice(features, func(i, j int) bool { return features[i].Option < features[j].Option })
+	return features
+}
+
+// FeatureOf returns the feature that is enabled by the given option and true, or nil and false if the
+// option doesn't enable a feature
+func FeatureOf(option Option) (*Feature, bool) {
+	f, ok := featureRegistry[option]
+	return f, ok
+}
+
+// disabledFeatureError returns a PARSE_FEATURE_NOT_ENABLED error located at the last construct that was
+// lexed or parsed before the given error if that construct requires a feature that isn't enabled. Such a
+// construct is the likely cause of the error. The given error is returned in all other cases.
+func (ctx *context) disabledFeatureError(err error) error {
+	if ctx.disabledStart < 0 {
+		return err
+	}
+	if ri, ok := err.(issue.Reported); ok {
+		if loc, ok := ri.Location().(*location); ok && loc.byteOffset >= ctx.disabledStart {
+			ctx.SetPos(ctx.disabledStart)
+			return ctx.parseIssue2(PARSE_FEATURE_NOT_ENABLED, issue.H{`syntax`: ctx.disabledSyntax, `feature`: featureRegistry[ctx.disabledOption].Name})
+		}
+	}
+	return err
+}
+
+// useOfDisabled records that the given syntax, which starts at the given position, requires the feature
+// that is enabled by the given option
+func (ctx *context) useOfDisabled(start int, syntax string, option Option) {
+	ctx.disabledStart = start
+	ctx.disabledSyntax = syntax
+	ctx.disabledOption = option
+}
@@ -0,0 +1,134 @@
+package parser
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// A GrammarRule is a production of the grammar that the parser accepts. The grammar is maintained as a
+// table next to the parser so that fuzzers, editors, and documentation generators can stay in sync with
+// the language without parsing the Go source.
+type GrammarRule struct {
+	// Name is the name of the nonterminal that the rule defines
+	Name string
+
+	// Definition is the right hand side of the rule in ISO EBNF notation. Terminals are quoted, `[ x ]`
+	// is an optional x, `{ x }` is zero or more x, and `? text ?` describes a lexical token in prose.
+	Definition string
+
+	// Option is the parser option that enables the feature that the rule belongs to, or zero when the
+	// rule is always part of the grammar
+	Option Option
+}
+
+// grammar is ordered from the start symbol down through the precedence levels of the parser, followed
+// by definitions and lexical tokens. Each level corresponds to a method of the recursive descent parser.
+var grammar = []*GrammarRule{
+	{Name: `program`, Definition: `statements`},
+	{Name: `program`, Definition: `epp_text , { epp_block , epp_text }`, Option: PARSER_EPP_MODE},
+	{Name: `epp_block`, Definition: `( '<%' | '<%-' ) , [ '|' , [ parameters ] , '|' ] , statements , ( '%>' | '-%>' ) | '<%=' , expression , ( '%>' | '-%>' )`, Option: PARSER_EPP_MODE},
+	{Name: `statements`, Definition: `{ statement , [ ';' ] }`},
+	{Name: `statements`, Definition: `{ statement , [ ';' | ',' ] }`, Option: PARSER_LENIENT_COMMAS},
+	{Name: `statement`, Definition: `relationship , { ',' , relationship } | statement_call | definition`},
+	{Name: `statement`, Definition: `relationship , { ',' , relationship } | statement_call | definition | 'import' , string , { ',' , string }`, Option: PARSER_IMPORT_COMPAT},
+	{Name: `statement_call`, Definition: `( 'require' | 'realize' | 'include' | 'contain' | 'tag' | 'debug' | 'info' | 'notice' | 'warning' | 'err' | 'fail' | 'import' ) , argument , { ',' , argument }`},
+	{Name: `relationship`, Definition: `assignment , { ( '->' | '~>' | '<-' | '<~' ) , assignment }`},
+	{Name: `assignment`, Definition: `resource , [ ( '=' | '+=' | '-=' ) , assignment ]`},
+	{Name: `assignment`, Definition: `activity | resource , [ ( '=' | '+=' | '-=' ) , assignment ]`, Option: PARSER_WORKFLOW_ENABLED},
+	{Name: `activity`, Definition: `( 'workflow' | 'action' | 'resource' | 'stateless' ) , identifier , [ hash ] , [ '{' , statements , '}' ]`, Option: PARSER_WORKFLOW_ENABLED},
+	{Name: `resource`, Definition: `[ '@' | '@@' ] , expression , [ '{' , ( resource_bodies | attribute_operations ) , '}' ]`},
+	{Name: `resource_bodies`, Definition: `resource_body , { ';' , [ resource_body ] }`},
+	{Name: `resource_body`, Definition: `expression , ':' , attribute_operations`},
+	{Name: `attribute_operations`, Definition: `[ attribute_operation , { ',' , attribute_operation } , [ ',' ] ]`},
+	{Name: `attribute_operation`, Definition: `( identifier | keyword ) , ( '=>' | '+>' ) , expression | '*' , '=>' , expression`},
+	{Name: `expression`, Definition: `select_expression , [ ( 'produces' | 'consumes' ) , '{' , attribute_operations , '}' ]`},
+	{Name: `select_expression`, Definition: `or_expression , { '?' , '{' , selector_entries , '}' }`},
+	{Name: `selector_entries`, Definition: `[ or_expression , '=>' , expression , { ',' , or_expression , '=>' , expression } , [ ',' ] ]`},
+	{Name: `or_expression`, Definition: `and_expression , { 'or' , and_expression }`},
+	{Name: `and_expression`, Definition: `compare_expression , { 'and' , compare_expression }`},
+	{Name: `compare_expression`, Definition: `equal_expression , { ( '<' | '<=' | '>' | '>=' ) , equal_expression }`},
+	{Name: `equal_expression`, Definition: `shift_expression , { ( '==' | '!=' ) , shift_expression }`},
+	{Name: `shift_expression`, Definition: `additive_expression , { ( '<<' | '>>' ) , additive_expression }`},
+	{Name: `additive_expression`, Definition: `multiplicative_expression , { ( '+' | '-' ) , multiplicative_expression }`},
+	{Name: `multiplicative_expression`, Definition: `match_expression , { ( '*' | '/' | '%' ) , match_expression }`},
+	{Name: `match_expression`, Definition: `in_expression , { ( '=~' | '!~' ) , in_expression }`},
+	{Name: `in_expression`, Definition: `unary_expression , { 'in' , unary_expression }`},
+	{Name: `unary_expression`, Definition: `( '-' | '+' | '!' | '*' ) , unary_expression | primary_expression`},
+	{Name: `primary_expression`, Definition: `atom , { call_arguments | collect | '[' , arguments , ']' | '.' , ( identifier | type_name ) , [ call_arguments ] }`},
+	{Name: `call_arguments`, Definition: `'(' , arguments , ')' , [ lambda ] | lambda`},
+	{Name: `arguments`, Definition: `[ argument , { ',' , argument } , [ ',' ] ]`},
+	{Name: `argument`, Definition: `expression , [ '=>' , expression ]`},
+	{Name: `lambda`, Definition: `'|' , [ parameters ] , '|' , [ '>>' , parameter_type ] , '{' , statements , '}'`},
+	{Name: `collect`, Definition: `( '<|' , [ expression ] , '|>' | '<<|' , [ expression ] , '|>>' ) , [ '{' , attribute_operations , '}' ]`},
+	{Name: `atom`, Definition: `'(' , relationship , ')' | array | hash | literal | string | heredoc | regexp | type_name | identifier | variable | 'attr' | 'private' | case_expression | if_expression | unless_expression | definition`},
+	{Name: `literal`, Definition: `integer | float | 'true' | 'false' | 'default' | 'undef'`},
+	{Name: `array`, Definition: `'[' , arguments , ']'`},
+	{Name: `hash`, Definition: `'{' , [ hash_entry , { ',' , hash_entry } , [ ',' ] ] , '}'`},
+	{Name: `hash_entry`, Definition: `expression , '=>' , expression`},
+	{Name: `case_expression`, Definition: `'case' , expression , '{' , case_option , { case_option } , '}'`},
+	{Name: `case_option`, Definition: `expression , { ',' , expression } , ':' , '{' , statements , '}'`},
+	{Name: `if_expression`, Definition: `'if' , expression , '{' , statements , '}' , { 'elsif' , expression , '{' , statements , '}' } , [ 'else' , '{' , statements , '}' ]`},
+	{Name: `unless_expression`, Definition: `'unless' , expression , '{' , statements , '}' , [ 'else' , '{' , statements , '}' ]`},
+	{Name: `definition`, Definition: `class_definition | resource_type_definition | node_definition | function_definition | type_definition | application_definition | site_definition`},
+	{Name: `definition`, Definition: `class_definition | resource_type_definition | node_definition | function_definition | type_definition | application_definition | site_definition | plan_definition`, Option: PARSER_TASKS_ENABLED},
+	{Name: `class_definition`, Definition: `'class' , class_name , [ parameter_list ] , [ 'inherits' , ( class_name | 'default' ) ] , '{' , statements , '}'`},
+	{Name: `class_name`, Definition: `identifier | type_name | string | 'class'`},
+	{Name: `resource_type_definition`, Definition: `'define' , class_name , [ parameter_list ] , '{' , statements , '}'`},
+	{Name: `application_definition`, Definition: `'application' , class_name , [ parameter_list ] , '{' , statements , '}'`},
+	{Name: `site_definition`, Definition: `'site' , '{' , statements , '}'`},
+	{Name: `node_definition`, Definition: `'node' , hostname , { ',' , hostname } , [ ',' ] , [ 'inherits' , hostname ] , '{' , statements , '}'`},
+	{Name: `hostname`, Definition: `dotted_name | regexp | string | 'default'`},
+	{Name: `dotted_name`, Definition: `( identifier | type_name | integer | float ) , { '.' , ( identifier | type_name | integer | float ) }`},
+	{Name: `function_definition`, Definition: `'function' , ( identifier | type_name ) , [ parameter_list ] , [ '>>' , parameter_type ] , '{' , statements , '}'`},
+	{Name: `plan_definition`, Definition: `'plan' , ( identifier | type_name ) , [ parameter_list ] , [ '>>' , parameter_type ] , '{' , statements , '}'`, Option: PARSER_TASKS_ENABLED},
+	{Name: `type_definition`, Definition: `'type' , parameter_type , '=' , expression | 'type' , type_name , [ 'inherits' , type_name ] , '{' , statements , '}'`},
+	{Name: `parameter_list`, Definition: `'(' , [ parameters ] , ')'`},
+	{Name: `parameters`, Definition: `parameter , { ',' , parameter } , [ ',' ]`},
+	{Name: `parameter`, Definition: `[ parameter_type ] , [ '*' ] , variable , [ '=' , expression ]`},
+	{Name: `parameter_type`, Definition: `type_name , [ '[' , arguments , ']' ]`},
+	{Name: `keyword`, Definition: `'and' | 'application' | 'attr' | 'case' | 'class' | 'consumes' | 'default' | 'define' | 'else' | 'elsif' | 'false' | 'function' | 'if' | 'in' | 'inherits' | 'node' | 'or' | 'plan' | 'private' | 'produces' | 'site' | 'true' | 'type' | 'undef' | 'unless'`},
+	{Name: `identifier`, Definition: `? a name such as notice, or a qualified name such as apache::params, that starts with a lowercase letter or an underscore ?`},
+	{Name: `type_name`, Definition: `? a name such as String, or a qualified name such as Apache::Params, where each segment starts with an uppercase letter ?`},
+	{Name: `variable`, Definition: `? '$' followed by a name, a qualified name, or a decimal number ?`},
+	{Name: `integer`, Definition: `? a decimal, hexadecimal (0x), or octal (0) integer ?`},
+	{Name: `float`, Definition: `? a decimal number with a fraction and/or an exponent ?`},
+	{Name: `string`, Definition: `? a single quoted string, or a double quoted string with escapes and ${...} or $name interpolations ?`},
+	{Name: `string`, Definition: "? a single quoted string, a double quoted string with escapes and ${...} or $name interpolations, or a '`' delimited string without escapes ?", Option: PARSER_HANDLE_BACKTICK_STRINGS},
+	{Name: `heredoc`, Definition: `? '@(' tag [ ':' syntax ] [ '/' flags ] ')' followed by the text up to a line with the '|' and/or '-' prefixed end tag ?`},
+	{Name: `regexp`, Definition: `? a regular expression delimited by '/' ?`},
+	{Name: `epp_text`, Definition: `? text that contains no '<%' other than the escaped '<%%' ?`, Option: PARSER_EPP_MODE},
+}
+
+// Grammar returns the rules of the grammar that a parser created with the given options accepts. A rule
+// that belongs to a feature replaces the rule with the same name that is used when the feature is
+// disabled. The returned rules are ordered from the start symbol down to the lexical tokens.
+func Grammar(parserOptions ...Option) []*GrammarRule {
+	var features featureSet
+	for _, option := range parserOptions {
+		features.add(option)
+	}
+	rules := make([]*GrammarRule, 0, len(grammar))
+	index := make(map[string]int, len(grammar))
+	for _, rule := range grammar {
+		if rule.Option != 0 && !features.has(rule.Option) {
+			continue
+		}
+		if i, ok := index[rule.Name]; ok {
+			rules[i] = rule
+			continue
+		}
+		index[rule.Name] = len(rules)
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// EBNF returns the grammar that a parser created with the given options accepts in ISO EBNF notation,
+// one rule per line
+func EBNF(parserOptions ...Option) string {
+	b := bytes.NewBufferString(``)
+	for _, rule := range Grammar(parserOptions...) {
+		fmt.Fprintf(b, "%s = %s ;\n", rule.Name, rule.Definition)
+	}
+	return b.String()
+}
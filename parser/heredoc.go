@@ -0,0 +1,106 @@
+package parser
+
+import (
+	"strings"
+
+	"github.com/lyraproj/puppet-parser/ast"
+)
+
+// HeredocSpec is the specification of a heredoc, i.e. what is declared between the parentheses of
+// @(...) and whether the end tag is preceded by '-'
+type HeredocSpec struct {
+	// Tag is the tag that ends the heredoc
+	Tag string
+
+	// Syntax is the optional syntax of the text, e.g. "json"
+	Syntax string
+
+	// Escapes are the enabled escapes, e.g. "t$". No escapes are enabled when empty
+	Escapes string
+
+	// Interpolate is true when the tag is quoted and the text is subject to interpolation
+	Interpolate bool
+
+	// TrimNewline is true when the last newline of the text is removed, i.e. when the end tag is
+	// preceded by '-'
+	TrimNewline bool
+}
+
+// String returns the heredoc declaration of the spec, e.g. @("END":json/t)
+func (s *HeredocSpec) String() string {
+	b := strings.Builder{}
+	b.WriteString(`@(`)
+	if s.Interpolate {
+		b.WriteByte('"')
+		b.WriteString(s.Tag)
+		b.WriteByte('"')
+	} else {
+		b.WriteString(s.Tag)
+	}
+	if s.Syntax != `` {
+		b.WriteByte(':')
+		b.WriteString(s.Syntax)
+	}
+	if s.Escapes != `` {
+		b.WriteByte('/')
+		b.WriteString(s.Escapes)
+	}
+	b.WriteByte(')')
+	return b.String()
+}
+
+func (ctx *context) ParseHeredoc(filename string, spec *HeredocSpec, body string) (text Expression, err error) {
+	header := spec.String() + "\n"
+	b := strings.Builder{}
+	b.WriteString(header)
+	b.WriteString(body)
+	if body != `` && !strings.HasSuffix(body, "\n") {
+		b.WriteByte('\n')
+	}
+	if spec.TrimNewline {
+		b.WriteByte('-')
+	}
+	b.WriteString(spec.Tag)
+
+	// The locator places the body at the start of the host document so that the declaration
+	// ends up on line 0
+	ctx.reset(ast.NewHeredocLocator(filename, b.String(), -1, -len(header), nil))
+	defer recoverParseError(&err)
+
+	ctx.nextToken()
+	heredoc, ok := ctx.tokenValue.(*HeredocExpression)
+	if !ok {
+		ctx.assertToken(TOKEN_HEREDOC)
+	}
+	ctx.nextToken()
+	ctx.assertToken(TOKEN_END)
+	text = heredoc.Text()
+	return
+}
+
+// parseEppHeredoc parses the given text of a heredoc with the syntax "epp" in EPP mode and returns the
+// template, or nil when the template has syntax errors. The text is the content between the given
+// offsets after the given margin has been stripped from each line. The positions of the template are
+// mapped back through the stripped margins to positions in the host document. The mapping is exact
+// unless escapes change the number of lines of the text.
+func (ctx *context) parseEppHeredoc(text string, contentStart, contentEnd, indentStrip int) Expression {
+	margins := make([]int, 0, 8)
+	stripped := 0
+	for _, line := range strings.SplitAfter(ctx.slice(contentStart, contentEnd), "\n") {
+		if indentStrip > 0 && len(line) >= indentStrip && strings.Trim(line[:indentStrip], " \t") == `` {
+			stripped += indentStrip
+		}
+		margins = append(margins, stripped)
+	}
+	locator := ast.NewHeredocLocator(ctx.locator.File(), text, ctx.locator.LineForOffset(contentStart)-1, ctx.locator.HostOffset(contentStart), margins)
+
+	eppCtx := &context{features: ctx.features, factory: ctx.factory}
+	eppCtx.features.add(PARSER_EPP_MODE)
+	eppCtx.reset(locator)
+	template, err := eppCtx.parseTopExpression(locator.File(), false)
+	if err != nil {
+		return nil
+	}
+	ctx.warnings = append(ctx.warnings, eppCtx.warnings...)
+	return template
+}
@@ -0,0 +1,181 @@
+// The dispatchgen program generates the functions that the lexer and parser of the parser package use to
+// look up keywords, statement calls, workflow styles, and keyword type names. The functions are switches
+// on strings, which are faster than lookups in the maps that they are generated from. Run it from the
+// directory of the parser package using
+//
+//	go generate
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// table describes a map of the parser package and the function that is generated from it
+type table struct {
+	// name of the map
+	variable string
+
+	// doc comment and signature of the generated function
+	doc       string
+	signature string
+
+	// format of the return statement of a case, given the value of an entry, and the final return statement
+	found    string
+	notFound string
+}
+
+var tables = []*table{
+	{
+		variable:  `keywords`,
+		doc:       `keywordToken returns the token of the given keyword and true, or 0 and false if the word isn't a keyword`,
+		signature: `keywordToken(word string) (int, bool)`,
+		found:     `return %s, true`,
+		notFound:  `return 0, false`,
+	},
+	{
+		variable:  `statementCalls`,
+		doc:       `isStatementCall returns true if the function with the given name can be called without parentheses`,
+		signature: `isStatementCall(name string) bool`,
+		found:     `return %s`,
+		notFound:  `return false`,
+	},
+	{
+		variable:  `workflowStyles`,
+		doc:       `workflowStyle returns the activity style that the given word declares and true, or "" and false`,
+		signature: `workflowStyle(word string) (ActivityStyle, bool)`,
+		found:     `return %s, true`,
+		notFound:  `return "", false`,
+	},
+	{
+		variable:  `keywordTypeNames`,
+		doc:       `isKeywordTypeName returns true if the given type name is a capitalized keyword that names a type`,
+		signature: `isKeywordTypeName(name string) bool`,
+		found:     `return %s`,
+		notFound:  `return false`,
+	},
+}
+
+func main() {
+	dir := flag.String(`d`, `.`, `directory of the parser package`)
+	out := flag.String(`o`, `dispatch_gen.go`, `name of the generated file`)
+	flag.Parse()
+
+	source, err := Generate(*dir, *out)
+	if err == nil {
+		err = ioutil.WriteFile(filepath.Join(*dir, *out), source, 0644)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+}
+
+// Generate returns the source of the generated file with the given name for the package in the
+// given directory
+func Generate(dir, out string) ([]byte, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), `_test.go`) && fi.Name() != out
+	}, 0)
+	if err != nil {
+		return nil, err
+	}
+	pkg, ok := pkgs[`parser`]
+	if !ok {
+		return nil, fmt.Errorf(`no parser package found in %s`, dir)
+	}
+
+	b := bytes.NewBufferString("// Code generated by dispatchgen. DO NOT EDIT.\n\npackage parser\n")
+	for _, t := range tables {
+		entries, err := mapEntries(pkg, t.variable)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(b, "\n// %s.\n// It is generated from %s.\nfunc %s {\n\tswitch %s {\n", t.doc, t.variable, t.signature, argName(t.signature))
+		for _, e := range entries {
+			fmt.Fprintf(b, "\tcase %s:\n\t\t"+t.found+"\n", strconv.Quote(e.key), e.value)
+		}
+		fmt.Fprintf(b, "\t}\n\t%s\n}\n", t.notFound)
+	}
+	return format.Source(b.Bytes())
+}
+
+type entry struct {
+	key   string
+	value string
+}
+
+// mapEntries returns the entries of the map literal that is assigned to the package variable with the
+// given name, sorted by key
+func mapEntries(pkg *ast.Package, variable string) ([]entry, error) {
+	var lit *ast.CompositeLit
+	for _, file := range pkg.Files {
+		for _, decl := range file.Decls {
+			if gd, ok := decl.(*ast.GenDecl); ok && gd.Tok == token.VAR {
+				for _, spec := range gd.Specs {
+					vs := spec.(*ast.ValueSpec)
+					for i, name := range vs.Names {
+						if name.Name == variable && i < len(vs.Values) {
+							lit, _ = vs.Values[i].(*ast.CompositeLit)
+						}
+					}
+				}
+			}
+		}
+	}
+	if lit == nil {
+		return nil, fmt.Errorf(`no map literal assigned to %s`, variable)
+	}
+
+	entries := make([]entry, 0, len(lit.Elts))
+	for _, elt := range lit.Elts {
+		kv := elt.(*ast.KeyValueExpr)
+		key, err := keyString(kv.Key)
+		if err != nil {
+			return nil, fmt.Errorf(`%s: %s`, variable, err.Error())
+		}
+		value, ok := kv.Value.(*ast.Ident)
+		if !ok {
+			return nil, fmt.Errorf(`%s: the value for key %q is not an identifier`, variable, key)
+		}
+		entries = append(entries, entry{key, value.Name})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+	return entries, nil
+}
+
+// keyString returns the string of a key that is either a string literal or a call token.String(TOKEN_X),
+// where the string is the lowercase name of the token
+func keyString(key ast.Expr) (string, error) {
+	switch key := key.(type) {
+	case *ast.BasicLit:
+		if key.Kind == token.STRING {
+			return strconv.Unquote(key.Value)
+		}
+	case *ast.CallExpr:
+		if len(key.Args) == 1 {
+			if id, ok := key.Args[0].(*ast.Ident); ok && strings.HasPrefix(id.Name, `TOKEN_`) {
+				return strings.ToLower(strings.TrimPrefix(id.Name, `TOKEN_`)), nil
+			}
+		}
+	}
+	return ``, fmt.Errorf(`unsupported key at offset %d`, key.Pos())
+}
+
+// argName returns the name of the single parameter in the given signature
+func argName(signature string) string {
+	params := signature[strings.Index(signature, `(`)+1 : strings.Index(signature, `)`)]
+	return strings.Fields(params)[0]
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+func TestGeneratedIsCurrent(t *testing.T) {
+	expected, err := Generate(`../..`, `dispatch_gen.go`)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	actual, err := ioutil.ReadFile(`../../dispatch_gen.go`)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if !bytes.Equal(expected, actual) {
+		t.Error(`parser/dispatch_gen.go is out of date, run go generate in the parser directory`)
+	}
+}
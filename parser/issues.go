@@ -35,6 +35,7 @@ const (
 	PARSE_EXPECTED_ACTIVITY_OPERATION       = `PARSE_EXPECTED_ACTIVITY_OPERATION`
 	PARSE_EXPECTED_ITERATOR_STYLE           = `PARSE_EXPECTED_ITERATOR_STYLE`
 	PARSE_EXPECTED_CLASS_NAME               = `PARSE_EXPECTED_CLASS_NAME`
+	PARSE_EXPECTED_EXPRESSION               = `PARSE_EXPECTED_EXPRESSION`
 	PARSE_EXPECTED_FARROW_AFTER_KEY         = `PARSE_EXPECTED_FARROW_AFTER_KEY`
 	PARSE_EXPECTED_NAME_OR_NUMBER_AFTER_DOT = `PARSE_EXPECTED_NAME_OR_NUMBER_AFTER_DOT`
 	PARSE_EXPECTED_NAME_AFTER_FUNCTION      = `PARSE_EXPECTED_NAME_AFTER_FUNCTION`
@@ -47,13 +48,16 @@ const (
 	PARSE_EXPECTED_TYPE_NAME_AFTER_TYPE     = `PARSE_EXPECTED_TYPE_NAME_AFTER_TYPE`
 	PARSE_EXPECTED_VARIABLE                 = `PARSE_EXPECTED_VARIABLE`
 	PARSE_EXTRANEOUS_COMMA                  = `PARSE_EXTRANEOUS_COMMA`
+	PARSE_FEATURE_NOT_ENABLED               = `PARSE_FEATURE_NOT_ENABLED`
 	PARSE_ILLEGAL_EPP_PARAMETERS            = `PARSE_ILLEGAL_EPP_PARAMETERS`
 	PARSE_INVALID_ACTIVITY_ATTRIBUTE        = `PARSE_INVALID_ACTIVITY_ATTRIBUTE`
 	PARSE_INVALID_ATTRIBUTE                 = `PARSE_INVALID_ATTRIBUTE`
 	PARSE_INVALID_RESOURCE                  = `PARSE_INVALID_RESOURCE`
 	PARSE_INHERITS_MUST_BE_TYPE_NAME        = `PARSE_INHERITS_MUST_BE_TYPE_NAME`
+	PARSE_KEYWORD_CASE                      = `PARSE_KEYWORD_CASE`
 	PARSE_RESOURCE_WITHOUT_TITLE            = `PARSE_RESOURCE_WITHOUT_TITLE`
 	PARSE_QUOTED_NOT_VALID_NAME             = `PARSE_QUOTED_NOT_VALID_NAME`
+	PARSE_TRAILING_INPUT                    = `PARSE_TRAILING_INPUT`
 )
 
 func init() {
@@ -88,6 +92,7 @@ func init() {
 	issue.Hard(PARSE_EXPECTED_ACTIVITY_STYLE, `expected one of 'action', 'resource', or 'workflow'`)
 	issue.Hard(PARSE_EXPECTED_ATTRIBUTE_NAME, `expected attribute name`)
 	issue.Hard(PARSE_EXPECTED_CLASS_NAME, `expected name of class`)
+	issue.Hard(PARSE_EXPECTED_EXPRESSION, `expected an expression`)
 	issue.Hard(PARSE_EXPECTED_FARROW_AFTER_KEY, `expected '=>' to follow hash key`)
 	issue.Hard(PARSE_EXPECTED_HOSTNAME, `hostname expected`)
 	issue.Hard(PARSE_EXPECTED_NAME_OR_NUMBER_AFTER_DOT, `expected name or number to follow '.'`)
@@ -100,11 +105,15 @@ func init() {
 	issue.Hard(PARSE_EXPECTED_TYPE_NAME_AFTER_TYPE, `expected type name to follow 'type'`)
 	issue.Hard(PARSE_EXPECTED_VARIABLE, `expected variable declaration`)
 	issue.Hard(PARSE_EXTRANEOUS_COMMA, `Extraneous comma between statements`)
+
+	issue.Hard(PARSE_FEATURE_NOT_ENABLED, `Syntax error at '%{syntax}', which requires the '%{feature}' feature. The feature is not enabled`)
 	issue.Hard(PARSE_ILLEGAL_EPP_PARAMETERS, `Ambiguous EPP parameter expression. Probably missing '<%%-' before parameters to remove leading whitespace`)
 	issue.Hard(PARSE_INVALID_ACTIVITY_ATTRIBUTE, `Attribute '%{name}' is not valid in a '%{style}' definition`)
 	issue.Hard(PARSE_INVALID_ATTRIBUTE, `invalid attribute operation`)
 	issue.Hard(PARSE_INVALID_RESOURCE, `invalid resource expression`)
 	issue.Hard(PARSE_INHERITS_MUST_BE_TYPE_NAME, `expected type name to follow 'inherits'`)
+	issue.Hard(PARSE_KEYWORD_CASE, `Syntax error after '%{name}', which is a type name and not a keyword. Did you mean '%{keyword}'?`)
 	issue.Hard(PARSE_RESOURCE_WITHOUT_TITLE, `This expression is invalid. Did you try declaring a '%{name}' resource without a title?`)
 	issue.Hard(PARSE_QUOTED_NOT_VALID_NAME, `a quoted string is not valid as a name at this location`)
+	issue.Hard(PARSE_TRAILING_INPUT, `unexpected '%{actual}' after the end of the expression`)
 }
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"encoding/json"
 	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-parser/pn"
+	"regexp"
 	"testing"
 )
 
@@ -26,23 +28,177 @@ func TestManifest(t *testing.T) {
 		`{"^":["block",{"^":["resource",{"#":["type",{"^":["qn","file"]},"bodies",[{"#":["title","/tmp/foo","ops",[{"^":["=>","mode","0640"]},{"^":["=>","ensure",{"^":["qn","present"]}]}]]},{"#":["title","/tmp/bar","ops",[{"^":["=>","mode","0640"]},{"^":["=>","ensure",{"^":["qn","present"]}]}]]}]]}]},{"^":["=",{"^":["var","rootgroup"]},{"^":["?",{"^":["access",{"^":["access",{"^":["var","facts"]},"os"]},"family"]},[{"^":["=>","Solaris","wheel"]}]]}]},{"^":["function",{"#":["name","foo","params",{"#":["in",{"#":["type",{"^":["access",{"^":["qr","Integer"]},2,3]}]},"n",{"#":["type",{"^":["qr","String"]},"value","vi"]}]},"body",[{"^":["invoke",{"#":["functor",{"^":["qn","notice"]},"args",[{"^":["concat","show the ",{"^":["str",{"^":["var","n"]}]}]}]]}]},{"^":["*",{"^":["var","in"]},3.14]}],"returns",{"^":["access",{"^":["qr","Float"]},0]}]}]}]}`)
 }
 
-func toJSON(e Expression) string {
+func TestPositions(t *testing.T) {
+	// The block of the program spans the whole source
+	expectJSON(t, issue.Unindent(`
+      $x = 'a'
+      notice($x)`),
+		`{"@":[1,1,0,19],"^":["block",{"@":[1,1,0,15],"^":["=",{"@":[1,1,0,4],"^":["var","x"]},{"=":"a","@":[1,6,5,3]}]},{"@":[2,1,9,10],"^":["invoke",{"#":["functor",{"@":[2,1,9,6],"^":["qn","notice"]},"args",[{"@":[2,8,16,3],"^":["var","x"]}]]}]}]}`,
+		pn.WITH_POSITIONS)
+
+	expr, _ := CreateParser().Parse(``, `$x = 'a'`, true)
+	b := bytes.NewBufferString(``)
+	pn.FormatWith(pn.Locate(expr.ToPN(), expr), b, pn.WITH_POSITIONS)
+	expected := `^[1 1 0 8] (= ^[1 1 0 4] (var "x") ^[1 6 5 3] "a")`
+	if expected != b.String() {
+		t.Errorf("expected '%s', got '%s'", expected, b.String())
+	}
+}
+
+func toJSON(e Expression, opts ...pn.Option) string {
 	result := bytes.NewBufferString(``)
 	enc := json.NewEncoder(result)
 	enc.SetEscapeHTML(false)
-	enc.Encode(e.ToPN().ToData())
+	enc.Encode(pn.ToDataWith(e.ToPN(), opts...))
 	result.Truncate(result.Len() - 1)
 	return result.String()
 }
 
-func expectJSON(t *testing.T, source string, expected string) {
+func expectJSON(t *testing.T, source string, expected string, opts ...pn.Option) {
 	expr, err := CreateParser().Parse(``, source, false)
 	if err != nil {
-		t.Errorf(err.Error())
+		t.Error(err.Error())
 	} else {
-		actual := toJSON(expr)
+		actual := toJSON(expr, opts...)
 		if expected != actual {
 			t.Errorf("expected '%s', got '%s'", expected, actual)
 		}
 	}
 }
+
+func TestEncodePN(t *testing.T) {
+	source := issue.Unindent(`
+      file { '/tmp/foo':
+        mode => '0640',
+        ensure => present;
+      }
+      $x = "<&> ${y}   \t"
+      $rootgroup = $facts['os']['family'] ? 'Solaris' => 'wheel'
+      notice(0.5e-9, 3.14, undef, true, [1, 2])`)
+
+	expr, err := CreateParser().Parse(``, source, false)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	for _, opts := range [][]pn.Option{{}, {pn.WITH_POSITIONS}, {pn.WITH_SCHEMA_VERSION}, {pn.WITH_POSITIONS, pn.WITH_SCHEMA_VERSION}} {
+		expected := toJSON(expr, opts...)
+		b := bytes.NewBufferString(``)
+		if err = EncodePN(b, expr, opts...); err != nil {
+			t.Fatal(err.Error())
+		}
+		actual := b.String()
+		if expected+"\n" != actual {
+			t.Errorf("expected '%s', got '%s'", expected, actual)
+		}
+	}
+}
+
+func TestSchema(t *testing.T) {
+	source := issue.Unindent(`
+      class foo::bar(Integer $x = 3) inherits foo {
+        File <<| tag == 'a' |>> { mode => '0640' }
+        unless $x > 2 { fail("too small ${x}") } else { $y = [*$x, -$x, !$x] }
+        $z = $x ? { 1 => a, default => b }
+        case $facts['os'] { /Debian|Ubuntu/: { include apt } default: {} }
+      }
+      define foo::baz() { notify { $title: message => @(END) } }
+        text
+        |-END
+      node default { class { 'foo::bar': x => 4 } -> Service['a'] }
+      type MyType = Object[{ attributes => { a => Integer } }]
+      function foo::f($a) >> String { $a.map |$v| { "${v}" } }
+      $h = { 'a' => 1 } + { 'b' => 2.5 }`)
+
+	expr, err := CreateParser().Parse(``, source, false)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	b := bytes.NewBufferString(``)
+	if err = EncodePN(b, expr, pn.WITH_POSITIONS, pn.WITH_SCHEMA_VERSION); err != nil {
+		t.Fatal(err.Error())
+	}
+	var data map[string]interface{}
+	if err = json.Unmarshal(b.Bytes(), &data); err != nil {
+		t.Fatal(err.Error())
+	}
+	if data[`pn`] != pn.SCHEMA_VERSION {
+		t.Errorf("expected schema version %s, got %v", pn.SCHEMA_VERSION, data[`pn`])
+	}
+	if err = pn.ValidateData(data); err != nil {
+		t.Error(err.Error())
+	}
+
+	data[`pn`] = `2.0`
+	if err = pn.ValidateData(data); err == nil {
+		t.Error(`expected incompatible schema version to be detected`)
+	}
+	if err = pn.ValidateData(map[string]interface{}{`^`: []interface{}{`nosuchtag`}}); err == nil {
+		t.Error(`expected unknown tag to be detected`)
+	}
+}
+
+func TestRedactedPN(t *testing.T) {
+	expr, err := CreateParser().Parse(``, issue.Unindent(`
+      $a = Sensitive('secret')
+      $b = Sensitive.new("x${y}z")
+      function f(Sensitive[String] $p = 'pwd', String $q = 'plain') {}
+      user { 'root': password => 'AKIA0123' }`), false)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expected := `(block` +
+		` (= (var "a") (call {:functor (qr "Sensitive") :args ["[redacted]"]}))` +
+		` (= (var "b") (call-method {:functor (. (qr "Sensitive") (qn "new")) :args [(concat "[redacted]" (str (var "y")) "[redacted]")]}))` +
+		` (function {:name "f" :params {:p {:type (access (qr "Sensitive") (qr "String")) :value "[redacted]"} :q {:type (qr "String") :value "plain"}} :body []})` +
+		` (resource {:type (qn "user") :bodies [{:title "root" :ops [(=> "password" "[redacted]")]}]}))`
+	actual := RedactedPN(expr, regexp.MustCompile(`^AKIA`)).String()
+	if expected != actual {
+		t.Errorf("expected '%s', got '%s'", expected, actual)
+	}
+}
+
+func TestAnnotations(t *testing.T) {
+	expr, err := CreateParser().Parse(``, `$x = 'a'`, false)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expr.AllContents(nil, func(path []Expression, e Expression) {
+		switch e.(type) {
+		case *VariableExpression:
+			e.Annotate(`type`, `String`)
+		case *LiteralString:
+			e.Annotate(`type`, `String`)
+			e.Annotate(`ignored`, true)
+			e.Annotate(`ignored`, nil)
+			e.Annotate(`source`, pn.Call(`qn`, pn.Literal(`test`)))
+		}
+	})
+
+	expected := `(block (= ^{:type "String"} (var "x") ^{:type "String" :source (qn "test")} "a"))`
+	b := bytes.NewBufferString(``)
+	pn.FormatWith(expr.ToPN(), b, pn.WITH_ANNOTATIONS)
+	if expected != b.String() {
+		t.Errorf("expected '%s', got '%s'", expected, b.String())
+	}
+
+	expected = `{"^":["block",{"^":["=",{"!":{"#":["type","String"]},"^":["var","x"]},{"!":{"#":["type","String","source",{"^":["qn","test"]}]},"=":"a"}]}]}`
+	if actual := toJSON(expr, pn.WITH_ANNOTATIONS); expected != actual {
+		t.Errorf("expected '%s', got '%s'", expected, actual)
+	}
+
+	opts := []pn.Option{pn.WITH_ANNOTATIONS, pn.WITH_POSITIONS, pn.WITH_SCHEMA_VERSION}
+	b = bytes.NewBufferString(``)
+	if err = EncodePN(b, expr, opts...); err != nil {
+		t.Fatal(err.Error())
+	}
+	if expected = toJSON(expr, opts...) + "\n"; expected != b.String() {
+		t.Errorf("expected '%s', got '%s'", expected, b.String())
+	}
+	var data interface{}
+	if err = json.Unmarshal(b.Bytes(), &data); err != nil {
+		t.Fatal(err.Error())
+	}
+	if err = pn.ValidateData(data); err != nil {
+		t.Error(err.Error())
+	}
+}
@@ -3,10 +3,13 @@ package parser
 import (
 	"bytes"
 	"strconv"
+	"strings"
 	"unicode"
 	"unicode/utf8"
 
 	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-parser/ast"
+	"github.com/lyraproj/puppet-parser/token"
 )
 
 // Recursive descent lexer for the Puppet language.
@@ -17,7 +20,7 @@ type location struct {
 }
 
 func (l *location) File() string {
-	return l.locator.file
+	return l.locator.File()
 }
 
 func (l *location) Line() int {
@@ -36,292 +39,215 @@ func (ctx *context) parseIssue2(issueCode issue.Code, args issue.H) issue.Report
 	return issue.NewReported(issueCode, issue.SEVERITY_ERROR, args, &location{ctx.locator, ctx.Pos()})
 }
 
+// The tokens produced by the lexer. They are aliases of the constants in the token package.
 const (
-	TOKEN_END = 0
+	TOKEN_END = token.END
 
 	// Binary ops
-	TOKEN_ASSIGN          = 1
-	TOKEN_ADD_ASSIGN      = 2
-	TOKEN_SUBTRACT_ASSIGN = 3
+	TOKEN_ASSIGN          = token.ASSIGN
+	TOKEN_ADD_ASSIGN      = token.ADD_ASSIGN
+	TOKEN_SUBTRACT_ASSIGN = token.SUBTRACT_ASSIGN
 
-	TOKEN_MULTIPLY  = 10
-	TOKEN_DIVIDE    = 11
-	TOKEN_REMAINDER = 12
-	TOKEN_SUBTRACT  = 13
-	TOKEN_ADD       = 14
+	TOKEN_MULTIPLY  = token.MULTIPLY
+	TOKEN_DIVIDE    = token.DIVIDE
+	TOKEN_REMAINDER = token.REMAINDER
+	TOKEN_SUBTRACT  = token.SUBTRACT
+	TOKEN_ADD       = token.ADD
 
-	TOKEN_LSHIFT = 20
-	TOKEN_RSHIFT = 21
+	TOKEN_LSHIFT = token.LSHIFT
+	TOKEN_RSHIFT = token.RSHIFT
 
-	TOKEN_EQUAL         = 30
-	TOKEN_NOT_EQUAL     = 31
-	TOKEN_LESS          = 32
-	TOKEN_LESS_EQUAL    = 33
-	TOKEN_GREATER       = 34
-	TOKEN_GREATER_EQUAL = 35
+	TOKEN_EQUAL         = token.EQUAL
+	TOKEN_NOT_EQUAL     = token.NOT_EQUAL
+	TOKEN_LESS          = token.LESS
+	TOKEN_LESS_EQUAL    = token.LESS_EQUAL
+	TOKEN_GREATER       = token.GREATER
+	TOKEN_GREATER_EQUAL = token.GREATER_EQUAL
 
-	TOKEN_MATCH     = 40
-	TOKEN_NOT_MATCH = 41
+	TOKEN_MATCH     = token.MATCH
+	TOKEN_NOT_MATCH = token.NOT_MATCH
 
-	TOKEN_LCOLLECT  = 50
-	TOKEN_LLCOLLECT = 51
+	TOKEN_LCOLLECT  = token.LCOLLECT
+	TOKEN_LLCOLLECT = token.LLCOLLECT
 
-	TOKEN_RCOLLECT  = 60
-	TOKEN_RRCOLLECT = 61
+	TOKEN_RCOLLECT  = token.RCOLLECT
+	TOKEN_RRCOLLECT = token.RRCOLLECT
 
-	TOKEN_FARROW = 70
-	TOKEN_PARROW = 71
+	TOKEN_FARROW = token.FARROW
+	TOKEN_PARROW = token.PARROW
 
-	TOKEN_IN_EDGE      = 72
-	TOKEN_IN_EDGE_SUB  = 73
-	TOKEN_OUT_EDGE     = 74
-	TOKEN_OUT_EDGE_SUB = 75
+	TOKEN_IN_EDGE      = token.IN_EDGE
+	TOKEN_IN_EDGE_SUB  = token.IN_EDGE_SUB
+	TOKEN_OUT_EDGE     = token.OUT_EDGE
+	TOKEN_OUT_EDGE_SUB = token.OUT_EDGE_SUB
 
 	// Unary ops
-	TOKEN_NOT  = 80
-	TOKEN_AT   = 81
-	TOKEN_ATAT = 82
+	TOKEN_NOT  = token.NOT
+	TOKEN_AT   = token.AT
+	TOKEN_ATAT = token.ATAT
 
 	// ()
-	TOKEN_LP   = 90
-	TOKEN_WSLP = 91
-	TOKEN_RP   = 92
+	TOKEN_LP   = token.LP
+	TOKEN_WSLP = token.WSLP
+	TOKEN_RP   = token.RP
 
 	// []
-	TOKEN_LB        = 100
-	TOKEN_LISTSTART = 101
-	TOKEN_RB        = 102
+	TOKEN_LB        = token.LB
+	TOKEN_LISTSTART = token.LISTSTART
+	TOKEN_RB        = token.RB
 
 	// {}
-	TOKEN_LC   = 110
-	TOKEN_SELC = 111
-	TOKEN_RC   = 112
+	TOKEN_LC   = token.LC
+	TOKEN_SELC = token.SELC
+	TOKEN_RC   = token.RC
 
 	// | |
-	TOKEN_PIPE     = 120
-	TOKEN_PIPE_END = 121
+	TOKEN_PIPE     = token.PIPE
+	TOKEN_PIPE_END = token.PIPE_END
 
 	// EPP
-	TOKEN_EPP_END       = 130
-	TOKEN_EPP_END_TRIM  = 131
-	TOKEN_RENDER_EXPR   = 132
-	TOKEN_RENDER_STRING = 133
+	TOKEN_EPP_END       = token.EPP_END
+	TOKEN_EPP_END_TRIM  = token.EPP_END_TRIM
+	TOKEN_RENDER_EXPR   = token.RENDER_EXPR
+	TOKEN_RENDER_STRING = token.RENDER_STRING
 
 	// Separators
-	TOKEN_COMMA     = 140
-	TOKEN_DOT       = 141
-	TOKEN_QMARK     = 142
-	TOKEN_COLON     = 143
-	TOKEN_SEMICOLON = 144
+	TOKEN_COMMA     = token.COMMA
+	TOKEN_DOT       = token.DOT
+	TOKEN_QMARK     = token.QMARK
+	TOKEN_COLON     = token.COLON
+	TOKEN_SEMICOLON = token.SEMICOLON
 
 	// Strings with semantics
-	TOKEN_IDENTIFIER          = 150
-	TOKEN_STRING              = 151
-	TOKEN_INTEGER             = 152
-	TOKEN_FLOAT               = 153
-	TOKEN_BOOLEAN             = 154
-	TOKEN_CONCATENATED_STRING = 155
-	TOKEN_HEREDOC             = 156
-	TOKEN_VARIABLE            = 157
-	TOKEN_REGEXP              = 158
-	TOKEN_TYPE_NAME           = 159
+	TOKEN_IDENTIFIER          = token.IDENTIFIER
+	TOKEN_STRING              = token.STRING
+	TOKEN_INTEGER             = token.INTEGER
+	TOKEN_FLOAT               = token.FLOAT
+	TOKEN_BOOLEAN             = token.BOOLEAN
+	TOKEN_CONCATENATED_STRING = token.CONCATENATED_STRING
+	TOKEN_HEREDOC             = token.HEREDOC
+	TOKEN_VARIABLE            = token.VARIABLE
+	TOKEN_REGEXP              = token.REGEXP
+	TOKEN_TYPE_NAME           = token.TYPE_NAME
+
+	// Comments, only produced by a lexer that emits comments
+	TOKEN_COMMENT = token.COMMENT
+
+	// Source that cannot be lexed, only produced by a lexer that recovers from errors
+	TOKEN_ERROR = token.ERROR
 
 	// Keywords
-	TOKEN_AND         = 200
-	TOKEN_APPLICATION = 201
-	TOKEN_ATTR        = 202
-	TOKEN_CASE        = 203
-	TOKEN_CLASS       = 204
-	TOKEN_CONSUMES    = 205
-	TOKEN_DEFAULT     = 206
-	TOKEN_DEFINE      = 207
-	TOKEN_FUNCTION    = 208
-	TOKEN_IF          = 209
-	TOKEN_IN          = 210
-	TOKEN_INHERITS    = 211
-	TOKEN_ELSE        = 212
-	TOKEN_ELSIF       = 213
-	TOKEN_NODE        = 214
-	TOKEN_OR          = 215
-	TOKEN_PLAN        = 216
-	TOKEN_PRIVATE     = 217
-	TOKEN_PRODUCES    = 218
-	TOKEN_SITE        = 219
-	TOKEN_TYPE        = 220
-	TOKEN_UNDEF       = 221
-	TOKEN_UNLESS      = 222
+	TOKEN_AND         = token.AND
+	TOKEN_APPLICATION = token.APPLICATION
+	TOKEN_ATTR        = token.ATTR
+	TOKEN_CASE        = token.CASE
+	TOKEN_CLASS       = token.CLASS
+	TOKEN_CONSUMES    = token.CONSUMES
+	TOKEN_DEFAULT     = token.DEFAULT
+	TOKEN_DEFINE      = token.DEFINE
+	TOKEN_FUNCTION    = token.FUNCTION
+	TOKEN_IF          = token.IF
+	TOKEN_IN          = token.IN
+	TOKEN_INHERITS    = token.INHERITS
+	TOKEN_ELSE        = token.ELSE
+	TOKEN_ELSIF       = token.ELSIF
+	TOKEN_NODE        = token.NODE
+	TOKEN_OR          = token.OR
+	TOKEN_PLAN        = token.PLAN
+	TOKEN_PRIVATE     = token.PRIVATE
+	TOKEN_PRODUCES    = token.PRODUCES
+	TOKEN_SITE        = token.SITE
+	TOKEN_TYPE        = token.TYPE
+	TOKEN_UNDEF       = token.UNDEF
+	TOKEN_UNLESS      = token.UNLESS
 )
 
-func IsKeywordToken(token int) bool {
-	return token >= TOKEN_AND && token <= TOKEN_UNLESS
+func IsKeywordToken(t int) bool {
+	return token.IsKeyword(t)
 }
 
-var tokenMap = map[int]string{
-	TOKEN_END: `EOF`,
-
-	// Binary ops
-	TOKEN_ASSIGN:          `=`,
-	TOKEN_ADD_ASSIGN:      `+=`,
-	TOKEN_SUBTRACT_ASSIGN: `-=`,
-
-	TOKEN_MULTIPLY:  `*`,
-	TOKEN_DIVIDE:    `/`,
-	TOKEN_REMAINDER: `%`,
-	TOKEN_SUBTRACT:  `-`,
-	TOKEN_ADD:       `+`,
-
-	TOKEN_LSHIFT: `<<`,
-	TOKEN_RSHIFT: `>>`,
-
-	TOKEN_EQUAL:         `==`,
-	TOKEN_NOT_EQUAL:     `!=`,
-	TOKEN_LESS:          `<`,
-	TOKEN_LESS_EQUAL:    `<=`,
-	TOKEN_GREATER:       `>`,
-	TOKEN_GREATER_EQUAL: `>=`,
-
-	TOKEN_MATCH:     `=~`,
-	TOKEN_NOT_MATCH: `!~`,
-
-	TOKEN_LCOLLECT:  `<|`,
-	TOKEN_LLCOLLECT: `<<|`,
-
-	TOKEN_RCOLLECT:  `|>`,
-	TOKEN_RRCOLLECT: `|>>`,
-
-	TOKEN_FARROW: `=>`,
-	TOKEN_PARROW: `+>`,
-
-	TOKEN_IN_EDGE:      `->`,
-	TOKEN_IN_EDGE_SUB:  `~>`,
-	TOKEN_OUT_EDGE:     `<-`,
-	TOKEN_OUT_EDGE_SUB: `<~`,
-
-	// Unary ops
-	TOKEN_NOT:  `!`,
-	TOKEN_AT:   `@`,
-	TOKEN_ATAT: `@@`,
-
-	TOKEN_COMMA: `,`,
-
-	// ()
-	TOKEN_LP:   `(`,
-	TOKEN_WSLP: `(`,
-	TOKEN_RP:   `)`,
-
-	// []
-	TOKEN_LB:        `[`,
-	TOKEN_LISTSTART: `[`,
-	TOKEN_RB:        `]`,
-
-	// {}
-	TOKEN_LC:   `{`,
-	TOKEN_SELC: `{`,
-	TOKEN_RC:   `}`,
-
-	// | |
-	TOKEN_PIPE:     `|`,
-	TOKEN_PIPE_END: `|`,
-
-	// EPP
-	TOKEN_EPP_END:       `%>`,
-	TOKEN_EPP_END_TRIM:  `-%>`,
-	TOKEN_RENDER_EXPR:   `<%=`,
-	TOKEN_RENDER_STRING: `epp text`,
-
-	// Separators
-	TOKEN_DOT:       `.`,
-	TOKEN_QMARK:     `?`,
-	TOKEN_COLON:     `:`,
-	TOKEN_SEMICOLON: `;`,
-
-	// Strings with semantics
-	TOKEN_IDENTIFIER:          `identifier`,
-	TOKEN_STRING:              `string literal`,
-	TOKEN_INTEGER:             `integer literal`,
-	TOKEN_FLOAT:               `float literal`,
-	TOKEN_BOOLEAN:             `boolean literal`,
-	TOKEN_CONCATENATED_STRING: `dq string literal`,
-	TOKEN_HEREDOC:             `heredoc`,
-	TOKEN_VARIABLE:            `variable`,
-	TOKEN_REGEXP:              `regexp`,
-	TOKEN_TYPE_NAME:           `type name`,
+// KeywordLookalike returns the keyword that the given type name differs from only by case, e.g. `if` for
+// `If` and `class` for `CLASS`. The second return value is false if there is no such keyword or if the
+// type name is the name of a type, such as `Class`.
+func KeywordLookalike(typeName string) (string, bool) {
+	if isKeywordTypeName(typeName) {
+		return ``, false
+	}
+	keyword := strings.ToLower(typeName)
+	_, ok := keywordToken(keyword)
+	return keyword, ok
+}
 
-	// Keywords
-	TOKEN_AND:         `and`,
-	TOKEN_APPLICATION: `application`,
-	TOKEN_ATTR:        `attr`,
-	TOKEN_CASE:        `case`,
-	TOKEN_CLASS:       `class`,
-	TOKEN_CONSUMES:    `consumes`,
-	TOKEN_DEFAULT:     `default`,
-	TOKEN_DEFINE:      `define`,
-	TOKEN_FUNCTION:    `function`,
-	TOKEN_IF:          `if`,
-	TOKEN_IN:          `in`,
-	TOKEN_INHERITS:    `inherits`,
-	TOKEN_ELSE:        `else`,
-	TOKEN_ELSIF:       `elsif`,
-	TOKEN_NODE:        `node`,
-	TOKEN_OR:          `or`,
-	TOKEN_PLAN:        `plan`,
-	TOKEN_PRIVATE:     `private`,
-	TOKEN_PRODUCES:    `produces`,
-	TOKEN_SITE:        `site`,
-	TOKEN_TYPE:        `type`,
-	TOKEN_UNDEF:       `undef`,
-	TOKEN_UNLESS:      `unless`,
+// Keywords that, when capitalized, are names of types. The lexer uses isKeywordTypeName, which is
+// generated from this map.
+var keywordTypeNames = map[string]bool{
+	`Application`: true,
+	`Class`:       true,
+	`Default`:     true,
+	`Type`:        true,
+	`Undef`:       true,
 }
 
+// The keywords and the tokens that the lexer produces for them. The lexer uses keywordToken, which is
+// generated from this map.
+//
+//go:generate go run ./internal/dispatchgen
 var keywords = map[string]int{
-	tokenMap[TOKEN_APPLICATION]: TOKEN_APPLICATION,
-	tokenMap[TOKEN_AND]:         TOKEN_AND,
-	tokenMap[TOKEN_ATTR]:        TOKEN_ATTR,
-	tokenMap[TOKEN_CASE]:        TOKEN_CASE,
-	tokenMap[TOKEN_CLASS]:       TOKEN_CLASS,
-	tokenMap[TOKEN_CONSUMES]:    TOKEN_CONSUMES,
-	tokenMap[TOKEN_DEFAULT]:     TOKEN_DEFAULT,
-	tokenMap[TOKEN_DEFINE]:      TOKEN_DEFINE,
-	`false`:                     TOKEN_BOOLEAN,
-	tokenMap[TOKEN_FUNCTION]:    TOKEN_FUNCTION,
-	tokenMap[TOKEN_ELSE]:        TOKEN_ELSE,
-	tokenMap[TOKEN_ELSIF]:       TOKEN_ELSIF,
-	tokenMap[TOKEN_IF]:          TOKEN_IF,
-	tokenMap[TOKEN_IN]:          TOKEN_IN,
-	tokenMap[TOKEN_INHERITS]:    TOKEN_INHERITS,
-	tokenMap[TOKEN_NODE]:        TOKEN_NODE,
-	tokenMap[TOKEN_OR]:          TOKEN_OR,
-	tokenMap[TOKEN_PLAN]:        TOKEN_PLAN,
-	tokenMap[TOKEN_PRIVATE]:     TOKEN_PRIVATE,
-	tokenMap[TOKEN_PRODUCES]:    TOKEN_PRODUCES,
-	tokenMap[TOKEN_SITE]:        TOKEN_SITE,
-	`true`:                      TOKEN_BOOLEAN,
-	tokenMap[TOKEN_TYPE]:        TOKEN_TYPE,
-	tokenMap[TOKEN_UNDEF]:       TOKEN_UNDEF,
-	tokenMap[TOKEN_UNLESS]:      TOKEN_UNLESS,
+	token.String(TOKEN_APPLICATION): TOKEN_APPLICATION,
+	token.String(TOKEN_AND):         TOKEN_AND,
+	token.String(TOKEN_ATTR):        TOKEN_ATTR,
+	token.String(TOKEN_CASE):        TOKEN_CASE,
+	token.String(TOKEN_CLASS):       TOKEN_CLASS,
+	token.String(TOKEN_CONSUMES):    TOKEN_CONSUMES,
+	token.String(TOKEN_DEFAULT):     TOKEN_DEFAULT,
+	token.String(TOKEN_DEFINE):      TOKEN_DEFINE,
+	`false`:                         TOKEN_BOOLEAN,
+	token.String(TOKEN_FUNCTION):    TOKEN_FUNCTION,
+	token.String(TOKEN_ELSE):        TOKEN_ELSE,
+	token.String(TOKEN_ELSIF):       TOKEN_ELSIF,
+	token.String(TOKEN_IF):          TOKEN_IF,
+	token.String(TOKEN_IN):          TOKEN_IN,
+	token.String(TOKEN_INHERITS):    TOKEN_INHERITS,
+	token.String(TOKEN_NODE):        TOKEN_NODE,
+	token.String(TOKEN_OR):          TOKEN_OR,
+	token.String(TOKEN_PLAN):        TOKEN_PLAN,
+	token.String(TOKEN_PRIVATE):     TOKEN_PRIVATE,
+	token.String(TOKEN_PRODUCES):    TOKEN_PRODUCES,
+	token.String(TOKEN_SITE):        TOKEN_SITE,
+	`true`:                          TOKEN_BOOLEAN,
+	token.String(TOKEN_TYPE):        TOKEN_TYPE,
+	token.String(TOKEN_UNDEF):       TOKEN_UNDEF,
+	token.String(TOKEN_UNLESS):      TOKEN_UNLESS,
 }
 
-var DEFAULT_INSTANCE = Default{}
-
-type Default struct{}
-
 type context struct {
 	stringReader
-	locator               *Locator
-	eppMode               bool
-	handleBacktickStrings bool
-	handleHexEscapes      bool
-	tasks                 bool
-	workflow              bool
-	nextLineStart         int
-	currentToken          int
-	beginningOfLine       int
-	tokenStartPos         int
-	tokenValue            interface{}
-	radix                 int
-	factory               ExpressionFactory
-	nameStack             []string
-	definitions           []Definition
+	locator          *Locator
+	features         featureSet
+	emitComments     bool
+	stopAtComment    bool
+	lookalikeStart   int
+	lookalikeName    string
+	disabledStart    int
+	disabledSyntax   string
+	disabledOption   Option
+	nextLineStart    int
+	currentToken     int
+	beginningOfLine  int
+	tokenStartPos    int
+	tokenEnd         int
+	previousTokenEnd int
+	eppTextStart     int
+	tokenValue       interface{}
+	radix            int
+	factory          ExpressionFactory
+	nameStack        []string
+	definitions      []Definition
+	warnings         []issue.Reported
+	tracer           Tracer
+	traceDepth       int
+	tokens           *TokenRecording
+	recordTokens     bool
 }
 
 func (ctx *context) setToken(token int) {
@@ -347,11 +273,14 @@ func (ctx *context) unterminatedQuote(start int, delimiter rune) issue.Reported
 	return ctx.parseIssue2(LEX_UNTERMINATED_STRING, issue.H{`string_type`: stringType})
 }
 
-func (ctx *context) nextToken() {
+// lexToken lexes the next token and makes it current
+func (ctx *context) lexToken() {
 	sz := 0
 	scanStart := ctx.Pos()
 
+	ctx.stopAtComment = ctx.emitComments
 	c, start := ctx.skipWhite(false)
+	ctx.stopAtComment = false
 	ctx.tokenStartPos = start
 
 	switch {
@@ -372,6 +301,12 @@ func (ctx *context) nextToken() {
 
 	case 'A' <= c && c <= 'Z':
 		ctx.consumeQualifiedName(start, TOKEN_TYPE_NAME)
+		if name := ctx.tokenString(); start > ctx.lookalikeStart {
+			if _, ok := KeywordLookalike(name); ok {
+				ctx.lookalikeStart = start
+				ctx.lookalikeName = name
+			}
+		}
 
 	case 'a' <= c && c <= 'z':
 		ctx.consumeQualifiedName(start, TOKEN_IDENTIFIER)
@@ -471,7 +406,7 @@ func (ctx *context) nextToken() {
 				ctx.Advance(sz)
 				ctx.setToken(TOKEN_IN_EDGE)
 			case '%':
-				if ctx.eppMode {
+				if ctx.features.has(PARSER_EPP_MODE) {
 					ctx.Advance(sz)
 					c, sz = ctx.Peek()
 					if c == '>' {
@@ -511,7 +446,7 @@ func (ctx *context) nextToken() {
 
 		case '%':
 			ctx.setToken(TOKEN_REMAINDER)
-			if ctx.eppMode {
+			if ctx.features.has(PARSER_EPP_MODE) {
 				c, sz = ctx.Peek()
 				if c == '>' {
 					ctx.Advance(sz)
@@ -591,7 +526,7 @@ func (ctx *context) nextToken() {
 				ctx.Advance(sz)
 				ctx.setToken(TOKEN_OUT_EDGE_SUB)
 			case '%':
-				if ctx.eppMode {
+				if ctx.features.has(PARSER_EPP_MODE) {
 					ctx.Advance(sz)
 					// <%# and <%% has been dealt with in consumeEPP so there's no need to deal with
 					// that. Only <%, <%- and <%= can show up here
@@ -608,7 +543,8 @@ func (ctx *context) nextToken() {
 					}
 					break
 				}
-				fallthrough
+				ctx.useOfDisabled(start, `<%`, PARSER_EPP_MODE)
+				ctx.setToken(TOKEN_LESS)
 			default:
 				ctx.setToken(TOKEN_LESS)
 			}
@@ -633,7 +569,7 @@ func (ctx *context) nextToken() {
 					pos := ctx.Pos()
 					n, _ := ctx.skipWhite(false)
 					ctx.SetPos(pos)
-					if n == '{' || n == '>' || ctx.eppMode && (n == '%' || n == '-') {
+					if n == '{' || n == '>' || ctx.features.has(PARSER_EPP_MODE) && (n == '%' || n == '-') {
 						// A lambda parameter list cannot start with either of these tokens so
 						// this must be the end (next is either block body or block return type declaration)
 						ctx.setToken(TOKEN_PIPE_END)
@@ -649,7 +585,18 @@ func (ctx *context) nextToken() {
 		case '\'':
 			ctx.consumeSingleQuotedString()
 
+		case '#':
+			// Only reached when comments are emitted
+			ctx.consumeLineComment(start)
+
 		case '/':
+			if ctx.emitComments {
+				if c, sz = ctx.Peek(); c == '*' {
+					ctx.Advance(sz)
+					ctx.consumeBlockComment(start)
+					return
+				}
+			}
 			if ctx.isRegexpAcceptable() && ctx.consumeRegexp() {
 				return
 			}
@@ -657,7 +604,8 @@ func (ctx *context) nextToken() {
 
 		case '$':
 			c, sz = ctx.Peek()
-			if c == ':' {
+			global := c == ':'
+			if global {
 				ctx.Advance(sz)
 				c, sz = ctx.Peek()
 				if c != ':' {
@@ -667,7 +615,8 @@ func (ctx *context) nextToken() {
 				ctx.Advance(sz)
 				c, sz = ctx.Peek()
 			}
-			if isLowercaseLetter(c) {
+			if isLowercaseLetter(c) || c == '_' && !global {
+				// A local variable name may start with an underscore, e.g. $_unused
 				ctx.Advance(sz)
 				ctx.consumeQualifiedName(start, TOKEN_VARIABLE)
 			} else if isDecimalDigit(c) {
@@ -729,11 +678,12 @@ func (ctx *context) nextToken() {
 			}
 
 		case '`':
-			if ctx.handleBacktickStrings {
+			if ctx.features.has(PARSER_HANDLE_BACKTICK_STRINGS) {
 				ctx.consumeBacktickedString()
 				break
 			}
-			fallthrough
+			ctx.SetPos(start)
+			panic(ctx.parseIssue2(PARSE_FEATURE_NOT_ENABLED, issue.H{`syntax`: "`", `feature`: featureRegistry[PARSER_HANDLE_BACKTICK_STRINGS].Name}))
 
 		default:
 			ctx.SetPos(start)
@@ -775,6 +725,9 @@ func (ctx *context) skipWhite(breakOnNewLine bool) (c rune, start int) {
 
 		case '#':
 			if commentStart == 0 {
+				if ctx.stopAtComment {
+					return
+				}
 				commentStart = '#'
 				commentStartPos = start
 			}
@@ -783,6 +736,9 @@ func (ctx *context) skipWhite(breakOnNewLine bool) (c rune, start int) {
 			if commentStart == 0 {
 				tc, sz := ctx.Peek()
 				if tc == '*' {
+					if ctx.stopAtComment {
+						return
+					}
 					ctx.Advance(sz)
 					commentStart = '*'
 					commentStartPos = start
@@ -816,6 +772,37 @@ func (ctx *context) skipWhite(breakOnNewLine bool) (c rune, start int) {
 	}
 }
 
+// consumeLineComment consumes a comment that starts with '#' at the given start position and ends at
+// the end of the line
+func (ctx *context) consumeLineComment(start int) {
+	for {
+		c, sz := ctx.Peek()
+		if c == 0 || c == '\n' {
+			break
+		}
+		ctx.Advance(sz)
+	}
+	ctx.setTokenValue(TOKEN_COMMENT, strings.TrimSuffix(ctx.From(start), "\r"))
+}
+
+// consumeBlockComment consumes a comment that starts with '/*' at the given start position
+func (ctx *context) consumeBlockComment(start int) {
+	for {
+		c, _ := ctx.Next()
+		switch c {
+		case 0:
+			ctx.SetPos(start)
+			panic(ctx.parseIssue(LEX_UNTERMINATED_COMMENT))
+		case '*':
+			if tc, sz := ctx.Peek(); tc == '/' {
+				ctx.Advance(sz)
+				ctx.setTokenValue(TOKEN_COMMENT, ctx.From(start))
+				return
+			}
+		}
+	}
+}
+
 // Skips to next non-whitespace or newline character and returns that character and its start position without
 // comment recognition
 func (ctx *context) skipWhiteInLiteral() (c rune, start int) {
@@ -861,7 +848,9 @@ func isUppercaseLetter(c rune) bool {
 }
 
 func (ctx *context) consumeQualifiedName(start int, token int) {
-	lastStartsWithUnderscore := false
+	// Only the last segment of a name may start with an underscore, so a variable name that starts with one
+	// can't be qualified
+	lastStartsWithUnderscore := token == TOKEN_VARIABLE && ctx.From(start) == `$_`
 	hasDash := false
 outer:
 	for {
@@ -935,7 +924,7 @@ outer:
 	if token == TOKEN_IDENTIFIER {
 		if hasDash {
 			token = TOKEN_STRING
-		} else if kwToken, ok := keywords[word]; ok {
+		} else if kwToken, ok := keywordToken(word); ok {
 			switch kwToken {
 			case TOKEN_BOOLEAN:
 				ctx.setTokenValue(kwToken, word == `true`)
@@ -944,8 +933,10 @@ outer:
 				ctx.setTokenValue(kwToken, DEFAULT_INSTANCE)
 				return
 			case TOKEN_PLAN:
-				if ctx.tasks {
+				if ctx.features.has(PARSER_TASKS_ENABLED) {
 					token = kwToken
+				} else {
+					ctx.useOfDisabled(start, word, PARSER_TASKS_ENABLED)
 				}
 			default:
 				token = kwToken
@@ -1046,93 +1037,122 @@ func (ctx *context) consumeDelimitedString(delimiter rune, delimiterStart int, i
 }
 
 func (ctx *context) consumeEPP() {
-	buf := bytes.NewBufferString(``)
-	lastNonWS := 0
-	var sz int
-	for ec, start := ctx.Next(); ec != 0; ec, start = ctx.Next() {
-		switch ec {
-		case '<':
-			ec, sz = ctx.Peek()
-			if ec != '%' {
-				buf.WriteByte('<')
-				lastNonWS = buf.Len()
-				continue
-			}
-			ctx.Advance(sz)
+	// The text token starts after the end of the preceding tag
+	ctx.tokenStartPos = ctx.Pos()
+	ctx.eppTextStart = ctx.tokenStartPos
+	text, inTag := ctx.consumeEPPText()
+	if inTag {
+		ctx.setTokenValue(TOKEN_RENDER_STRING, text)
+		if text == `` {
+			ctx.nextToken()
+		}
+		return
+	}
+	if text == `` {
+		ctx.setToken(TOKEN_END)
+	} else {
+		ctx.setTokenValue(TOKEN_RENDER_STRING, text)
+	}
+}
 
-			ec, sz = ctx.Peek()
-			switch ec {
-			case '%':
-				// <%% is verbatim <%
-				ctx.Advance(sz)
-				buf.WriteString(`<%`)
-				lastNonWS = buf.Len()
-				continue
+// consumeEPPText returns the text that precedes the next EPP tag and true with the position set to
+// the start of the tag, or the text up to the end of input and false when there are no more tags.
+// Comment tags are skipped, and escaped tags and whitespace trimmed by a <%- tag are dealt with.
+//
+// The returned text is a substring of the source unless it contains escaped tags or comment tags, so
+// templates that consist of large amounts of literal text are lexed without copying that text.
+func (ctx *context) consumeEPPText() (string, bool) {
+	text := ctx.Text()
+	pos := ctx.Pos()
+	runStart := pos
+	var b *strings.Builder
+
+	// skip ends the current run of text at the given position and starts a new run at the position
+	// after the skipped text. The given replacement is added in place of the skipped text.
+	skip := func(start, next int, replacement string) {
+		ctx.assertValidText(runStart, start)
+		if b == nil {
+			b = &strings.Builder{}
+		}
+		b.WriteString(text[runStart:start])
+		b.WriteString(replacement)
+		pos = next
+		runStart = next
+	}
 
-			case '#':
-				ctx.Advance(sz)
-				prev := ec
-				foundEnd := false
-				for ec, _ = ctx.Next(); ec != 0; ec, _ = ctx.Next() {
-					if ec == '%' {
-						ec, sz = ctx.Peek()
-						if ec == '>' && prev != '%' {
-							ctx.Advance(sz)
-							foundEnd = true
-							break
-						}
-					}
-					prev = ec
-				}
-				if !foundEnd {
+	// result returns all text up to the given position
+	result := func(end int) string {
+		ctx.assertValidText(runStart, end)
+		if b == nil {
+			return text[runStart:end]
+		}
+		b.WriteString(text[runStart:end])
+		return b.String()
+	}
+
+	for {
+		i := strings.IndexAny(text[pos:], `<%`)
+		if i < 0 {
+			ctx.SetPos(len(text))
+			return result(len(text)), false
+		}
+		start := pos + i
+		rest := text[start:]
+		switch {
+		case strings.HasPrefix(rest, `<%%`):
+			// <%% is verbatim <%
+			skip(start, start+3, `<%`)
+
+		case strings.HasPrefix(rest, `<%#`):
+			// The comment ends with the first %> that isn't escaped as %%>
+			end := start + 3
+			for {
+				j := strings.Index(text[end:], `%>`)
+				if j < 0 {
 					ctx.SetPos(start)
 					panic(ctx.parseIssue(LEX_UNBALANCED_EPP_COMMENT))
 				}
-				continue
+				end += j + 2
+				if text[end-3] != '%' {
+					break
+				}
+			}
+			skip(start, end, ``)
 
-			case '-':
-				// trim whitespaces leading up to <%-
-				ctx.Advance(sz)
-				buf.Truncate(lastNonWS)
+		case strings.HasPrefix(rest, `<%-`):
+			// trim whitespaces leading up to <%-
+			ctx.SetPos(start)
+			return strings.TrimRight(result(start), " \t"), true
 
-			case '=':
-				ctx.Advance(sz)
-			}
+		case strings.HasPrefix(rest, `<%`):
 			ctx.SetPos(start) // Next token will be TOKEN_RENDER_EXPR
-			ctx.setTokenValue(TOKEN_RENDER_STRING, buf.String())
-			if buf.Len() == 0 {
-				ctx.nextToken()
-			}
-			return
-
-		case ' ', '\t':
-			buf.WriteRune(ec)
+			return result(start), true
 
-		case '%':
+		case strings.HasPrefix(rest, `%%>`):
 			// %%> is verbatim %>
-			buf.WriteByte('%')
-			ec, sz = ctx.Peek()
-			if ec == '%' {
-				ctx.Advance(sz)
-				ec, sz = ctx.Peek()
-				if ec == '>' {
-					ctx.Advance(sz)
-					buf.WriteByte('>')
-				} else {
-					buf.WriteByte('%')
-				}
-			}
-			lastNonWS = buf.Len()
+			skip(start, start+3, `%>`)
+
+		case strings.HasPrefix(rest, `%%`):
+			pos = start + 2
 
 		default:
-			buf.WriteRune(ec)
-			lastNonWS = buf.Len()
+			pos = start + 1
 		}
 	}
-	if buf.Len() == 0 {
-		ctx.setToken(TOKEN_END)
-	} else {
-		ctx.setTokenValue(TOKEN_RENDER_STRING, buf.String())
+}
+
+// assertValidText panics with a ParseError if the source text between the given positions contains
+// invalid unicode
+func (ctx *context) assertValidText(start, end int) {
+	text := ctx.slice(start, end)
+	if utf8.ValidString(text) {
+		return
+	}
+	for i, c := range text {
+		if c == utf8.RuneError {
+			ctx.SetPos(start + i)
+			panic(ctx.invalidUnicode())
+		}
 	}
 }
 
@@ -1141,7 +1161,6 @@ func (ctx *context) consumeEPP() {
 //   - Unless the string is empty, adds a StringExpression that represents the string to the segments slice
 //   - Asks the context to perform interpolation and adds the resulting expression to the segments slice
 //   - Sets the tokenStartPos to the position just after the end of the interpolation expression
-//
 func (ctx *context) handleInterpolation(start int, segments []Expression, buf *bytes.Buffer) []Expression {
 	precedingString := buf.String()
 	buf.Reset()
@@ -1171,18 +1190,13 @@ func (ctx *context) interpolate(start int) Expression {
 		case *QualifiedName:
 			expr = ctx.factory.Variable(expr, ctx.locator, start, ctx.Pos()-start)
 		case *AccessExpression:
-			access := expr.(*AccessExpression)
-			if identifier, ok := access.operand.(*QualifiedName); ok {
-				expr = ctx.factory.Access(
-					ctx.factory.Variable(identifier, ctx.locator, start, identifier.ByteLength()+1),
-					access.keys, ctx.locator, start, access.ByteLength()+1)
-			}
+			expr = ctx.convertAccessOperand(expr.(*AccessExpression), start)
 		case *CallMethodExpression:
 			call := expr.(*CallMethodExpression)
-			if ne, ok := call.functor.(*NamedAccessExpression); ok {
+			if ne, ok := call.Functor().(*NamedAccessExpression); ok {
 				modNe := ctx.convertNamedAccessLHS(ne, start)
 				if modNe != ne {
-					expr = ctx.factory.CallMethod(modNe, call.arguments, call.lambda, ctx.locator, start, call.ByteLength()+1)
+					expr = ctx.factory.CallMethod(modNe, call.Arguments(), call.Lambda(), ctx.locator, start, call.ByteLength()+1)
 				}
 			}
 		}
@@ -1203,34 +1217,48 @@ func (ctx *context) interpolate(start int) Expression {
 }
 
 func (ctx *context) convertNamedAccessLHS(expr *NamedAccessExpression, start int) Expression {
-	lhs := expr.lhs
+	lhs := expr.Lhs()
 	switch lhs.(type) {
 	case *QualifiedName:
 		return ctx.factory.NamedAccess(
 			ctx.factory.Variable(lhs, ctx.locator, start, lhs.ByteLength()+1),
-			expr.rhs, ctx.locator, start, expr.ByteLength()+1)
+			expr.Rhs(), ctx.locator, start, expr.ByteLength()+1)
 	case *AccessExpression:
-		access := lhs.(*AccessExpression)
-		if identifier, ok := access.operand.(*QualifiedName); ok {
-			lhs = ctx.factory.Access(
-				ctx.factory.Variable(identifier, ctx.locator, start, identifier.ByteLength()+1),
-				access.keys, ctx.locator, start, access.ByteLength()+1)
-		}
-		return ctx.factory.NamedAccess(lhs, expr.rhs, ctx.locator, start, expr.ByteLength()+1)
+		return ctx.factory.NamedAccess(
+			ctx.convertAccessOperand(lhs.(*AccessExpression), start),
+			expr.Rhs(), ctx.locator, start, expr.ByteLength()+1)
 	case *NamedAccessExpression:
 		return ctx.factory.NamedAccess(
 			ctx.convertNamedAccessLHS(lhs.(*NamedAccessExpression), start),
-			expr.rhs, ctx.locator, start, expr.ByteLength()+1)
+			expr.Rhs(), ctx.locator, start, expr.ByteLength()+1)
 	case *CallMethodExpression:
 		call := lhs.(*CallMethodExpression)
 		lhs = ctx.factory.CallMethod(
-			ctx.convertNamedAccessLHS(call.functor.(*NamedAccessExpression), start),
-			call.arguments, call.lambda, ctx.locator, start, call.ByteLength()+1)
-		return ctx.factory.NamedAccess(lhs, expr.rhs, ctx.locator, start, expr.ByteLength()+1)
+			ctx.convertNamedAccessLHS(call.Functor().(*NamedAccessExpression), start),
+			call.Arguments(), call.Lambda(), ctx.locator, start, call.ByteLength()+1)
+		return ctx.factory.NamedAccess(lhs, expr.Rhs(), ctx.locator, start, expr.ByteLength()+1)
 	}
 	return expr
 }
 
+// convertAccessOperand converts the QualifiedName that is the innermost operand of the given, possibly nested,
+// access expression into a variable
+func (ctx *context) convertAccessOperand(access *AccessExpression, start int) Expression {
+	var operand Expression
+	switch o := access.Operand().(type) {
+	case *QualifiedName:
+		operand = ctx.factory.Variable(o, ctx.locator, start, o.ByteLength()+1)
+	case *AccessExpression:
+		operand = ctx.convertAccessOperand(o, start)
+		if operand == o {
+			return access
+		}
+	default:
+		return access
+	}
+	return ctx.factory.Access(operand, access.Keys(), ctx.locator, start, access.ByteLength()+1)
+}
+
 func (ctx *context) consumeBacktickedString() {
 	start := ctx.Pos()
 	c, sz := ctx.Peek()
@@ -1271,7 +1299,7 @@ func (ctx *context) consumeDoubleQuotedString() {
 			case 'u':
 				ctx.appendUnicode(buf)
 			case 'x':
-				if ctx.handleHexEscapes {
+				if ctx.features.has(PARSER_HANDLE_HEX_ESCAPES) {
 					ctx.appendHexadec(buf)
 					break
 				}
@@ -1296,7 +1324,7 @@ func (ctx *context) consumeDoubleQuotedString() {
 	} else {
 		segments = append(segments, ctx.factory.String(ctx.tokenValue.(string), ctx.locator, ctx.tokenStartPos, ctx.Pos()-ctx.tokenStartPos))
 	}
-	firstPos := segments[0].ByteOffset()
+	firstPos := ast.SourceOffset(segments[0])
 	if len(segments) == 1 {
 		if _, ok := segments[0].(*LiteralString); ok {
 			// Avoid turning a single string literal into a concatenated string
@@ -1506,9 +1534,8 @@ findEndOfText:
 				continue
 			}
 
-			expr := ctx.Text()
 			tagEnd := n + tagLen
-			if tagEnd <= len(expr) && tag == expr[n:tagEnd] {
+			if tagEnd <= ctx.length() && tag == ctx.slice(n, tagEnd) {
 				// tag found if rest of line is whitespace
 				ctx.SetPos(tagEnd)
 				c, n = ctx.skipWhiteInLiteral()
@@ -1517,7 +1544,7 @@ findEndOfText:
 					heredocContentEnd = lineStart
 					if suppressLastNL {
 						heredocContentEnd--
-						if expr[heredocContentEnd-1] == '\r' {
+						if ctx.slice(heredocContentEnd-1, heredocContentEnd) == "\r" {
 							heredocContentEnd--
 						}
 					}
@@ -1556,7 +1583,11 @@ findEndOfText:
 	ctx.nextLineStart = heredocEnd + 1 // and next newline will jump to here
 	if ctx.factory != nil {
 		textExpr := ctx.factory.String(heredoc, ctx.locator, heredocContentStart, heredocContentEnd-heredocContentStart)
-		ctx.setTokenValue(TOKEN_HEREDOC, ctx.factory.Heredoc(textExpr, syntax, ctx.locator, heredocStart, heredocContentEnd-heredocStart))
+		expr := ctx.factory.Heredoc(textExpr, syntax, ctx.locator, heredocStart, heredocContentEnd-heredocStart)
+		if he, ok := expr.(*HeredocExpression); ok && syntax == `epp` {
+			he.SetTemplate(ctx.parseEppHeredoc(heredoc, heredocContentStart, heredocContentEnd, indentStrip))
+		}
+		ctx.setTokenValue(TOKEN_HEREDOC, expr)
 	} else {
 		ctx.setTokenValue(TOKEN_STRING, heredoc)
 	}
@@ -0,0 +1,18 @@
+package parser
+
+// ParseFileMapped parses the file with the given name using the given parser without reading the file into
+// memory. The file is memory-mapped where the platform supports it and the mapped bytes are parsed with
+// ParseBytes, which avoids copying large files when many of them are scanned.
+//
+// The parsed expression, the strings obtained from it, and reported issues refer to the mapped memory. The
+// returned release function unmaps the file and must not be called until none of them are in use. It must
+// be called also when an error is returned, unless the error is from opening or mapping the file, in which
+// case the release function is nil.
+func ParseFileMapped(p ExpressionParser, filename string, singleExpression bool) (expr Expression, release func() error, err error) {
+	var data []byte
+	if data, release, err = mapFile(filename); err != nil {
+		return nil, nil, err
+	}
+	expr, err = p.ParseBytes(filename, data, singleExpression)
+	return
+}
@@ -0,0 +1,14 @@
+//go:build !unix
+
+package parser
+
+import "os"
+
+// mapFile reads the file with the given name on platforms that don't support memory-mapping
+func mapFile(filename string) ([]byte, func() error, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, func() error { return nil }, nil
+}
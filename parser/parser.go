@@ -4,8 +4,12 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"unicode/utf8"
+	"unsafe"
 
 	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-parser/ast"
+	"github.com/lyraproj/puppet-parser/token"
 )
 
 // Recursive descent context for the Puppet language.
@@ -15,8 +19,69 @@ import (
 // it encounters double quoted strings or heredoc with interpolation).
 
 type (
+	// ExpressionParser parses Puppet source. Implementations are safe for concurrent use by multiple
+	// goroutines.
 	ExpressionParser interface {
 		Parse(filename string, source string, singleExpression bool) (expr Expression, err error)
+
+		// ParseSnippet is like Parse but the source is a snippet that starts at the given line and
+		// column (both 1-based) and byte offset of a larger host document, e.g. Puppet code embedded
+		// in YAML or Markdown. All positions of the parsed expressions and of reported issues are
+		// relative to the host document.
+		ParseSnippet(filename string, source string, line, column, offset int, singleExpression bool) (expr Expression, err error)
+
+		// ParseBytes is like Parse but the source is given as bytes. The bytes are not copied, so they must not
+		// be modified for as long as the parsed expression, its strings, or reported issues are in use.
+		ParseBytes(filename string, source []byte, singleExpression bool) (expr Expression, err error)
+
+		// ParseSource is like Parse but the source is read one chunk at a time so that a text that is stored
+		// in pieces, such as an editor buffer, need not be flattened into one string. See Source.
+		ParseSource(filename string, source Source, singleExpression bool) (expr Expression, err error)
+
+		// ParseExpression parses a source that consists of exactly one expression, such as
+		// `$port + 1` or `{ owner => root }`, e.g. a value in a YAML or JSON configuration that is bound
+		// to Puppet code. Unlike Parse with singleExpression set, an empty source is an error and the
+		// source is never treated as EPP. Input that follows the expression is a PARSE_TRAILING_INPUT
+		// error. Keywords that are valid as names, such as 'default' or 'site', are parsed the way they
+		// are in a collection.
+		ParseExpression(filename string, source string) (expr Expression, err error)
+
+		// ParseExpressionList parses a comma separated list of expressions such as `1, 'two', $three`.
+		// The list may be empty and may end with a comma. Each element is parsed the way that
+		// ParseExpression parses its source.
+		ParseExpressionList(filename string, source string) (exprs []Expression, err error)
+
+		// ParseAttributeOperations parses a comma separated list of attribute operations such as
+		// `mode => '0644', owner => root` that is not enclosed in a resource body. The list may be
+		// empty and may end with a comma. The elements of the returned slice are AttributeOperation
+		// and AttributesOperation expressions.
+		ParseAttributeOperations(filename string, source string) (ops []Expression, err error)
+
+		// ParseParameterList parses a comma separated list of parameters such as
+		// `Integer $port = 80, String $host` that is not enclosed in a definition. The list may be
+		// empty and may end with a comma. The elements of the returned slice are Parameter expressions.
+		ParseParameterList(filename string, source string) (params []Expression, err error)
+
+		// ParseHeredoc parses the given body of a heredoc that is declared by the given spec. The body is
+		// the text between the line of the declaration and the line of the end tag. The returned expression
+		// is the text of the heredoc, i.e. a LiteralString or, when the heredoc is subject to interpolation,
+		// a ConcatenatedString. All positions are relative to the body. Errors in the spec itself are
+		// reported on line 0, i.e. without a position in the body.
+		ParseHeredoc(filename string, spec *HeredocSpec, body string) (text Expression, err error)
+
+		// Enabled returns true if the feature that is enabled by the given option is enabled in this
+		// parser. See Features for a list of all features.
+		Enabled(option Option) bool
+	}
+
+	// WarningsParser is an ExpressionParser that also returns the warnings that a parse issues, such as the
+	// warnings for extraneous commas that PARSER_LENIENT_COMMAS enables. The parsers that CreateParser
+	// returns are WarningsParsers.
+	WarningsParser interface {
+		ExpressionParser
+
+		// ParseWithWarnings is like Parse but also returns the warnings that were issued by the call
+		ParseWithWarnings(filename string, source string, singleExpression bool) (expr Expression, warnings []issue.Reported, err error)
 	}
 
 	// For argument lists that are not within parameters
@@ -26,7 +91,8 @@ type (
 )
 
 // Set of names that will be treated as top level function calls rather than just identifiers
-// when followed by a single expression that is not within parenthesis.
+// when followed by a single expression that is not within parenthesis. The parser uses
+// isStatementCall, which is generated from this map.
 var statementCalls = map[string]bool{
 	`require`: true,
 	`realize`: true,
@@ -47,6 +113,7 @@ var statementCalls = map[string]bool{
 	`return`: true,
 }
 
+// The words that declare activities. The parser uses workflowStyle, which is generated from this map.
 var workflowStyles = map[string]ActivityStyle{
 	`workflow`:  ActivityStyleWorkflow,
 	`resource`:  ActivityStyleResource,
@@ -70,10 +137,43 @@ type Lexer interface {
 	TokenString() string
 
 	AssertToken(token int)
+
+	// PeekToken returns the token that is n tokens ahead of the current token without changing the
+	// state of the lexer. PeekToken(0) returns the current token.
+	PeekToken(n int) int
+
+	// Mark returns a mark that captures the current state of the lexer
+	Mark() LexerMark
+
+	// Rewind restores the state of the lexer to the state captured by the given mark
+	Rewind(mark LexerMark)
+}
+
+// LexerMark is the state of a Lexer returned by its Mark method
+type LexerMark struct {
+	pos              int
+	nextLineStart    int
+	beginningOfLine  int
+	currentToken     int
+	tokenStartPos    int
+	tokenEnd         int
+	previousTokenEnd int
+	tokenValue       interface{}
+	radix            int
+	lookalikeStart   int
+	lookalikeName    string
+	disabledStart    int
+	disabledSyntax   string
+	disabledOption   Option
+	eppTextStart     int
+	tokens           *TokenRecording
+	recordTokens     bool
+	recorded         int
 }
 
 type lexer struct {
 	context
+	errorRecovery bool
 }
 
 type Option int
@@ -84,16 +184,61 @@ const PARSER_TASKS_ENABLED = Option(3)
 const PARSER_WORKFLOW_ENABLED = Option(4)
 const PARSER_EPP_MODE = Option(5)
 
-func NewSimpleLexer(filename string, source string) Lexer {
-	// Essentially a lexer that has no knowledge of interpolations
-	return &lexer{context{
-		stringReader:          stringReader{text: source},
-		factory:               nil,
-		locator:               &Locator{string: source, file: filename},
-		handleBacktickStrings: false,
-		handleHexEscapes:      false,
-		tasks:                 false,
-		workflow:              false}}
+// LEXER_ERROR_RECOVERY makes a Lexer created by NewLexer produce a TOKEN_ERROR instead of panicking when
+// it encounters source that cannot be lexed. The option has no effect on parsers.
+const LEXER_ERROR_RECOVERY = Option(6)
+
+// LEXER_EMIT_COMMENTS makes a Lexer created by NewLexer produce a TOKEN_COMMENT for each comment instead
+// of skipping it. The TokenString of a comment token is the whole comment, including its delimiters but
+// excluding the newline that ends a line comment. The option has no effect on parsers.
+const LEXER_EMIT_COMMENTS = Option(7)
+
+// PARSER_LENIENT_COMMAS makes the parser accept extraneous commas between statements. A warning is issued
+// for each such comma instead of the PARSE_EXTRANEOUS_COMMA error.
+const PARSER_LENIENT_COMMAS = Option(8)
+
+// PARSER_IMPORT_COMPAT makes the parser produce an ImportExpression for a legacy Puppet 3 statement such
+// as `import 'nodes/*.pp'`. Without this option, such a statement is parsed as a call to a function
+// named 'import'.
+const PARSER_IMPORT_COMPAT = Option(9)
+
+// NewSimpleLexer returns a lexer for the given source. It is equivalent to NewLexer.
+func NewSimpleLexer(filename string, source string, options ...Option) Lexer {
+	return NewLexer(filename, source, options...)
+}
+
+// NewLexer returns a lexer for the given source. The lexer has no knowledge of interpolations. Its
+// behavior can be modified using the options PARSER_HANDLE_BACKTICK_STRINGS, PARSER_HANDLE_HEX_ESCAPES,
+// LEXER_ERROR_RECOVERY, and LEXER_EMIT_COMMENTS. Other options are ignored.
+//
+// A lexer that recovers from errors produces a TOKEN_ERROR for the erroneous part of the source and then
+// continues with the rest. The TokenValue of the error token is the issue.Reported that describes the error
+// and its TokenString is the erroneous part of the source. An unterminated string, comment, or heredoc
+// extends to the end of the source. A string that contains an erroneous escape sequence or interpolation
+// extends to its closing delimiter.
+func NewLexer(filename string, source string, options ...Option) Lexer {
+	return NewSourceLexer(filename, StringSource(source), options...)
+}
+
+// NewSourceLexer is like NewLexer but the lexer reads the given source one chunk at a time. See Source.
+func NewSourceLexer(filename string, source Source, options ...Option) Lexer {
+	locator := NewSourceLocator(filename, source)
+	l := &lexer{context: context{
+		stringReader:  locatorReader(locator),
+		factory:       nil,
+		locator:       locator,
+		nextLineStart: -1}}
+	for _, option := range options {
+		switch option {
+		case PARSER_HANDLE_BACKTICK_STRINGS, PARSER_HANDLE_HEX_ESCAPES:
+			l.features.add(option)
+		case LEXER_ERROR_RECOVERY:
+			l.errorRecovery = true
+		case LEXER_EMIT_COMMENTS:
+			l.emitComments = true
+		}
+	}
+	return l
 }
 
 func (l *lexer) CurrentToken() int {
@@ -101,19 +246,80 @@ func (l *lexer) CurrentToken() int {
 }
 
 func (l *lexer) NextToken() int {
-	l.context.nextToken()
+	if l.errorRecovery {
+		l.nextTokenOrError()
+	} else {
+		l.context.nextToken()
+	}
 	return l.context.currentToken
 }
 
+// nextTokenOrError lexes the next token and produces a TOKEN_ERROR if that fails
+func (l *lexer) nextTokenOrError() {
+	c := &l.context
+	c.tokenStartPos = -1
+	defer func() {
+		if r := recover(); r != nil {
+			ri, ok := r.(issue.Reported)
+			if !ok {
+				panic(r)
+			}
+			errorPos := c.Pos()
+			start := c.tokenStartPos
+			if start < 0 || start > errorPos {
+				start = errorPos
+			}
+			c.SetPos(errorTokenEnd(c.Text(), start, errorPos, ri.Code()))
+			c.setTokenValue(TOKEN_ERROR, ri)
+			c.tokenStartPos = start
+		}
+	}()
+	c.nextToken()
+}
+
+// errorTokenEnd returns the end of an erroneous token that starts at the given start position and
+// for which the given error was detected at the given error position
+func errorTokenEnd(text string, start, errorPos int, code issue.Code) int {
+	switch code {
+	case LEX_UNTERMINATED_COMMENT, LEX_UNTERMINATED_STRING, LEX_HEREDOC_UNTERMINATED, LEX_UNBALANCED_EPP_COMMENT:
+		return len(text)
+	}
+	if start < len(text) {
+		switch delimiter := text[start]; delimiter {
+		case '"', '\'', '`':
+			for i := start + 1; i < len(text); i++ {
+				switch text[i] {
+				case '\\':
+					i++
+				case delimiter:
+					return i + 1
+				}
+			}
+			return len(text)
+		}
+	}
+	if errorPos > start {
+		return errorPos
+	}
+	if start < len(text) {
+		_, sz := utf8.DecodeRuneInString(text[start:])
+		return start + sz
+	}
+	return len(text)
+}
+
 func (l *lexer) SetPos(pos int) {
 	l.context.SetPos(pos)
 }
 
 func (l *lexer) SyntaxError() {
-	panic(l.context.parseIssue2(LEX_UNEXPECTED_TOKEN, issue.H{`token`: tokenMap[l.context.currentToken]}))
+	panic(l.context.parseIssue2(LEX_UNEXPECTED_TOKEN, issue.H{`token`: token.String(l.context.currentToken)}))
 }
 
 func (l *lexer) TokenString() string {
+	if l.context.currentToken == TOKEN_ERROR {
+		return l.context.From(l.context.tokenStartPos)
+	}
 	return l.context.tokenString()
 }
 
@@ -129,29 +335,156 @@ func (l *lexer) AssertToken(token int) {
 	l.context.assertToken(token)
 }
 
+func (l *lexer) PeekToken(n int) int {
+	mark := l.Mark()
+	defer l.Rewind(mark)
+	for ; n > 0 && l.context.currentToken != TOKEN_END; n-- {
+		l.NextToken()
+	}
+	return l.context.currentToken
+}
+
+func (l *lexer) Mark() LexerMark {
+	c := &l.context
+	recorded := 0
+	if c.recordTokens {
+		recorded = len(c.tokens.keys)
+	}
+	return LexerMark{
+		pos:              c.Pos(),
+		nextLineStart:    c.nextLineStart,
+		beginningOfLine:  c.beginningOfLine,
+		currentToken:     c.currentToken,
+		tokenStartPos:    c.tokenStartPos,
+		tokenEnd:         c.tokenEnd,
+		previousTokenEnd: c.previousTokenEnd,
+		tokenValue:       c.tokenValue,
+		radix:            c.radix,
+		lookalikeStart:   c.lookalikeStart,
+		lookalikeName:    c.lookalikeName,
+		disabledStart:    c.disabledStart,
+		disabledSyntax:   c.disabledSyntax,
+		disabledOption:   c.disabledOption,
+		eppTextStart:     c.eppTextStart,
+		tokens:           c.tokens,
+		recordTokens:     c.recordTokens,
+		recorded:         recorded}
+}
+
+func (l *lexer) Rewind(mark LexerMark) {
+	c := &l.context
+	c.SetPos(mark.pos)
+	c.nextLineStart = mark.nextLineStart
+	c.beginningOfLine = mark.beginningOfLine
+	c.currentToken = mark.currentToken
+	c.tokenStartPos = mark.tokenStartPos
+	c.tokenEnd = mark.tokenEnd
+	c.previousTokenEnd = mark.previousTokenEnd
+	c.tokenValue = mark.tokenValue
+	c.radix = mark.radix
+	c.lookalikeStart = mark.lookalikeStart
+	c.lookalikeName = mark.lookalikeName
+	c.disabledStart = mark.disabledStart
+	c.disabledSyntax = mark.disabledSyntax
+	c.disabledOption = mark.disabledOption
+	c.eppTextStart = mark.eppTextStart
+	c.tokens = mark.tokens
+	c.recordTokens = mark.recordTokens
+	if c.recordTokens {
+		// Tokens that were recorded after the mark must not be replayed in a state that they weren't
+		// lexed in
+		c.tokens.truncate(mark.recorded)
+	}
+}
+
 // CreatePspecParser returns a parser that is capable of lexing backticked strings and that
 // will recognize \xNN escapes in double qouted strings
 func CreatePspecParser() ExpressionParser {
 	return CreateParser(PARSER_HANDLE_BACKTICK_STRINGS, PARSER_HANDLE_HEX_ESCAPES)
 }
 
+// CreateParser returns a parser with the features that are enabled by the given options.
+//
+// The parser is safe for concurrent use by multiple goroutines. Each call to one of its parse methods
+// uses state of its own, so a service can create one parser per set of options and share it. The
+// expressions that a parser returns are not modified once they have been returned and can be read, e.g.
+// by validators that run in different goroutines, without synchronization.
 func CreateParser(parserOptions ...Option) ExpressionParser {
-	ctx := &context{factory: DefaultFactory(), handleBacktickStrings: false, handleHexEscapes: false, tasks: false, workflow: false}
+	p := &parser{factory: DefaultFactory()}
 	for _, option := range parserOptions {
-		switch option {
-		case PARSER_EPP_MODE:
-			ctx.eppMode = true
-		case PARSER_HANDLE_BACKTICK_STRINGS:
-			ctx.handleBacktickStrings = true
-		case PARSER_HANDLE_HEX_ESCAPES:
-			ctx.handleHexEscapes = true
-		case PARSER_TASKS_ENABLED:
-			ctx.tasks = true
-		case PARSER_WORKFLOW_ENABLED:
-			ctx.workflow = true
+		if _, ok := featureRegistry[option]; ok {
+			p.features.add(option)
 		}
 	}
-	return ctx
+	return p
+}
+
+// parser is the ExpressionParser returned by CreateParser. It holds the configuration that is shared by
+// all calls and creates a new context for each call.
+type parser struct {
+	features featureSet
+	factory  ExpressionFactory
+	tracer   Tracer
+}
+
+// newContext returns a context for one call to a parse method
+func (p *parser) newContext() *context {
+	return &context{features: p.features, factory: p.factory, tracer: p.tracer}
+}
+
+func (p *parser) Enabled(option Option) bool {
+	return p.features.has(option)
+}
+
+func (p *parser) Parse(filename string, source string, singleExpression bool) (Expression, error) {
+	expr, _, err := p.ParseWithWarnings(filename, source, singleExpression)
+	return expr, err
+}
+
+func (p *parser) ParseWithWarnings(filename string, source string, singleExpression bool) (expr Expression, warnings []issue.Reported, err error) {
+	ctx := p.newContext()
+	expr, err = ctx.Parse(filename, source, singleExpression)
+	return expr, ctx.warnings, err
+}
+
+func (p *parser) ParseSnippet(filename string, source string, line, column, offset int, singleExpression bool) (Expression, error) {
+	ctx := p.newContext()
+	return ctx.ParseSnippet(filename, source, line, column, offset, singleExpression)
+}
+
+func (p *parser) ParseBytes(filename string, source []byte, singleExpression bool) (Expression, error) {
+	ctx := p.newContext()
+	return ctx.ParseBytes(filename, source, singleExpression)
+}
+
+func (p *parser) ParseSource(filename string, source Source, singleExpression bool) (Expression, error) {
+	ctx := p.newContext()
+	return ctx.ParseSource(filename, source, singleExpression)
+}
+
+func (p *parser) ParseExpression(filename string, source string) (Expression, error) {
+	ctx := p.newContext()
+	return ctx.ParseExpression(filename, source)
+}
+
+func (p *parser) ParseExpressionList(filename string, source string) ([]Expression, error) {
+	ctx := p.newContext()
+	return ctx.ParseExpressionList(filename, source)
+}
+
+func (p *parser) ParseAttributeOperations(filename string, source string) ([]Expression, error) {
+	ctx := p.newContext()
+	return ctx.ParseAttributeOperations(filename, source)
+}
+
+func (p *parser) ParseParameterList(filename string, source string) ([]Expression, error) {
+	ctx := p.newContext()
+	return ctx.ParseParameterList(filename, source)
+}
+
+func (p *parser) ParseHeredoc(filename string, spec *HeredocSpec, body string) (Expression, error) {
+	ctx := p.newContext()
+	return ctx.ParseHeredoc(filename, spec, body)
 }
 
 // Parse the contents of the given source. The filename is optional and will be used
@@ -160,31 +493,152 @@ func CreateParser(parserOptions ...Option) ExpressionParser {
 // If eppMode is true, the context will treat the given source as text with embedded puppet
 // expressions.
 func (ctx *context) Parse(filename string, source string, singleExpression bool) (expr Expression, err error) {
-	ctx.stringReader = stringReader{text: source}
-	ctx.locator = &Locator{string: source, file: filename}
-	ctx.definitions = make([]Definition, 0, 8)
-	ctx.nextLineStart = -1
+	return ctx.parseWithLocator(NewLocator(filename, source), singleExpression)
+}
+
+func (ctx *context) ParseSnippet(filename string, source string, line, column, offset int, singleExpression bool) (expr Expression, err error) {
+	return ctx.parseWithLocator(NewSnippetLocator(filename, source, line, column, offset), singleExpression)
+}
+
+func (ctx *context) ParseBytes(filename string, source []byte, singleExpression bool) (expr Expression, err error) {
+	return ctx.Parse(filename, unsafe.String(unsafe.SliceData(source), len(source)), singleExpression)
+}
+
+func (ctx *context) ParseSource(filename string, source Source, singleExpression bool) (expr Expression, err error) {
+	return ctx.parseWithLocator(NewSourceLocator(filename, source), singleExpression)
+}
+
+func (ctx *context) ParseExpression(filename string, source string) (Expression, error) {
+	return parseFragment(ctx, NewLocator(filename, source), func() Expression {
+		ctx.nextToken()
+		if ctx.currentToken == TOKEN_END {
+			panic(ctx.parseIssue(PARSE_EXPECTED_EXPRESSION))
+		}
+		expr := ctx.hashEntry()
+		if ctx.currentToken != TOKEN_END {
+			ctx.SetPos(ctx.tokenStartPos)
+			panic(ctx.parseIssue2(PARSE_TRAILING_INPUT, issue.H{`actual`: token.String(ctx.currentToken)}))
+		}
+		return expr
+	})
+}
+
+func (ctx *context) ParseExpressionList(filename string, source string) ([]Expression, error) {
+	return parseFragment(ctx, NewLocator(filename, source), func() []Expression {
+		ctx.nextToken()
+		return ctx.expressions(TOKEN_END, ctx.hashEntry)
+	})
+}
 
-	expr, err = ctx.parseTopExpression(filename, source, singleExpression)
+func (ctx *context) ParseAttributeOperations(filename string, source string) ([]Expression, error) {
+	return parseFragment(ctx, NewLocator(filename, source), func() []Expression {
+		ctx.nextToken()
+		ops := make([]Expression, 0, 5)
+		for ctx.currentToken != TOKEN_END {
+			ops = append(ops, ctx.attributeOperation())
+			if ctx.currentToken != TOKEN_COMMA {
+				break
+			}
+			ctx.nextToken()
+		}
+		ctx.assertToken(TOKEN_END)
+		return ops
+	})
+}
+
+func (ctx *context) ParseParameterList(filename string, source string) ([]Expression, error) {
+	return parseFragment(ctx, NewLocator(filename, source), func() []Expression {
+		ctx.nextToken()
+		return ctx.expressions(TOKEN_END, ctx.parameter)
+	})
+}
+
+// parseFragment parses the source of the given locator using the given function. The result is the zero
+// value when parsing fails, and the error is mapped the same way as an error from Parse.
+func parseFragment[T any](ctx *context, locator *Locator, parse func() T) (result T, err error) {
+	ctx.reset(locator)
+	result, err = recoverParse(parse)
+	if err != nil {
+		var zero T
+		return zero, ctx.sourceError(err)
+	}
+	return
+}
+
+// recoverParse returns the result of the given function, or the issue or parse error that it panics with
+func recoverParse[T any](parse func() T) (result T, err error) {
+	defer recoverParseError(&err)
+	result = parse()
+	return
+}
+
+func (ctx *context) parseWithLocator(locator *Locator, singleExpression bool) (expr Expression, err error) {
+	ctx.reset(locator)
+	expr, err = ctx.parseTopExpression(locator.File(), singleExpression)
+	if err != nil {
+		err = ctx.sourceError(err)
+	}
 	if err == nil && !singleExpression {
 		expr = ctx.factory.Program(expr, ctx.definitions, ctx.locator, 0, ctx.Pos())
 	}
 	return
 }
 
-func (ctx *context) parseTopExpression(filename string, source string, singleExpression bool) (expr Expression, err error) {
-	defer func() {
-		if r := recover(); r != nil {
-			var ok bool
-			if err, ok = r.(issue.Reported); !ok {
-				if err, ok = r.(*ParseError); !ok {
-					panic(r)
-				}
+// reset prepares the context for parsing the source of the given locator
+func (ctx *context) reset(locator *Locator) {
+	ctx.stringReader = locatorReader(locator)
+	ctx.locator = locator
+	ctx.definitions = make([]Definition, 0, 8)
+	ctx.nextLineStart = -1
+	ctx.lookalikeStart = -1
+	ctx.lookalikeName = ``
+	ctx.disabledStart = -1
+	ctx.eppTextStart = -1
+	ctx.warnings = nil
+}
+
+// sourceError returns the error to report for the given error that parsing the source failed with. It
+// points to a keyword that was written with the wrong case or to a feature that isn't enabled when that
+// is the likely cause of the error.
+func (ctx *context) sourceError(err error) error {
+	return ctx.disabledFeatureError(ctx.keywordCaseError(err))
+}
+
+// keywordCaseError returns a PARSE_KEYWORD_CASE error located at the last type name that was lexed
+// before the given error if that type name differs from a keyword only by case, e.g. 'If', and is in the
+// statement of the error or ends the statement before it on the same line. Such a type name is the likely
+// cause of the error. The given error is returned in all other cases.
+func (ctx *context) keywordCaseError(err error) error {
+	if ctx.lookalikeStart < 0 {
+		return err
+	}
+	if ri, ok := err.(issue.Reported); ok {
+		if loc, ok := ri.Location().(*location); ok && loc.byteOffset > ctx.lookalikeStart {
+			keyword, _ := KeywordLookalike(ctx.lookalikeName)
+			ctx.SetPos(ctx.lookalikeStart)
+			return ctx.parseIssue2(PARSE_KEYWORD_CASE, issue.H{`name`: ctx.lookalikeName, `keyword`: keyword})
+		}
+	}
+	return err
+}
+
+// recoverParseError assigns a recovered issue or parse error to the given error. Any other recovered
+// value is propagated.
+func recoverParseError(err *error) {
+	if r := recover(); r != nil {
+		var ok bool
+		if *err, ok = r.(issue.Reported); !ok {
+			if *err, ok = r.(*ParseError); !ok {
+				panic(r)
 			}
 		}
-	}()
+	}
+}
+
+func (ctx *context) parseTopExpression(filename string, singleExpression bool) (expr Expression, err error) {
+	defer recoverParseError(&err)
 
-	if ctx.eppMode {
+	if ctx.features.has(PARSER_EPP_MODE) {
 		ctx.consumeEPP()
 
 		var text string
@@ -195,14 +649,14 @@ func (ctx *context) parseTopExpression(filename string, source string, singleExp
 
 		asEppLambda := func(e Expression) Expression {
 			if l, ok := e.(*LambdaExpression); ok {
-				if _, ok = l.body.(*EppExpression); ok {
+				if _, ok = l.Body().(*EppExpression); ok {
 					return e
 				}
 			}
 			if _, ok := e.(*BlockExpression); !ok {
 				e = ctx.factory.Block([]Expression{e}, ctx.locator, 0, ctx.Pos())
 			}
-			return ctx.factory.EppExpression([]Expression{}, e, ctx.locator, 0, ctx.Pos())
+			return ctx.factory.EppExpression(nil, e, ctx.locator, 0, ctx.Pos())
 		}
 
 		if ctx.currentToken == TOKEN_END {
@@ -243,8 +697,8 @@ func (ctx *context) parseTopExpression(filename string, source string, singleExp
 }
 
 func (ctx *context) parse(expectedEnd int, singleExpression bool) (expr Expression) {
-	_, start := ctx.skipWhite(false)
-	ctx.SetPos(start)
+	// The first token of the block is current, so the block starts where that token starts
+	start := ctx.tokenStartPos
 	if singleExpression {
 		if ctx.currentToken == expectedEnd {
 			expr = ctx.factory.Undef(ctx.locator, start, 0)
@@ -257,7 +711,22 @@ func (ctx *context) parse(expectedEnd int, singleExpression bool) (expr Expressi
 
 	expressions := make([]Expression, 0, 10)
 	for ctx.currentToken != expectedEnd {
-		expressions = append(expressions, ctx.syntacticStatement())
+		stmt := ctx.syntacticStatement()
+		if qn, ok := stmt.(*QualifiedName); !ok || ast.SourceOffset(qn) != ctx.disabledStart {
+			// A use of a disabled feature is only considered the cause of errors in the same statement. A
+			// name such as 'plan' is a statement of its own at this point.
+			ctx.disabledStart = -1
+		}
+		if ctx.lookalikeStart >= 0 && ctx.lookalikeStart < ctx.tokenStartPos {
+			// A keyword look-alike is only considered the cause of errors in the same statement, or in the
+			// statement that follows it on the same line when it is the last token of its statement
+			end := ctx.lookalikeStart + len(ctx.lookalikeName)
+			if end != ctx.previousTokenEnd || strings.IndexByte(ctx.locator.Slice(end, ctx.tokenStartPos), '\n') >= 0 {
+				ctx.lookalikeStart = -1
+				ctx.lookalikeName = ``
+			}
+		}
+		expressions = append(expressions, stmt)
 		if ctx.currentToken == TOKEN_SEMICOLON {
 			ctx.nextToken()
 		}
@@ -266,21 +735,21 @@ func (ctx *context) parse(expectedEnd int, singleExpression bool) (expr Expressi
 	return
 }
 
-func (ctx *context) assertToken(token int) {
-	if ctx.currentToken != token {
+func (ctx *context) assertToken(expected int) {
+	if ctx.currentToken != expected {
 		ctx.SetPos(ctx.tokenStartPos)
-		panic(ctx.parseIssue2(PARSE_EXPECTED_TOKEN, issue.H{`expected`: tokenMap[token], `actual`: tokenMap[ctx.currentToken]}))
+		panic(ctx.parseIssue2(PARSE_EXPECTED_TOKEN, issue.H{`expected`: token.String(expected), `actual`: token.String(ctx.currentToken)}))
 	}
 }
 
 func (ctx *context) tokenString() string {
 	if ctx.tokenValue == nil {
-		return tokenMap[ctx.currentToken]
+		return token.String(ctx.currentToken)
 	}
 	if str, ok := ctx.tokenValue.(string); ok {
 		return str
 	}
-	panic(fmt.Sprintf("Token '%s' has no string representation", tokenMap[ctx.currentToken]))
+	panic(fmt.Sprintf("Token '%s' has no string representation", token.String(ctx.currentToken)))
 }
 
 // Iterates all statements in a block and transforms qualified names that names a "statement call" and are followed
@@ -296,16 +765,22 @@ func (ctx *context) transformCalls(exprs []Expression, start int) (result []Expr
 	idx := 1
 	for ; idx < top; idx++ {
 		expr := exprs[idx]
-		if qname, ok := memo.(*QualifiedName); ok && statementCalls[qname.name] {
+		if qname, ok := memo.(*QualifiedName); ok && isStatementCall(qname.Name()) {
 			var args []Expression
 			if csList, ok := expr.(*commaSeparatedList); ok {
-				args = csList.elements
+				args = csList.Elements()
 			} else {
 				args = []Expression{expr}
 			}
-			cn := ctx.factory.CallNamed(memo, false, args, nil, ctx.locator, memo.ByteOffset(), (expr.ByteOffset()+expr.ByteLength())-memo.ByteOffset())
+			var cn Expression
+			length := (ast.SourceOffset(expr) + expr.ByteLength()) - ast.SourceOffset(memo)
+			if qname.Name() == `import` && ctx.features.has(PARSER_IMPORT_COMPAT) {
+				cn = ctx.factory.Import(args, ctx.locator, ast.SourceOffset(memo), length)
+			} else {
+				cn = ctx.factory.CallNamed(memo, false, args, nil, ctx.locator, ast.SourceOffset(memo), length)
+			}
 			if cnFunc, ok := expr.(*CallNamedFunctionExpression); ok {
-				cnFunc.rvalRequired = true
+				cnFunc.SetRvalRequired(true)
 			}
 			result = append(result, cn)
 			idx++
@@ -315,31 +790,58 @@ func (ctx *context) transformCalls(exprs []Expression, start int) (result []Expr
 			memo = exprs[idx]
 		} else {
 			if cnFunc, ok := memo.(*CallNamedFunctionExpression); ok {
-				cnFunc.rvalRequired = false
+				cnFunc.SetRvalRequired(false)
 			}
 			result = append(result, memo)
 			memo = expr
 		}
 	}
 	if cnFunc, ok := memo.(*CallNamedFunctionExpression); ok {
-		cnFunc.rvalRequired = false
+		cnFunc.SetRvalRequired(false)
 	}
 	result = append(result, memo)
+	extraneous := false
 	for _, ex := range result {
 		if csl, ok := ex.(*commaSeparatedList); ok {
 			// This happens when a block contains extraneous commas between statements. The
 			// location of the comma is estimated to be right after the first statement in
 			// the list
-			f := csl.elements[0]
-			p := f.ByteOffset() + f.ByteLength()
+			f := csl.Elements()[0]
+			p := ast.SourceOffset(f) + f.ByteLength()
 			l := ctx.locator
 			loc := issue.NewLocation(f.File(), l.LineForOffset(p), l.PosOnLine(p))
-			panic(issue.NewReported(PARSE_EXTRANEOUS_COMMA, issue.SEVERITY_ERROR, issue.NO_ARGS, loc))
+			if !ctx.features.has(PARSER_LENIENT_COMMAS) {
+				panic(issue.NewReported(PARSE_EXTRANEOUS_COMMA, issue.SEVERITY_ERROR, issue.NO_ARGS, loc))
+			}
+			ctx.warnings = append(ctx.warnings, issue.NewReported(PARSE_EXTRANEOUS_COMMA, issue.SEVERITY_WARNING, issue.NO_ARGS, loc))
+			extraneous = true
 		}
 	}
+	if extraneous {
+		result = spliceStatements(result)
+	}
 	return
 }
 
+// spliceStatements replaces each comma separated list in the given statements with the elements of
+// that list
+func spliceStatements(exprs []Expression) []Expression {
+	result := make([]Expression, 0, len(exprs))
+	for _, ex := range exprs {
+		if csl, ok := ex.(*commaSeparatedList); ok {
+			for _, e := range csl.Elements() {
+				if cnFunc, ok := e.(*CallNamedFunctionExpression); ok {
+					cnFunc.SetRvalRequired(false)
+				}
+				result = append(result, e)
+			}
+		} else {
+			result = append(result, ex)
+		}
+	}
+	return result
+}
+
 func (ctx *context) expressions(endToken int, producerFunc func() Expression) (exprs []Expression) {
 	exprs = make([]Expression, 0, 4)
 	for {
@@ -351,8 +853,8 @@ func (ctx *context) expressions(endToken int, producerFunc func() Expression) (e
 			if ctx.currentToken != endToken {
 				ctx.SetPos(ctx.tokenStartPos)
 				panic(ctx.parseIssue2(PARSE_EXPECTED_ONE_OF_TOKENS, issue.H{
-					`expected`: fmt.Sprintf(`'%s' or '%s'`, tokenMap[TOKEN_COMMA], tokenMap[endToken]),
-					`actual`:   tokenMap[ctx.currentToken]}))
+					`expected`: fmt.Sprintf(`'%s' or '%s'`, token.String(TOKEN_COMMA), token.String(endToken)),
+					`actual`:   token.String(ctx.currentToken)}))
 			}
 			return
 		}
@@ -361,6 +863,9 @@ func (ctx *context) expressions(endToken int, producerFunc func() Expression) (e
 }
 
 func (ctx *context) syntacticStatement() (expr Expression) {
+	if tracing {
+		defer ctx.trace(`syntacticStatement`)()
+	}
 	var args []Expression
 	expr = ctx.relationship()
 	for ctx.currentToken == TOKEN_COMMA {
@@ -372,26 +877,35 @@ func (ctx *context) syntacticStatement() (expr Expression) {
 		args = append(args, ctx.relationship())
 	}
 	if args != nil {
-		expr = &commaSeparatedList{LiteralList{Positioned{ctx.locator, expr.ByteOffset(), ctx.Pos() - expr.ByteOffset()}, args}}
+		expr = &commaSeparatedList{*DefaultFactory().Array(args, ctx.locator, ast.SourceOffset(expr), ctx.Pos()-ast.SourceOffset(expr)).(*LiteralList)}
 	}
 	return
 }
 
 func (ctx *context) collectionEntry() (expr Expression) {
+	if tracing {
+		defer ctx.trace(`collectionEntry`)()
+	}
 	return ctx.argument()
 }
 
 func (ctx *context) argument() (expr Expression) {
+	if tracing {
+		defer ctx.trace(`argument`)()
+	}
 	expr = ctx.handleKeyword(ctx.relationship)
 	if ctx.currentToken == TOKEN_FARROW {
 		ctx.nextToken()
 		value := ctx.handleKeyword(ctx.relationship)
-		expr = ctx.factory.KeyedEntry(expr, value, ctx.locator, expr.ByteOffset(), ctx.Pos()-expr.ByteOffset())
+		expr = ctx.factory.KeyedEntry(expr, value, ctx.locator, ast.SourceOffset(expr), ctx.Pos()-ast.SourceOffset(expr))
 	}
 	return
 }
 
 func (ctx *context) hashEntry() (expr Expression) {
+	if tracing {
+		defer ctx.trace(`hashEntry`)()
+	}
 	return ctx.handleKeyword(ctx.relationship)
 }
 
@@ -410,13 +924,16 @@ func (ctx *context) handleKeyword(next func() Expression) (expr Expression) {
 }
 
 func (ctx *context) relationship() (expr Expression) {
+	if tracing {
+		defer ctx.trace(`relationship`)()
+	}
 	expr = ctx.assignment()
 	for {
 		switch ctx.currentToken {
 		case TOKEN_IN_EDGE, TOKEN_IN_EDGE_SUB, TOKEN_OUT_EDGE, TOKEN_OUT_EDGE_SUB:
 			op := ctx.tokenString()
 			ctx.nextToken()
-			expr = ctx.factory.RelOp(op, expr, ctx.assignment(), ctx.locator, expr.ByteOffset(), ctx.Pos()-expr.ByteOffset())
+			expr = ctx.factory.RelOp(op, expr, ctx.assignment(), ctx.locator, ast.SourceOffset(expr), ctx.Pos()-ast.SourceOffset(expr))
 		default:
 			return expr
 		}
@@ -424,13 +941,16 @@ func (ctx *context) relationship() (expr Expression) {
 }
 
 func (ctx *context) assignment() (expr Expression) {
+	if tracing {
+		defer ctx.trace(`assignment`)()
+	}
 	expr = ctx.activity()
 	for {
 		switch ctx.currentToken {
 		case TOKEN_ASSIGN, TOKEN_ADD_ASSIGN, TOKEN_SUBTRACT_ASSIGN:
 			op := ctx.tokenString()
 			ctx.nextToken()
-			expr = ctx.factory.Assignment(op, expr, ctx.assignment(), ctx.locator, expr.ByteOffset(), ctx.Pos()-expr.ByteOffset())
+			expr = ctx.factory.Assignment(op, expr, ctx.assignment(), ctx.locator, ast.SourceOffset(expr), ctx.Pos()-ast.SourceOffset(expr))
 		default:
 			return expr
 		}
@@ -438,15 +958,17 @@ func (ctx *context) assignment() (expr Expression) {
 }
 
 func (ctx *context) activity() (expr Expression) {
+	if tracing {
+		defer ctx.trace(`activity`)()
+	}
 	start := ctx.Pos()
 	expr = ctx.resource()
-	if ctx.workflow {
-		if qn, ok := expr.(*QualifiedName); ok {
-			s := qn.Name()
-			if style, ok := workflowStyles[s]; ok {
-				if name, ok := ctx.identifier(); ok {
-					expr = ctx.activityDeclaration(start, style, name, true)
-				}
+	if qn, ok := expr.(*QualifiedName); ok {
+		if style, ok := workflowStyle(qn.Name()); ok {
+			if !ctx.features.has(PARSER_WORKFLOW_ENABLED) {
+				ctx.useOfDisabled(ast.SourceOffset(qn), qn.Name(), PARSER_WORKFLOW_ENABLED)
+			} else if name, ok := ctx.identifier(); ok {
+				expr = ctx.activityDeclaration(start, style, name, true)
 			}
 		}
 	}
@@ -454,14 +976,20 @@ func (ctx *context) activity() (expr Expression) {
 }
 
 func (ctx *context) resource() (expr Expression) {
+	if tracing {
+		defer ctx.trace(`resource`)()
+	}
 	expr = ctx.expression()
 	if ctx.currentToken == TOKEN_LC {
-		expr = ctx.resourceExpression(expr.ByteOffset(), expr, REGULAR)
+		expr = ctx.resourceExpression(ast.SourceOffset(expr), expr, REGULAR)
 	}
 	return
 }
 
 func (ctx *context) expression() (expr Expression) {
+	if tracing {
+		defer ctx.trace(`expression`)()
+	}
 	expr = ctx.selectExpression()
 	for {
 		switch ctx.currentToken {
@@ -480,14 +1008,17 @@ func (ctx *context) expression() (expr Expression) {
 
 func (ctx *context) convertLhsToCall(ne *NamedAccessExpression, args []Expression, lambda Expression, start, len int) Expression {
 	f := ctx.factory
-	if nal, ok := ne.lhs.(*NamedAccessExpression); ok {
-		ne = f.NamedAccess(ctx.convertLhsToCall(nal, []Expression{}, nil, nal.ByteOffset(), nal.ByteLength()),
-			ne.rhs, ctx.locator, ne.ByteOffset(), ne.ByteLength()).(*NamedAccessExpression)
+	if nal, ok := ne.Lhs().(*NamedAccessExpression); ok {
+		ne = f.NamedAccess(ctx.convertLhsToCall(nal, []Expression{}, nil, ast.SourceOffset(nal), nal.ByteLength()),
+			ne.Rhs(), ctx.locator, ast.SourceOffset(ne), ne.ByteLength()).(*NamedAccessExpression)
 	}
 	return f.CallMethod(ne, args, lambda, ctx.locator, start, len)
 }
 
 func (ctx *context) selectExpression() (expr Expression) {
+	if tracing {
+		defer ctx.trace(`selectExpression`)()
+	}
 	expr = ctx.orExpression()
 	for {
 		switch ctx.currentToken {
@@ -500,12 +1031,15 @@ func (ctx *context) selectExpression() (expr Expression) {
 }
 
 func (ctx *context) orExpression() (expr Expression) {
+	if tracing {
+		defer ctx.trace(`orExpression`)()
+	}
 	expr = ctx.andExpression()
 	for {
 		switch ctx.currentToken {
 		case TOKEN_OR:
 			ctx.nextToken()
-			expr = ctx.factory.Or(expr, ctx.orExpression(), ctx.locator, expr.ByteOffset(), ctx.Pos()-expr.ByteOffset())
+			expr = ctx.factory.Or(expr, ctx.orExpression(), ctx.locator, ast.SourceOffset(expr), ctx.Pos()-ast.SourceOffset(expr))
 		default:
 			return
 		}
@@ -513,12 +1047,15 @@ func (ctx *context) orExpression() (expr Expression) {
 }
 
 func (ctx *context) andExpression() (expr Expression) {
+	if tracing {
+		defer ctx.trace(`andExpression`)()
+	}
 	expr = ctx.compareExpression()
 	for {
 		switch ctx.currentToken {
 		case TOKEN_AND:
 			ctx.nextToken()
-			expr = ctx.factory.And(expr, ctx.andExpression(), ctx.locator, expr.ByteOffset(), ctx.Pos()-expr.ByteOffset())
+			expr = ctx.factory.And(expr, ctx.andExpression(), ctx.locator, ast.SourceOffset(expr), ctx.Pos()-ast.SourceOffset(expr))
 		default:
 			return
 		}
@@ -526,13 +1063,16 @@ func (ctx *context) andExpression() (expr Expression) {
 }
 
 func (ctx *context) compareExpression() (expr Expression) {
+	if tracing {
+		defer ctx.trace(`compareExpression`)()
+	}
 	expr = ctx.equalExpression()
 	for {
 		switch ctx.currentToken {
 		case TOKEN_LESS, TOKEN_LESS_EQUAL, TOKEN_GREATER, TOKEN_GREATER_EQUAL:
 			op := ctx.tokenString()
 			ctx.nextToken()
-			expr = ctx.factory.Comparison(op, expr, ctx.compareExpression(), ctx.locator, expr.ByteOffset(), ctx.Pos()-expr.ByteOffset())
+			expr = ctx.factory.Comparison(op, expr, ctx.compareExpression(), ctx.locator, ast.SourceOffset(expr), ctx.Pos()-ast.SourceOffset(expr))
 
 		default:
 			return
@@ -541,6 +1081,9 @@ func (ctx *context) compareExpression() (expr Expression) {
 }
 
 func (ctx *context) equalExpression() (expr Expression) {
+	if tracing {
+		defer ctx.trace(`equalExpression`)()
+	}
 	expr = ctx.shiftExpression()
 	for {
 		t := ctx.currentToken
@@ -548,7 +1091,7 @@ func (ctx *context) equalExpression() (expr Expression) {
 		case TOKEN_EQUAL, TOKEN_NOT_EQUAL:
 			op := ctx.tokenString()
 			ctx.nextToken()
-			expr = ctx.factory.Comparison(op, expr, ctx.equalExpression(), ctx.locator, expr.ByteOffset(), ctx.Pos()-expr.ByteOffset())
+			expr = ctx.factory.Comparison(op, expr, ctx.equalExpression(), ctx.locator, ast.SourceOffset(expr), ctx.Pos()-ast.SourceOffset(expr))
 
 		default:
 			return
@@ -557,6 +1100,9 @@ func (ctx *context) equalExpression() (expr Expression) {
 }
 
 func (ctx *context) shiftExpression() (expr Expression) {
+	if tracing {
+		defer ctx.trace(`shiftExpression`)()
+	}
 	expr = ctx.additiveExpression()
 	for {
 		t := ctx.currentToken
@@ -564,7 +1110,7 @@ func (ctx *context) shiftExpression() (expr Expression) {
 		case TOKEN_LSHIFT, TOKEN_RSHIFT:
 			op := ctx.tokenString()
 			ctx.nextToken()
-			expr = ctx.factory.Arithmetic(op, expr, ctx.shiftExpression(), ctx.locator, expr.ByteOffset(), ctx.Pos()-expr.ByteOffset())
+			expr = ctx.factory.Arithmetic(op, expr, ctx.shiftExpression(), ctx.locator, ast.SourceOffset(expr), ctx.Pos()-ast.SourceOffset(expr))
 
 		default:
 			return
@@ -573,6 +1119,9 @@ func (ctx *context) shiftExpression() (expr Expression) {
 }
 
 func (ctx *context) additiveExpression() (expr Expression) {
+	if tracing {
+		defer ctx.trace(`additiveExpression`)()
+	}
 	expr = ctx.multiplicativeExpression()
 	for {
 		t := ctx.currentToken
@@ -580,7 +1129,7 @@ func (ctx *context) additiveExpression() (expr Expression) {
 		case TOKEN_ADD, TOKEN_SUBTRACT:
 			op := ctx.tokenString()
 			ctx.nextToken()
-			expr = ctx.factory.Arithmetic(op, expr, ctx.additiveExpression(), ctx.locator, expr.ByteOffset(), ctx.Pos()-expr.ByteOffset())
+			expr = ctx.factory.Arithmetic(op, expr, ctx.additiveExpression(), ctx.locator, ast.SourceOffset(expr), ctx.Pos()-ast.SourceOffset(expr))
 
 		default:
 			return
@@ -589,6 +1138,9 @@ func (ctx *context) additiveExpression() (expr Expression) {
 }
 
 func (ctx *context) multiplicativeExpression() (expr Expression) {
+	if tracing {
+		defer ctx.trace(`multiplicativeExpression`)()
+	}
 	expr = ctx.matchExpression()
 	for {
 		t := ctx.currentToken
@@ -596,7 +1148,7 @@ func (ctx *context) multiplicativeExpression() (expr Expression) {
 		case TOKEN_MULTIPLY, TOKEN_DIVIDE, TOKEN_REMAINDER:
 			op := ctx.tokenString()
 			ctx.nextToken()
-			expr = ctx.factory.Arithmetic(op, expr, ctx.multiplicativeExpression(), ctx.locator, expr.ByteOffset(), ctx.Pos()-expr.ByteOffset())
+			expr = ctx.factory.Arithmetic(op, expr, ctx.multiplicativeExpression(), ctx.locator, ast.SourceOffset(expr), ctx.Pos()-ast.SourceOffset(expr))
 
 		default:
 			return
@@ -605,6 +1157,9 @@ func (ctx *context) multiplicativeExpression() (expr Expression) {
 }
 
 func (ctx *context) matchExpression() (expr Expression) {
+	if tracing {
+		defer ctx.trace(`matchExpression`)()
+	}
 	expr = ctx.inExpression()
 	for {
 		t := ctx.currentToken
@@ -612,7 +1167,7 @@ func (ctx *context) matchExpression() (expr Expression) {
 		case TOKEN_MATCH, TOKEN_NOT_MATCH:
 			op := ctx.tokenString()
 			ctx.nextToken()
-			expr = ctx.factory.Match(op, expr, ctx.matchExpression(), ctx.locator, expr.ByteOffset(), ctx.Pos()-expr.ByteOffset())
+			expr = ctx.factory.Match(op, expr, ctx.matchExpression(), ctx.locator, ast.SourceOffset(expr), ctx.Pos()-ast.SourceOffset(expr))
 
 		default:
 			return
@@ -621,12 +1176,15 @@ func (ctx *context) matchExpression() (expr Expression) {
 }
 
 func (ctx *context) inExpression() (expr Expression) {
+	if tracing {
+		defer ctx.trace(`inExpression`)()
+	}
 	expr = ctx.unaryExpression()
 	for {
 		switch ctx.currentToken {
 		case TOKEN_IN:
 			ctx.nextToken()
-			expr = ctx.factory.In(expr, ctx.inExpression(), ctx.locator, expr.ByteOffset(), ctx.Pos()-expr.ByteOffset())
+			expr = ctx.factory.In(expr, ctx.inExpression(), ctx.locator, ast.SourceOffset(expr), ctx.Pos()-ast.SourceOffset(expr))
 
 		default:
 			return expr
@@ -639,13 +1197,16 @@ func (ctx *context) arrayExpression() (elements []Expression) {
 }
 
 func (ctx *context) keyedEntry() Expression {
+	if tracing {
+		defer ctx.trace(`keyedEntry`)()
+	}
 	key := ctx.hashEntry()
 	if ctx.currentToken != TOKEN_FARROW {
 		panic(ctx.parseIssue(PARSE_EXPECTED_FARROW_AFTER_KEY))
 	}
 	ctx.nextToken()
 	value := ctx.hashEntry()
-	return ctx.factory.KeyedEntry(key, value, ctx.locator, key.ByteOffset(), ctx.Pos()-key.ByteOffset())
+	return ctx.factory.KeyedEntry(key, value, ctx.locator, ast.SourceOffset(key), ctx.Pos()-ast.SourceOffset(key))
 }
 
 func (ctx *context) hashExpression() (entries []Expression) {
@@ -653,6 +1214,9 @@ func (ctx *context) hashExpression() (entries []Expression) {
 }
 
 func (ctx *context) unaryExpression() Expression {
+	if tracing {
+		defer ctx.trace(`unaryExpression`)()
+	}
 	unaryStart := ctx.tokenStartPos
 	switch ctx.currentToken {
 	case TOKEN_SUBTRACT:
@@ -664,7 +1228,7 @@ func (ctx *context) unaryExpression() Expression {
 				ctx.setTokenValue(ctx.currentToken, -ctx.tokenValue.(float64))
 			}
 			expr := ctx.primaryExpression()
-			expr.updateOffsetAndLength(unaryStart, ctx.Pos()-unaryStart)
+			ast.UpdateOffsetAndLength(expr, unaryStart, ctx.Pos()-unaryStart)
 			return expr
 		}
 		ctx.nextToken()
@@ -676,7 +1240,7 @@ func (ctx *context) unaryExpression() Expression {
 		if c, _ := ctx.Peek(); isDecimalDigit(c) {
 			ctx.nextToken()
 			expr := ctx.primaryExpression()
-			expr.updateOffsetAndLength(unaryStart, ctx.Pos()-unaryStart)
+			ast.UpdateOffsetAndLength(expr, unaryStart, ctx.Pos()-unaryStart)
 			return expr
 		}
 		panic(ctx.parseIssue2(LEX_UNEXPECTED_TOKEN, issue.H{`token`: `+`}))
@@ -707,6 +1271,9 @@ func (ctx *context) unaryExpression() Expression {
 }
 
 func (ctx *context) primaryExpression() (expr Expression) {
+	if tracing {
+		defer ctx.trace(`primaryExpression`)()
+	}
 	expr = ctx.atomExpression()
 	for {
 		switch ctx.currentToken {
@@ -719,13 +1286,13 @@ func (ctx *context) primaryExpression() (expr Expression) {
 			params := ctx.arrayExpression()
 			isCall := false
 			if qn, ok := expr.(*QualifiedName); ok {
-				_, isCall = statementCalls[qn.name]
+				isCall = isStatementCall(qn.Name())
 			}
-			len := ctx.Pos() - expr.ByteOffset()
+			len := ctx.Pos() - ast.SourceOffset(expr)
 			if isCall {
-				expr = ctx.factory.CallNamed(expr, false, []Expression{ctx.factory.Array(params, ctx.locator, expr.ByteOffset(), len)}, nil, ctx.locator, expr.ByteOffset(), len)
+				expr = ctx.factory.CallNamed(expr, false, []Expression{ctx.factory.Array(params, ctx.locator, ast.SourceOffset(expr), len)}, nil, ctx.locator, ast.SourceOffset(expr), len)
 			} else {
-				expr = ctx.factory.Access(expr, params, ctx.locator, expr.ByteOffset(), len)
+				expr = ctx.factory.Access(expr, params, ctx.locator, ast.SourceOffset(expr), len)
 			}
 			ctx.nextToken()
 		case TOKEN_DOT:
@@ -737,11 +1304,11 @@ func (ctx *context) primaryExpression() (expr Expression) {
 			} else {
 				rhs = ctx.atomExpression()
 			}
-			expr = ctx.factory.NamedAccess(expr, rhs, ctx.locator, expr.ByteOffset(), ctx.Pos()-expr.ByteOffset())
+			expr = ctx.factory.NamedAccess(expr, rhs, ctx.locator, ast.SourceOffset(expr), ctx.Pos()-ast.SourceOffset(expr))
 		default:
 			if namedAccess, ok := expr.(*NamedAccessExpression); ok {
 				// Transform into method calls
-				expr = ctx.convertLhsToCall(namedAccess, []Expression{}, nil, expr.ByteOffset(), expr.ByteLength())
+				expr = ctx.convertLhsToCall(namedAccess, []Expression{}, nil, ast.SourceOffset(expr), expr.ByteLength())
 			}
 			return
 		}
@@ -749,6 +1316,9 @@ func (ctx *context) primaryExpression() (expr Expression) {
 }
 
 func (ctx *context) atomExpression() (expr Expression) {
+	if tracing {
+		defer ctx.trace(`atomExpression`)()
+	}
 	atomStart := ctx.tokenStartPos
 	switch ctx.currentToken {
 	case TOKEN_LP, TOKEN_WSLP:
@@ -873,11 +1443,17 @@ func (ctx *context) atomExpression() (expr Expression) {
 
 	case TOKEN_RENDER_EXPR:
 		ctx.nextToken()
-		expr = ctx.factory.RenderExpression(ctx.expression(), ctx.locator, atomStart, ctx.Pos()-atomStart)
+		rendered := ctx.expression()
+		end := ctx.eppTextStart
+		if end < atomStart {
+			// The tag is not terminated
+			end = ctx.Pos()
+		}
+		expr = ctx.factory.RenderExpression(rendered, ctx.locator, atomStart, end-atomStart)
 
 	default:
 		ctx.SetPos(ctx.tokenStartPos)
-		panic(ctx.parseIssue2(LEX_UNEXPECTED_TOKEN, issue.H{`token`: tokenMap[ctx.currentToken]}))
+		panic(ctx.parseIssue2(LEX_UNEXPECTED_TOKEN, issue.H{`token`: token.String(ctx.currentToken)}))
 	}
 	return
 }
@@ -927,7 +1503,7 @@ func (ctx *context) selectorsExpression(test Expression) (expr Expression) {
 	} else {
 		selectors = []Expression{ctx.selectorEntry()}
 	}
-	expr = ctx.factory.Select(test, selectors, ctx.locator, test.ByteOffset(), ctx.Pos()-test.ByteOffset())
+	expr = ctx.factory.Select(test, selectors, ctx.locator, ast.SourceOffset(test), ctx.Pos()-ast.SourceOffset(test))
 	if needNext {
 		ctx.nextToken()
 	}
@@ -935,6 +1511,9 @@ func (ctx *context) selectorsExpression(test Expression) (expr Expression) {
 }
 
 func (ctx *context) selectorEntry() (expr Expression) {
+	if tracing {
+		defer ctx.trace(`selectorEntry`)()
+	}
 	start := ctx.tokenStartPos
 	lhs := ctx.expression()
 	ctx.assertToken(TOKEN_FARROW)
@@ -943,6 +1522,9 @@ func (ctx *context) selectorEntry() (expr Expression) {
 }
 
 func (ctx *context) caseExpression() Expression {
+	if tracing {
+		defer ctx.trace(`caseExpression`)()
+	}
 	start := ctx.tokenStartPos
 	ctx.nextToken()
 	test := ctx.expression()
@@ -965,6 +1547,9 @@ func (ctx *context) caseOptions() (exprs []Expression) {
 }
 
 func (ctx *context) caseOption() Expression {
+	if tracing {
+		defer ctx.trace(`caseOption`)()
+	}
 	start := ctx.tokenStartPos
 	expressions := ctx.expressions(TOKEN_COLON, ctx.expression)
 	ctx.nextToken()
@@ -998,8 +1583,8 @@ func (ctx *context) resourceExpression(start int, first Expression, form Resourc
 			fqn, ok := first.(*QualifiedName)
 			name := ``
 			if ok {
-				name = fqn.name
-				if _, ok := statementCalls[name]; ok {
+				name = fqn.Name()
+				if isStatementCall(name) {
 					// Handle the call here and set lexer position to where the next expression (the one starting
 					// with a curly brace) starts.
 					args := make([]Expression, 1)
@@ -1024,7 +1609,7 @@ func (ctx *context) resourceExpression(start int, first Expression, form Resourc
 			ops := ctx.attributeOperations()
 			expr = ctx.factory.ResourceOverride(form, first, ops, ctx.locator, start, ctx.Pos()-start)
 		default:
-			ctx.SetPos(first.ByteOffset())
+			ctx.SetPos(ast.SourceOffset(first))
 			panic(ctx.parseIssue(PARSE_INVALID_RESOURCE))
 		}
 	} else {
@@ -1045,7 +1630,7 @@ func (ctx *context) resourceShape(expr Expression) string {
 		return "defaults"
 	}
 	if accessExpr, ok := expr.(*AccessExpression); ok {
-		if qn, ok := accessExpr.operand.(*QualifiedReference); ok && qn.String() == `Resource` && len(accessExpr.keys) == 1 {
+		if qn, ok := accessExpr.Operand().(*QualifiedReference); ok && qn.String() == `Resource` && len(accessExpr.Keys()) == 1 {
 			return "defaults"
 		}
 		return "override"
@@ -1070,12 +1655,12 @@ func (ctx *context) resourceBodies(title Expression) (result []Expression) {
 
 func (ctx *context) resourceBody(title Expression) Expression {
 	if ctx.currentToken != TOKEN_COLON {
-		ctx.SetPos(title.ByteOffset())
+		ctx.SetPos(ast.SourceOffset(title))
 		panic(ctx.parseIssue(PARSE_EXPECTED_TITLE))
 	}
 	ctx.nextToken()
 	ops := ctx.attributeOperations()
-	return ctx.factory.ResourceBody(title, ops, ctx.locator, title.ByteOffset(), ctx.Pos()-title.ByteOffset())
+	return ctx.factory.ResourceBody(title, ops, ctx.locator, ast.SourceOffset(title), ctx.Pos()-ast.SourceOffset(title))
 }
 
 func (ctx *context) attributeOperations() (result []Expression) {
@@ -1194,10 +1779,13 @@ func (ctx *context) collectExpression(lhs Expression) Expression {
 		ctx.assertToken(TOKEN_RC)
 		ctx.nextToken()
 	}
-	return ctx.factory.Collect(lhs, collectQuery, attributeOps, ctx.locator, lhs.ByteOffset(), ctx.Pos()-lhs.ByteOffset())
+	return ctx.factory.Collect(lhs, collectQuery, attributeOps, ctx.locator, ast.SourceOffset(lhs), ctx.Pos()-ast.SourceOffset(lhs))
 }
 
 func (ctx *context) typeAliasOrDefinition() Expression {
+	if tracing {
+		defer ctx.trace(`typeAliasOrDefinition`)()
+	}
 	start := ctx.tokenStartPos
 	typeExpr := ctx.parameterType()
 	fqr, ok := typeExpr.(*QualifiedReference)
@@ -1222,32 +1810,32 @@ func (ctx *context) typeAliasOrDefinition() Expression {
 			if ctx.currentToken == TOKEN_LC {
 				pn := body.(*QualifiedReference)
 				hash := ctx.expression().(*LiteralHash)
-				if pn.name == `Object` || pn.name == `TypeSet` {
+				if pn.Name() == `Object` || pn.Name() == `TypeSet` {
 					body = ctx.factory.Access(pn, []Expression{hash}, ctx.locator, bodyStart, ctx.Pos()-bodyStart)
 				} else {
-					pref := ctx.factory.String(`parent`, ctx.locator, pn.ByteOffset(), pn.ByteLength())
+					pref := ctx.factory.String(`parent`, ctx.locator, ast.SourceOffset(pn), pn.ByteLength())
 					hash := ctx.factory.Hash(
-						append([]Expression{ctx.factory.KeyedEntry(pref, pn, ctx.locator, pn.ByteOffset(), pn.ByteLength())}, hash.entries...),
+						append([]Expression{ctx.factory.KeyedEntry(pref, pn, ctx.locator, ast.SourceOffset(pn), pn.ByteLength())}, hash.Entries()...),
 						ctx.locator, bodyStart, ctx.Pos()-bodyStart)
 					body = ctx.factory.Access(ctx.factory.QualifiedReference(`Object`, ctx.locator, bodyStart, 0), []Expression{hash}, ctx.locator, bodyStart, ctx.Pos()-bodyStart)
 				}
 			}
 		case *LiteralList:
 			lr := body.(*LiteralList)
-			if len(lr.elements) == 1 {
-				body = ctx.factory.Access(ctx.factory.QualifiedReference(`Object`, ctx.locator, bodyStart, 0), lr.elements, ctx.locator, bodyStart, ctx.Pos()-bodyStart)
+			if len(lr.Elements()) == 1 {
+				body = ctx.factory.Access(ctx.factory.QualifiedReference(`Object`, ctx.locator, bodyStart, 0), lr.Elements(), ctx.locator, bodyStart, ctx.Pos()-bodyStart)
 			}
 		case *LiteralHash:
 			body = ctx.factory.Access(ctx.factory.QualifiedReference(`Object`, ctx.locator, bodyStart, 0), []Expression{body}, ctx.locator, bodyStart, ctx.Pos()-bodyStart)
 		}
-		return ctx.addDefinition(ctx.factory.TypeAlias(fqr.name, body, ctx.locator, start, ctx.Pos()-start))
+		return ctx.addDefinition(ctx.factory.TypeAlias(fqr.Name(), body, ctx.locator, start, ctx.Pos()-start))
 	case TOKEN_INHERITS:
 		ctx.nextToken()
 		nameExpr := ctx.typeName()
 		if nameExpr == nil {
 			panic(ctx.parseIssue(PARSE_INHERITS_MUST_BE_TYPE_NAME))
 		}
-		parent = nameExpr.(*QualifiedReference).name
+		parent = nameExpr.(*QualifiedReference).Name()
 		ctx.assertToken(TOKEN_LC)
 		fallthrough
 
@@ -1255,16 +1843,16 @@ func (ctx *context) typeAliasOrDefinition() Expression {
 		ctx.nextToken()
 		body := ctx.parse(TOKEN_RC, false)
 		ctx.nextToken() // consume TOKEN_RC
-		return ctx.addDefinition(ctx.factory.TypeDefinition(fqr.name, parent, body, ctx.locator, start, ctx.Pos()-start))
+		return ctx.addDefinition(ctx.factory.TypeDefinition(fqr.Name(), parent, body, ctx.locator, start, ctx.Pos()-start))
 
 	default:
-		panic(ctx.parseIssue2(LEX_UNEXPECTED_TOKEN, issue.H{`token`: tokenMap[ctx.currentToken]}))
+		panic(ctx.parseIssue2(LEX_UNEXPECTED_TOKEN, issue.H{`token`: token.String(ctx.currentToken)}))
 	}
 }
 
 func (ctx *context) callFunctionExpression(functorExpr Expression) Expression {
 	var args []Expression
-	start := functorExpr.ByteOffset()
+	start := ast.SourceOffset(functorExpr)
 	end := start + functorExpr.ByteLength()
 	if ctx.currentToken != TOKEN_PIPE {
 		ctx.nextToken()
@@ -1275,7 +1863,7 @@ func (ctx *context) callFunctionExpression(functorExpr Expression) Expression {
 	var block Expression
 	if ctx.currentToken == TOKEN_PIPE {
 		block = ctx.lambda()
-		end = block.ByteOffset() + block.ByteLength()
+		end = ast.SourceOffset(block) + block.ByteLength()
 	}
 	if namedAccess, ok := functorExpr.(*NamedAccessExpression); ok {
 		return ctx.convertLhsToCall(namedAccess, args, block, start, end-start)
@@ -1286,7 +1874,7 @@ func (ctx *context) callFunctionExpression(functorExpr Expression) Expression {
 func (ctx *context) activityStyle() ActivityStyle {
 	switch ctx.currentToken {
 	case TOKEN_IDENTIFIER:
-		if style, ok := workflowStyles[ctx.tokenString()]; ok {
+		if style, ok := workflowStyle(ctx.tokenString()); ok {
 			ctx.nextToken()
 			return style
 		}
@@ -1315,7 +1903,7 @@ func (ctx *context) activityProperty() Expression {
 	ctx.nextToken()
 
 	vstart := ctx.Pos()
-	name := key.(*QualifiedName).name
+	name := key.(*QualifiedName).Name()
 	var value Expression
 	switch name {
 	case `input`:
@@ -1360,27 +1948,27 @@ func convertToDeferred(f ExpressionFactory, e Expression) Expression {
 		return nil
 	}
 	l := e.Locator()
-	bo := e.ByteOffset()
+	bo := ast.SourceOffset(e)
 	bl := e.ByteLength()
 	switch e.(type) {
 	case *LiteralList:
-		e = f.Array(convertSliceToDeferred(f, e.(*LiteralList).elements), l, bo, bl)
+		e = f.Array(convertSliceToDeferred(f, e.(*LiteralList).Elements()), l, bo, bl)
 	case *LiteralHash:
-		e = f.Hash(convertSliceToDeferred(f, e.(*LiteralHash).entries), l, bo, bl)
+		e = f.Hash(convertSliceToDeferred(f, e.(*LiteralHash).Entries()), l, bo, bl)
 	case *KeyedEntry:
 		ke := e.(*KeyedEntry)
-		e = f.KeyedEntry(convertToDeferred(f, ke.key), convertToDeferred(f, ke.value), l, bo, bl)
+		e = f.KeyedEntry(convertToDeferred(f, ke.Key()), convertToDeferred(f, ke.Value()), l, bo, bl)
 	case *CallNamedFunctionExpression:
 		cf := e.(*CallNamedFunctionExpression)
-		switch cf.functor.(type) {
+		switch cf.Functor().(type) {
 		case *QualifiedName:
-			n := cf.functor.(*QualifiedName).Name()
+			n := cf.Functor().(*QualifiedName).Name()
 			new := f.QualifiedName(`new`, l, bo, 0)
 			e = f.CallMethod(f.NamedAccess(f.QualifiedReference(`Deferred`, l, bo, 0), new, l, bo, 0),
-				[]Expression{f.String(n, l, e.ByteOffset(), e.ByteLength()), f.Array(convertSliceToDeferred(f, cf.arguments), l, bo, 0)}, nil, l, bo, bl)
+				[]Expression{f.String(n, l, ast.SourceOffset(e), e.ByteLength()), f.Array(convertSliceToDeferred(f, cf.Arguments()), l, bo, 0)}, nil, l, bo, bl)
 		case *QualifiedReference:
 			new := f.QualifiedName(`new`, l, bo, 0)
-			args := append([]Expression{cf.functor}, cf.arguments...)
+			args := append([]Expression{cf.Functor()}, cf.Arguments()...)
 			e = f.CallMethod(f.NamedAccess(f.QualifiedReference(`Deferred`, l, bo, 0), new, l, bo, 0),
 				[]Expression{new, f.Array(convertSliceToDeferred(f, args), l, bo, 0)}, nil, l, bo, bl)
 		}
@@ -1410,6 +1998,9 @@ func (ctx *context) stateAttribute() (op Expression) {
 }
 
 func (ctx *context) activityExpression() Expression {
+	if tracing {
+		defer ctx.trace(`activityExpression`)()
+	}
 	start := ctx.Pos()
 	if ctx.currentToken == TOKEN_FUNCTION {
 		return ctx.functionDefinition()
@@ -1587,9 +2178,9 @@ func (ctx *context) processHashEntries(exprs []Expression) (result []Expression)
 }
 
 func (ctx *context) newHashWithoutBraces(entries []Expression) Expression {
-	start := entries[0].ByteOffset()
+	start := ast.SourceOffset(entries[0])
 	last := entries[len(entries)-1]
-	end := last.ByteOffset() + last.ByteLength()
+	end := ast.SourceOffset(last) + last.ByteLength()
 	return ctx.factory.Hash(entries, ctx.locator, start, end-start)
 }
 
@@ -1598,6 +2189,9 @@ func (ctx *context) arguments() (result []Expression) {
 }
 
 func (ctx *context) functionDefinition() Expression {
+	if tracing {
+		defer ctx.trace(`functionDefinition`)()
+	}
 	start := ctx.tokenStartPos
 	ctx.nextToken()
 	var name string
@@ -1634,6 +2228,9 @@ func (ctx *context) functionDefinition() Expression {
 }
 
 func (ctx *context) planDefinition() Expression {
+	if tracing {
+		defer ctx.trace(`planDefinition`)()
+	}
 	start := ctx.tokenStartPos
 	ctx.nextToken()
 	var name string
@@ -1675,6 +2272,9 @@ func (ctx *context) planDefinition() Expression {
 }
 
 func (ctx *context) nodeDefinition() Expression {
+	if tracing {
+		defer ctx.trace(`nodeDefinition`)()
+	}
 	start := ctx.tokenStartPos
 	ctx.nextToken()
 	hostnames := ctx.hostnames()
@@ -1767,6 +2367,9 @@ func (ctx *context) lambdaParameterList() (result []Expression) {
 }
 
 func (ctx *context) parameter() Expression {
+	if tracing {
+		defer ctx.trace(`parameter`)()
+	}
 	var typeExpr, defaultExpression Expression
 
 	start := ctx.tokenStartPos
@@ -1867,6 +2470,9 @@ func (ctx *context) parameterType() Expression {
 }
 
 func (ctx *context) typeName() Expression {
+	if tracing {
+		defer ctx.trace(`typeName`)()
+	}
 	if ctx.currentToken == TOKEN_TYPE_NAME {
 		name := ctx.factory.QualifiedReference(ctx.tokenString(), ctx.locator, ctx.tokenStartPos, ctx.Pos()-ctx.tokenStartPos)
 		ctx.nextToken()
@@ -1897,7 +2503,7 @@ func (ctx *context) classExpression(start int) Expression {
 	if ctx.currentToken == TOKEN_INHERITS {
 		ctx.nextToken()
 		if ctx.currentToken == TOKEN_DEFAULT {
-			parent = tokenMap[TOKEN_DEFAULT]
+			parent = token.String(TOKEN_DEFAULT)
 			ctx.nextToken()
 		} else {
 			parent = ctx.className()
@@ -1933,8 +2539,8 @@ func (ctx *context) className() (name string) {
 
 func (ctx *context) keyword() (word string, ok bool) {
 	if ctx.currentToken != TOKEN_BOOLEAN {
-		str := tokenMap[ctx.currentToken]
-		if _, ok = keywords[str]; ok {
+		str := token.String(ctx.currentToken)
+		if _, ok = keywordToken(str); ok {
 			word = str
 		}
 	}
@@ -1942,7 +2548,7 @@ func (ctx *context) keyword() (word string, ok bool) {
 }
 
 func (ctx *context) qualifiedName(name string) string {
-	return strings.Join(append(ctx.nameStack, name), `::`)
+	return ResolveRelativeName(strings.Join(ctx.nameStack, `::`), name)
 }
 
 func (ctx *context) capabilityMapping(component Expression, kind string) Expression {
@@ -1960,12 +2566,15 @@ func (ctx *context) capabilityMapping(component Expression, kind string) Express
 		// No action
 	case *ReservedWord:
 		// All reserved words are lowercase only
-		component = ctx.factory.QualifiedName(ctx.qualifiedName(component.(*ReservedWord).Name()), ctx.locator, component.ByteOffset(), component.ByteLength())
+		component = ctx.factory.QualifiedName(ctx.qualifiedName(component.(*ReservedWord).Name()), ctx.locator, ast.SourceOffset(component), component.ByteLength())
 	}
 	return ctx.addDefinition(ctx.factory.CapabilityMapping(kind, component, ctx.qualifiedName(capName), mappings, ctx.locator, start, ctx.Pos()-start))
 }
 
 func (ctx *context) siteDefinition() Expression {
+	if tracing {
+		defer ctx.trace(`siteDefinition`)()
+	}
 	start := ctx.tokenStartPos
 	ctx.nextToken()
 	ctx.assertToken(TOKEN_LC)
@@ -2002,7 +2611,13 @@ func (ctx *context) resourceDefinition(resourceToken int) Expression {
 	return ctx.addDefinition(def)
 }
 
+// addDefinition adds the given definition to the definitions of the program. The range of the definition
+// ends with its last token, i.e. the token that the parser has looked at after the definition and the
+// whitespace and comments that precede that token are not included.
 func (ctx *context) addDefinition(expr Expression) Expression {
+	if start := ast.SourceOffset(expr); ctx.previousTokenEnd > start {
+		ast.UpdateOffsetAndLength(expr, start, ctx.previousTokenEnd-start)
+	}
 	ctx.definitions = append(ctx.definitions, expr.(Definition))
 	return expr
 }
@@ -2,8 +2,15 @@ package parser
 
 import (
 	"bytes"
+	"fmt"
 	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-parser/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"unsafe"
 )
 
 func TestEmpty(t *testing.T) {
@@ -230,6 +237,44 @@ func TestHeredocSyntax(t *testing.T) {
 		`more than one syntax declaration in heredoc (line: 1, column: 11)`)
 }
 
+func TestHeredocEppTemplate(t *testing.T) {
+	source := issue.Unindent(`
+    $x = inline_epp(@(END:epp))
+        <%- | $a | -%>
+      Hello <%= $a %>
+        | END
+    $y = 1`)
+	heredoc := parse(t, source).(*BlockExpression).Statements()[0].(*AssignmentExpression).Rhs().(*CallNamedFunctionExpression).Arguments()[0].(*HeredocExpression)
+	template, ok := heredoc.Template().(*LambdaExpression)
+	if !ok {
+		t.Fatalf("expected a template, got %v", heredoc.Template())
+	}
+	if len(template.Parameters()) != 1 {
+		t.Errorf("expected one template parameter")
+	}
+
+	// The second line of the text has less indentation than the margin so it isn't stripped at all
+	var variable Expression
+	template.AllContents(nil, func(path []Expression, e Expression) {
+		if _, ok := e.(*VariableExpression); ok {
+			variable = e
+		}
+	})
+	if variable.Line() != 3 || variable.Pos() != 13 || source[variable.ByteOffset():variable.ByteOffset()+2] != `$a` {
+		t.Errorf("expected $a at line 3, column 13, got line %d, column %d", variable.Line(), variable.Pos())
+	}
+	param := template.Parameters()[0]
+	if param.Line() != 2 || param.Pos() != 11 || source[param.ByteOffset():param.ByteOffset()+2] != `$a` {
+		t.Errorf("expected parameter $a at line 2, column 11, got line %d, column %d", param.Line(), param.Pos())
+	}
+
+	for _, src := range []string{"@(END:epp)\n<%= $a ) %>\nEND", "@(END:json)\n{}\nEND", "@(\"END\":epp)\n<%= ${a} %>\nEND"} {
+		if heredoc, ok := parse(t, "$x = "+src).(*BlockExpression).Statements()[0].(*AssignmentExpression).Rhs().(*HeredocExpression); !ok || heredoc.Template() != nil {
+			t.Errorf("expected no template for %s", src)
+		}
+	}
+}
+
 func TestHeredocFlags(t *testing.T) {
 	expectHeredoc(t,
 		issue.Unindent(`
@@ -528,6 +573,14 @@ func TestVariable(t *testing.T) {
 		`$::var::_b`,
 		`(var "::var::_b")`)
 
+	expectDump(t,
+		`$_var`,
+		`(var "_var")`)
+
+	expectDump(t,
+		`$_`,
+		`(var "_")`)
+
 	expectDump(t,
 		`$2`,
 		`(var 2)`)
@@ -556,6 +609,14 @@ func TestVariable(t *testing.T) {
 		`$::var::_b::c`,
 		`invalid variable name (line: 1, column: 1)`)
 
+	expectError(t,
+		`$_var::b`,
+		`invalid variable name (line: 1, column: 1)`)
+
+	expectError(t,
+		`$::_var`,
+		`unexpected token '_' (line: 1, column: 4)`)
+
 	expectError(t,
 		`$::_var::b`,
 		`unexpected token '_' (line: 1, column: 4)`)
@@ -1244,6 +1305,18 @@ func TestCallMethodNoArgs(t *testing.T) {
 		`(concat (str (call-method {:functor (. (call-method {:functor (. (access (var "x") 3) (qn "y")) :args []}) (qn "z")) :args []})))`)
 }
 
+func TestInterpolatedNestedAccess(t *testing.T) {
+	expectDump(t,
+		issue.Unindent(`
+      "${facts['os']['family']}"`),
+		`(concat (str (access (access (var "facts") "os") "family")))`)
+
+	expectDump(t,
+		issue.Unindent(`
+      "${x[1][2].y}"`),
+		`(concat (str (call-method {:functor (. (access (access (var "x") 1) 2) (qn "y")) :args []})))`)
+}
+
 func TestCallMethodNoArgsLambda(t *testing.T) {
 	expectDump(t,
 		issue.Unindent(`
@@ -2032,7 +2105,7 @@ func TestEPP(t *testing.T) {
 	expectError(t,
 		issue.Unindent(`
       <% $x = 3 %> text`),
-		`unexpected token '<' (line: 1, column: 1)`)
+		`Syntax error at '<%', which requires the 'epp' feature. The feature is not enabled (line: 1, column: 1)`)
 
 	expectError(t,
 		issue.Unindent(`
@@ -2049,6 +2122,19 @@ func TestEPP(t *testing.T) {
 		`Ambiguous EPP parameter expression. Probably missing '<%-' before parameters to remove leading whitespace (line: 2, column: 5)`)
 }
 
+func TestEPPParametersSpecified(t *testing.T) {
+	for source, specified := range map[string]bool{
+		`text <%= $x %>`:              false,
+		`<%||%> text`:                 true,
+		`<%- | $x, $y = 1 | -%> text`: true,
+	} {
+		lambda := parse(t, source, PARSER_EPP_MODE).(*LambdaExpression)
+		if lambda.Body().(*EppExpression).ParametersSpecified() != specified {
+			t.Errorf("expected ParametersSpecified of '%s' to be %t", source, specified)
+		}
+	}
+}
+
 func expectDumpEPP(t *testing.T, source string, expected string) {
 	expectDump(t, source, expected, PARSER_EPP_MODE)
 }
@@ -2069,7 +2155,7 @@ func expectDump(t *testing.T, source string, expected string, parserOptions ...O
 func expectBlock(t *testing.T, source string, expected string, parserOptions ...Option) {
 	expr, err := CreateParser(parserOptions...).Parse(``, source, false)
 	if err != nil {
-		t.Errorf(err.Error())
+		t.Error(err.Error())
 	} else {
 		actual := dump(expr)
 		if expected != actual {
@@ -2101,12 +2187,12 @@ func expectHeredoc(t *testing.T, str string, args ...interface{}) {
 		return
 	}
 	if heredoc, ok := expr.(*HeredocExpression); ok {
-		if len(args) > 1 && heredoc.syntax != args[1] {
-			t.Errorf("Expected syntax '%s', got '%s'", args[1], heredoc.syntax)
+		if len(args) > 1 && heredoc.Syntax() != args[1] {
+			t.Errorf("Expected syntax '%s', got '%s'", args[1], heredoc.Syntax())
 		}
-		if textExpr, ok := heredoc.text.(*LiteralString); ok {
-			if textExpr.value != expected {
-				t.Errorf("Expected heredoc '%s', got '%s'", expected, textExpr.value)
+		if textExpr, ok := heredoc.Text().(*LiteralString); ok {
+			if textExpr.StringValue() != expected {
+				t.Errorf("Expected heredoc '%s', got '%s'", expected, textExpr.StringValue())
 			}
 			return
 		}
@@ -2122,7 +2208,7 @@ func expectHeredoc(t *testing.T, str string, args ...interface{}) {
 func parse(t *testing.T, str string, parserOptions ...Option) Expression {
 	expr, err := CreateParser(parserOptions...).Parse(``, str, false)
 	if err != nil {
-		t.Errorf(err.Error())
+		t.Error(err.Error())
 		return nil
 	}
 	program, ok := expr.(*Program)
@@ -2130,17 +2216,1162 @@ func parse(t *testing.T, str string, parserOptions ...Option) Expression {
 		t.Errorf("'%s' did not parse to a program", str)
 		return nil
 	}
-	return program.body
+	return program.Body()
 }
 
 func parseExpression(t *testing.T, str string, parserOptions ...Option) Expression {
 	expr := parse(t, str, parserOptions...)
 	if block, ok := expr.(*BlockExpression); ok {
-		if len(block.statements) == 1 {
-			return block.statements[0]
+		if len(block.Statements()) == 1 {
+			return block.Statements()[0]
 		}
 		t.Errorf("'%s' did not parse to a block with exactly one expression", str)
 		return nil
 	}
 	return expr
 }
+
+func TestComposePrograms(t *testing.T) {
+	p := CreateParser()
+	site, err := p.Parse(`site.pp`, "node default {\n  include foo\n}", false)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	foo, err := p.Parse(`foo.pp`, "$x = 1\nclass foo {\n}", false)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	bar, err := p.Parse(`bar.pp`, "function bar() {}", false)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	program := ComposePrograms(ComposePrograms(site.(*Program), foo.(*Program)), bar.(*Program))
+	if len(program.Programs()) != 3 {
+		t.Fatalf("expected 3 programs, got %d", len(program.Programs()))
+	}
+	if len(program.Definitions()) != 3 {
+		t.Errorf("expected 3 definitions, got %d", len(program.Definitions()))
+	}
+	if fp, ok := program.Program(`foo.pp`); !ok || fp != foo {
+		t.Errorf("expected to find program for foo.pp")
+	}
+
+	expected := `(block (block (node {:matches [(default)] :body [(invoke {:functor (qn "include") :args [(qn "foo")]})]})) (block (= (var "x") 1) (class {:name "foo" :body []})) (block (function {:name "bar" :body []})))`
+	if actual := program.ToPN().String(); expected != actual {
+		t.Errorf("expected '%s', got '%s'", expected, actual)
+	}
+
+	program.AllContents(nil, func(path []Expression, e Expression) {
+		if hc, ok := e.(*HostClassDefinition); ok && (hc.File() != `foo.pp` || hc.Line() != 2) {
+			t.Errorf("expected class to be at foo.pp:2, got %s:%d", hc.File(), hc.Line())
+		}
+	})
+}
+
+func TestProgramDefinitionAccessors(t *testing.T) {
+	expr, err := CreateParser(PARSER_TASKS_ENABLED).Parse(`t.pp`, issue.Unindent(`
+    class a {
+      class b {}
+    }
+    define a::d() {}
+    function a::f() {}
+    plan a::p() {}
+    type A::T = String
+    node default {}`), false)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	program := expr.(*Program)
+	if len(program.Classes()) != 2 || program.Classes()[0].Name() != `a::b` {
+		t.Errorf("unexpected classes")
+	}
+	if len(program.DefinedTypes()) != 1 || len(program.Functions()) != 1 || len(program.Plans()) != 1 || len(program.TypeAliases()) != 1 || len(program.Nodes()) != 1 {
+		t.Errorf("unexpected definitions")
+	}
+	if d, ok := program.DefinitionByName(`::a::t`); !ok || d != program.TypeAliases()[0] {
+		t.Errorf("expected to find type alias by name")
+	}
+	if d, ok := program.DefinitionByName(`a::p`); !ok || d != program.Plans()[0] {
+		t.Errorf("expected to find plan by name")
+	}
+	if _, ok := program.DefinitionByName(`a::x`); ok {
+		t.Errorf("expected not to find a::x")
+	}
+}
+
+func TestProgramSourceOf(t *testing.T) {
+	p := CreateParser()
+	source := "$x = 1\n# the class\nclass a(\n  $p = 1, # comment\n) {\n  notice($p)\n}\n\ndefine b() {}\n"
+	expr, err := p.Parse(`a.pp`, source, false)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	other, err := p.Parse(`c.pp`, "class c {\n  class d {} # comment\n}", false)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	program := ComposePrograms(other.(*Program), expr.(*Program))
+
+	text, r := program.SourceOf(program.Classes()[2])
+	if text != "class a(\n  $p = 1, # comment\n) {\n  notice($p)\n}" {
+		t.Errorf("unexpected source '%s'", text)
+	}
+	if expected := (Range{Offset: 19, Length: len(text), Line: 3, Column: 1, EndLine: 7, EndColumn: 2}); r != expected {
+		t.Errorf("expected %v, got %v", expected, r)
+	}
+	if source[r.Offset:r.Offset+r.Length] != text {
+		t.Errorf("range does not match the text")
+	}
+
+	if text, r = program.SourceOf(program.DefinedTypes()[0]); text != `define b() {}` || r.Line != 9 || r.EndColumn != 14 {
+		t.Errorf("unexpected source '%s' at %v", text, r)
+	}
+	if text, _ = program.SourceOf(program.Classes()[0]); text != `class d {}` {
+		t.Errorf("unexpected source '%s'", text)
+	}
+	if text, _ = program.SourceOf(program.Classes()[1]); text != "class c {\n  class d {} # comment\n}" {
+		t.Errorf("unexpected source '%s'", text)
+	}
+	if text, r = other.(*Program).SourceOf(program.Classes()[2]); text != `` || r != (Range{}) {
+		t.Errorf("expected no source for a definition from another program")
+	}
+}
+
+func TestParseSnippet(t *testing.T) {
+	// Snippet starts at line 3, column 5, offset 40 of the host document
+	expr, err := CreateParser().ParseSnippet(`host.md`, "$x = 1\nnotice($x)", 3, 5, 40, false)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expr.AllContents(nil, func(path []Expression, e Expression) {
+		switch e := e.(type) {
+		case *AssignmentExpression:
+			if e.Line() != 3 || e.Pos() != 5 || e.ByteOffset() != 40 {
+				t.Errorf("expected assignment at 3:5 offset 40, got %d:%d offset %d", e.Line(), e.Pos(), e.ByteOffset())
+			}
+		case *CallNamedFunctionExpression:
+			if e.Line() != 4 || e.Pos() != 1 || e.ByteOffset() != 47 {
+				t.Errorf("expected call at 4:1 offset 47, got %d:%d offset %d", e.Line(), e.Pos(), e.ByteOffset())
+			}
+			if e.String() != `notice($x)` {
+				t.Errorf("expected call source 'notice($x)', got '%s'", e.String())
+			}
+		}
+	})
+
+	_, err = CreateParser().ParseSnippet(`host.md`, "$x = 1\n$y = ", 3, 5, 40, false)
+	if err == nil {
+		t.Fatal(`expected parse error`)
+	}
+	if ri, ok := err.(issue.Reported); !ok || ri.Location().Line() != 4 || ri.Location().File() != `host.md` {
+		t.Errorf("expected error on line 4 in host.md, got %s", err.Error())
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	expectNormalized(t,
+		`file { '/a': owner => root, mode => '0644', * => $h, group => root }`,
+		`file { '/a': * => $h, group => root, mode => '0644', owner => root }`,
+		NORMALIZE_SORT_ATTRIBUTES)
+
+	expectNormalized(t,
+		`notice 'x' $x.each |$v| { notice $v } $y = $x.map |$v| { $v }`,
+		`notice('x') each($x) |$v| { notice($v) } $y = map($x) |$v| { $v }`,
+		NORMALIZE_EXPAND_CALLS)
+
+	expectNormalized(t,
+		`$y = $x ? { default => 'a' } $z = $x ? { 1 => 'a' }`,
+		`$y = 'a' $z = $x ? { 1 => 'a' }`,
+		NORMALIZE_SELECTORS)
+
+	expectNormalized(t,
+		`$y = (1 + 2) * ($x)`,
+		`$y = (1 + 2) * $x`,
+		NORMALIZE_STRIP_PARENTHESES)
+
+	expectDump(t, `$y = (1 + 2) * ($x)`, `(= (var "y") (* (paren (+ 1 2)) (paren (var "x"))))`)
+
+	source := `class a { $y = ($x) } function f() { 1 }`
+	program, err := CreateParser().Parse(``, source, false)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	normalized := Normalize(program, NORMALIZE_STRIP_PARENTHESES).(*Program)
+	if dump(program) != `(block (class {:name "a" :body [(= (var "y") (paren (var "x")))]}) (function {:name "f" :body [1]}))` {
+		t.Errorf("the normalized program was modified: %s", dump(program))
+	}
+	defs := normalized.Definitions()
+	if len(defs) != 2 || defs[0] == program.(*Program).Definitions()[0] || defs[1] != program.(*Program).Definitions()[1] {
+		t.Errorf(`expected definitions of normalized program to reflect the transformed definitions`)
+	}
+	if defs[0].Line() != 1 || defs[0].ByteLength() == 0 {
+		t.Errorf(`expected transformed definition to retain its position`)
+	}
+}
+
+// expectNormalized checks that the given source has the same PN as the expected source when both
+// are normalized using the given options. Parentheses are stripped from the expected source only.
+func expectNormalized(t *testing.T, source, expected string, options ...NormalizeOption) {
+	t.Helper()
+	actual := dump(Normalize(parse(t, source), options...))
+	if e := dump(Normalize(parse(t, expected), append(options, NORMALIZE_STRIP_PARENTHESES)...)); actual != e {
+		t.Errorf("expected '%s', got '%s'", e, actual)
+	}
+}
+
+func TestParseAttributeOperations(t *testing.T) {
+	ops, err := CreateParser().ParseAttributeOperations(`attrs.yaml`, `mode => '0644', owner => root, * => $h, require +> File['a'],`)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	actual := make([]string, len(ops))
+	for i, op := range ops {
+		actual[i] = dump(op)
+	}
+	expected := `(=> "mode" "0644") (=> "owner" (qn "root")) (splat-hash (var "h")) (+> "require" (access (qr "File") "a"))`
+	if strings.Join(actual, ` `) != expected {
+		t.Errorf("expected '%s', got '%s'", expected, strings.Join(actual, ` `))
+	}
+	if ops[1].Line() != 1 || ops[1].Pos() != 17 || ops[1].File() != `attrs.yaml` {
+		t.Errorf("expected owner at attrs.yaml:1:17, got %s:%d:%d", ops[1].File(), ops[1].Line(), ops[1].Pos())
+	}
+
+	ops, err = CreateParser().ParseAttributeOperations(``, ``)
+	if err != nil || len(ops) != 0 {
+		t.Errorf(`expected empty source to parse into no operations`)
+	}
+
+	ops, err = CreateParser().ParseAttributeOperations(``, `mode => '0644' owner => root`)
+	if err == nil || err.Error() != `expected token 'EOF', got 'identifier' (line: 1, column: 16)` {
+		t.Errorf("unexpected error %v", err)
+	}
+	if ops != nil {
+		t.Errorf(`expected no operations together with the error`)
+	}
+
+	_, err = CreateParser().ParseAttributeOperations(``, `'mode' => '0644'`)
+	if err == nil || !strings.HasPrefix(err.Error(), `expected attribute name`) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestParseParameterList(t *testing.T) {
+	params, err := CreateParser().ParseParameterList(`task.json`, `Integer[1] $port = 80, String $host, *$rest,`)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	actual := make([]string, len(params))
+	for i, param := range params {
+		actual[i] = dump(param)
+	}
+	expected := `(param {:name "port" :type (access (qr "Integer") 1) :value 80}) (param {:name "host" :type (qr "String")}) (param {:name "rest" :splat true})`
+	if strings.Join(actual, ` `) != expected {
+		t.Errorf("expected '%s', got '%s'", expected, strings.Join(actual, ` `))
+	}
+
+	params, err = CreateParser().ParseParameterList(``, ``)
+	if err != nil || len(params) != 0 {
+		t.Errorf(`expected empty source to parse into no parameters`)
+	}
+
+	_, err = CreateParser().ParseParameterList(``, `String $a $b`)
+	if err == nil || err.Error() != `expected one of ',' or 'EOF', got 'variable' (line: 1, column: 11)` {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	_, err = CreateParser().ParseParameterList(``, `String a`)
+	if err == nil || !strings.HasPrefix(err.Error(), `expected variable`) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestParseExpression(t *testing.T) {
+	p := CreateParser(PARSER_EPP_MODE)
+	for source, expected := range map[string]string{
+		`$port + 1`:              `(+ (var "port") 1)`,
+		` { owner => root } `:    `(hash (=> (qn "owner") (qn "root")))`,
+		`default`:                `(default)`,
+		`"${a}-b"`:               `(concat (str (var "a")) "-b")`,
+		`File['a'] -> File['b']`: `(-> (access (qr "File") "a") (access (qr "File") "b"))`,
+	} {
+		expr, err := p.ParseExpression(`config.yaml`, source)
+		if err != nil {
+			t.Errorf("%s: %s", source, err.Error())
+			continue
+		}
+		if actual := dump(expr); actual != expected {
+			t.Errorf("%s: expected '%s', got '%s'", source, expected, actual)
+		}
+	}
+
+	expr, _ := p.ParseExpression(`config.yaml`, ` $x`)
+	if expr.Pos() != 2 || expr.File() != `config.yaml` {
+		t.Errorf("expected expression at config.yaml:1:2, got %s:%d:%d", expr.File(), expr.Line(), expr.Pos())
+	}
+
+	for source, expected := range map[string]string{
+		``:                    `expected an expression (line: 1, column: 1)`,
+		`  `:                  `expected an expression (line: 1, column: 3)`,
+		`1, 2`:                `unexpected ',' after the end of the expression (line: 1, column: 2)`,
+		`$x = 1 2`:            `unexpected 'integer literal' after the end of the expression (line: 1, column: 8)`,
+		`If $x { notice(1) }`: `Syntax error after 'If', which is a type name and not a keyword. Did you mean 'if'? (line: 1, column: 1)`,
+	} {
+		expr, err := p.ParseExpression(``, source)
+		if err == nil || err.Error() != expected {
+			t.Errorf("%q: expected error '%s', got %v", source, expected, err)
+		}
+		if expr != nil {
+			t.Errorf("%q: expected no expression together with the error, got %s", source, dump(expr))
+		}
+	}
+}
+
+func TestParseExpressionList(t *testing.T) {
+	exprs, err := CreateParser().ParseExpressionList(`config.yaml`, `1, 'two', $three, default,`)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	actual := make([]string, len(exprs))
+	for i, expr := range exprs {
+		actual[i] = dump(expr)
+	}
+	expected := `1 "two" (var "three") (default)`
+	if strings.Join(actual, ` `) != expected {
+		t.Errorf("expected '%s', got '%s'", expected, strings.Join(actual, ` `))
+	}
+
+	exprs, err = CreateParser().ParseExpressionList(``, ``)
+	if err != nil || len(exprs) != 0 {
+		t.Errorf(`expected empty source to parse into no expressions`)
+	}
+
+	_, err = CreateParser().ParseExpressionList(``, `1 2`)
+	if err == nil || err.Error() != `expected one of ',' or 'EOF', got 'integer literal' (line: 1, column: 3)` {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestParseHeredoc(t *testing.T) {
+	text, err := CreateParser().ParseHeredoc(`t.pp`, &HeredocSpec{Tag: `END`, Syntax: `json`}, "{\n  \"a\": 1\n}\n")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if actual := dump(text); actual != `"{\n  \"a\": 1\n}\n"` {
+		t.Errorf("unexpected text %s", actual)
+	}
+	if text.Line() != 1 || text.Pos() != 1 || text.ByteOffset() != 0 {
+		t.Errorf("unexpected position %d:%d offset %d", text.Line(), text.Pos(), text.ByteOffset())
+	}
+
+	text, err = CreateParser().ParseHeredoc(`t.pp`, &HeredocSpec{Tag: `END`, Escapes: `t`, Interpolate: true, TrimNewline: true}, "a\tb\n${x}")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if actual := dump(text); actual != `(concat "a\tb\n" (str (var "x")))` {
+		t.Errorf("unexpected text %s", actual)
+	}
+
+	text, err = CreateParser().ParseHeredoc(``, &HeredocSpec{Tag: `END`}, ``)
+	if err != nil || dump(text) != `""` {
+		t.Errorf("expected empty body to parse into an empty string")
+	}
+
+	_, err = CreateParser().ParseHeredoc(``, &HeredocSpec{Tag: `END`, Interpolate: true}, "a\nb ${x y}\n")
+	if err == nil || !strings.HasSuffix(err.Error(), `(line: 2, column: 7)`) {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	_, err = CreateParser().ParseHeredoc(``, &HeredocSpec{Tag: `END`}, "a\nEND\nb\n")
+	if err == nil || !strings.HasSuffix(err.Error(), `(line: 3, column: 1)`) {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	_, err = CreateParser().ParseHeredoc(``, &HeredocSpec{Tag: `END`, Escapes: `x`}, "a\n")
+	if ri, ok := err.(issue.Reported); !ok || ri.Code() != LEX_HEREDOC_ILLEGAL_ESCAPE || ri.Location().Line() != 0 {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestExtractEpp(t *testing.T) {
+	source := issue.Unindent(`
+    <%- | String $x | -%>
+    Hello <%= $x %>!
+    <%# a comment %>
+    <% if $y { -%>
+      <%= "b%>${z}" -%>  <%%
+    <% } %>`)
+	segments, err := ExtractEpp(`t.epp`, source)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expected := []struct {
+		kind EppSegmentKind
+		text string
+	}{
+		{EPP_CODE, `| String $x |`},
+		{EPP_TEXT, `Hello `},
+		{EPP_EXPRESSION, `$x`},
+		{EPP_TEXT, "!\n\n"},
+		{EPP_CODE, `if $y {`},
+		{EPP_TEXT, `  `},
+		{EPP_EXPRESSION, `"b%>${z}"`},
+		{EPP_TEXT, "<%\n"},
+		{EPP_CODE, `}`},
+	}
+	if len(segments) != len(expected) {
+		t.Fatalf("expected %d segments, got %d", len(expected), len(segments))
+	}
+	for i, s := range segments {
+		if s.Kind != expected[i].kind || s.Text != expected[i].text {
+			t.Errorf("unexpected segment %d: %d %q", i, s.Kind, s.Text)
+		}
+		if s.Kind != EPP_TEXT && source[s.Offset:s.Offset+s.Length] != s.Text {
+			t.Errorf("unexpected range of segment %d: %q", i, source[s.Offset:s.Offset+s.Length])
+		}
+	}
+	if s := segments[3]; source[s.Offset:s.Offset+s.Length] != "!\n<%# a comment %>\n" {
+		t.Errorf("unexpected range of text segment: %q", source[s.Offset:s.Offset+s.Length])
+	}
+
+	segments, err = ExtractEpp(`t.epp`, `a <% %><%= $x`)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(segments) != 3 || segments[1].Length != 0 || segments[1].Offset != 5 || segments[2].Text != `$x` {
+		t.Errorf("unexpected segments of unterminated tag")
+	}
+
+	_, err = ExtractEpp(`t.epp`, "a\n<%# b")
+	if ri, ok := err.(issue.Reported); !ok || ri.Code() != LEX_UNBALANCED_EPP_COMMENT {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestEPPTextIsNotCopied(t *testing.T) {
+	source := strings.Repeat("some text\n", 1000) + `<%= $x %>` + strings.Repeat("more text\n", 1000)
+	expr, err := CreateParser(PARSER_EPP_MODE).Parse(`t.epp`, source, false)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	texts := make([]string, 0)
+	expr.AllContents(nil, func(path []Expression, e Expression) {
+		if rs, ok := e.(*RenderStringExpression); ok {
+			texts = append(texts, rs.StringValue())
+		}
+	})
+	if len(texts) != 2 {
+		t.Fatalf("expected 2 texts, got %d", len(texts))
+	}
+	if unsafe.StringData(texts[0]) != unsafe.StringData(source) || unsafe.StringData(texts[1]) != unsafe.StringData(source[len(source)-len(texts[1]):]) {
+		t.Errorf("expected text to be a substring of the source")
+	}
+
+	segments, err := ExtractEpp(`t.epp`, "a <%% b %%> c<%# d %> e")
+	if err != nil || len(segments) != 1 || segments[0].Text != `a <% b %> c e` {
+		t.Errorf("unexpected segments of escaped text")
+	}
+
+	_, err = ExtractEpp(`t.epp`, "a <%= $x %> b\xa0")
+	if err == nil || err.Error() != `invalid unicode character at offset 13` {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestEppSourceMap(t *testing.T) {
+	expr, err := CreateParser(PARSER_EPP_MODE).Parse(`t.epp`, issue.Unindent(`
+    <%- | $x | -%>
+    Hello <%= $x %>!
+    <% if $x { -%>
+      <%= $x.upcase %>
+    <% } -%>`), false)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expected := []string{
+		`"Hello " 2:1-2:7`,
+		`"<%= $x %>" 2:7-2:16`,
+		`"!\n" 2:16-3:1`,
+		`"  " 4:1-4:3`,
+		`"<%= $x.upcase %>" 4:3-4:19`,
+		`"\n" 4:19-5:1`,
+	}
+	ranges := EppSourceMap(expr)
+	if len(ranges) != len(expected) {
+		t.Fatalf("expected %d ranges, got %d", len(expected), len(ranges))
+	}
+	for i, r := range ranges {
+		actual := fmt.Sprintf(`%q %d:%d-%d:%d`, r.Expression.String(), r.Line, r.Column, r.EndLine, r.EndColumn)
+		if actual != expected[i] {
+			t.Errorf("expected range %s, got %s", expected[i], actual)
+		}
+	}
+}
+
+func TestResolveNames(t *testing.T) {
+	if n := ResolveRelativeName(`a::b`, `c`); n != `a::b::c` {
+		t.Errorf("unexpected name %s", n)
+	}
+	if n := ResolveRelativeName(``, `::c`); n != `c` {
+		t.Errorf("unexpected name %s", n)
+	}
+	if n := ResolveReference(`::Foo::Bar`); n != `foo::bar` {
+		t.Errorf("unexpected reference %s", n)
+	}
+	if !IsAbsoluteName(`::foo`) || IsAbsoluteName(`foo::bar`) {
+		t.Errorf("unexpected classification")
+	}
+}
+
+func TestSyntheticLocator(t *testing.T) {
+	locator := NewSyntheticLocator(`template 'motd.epp'`, nil)
+	e := DefaultFactory().String(`hello`, locator, 0, 0)
+	if !locator.IsSynthetic() || locator.Origin() != nil {
+		t.Errorf("expected synthetic locator without origin")
+	}
+	if loc := issue.LocationString(e); loc != `(file: generated from template 'motd.epp')` {
+		t.Errorf("unexpected location %s", loc)
+	}
+
+	origin, _ := First[*CallNamedFunctionExpression](parse(t, "\n  notice('x')"))
+	locator = NewSyntheticLocator(`template 'motd.epp'`, origin)
+	e = DefaultFactory().String(`hello`, locator, 0, 0)
+	if e.File() != `` || e.Line() != 2 || e.Pos() != 3 || e.ByteOffset() != 3 {
+		t.Errorf("expected position of origin, got %d:%d offset %d", e.Line(), e.Pos(), e.ByteOffset())
+	}
+	if locator.Provenance() != `generated from template 'motd.epp'` {
+		t.Errorf("unexpected provenance %s", locator.Provenance())
+	}
+	if NewLocator(`a.pp`, ``).IsSynthetic() {
+		t.Errorf("expected locator that isn't synthetic")
+	}
+}
+
+func TestFind(t *testing.T) {
+	program := parse(t, `class a { file { '/a': } } class b { package { 'b': } service { 'b': } } function c() {}`)
+
+	resources := FindAll[*ResourceExpression](program)
+	if len(resources) != 3 || dump(resources[2].TypeName()) != `(qn "service")` {
+		t.Errorf("expected three resources, got %d", len(resources))
+	}
+	if definitions := FindAll[Definition](program); len(definitions) != 3 {
+		t.Errorf("expected three definitions, got %d", len(definitions))
+	}
+	if calls := FindAll[*CallMethodExpression](program); len(calls) != 0 {
+		t.Errorf("expected no method calls, got %d", len(calls))
+	}
+
+	if class, ok := First[*HostClassDefinition](program); !ok || class.Name() != `a` {
+		t.Errorf(`expected first class to be 'a'`)
+	}
+	if block, ok := First[*BlockExpression](program); !ok || block != program {
+		t.Errorf(`expected the root to be found first`)
+	}
+	if _, ok := First[*NodeDefinition](program); ok {
+		t.Errorf(`expected no node definition to be found`)
+	}
+}
+
+type resourceCounter struct {
+	DefaultVisitor
+	resources []string
+}
+
+func (v *resourceCounter) VisitResourceBody(e *ResourceBody) {
+	v.resources = append(v.resources, dump(e.Title()))
+}
+
+func TestVisitor(t *testing.T) {
+	others := make(map[string]int)
+	v := &resourceCounter{DefaultVisitor: DefaultVisitor{Fallback: func(e Expression) { others[fmt.Sprintf(`%T`, e)]++ }}}
+	Walk(v, parse(t, `file { '/a': } if $x { package { 'b': ensure => present } }`))
+	if strings.Join(v.resources, ` `) != `"/a" "b"` {
+		t.Errorf("expected resources \"/a\" \"b\", got %s", strings.Join(v.resources, ` `))
+	}
+	if others[`*ast.ResourceBody`] != 0 || others[`*ast.ResourceExpression`] != 2 || others[`*ast.IfExpression`] != 1 {
+		t.Errorf("unexpected fallback calls %v", others)
+	}
+}
+
+func TestLexerPeekAndRewind(t *testing.T) {
+	l := NewSimpleLexer(``, `$x = foo(1, 'a')`)
+	if l.NextToken() != TOKEN_VARIABLE {
+		t.Fatal(`expected variable`)
+	}
+	if l.PeekToken(0) != TOKEN_VARIABLE || l.PeekToken(1) != TOKEN_ASSIGN || l.PeekToken(3) != TOKEN_LP || l.PeekToken(20) != TOKEN_END {
+		t.Error(`unexpected peeked token`)
+	}
+	if l.CurrentToken() != TOKEN_VARIABLE || l.TokenString() != `x` {
+		t.Error(`expected peek to retain the current token`)
+	}
+
+	l.NextToken()
+	mark := l.Mark()
+	l.NextToken()
+	l.NextToken()
+	l.NextToken()
+	if l.CurrentToken() != TOKEN_INTEGER {
+		t.Fatal(`expected integer`)
+	}
+	l.Rewind(mark)
+	if l.CurrentToken() != TOKEN_ASSIGN || l.TokenStartPos() != 3 {
+		t.Error(`expected rewind to restore the current token`)
+	}
+	if l.NextToken() != TOKEN_IDENTIFIER || l.TokenString() != `foo` {
+		t.Error(`expected rewind to restore the position`)
+	}
+}
+
+func TestLexerPeekAndRewindWhileRecording(t *testing.T) {
+	l := NewSimpleLexer(``, `$x = foo(1, 'a')`).(*lexer)
+	l.tokens = &TokenRecording{tokens: make(map[tokenKey]*recordedToken)}
+	l.recordTokens = true
+	l.NextToken()
+	if l.PeekToken(3) != TOKEN_LP || l.tokens.Len() != 1 {
+		t.Errorf("expected the peeked tokens to be removed from the recording, got %d tokens", l.tokens.Len())
+	}
+
+	mark := l.Mark()
+	l.NextToken()
+	l.NextToken()
+	l.Rewind(mark)
+	if l.tokens.Len() != 1 || len(l.tokens.keys) != 1 {
+		t.Errorf("expected the tokens after the mark to be removed from the recording, got %d tokens", l.tokens.Len())
+	}
+	for l.NextToken() != TOKEN_END {
+	}
+	if l.tokens.Len() != 9 {
+		t.Errorf("expected all tokens to be recorded, got %d tokens", l.tokens.Len())
+	}
+}
+
+func TestLexerPeekWithErrorRecovery(t *testing.T) {
+	l := NewLexer(``, `$a = ¤ 3`, LEXER_ERROR_RECOVERY)
+	l.NextToken()
+	if l.PeekToken(2) != TOKEN_ERROR || l.PeekToken(3) != TOKEN_INTEGER {
+		t.Error(`expected peek to produce an error token`)
+	}
+	if l.CurrentToken() != TOKEN_VARIABLE || l.TokenString() != `a` {
+		t.Error(`expected peek to retain the current token`)
+	}
+	l.NextToken()
+	if l.NextToken() != TOKEN_ERROR || l.TokenString() != `¤` {
+		t.Errorf("expected error token, got '%s'", l.TokenString())
+	}
+}
+
+func TestLexerRewindRestoresLookalike(t *testing.T) {
+	l := NewLexer(``, `$a = 1 Node`).(*lexer)
+	l.lookalikeStart = -1
+	l.NextToken()
+	mark := l.Mark()
+	for l.NextToken() != TOKEN_END {
+	}
+	if l.lookalikeName != `Node` || l.tokenEnd != 11 {
+		t.Fatalf("expected lookalike 'Node' to be seen, got '%s'", l.lookalikeName)
+	}
+	l.Rewind(mark)
+	if l.lookalikeStart != -1 || l.lookalikeName != `` || l.tokenEnd != 2 || l.previousTokenEnd != 0 {
+		t.Errorf("expected rewind to restore the lookalike and token ends, got '%s' at %d", l.lookalikeName, l.lookalikeStart)
+	}
+}
+
+func TestLexerErrorRecovery(t *testing.T) {
+	expectTokens(t, `$x = 1a + "x\u{zz}y" + 2`,
+		`variable(x) = error(1) identifier(a) + error("x\u{zz}y") + integer(2)`)
+	expectTokens(t, `$x = 'unterminated + 1`, `variable(x) = error('unterminated + 1)`)
+	expectTokens(t, `1 /* unterminated`, `integer(1) error(/* unterminated)`)
+	expectTokens(t, `1 ~ 2`, `integer(1) error(~) integer(2)`)
+
+	l := NewLexer(``, `~`, LEXER_ERROR_RECOVERY)
+	l.NextToken()
+	if ri, ok := l.TokenValue().(issue.Reported); !ok || ri.Code() != LEX_UNEXPECTED_TOKEN {
+		t.Errorf("expected error token to have an issue value, got %v", l.TokenValue())
+	}
+
+	defer func() {
+		if _, ok := recover().(issue.Reported); !ok {
+			t.Error(`expected a lexer without recovery to panic`)
+		}
+	}()
+	NewLexer(``, `~`).NextToken()
+}
+
+func expectTokens(t *testing.T, source string, expected string) {
+	t.Helper()
+	l := NewLexer(``, source, LEXER_ERROR_RECOVERY)
+	tokens := make([]string, 0)
+	for l.NextToken() != TOKEN_END {
+		switch l.CurrentToken() {
+		case TOKEN_ERROR, TOKEN_VARIABLE, TOKEN_IDENTIFIER:
+			tokens = append(tokens, fmt.Sprintf(`%s(%s)`, token.String(l.CurrentToken()), l.TokenString()))
+		case TOKEN_INTEGER:
+			tokens = append(tokens, fmt.Sprintf(`integer(%d)`, l.TokenValue()))
+		default:
+			tokens = append(tokens, l.TokenString())
+		}
+	}
+	if actual := strings.Join(tokens, ` `); actual != expected {
+		t.Errorf("expected '%s', got '%s'", expected, actual)
+	}
+}
+
+func TestLexerComments(t *testing.T) {
+	l := NewSimpleLexer(``, "$x = 1 # one\r\n/* two\n */ $y = 4 / 2 #", LEXER_EMIT_COMMENTS)
+	tokens := make([]string, 0)
+	for l.NextToken() != TOKEN_END {
+		if l.CurrentToken() == TOKEN_COMMENT {
+			tokens = append(tokens, fmt.Sprintf(`%d:%q`, l.TokenStartPos(), l.TokenString()))
+		} else {
+			tokens = append(tokens, token.String(l.CurrentToken()))
+		}
+	}
+	expected := `variable = integer literal 7:"# one" 14:"/* two\n */" variable = integer literal / integer literal 36:"#"`
+	if actual := strings.Join(tokens, ` `); actual != expected {
+		t.Errorf("expected '%s', got '%s'", expected, actual)
+	}
+
+	l = NewSimpleLexer(``, "1 # one\n/* two */ 2")
+	if l.NextToken() != TOKEN_INTEGER || l.NextToken() != TOKEN_INTEGER || l.NextToken() != TOKEN_END {
+		t.Error(`expected comments to be skipped by default`)
+	}
+
+	expectTokens(t, `1 /* unterminated`, `integer(1) error(/* unterminated)`)
+}
+
+func TestKeywordCase(t *testing.T) {
+	expectError(t, `If $x { notice(1) }`, `Syntax error after 'If', which is a type name and not a keyword. Did you mean 'if'? (line: 1, column: 1)`)
+	expectError(t, "$a = 1\nCLASS foo { }", `Syntax error after 'CLASS', which is a type name and not a keyword. Did you mean 'class'? (line: 2, column: 1)`)
+	expectError(t, `Class foo { }`, `unexpected token '}' (line: 1, column: 13)`)
+	expectError(t, `$x = If $y = `, `Syntax error after 'If', which is a type name and not a keyword. Did you mean 'if'? (line: 1, column: 6)`)
+	expectDump(t, `$x = If`, `(= (var "x") (qr "If"))`)
+	expectError(t, "$a = Node\n$b = 1\n$c = 2\n$d = [1 2 3", `expected one of ',' or ']', got 'integer literal' (line: 4, column: 9)`)
+	expectError(t, "$a = Node\n$d = [1 2 3", `expected one of ',' or ']', got 'integer literal' (line: 2, column: 9)`)
+	expectError(t, "$a = [If]\n$x = [1 2]", `expected one of ',' or ']', got 'integer literal' (line: 2, column: 9)`)
+
+	if keyword, ok := KeywordLookalike(`UNLESS`); !ok || keyword != `unless` {
+		t.Error(`expected UNLESS to look like unless`)
+	}
+	if _, ok := KeywordLookalike(`Type`); ok {
+		t.Error(`expected Type to be a type`)
+	}
+}
+
+func TestLenientCommas(t *testing.T) {
+	source := "$a = 'a',\n$b = 'b', notice($b)\n$c = 'c'"
+	p := CreateParser(PARSER_LENIENT_COMMAS).(WarningsParser)
+	expr, warnings, err := p.ParseWithWarnings(``, source, false)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expected := `(block (= (var "a") "a") (= (var "b") "b") (invoke {:functor (qn "notice") :args [(var "b")]}) (= (var "c") "c"))`
+	if actual := dump(expr); actual != expected {
+		t.Errorf("expected '%s', got '%s'", expected, actual)
+	}
+	if len(warnings) != 1 || warnings[0].Severity() != issue.SEVERITY_WARNING || warnings[0].Error() != `Extraneous comma between statements (line: 1, column: 10)` {
+		t.Errorf("unexpected warnings %v", warnings)
+	}
+
+	if _, warnings, err = p.ParseWithWarnings(``, `$a = 1`, false); err != nil || len(warnings) != 0 {
+		t.Error(`expected no warnings from the next parse`)
+	}
+	expectError(t, source, `Extraneous comma between statements (line: 1, column: 10)`)
+}
+
+func TestFeatures(t *testing.T) {
+	p := CreateParser(PARSER_TASKS_ENABLED, LEXER_EMIT_COMMENTS)
+	if !p.Enabled(PARSER_TASKS_ENABLED) || p.Enabled(PARSER_WORKFLOW_ENABLED) || p.Enabled(LEXER_EMIT_COMMENTS) {
+		t.Error(`unexpected set of enabled features`)
+	}
+	if f, ok := FeatureOf(PARSER_TASKS_ENABLED); !ok || f.Name != `tasks` {
+		t.Error(`expected PARSER_TASKS_ENABLED to enable the 'tasks' feature`)
+	}
+	if _, ok := FeatureOf(LEXER_EMIT_COMMENTS); ok {
+		t.Error(`expected LEXER_EMIT_COMMENTS to not be a feature`)
+	}
+	features := Features()
+	if len(features) != 7 || features[0].Option != PARSER_HANDLE_BACKTICK_STRINGS || features[6].Option != PARSER_IMPORT_COMPAT {
+		t.Errorf(`unexpected features %v`, features)
+	}
+
+	expectError(t, `plan foo(String $x) { notice($x) }`,
+		`Syntax error at 'plan', which requires the 'tasks' feature. The feature is not enabled (line: 1, column: 1)`)
+
+	expectError(t, `workflow foo {}`,
+		`Syntax error at 'workflow', which requires the 'workflow' feature. The feature is not enabled (line: 1, column: 1)`)
+
+	expectError(t, "notice(`x`)",
+		"Syntax error at '`', which requires the 'backtick strings' feature. The feature is not enabled (line: 1, column: 8)")
+
+	expectError(t, `Hello <%= $x %>`,
+		`Syntax error at '<%', which requires the 'epp' feature. The feature is not enabled (line: 1, column: 7)`)
+
+	// Use of a word is only suspect when the error occurs in the same statement
+	expectError(t, "$x = plan\n$y = )", `unexpected token ')' (line: 2, column: 6)`)
+
+	expectDump(t, `$x = plan`, `(= (var "x") (qn "plan"))`)
+}
+
+func TestImportCompat(t *testing.T) {
+	expectDump(t, `import 'nodes/*.pp'`, `(invoke {:functor (qn "import") :args ["nodes/*.pp"]})`)
+
+	expectDump(t, `import 'nodes/*.pp', 'classes.pp'`, `(import "nodes/*.pp" "classes.pp")`, PARSER_IMPORT_COMPAT)
+
+	imports := FindAll[*ImportExpression](parse(t, "class a {}\nimport 'a.pp'", PARSER_IMPORT_COMPAT))
+	if len(imports) != 1 || imports[0].Line() != 2 || len(imports[0].Patterns()) != 1 {
+		t.Errorf(`expected one import on line 2`)
+	}
+}
+
+func TestGrammar(t *testing.T) {
+	all := []Option{PARSER_HANDLE_BACKTICK_STRINGS, PARSER_TASKS_ENABLED, PARSER_WORKFLOW_ENABLED, PARSER_LENIENT_COMMAS, PARSER_IMPORT_COMPAT}
+	ebnf := EBNF(all...)
+
+	// Every keyword and operator that the lexer produces must be a terminal of the grammar
+	for word := range keywords {
+		if !strings.Contains(ebnf, `'`+word+`'`) {
+			t.Errorf(`keyword '%s' is not in the grammar`, word)
+		}
+	}
+	for tk := TOKEN_END; tk <= TOKEN_UNLESS; tk++ {
+		op := token.String(tk)
+		if op != `` && strings.IndexFunc(op, func(c rune) bool { return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }) < 0 &&
+			!strings.Contains(EBNF(append(all, PARSER_EPP_MODE)...), `'`+op+`'`) {
+			t.Errorf(`operator '%s' is not in the grammar`, op)
+		}
+	}
+
+	// Every nonterminal must be defined
+	for _, options := range [][]Option{nil, all, {PARSER_EPP_MODE}} {
+		rules := Grammar(options...)
+		defined := make(map[string]bool, len(rules))
+		for _, rule := range rules {
+			defined[rule.Name] = true
+		}
+		for _, rule := range rules {
+			for i, part := range strings.Split(rule.Definition, `'`) {
+				if i%2 == 1 || strings.HasPrefix(rule.Definition, `?`) {
+					continue
+				}
+				for _, name := range strings.FieldsFunc(part, func(c rune) bool { return !(c == '_' || c >= 'a' && c <= 'z') }) {
+					if !defined[name] {
+						t.Errorf(`rule '%s' refers to undefined '%s'`, rule.Name, name)
+					}
+				}
+			}
+		}
+	}
+
+	if strings.Contains(EBNF(), `plan_definition`) || !strings.Contains(ebnf, `| plan_definition ;`) {
+		t.Error(`expected plan_definition to be enabled by PARSER_TASKS_ENABLED only`)
+	}
+	if !strings.HasPrefix(EBNF(), "program = statements ;\nstatements = { statement , [ ';' ] } ;\n") {
+		t.Errorf(`unexpected start of grammar %s`, EBNF())
+	}
+}
+
+func TestParseSource(t *testing.T) {
+	source := issue.Unindent(`
+    # A comment with a multibyte character: Ω
+    class ntp(String $server = 'pool.ntp.org', Array[String] $opts = []) inherits ntp::params {
+      $msg = "server ${server} is Ω-compatible: ${opts.map |$o| { "[$o]" }.join(',')}"
+      file { '/etc/ntp.conf':
+        ensure  => file,
+        content => @("END"/L),
+          server ${server}
+          Ω \
+          done
+          | END
+        mode    => '0644',
+      }
+      if $facts['os']['family'] =~ /^(RedHat|Debian)$/ {
+        notice($msg)
+      }
+      /* a block comment */
+      $x = 1 / 2 / 3
+    }`)
+	body := parse(t, source)
+	expected := dump(body)
+	expectedHeredoc, _ := First[*HeredocExpression](body)
+	for _, size := range []int{1, 2, 3, 5, 17, len(source)} {
+		chunks := make([]string, 0, len(source)/size+1)
+		for i := 0; i < len(source); i += size {
+			chunks = append(chunks, source[i:min(i+size, len(source))])
+		}
+		expr, err := CreateParser().ParseSource(`ntp.pp`, NewChunkedSource(chunks...), false)
+		if err != nil {
+			t.Fatalf("chunk size %d: %s", size, err.Error())
+		}
+		program := expr.(*Program)
+		if actual := dump(program.Body()); actual != expected {
+			t.Errorf("chunk size %d: expected '%s', got '%s'", size, expected, actual)
+		}
+		heredoc, _ := First[*HeredocExpression](program)
+		if heredoc.Line() != expectedHeredoc.Line() || heredoc.Pos() != expectedHeredoc.Pos() || heredoc.String() != expectedHeredoc.String() {
+			t.Errorf("chunk size %d: unexpected heredoc position %d:%d or text %q", size, heredoc.Line(), heredoc.Pos(), heredoc.String())
+		}
+		if _, source := program.Locator().Text(); source == nil {
+			t.Errorf("chunk size %d: expected the source to not be flattened", size)
+		}
+	}
+
+	_, err := CreateParser().ParseSource(`x.pp`, NewChunkedSource("$x = 'Ω", "\n"), false)
+	if err == nil || err.Error() != `unterminated single quoted string (file: x.pp, line: 1, column: 6)` {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestSourceLexer(t *testing.T) {
+	l := NewSourceLexer(``, NewChunkedSource(`not`, `ice(`, `'Ω`, `')`), LEXER_ERROR_RECOVERY)
+	tokens := make([]string, 0)
+	for l.NextToken() != TOKEN_END {
+		tokens = append(tokens, l.TokenString())
+	}
+	if strings.Join(tokens, ` `) != `notice ( Ω )` {
+		t.Errorf("unexpected tokens %v", tokens)
+	}
+}
+
+func TestParseBytes(t *testing.T) {
+	expr, err := CreateParser().ParseBytes(`x.pp`, []byte(`$x = 'Ω'`), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual := dump(expr); actual != `(= (var "x") "Ω")` {
+		t.Errorf(`unexpected result %s`, actual)
+	}
+}
+
+func TestParseFileMapped(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, `init.pp`)
+	if err := os.WriteFile(file, []byte("class a {\n  notice('a')\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	expr, release, err := ParseFileMapped(CreateParser(), file, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	call, _ := First[*CallNamedFunctionExpression](expr)
+	if call.String() != `notice('a')` || call.File() != file || call.Line() != 2 {
+		t.Errorf(`unexpected call %s at %s:%d`, call.String(), call.File(), call.Line())
+	}
+	if err = release(); err != nil {
+		t.Error(err)
+	}
+
+	empty := filepath.Join(dir, `empty.pp`)
+	if err := os.WriteFile(empty, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, release, err = ParseFileMapped(CreateParser(), empty, false); err != nil || release() != nil {
+		t.Errorf(`expected an empty file to parse, got %v`, err)
+	}
+
+	if _, release, err = ParseFileMapped(CreateParser(), filepath.Join(dir, `missing.pp`), false); err == nil || release != nil {
+		t.Error(`expected an error and no release function for a missing file`)
+	}
+}
+
+func TestConcurrentParse(t *testing.T) {
+	// Run with -race to detect state that is shared by the calls
+	p := CreateParser(PARSER_LENIENT_COMMAS).(WarningsParser)
+	wg := sync.WaitGroup{}
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				source := fmt.Sprintf("$a = %d,\n$b = \"${a}-%d\"", g, i)
+				if i%2 == 1 {
+					source = fmt.Sprintf("$a = %d\n$b = \"${a}-%d\"", g, i)
+				}
+				expr, warnings, err := p.ParseWithWarnings(`site.pp`, source, false)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				expected := fmt.Sprintf(`(block (= (var "a") %d) (= (var "b") (concat (str (var "a")) "-%d")))`, g, i)
+				if actual := dump(expr); actual != expected {
+					t.Errorf("expected '%s', got '%s'", expected, actual)
+				}
+				if len(warnings) != 1-i%2 {
+					t.Errorf("unexpected warnings %v for %q", warnings, source)
+				}
+				if _, err = p.ParseAttributeOperations(``, `mode => '0644'`); err != nil {
+					t.Error(err)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+}
+
+func TestConcurrentLocatorAccess(t *testing.T) {
+	// Run with -race to detect unsynchronized lazy state in the locator
+	source := "class a {\n  notice('a')\n}\n$x = 'Ω'\n"
+	expr, err := CreateParser().ParseSource(`a.pp`, NewChunkedSource(source[:7], source[7:20], source[20:]), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, _ := First[*VariableExpression](expr)
+	wg := sync.WaitGroup{}
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			if g%2 == 0 && x.Locator().String() != source {
+				t.Error(`unexpected locator source`)
+			}
+			if x.Line() != 4 || x.Pos() != 1 || !strings.HasPrefix(x.String(), `$x`) {
+				t.Errorf("unexpected position %d:%d of %s", x.Line(), x.Pos(), x.String())
+			}
+		}(g)
+	}
+	wg.Wait()
+}
+
+type panickingFactory struct {
+	ExpressionFactory
+}
+
+func (f *panickingFactory) Variable(expr Expression, locator *Locator, offset int, length int) Expression {
+	panic(`variables are not supported`)
+}
+
+type panickingParser struct {
+	ExpressionParser
+}
+
+func (p *panickingParser) Parse(filename string, source string, singleExpression bool) (Expression, error) {
+	var m map[string]int
+	m[source] = 1
+	return nil, nil
+}
+
+func TestSafeParse(t *testing.T) {
+	expr, err := SafeParse(CreateParser(), `a.pp`, `$x = 1`, false)
+	if err != nil || dump(expr) != `(block (= (var "x") 1))` {
+		t.Errorf("unexpected result %v, %v", expr, err)
+	}
+	if _, err = SafeParse(CreateParser(), `a.pp`, `$x = `, false); err == nil || !strings.HasPrefix(err.Error(), `unexpected token 'EOF'`) {
+		t.Errorf("expected a parse error, got %v", err)
+	}
+
+	source := "notice('ok')\n  notice('Ω', " + strings.Repeat(`1, `, 40) + "$x)\n"
+	p := &parser{factory: &panickingFactory{DefaultFactory()}}
+	expr, err = SafeParse(p, `a.pp`, source, false)
+	ie, ok := err.(*InternalError)
+	if !ok || expr != nil {
+		t.Fatalf("expected an internal error, got %v", err)
+	}
+	if ie.Value != `variables are not supported` || ie.File != `a.pp` || ie.Line != 2 || ie.Pos != 138 || len(ie.Stack) == 0 {
+		t.Errorf("unexpected error %#v", ie)
+	}
+	if line := strings.Split(source, "\n")[1]; ie.Snippet != line[len(line)-SNIPPET_WIDTH:] {
+		t.Errorf("expected snippet '%s', got '%s'", line[len(line)-SNIPPET_WIDTH:], ie.Snippet)
+	}
+	if !strings.HasPrefix(ie.Error(), `internal parser error: variables are not supported (file: a.pp, line: 2, column: 138) near ', 1, 1, `) {
+		t.Errorf("unexpected message %s", ie.Error())
+	}
+
+	_, err = SafeParse(&panickingParser{}, `b.pp`, `$x`, false)
+	if ie, ok = err.(*InternalError); !ok || ie.Line != 0 || ie.Error() != `internal parser error: assignment to entry in nil map` {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestTraceParser(t *testing.T) {
+	b := bytes.NewBufferString(``)
+	p := TraceParser(CreateParser(PARSER_LENIENT_COMMAS), NewLogTracer(b))
+	if !p.Enabled(PARSER_LENIENT_COMMAS) {
+		t.Error(`expected the traced parser to have the features of the given parser`)
+	}
+	if _, err := p.Parse(``, `$x = 1`, false); err != nil {
+		t.Fatal(err)
+	}
+	if !TRACING_ENABLED {
+		if b.Len() != 0 {
+			t.Errorf("expected no trace, got %s", b.String())
+		}
+		return
+	}
+	lines := strings.Split(b.String(), "\n")
+	if lines[0] != `> syntacticStatement 1:1 variable '$x'` || lines[1] != `  > relationship 1:1 variable '$x'` || lines[len(lines)-2] != `< syntacticStatement 1:7 EOF ''` {
+		t.Errorf("unexpected trace %s", b.String())
+	}
+
+	events := make([]TraceEvent, 0)
+	p = TraceParser(CreateParser(), func(e TraceEvent) { events = append(events, e) })
+	if _, err := p.Parse(``, `notice(`, false); err == nil {
+		t.Fatal(`expected an error`)
+	}
+	if last := events[len(events)-1]; !last.Exit || last.Depth != 0 || last.Rule != `syntacticStatement` {
+		t.Errorf("expected the rules to be exited when the parse fails, got %v", last)
+	}
+}
+
+func TestTokenRecording(t *testing.T) {
+	sources := []string{
+		issue.Unindent(`
+      class ntp(String $server = 'pool.ntp.org', Array[String] $opts = []) inherits ntp::params {
+        $msg = "server ${server}: ${opts.map |$o| { "[$o]" }.join(',')}"
+        file { '/etc/ntp.conf':
+          content => @("END"/L),
+            server ${server} \
+            done
+            | END
+          mode    => 0x1A4,
+        }
+        File { mode => '0644' }
+        notice { a => 1.5e3 }
+        if $facts['os']['family'] =~ /^(RedHat|Debian)$/ { notice($msg) }
+      }`),
+		`If $x { }`,
+		`plan foo() { }`,
+		`$x = [1, 2,`,
+	}
+	p := CreateParser()
+	for i, source := range sources {
+		expected, expectedErr := p.Parse(`a.pp`, source, false)
+		if (expectedErr != nil) != (i > 0) {
+			t.Fatalf("%s: unexpected result of parse: %v", source, expectedErr)
+		}
+		expr, recording, err := ParseAndRecord(p, `a.pp`, source, false)
+		if fmt.Sprint(err) != fmt.Sprint(expectedErr) || (err == nil && dump(expr) != dump(expected)) {
+			t.Errorf("%s: recording parse differs from parse", source)
+		}
+		if recording.Len() == 0 || !recording.Matches(p, source) {
+			t.Fatalf("%s: expected a recording", source)
+		}
+		expr, err = ParseRecorded(p, recording, `a.pp`, source, false)
+		if fmt.Sprint(err) != fmt.Sprint(expectedErr) || (err == nil && dump(expr) != dump(expected)) {
+			t.Errorf("%s: replayed parse differs from parse: %v", source, err)
+		}
+	}
+
+	source := `notice('a')`
+	_, recording, _ := ParseAndRecord(p, `a.pp`, source, false)
+	for _, rt := range recording.tokens {
+		if rt.value == `notice` {
+			rt.value = `warning`
+		}
+	}
+	if expr, _ := ParseRecorded(p, recording, `a.pp`, source, false); dump(expr) != `(block (invoke {:functor (qn "warning") :args ["a"]}))` {
+		t.Errorf("expected tokens to be replayed, got %s", dump(expr))
+	}
+	if recording.Matches(p, `notice('b')`) || recording.Matches(CreateParser(PARSER_EPP_MODE), source) {
+		t.Errorf("expected recording to match only the recorded source and features")
+	}
+	if expr, _ := ParseRecorded(p, recording, `a.pp`, `notice('b')`, false); dump(expr) != `(block (invoke {:functor (qn "notice") :args ["b"]}))` {
+		t.Errorf("expected recording to be ignored, got %s", dump(expr))
+	}
+
+	eppSource := "<%- | $x | -%>\nHello <%= $x %> and <%# comment %>bye\n"
+	epp := CreateParser(PARSER_EPP_MODE)
+	expected, _ := epp.Parse(`a.epp`, eppSource, false)
+	_, recording, _ = ParseAndRecord(epp, `a.epp`, eppSource, false)
+	if expr, err := ParseRecorded(epp, recording, `a.epp`, eppSource, false); err != nil || dump(expr) != dump(expected) {
+		t.Errorf("replayed EPP parse differs from parse: %v", err)
+	}
+}
@@ -3,6 +3,8 @@ package parser
 import (
 	"fmt"
 	"unicode/utf8"
+
+	"github.com/lyraproj/puppet-parser/ast"
 )
 
 type StringReader interface {
@@ -32,9 +34,13 @@ type ParseError struct {
 	offset    int
 }
 
+// stringReader reads a string, or a Source one chunk at a time. The text is the whole string, or the
+// chunk of the source that starts at base.
 type stringReader struct {
-	i    int
-	text string
+	i      int
+	text   string
+	base   int
+	source Source
 }
 
 func (e *ParseError) Error() string {
@@ -45,6 +51,35 @@ func NewStringReader(s string) StringReader {
 	return &stringReader{i: 0, text: s}
 }
 
+// NewSourceReader returns a reader that reads the given source one chunk at a time. The source is
+// flattened into one string only if the Text method is called.
+func NewSourceReader(source Source) StringReader {
+	r := newSourceReader(source)
+	return &r
+}
+
+// newSourceReader returns a reader of the given source. A source that consists of one chunk is read as
+// a string.
+func newSourceReader(source Source) stringReader {
+	n := source.Len()
+	if n == 0 {
+		return stringReader{}
+	}
+	if start, chunk := source.Chunk(0); start == 0 && len(chunk) == n {
+		return stringReader{text: chunk}
+	}
+	return stringReader{source: source}
+}
+
+// locatorReader returns a reader of the source of the given locator
+func locatorReader(locator *Locator) stringReader {
+	s, source := locator.Text()
+	if source != nil {
+		return newSourceReader(source)
+	}
+	return stringReader{text: s}
+}
+
 func (r *stringReader) parseError(message string) *ParseError {
 	return &ParseError{message: message, offset: r.i}
 }
@@ -55,15 +90,19 @@ func (r *stringReader) invalidUnicode() *ParseError {
 
 func (r *stringReader) Next() (c rune, start int) {
 	start = r.i
-	if r.i >= len(r.text) {
-		return
+	j := r.i - r.base
+	if j < 0 || j >= len(r.text) {
+		if !r.load(r.i) {
+			return
+		}
+		j = r.i - r.base
 	}
-	c = rune(r.text[r.i])
+	c = rune(r.text[j])
 	if c < utf8.RuneSelf {
 		r.i++
 		return
 	}
-	c, size := utf8.DecodeRuneInString(r.text[r.i:])
+	c, size := r.decode(r.i)
 	if c == utf8.RuneError {
 		panic(r.invalidUnicode())
 	}
@@ -72,35 +111,47 @@ func (r *stringReader) Next() (c rune, start int) {
 }
 
 func (r *stringReader) Peek() (c rune, size int) {
-	if r.i >= len(r.text) {
-		return
+	return r.PeekAt(r.i)
+}
+
+func (r *stringReader) PeekAt(pos int) (c rune, size int) {
+	j := pos - r.base
+	if j < 0 || j >= len(r.text) {
+		if pos < 0 || !r.load(pos) {
+			return
+		}
+		j = pos - r.base
 	}
-	c = rune(r.text[r.i])
+	c = rune(r.text[j])
 	if c < utf8.RuneSelf {
 		size = 1
 		return
 	}
-	c, size = utf8.DecodeRuneInString(r.text[r.i:])
+	c, size = r.decode(pos)
 	if c == utf8.RuneError {
 		panic(r.invalidUnicode())
 	}
 	return c, size
 }
 
-func (r *stringReader) PeekAt(pos int) (c rune, size int) {
-	if pos < 0 || pos >= len(r.text) {
-		return
+// load makes the chunk that contains the given position current and returns true, or returns false if
+// the position is at or beyond the end of the text
+func (r *stringReader) load(pos int) bool {
+	if r.source == nil || pos >= r.source.Len() {
+		return false
 	}
-	c = rune(r.text[pos])
-	if c < utf8.RuneSelf {
-		size = 1
-		return
-	}
-	c, size = utf8.DecodeRuneInString(r.text[pos:])
-	if c == utf8.RuneError {
-		panic(r.invalidUnicode())
+	r.base, r.text = r.source.Chunk(pos)
+	return true
+}
+
+// decode decodes the multibyte rune at the given position of the current chunk, including a rune that
+// continues in the next chunk
+func (r *stringReader) decode(pos int) (rune, int) {
+	text := r.text[pos-r.base:]
+	if r.source != nil && !utf8.FullRuneInString(text) {
+		text = r.slice(pos, min(pos+utf8.UTFMax, r.source.Len()))
 	}
-	return c, size
+	return utf8.DecodeRuneInString(text)
 }
 
 func (r *stringReader) Advance(size int) {
@@ -115,12 +166,35 @@ func (r *stringReader) SetPos(pos int) {
 	r.i = pos
 }
 
+// Text returns the whole text. A reader that reads a Source flattens the source into one string when this
+// method is first called and then reads that string.
 func (r *stringReader) Text() string {
+	if r.source != nil {
+		r.text = ast.SourceText(r.source)
+		r.base = 0
+		r.source = nil
+	}
 	return r.text
 }
 
 // Returns a substring of the contained string that starts at the given position and ends at
 // the current position
 func (r *stringReader) From(start int) string {
-	return r.text[start:r.i]
+	return r.slice(start, r.i)
+}
+
+// length returns the length of the text in bytes
+func (r *stringReader) length() int {
+	if r.source != nil {
+		return r.source.Len()
+	}
+	return len(r.text)
+}
+
+// slice returns the text between the given positions
+func (r *stringReader) slice(start, end int) string {
+	if start >= r.base && end <= r.base+len(r.text) {
+		return r.text[start-r.base : end-r.base]
+	}
+	return ast.SourceSlice(r.source, start, end)
 }
@@ -0,0 +1,180 @@
+package parser
+
+import (
+	"hash/fnv"
+)
+
+// TokenRecording holds the tokens that were lexed during a parse of a source. A parse of the same source
+// can take its tokens from the recording instead of lexing them again, e.g. when a file that hasn't
+// changed is validated again with a different configuration. See ParseAndRecord and ParseRecorded.
+//
+// A token is recorded together with the position where the lexer started to scan for it, so tokens are
+// found also when the parser backtracks. Strings with interpolations and heredocs are not recorded since
+// their values are expressions that are created by the factory of the parser. They are lexed again when
+// the recording is replayed. A recording is not modified by a replay and can be replayed by several
+// goroutines at once.
+type TokenRecording struct {
+	hash     uint64
+	length   int
+	features featureSet
+	tokens   map[tokenKey]*recordedToken
+
+	// keys are the keys of the tokens in the order that they were recorded
+	keys []tokenKey
+}
+
+// tokenKey is the lexer state that determines the next token, besides the source
+type tokenKey struct {
+	pos           int
+	nextLineStart int
+}
+
+// recordedToken is the lexer state after a token was lexed
+type recordedToken struct {
+	token           int
+	value           interface{}
+	radix           int
+	start           int
+	end             int
+	beginningOfLine int
+	nextLineStart   int
+
+	// Changes of the context that the lexer made while lexing the token
+	lookalikeName  string
+	disabledStart  int
+	disabledSyntax string
+	disabledOption Option
+	eppTextStart   int
+}
+
+// Len returns the number of recorded tokens
+func (r *TokenRecording) Len() int {
+	return len(r.tokens)
+}
+
+// Matches returns true if the recording was made by a parse of the given source by a parser with the
+// features of the given parser, i.e. if ParseRecorded can take tokens from it
+func (r *TokenRecording) Matches(p ExpressionParser, source string) bool {
+	sp, ok := p.(*parser)
+	return ok && r.length == len(source) && r.features == sp.features && r.hash == sourceHash(source)
+}
+
+// ParseAndRecord is like the Parse method of the given parser, which must have been created with
+// CreateParser, but it also returns a recording of the lexed tokens. The recording is returned also when
+// the parse fails. It then holds the tokens up to the error.
+func ParseAndRecord(p ExpressionParser, filename string, source string, singleExpression bool) (expr Expression, recording *TokenRecording, err error) {
+	sp := p.(*parser)
+	recording = &TokenRecording{hash: sourceHash(source), length: len(source), features: sp.features, tokens: make(map[tokenKey]*recordedToken)}
+	ctx := sp.newContext()
+	ctx.tokens = recording
+	ctx.recordTokens = true
+	expr, err = ctx.Parse(filename, source, singleExpression)
+	return
+}
+
+// ParseRecorded is like the Parse method of the given parser, which must have been created with
+// CreateParser, but it takes the tokens from the given recording instead of lexing them. The source must
+// be the source that was recorded and the parser must have the same features as the parser that made the
+// recording. The recording is ignored and the source is lexed when that isn't the case.
+func ParseRecorded(p ExpressionParser, recording *TokenRecording, filename string, source string, singleExpression bool) (expr Expression, err error) {
+	sp := p.(*parser)
+	ctx := sp.newContext()
+	if recording.Matches(p, source) {
+		ctx.tokens = recording
+	}
+	return ctx.Parse(filename, source, singleExpression)
+}
+
+func sourceHash(source string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(source))
+	return h.Sum64()
+}
+
+// nextToken makes the next token current
+func (ctx *context) nextToken() {
+	ctx.previousTokenEnd = ctx.tokenEnd
+	ctx.readToken()
+	ctx.tokenEnd = ctx.Pos()
+}
+
+// readToken lexes the next token. The token is taken from the recording of the context when the context
+// replays one, and added to it when the context records one.
+func (ctx *context) readToken() {
+	if ctx.tokens == nil {
+		ctx.lexToken()
+		return
+	}
+	key := tokenKey{ctx.Pos(), ctx.nextLineStart}
+	if !ctx.recordTokens {
+		if rt, ok := ctx.tokens.tokens[key]; ok {
+			ctx.replayToken(rt)
+			return
+		}
+		ctx.lexToken()
+		return
+	}
+
+	lookalikeStart, disabledStart, eppTextStart := ctx.lookalikeStart, ctx.disabledStart, ctx.eppTextStart
+	ctx.lexToken()
+	switch ctx.tokenValue.(type) {
+	case nil, string, int64, float64, bool:
+	default:
+		// The value is an expression that is created by the factory
+		return
+	}
+	rt := &recordedToken{
+		token:           ctx.currentToken,
+		value:           ctx.tokenValue,
+		radix:           ctx.radix,
+		start:           ctx.tokenStartPos,
+		end:             ctx.Pos(),
+		beginningOfLine: ctx.beginningOfLine,
+		nextLineStart:   ctx.nextLineStart,
+		disabledStart:   -1,
+		eppTextStart:    -1,
+	}
+	if ctx.lookalikeStart != lookalikeStart {
+		rt.lookalikeName = ctx.lookalikeName
+	}
+	if ctx.disabledStart != disabledStart {
+		rt.disabledStart, rt.disabledSyntax, rt.disabledOption = ctx.disabledStart, ctx.disabledSyntax, ctx.disabledOption
+	}
+	if ctx.eppTextStart != eppTextStart {
+		rt.eppTextStart = ctx.eppTextStart
+	}
+	if _, ok := ctx.tokens.tokens[key]; !ok {
+		ctx.tokens.keys = append(ctx.tokens.keys, key)
+	}
+	ctx.tokens.tokens[key] = rt
+}
+
+// truncate removes the tokens that were recorded after the first n tokens, e.g. the tokens that a lexer
+// recorded while it peeked ahead
+func (r *TokenRecording) truncate(n int) {
+	for _, key := range r.keys[n:] {
+		delete(r.tokens, key)
+	}
+	r.keys = r.keys[:n]
+}
+
+// replayToken restores the state of the context to the state after the given token was lexed
+func (ctx *context) replayToken(rt *recordedToken) {
+	ctx.SetPos(rt.end)
+	ctx.currentToken = rt.token
+	ctx.tokenValue = rt.value
+	ctx.radix = rt.radix
+	ctx.tokenStartPos = rt.start
+	ctx.beginningOfLine = rt.beginningOfLine
+	ctx.nextLineStart = rt.nextLineStart
+	if rt.lookalikeName != `` && rt.start > ctx.lookalikeStart {
+		ctx.lookalikeStart = rt.start
+		ctx.lookalikeName = rt.lookalikeName
+	}
+	if rt.disabledStart >= 0 {
+		ctx.useOfDisabled(rt.disabledStart, rt.disabledSyntax, rt.disabledOption)
+	}
+	if rt.eppTextStart >= 0 {
+		ctx.eppTextStart = rt.eppTextStart
+	}
+}
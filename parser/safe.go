@@ -0,0 +1,92 @@
+package parser
+
+import (
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"unicode/utf8"
+)
+
+// InternalError is the error that SafeParse returns when the parser panics for a reason other than an
+// error in the source, i.e. because of a bug in the parser or in its ExpressionFactory
+type InternalError struct {
+	// Value is the value that was recovered from the panic
+	Value interface{}
+
+	// Stack is the stack trace of the goroutine that panicked
+	Stack []byte
+
+	// File is the name of the parsed file
+	File string
+
+	// Line and Pos are the 1-based line and position on the line that the parser had reached when it
+	// panicked. They are zero when the position is unknown.
+	Line, Pos int
+
+	// Snippet is the source text of that line, shortened to at most 80 characters around the position
+	Snippet string
+}
+
+// SNIPPET_WIDTH is the maximum number of characters in the Snippet of an InternalError
+const SNIPPET_WIDTH = 80
+
+func (e *InternalError) Error() string {
+	b := strings.Builder{}
+	fmt.Fprintf(&b, `internal parser error: %v`, e.Value)
+	if e.Line > 0 {
+		fmt.Fprintf(&b, ` (file: %s, line: %d, column: %d) near '%s'`, e.File, e.Line, e.Pos, e.Snippet)
+	}
+	return b.String()
+}
+
+// SafeParse is like the Parse method of the given parser but it never panics. A panic that is not caused
+// by an error in the source is recovered and returned as an *InternalError. Services that parse sources
+// that they don't control can use it to keep an unexpected failure from taking down the process.
+//
+// The parser cannot recover from a fatal error of the Go runtime, such as running out of memory.
+func SafeParse(p ExpressionParser, filename string, source string, singleExpression bool) (expr Expression, err error) {
+	var ctx *context
+	defer func() {
+		if r := recover(); r != nil {
+			ie := &InternalError{Value: r, Stack: debug.Stack(), File: filename}
+			if ctx != nil {
+				ie.locate(ctx.locator, min(ctx.Pos(), len(source)))
+			}
+			expr, err = nil, ie
+		}
+	}()
+
+	if sp, ok := p.(*parser); ok {
+		ctx = sp.newContext()
+		return ctx.Parse(filename, source, singleExpression)
+	}
+	return p.Parse(filename, source, singleExpression)
+}
+
+// locate sets the position and the snippet of the error to those of the given offset
+func (e *InternalError) locate(locator *Locator, offset int) {
+	if locator == nil || offset < 0 {
+		return
+	}
+	source := locator.String()
+	for offset > 0 && offset < len(source) && !utf8.RuneStart(source[offset]) {
+		offset--
+	}
+	e.Line = locator.LineForOffset(offset)
+	e.Pos = locator.PosOnLine(offset)
+
+	start := strings.LastIndexByte(source[:offset], '\n') + 1
+	end := strings.IndexByte(source[offset:], '\n')
+	if end < 0 {
+		end = len(source)
+	} else {
+		end += offset
+	}
+	line := []rune(strings.TrimRight(source[start:end], "\r"))
+	col := utf8.RuneCountInString(source[start:offset])
+	if len(line) > SNIPPET_WIDTH {
+		from := max(0, min(col-SNIPPET_WIDTH/2, len(line)-SNIPPET_WIDTH))
+		line = line[from : from+SNIPPET_WIDTH]
+	}
+	e.Snippet = string(line)
+}
@@ -0,0 +1,98 @@
+package parser
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/lyraproj/puppet-parser/token"
+)
+
+// TRACING_ENABLED is true when the parser is built with the parsertrace tag, e.g.
+//
+//	go test -tags parsertrace ./...
+//
+// Only then does a Tracer receive events. The hook costs nothing in other builds.
+const TRACING_ENABLED = tracing
+
+// TraceEvent describes the entry to or the exit from a rule of the grammar
+type TraceEvent struct {
+	// Rule is the name of the rule, e.g. "relationship" or "atomExpression"
+	Rule string
+
+	// Exit is false when the rule is entered and true when it is exited
+	Exit bool
+
+	// Depth is the number of rules that are entered and not yet exited, excluding this rule
+	Depth int
+
+	// Token is the name of the current token and Text is its source text
+	Token, Text string
+
+	// Line and Pos are the 1-based line and position on the line where the current token starts
+	Line, Pos int
+}
+
+// A Tracer receives the events of a traced parser. See TraceParser.
+type Tracer func(event TraceEvent)
+
+// TraceParser returns a parser that has the features of the given parser, which must have been created
+// with CreateParser, and that calls the given tracer on entry to and exit from each rule of the grammar.
+// The tracer is called only when TRACING_ENABLED is true. It is called by the goroutine that parses, so
+// a tracer that is shared by goroutines must be safe for concurrent use.
+func TraceParser(p ExpressionParser, tracer Tracer) ExpressionParser {
+	sp := p.(*parser)
+	return &parser{features: sp.features, factory: sp.factory, tracer: tracer}
+}
+
+// NewLogTracer returns a tracer that writes one line per event to the given writer, indented by the
+// depth of the event, e.g.
+//
+//	> syntacticStatement 1:1 variable '$x'
+//	  > relationship 1:1 variable '$x'
+//	    > assignment 1:1 variable '$x'
+//	...
+//	    < assignment 1:7 EOF ''
+//	  < relationship 1:7 EOF ''
+//	< syntacticStatement 1:7 EOF ''
+func NewLogTracer(w io.Writer) Tracer {
+	return func(e TraceEvent) {
+		dir := '>'
+		if e.Exit {
+			dir = '<'
+		}
+		fmt.Fprintf(w, "%s%c %s %d:%d %s '%s'\n", strings.Repeat(`  `, e.Depth), dir, e.Rule, e.Line, e.Pos, e.Token, e.Text)
+	}
+}
+
+// trace reports the entry to the given rule to the tracer of the context, if any, and returns the function
+// that reports the exit. It is called as
+//
+//	if tracing {
+//	  defer ctx.trace(`rule`)()
+//	}
+func (ctx *context) trace(rule string) func() {
+	if ctx.tracer == nil {
+		return func() {}
+	}
+	ctx.tracer(ctx.traceEvent(rule, false))
+	ctx.traceDepth++
+	return func() {
+		ctx.traceDepth--
+		ctx.tracer(ctx.traceEvent(rule, true))
+	}
+}
+
+func (ctx *context) traceEvent(rule string, exit bool) TraceEvent {
+	end := min(max(ctx.Pos(), ctx.tokenStartPos), ctx.locator.Len())
+	text := strings.TrimRight(ctx.locator.Slice(min(ctx.tokenStartPos, end), end), " \t\r\n")
+	return TraceEvent{
+		Rule:  rule,
+		Exit:  exit,
+		Depth: ctx.traceDepth,
+		Token: token.String(ctx.currentToken),
+		Text:  text,
+		Line:  ctx.locator.LineForOffset(ctx.tokenStartPos),
+		Pos:   ctx.locator.PosOnLine(ctx.tokenStartPos),
+	}
+}
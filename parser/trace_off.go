@@ -0,0 +1,7 @@
+//go:build !parsertrace
+
+package parser
+
+// tracing is false unless the parser is built with the parsertrace tag. The calls to trace are then
+// removed by the compiler.
+const tracing = false
@@ -0,0 +1,6 @@
+//go:build parsertrace
+
+package parser
+
+// tracing is true when the parser is built with the parsertrace tag
+const tracing = true
@@ -0,0 +1,218 @@
+// Package parsertest provides helpers for tests of code that embeds the parser. The helpers render
+// parsed sources as PN or JSON with a stable layout, report differences as line diffs, and maintain
+// golden files, so that table driven tests can be written as:
+//
+//	tests := []struct{ source, pn string }{
+//	  {`$x = 1`, `(= (var "x") 1)`},
+//	  {`notice($x)`, `(invoke {:functor (qn "notice") :args [(var "x")]})`},
+//	}
+//	for _, test := range tests {
+//	  parsertest.ExpectEqual(t, test.source, test.pn, parsertest.PN(parsertest.Parse(t, test.source)))
+//	}
+//
+// or, with the expected output in files below testdata:
+//
+//	parsertest.Golden(t, `testdata/manifest.json`, parsertest.IndentedJSON(parsertest.Parse(t, source)))
+//
+// Golden files are created or updated instead of compared when the tests are run with the flag
+// -parsertest.update.
+package parsertest
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/lyraproj/puppet-parser/parser"
+	"github.com/lyraproj/puppet-parser/pn"
+)
+
+var update = flag.Bool(`parsertest.update`, false, `update golden files instead of comparing with them`)
+
+// Parse parses the given source using a parser created with the given options. The test fails
+// immediately when the source can't be parsed. The result is a single expression when the source
+// consists of one statement and a Block otherwise, i.e. the body of the parsed Program.
+func Parse(t testing.TB, source string, parserOptions ...parser.Option) parser.Expression {
+	t.Helper()
+	expr, err := parser.CreateParser(parserOptions...).Parse(``, source, false)
+	if err != nil {
+		t.Fatalf("parse of %q failed: %s", source, err.Error())
+	}
+	body := expr.(*parser.Program).Body()
+	if b, ok := body.(*parser.BlockExpression); ok && len(b.Statements()) == 1 {
+		return b.Statements()[0]
+	}
+	return body
+}
+
+// PN returns the string representation of the PN of the given expression
+func PN(e parser.Expression, opts ...pn.Option) string {
+	b := bytes.NewBufferString(``)
+	pn.FormatWith(pn.Locate(e.ToPN(), e), b, opts...)
+	return b.String()
+}
+
+// JSON returns the PN of the given expression as compact JSON without a trailing newline
+func JSON(e parser.Expression, opts ...pn.Option) string {
+	b := bytes.NewBufferString(``)
+	if err := pn.EncodeJSON(b, pn.Locate(e.ToPN(), e), opts...); err != nil {
+		panic(err)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// IndentedJSON is like JSON but the JSON is indented with two spaces, which gives small diffs when
+// the output changes
+func IndentedJSON(e parser.Expression, opts ...pn.Option) string {
+	b := bytes.NewBufferString(``)
+	if err := json.Indent(b, []byte(JSON(e, opts...)), ``, `  `); err != nil {
+		panic(err)
+	}
+	return b.String()
+}
+
+// ExpectEqual reports an error with a line diff when the actual string differs from the expected
+// one. The name identifies the case in the report, e.g. a source or the name of a table entry.
+func ExpectEqual(t testing.TB, name, expected, actual string) {
+	t.Helper()
+	if expected != actual {
+		t.Errorf("%s: result differs from expected (-expected +actual):\n%s", name, Diff(expected, actual))
+	}
+}
+
+// Golden compares the actual string with the content of the golden file at the given path and reports
+// an error with a line diff when they differ. The golden file is written instead when the tests are run
+// with the flag -parsertest.update.
+func Golden(t testing.TB, path, actual string) {
+	t.Helper()
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(actual), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	expected, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%s (run the tests with -parsertest.update to create it)", err.Error())
+	}
+	ExpectEqual(t, path, string(expected), actual)
+}
+
+// Concurrently calls f the given number of times in each of the given number of goroutines and waits
+// for all calls to return. The goroutines are released at the same time to maximize the overlap of the
+// calls. A panic in f fails the test. Run the test with -race to detect unsynchronized access to state
+// that the calls share, e.g. a parser or a parsed expression:
+//
+//	p := parser.CreateParser()
+//	parsertest.Concurrently(t, 8, 100, func(g, i int) {
+//	  if _, err := p.Parse(``, source, false); err != nil {
+//	    t.Error(err)
+//	  }
+//	})
+func Concurrently(t testing.TB, goroutines, iterations int, f func(g, i int)) {
+	t.Helper()
+	start := make(chan struct{})
+	wg := sync.WaitGroup{}
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					t.Errorf("goroutine %d: %v", g, r)
+				}
+			}()
+			<-start
+			for i := 0; i < iterations; i++ {
+				f(g, i)
+			}
+		}(g)
+	}
+	close(start)
+	wg.Wait()
+}
+
+// Diff returns the lines that differ between the expected and the actual string. Lines only in the
+// expected string are prefixed with '-', lines only in the actual string with '+', and equal lines
+// with a space. At most three equal lines are retained around each difference. The result is empty
+// when the strings are equal.
+func Diff(expected, actual string) string {
+	if expected == actual {
+		return ``
+	}
+	a := strings.Split(expected, "\n")
+	b := strings.Split(actual, "\n")
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	lines := make([]string, 0)
+	for i, j := 0, 0; i < len(a) || j < len(b); {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, ` `+a[i])
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, `-`+a[i])
+			i++
+		default:
+			lines = append(lines, `+`+b[j])
+			j++
+		}
+	}
+	return strings.Join(withContext(lines, 3), "\n")
+}
+
+// withContext removes equal lines that are further than the given number of lines from a difference and
+// replaces each removed run with a line that tells how many lines were removed
+func withContext(lines []string, context int) []string {
+	keep := make([]bool, len(lines))
+	for i, line := range lines {
+		if line[0] != ' ' {
+			for k := max(0, i-context); k <= min(len(lines)-1, i+context); k++ {
+				keep[k] = true
+			}
+		}
+	}
+	result := make([]string, 0, len(lines))
+	for i := 0; i < len(lines); {
+		if keep[i] {
+			result = append(result, lines[i])
+			i++
+			continue
+		}
+		start := i
+		for i < len(lines) && !keep[i] {
+			i++
+		}
+		if i-start == 1 {
+			result = append(result, `@@ 1 equal line @@`)
+		} else {
+			result = append(result, fmt.Sprintf(`@@ %d equal lines @@`, i-start))
+		}
+	}
+	return result
+}
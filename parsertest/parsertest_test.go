@@ -0,0 +1,107 @@
+package parsertest
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/lyraproj/puppet-parser/parser"
+	"github.com/lyraproj/puppet-parser/pn"
+	"github.com/lyraproj/puppet-parser/validator"
+)
+
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestRendering(t *testing.T) {
+	e := Parse(t, `$x = 'a'`)
+	ExpectEqual(t, `PN`, `(= (var "x") "a")`, PN(e))
+	ExpectEqual(t, `JSON`, `{"^":["=",{"^":["var","x"]},"a"]}`, JSON(e))
+	ExpectEqual(t, `positions`, `{"@":[1,1,0,8],"^":["=",{"@":[1,1,0,4],"^":["var","x"]},{"=":"a","@":[1,6,5,3]}]}`, JSON(e, pn.WITH_POSITIONS))
+	ExpectEqual(t, `indented`, "{\n  \"^\": [\n    \"=\",\n    {\n      \"^\": [\n        \"var\",\n        \"x\"\n      ]\n    },\n    \"a\"\n  ]\n}", IndentedJSON(e))
+	ExpectEqual(t, `block`, `(block (= (var "x") 1) (var "x"))`, PN(Parse(t, `$x = 1 $x`)))
+}
+
+func TestDiff(t *testing.T) {
+	expected := "1\n2\n3\n4\n5\n6\n7\n8\n9"
+	actual := "1\n2\n3\n4\n5\nsix\n7\n8\n9\n10"
+	ExpectEqual(t, `diff`, strings.Join([]string{
+		`@@ 2 equal lines @@`,
+		` 3`,
+		` 4`,
+		` 5`,
+		`-6`,
+		`+six`,
+		` 7`,
+		` 8`,
+		` 9`,
+		`+10`,
+	}, "\n"), Diff(expected, actual))
+
+	if Diff(expected, expected) != `` {
+		t.Errorf("expected no diff for equal strings")
+	}
+}
+
+func TestExpectEqualReportsDiff(t *testing.T) {
+	r := &recorder{TB: t}
+	ExpectEqual(r, `case`, "a\nb", "a\nc")
+	if len(r.errors) != 1 || r.errors[0] != "case: result differs from expected (-expected +actual):\n a\n-b\n+c" {
+		t.Errorf("unexpected errors %q", r.errors)
+	}
+}
+
+func TestGolden(t *testing.T) {
+	path := filepath.Join(t.TempDir(), `golden`, `x.json`)
+	*update = true
+	Golden(t, path, `{"a":1}`)
+	*update = false
+
+	Golden(t, path, `{"a":1}`)
+	r := &recorder{TB: t}
+	Golden(r, path, `{"a":2}`)
+	if len(r.errors) != 1 || !strings.Contains(r.errors[0], "-{\"a\":1}\n+{\"a\":2}") {
+		t.Errorf("unexpected errors %q", r.errors)
+	}
+}
+
+func TestConcurrently(t *testing.T) {
+	p := parser.CreateParser()
+	shared := Parse(t, "$a::b = 1\nnotice($a::b)")
+	calls := int32(0)
+	Concurrently(t, 4, 25, func(g, i int) {
+		atomic.AddInt32(&calls, 1)
+		expr, err := p.Parse(``, fmt.Sprintf(`$x = %d`, i), false)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		ExpectEqual(t, `parse`, fmt.Sprintf(`(block (= (var "x") %d))`, i), PN(expr.(*parser.Program).Body()))
+		if issues := validator.ValidatePuppet(shared, validator.STRICT_ERROR).Issues(); len(issues) != 1 || issues[0].Error() != `Illegal attempt to assign to 'a::b'. Cannot assign to variables in other namespaces (line: 1, column: 1)` {
+			t.Errorf("unexpected issues %v", issues)
+		}
+	})
+	if calls != 100 {
+		t.Errorf("expected 100 calls, got %d", calls)
+	}
+
+	r := &recorder{TB: t}
+	Concurrently(r, 2, 1, func(g, i int) {
+		if g == 1 {
+			panic(`boom`)
+		}
+	})
+	if len(r.errors) != 1 || r.errors[0] != `goroutine 1: boom` {
+		t.Errorf("unexpected errors %q", r.errors)
+	}
+}
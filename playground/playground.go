@@ -0,0 +1,140 @@
+// Package playground implements the operations of an in-browser Puppet parser playground. Each operation
+// takes a source and a set of options and returns plain data (maps, slices, strings, numbers, and booleans)
+// that can be handed to JavaScript as is. The command in the wasm directory binds the operations to a
+// global JavaScript object when the package is built with GOOS=js and GOARCH=wasm.
+package playground
+
+import (
+	"fmt"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-parser/parser"
+	"github.com/lyraproj/puppet-parser/pn"
+	"github.com/lyraproj/puppet-parser/printer"
+	"github.com/lyraproj/puppet-parser/validator"
+)
+
+// Options controls how a source is parsed and validated
+type Options struct {
+	// EPP parses the source as embedded Puppet
+	EPP bool
+
+	// Tasks enables plan definitions and validates the source with the tasks validator
+	Tasks bool
+
+	// Workflow enables workflow activities and validates the source with the workflow validator
+	Workflow bool
+
+	// Lenient accepts extraneous commas between statements with a warning
+	Lenient bool
+
+	// Strict is the strictness of the Puppet validator, "off", "warning", or "error". The default is "off".
+	Strict string
+
+	// Positions includes the position of each node in the AST returned by Parse
+	Positions bool
+}
+
+// Parse parses and validates the source and returns a map with the key "ast", the PN data of the AST, when
+// the source could be parsed and the key "issues" when issues were reported
+func Parse(source string, options Options) map[string]interface{} {
+	return run(source, options, func(result map[string]interface{}, expr parser.Expression) {
+		pnOpts := []pn.Option{pn.WITH_SCHEMA_VERSION}
+		if options.Positions {
+			pnOpts = append(pnOpts, pn.WITH_POSITIONS)
+		}
+		result[`ast`] = pn.ToDataWith(expr.ToPN(), pnOpts...)
+	})
+}
+
+// Validate parses and validates the source and returns a map with the key "valid", which is false when an
+// error was reported, and the key "issues" when issues were reported
+func Validate(source string, options Options) map[string]interface{} {
+	return run(source, options, func(result map[string]interface{}, expr parser.Expression) {})
+}
+
+// Format parses the source and returns a map with the key "source", the source as printed by the printer
+// package, when the source could be parsed and the key "issues" when issues were reported
+func Format(source string, options Options) map[string]interface{} {
+	return run(source, options, func(result map[string]interface{}, expr parser.Expression) {
+		result[`source`] = printer.String(expr)
+	})
+}
+
+// run parses and validates the source and calls the given function with the result map and the parsed
+// expression unless the source couldn't be parsed. A panic, e.g. due to invalid options, is returned as
+// the key "error" since it cannot propagate to JavaScript.
+func run(source string, options Options, f func(result map[string]interface{}, expr parser.Expression)) (result map[string]interface{}) {
+	result = make(map[string]interface{}, 3)
+	defer func() {
+		if r := recover(); r != nil {
+			result = map[string]interface{}{`valid`: false, `error`: fmt.Sprint(r)}
+		}
+	}()
+
+	p := parser.CreateParser(options.parserOptions()...)
+	expr, issues := validator.NewParserValidator(p, options.validator()).Parse(`playground`, source)
+
+	valid := true
+	if issues != nil {
+		data := make([]interface{}, len(issues.Issues()))
+		for i, ri := range issues.Issues() {
+			data[i] = issueData(ri)
+			if ri.Severity() == issue.SEVERITY_ERROR {
+				valid = false
+			}
+		}
+		result[`issues`] = data
+	}
+	result[`valid`] = valid
+	if expr != nil {
+		f(result, expr)
+	}
+	return
+}
+
+func (o Options) parserOptions() []parser.Option {
+	opts := []parser.Option{parser.PARSER_HANDLE_BACKTICK_STRINGS, parser.PARSER_HANDLE_HEX_ESCAPES}
+	if o.EPP {
+		opts = append(opts, parser.PARSER_EPP_MODE)
+	}
+	if o.Tasks {
+		opts = append(opts, parser.PARSER_TASKS_ENABLED)
+	}
+	if o.Workflow {
+		opts = append(opts, parser.PARSER_WORKFLOW_ENABLED)
+	}
+	if o.Lenient {
+		opts = append(opts, parser.PARSER_LENIENT_COMMAS)
+	}
+	return opts
+}
+
+func (o Options) validator() validator.Validator {
+	switch {
+	case o.Tasks:
+		return validator.NewTasksChecker()
+	case o.Workflow:
+		return validator.NewWorkflowChecker()
+	default:
+		return validator.NewChecker(validator.Strict(o.Strict))
+	}
+}
+
+// issueData returns the data of the issue with the line and column of its location so that an editor
+// can mark it
+func issueData(ri issue.Reported) map[string]interface{} {
+	data := map[string]interface{}{
+		`code`:     string(ri.Code()),
+		`severity`: ri.Severity().String(),
+		`message`:  ri.Error(),
+	}
+	if url := pn.DocURL(ri.Code()); url != `` {
+		data[`url`] = url
+	}
+	if loc := ri.Location(); loc != nil {
+		data[`line`] = loc.Line()
+		data[`column`] = loc.Pos()
+	}
+	return data
+}
@@ -0,0 +1,74 @@
+package playground
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lyraproj/puppet-parser/pn"
+)
+
+func TestParse(t *testing.T) {
+	result := Parse(`$x = 1`, Options{})
+	expected := map[string]interface{}{
+		`valid`: true,
+		`ast`: map[string]interface{}{
+			`^`:  []interface{}{`block`, map[string]interface{}{`^`: []interface{}{`=`, map[string]interface{}{`^`: []interface{}{`var`, `x`}}, int64(1)}}},
+			`pn`: pn.SCHEMA_VERSION},
+	}
+	if !reflect.DeepEqual(expected, result) {
+		t.Errorf("expected %v, got %v", expected, result)
+	}
+}
+
+func TestParseError(t *testing.T) {
+	result := Parse("$x = \n'a", Options{})
+	issues, ok := result[`issues`].([]interface{})
+	if !ok || len(issues) != 1 || result[`valid`] != false || result[`ast`] != nil {
+		t.Fatalf("unexpected result %v", result)
+	}
+	expected := map[string]interface{}{
+		`code`:     `LEX_UNTERMINATED_STRING`,
+		`severity`: `error`,
+		`message`:  `unterminated single quoted string (file: playground, line: 2, column: 1)`,
+		`line`:     2,
+		`column`:   1,
+	}
+	if !reflect.DeepEqual(expected, issues[0]) {
+		t.Errorf("expected %v, got %v", expected, issues[0])
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if result := Validate(`$a::b = 1`, Options{}); result[`valid`] != false || len(result[`issues`].([]interface{})) != 1 {
+		t.Errorf("unexpected result %v", result)
+	}
+	if result := Validate(`plan foo() {}`, Options{Tasks: true}); result[`valid`] != true || result[`issues`] != nil {
+		t.Errorf("unexpected result %v", result)
+	}
+	if result := Validate(`$x = 1`, Options{Strict: `nonsense`}); result[`valid`] != false || result[`error`] != `Invalid Strictness value 'nonsense'` {
+		t.Errorf("unexpected result %v", result)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	result := Format(`if $x{notice( 'a' )}`, Options{})
+	if result[`source`] != "if $x {\n  notice('a')\n}\n" {
+		t.Errorf("unexpected result %q", result[`source`])
+	}
+}
+
+func TestDocURL(t *testing.T) {
+	pn.MapDocURLs(pn.DocURLTemplate(`https://example.com/issues/%{key}.html`))
+	defer pn.MapDocURLs(nil)
+
+	issues := Validate(`$a::b = 1`, Options{})[`issues`].([]interface{})
+	if url := issues[0].(map[string]interface{})[`url`]; url != `https://example.com/issues/validate-cross-scope-assignment.html` {
+		t.Errorf("unexpected url %v", url)
+	}
+
+	pn.MapDocURLs(func(key string) string { return `` })
+	issues = Validate(`$a::b = 1`, Options{})[`issues`].([]interface{})
+	if _, ok := issues[0].(map[string]interface{})[`url`]; ok {
+		t.Errorf("expected no url")
+	}
+}
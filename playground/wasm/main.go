@@ -0,0 +1,69 @@
+//go:build js && wasm
+
+// Command wasm exposes the playground operations to JavaScript. Build it with
+//
+//	GOOS=js GOARCH=wasm go build -o puppet-parser.wasm ./playground/wasm
+//
+// and load the result with the wasm_exec.js that is shipped with Go. The program defines a global object
+// named puppetParser with the functions parse, validate, and format. Each function takes a source string
+// and an optional options object with the keys epp, tasks, workflow, lenient, strict, and positions, and
+// returns an object as described by the corresponding function of the playground package.
+package main
+
+import (
+	"bytes"
+	"syscall/js"
+
+	"github.com/lyraproj/puppet-parser/json"
+	"github.com/lyraproj/puppet-parser/playground"
+)
+
+func main() {
+	js.Global().Set(`puppetParser`, js.ValueOf(map[string]interface{}{
+		`parse`:    bind(playground.Parse),
+		`validate`: bind(playground.Validate),
+		`format`:   bind(playground.Format),
+	}))
+
+	// Keep the functions alive for as long as the page is
+	select {}
+}
+
+func bind(op func(string, playground.Options) map[string]interface{}) js.Func {
+	return js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		source := ``
+		if len(args) > 0 && args[0].Type() == js.TypeString {
+			source = args[0].String()
+		}
+		var options playground.Options
+		if len(args) > 1 && args[1].Type() == js.TypeObject {
+			options = optionsOf(args[1])
+		}
+		return toJS(op(source, options))
+	})
+}
+
+func optionsOf(v js.Value) playground.Options {
+	flag := func(key string) bool {
+		return v.Get(key).Truthy()
+	}
+	options := playground.Options{
+		EPP:       flag(`epp`),
+		Tasks:     flag(`tasks`),
+		Workflow:  flag(`workflow`),
+		Lenient:   flag(`lenient`),
+		Positions: flag(`positions`),
+	}
+	if s := v.Get(`strict`); s.Type() == js.TypeString {
+		options.Strict = s.String()
+	}
+	return options
+}
+
+// toJS converts the result to a JavaScript value by means of JSON since js.ValueOf doesn't accept all
+// the numeric types that the result may contain
+func toJS(result map[string]interface{}) js.Value {
+	b := bytes.NewBufferString(``)
+	json.ToJson(result, b)
+	return js.Global().Get(`JSON`).Call(`parse`, b.String())
+}
@@ -0,0 +1,53 @@
+package pn
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/lyraproj/issue/issue"
+)
+
+// A DocURLMapper maps the documentation key of an issue code to the URL of a page that explains the
+// issue. An empty string means that there is no such page.
+type DocURLMapper func(key string) string
+
+var docURLs = struct {
+	lock   sync.RWMutex
+	mapper DocURLMapper
+}{}
+
+// DocKey returns the documentation key of the given issue code, i.e. the code in lower case with
+// underscores replaced by dashes, e.g. "validate-cross-scope-assignment" for the code
+// VALIDATE_CROSS_SCOPE_ASSIGNMENT. The key is stable for as long as the code is, and is suitable as
+// the name or anchor of a documentation page.
+func DocKey(code issue.Code) string {
+	return strings.ReplaceAll(strings.ToLower(string(code)), `_`, `-`)
+}
+
+// MapDocURLs sets the mapper that DocURL uses. Renderers of issues, such as ReportedToPN, then include
+// the URL of each issue that has one. A nil mapper removes a previously set mapper.
+func MapDocURLs(mapper DocURLMapper) {
+	docURLs.lock.Lock()
+	docURLs.mapper = mapper
+	docURLs.lock.Unlock()
+}
+
+// DocURLTemplate returns a mapper that replaces each occurrence of "%{key}" in the given template with
+// the documentation key, e.g. DocURLTemplate(`https://example.com/issues.html#%{key}`).
+func DocURLTemplate(template string) DocURLMapper {
+	return func(key string) string {
+		return strings.ReplaceAll(template, `%{key}`, key)
+	}
+}
+
+// DocURL returns the URL of the documentation of the given issue code, or an empty string when no mapper
+// has been set or the mapper has no URL for the code
+func DocURL(code issue.Code) string {
+	docURLs.lock.RLock()
+	mapper := docURLs.mapper
+	docURLs.lock.RUnlock()
+	if mapper == nil {
+		return ``
+	}
+	return mapper(DocKey(code))
+}
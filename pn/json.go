@@ -0,0 +1,184 @@
+package pn
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"strconv"
+)
+
+type jsonEncoder struct {
+	w   *bufio.Writer
+	o   *options
+	buf bytes.Buffer
+	enc *json.Encoder
+}
+
+// EncodeJSON writes the Data representation of the given PN as JSON to the given writer. The
+// result is identical to encoding the result of ToDataWith but the JSON is produced while
+// traversing the PN so no intermediate Data is created. The output is terminated by a newline.
+func EncodeJSON(w io.Writer, pn PN, opts ...Option) (err error) {
+	e := &jsonEncoder{w: bufio.NewWriter(w), o: newOptions(opts)}
+	e.enc = json.NewEncoder(&e.buf)
+	e.enc.SetEscapeHTML(false)
+
+	defer func() {
+		if r := recover(); r != nil {
+			if je, ok := r.(*jsonError); ok {
+				err = je.cause
+			} else {
+				panic(r)
+			}
+		}
+	}()
+
+	if e.o.version {
+		e.w.WriteByte('{')
+		e.members(pn)
+		e.w.WriteString(`,"pn":`)
+		e.value(SCHEMA_VERSION)
+		e.w.WriteByte('}')
+	} else {
+		encodeJSON(pn, e)
+	}
+	e.w.WriteByte('\n')
+	return e.w.Flush()
+}
+
+type jsonError struct {
+	cause error
+}
+
+func encodeJSON(pn PN, e *jsonEncoder) {
+	if r, ok := pn.(renderer); ok {
+		r.encodeJSON(e)
+	} else {
+		e.value(pn.ToData())
+	}
+}
+
+// value writes a JSON value using the standard encoder so that strings and numbers are
+// encoded exactly as they would be if the Data representation was encoded
+func (e *jsonEncoder) value(v interface{}) {
+	e.buf.Reset()
+	if err := e.enc.Encode(v); err != nil {
+		panic(&jsonError{err})
+	}
+	// Strip the newline that the encoder appends
+	e.w.Write(e.buf.Bytes()[:e.buf.Len()-1])
+}
+
+func (e *jsonEncoder) position(l Located) {
+	e.w.WriteString(`"@":[`)
+	e.w.WriteString(strconv.Itoa(l.Line()))
+	e.w.WriteByte(',')
+	e.w.WriteString(strconv.Itoa(l.Pos()))
+	e.w.WriteByte(',')
+	e.w.WriteString(strconv.Itoa(l.ByteOffset()))
+	e.w.WriteByte(',')
+	e.w.WriteString(strconv.Itoa(l.ByteLength()))
+	e.w.WriteByte(']')
+}
+
+func (e *jsonEncoder) elements(elements []PN) {
+	for idx, elem := range elements {
+		if idx > 0 {
+			e.w.WriteByte(',')
+		}
+		encodeJSON(elem, e)
+	}
+}
+
+func (pn *listPN) encodeJSON(e *jsonEncoder) {
+	e.w.WriteByte('[')
+	e.elements(pn.elements)
+	e.w.WriteByte(']')
+}
+
+func (pn *callPN) encodeJSON(e *jsonEncoder) {
+	e.w.WriteByte('{')
+	pn.encodeArgs(e)
+	e.w.WriteByte('}')
+}
+
+func (pn *callPN) encodeArgs(e *jsonEncoder) {
+	e.w.WriteString(`"^":[`)
+	e.value(pn.name)
+	if len(pn.elements) > 0 {
+		e.w.WriteByte(',')
+		e.elements(pn.elements)
+	}
+	e.w.WriteByte(']')
+}
+
+func (pn *mapPN) encodeJSON(e *jsonEncoder) {
+	e.w.WriteByte('{')
+	pn.encodeEntries(e)
+	e.w.WriteByte('}')
+}
+
+func (pn *mapPN) encodeEntries(e *jsonEncoder) {
+	e.w.WriteString(`"#":[`)
+	for idx, entry := range pn.entries {
+		if idx > 0 {
+			e.w.WriteByte(',')
+		}
+		e.value(entry.Key())
+		e.w.WriteByte(',')
+		encodeJSON(entry.Value(), e)
+	}
+	e.w.WriteByte(']')
+}
+
+func (pn *literalPN) encodeJSON(e *jsonEncoder) {
+	e.value(pn.val)
+}
+
+func (pn *lazyPN) encodeJSON(e *jsonEncoder) {
+	encodeJSON(pn.produce(), e)
+}
+
+func (pn *locatedPN) encodeJSON(e *jsonEncoder) {
+	if !e.o.positions && pn.annotations(e.o) == nil {
+		encodeJSON(pn.PN, e)
+		return
+	}
+	e.w.WriteByte('{')
+	e.members(pn)
+	e.w.WriteByte('}')
+}
+
+// members writes the members of the JSON object that represents the given node. The keys are
+// written in the order that they are written by the standard encoder, i.e. sorted.
+func (e *jsonEncoder) members(pn PN) {
+	switch n := resolve(pn).(type) {
+	case *callPN:
+		n.encodeArgs(e)
+	case *mapPN:
+		n.encodeEntries(e)
+	case *locatedPN:
+		if a := n.annotations(e.o); a != nil {
+			e.w.WriteString(`"!":`)
+			a.encodeJSON(e)
+			e.w.WriteByte(',')
+		}
+		inner := resolve(n.PN)
+		if !e.o.positions {
+			e.members(inner)
+			return
+		}
+		if _, ok := inner.(*callPN); ok {
+			e.position(n.location)
+			e.w.WriteByte(',')
+			e.members(inner)
+		} else {
+			e.members(inner)
+			e.w.WriteByte(',')
+			e.position(n.location)
+		}
+	default:
+		e.w.WriteString(`"=":`)
+		encodeJSON(n, e)
+	}
+}
@@ -73,16 +73,103 @@ type (
 		listPN
 		name string
 	}
+
+	// Located is implemented by values that know their position in a source text, such
+	// as the expressions produced by the parser.
+	Located interface {
+		issue.Location
+
+		ByteOffset() int
+
+		ByteLength() int
+	}
+
+	// Annotated is implemented by a Located that has annotations. The annotations are included
+	// in the output when the WITH_ANNOTATIONS option is used.
+	Annotated interface {
+		AnnotationKeys() []string
+
+		Annotation(key string) (interface{}, bool)
+	}
+
+	locatedPN struct {
+		PN
+		location Located
+	}
+
+	lazyPN struct {
+		produce func() PN
+	}
+
+	// Option controls what FormatWith and ToDataWith includes in their output
+	Option int
+
+	options struct {
+		positions   bool
+		version     bool
+		annotations bool
+	}
+
+	// renderer is implemented by all nodes in this package
+	renderer interface {
+		format(b *bytes.Buffer, o *options)
+
+		toData(o *options) interface{}
+
+		encodeJSON(e *jsonEncoder)
+	}
 )
 
+// WITH_POSITIONS includes the line, position on line, byte offset, and byte length of every
+// located node in the output.
+//
+// The string representation is prefixed with the position as a list, e.g.
+//
+//	^[1 3 2 5] (qn "hello")
+//
+// The Data representation adds the key '@' to the Call or Map that represents the node, e.g.
+//
+//	{"^": ["qn", "hello"], "@": [1, 3, 2, 5]}
+//
+// and a located node of other kinds is wrapped in a Hash using the key '=', e.g.
+//
+//	{"=": "hello", "@": [1, 3, 2, 5]}
+const WITH_POSITIONS = Option(1)
+
+// WITH_SCHEMA_VERSION adds the key 'pn' with the value SCHEMA_VERSION to the Data representation
+// of the root node. A root node that isn't represented by a Hash is wrapped in a Hash using the
+// key '='. The option has no effect on the string representation.
+const WITH_SCHEMA_VERSION = Option(2)
+
+// WITH_ANNOTATIONS includes the annotations of every located node that is Annotated. An annotation
+// value that is a PN is included verbatim and other values are included as literals.
+//
+// The string representation is prefixed with the annotations as a map, e.g.
+//
+//	^{:type "Integer"} (var "x")
+//
+// The Data representation adds the key '!' to the Call or Map that represents the node, e.g.
+//
+//	{"!": {"#": ["type", "Integer"]}, "^": ["var", "x"]}
+//
+// and a located node of other kinds is wrapped in a Hash using the key '='.
+const WITH_ANNOTATIONS = Option(3)
+
+var noOptions = &options{}
+
 var keyPattern = regexp.MustCompile(`^[A-Za-z_-][0-9A-Za-z_-]*$`)
 
-// Represent the Reported using Puppet Extended S-Expresssion Notation (PN)
+// Represent the Reported using Puppet Extended S-Expresssion Notation (PN). The result is a map with the
+// code, severity, and message of the issue, and the url of its documentation when DocURL returns one.
 func ReportedToPN(ri issue.Reported) PN {
-	return Map([]Entry{
+	entries := []Entry{
 		Literal(ri.Code()).WithName(`code`),
 		Literal(ri.Severity().String()).WithName(`severity`),
-		Literal(ri.Error()).WithName(`message`)})
+		Literal(ri.Error()).WithName(`message`)}
+	if url := DocURL(ri.Code()); url != `` {
+		entries = append(entries, Literal(url).WithName(`url`))
+	}
+	return Map(entries)
 }
 
 func (e *pnError) Error() string {
@@ -103,6 +190,11 @@ func Map(entries []Entry) PN {
 	return &mapPN{entries}
 }
 
+// ValidKey returns true if the given string is valid as a key in a Map
+func ValidKey(key string) bool {
+	return keyPattern.MatchString(key)
+}
+
 func Literal(val interface{}) PN {
 	return &literalPN{val}
 }
@@ -111,6 +203,73 @@ func Call(name string, elements ...PN) PN {
 	return &callPN{listPN{elements}, name}
 }
 
+// Lazy returns a PN that renders like the PN returned by the given function. The function is called
+// each time the PN is rendered, so a tree that is built from lazy nodes is produced one node at a time
+// while it is rendered and is never held in memory in full.
+func Lazy(produce func() PN) PN {
+	return &lazyPN{produce}
+}
+
+// Locate returns a PN that renders like the given PN but also knows the position of the
+// source that it was created from. The position is only included in the output when the
+// PN is rendered using the WITH_POSITIONS option.
+func Locate(pn PN, location Located) PN {
+	if lp, ok := pn.(*locatedPN); ok {
+		pn = lp.PN
+	}
+	return &locatedPN{pn, location}
+}
+
+// FormatWith is like PN.Format but allows options that control the output
+func FormatWith(pn PN, b *bytes.Buffer, opts ...Option) {
+	format(pn, b, newOptions(opts))
+}
+
+// ToDataWith is like PN.ToData but allows options that control the output
+func ToDataWith(pn PN, opts ...Option) interface{} {
+	o := newOptions(opts)
+	data := toData(pn, o)
+	if o.version {
+		m, ok := data.(map[string]interface{})
+		if !ok {
+			m = map[string]interface{}{`=`: data}
+		}
+		m[`pn`] = SCHEMA_VERSION
+		data = m
+	}
+	return data
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		switch opt {
+		case WITH_POSITIONS:
+			o.positions = true
+		case WITH_SCHEMA_VERSION:
+			o.version = true
+		case WITH_ANNOTATIONS:
+			o.annotations = true
+		}
+	}
+	return o
+}
+
+func format(pn PN, b *bytes.Buffer, o *options) {
+	if r, ok := pn.(renderer); ok {
+		r.format(b, o)
+	} else {
+		pn.Format(b)
+	}
+}
+
+func toData(pn PN, o *options) interface{} {
+	if r, ok := pn.(renderer); ok {
+		return r.toData(o)
+	}
+	return pn.ToData()
+}
+
 func ToString(pn PN) string {
 	b := bytes.NewBufferString(``)
 	pn.Format(b)
@@ -126,15 +285,23 @@ func (pn *listPN) AsParameters() []PN {
 }
 
 func (pn *listPN) Format(b *bytes.Buffer) {
+	pn.format(b, noOptions)
+}
+
+func (pn *listPN) format(b *bytes.Buffer, o *options) {
 	b.WriteByte('[')
-	formatElements(pn.elements, b)
+	formatElements(pn.elements, b, o)
 	b.WriteByte(']')
 }
 
 func (pn *listPN) ToData() interface{} {
+	return pn.toData(noOptions)
+}
+
+func (pn *listPN) toData(o *options) interface{} {
 	me := make([]interface{}, len(pn.elements))
 	for idx, op := range pn.elements {
-		me[idx] = op.ToData()
+		me[idx] = toData(op, o)
 	}
 	return me
 }
@@ -156,21 +323,29 @@ func (pn *callPN) AsParameters() []PN {
 }
 
 func (pn *callPN) Format(b *bytes.Buffer) {
+	pn.format(b, noOptions)
+}
+
+func (pn *callPN) format(b *bytes.Buffer, o *options) {
 	b.WriteByte('(')
 	b.WriteString(pn.name)
 	if len(pn.elements) > 0 {
 		b.WriteByte(' ')
-		formatElements(pn.elements, b)
+		formatElements(pn.elements, b, o)
 	}
 	b.WriteByte(')')
 }
 
 func (pn *callPN) ToData() interface{} {
+	return pn.toData(noOptions)
+}
+
+func (pn *callPN) toData(o *options) interface{} {
 	top := len(pn.elements)
 	args := make([]interface{}, 0, top+1)
 	args = append(args, pn.name)
 	if top > 0 {
-		params := pn.listPN.ToData()
+		params := pn.listPN.toData(o)
 		args = append(args, params.([]interface{})...)
 	}
 	return map[string]interface{}{`^`: args}
@@ -201,29 +376,37 @@ func (pn *mapPN) AsParameters() []PN {
 }
 
 func (pn *mapPN) Format(b *bytes.Buffer) {
+	pn.format(b, noOptions)
+}
+
+func (pn *mapPN) format(b *bytes.Buffer, o *options) {
 	b.WriteByte('{')
 	if top := len(pn.entries); top > 0 {
-		formatEntry(pn.entries[0], b)
+		formatEntry(pn.entries[0], b, o)
 		for idx := 1; idx < top; idx++ {
 			b.WriteByte(' ')
-			formatEntry(pn.entries[idx], b)
+			formatEntry(pn.entries[idx], b, o)
 		}
 	}
 	b.WriteByte('}')
 }
 
-func formatEntry(entry Entry, b *bytes.Buffer) {
+func formatEntry(entry Entry, b *bytes.Buffer, o *options) {
 	b.WriteByte(':')
 	b.WriteString(entry.Key())
 	b.WriteByte(' ')
-	entry.Value().Format(b)
+	format(entry.Value(), b, o)
 }
 
 func (pn *mapPN) ToData() interface{} {
+	return pn.toData(noOptions)
+}
+
+func (pn *mapPN) toData(o *options) interface{} {
 	top := len(pn.entries) * 2
 	args := make([]interface{}, 0, top)
 	for _, entry := range pn.entries {
-		args = append(args, entry.Key(), entry.Value().ToData())
+		args = append(args, entry.Key(), toData(entry.Value(), o))
 	}
 	return map[string]interface{}{`#`: args}
 }
@@ -249,6 +432,10 @@ func (pn *literalPN) AsParameters() []PN {
 var STRIP_TRAILING_ZEROES = regexp.MustCompile("\\A(.*(?:\\.0|[1-9]))0+(e[+-]?\\d+)?\\z")
 
 func (pn *literalPN) Format(b *bytes.Buffer) {
+	pn.format(b, noOptions)
+}
+
+func (pn *literalPN) format(b *bytes.Buffer, o *options) {
 	switch pn.val.(type) {
 	case nil:
 		b.WriteString(`nil`)
@@ -282,6 +469,10 @@ func (pn *literalPN) ToData() interface{} {
 	return pn.val
 }
 
+func (pn *literalPN) toData(o *options) interface{} {
+	return pn.val
+}
+
 func (pn *literalPN) String() string {
 	return ToString(pn)
 }
@@ -290,13 +481,119 @@ func (pn *literalPN) WithName(name string) Entry {
 	return &mapEntry{name, pn}
 }
 
-func formatElements(elements []PN, b *bytes.Buffer) {
+func (pn *lazyPN) AsCall(name string) PN {
+	return pn.produce().AsCall(name)
+}
+
+func (pn *lazyPN) AsParameters() []PN {
+	return pn.produce().AsParameters()
+}
+
+func (pn *lazyPN) Format(b *bytes.Buffer) {
+	pn.format(b, noOptions)
+}
+
+func (pn *lazyPN) format(b *bytes.Buffer, o *options) {
+	format(pn.produce(), b, o)
+}
+
+func (pn *lazyPN) ToData() interface{} {
+	return pn.toData(noOptions)
+}
+
+func (pn *lazyPN) toData(o *options) interface{} {
+	return toData(pn.produce(), o)
+}
+
+func (pn *lazyPN) String() string {
+	return ToString(pn)
+}
+
+func (pn *lazyPN) WithName(name string) Entry {
+	return &mapEntry{name, pn}
+}
+
+// resolve returns the given PN or, if it is lazy, the PN that it produces
+func resolve(pn PN) PN {
+	for {
+		lp, ok := pn.(*lazyPN)
+		if !ok {
+			return pn
+		}
+		pn = lp.produce()
+	}
+}
+
+func (pn *locatedPN) format(b *bytes.Buffer, o *options) {
+	if o.positions {
+		l := pn.location
+		fmt.Fprintf(b, `^[%d %d %d %d] `, l.Line(), l.Pos(), l.ByteOffset(), l.ByteLength())
+	}
+	if a := pn.annotations(o); a != nil {
+		b.WriteByte('^')
+		a.format(b, o)
+		b.WriteByte(' ')
+	}
+	format(pn.PN, b, o)
+}
+
+func (pn *locatedPN) toData(o *options) interface{} {
+	data := toData(pn.PN, o)
+	a := pn.annotations(o)
+	if !o.positions && a == nil {
+		return data
+	}
+	m, ok := data.(map[string]interface{})
+	if !ok {
+		m = map[string]interface{}{`=`: data}
+	}
+	if o.positions {
+		l := pn.location
+		m[`@`] = []interface{}{l.Line(), l.Pos(), l.ByteOffset(), l.ByteLength()}
+	}
+	if a != nil {
+		m[`!`] = a.toData(o)
+	}
+	return m
+}
+
+// annotations returns the annotations of the source of this node as a map or nil if the source has no
+// annotations or if annotations are not included in the output
+func (pn *locatedPN) annotations(o *options) *mapPN {
+	if !o.annotations {
+		return nil
+	}
+	a, ok := pn.location.(Annotated)
+	if !ok {
+		return nil
+	}
+	keys := a.AnnotationKeys()
+	if len(keys) == 0 {
+		return nil
+	}
+	entries := make([]Entry, len(keys))
+	for idx, key := range keys {
+		v, _ := a.Annotation(key)
+		vp, ok := v.(PN)
+		if !ok {
+			vp = Literal(v)
+		}
+		entries[idx] = &mapEntry{key, vp}
+	}
+	return &mapPN{entries}
+}
+
+func (pn *locatedPN) WithName(name string) Entry {
+	return &mapEntry{name, pn}
+}
+
+func formatElements(elements []PN, b *bytes.Buffer, o *options) {
 	top := len(elements)
 	if top > 0 {
-		elements[0].Format(b)
+		format(elements[0], b, o)
 		for idx := 1; idx < top; idx++ {
 			b.WriteByte(' ')
-			elements[idx].Format(b)
+			format(elements[idx], b, o)
 		}
 	}
 }
@@ -0,0 +1,57 @@
+package pn
+
+import "regexp"
+
+// REDACTED is the value that replaces redacted string literals
+const REDACTED = `[redacted]`
+
+// Redact returns a copy of the given PN where the value of each located string literal for which
+// the given function returns true is replaced by REDACTED. The structure of the PN, including the
+// positions, is retained. Literals that aren't located, such as the names of variables in a PN
+// created from an expression, are never redacted.
+func Redact(pn PN, redact func(source Located, value string) bool) PN {
+	switch n := resolve(pn).(type) {
+	case *locatedPN:
+		inner := resolve(n.PN)
+		if l, ok := inner.(*literalPN); ok {
+			if s, ok := l.val.(string); ok && redact(n.location, s) {
+				return &locatedPN{Literal(REDACTED), n.location}
+			}
+			return &locatedPN{l, n.location}
+		}
+		return &locatedPN{Redact(inner, redact), n.location}
+	case *callPN:
+		return &callPN{listPN{redactAll(n.elements, redact)}, n.name}
+	case *listPN:
+		return &listPN{redactAll(n.elements, redact)}
+	case *mapPN:
+		entries := make([]Entry, len(n.entries))
+		for idx, entry := range n.entries {
+			entries[idx] = &mapEntry{entry.Key(), Redact(entry.Value(), redact)}
+		}
+		return &mapPN{entries}
+	default:
+		return n
+	}
+}
+
+// RedactMatching returns a function for Redact that will redact values that match any of
+// the given patterns
+func RedactMatching(patterns ...*regexp.Regexp) func(source Located, value string) bool {
+	return func(source Located, value string) bool {
+		for _, p := range patterns {
+			if p.MatchString(value) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func redactAll(elements []PN, redact func(source Located, value string) bool) []PN {
+	result := make([]PN, len(elements))
+	for idx, elem := range elements {
+		result[idx] = Redact(elem, redact)
+	}
+	return result
+}
@@ -0,0 +1,236 @@
+package pn
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SCHEMA_VERSION is the version of the PN schema. The minor number is increased when tags or
+// other additions are made that are backward compatible and the major number is increased when
+// a change is made that consumers cannot handle without modification.
+const SCHEMA_VERSION = `1.1`
+
+// The JSON Schema that describes the Data representation of a PN
+//
+//go:embed schema.json
+var schema []byte
+
+var tags map[string]bool
+
+func init() {
+	var s struct {
+		Version     string
+		Definitions struct {
+			Tag struct {
+				Enum []string
+			}
+		}
+	}
+	if err := json.Unmarshal(schema, &s); err != nil {
+		panic(err)
+	}
+	if s.Version != SCHEMA_VERSION {
+		panic(fmt.Sprintf("schema.json has version %s, expected %s", s.Version, SCHEMA_VERSION))
+	}
+	tags = make(map[string]bool, len(s.Definitions.Tag.Enum))
+	for _, tag := range s.Definitions.Tag.Enum {
+		tags[tag] = true
+	}
+}
+
+// Schema returns the JSON Schema that describes the Data representation of a PN
+func Schema() []byte {
+	return schema
+}
+
+// IsTag returns true if the given name is the name of a call in the PN vocabulary
+func IsTag(name string) bool {
+	return tags[name]
+}
+
+// CheckSchemaVersion returns an error unless a PN produced using the given schema version can be
+// consumed by this version, i.e. unless the major versions are equal and the given minor version
+// is less than or equal to the minor version of SCHEMA_VERSION.
+func CheckSchemaVersion(version string) error {
+	major, minor, ok := splitVersion(version)
+	if ok {
+		myMajor, myMinor, _ := splitVersion(SCHEMA_VERSION)
+		if major == myMajor && minor <= myMinor {
+			return nil
+		}
+	}
+	return &pnError{fmt.Sprintf("PN schema version %s is not compatible with version %s", version, SCHEMA_VERSION)}
+}
+
+func splitVersion(version string) (major, minor int, ok bool) {
+	parts := strings.Split(version, `.`)
+	if len(parts) != 2 {
+		return
+	}
+	var err error
+	if major, err = strconv.Atoi(parts[0]); err != nil {
+		return
+	}
+	if minor, err = strconv.Atoi(parts[1]); err != nil {
+		return
+	}
+	return major, minor, true
+}
+
+// ValidateData returns an error if the given value isn't a valid Data representation of a PN
+// according to the schema. The value is typically obtained by unmarshalling JSON. If the root
+// node has a schema version marker, then that version is checked using CheckSchemaVersion.
+func ValidateData(data interface{}) error {
+	if m, ok := data.(map[string]interface{}); ok {
+		if v, ok := m[`pn`]; ok {
+			version, ok := v.(string)
+			if !ok {
+				return validationError(``, `schema version must be a string`)
+			}
+			if err := CheckSchemaVersion(version); err != nil {
+				return err
+			}
+			c := make(map[string]interface{}, len(m)-1)
+			for k, v := range m {
+				if k != `pn` {
+					c[k] = v
+				}
+			}
+			if w, ok := c[`=`]; ok && len(c) == 1 {
+				// Root that was wrapped only to make room for the version
+				data = w
+			} else {
+				data = c
+			}
+		}
+	}
+	return validateNode(``, data)
+}
+
+func validationError(path string, format string, args ...interface{}) error {
+	if path == `` {
+		path = `/`
+	}
+	return &pnError{fmt.Sprintf(`invalid PN at %s: %s`, path, fmt.Sprintf(format, args...))}
+}
+
+func validateNode(path string, data interface{}) error {
+	switch data := data.(type) {
+	case nil, bool, string, float32, float64, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, json.Number:
+		return nil
+	case []interface{}:
+		return validateList(path, data)
+	case map[string]interface{}:
+		return validateObject(path, data)
+	default:
+		return validationError(path, `unexpected value of type %T`, data)
+	}
+}
+
+func validateList(path string, elements []interface{}) error {
+	for idx, elem := range elements {
+		if err := validateNode(fmt.Sprintf(`%s/%d`, path, idx), elem); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateObject(path string, m map[string]interface{}) error {
+	kind := ``
+	for k, v := range m {
+		var err error
+		switch k {
+		case `^`, `#`, `=`:
+			if kind != `` {
+				return validationError(path, `both '%s' and '%s' present`, kind, k)
+			}
+			kind = k
+			continue
+		case `@`:
+			err = validatePosition(path+`/@`, v)
+		case `!`:
+			if a, ok := v.(map[string]interface{}); ok && len(a) == 1 && a[`#`] != nil {
+				err = validateObject(path+`/!`, a)
+			} else {
+				err = validationError(path+`/!`, `annotations must be a map`)
+			}
+		default:
+			err = validationError(path, `unexpected key '%s'`, k)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	path = path + `/` + kind
+	v := m[kind]
+	switch kind {
+	case `^`:
+		args, ok := v.([]interface{})
+		if !ok || len(args) == 0 {
+			return validationError(path, `call must be a non empty array`)
+		}
+		if name, ok := args[0].(string); !ok || !IsTag(name) {
+			return validationError(path+`/0`, `unknown tag %v`, args[0])
+		}
+		for idx := 1; idx < len(args); idx++ {
+			if err := validateNode(fmt.Sprintf(`%s/%d`, path, idx), args[idx]); err != nil {
+				return err
+			}
+		}
+	case `#`:
+		entries, ok := v.([]interface{})
+		if !ok || len(entries)%2 != 0 {
+			return validationError(path, `map must be an array with an even number of elements`)
+		}
+		for idx := 0; idx < len(entries); idx += 2 {
+			if key, ok := entries[idx].(string); !ok || !keyPattern.MatchString(key) {
+				return validationError(fmt.Sprintf(`%s/%d`, path, idx), `invalid key %v`, entries[idx])
+			}
+			if err := validateNode(fmt.Sprintf(`%s/%d`, path, idx+1), entries[idx+1]); err != nil {
+				return err
+			}
+		}
+	case `=`:
+		_, hasPos := m[`@`]
+		_, hasAnnotations := m[`!`]
+		if !(hasPos || hasAnnotations) {
+			return validationError(path, `wrapped value without position or annotations`)
+		}
+		return validateNode(path, v)
+	default:
+		return validationError(path, `object is neither a call, a map, nor a wrapped value`)
+	}
+	return nil
+}
+
+func validatePosition(path string, v interface{}) error {
+	pos, ok := v.([]interface{})
+	ok = ok && len(pos) == 4
+	if ok {
+		for _, p := range pos {
+			switch p := p.(type) {
+			case int:
+				ok = p >= 0
+			case float64:
+				ok = p >= 0 && p == float64(int(p))
+			case json.Number:
+				_, err := strconv.Atoi(string(p))
+				ok = err == nil
+			default:
+				ok = false
+			}
+			if !ok {
+				break
+			}
+		}
+	}
+	if !ok {
+		return validationError(path, `position must be an array of four non negative integers`)
+	}
+	return nil
+}
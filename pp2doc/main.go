@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-parser/docgen"
+	"github.com/lyraproj/puppet-parser/json"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+// Program that generates a REFERENCE.md document from the documentation comments of the classes, defined
+// types, functions, type aliases, and plans declared in .pp files, e.g.
+//
+//	pp2doc -o REFERENCE.md manifests/*.pp functions/*.pp types/*.pp
+var output = flag.String("o", ``, "output file (defaults to stdout)")
+var jsonOutput = flag.Bool("j", false, "output the documentation model as JSON instead of Markdown")
+
+func main() {
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: pp2doc [options] <pp files to read>\nValid options are:")
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+
+	roots := make([]parser.Expression, 0, len(args))
+	for _, fileName := range args {
+		content, err := ioutil.ReadFile(fileName)
+		if err != nil {
+			panic(err)
+		}
+		expr, err := parser.CreateParser(parser.PARSER_TASKS_ENABLED).Parse(fileName, string(content), false)
+		if err != nil {
+			if _, ok := err.(issue.Reported); ok {
+				fmt.Fprintln(os.Stderr, err.Error())
+				os.Exit(1)
+			}
+			panic(err)
+		}
+		roots = append(roots, expr)
+	}
+
+	b := bytes.NewBufferString(``)
+	ref := docgen.Extract(roots...)
+	if *jsonOutput {
+		json.ToJson(ref.ToData(), b)
+	} else {
+		ref.Markdown(b)
+	}
+	if *output == `` {
+		os.Stdout.Write(b.Bytes())
+	} else if err := ioutil.WriteFile(*output, b.Bytes(), 0644); err != nil {
+		panic(err)
+	}
+}
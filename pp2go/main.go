@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-parser/parser"
+	"github.com/lyraproj/puppet-parser/typegen"
+)
+
+// Program that generates Go types from the type aliases declared in .pp files. Intended for use
+// with go:generate, e.g.
+//
+//	//go:generate pp2go -o config.go ../manifests/types/config.pp
+var pkg = flag.String("p", os.Getenv(`GOPACKAGE`), "name of generated package (defaults to $GOPACKAGE)")
+var output = flag.String("o", ``, "output file (defaults to stdout)")
+
+func main() {
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) == 0 || *pkg == `` {
+		fmt.Fprintln(os.Stderr, "Usage: pp2go [options] <pp files to read>\nValid options are:")
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+
+	g := typegen.NewGenerator(*pkg)
+	for _, fileName := range args {
+		content, err := ioutil.ReadFile(fileName)
+		if err != nil {
+			panic(err)
+		}
+		expr, err := parser.CreateParser().Parse(fileName, string(content), false)
+		if err != nil {
+			if _, ok := err.(issue.Reported); ok {
+				fmt.Fprintln(os.Stderr, err.Error())
+				os.Exit(1)
+			}
+			panic(err)
+		}
+		g.Add(expr)
+	}
+
+	b := bytes.NewBufferString(``)
+	if err := g.Generate(b); err != nil {
+		panic(err)
+	}
+	if *output == `` {
+		os.Stdout.Write(b.Bytes())
+	} else if err := ioutil.WriteFile(*output, b.Bytes(), 0644); err != nil {
+		panic(err)
+	}
+}
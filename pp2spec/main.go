@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-parser/parser"
+	"github.com/lyraproj/puppet-parser/specgen"
+)
+
+// Program that generates skeleton rspec-puppet spec files for the classes and defined types declared in
+// .pp files. Existing spec files are regenerated by replacing their generated part, e.g.
+//
+//	pp2spec -m . manifests/*.pp
+var moduleDir = flag.String("m", `.`, "root directory of the module where the spec files are written")
+var dryRun = flag.Bool("n", false, "print the paths of the spec files that would be written")
+
+func main() {
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: pp2spec [options] <pp files to read>\nValid options are:")
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+
+	for _, fileName := range args {
+		content, err := ioutil.ReadFile(fileName)
+		if err != nil {
+			panic(err)
+		}
+		expr, err := parser.CreateParser(parser.PARSER_TASKS_ENABLED).Parse(fileName, string(content), false)
+		if err != nil {
+			if _, ok := err.(issue.Reported); ok {
+				fmt.Fprintln(os.Stderr, err.Error())
+				os.Exit(1)
+			}
+			panic(err)
+		}
+		for _, spec := range specgen.Generate(expr) {
+			write(spec)
+		}
+	}
+}
+
+func write(spec *specgen.Spec) {
+	path := filepath.Join(*moduleDir, filepath.FromSlash(spec.Path))
+	source := spec.Source
+	if existing, err := ioutil.ReadFile(path); err == nil {
+		merged, ok := specgen.Merge(string(existing), spec)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "%s: skipped since it has no generated part\n", path)
+			return
+		}
+		if merged == string(existing) {
+			return
+		}
+		source = merged
+	}
+	fmt.Println(path)
+	if *dryRun {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		panic(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(source), 0644); err != nil {
+		panic(err)
+	}
+}
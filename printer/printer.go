@@ -0,0 +1,993 @@
+// Package printer produces Puppet source from an AST.
+//
+// The printed source uses a canonical layout with two space indentation. Parsing the printed
+// source yields an AST that is equal to the printed AST except for positions. Parentheses are
+// added when needed to retain the structure of an AST that was created programmatically.
+package printer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+// Lines are kept within this width when possible
+const maxWidth = 80
+
+// Operator precedence, lowest first. Matches the order of the recursive descent in the parser
+const (
+	precRelationship = iota
+	precAssignment
+	precResource
+	precSelector
+	precOr
+	precAnd
+	precCompare
+	precEqual
+	precShift
+	precAdditive
+	precMultiplicative
+	precMatch
+	precIn
+	precUnary
+	precPrimary
+)
+
+type (
+	printer struct {
+		b        bytes.Buffer
+		indent   int
+		bol      bool
+		epp      bool
+		heredocs []*pendingHeredoc
+
+		// names are the names of the enclosing definitions that the parser qualifies the names of
+		// nested classes and activities with
+		names []string
+	}
+
+	pendingHeredoc struct {
+		expr   *parser.HeredocExpression
+		indent int
+	}
+)
+
+// Print writes the Puppet source that represents the given expression to the given writer
+func Print(w io.Writer, e parser.Expression) error {
+	_, err := io.WriteString(w, String(e))
+	return err
+}
+
+// String returns the Puppet source that represents the given expression. The source of a
+// Program or a Block is terminated with a newline.
+func String(e parser.Expression) string {
+	p := &printer{}
+	p.top(e)
+	if len(p.heredocs) > 0 {
+		p.nl()
+	}
+	return p.b.String()
+}
+
+func (p *printer) top(e parser.Expression) {
+	switch e := e.(type) {
+	case *parser.Program:
+		p.top(e.Body())
+	case *parser.LambdaExpression:
+		if epp, ok := e.Body().(*parser.EppExpression); ok {
+			p.eppTemplate(e, epp)
+		} else {
+			p.expr(e, precRelationship)
+		}
+	case *parser.BlockExpression:
+		if len(e.Statements()) > 0 {
+			p.statements(e.Statements())
+			p.nl()
+		}
+	case nil, *parser.Nop:
+	default:
+		p.expr(e, precRelationship)
+	}
+}
+
+func (p *printer) write(s string) {
+	if p.bol && s != `` {
+		p.b.WriteString(strings.Repeat(`  `, p.indent))
+		p.bol = false
+	}
+	p.b.WriteString(s)
+}
+
+// nl ends the current line and writes the text of all heredocs that were started on that line
+func (p *printer) nl() {
+	p.b.WriteByte('\n')
+	heredocs := p.heredocs
+	p.heredocs = nil
+	for _, h := range heredocs {
+		p.heredocText(h)
+	}
+	p.bol = true
+}
+
+func (p *printer) column() int {
+	if p.bol {
+		return p.indent * 2
+	}
+	bs := p.b.Bytes()
+	return len(bs) - (bytes.LastIndexByte(bs, '\n') + 1)
+}
+
+// oneLine returns the given expression rendered on one line and true, or false when that
+// isn't possible
+func (p *printer) oneLine(e parser.Expression, min int) (string, bool) {
+	s := &printer{epp: p.epp}
+	s.operand(e, min)
+	r := s.b.String()
+	if len(s.heredocs) > 0 || strings.IndexByte(r, '\n') >= 0 {
+		return ``, false
+	}
+	return r, true
+}
+
+// fits returns the given expressions rendered on one line and true if they can be written on
+// the current line in the given form, i.e. separated by ', ' and enclosed in the given delimiters
+func (p *printer) fits(start, end string, exprs []parser.Expression, min int) ([]string, bool) {
+	w := p.column() + len(start) + len(end)
+	strs := make([]string, len(exprs))
+	for i, e := range exprs {
+		s, ok := p.oneLine(e, min)
+		if !ok {
+			return nil, false
+		}
+		if i > 0 {
+			w += 2
+		}
+		w += len(s)
+		if w > maxWidth {
+			return nil, false
+		}
+		strs[i] = s
+	}
+	return strs, true
+}
+
+// list writes the given expressions enclosed in the given delimiters. The expressions are written
+// on one line when they fit and one per line otherwise.
+func (p *printer) list(start, end string, exprs []parser.Expression, min int) {
+	if strs, ok := p.fits(start, end, exprs, min); ok {
+		p.write(start)
+		p.write(strings.Join(strs, `, `))
+		p.write(end)
+		return
+	}
+	p.write(start)
+	p.indent++
+	for _, e := range exprs {
+		p.nl()
+		p.operand(e, min)
+		p.write(`,`)
+	}
+	p.indent--
+	p.nl()
+	p.write(end)
+}
+
+// entries writes lines with keys and values separated by the given operators. Each line ends with
+// sep except the last which ends with lastSep. The operators are aligned when all keys can be
+// written on one line.
+func (p *printer) entries(keys []parser.Expression, ops []string, values []parser.Expression, sep, lastSep string, min int) {
+	strs := make([]string, len(keys))
+	width := 0
+	for i, k := range keys {
+		s, ok := p.oneLine(k, min)
+		if !ok {
+			strs = nil
+			break
+		}
+		strs[i] = s
+		if len(s) > width {
+			width = len(s)
+		}
+	}
+	last := len(keys) - 1
+	for i, k := range keys {
+		if i > 0 {
+			p.nl()
+		}
+		if strs == nil {
+			p.operand(k, min)
+		} else {
+			p.write(strs[i])
+			p.write(strings.Repeat(` `, width-len(strs[i])))
+		}
+		p.write(` ` + ops[i] + ` `)
+		p.operand(values[i], min)
+		if i == last {
+			p.write(lastSep)
+		} else {
+			p.write(sep)
+		}
+	}
+}
+
+func (p *printer) attributeOps(ops []parser.Expression, lastSep string) {
+	keys := make([]parser.Expression, len(ops))
+	operators := make([]string, len(ops))
+	values := make([]parser.Expression, len(ops))
+	for i, op := range ops {
+		switch op := op.(type) {
+		case *parser.AttributeOperation:
+			keys[i] = name(op.Name())
+			operators[i] = op.Operator()
+			values[i] = op.Value()
+		case *parser.AttributesOperation:
+			keys[i] = name(`*`)
+			operators[i] = `=>`
+			values[i] = op.Expr()
+		}
+	}
+	p.entries(keys, operators, values, `,`, lastSep, precSelector)
+}
+
+// name returns an expression that prints as the given name
+func name(n string) parser.Expression {
+	return parser.DefaultFactory().QualifiedName(n, nil, 0, 0)
+}
+
+func isDefinition(e parser.Expression) bool {
+	switch e.(type) {
+	case parser.Definition, *parser.Program:
+		return true
+	}
+	return false
+}
+
+func (p *printer) statements(stmts []parser.Expression) {
+	for i, s := range stmts {
+		if i > 0 {
+			p.nl()
+			if isDefinition(s) || isDefinition(stmts[i-1]) {
+				p.nl()
+			}
+		}
+		if ps, ok := s.(*parser.Program); ok {
+			// Part of a composed program
+			p.statements(statementsOf(ps.Body()))
+			continue
+		}
+		p.expr(s, precRelationship)
+	}
+}
+
+func statementsOf(e parser.Expression) []parser.Expression {
+	switch e := e.(type) {
+	case nil, *parser.Nop:
+		return []parser.Expression{}
+	case *parser.BlockExpression:
+		return e.Statements()
+	default:
+		return []parser.Expression{e}
+	}
+}
+
+// body writes a block enclosed in braces
+func (p *printer) body(e parser.Expression) {
+	stmts := statementsOf(e)
+	if p.epp && containsRender(stmts) {
+		p.write(`{ %>`)
+		p.eppStatements(stmts)
+		p.write(`<% }`)
+		return
+	}
+	if len(stmts) == 0 {
+		p.write(`{}`)
+		return
+	}
+	p.write(`{`)
+	p.indent++
+	p.nl()
+	p.statements(stmts)
+	p.indent--
+	p.nl()
+	p.write(`}`)
+}
+
+func containsRender(stmts []parser.Expression) bool {
+	for _, s := range stmts {
+		switch s.(type) {
+		case *parser.RenderExpression, *parser.RenderStringExpression:
+			return true
+		}
+	}
+	return false
+}
+
+func (p *printer) eppTemplate(l *parser.LambdaExpression, epp *parser.EppExpression) {
+	p.epp = true
+	if epp.ParametersSpecified() {
+		p.write(`<%- `)
+		p.params(`|`, `|`, l.Parameters())
+		p.write(" -%>\n")
+	}
+	p.eppStatements(statementsOf(epp.Body()))
+	p.epp = false
+}
+
+func (p *printer) eppStatements(stmts []parser.Expression) {
+	for _, s := range stmts {
+		switch s := s.(type) {
+		case *parser.RenderStringExpression:
+			p.b.WriteString(strings.Replace(s.StringValue(), `<%`, `<%%`, -1))
+		case *parser.RenderExpression:
+			p.write(`<%= `)
+			p.expr(s.Expr(), precRelationship)
+			p.write(` %>`)
+		default:
+			p.write(`<% `)
+			p.expr(s, precRelationship)
+			p.write(` %>`)
+		}
+	}
+}
+
+func (p *printer) params(start, end string, params []parser.Expression) {
+	if len(params) == 0 {
+		if start == `(` {
+			return
+		}
+		p.write(start + end)
+		return
+	}
+	p.list(start, end, params, precSelector)
+}
+
+// operand writes the given expression, enclosed in parentheses unless it has at least the given
+// precedence
+func (p *printer) operand(e parser.Expression, min int) {
+	if precedence(e) < min {
+		p.write(`(`)
+		p.expr(e, precRelationship)
+		p.write(`)`)
+	} else {
+		p.expr(e, min)
+	}
+}
+
+func precedence(e parser.Expression) int {
+	switch e := e.(type) {
+	case *parser.RelationshipExpression:
+		return precRelationship
+	case *parser.AssignmentExpression:
+		return precAssignment
+	case *parser.ResourceExpression, *parser.ResourceDefaultsExpression, *parser.ResourceOverrideExpression, *parser.CapabilityMapping:
+		return precResource
+	case *parser.SelectorExpression:
+		return precSelector
+	case *parser.OrExpression:
+		return precOr
+	case *parser.AndExpression:
+		return precAnd
+	case *parser.ComparisonExpression:
+		if e.Operator() == `==` || e.Operator() == `!=` {
+			return precEqual
+		}
+		return precCompare
+	case *parser.ArithmeticExpression:
+		switch e.Operator() {
+		case `<<`, `>>`:
+			return precShift
+		case `+`, `-`:
+			return precAdditive
+		default:
+			return precMultiplicative
+		}
+	case *parser.MatchExpression:
+		return precMatch
+	case *parser.InExpression:
+		return precIn
+	case *parser.NotExpression, *parser.UnaryMinusExpression, *parser.UnfoldExpression:
+		return precUnary
+	case *parser.LiteralInteger:
+		if e.Int() < 0 {
+			return precUnary
+		}
+	case *parser.LiteralFloat:
+		if e.Float() < 0 {
+			return precUnary
+		}
+	}
+	return precPrimary
+}
+
+// binary writes a binary expression. All binary operators except the relationship operators are
+// right associative in the parser so a left operand with the same precedence must be enclosed in
+// parentheses to retain the structure of the tree.
+func (p *printer) binary(op string, e parser.BinaryExpression) {
+	prec := precedence(e)
+	if prec == precRelationship {
+		p.operand(e.Lhs(), prec)
+		p.write(` ` + op + ` `)
+		p.operand(e.Rhs(), prec+1)
+	} else {
+		if op == `/` && precedence(e.Lhs()) > prec && endsWithRegexpPrefix(e.Lhs()) {
+			// The lexer would take the '/' for the start of a regular expression
+			p.write(`(`)
+			p.expr(e.Lhs(), precRelationship)
+			p.write(`)`)
+		} else {
+			p.operand(e.Lhs(), prec+1)
+		}
+		p.write(` ` + op + ` `)
+		p.operand(e.Rhs(), prec)
+	}
+}
+
+// endsWithRegexpPrefix returns true if the source of the given expression ends with a token after
+// which the lexer takes a '/' for the start of a regular expression rather than for a division, e.g.
+// a '}' or the keyword 'default'
+func endsWithRegexpPrefix(e parser.Expression) bool {
+	switch e := e.(type) {
+	case *parser.LiteralHash, *parser.LiteralDefault, *parser.LiteralUndef, *parser.LambdaExpression,
+		*parser.IfExpression, *parser.UnlessExpression, *parser.CaseExpression, *parser.SelectorExpression:
+		return true
+	case *parser.NotExpression:
+		return endsWithRegexpPrefix(e.Expr())
+	case *parser.UnaryMinusExpression:
+		return endsWithRegexpPrefix(e.Expr())
+	case *parser.CallNamedFunctionExpression:
+		return e.Lambda() != nil
+	case *parser.CallMethodExpression:
+		return e.Lambda() != nil
+	case parser.BinaryExpression:
+		return precedence(e.Rhs()) >= precedence(e) && endsWithRegexpPrefix(e.Rhs())
+	}
+	return false
+}
+
+func (p *printer) args(args []parser.Expression, lambda parser.Expression, parens bool) {
+	if parens || len(args) > 0 {
+		p.list(`(`, `)`, args, precRelationship)
+	}
+	if lambda != nil {
+		p.write(` `)
+		p.expr(lambda, precRelationship)
+	}
+}
+
+func (p *printer) expr(e parser.Expression, min int) {
+	switch e := e.(type) {
+	case *parser.AccessExpression:
+		p.operand(e.Operand(), precPrimary)
+		p.list(`[`, `]`, e.Keys(), precRelationship)
+	case *parser.ActivityExpression:
+		p.activity(e)
+	case *parser.AndExpression:
+		p.binary(`and`, e)
+	case *parser.Application:
+		p.namedDefinition(`application`, e, ``, nil)
+	case *parser.ArithmeticExpression:
+		p.binary(e.Operator(), e)
+	case *parser.AssignmentExpression:
+		p.binary(e.Operator(), e)
+	case *parser.AttributeOperation:
+		p.attributeOps([]parser.Expression{e}, ``)
+	case *parser.AttributesOperation:
+		p.attributeOps([]parser.Expression{e}, ``)
+	case *parser.BlockExpression:
+		p.body(e)
+	case *parser.CallMethodExpression:
+		if na, ok := e.Functor().(*parser.NamedAccessExpression); ok {
+			p.operand(na.Lhs(), precPrimary)
+			p.write(`.`)
+			p.expr(na.Rhs(), precPrimary)
+			p.args(e.Arguments(), e.Lambda(), false)
+		} else {
+			p.operand(e.Functor(), precPrimary)
+			p.args(e.Arguments(), e.Lambda(), true)
+		}
+	case *parser.CallNamedFunctionExpression:
+		p.operand(e.Functor(), precPrimary)
+		p.args(e.Arguments(), e.Lambda(), true)
+	case *parser.CallFunctionExpression:
+		p.operand(e.Functor(), precPrimary)
+		p.args(e.Arguments(), e.Lambda(), true)
+	case *parser.CapabilityMapping:
+		p.operand(e.Component(), precPrimary)
+		p.write(` ` + e.Kind() + ` ` + e.Capability() + ` `)
+		p.attributeBlock(e.Mappings())
+	case *parser.CaseExpression:
+		p.write(`case `)
+		p.operand(e.Test(), precSelector)
+		p.write(` {`)
+		p.indent++
+		for _, o := range e.Options() {
+			p.nl()
+			co := o.(*parser.CaseOption)
+			for i, v := range co.Values() {
+				if i > 0 {
+					p.write(`, `)
+				}
+				p.operand(v, precSelector)
+			}
+			p.write(`: `)
+			p.body(co.Then())
+		}
+		p.indent--
+		p.nl()
+		p.write(`}`)
+	case *parser.CollectExpression:
+		p.operand(e.ResourceType(), precPrimary)
+		q := e.Query().(parser.QueryExpression)
+		start, end := `<|`, `|>`
+		if _, ok := q.(*parser.ExportedQuery); ok {
+			start, end = `<<|`, `|>>`
+		}
+		p.write(` ` + start + ` `)
+		if qe := q.Expr(); qe != nil && !qe.IsNop() {
+			p.expr(qe, precRelationship)
+			p.write(` `)
+		}
+		p.write(end)
+		if len(e.Operations()) > 0 {
+			p.write(` `)
+			p.attributeBlock(e.Operations())
+		}
+	case *parser.ComparisonExpression:
+		p.binary(e.Operator(), e)
+	case *parser.ConcatenatedString:
+		p.write(`"`)
+		for _, s := range e.Segments() {
+			p.segment(s)
+		}
+		p.write(`"`)
+	case *parser.FunctionDefinition:
+		p.namedDefinition(`function`, e, ``, e.ReturnType())
+	case *parser.HeredocExpression:
+		p.heredoc(e)
+	case *parser.HostClassDefinition:
+		p.namedDefinition(`class`, e, e.ParentClass(), nil)
+	case *parser.IfExpression:
+		p.ifExpression(`if`, e.Test(), e.Then(), e.Else())
+	case *parser.ImportExpression:
+		p.write(`import `)
+		for i, pattern := range e.Patterns() {
+			if i > 0 {
+				p.write(`, `)
+			}
+			p.expr(pattern, precRelationship)
+		}
+	case *parser.InExpression:
+		p.binary(`in`, e)
+	case *parser.KeyedEntry:
+		p.operand(e.Key(), precRelationship)
+		p.write(` => `)
+		p.operand(e.Value(), precRelationship)
+	case *parser.LambdaExpression:
+		p.params(`|`, `|`, e.Parameters())
+		if e.ReturnType() != nil {
+			p.write(` >> `)
+			p.operand(e.ReturnType(), precPrimary)
+		}
+		p.write(` `)
+		p.body(e.Body())
+	case *parser.LiteralBoolean:
+		p.write(strconv.FormatBool(e.Bool()))
+	case *parser.LiteralDefault:
+		p.write(`default`)
+	case *parser.LiteralFloat:
+		p.write(formatFloat(e.Float()))
+	case *parser.LiteralHash:
+		p.hash(e.Entries())
+	case *parser.LiteralInteger:
+		p.write(formatInt(e.Int(), e.Radix()))
+	case *parser.LiteralList:
+		p.list(`[`, `]`, e.Elements(), precRelationship)
+	case *parser.RenderStringExpression:
+		p.write(Quote(e.StringValue()))
+	case *parser.LiteralString:
+		p.write(Quote(e.StringValue()))
+	case *parser.LiteralUndef:
+		p.write(`undef`)
+	case *parser.MatchExpression:
+		p.binary(e.Operator(), e)
+	case *parser.NamedAccessExpression:
+		p.operand(e.Lhs(), precPrimary)
+		p.write(`.`)
+		p.expr(e.Rhs(), precPrimary)
+	case *parser.NodeDefinition:
+		p.write(`node `)
+		for i, h := range e.HostMatches() {
+			if i > 0 {
+				p.write(`, `)
+			}
+			p.expr(h, precPrimary)
+		}
+		if e.Parent() != nil {
+			p.write(` inherits `)
+			p.expr(e.Parent(), precPrimary)
+		}
+		p.write(` `)
+		p.body(e.Body())
+	case *parser.Nop:
+	case *parser.NotExpression:
+		p.write(`!`)
+		p.operand(e.Expr(), precUnary)
+	case *parser.OrExpression:
+		p.binary(`or`, e)
+	case *parser.Parameter:
+		if e.Type() != nil {
+			p.operand(e.Type(), precPrimary)
+			p.write(` `)
+		}
+		if e.CapturesRest() {
+			p.write(`*`)
+		}
+		p.write(`$` + e.Name())
+		if e.Value() != nil {
+			p.write(` = `)
+			p.operand(e.Value(), precSelector)
+		}
+	case *parser.ParenthesizedExpression:
+		p.write(`(`)
+		p.expr(e.Expr(), precRelationship)
+		p.write(`)`)
+	case *parser.PlanDefinition:
+		p.namedDefinition(`plan`, e, ``, e.ReturnType())
+	case *parser.Program:
+		p.top(e)
+	case *parser.QualifiedName:
+		p.write(e.Name())
+	case *parser.QualifiedReference:
+		p.write(e.Name())
+	case *parser.RegexpExpression:
+		p.write(regexpLiteral(e.Value().(string)))
+	case *parser.RelationshipExpression:
+		p.binary(e.Operator(), e)
+	case *parser.RenderExpression:
+		p.write(`<%= `)
+		p.expr(e.Expr(), precRelationship)
+		p.write(` %>`)
+	case *parser.ReservedWord:
+		p.write(e.Name())
+	case *parser.ResourceDefaultsExpression:
+		p.form(e.Form())
+		p.operand(e.TypeRef(), precPrimary)
+		p.write(` `)
+		p.attributeBlock(e.Operations())
+	case *parser.ResourceExpression:
+		p.resource(e)
+	case *parser.ResourceOverrideExpression:
+		p.form(e.Form())
+		p.operand(e.Resources(), precPrimary)
+		p.write(` `)
+		p.attributeBlock(e.Operations())
+	case *parser.ResourceTypeDefinition:
+		p.namedDefinition(`define`, e, ``, nil)
+	case *parser.SelectorExpression:
+		p.operand(e.Lhs(), precOr)
+		p.write(` ? {`)
+		p.indent++
+		p.nl()
+		sels := e.Selectors()
+		keys := make([]parser.Expression, len(sels))
+		ops := make([]string, len(sels))
+		values := make([]parser.Expression, len(sels))
+		for i, s := range sels {
+			se := s.(*parser.SelectorEntry)
+			keys[i] = se.Matching()
+			ops[i] = `=>`
+			values[i] = se.Value()
+		}
+		p.entries(keys, ops, values, `,`, `,`, precSelector)
+		p.indent--
+		p.nl()
+		p.write(`}`)
+	case *parser.SiteDefinition:
+		p.write(`site `)
+		p.body(e.Body())
+	case *parser.TextExpression:
+		p.write(`"`)
+		p.segment(e)
+		p.write(`"`)
+	case *parser.TypeAlias:
+		p.write(`type ` + e.Name() + ` = `)
+		p.expr(e.Type(), precRelationship)
+	case *parser.TypeDefinition:
+		p.write(`type ` + e.Name())
+		if e.Parent() != `` {
+			p.write(` inherits ` + e.Parent())
+		}
+		if e.Body() != nil {
+			p.write(` `)
+			p.expr(e.Body(), precPrimary)
+		}
+	case *parser.TypeMapping:
+		p.write(`type `)
+		p.expr(e.Type(), precPrimary)
+		p.write(` = `)
+		p.expr(e.Mapping(), precRelationship)
+	case *parser.UnaryMinusExpression:
+		p.write(`-`)
+		p.operand(e.Expr(), precPrimary)
+	case *parser.UnfoldExpression:
+		p.write(`*`)
+		p.operand(e.Expr(), precUnary)
+	case *parser.UnlessExpression:
+		p.ifExpression(`unless`, e.Test(), e.Then(), e.Else())
+	case *parser.VariableExpression:
+		p.write(`$`)
+		p.expr(e.Expr(), precPrimary)
+	default:
+		panic(fmt.Sprintf("printer: unable to print expression of type %T", e))
+	}
+}
+
+// namedDefinition writes a definition. The name of a class is written relative to the enclosing
+// definitions since the parser qualifies it with their names.
+func (p *printer) namedDefinition(keyword string, e parser.NamedDefinition, parent string, returnType parser.Expression) {
+	name := e.Name()
+	if keyword == `class` {
+		name = p.relativeName(name)
+	}
+	p.write(keyword + ` ` + name)
+	p.params(`(`, `)`, e.Parameters())
+	if parent != `` {
+		p.write(` inherits ` + parent)
+	}
+	if returnType != nil {
+		p.write(` >> `)
+		p.operand(returnType, precPrimary)
+	}
+	p.write(` `)
+	if keyword == `class` || keyword == `plan` {
+		p.names = append(p.names, name)
+		defer func() { p.names = p.names[:len(p.names)-1] }()
+	}
+	p.body(e.Body())
+}
+
+// relativeName returns the given qualified name without the names of the enclosing definitions
+func (p *printer) relativeName(name string) string {
+	if len(p.names) == 0 {
+		return name
+	}
+	return strings.TrimPrefix(name, strings.Join(p.names, `::`)+`::`)
+}
+
+func (p *printer) ifExpression(keyword string, test, then, elseExpr parser.Expression) {
+	p.write(keyword + ` `)
+	p.operand(test, precOr)
+	p.write(` `)
+	p.body(then)
+	switch elseExpr := elseExpr.(type) {
+	case nil, *parser.Nop:
+	case *parser.IfExpression:
+		p.write(` els`)
+		p.ifExpression(`if`, elseExpr.Test(), elseExpr.Then(), elseExpr.Else())
+	default:
+		p.write(` else `)
+		p.body(elseExpr)
+	}
+}
+
+func (p *printer) hash(entries []parser.Expression) {
+	if strs, ok := p.fits(`{`, `}`, entries, precRelationship); ok {
+		p.write(`{`)
+		p.write(strings.Join(strs, `, `))
+		p.write(`}`)
+		return
+	}
+	keys := make([]parser.Expression, len(entries))
+	ops := make([]string, len(entries))
+	values := make([]parser.Expression, len(entries))
+	for i, e := range entries {
+		ke := e.(*parser.KeyedEntry)
+		keys[i] = ke.Key()
+		ops[i] = `=>`
+		values[i] = ke.Value()
+	}
+	p.write(`{`)
+	p.indent++
+	p.nl()
+	p.entries(keys, ops, values, `,`, `,`, precRelationship)
+	p.indent--
+	p.nl()
+	p.write(`}`)
+}
+
+// attributeBlock writes attribute operations enclosed in braces, one per line
+func (p *printer) attributeBlock(ops []parser.Expression) {
+	if len(ops) == 0 {
+		p.write(`{}`)
+		return
+	}
+	p.write(`{`)
+	p.indent++
+	p.nl()
+	p.attributeOps(ops, `,`)
+	p.indent--
+	p.nl()
+	p.write(`}`)
+}
+
+// form writes the prefix of a virtual or exported resource expression
+func (p *printer) form(form parser.ResourceForm) {
+	switch form {
+	case parser.VIRTUAL:
+		p.write(`@`)
+	case parser.EXPORTED:
+		p.write(`@@`)
+	}
+}
+
+func (p *printer) resource(e *parser.ResourceExpression) {
+	p.form(e.Form())
+	p.operand(e.TypeName(), precPrimary)
+	p.write(` {`)
+	bodies := e.Bodies()
+	if len(bodies) == 1 {
+		rb := bodies[0].(*parser.ResourceBody)
+		p.write(` `)
+		p.operand(rb.Title(), precSelector)
+		p.write(`:`)
+		if len(rb.Operations()) == 0 {
+			p.write(` }`)
+			return
+		}
+		p.indent++
+		p.nl()
+		p.attributeOps(rb.Operations(), `,`)
+		p.indent--
+		p.nl()
+		p.write(`}`)
+		return
+	}
+	p.indent++
+	for _, b := range bodies {
+		rb := b.(*parser.ResourceBody)
+		p.nl()
+		p.operand(rb.Title(), precSelector)
+		p.write(`:`)
+		if len(rb.Operations()) == 0 {
+			p.write(`;`)
+			continue
+		}
+		p.indent++
+		p.nl()
+		p.attributeOps(rb.Operations(), `;`)
+		p.indent--
+	}
+	p.indent--
+	p.nl()
+	p.write(`}`)
+}
+
+// activity writes a workflow activity. The iteration of an activity is not written. The name is
+// written relative to the enclosing workflows since the parser qualifies it with their names.
+func (p *printer) activity(e *parser.ActivityExpression) {
+	name := p.relativeName(e.Name())
+	p.write(string(e.Style()) + ` ` + name + ` `)
+	props := make([]parser.Expression, 0)
+	if h, ok := e.Properties().(*parser.LiteralHash); ok {
+		for _, entry := range h.Entries() {
+			if ke, ok := entry.(*parser.KeyedEntry); ok {
+				if k, ok := ke.Key().(*parser.QualifiedName); ok && k.Name() == `iteration` {
+					continue
+				}
+			}
+			props = append(props, entry)
+		}
+	}
+	p.hash(props)
+	switch d := e.Definition().(type) {
+	case nil:
+	case *parser.LiteralHash:
+		p.write(` `)
+		p.hash(d.Entries())
+	default:
+		p.write(` `)
+		if e.Style() == parser.ActivityStyleWorkflow {
+			p.names = append(p.names, name)
+			defer func() { p.names = p.names[:len(p.names)-1] }()
+		}
+		p.body(d)
+	}
+}
+
+func (p *printer) segment(e parser.Expression) {
+	switch e := e.(type) {
+	case *parser.LiteralString:
+		p.write(escapeDoubleQuoted(e.StringValue()))
+	case *parser.TextExpression:
+		p.write(`${`)
+		if v, ok := e.Expr().(*parser.VariableExpression); ok {
+			p.expr(v.Expr(), precPrimary)
+		} else {
+			p.expr(e.Expr(), precRelationship)
+		}
+		p.write(`}`)
+	default:
+		p.write(`${`)
+		p.expr(e, precRelationship)
+		p.write(`}`)
+	}
+}
+
+func (p *printer) heredoc(e *parser.HeredocExpression) {
+	tag := heredocTag(heredocSource(e.Text()))
+	p.write(`@(`)
+	flags := ``
+	if cs, ok := e.Text().(*parser.ConcatenatedString); ok {
+		p.write(`"` + tag + `"`)
+		for _, s := range cs.Segments() {
+			if ls, ok := s.(*parser.LiteralString); ok && strings.IndexByte(ls.StringValue(), '$') >= 0 {
+				flags = `/$`
+				break
+			}
+		}
+	} else {
+		p.write(tag)
+	}
+	if e.Syntax() != `` {
+		p.write(`:` + e.Syntax())
+	}
+	p.write(flags + `)`)
+	p.heredocs = append(p.heredocs, &pendingHeredoc{e, p.indent})
+}
+
+// heredocSource returns the text of a heredoc as it must be written in the source
+func heredocSource(text parser.Expression) string {
+	switch text := text.(type) {
+	case *parser.LiteralString:
+		return text.StringValue()
+	case *parser.ConcatenatedString:
+		s := &printer{}
+		for _, seg := range text.Segments() {
+			if ls, ok := seg.(*parser.LiteralString); ok {
+				s.write(strings.Replace(ls.StringValue(), `$`, `\$`, -1))
+			} else {
+				s.segment(seg)
+			}
+		}
+		return s.b.String()
+	default:
+		return ``
+	}
+}
+
+func (p *printer) heredocText(h *pendingHeredoc) {
+	source := heredocSource(h.expr.Text())
+	p.b.WriteString(heredocBody(source, heredocTag(source), strings.Repeat(`  `, h.indent+1)))
+}
+
+func formatInt(v int64, radix int) string {
+	sign := ``
+	if v < 0 {
+		sign = `-`
+		v = -v
+	}
+	switch radix {
+	case 16:
+		return fmt.Sprintf(`%s0x%X`, sign, v)
+	case 8:
+		return fmt.Sprintf(`%s0%o`, sign, v)
+	default:
+		return sign + strconv.FormatInt(v, 10)
+	}
+}
+
+func formatFloat(v float64) string {
+	s := strconv.FormatFloat(v, 'g', -1, 64)
+	if e := strings.IndexByte(s, 'e'); e >= 0 {
+		if strings.IndexByte(s[:e], '.') < 0 {
+			s = s[:e] + `.0` + s[e:]
+		}
+	} else if strings.IndexByte(s, '.') < 0 {
+		s += `.0`
+	}
+	return s
+}
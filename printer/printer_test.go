@@ -0,0 +1,195 @@
+package printer
+
+import (
+	"testing"
+
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+func TestStatements(t *testing.T) {
+	expectCanonical(t, `$a=1 $b = [1,2,{a=>'x', 'b'=>2.5}] notice "hello ${name}!"`, `$a = 1
+$b = [1, 2, {a => 'x', 'b' => 2.5}]
+notice("hello ${name}!")
+`)
+}
+
+func TestDefinitions(t *testing.T) {
+	expectCanonical(t, `class a::b(Integer $x = 0x1F, $y) inherits c { notice($x) }
+define a::d() {}
+function f(String $a, *$r) >> String { $a }`, `class a::b(Integer $x = 0x1F, $y) inherits c {
+  notice($x)
+}
+
+define a::d {}
+
+function f(String $a, *$r) >> String {
+  $a
+}
+`)
+}
+
+func TestResources(t *testing.T) {
+	expectCanonical(t, `@@file { ['a','b']: ensure => present, * => $h; default: mode => '0644' }
+package { 'nginx': ensure => installed, require => File['a'] }
+File { mode => '0644' }
+Foo <| title == 'x' |> { x +> 1 }`, `@@file {
+  ['a', 'b']:
+    ensure => present,
+    *      => $h;
+  default:
+    mode => '0644';
+}
+package { 'nginx':
+  ensure  => installed,
+  require => File['a'],
+}
+File {
+  mode => '0644',
+}
+Foo <| title == 'x' |> {
+  x +> 1,
+}
+`)
+}
+
+func TestResourceForms(t *testing.T) {
+	expectCanonical(t, `@@File { mode => '0644' }
+@File['a'] { mode => '0600' }`, `@@File {
+  mode => '0644',
+}
+@File['a'] {
+  mode => '0600',
+}
+`)
+}
+
+func TestNestedDefinitions(t *testing.T) {
+	expectCanonical(t, `class foo { class bar { class baz {} } class ::qux {} }`, `class foo {
+  class bar {
+    class baz {}
+  }
+
+  class qux {}
+}
+`)
+	expectCanonical(t, `workflow foo {} { resource bar {} }`, `workflow foo {} {
+  resource bar {}
+}
+`, parser.PARSER_WORKFLOW_ENABLED)
+}
+
+func TestConditionals(t *testing.T) {
+	expectCanonical(t, `if $a { 1 } elsif $b { 2 } else { 3 }
+$y = $x ? { 1 => 'a', default => 'b' }
+case $x { 1, 2: { } default: { notice(x) } }`, `if $a {
+  1
+} elsif $b {
+  2
+} else {
+  3
+}
+$y = $x ? {
+  1       => 'a',
+  default => 'b',
+}
+case $x {
+  1, 2: {}
+  default: {
+    notice(x)
+  }
+}
+`)
+}
+
+func TestStrings(t *testing.T) {
+	expectCanonical(t, `$a = "tab\there \"q\" \$x \\ ${x}"
+$b = 'it\'s'
+$c = "line\n"
+$r = /a\/b/`, `$a = "tab\there \"q\" \$x \\ ${x}"
+$b = "it's"
+$c = "line\n"
+$r = /a\/b/
+`)
+}
+
+func TestHeredoc(t *testing.T) {
+	expectCanonical(t, `class a {
+  $x = @("EOT":json/$)
+    { "name": "${name}", "cost": "\$5" }
+    |- EOT
+  notice($x)
+}`, `class a {
+  $x = @("END":json/$)
+    { "name": "${name}", "cost": "\$5" }
+    |- END
+  notice($x)
+}
+`)
+}
+
+func TestLongCollections(t *testing.T) {
+	expectCanonical(t, `$h = { 'alpha' => 'some long value here', 'beta' => 'another long value here', 'gamma' => 1 }`,
+		`$h = {
+  'alpha' => 'some long value here',
+  'beta'  => 'another long value here',
+  'gamma' => 1,
+}
+`)
+}
+
+func TestEpp(t *testing.T) {
+	expectCanonical(t, `<%- | $x, $y = 1 | -%>
+Hello <%= $x %> <% if $y { %>yes<% } %> 100<%% done`, `<%- |$x, $y = 1| -%>
+Hello <%= $x %> <% if $y { %>yes<% } %> 100<%% done`, parser.PARSER_EPP_MODE)
+}
+
+func TestPrecedence(t *testing.T) {
+	f := parser.DefaultFactory()
+	l := parser.NewLocator(``, ``)
+	one := f.Integer(1, 10, l, 0, 0)
+	two := f.Integer(2, 10, l, 0, 0)
+	x := f.Variable(f.QualifiedName(`x`, l, 0, 0), l, 0, 0)
+
+	sum := f.Arithmetic(`+`, one, two, l, 0, 0)
+	expectString(t, f.Arithmetic(`*`, sum, x, l, 0, 0), `(1 + 2) * $x`)
+	expectString(t, f.Arithmetic(`-`, f.Arithmetic(`-`, x, one, l, 0, 0), two, l, 0, 0), `($x - 1) - 2`)
+	expectString(t, f.Arithmetic(`-`, x, f.Arithmetic(`-`, one, two, l, 0, 0), l, 0, 0), `$x - 1 - 2`)
+	expectString(t, f.Not(f.And(x, x, l, 0, 0), l, 0, 0), `!($x and $x)`)
+	expectString(t, f.Access(sum, []parser.Expression{one}, l, 0, 0), `(1 + 2)[1]`)
+	expectString(t, f.Arithmetic(`/`, f.Hash(nil, l, 0, 0), two, l, 0, 0), `({}) / 2`)
+	expectString(t, f.Arithmetic(`/`, f.Default(l, 0, 0), two, l, 0, 0), `(default) / 2`)
+	expectString(t, f.Arithmetic(`*`, f.Default(l, 0, 0), two, l, 0, 0), `default * 2`)
+}
+
+func expectCanonical(t *testing.T, source, expected string, opts ...parser.Option) {
+	t.Helper()
+	expr := parse(t, source, opts...)
+	actual := String(expr)
+	if actual != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, actual)
+		return
+	}
+	if reparsed := parse(t, actual, opts...); reparsed.ToPN().String() != expr.ToPN().String() {
+		t.Errorf("reparse of printed source yields:\n%s\nexpected:\n%s", reparsed.ToPN(), expr.ToPN())
+	}
+}
+
+func expectString(t *testing.T, expr parser.Expression, expected string) {
+	t.Helper()
+	if actual := String(expr); actual != expected {
+		t.Errorf("expected '%s', got '%s'", expected, actual)
+	}
+}
+
+func parse(t *testing.T, source string, opts ...parser.Option) parser.Expression {
+	t.Helper()
+	expr, err := parser.CreateParser(opts...).Parse(`test.pp`, source, false)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	return expr
+}
+
+func TestImport(t *testing.T) {
+	expectCanonical(t, `import 'a.pp','b/*.pp'`, "import 'a.pp', 'b/*.pp'\n", parser.PARSER_IMPORT_COMPAT)
+}
@@ -0,0 +1,162 @@
+package printer
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Quote returns the given string as a Puppet string literal using the quoting that needs the
+// least escaping. Single quotes are preferred when both forms are equally long. Double quotes
+// are always used for a string that contains control characters.
+func Quote(s string) string {
+	dq := DoubleQuote(s)
+	if hasControlChars(s) {
+		return dq
+	}
+	sq := SingleQuote(s)
+	if len(dq) < len(sq) {
+		return dq
+	}
+	return sq
+}
+
+// SingleQuote returns the given string as a single quoted Puppet string literal. Backslashes
+// and single quotes are escaped. Control characters such as newlines are retained verbatim.
+func SingleQuote(s string) string {
+	return `'` + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + `'`
+}
+
+// DoubleQuote returns the given string as a double quoted Puppet string literal. Backslashes,
+// double quotes, and dollar signs are escaped so that no interpolation takes place. Control
+// characters are written as escape sequences.
+func DoubleQuote(s string) string {
+	return `"` + escapeDoubleQuoted(s) + `"`
+}
+
+// Heredoc returns the given string as a Puppet heredoc with the given syntax, which may be
+// empty. No interpolation or escapes are enabled so the text is retained verbatim. The given
+// indent is written before each line of the text and before the end marker.
+//
+// The result starts with the heredoc declaration and ends with the end marker line, including
+// its newline. The text of a heredoc starts on the line after the declaration so the declaration
+// must be the last thing on its line, e.g.
+//
+//	$x = @(END:json)
+//	  {"a": 1}
+//	  | END
+func Heredoc(text, syntax, indent string) string {
+	tag := heredocTag(text)
+	b := bytes.NewBufferString(`@(`)
+	b.WriteString(tag)
+	if syntax != `` {
+		b.WriteString(`:`)
+		b.WriteString(syntax)
+	}
+	b.WriteString(")\n")
+	b.WriteString(heredocBody(text, tag, indent))
+	return b.String()
+}
+
+// heredocTag returns a heredoc tag that doesn't conflict with any line of the given text
+func heredocTag(text string) string {
+	lines := strings.Split(text, "\n")
+	tag := `END`
+	for n := 1; ; n++ {
+		found := false
+		for _, line := range lines {
+			if strings.Trim(line, " \t|-") == tag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return tag
+		}
+		tag = fmt.Sprintf(`END%d`, n)
+	}
+}
+
+// heredocBody returns the text lines and the end marker of a heredoc. The margin of the end
+// marker is used to strip the indent from the text lines. A trailing newline in the text is
+// represented by the end marker and its absence by the '-' of the end marker.
+func heredocBody(text, tag, indent string) string {
+	b := bytes.NewBufferString(``)
+	marker := `|`
+	if strings.HasSuffix(text, "\n") {
+		text = text[:len(text)-1]
+	} else {
+		marker = `|-`
+	}
+	if text != `` {
+		for _, line := range strings.Split(text, "\n") {
+			if line != `` {
+				b.WriteString(indent)
+				b.WriteString(line)
+			}
+			b.WriteByte('\n')
+		}
+	}
+	b.WriteString(indent)
+	b.WriteString(marker)
+	b.WriteString(` `)
+	b.WriteString(tag)
+	b.WriteByte('\n')
+	return b.String()
+}
+
+func hasControlChars(s string) bool {
+	for _, c := range s {
+		if c < ' ' || c == 0x7f {
+			return true
+		}
+	}
+	return false
+}
+
+func escapeDoubleQuoted(s string) string {
+	b := bytes.NewBufferString(``)
+	for _, c := range s {
+		switch c {
+		case '\\', '"', '$':
+			b.WriteByte('\\')
+			b.WriteRune(c)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if c < ' ' || c == 0x7f {
+				fmt.Fprintf(b, `\u%04X`, c)
+			} else {
+				b.WriteRune(c)
+			}
+		}
+	}
+	return b.String()
+}
+
+// regexpLiteral returns the source of a regular expression. The value is the text between
+// the slashes in which escaped slashes have been unescaped by the lexer.
+func regexpLiteral(v string) string {
+	b := bytes.NewBufferString(`/`)
+	for i := 0; i < len(v); i++ {
+		c := v[i]
+		switch c {
+		case '\\':
+			b.WriteByte(c)
+			if i+1 < len(v) {
+				i++
+				b.WriteByte(v[i])
+			}
+		case '/':
+			b.WriteString(`\/`)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('/')
+	return b.String()
+}
@@ -0,0 +1,51 @@
+package printer
+
+import (
+	"testing"
+
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+func TestQuote(t *testing.T) {
+	expectQuoted(t, Quote(`plain`), `'plain'`, `plain`)
+	expectQuoted(t, Quote(`it's`), `"it's"`, `it's`)
+	expectQuoted(t, Quote(`it's "$x"`), `'it\'s "$x"'`, `it's "$x"`)
+	expectQuoted(t, Quote("a\tb\n"), `"a\tb\n"`, "a\tb\n")
+	expectQuoted(t, Quote("bell\a"), `"bell\u0007"`, "bell\a")
+	expectQuoted(t, SingleQuote(`c:\temp\`), `'c:\\temp\\'`, `c:\temp\`)
+	expectQuoted(t, DoubleQuote(`${x} \ "y"`), `"\${x} \\ \"y\""`, `${x} \ "y"`)
+}
+
+func TestHeredocQuote(t *testing.T) {
+	text := "first ${x} \\n\n  second\nEND\n"
+	h := Heredoc(text, `text`, `  `)
+	expected := "@(END1:text)\n  first ${x} \\n\n    second\n  END\n  | END1\n"
+	if h != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, h)
+	}
+	expectQuoted(t, "$x = "+h, ``, text)
+	expectQuoted(t, "$x = "+Heredoc(`no newline`, ``, ``), ``, `no newline`)
+}
+
+// expectQuoted checks that the quoted string is the expected string (unless expected is empty)
+// and that it is parsed into the given value
+func expectQuoted(t *testing.T, quoted, expected, value string) {
+	t.Helper()
+	if expected != `` && quoted != expected {
+		t.Errorf("expected %s, got %s", expected, quoted)
+		return
+	}
+	expr, err := parser.CreateParser().Parse(`test.pp`, quoted, true)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if a, ok := expr.(*parser.AssignmentExpression); ok {
+		expr = a.Rhs()
+	}
+	if h, ok := expr.(*parser.HeredocExpression); ok {
+		expr = h.Text()
+	}
+	if s, ok := expr.(*parser.LiteralString); !ok || s.StringValue() != value {
+		t.Errorf("%s is not parsed into %q, got %s", quoted, value, expr.ToPN())
+	}
+}
@@ -0,0 +1,20 @@
+package pspec
+
+import "github.com/lyraproj/issue/issue"
+
+const (
+	PSPEC_ILLEGAL_ARGUMENT = `PSPEC_ILLEGAL_ARGUMENT`
+	PSPEC_MISSING_GIVEN    = `PSPEC_MISSING_GIVEN`
+	PSPEC_NOT_A_CALL       = `PSPEC_NOT_A_CALL`
+	PSPEC_UNKNOWN_FEATURE  = `PSPEC_UNKNOWN_FEATURE`
+	PSPEC_UNKNOWN_FUNCTION = `PSPEC_UNKNOWN_FUNCTION`
+)
+
+func init() {
+	issue.Hard(PSPEC_ILLEGAL_ARGUMENT, `illegal arguments to %{function}, expected %{expected}`)
+	issue.Hard(PSPEC_MISSING_GIVEN, `example '%{name}' has no given source`)
+	issue.Hard2(PSPEC_NOT_A_CALL, `expected a call to one of %{functions}, got %{expression}`,
+		issue.HF{`expression`: issue.A_an})
+	issue.Hard(PSPEC_UNKNOWN_FEATURE, `unknown parser feature '%{name}'`)
+	issue.Hard(PSPEC_UNKNOWN_FUNCTION, `unknown pspec function '%{name}'`)
+}
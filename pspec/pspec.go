@@ -0,0 +1,396 @@
+// Package pspec loads and runs parser specifications. A specification is written in Puppet syntax,
+// typically in a file with the extension .pspec, and consists of examples of Puppet source together
+// with expectations on the result of parsing and validating that source:
+//
+//	examples('strings',
+//	  example('single quoted',
+//	    given(`'a string'`),
+//	    parse(`"a string"`)),
+//	  example('unterminated',
+//	    given(`'a string`),
+//	    error(LEX_UNTERMINATED_STRING)))
+//
+//	example('plan',
+//	  given(`plan mymod() {}`, 'tasks'),
+//	  validates_ok())
+//
+// The functions are:
+//
+//	examples(name, example or examples...)    groups examples under a name
+//	example(name, given, expectation...)      an example with its expectations
+//	given(source, feature...)                 the source of an example and the names of the parser
+//	                                          features that must be enabled to parse it, e.g. 'tasks'
+//	parse(pn)                                 the source parses into an AST with the given PN. The PN
+//	                                          is the PN of the statement when there is only one
+//	validates_ok()                            the source parses and validates without issues
+//	error(issue...)                           the given issues are reported as errors
+//	warning(issue...)                         the given issues are reported as warnings
+//	issue(code, arguments)                    an issue with the given code and the given arguments
+//
+// An issue given to error or warning is an issue code, a call to issue, or a regular expression that
+// matches the message of the issue. Backticked strings are recognized so that sources and PN can be
+// written without escapes.
+package pspec
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-parser/literal"
+	"github.com/lyraproj/puppet-parser/parser"
+	"github.com/lyraproj/puppet-parser/validator"
+)
+
+type (
+	// Example is a source together with the expectations on the result of parsing and validating it
+	Example struct {
+		// Path contains the names of the enclosing examples followed by the name of this example
+		Path []string
+
+		// Expression is the call to example in the specification. It provides the position.
+		Expression parser.Expression
+
+		// Source is the Puppet source of the example
+		Source string
+
+		// Options are the options that enable the parser features that the source requires
+		Options []parser.Option
+
+		// Expectations are the expectations that must be met for the example to pass
+		Expectations []Expectation
+	}
+
+	// Expectation is an expectation on the outcome of parsing and validating the source of an example
+	Expectation interface {
+		// Check returns a description of each way in which the given outcome fails to meet the
+		// expectation, or an empty slice when the expectation is met
+		Check(outcome *Outcome) []string
+	}
+
+	// Outcome is the result of parsing and validating the source of an example
+	Outcome struct {
+		// Expression is the parsed program or nil when the source could not be parsed
+		Expression parser.Expression
+
+		// Issues are the parse error, or the parser warnings and the validation issues
+		Issues []issue.Reported
+	}
+
+	// Result is the result of running an example
+	Result struct {
+		Example *Example
+
+		// Failures describe the expectations that were not met
+		Failures []string
+	}
+
+	parseExpectation struct {
+		pn string
+	}
+
+	validatesOK struct{}
+
+	issueExpectation struct {
+		severity issue.Severity
+		code     issue.Code
+		args     map[string]interface{}
+		message  *regexp.Regexp
+	}
+)
+
+// Load returns the examples of the given specification in the order that they appear. The returned
+// error is an issue.Reported when the specification can't be parsed or is malformed.
+func Load(filename, source string) (examples []*Example, err error) {
+	program, err := parser.CreatePspecParser().Parse(filename, source, false)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			if ri, ok := r.(issue.Reported); ok {
+				err = ri
+				return
+			}
+			panic(r)
+		}
+	}()
+	examples = make([]*Example, 0)
+	for _, e := range statements(program.(*parser.Program).Body()) {
+		examples = appendExamples(examples, nil, e)
+	}
+	return examples, nil
+}
+
+// LoadFile reads the specification in the file at the given path and returns its examples
+func LoadFile(path string) ([]*Example, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Load(path, string(content))
+}
+
+// Run runs the given examples and returns their results in the same order
+func Run(examples []*Example) []*Result {
+	results := make([]*Result, len(examples))
+	for i, example := range examples {
+		results[i] = example.Run()
+	}
+	return results
+}
+
+// RunFile loads the specification in the file at the given path and runs its examples
+func RunFile(path string) ([]*Result, error) {
+	examples, err := LoadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Run(examples), nil
+}
+
+// Name returns the path of the example joined with " / "
+func (e *Example) Name() string {
+	return strings.Join(e.Path, ` / `)
+}
+
+// Run parses and validates the source of the example and checks its expectations. The source is
+// validated by the validator for the enabled features, i.e. the tasks validator when tasks are enabled,
+// the workflow validator when workflow is enabled, and the Puppet validator otherwise.
+func (e *Example) Run() *Result {
+	p := parser.CreateParser(e.Options...)
+	var v validator.Validator
+	switch {
+	case p.Enabled(parser.PARSER_TASKS_ENABLED):
+		v = validator.NewTasksChecker()
+	case p.Enabled(parser.PARSER_WORKFLOW_ENABLED):
+		v = validator.NewWorkflowChecker()
+	default:
+		v = validator.NewChecker(validator.STRICT_WARNING)
+	}
+	outcome := &Outcome{}
+	var result issue.Result
+	outcome.Expression, result = validator.NewParserValidator(p, v).Parse(e.Name(), e.Source)
+	if result != nil {
+		outcome.Issues = result.Issues()
+	}
+	failures := make([]string, 0)
+	for _, x := range e.Expectations {
+		failures = append(failures, x.Check(outcome)...)
+	}
+	return &Result{e, failures}
+}
+
+// Passed returns true if all expectations of the example were met
+func (r *Result) Passed() bool {
+	return len(r.Failures) == 0
+}
+
+func (r *Result) String() string {
+	if r.Passed() {
+		return `PASS ` + r.Example.Name()
+	}
+	return fmt.Sprintf("FAIL %s\n  %s", r.Example.Name(), strings.Join(r.Failures, "\n  "))
+}
+
+func (x *parseExpectation) Check(outcome *Outcome) []string {
+	if outcome.Expression == nil {
+		return []string{`expected source to parse but got ` + outcome.Issues[0].String()}
+	}
+	body := outcome.Expression.(*parser.Program).Body()
+	if stmts := statements(body); len(stmts) == 1 {
+		body = stmts[0]
+	}
+	if actual := body.ToPN().String(); actual != x.pn {
+		return []string{fmt.Sprintf(`expected parse result %s, got %s`, x.pn, actual)}
+	}
+	return []string{}
+}
+
+func (x validatesOK) Check(outcome *Outcome) []string {
+	failures := make([]string, len(outcome.Issues))
+	for i, ri := range outcome.Issues {
+		failures[i] = `unexpected issue ` + ri.String()
+	}
+	return failures
+}
+
+func (x *issueExpectation) Check(outcome *Outcome) []string {
+	for _, ri := range outcome.Issues {
+		if ri.Severity() == x.severity && x.matches(ri) {
+			return []string{}
+		}
+	}
+	return []string{fmt.Sprintf(`expected %s %s`, x.severity, x)}
+}
+
+func (x *issueExpectation) matches(ri issue.Reported) bool {
+	if x.message != nil {
+		return x.message.MatchString(ri.Error())
+	}
+	if ri.Code() != x.code {
+		return false
+	}
+	for k, v := range x.args {
+		if fmt.Sprint(ri.Argument(k)) != fmt.Sprint(v) {
+			return false
+		}
+	}
+	return true
+}
+
+func (x *issueExpectation) String() string {
+	if x.message != nil {
+		return `matching /` + x.message.String() + `/`
+	}
+	if len(x.args) == 0 {
+		return string(x.code)
+	}
+	return fmt.Sprintf(`%s with %v`, x.code, x.args)
+}
+
+// statements returns the statements of the given program body
+func statements(body parser.Expression) []parser.Expression {
+	switch body := body.(type) {
+	case *parser.BlockExpression:
+		return body.Statements()
+	case *parser.Nop:
+		return []parser.Expression{}
+	default:
+		return []parser.Expression{body}
+	}
+}
+
+// appendExamples appends the example or the examples of the group that the given call creates
+func appendExamples(examples []*Example, path []string, e parser.Expression) []*Example {
+	name, args := call(e, `examples`, `example`)
+	if len(args) == 0 {
+		panic(illegalArgument(e, name, `a name`))
+	}
+	path = append(path[:len(path):len(path)], stringArg(args[0], name, `a name`))
+	if name == `example` {
+		return append(examples, example(e, path, args[1:]))
+	}
+	for _, arg := range args[1:] {
+		examples = appendExamples(examples, path, arg)
+	}
+	return examples
+}
+
+func example(e parser.Expression, path []string, args []parser.Expression) *Example {
+	example := &Example{Path: path, Expression: e, Expectations: make([]Expectation, 0)}
+	given := false
+	for _, arg := range args {
+		name, fargs := call(arg, `given`, `parse`, `validates_ok`, `error`, `warning`)
+		switch name {
+		case `given`:
+			if len(fargs) == 0 {
+				panic(illegalArgument(arg, name, `a source`))
+			}
+			example.Source = stringArg(fargs[0], name, `a source`)
+			for _, f := range fargs[1:] {
+				example.Options = append(example.Options, featureOption(f))
+			}
+			given = true
+		case `parse`:
+			if len(fargs) != 1 {
+				panic(illegalArgument(arg, name, `a PN string`))
+			}
+			example.Expectations = append(example.Expectations, &parseExpectation{stringArg(fargs[0], name, `a PN string`)})
+		case `validates_ok`:
+			example.Expectations = append(example.Expectations, validatesOK{})
+		default:
+			severity := issue.SEVERITY_ERROR
+			if name == `warning` {
+				severity = issue.SEVERITY_WARNING
+			}
+			if len(fargs) == 0 {
+				panic(illegalArgument(arg, name, `an issue`))
+			}
+			for _, f := range fargs {
+				example.Expectations = append(example.Expectations, issueArg(f, name, severity))
+			}
+		}
+	}
+	if !given {
+		panic(issue.NewReported(PSPEC_MISSING_GIVEN, issue.SEVERITY_ERROR, issue.H{`name`: strings.Join(path, ` / `)}, e))
+	}
+	return example
+}
+
+func issueArg(e parser.Expression, function string, severity issue.Severity) *issueExpectation {
+	x := &issueExpectation{severity: severity}
+	switch e := e.(type) {
+	case *parser.RegexpExpression:
+		x.message = regexp.MustCompile(e.PatternString())
+		return x
+	case *parser.CallNamedFunctionExpression:
+		_, args := call(e, `issue`)
+		if len(args) == 0 || len(args) > 2 {
+			panic(illegalArgument(e, `issue`, `a code and an optional hash of arguments`))
+		}
+		x.code = issue.Code(codeArg(args[0], `issue`))
+		if len(args) == 2 {
+			h, ok := literal.ToLiteral(args[1])
+			hash, isHash := h.(map[interface{}]interface{})
+			if !(ok && isHash) {
+				panic(illegalArgument(args[1], `issue`, `a literal hash of arguments`))
+			}
+			x.args = make(map[string]interface{}, len(hash))
+			for k, v := range hash {
+				x.args[fmt.Sprint(k)] = v
+			}
+		}
+		return x
+	}
+	x.code = issue.Code(codeArg(e, function))
+	return x
+}
+
+func codeArg(e parser.Expression, function string) string {
+	if qr, ok := e.(*parser.QualifiedReference); ok {
+		return qr.Name()
+	}
+	return stringArg(e, function, `an issue code`)
+}
+
+func featureOption(e parser.Expression) parser.Option {
+	name := stringArg(e, `given`, `feature names`)
+	for _, f := range parser.Features() {
+		if f.Name == name {
+			return f.Option
+		}
+	}
+	panic(issue.NewReported(PSPEC_UNKNOWN_FEATURE, issue.SEVERITY_ERROR, issue.H{`name`: name}, e))
+}
+
+func stringArg(e parser.Expression, function, expected string) string {
+	if v, ok := literal.ToLiteral(e); ok {
+		if s, ok := v.(string); ok {
+			return s
+		}
+	}
+	panic(illegalArgument(e, function, expected))
+}
+
+// call returns the name and the arguments of the given expression, which must be a call to one of
+// the given functions
+func call(e parser.Expression, functions ...string) (string, []parser.Expression) {
+	if c, ok := e.(*parser.CallNamedFunctionExpression); ok {
+		if qn, ok := c.Functor().(*parser.QualifiedName); ok {
+			for _, f := range functions {
+				if qn.Name() == f {
+					return f, c.Arguments()
+				}
+			}
+			panic(issue.NewReported(PSPEC_UNKNOWN_FUNCTION, issue.SEVERITY_ERROR, issue.H{`name`: qn.Name()}, e))
+		}
+	}
+	panic(issue.NewReported(PSPEC_NOT_A_CALL, issue.SEVERITY_ERROR, issue.H{`functions`: strings.Join(functions, `, `), `expression`: e}, e))
+}
+
+func illegalArgument(e parser.Expression, function, expected string) issue.Reported {
+	return issue.NewReported(PSPEC_ILLEGAL_ARGUMENT, issue.SEVERITY_ERROR, issue.H{`function`: function, `expected`: expected}, e)
+}
@@ -0,0 +1,90 @@
+package pspec
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+)
+
+func TestRun(t *testing.T) {
+	examples, err := Load(`strings.pspec`, issue.Unindent(`
+    examples('strings',
+      example('single quoted',
+        given(`+"`'a string'`"+`),
+        parse(`+"`\"a string\"`"+`)),
+      example('unterminated',
+        given(`+"`'a string`"+`),
+        error(LEX_UNTERMINATED_STRING)))
+
+    example('plan',
+      given(`+"`plan mymod() {}`"+`, 'tasks'),
+      validates_ok())
+
+    example('cross scope',
+      given(`+"`$a::b = 1`"+`),
+      error(issue(VALIDATE_CROSS_SCOPE_ASSIGNMENT, { name => 'a::b' }), /other namespaces/),
+      parse(`+"`(= (var \"a::c\") 1)`"+`))`))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	results := Run(examples)
+	actual := make([]string, len(results))
+	for i, r := range results {
+		actual[i] = r.String()
+	}
+	expected := []string{
+		`PASS strings / single quoted`,
+		`PASS strings / unterminated`,
+		`PASS plan`,
+		"FAIL cross scope\n  expected parse result (= (var \"a::c\") 1), got (= (var \"a::b\") 1)",
+	}
+	if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(actual, "\n"))
+	}
+}
+
+func TestIssueMismatch(t *testing.T) {
+	examples, err := Load(`test.pspec`, "example('x', given(`$a = 1`), warning(VALIDATE_CROSS_SCOPE_ASSIGNMENT), validates_ok())")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	r := examples[0].Run()
+	if r.Passed() || len(r.Failures) != 1 || r.Failures[0] != `expected warning VALIDATE_CROSS_SCOPE_ASSIGNMENT` {
+		t.Errorf("unexpected failures %v", r.Failures)
+	}
+}
+
+func TestMalformed(t *testing.T) {
+	tests := map[string]issue.Code{
+		`example('x', parse('(int 1)'))`:                 PSPEC_MISSING_GIVEN,
+		`example('x', given('1', 'nonsense'))`:           PSPEC_UNKNOWN_FEATURE,
+		`examples('x', evaluates_to(1))`:                 PSPEC_UNKNOWN_FUNCTION,
+		`example(1, given('1'))`:                         PSPEC_ILLEGAL_ARGUMENT,
+		`$x = 1`:                                         PSPEC_NOT_A_CALL,
+		`example('x', given('1'), error(issue(X, [1])))`: PSPEC_ILLEGAL_ARGUMENT,
+	}
+	for source, code := range tests {
+		_, err := Load(`test.pspec`, source)
+		if ri, ok := err.(issue.Reported); !ok || ri.Code() != code {
+			t.Errorf("%s: expected %s, got %v", source, code, err)
+		}
+	}
+}
+
+func TestRunFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), `test.pspec`)
+	if err := os.WriteFile(path, []byte("example('x', given('1 +'), error(/unexpected token/))\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	results, err := RunFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || !results[0].Passed() {
+		t.Errorf("unexpected results %v", results)
+	}
+}
@@ -0,0 +1,71 @@
+package refactor
+
+import (
+	"path"
+	"strings"
+
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+// autoloadDirs are the directories of a module that Puppet autoloads definitions from
+var autoloadDirs = []string{`manifests`, `functions`, `plans`, `types`}
+
+// AutoloadPath returns the name of the module that Puppet autoloads the given definition from and the
+// path of the file, relative to the root of that module, that the definition must be in. The path of
+// the class mymod::config is manifests/config.pp and the path of the class mymod is manifests/init.pp.
+// Defined types follow the same rules as classes, functions are autoloaded from the functions
+// directory, plans from the plans directory, and type aliases from the types directory.
+//
+// The last return value is false when the definition isn't autoloaded, e.g. when it is a node
+// definition or a function with a name that has no module prefix.
+func AutoloadPath(def parser.Definition) (module string, file string, ok bool) {
+	var dir string
+	var name string
+	switch def := def.(type) {
+	case *parser.HostClassDefinition:
+		dir, name = `manifests`, def.Name()
+	case *parser.ResourceTypeDefinition:
+		dir, name = `manifests`, def.Name()
+	case *parser.PlanDefinition:
+		dir, name = `plans`, def.Name()
+	case *parser.FunctionDefinition:
+		dir, name = `functions`, def.Name()
+	case *parser.TypeAlias:
+		dir, name = `types`, def.Name()
+	default:
+		return ``, ``, false
+	}
+
+	segments := strings.Split(strings.ToLower(strings.TrimPrefix(name, `::`)), `::`)
+	for _, s := range segments {
+		if s == `` {
+			return ``, ``, false
+		}
+	}
+	module = segments[0]
+	switch {
+	case len(segments) > 1:
+		file = path.Join(dir, path.Join(segments[1:]...)+`.pp`)
+	case dir == `manifests` || dir == `plans`:
+		file = path.Join(dir, `init.pp`)
+	default:
+		// Functions and type aliases must have a module prefix
+		return ``, ``, false
+	}
+	return module, file, true
+}
+
+// ModuleRoot returns the root directory of the module that contains the file at the given path, i.e.
+// the directory that contains the manifests, functions, plans, or types directory that the file is in.
+// The root of manifests/init.pp is the empty string. A manifests directory takes precedence since the
+// other names are also valid names of subdirectories of manifests. The last return value is false when
+// the file isn't in one of those directories.
+func ModuleRoot(file string) (string, bool) {
+	f := `/` + path.Clean(strings.ReplaceAll(file, `\`, `/`))
+	for _, dir := range autoloadDirs {
+		if i := strings.LastIndex(f, `/`+dir+`/`); i >= 0 {
+			return strings.TrimPrefix(f[:i], `/`), true
+		}
+	}
+	return ``, false
+}
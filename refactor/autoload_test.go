@@ -0,0 +1,66 @@
+package refactor
+
+import (
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+func TestAutoloadPath(t *testing.T) {
+	expr, err := parser.CreateParser(parser.PARSER_TASKS_ENABLED).Parse(`x.pp`, issue.Unindent(`
+    class mymod {}
+    class mymod::a::b {}
+    define Mymod::Vhost() {}
+    function mymod::f() {}
+    function f() {}
+    plan mymod() {}
+    plan mymod::deploy() {}
+    type Mymod::Port = Integer
+    node default {}`), false)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expected := []string{
+		`mymod manifests/init.pp`,
+		`mymod manifests/a/b.pp`,
+		`mymod manifests/vhost.pp`,
+		`mymod functions/f.pp`,
+		``,
+		`mymod plans/init.pp`,
+		`mymod plans/deploy.pp`,
+		`mymod types/port.pp`,
+		``,
+	}
+	defs := expr.(*parser.Program).Definitions()
+	if len(defs) != len(expected) {
+		t.Fatalf("expected %d definitions, got %d", len(expected), len(defs))
+	}
+	for i, def := range defs {
+		actual := ``
+		if module, file, ok := AutoloadPath(def); ok {
+			actual = module + ` ` + file
+		}
+		if actual != expected[i] {
+			t.Errorf("expected '%s', got '%s'", expected[i], actual)
+		}
+	}
+}
+
+func TestModuleRoot(t *testing.T) {
+	for file, expected := range map[string]string{
+		`manifests/init.pp`:                   ``,
+		`mymod/manifests/a/b.pp`:              `mymod`,
+		`/src/mymod/manifests/plans/x.pp`:     `/src/mymod`,
+		`/src/types/mymod/types/port.pp`:      `/src/types/mymod`,
+		`C:\src\mymod\functions\f.pp`:         `C:/src/mymod`,
+		`/src/mymod/plans/../plans/deploy.pp`: `/src/mymod`,
+	} {
+		if root, ok := ModuleRoot(file); !ok || root != expected {
+			t.Errorf("expected root of %s to be '%s', got '%s'", file, expected, root)
+		}
+	}
+	if _, ok := ModuleRoot(`site.pp`); ok {
+		t.Errorf("expected no root for site.pp")
+	}
+}
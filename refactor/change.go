@@ -0,0 +1,108 @@
+// Package refactor computes refactorings of Puppet sources. A refactoring is described by a Change, i.e.
+// the file moves and text edits that it consists of, so that it can be reviewed before it is applied,
+// e.g. by an editor that presents the edits to the user.
+package refactor
+
+import (
+	"fmt"
+	"sort"
+)
+
+type (
+	// TextEdit replaces Length bytes at the byte Offset in the source of File with Text. An edit with
+	// zero Length inserts the text and an edit with empty Text deletes the bytes.
+	TextEdit struct {
+		File   string
+		Offset int
+		Length int
+		Text   string
+	}
+
+	// FileMove renames the file From to To
+	FileMove struct {
+		From string
+		To   string
+	}
+
+	// Change is a set of file changes. The Moves are applied first and the Edits refer to the files by
+	// the paths that they have after the moves. An edit of a file that doesn't exist creates the file. It
+	// must then be an insert at offset zero. The files in Deletes are deleted last.
+	Change struct {
+		Moves   []FileMove
+		Edits   []TextEdit
+		Deletes []string
+	}
+)
+
+// IsEmpty returns true if the change doesn't change any file
+func (c *Change) IsEmpty() bool {
+	return len(c.Moves) == 0 && len(c.Edits) == 0 && len(c.Deletes) == 0
+}
+
+// Apply applies the change to the given files, a map of path to source, and returns the changed files
+// in a new map. The given map is not modified. An error is returned when a moved or deleted file is
+// missing, when a file would be overwritten by a move, and when edits of a file overlap or are out of
+// range.
+func (c *Change) Apply(files map[string]string) (map[string]string, error) {
+	result := make(map[string]string, len(files))
+	for path, source := range files {
+		result[path] = source
+	}
+
+	for _, m := range c.Moves {
+		source, ok := result[m.From]
+		if !ok {
+			return nil, fmt.Errorf(`cannot move %s: no such file`, m.From)
+		}
+		if _, ok = result[m.To]; ok {
+			return nil, fmt.Errorf(`cannot move %s: %s already exists`, m.From, m.To)
+		}
+		delete(result, m.From)
+		result[m.To] = source
+	}
+
+	edits := make(map[string][]TextEdit)
+	paths := make([]string, 0)
+	for _, e := range c.Edits {
+		if _, ok := edits[e.File]; !ok {
+			paths = append(paths, e.File)
+		}
+		edits[e.File] = append(edits[e.File], e)
+	}
+	for _, path := range paths {
+		source, err := applyEdits(path, result, edits[path])
+		if err != nil {
+			return nil, err
+		}
+		result[path] = source
+	}
+
+	for _, path := range c.Deletes {
+		if _, ok := result[path]; !ok {
+			return nil, fmt.Errorf(`cannot delete %s: no such file`, path)
+		}
+		delete(result, path)
+	}
+	return result, nil
+}
+
+// applyEdits returns the source of the given file after the given edits of it have been applied
+func applyEdits(path string, files map[string]string, edits []TextEdit) (string, error) {
+	source, exists := files[path]
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].Offset < edits[j].Offset })
+	end := 0
+	for _, e := range edits {
+		if !exists && (e.Offset != 0 || e.Length != 0) {
+			return ``, fmt.Errorf(`cannot edit %s: no such file`, path)
+		}
+		if e.Offset < end || e.Length < 0 || e.Offset+e.Length > len(source) {
+			return ``, fmt.Errorf(`cannot edit %s: the edit at offset %d overlaps another edit or is out of range`, path, e.Offset)
+		}
+		end = e.Offset + e.Length
+	}
+	for i := len(edits) - 1; i >= 0; i-- {
+		e := edits[i]
+		source = source[:e.Offset] + e.Text + source[e.Offset+e.Length:]
+	}
+	return source, nil
+}
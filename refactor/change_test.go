@@ -0,0 +1,54 @@
+package refactor
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestChangeApply(t *testing.T) {
+	files := map[string]string{`a.pp`: `abcdef`, `b.pp`: `xyz`, `c.pp`: `gone`}
+	change := &Change{
+		Moves: []FileMove{{`b.pp`, `d.pp`}},
+		Edits: []TextEdit{
+			{File: `a.pp`, Offset: 4, Length: 2, Text: `EF`},
+			{File: `a.pp`, Offset: 0, Length: 1},
+			{File: `a.pp`, Offset: 6, Text: `>`},
+			{File: `d.pp`, Offset: 3, Text: `!`},
+			{File: `e.pp`, Text: `new`},
+		},
+		Deletes: []string{`c.pp`},
+	}
+	result, err := change.Apply(files)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expected := map[string]string{`a.pp`: `bcdEF>`, `d.pp`: `xyz!`, `e.pp`: `new`}
+	if !reflect.DeepEqual(expected, result) {
+		t.Errorf("expected %v, got %v", expected, result)
+	}
+	if len(files) != 3 || files[`a.pp`] != `abcdef` {
+		t.Errorf("the given files were modified")
+	}
+	if change.IsEmpty() || !(&Change{}).IsEmpty() {
+		t.Errorf("unexpected IsEmpty")
+	}
+}
+
+func TestChangeApplyErrors(t *testing.T) {
+	files := map[string]string{`a.pp`: `abcdef`, `b.pp`: `xyz`}
+	for _, tc := range []struct {
+		change   *Change
+		expected string
+	}{
+		{&Change{Moves: []FileMove{{`x.pp`, `y.pp`}}}, `cannot move x.pp: no such file`},
+		{&Change{Moves: []FileMove{{`a.pp`, `b.pp`}}}, `cannot move a.pp: b.pp already exists`},
+		{&Change{Edits: []TextEdit{{File: `a.pp`, Offset: 1, Length: 3}, {File: `a.pp`, Offset: 2, Length: 1}}}, `cannot edit a.pp: the edit at offset 2 overlaps another edit or is out of range`},
+		{&Change{Edits: []TextEdit{{File: `a.pp`, Offset: 5, Length: 3}}}, `cannot edit a.pp: the edit at offset 5 overlaps another edit or is out of range`},
+		{&Change{Edits: []TextEdit{{File: `x.pp`, Offset: 1, Text: `x`}}}, `cannot edit x.pp: no such file`},
+		{&Change{Deletes: []string{`x.pp`}}, `cannot delete x.pp: no such file`},
+	} {
+		if _, err := tc.change.Apply(files); err == nil || err.Error() != tc.expected {
+			t.Errorf("expected error '%s', got %v", tc.expected, err)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package refactor
+
+import (
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-parser/parser"
+	"github.com/lyraproj/puppet-parser/validator"
+)
+
+func init() {
+	RegisterFixProvider(validator.VALIDATE_NEGATED_IF, negatedIfFixes)
+	RegisterFixProvider(validator.VALIDATE_DOUBLE_NEGATION, doubleNegationFixes)
+	RegisterFixProvider(validator.VALIDATE_ELSE_IF, elseIfFixes)
+}
+
+// negatedIfFixes replaces an 'if' statement with a negated condition with an 'unless' statement, e.g.
+// if !$x { ... } becomes unless $x { ... }
+func negatedIfFixes(program *parser.Program, reported issue.Reported, options *FixOptions) []*Fix {
+	e, _, ok := expressionAt[*parser.IfExpression](program, reported.Location())
+	if !ok {
+		return []*Fix{}
+	}
+	not, ok := e.Test().(*parser.NotExpression)
+	if !ok {
+		return []*Fix{}
+	}
+	return []*Fix{{
+		Title:  `Replace 'if !' with 'unless'`,
+		Change: Change{Edits: []TextEdit{{File: e.File(), Offset: sourceOffset(e), Length: len(`if`), Text: `unless`}, removeNot(not)}},
+	}}
+}
+
+// doubleNegationFixes replaces an 'unless' statement with a negated condition with an 'if' statement,
+// and removes a negation of a negation that is used as a condition. A double negation that is used as a
+// value isn't removed since it converts the value to a Boolean.
+func doubleNegationFixes(program *parser.Program, reported issue.Reported, options *FixOptions) []*Fix {
+	if e, _, ok := expressionAt[*parser.UnlessExpression](program, reported.Location()); ok {
+		not, ok := e.Test().(*parser.NotExpression)
+		if !ok {
+			return []*Fix{}
+		}
+		return []*Fix{{
+			Title:  `Replace 'unless !' with 'if'`,
+			Change: Change{Edits: []TextEdit{{File: e.File(), Offset: sourceOffset(e), Length: len(`unless`), Text: `if`}, removeNot(not)}},
+		}}
+	}
+
+	not, path, ok := expressionAt[*parser.NotExpression](program, reported.Location())
+	if !ok || !isCondition(not, path) {
+		return []*Fix{}
+	}
+	inner, ok := not.Expr().(*parser.NotExpression)
+	if !ok {
+		return []*Fix{}
+	}
+	start := sourceOffset(not)
+	return []*Fix{{
+		Title:  `Remove the double negation`,
+		Change: Change{Edits: []TextEdit{{File: not.File(), Offset: start, Length: sourceOffset(inner.Expr()) - start}}},
+	}}
+}
+
+// elseIfFixes replaces an 'else' branch that contains nothing but an 'if' statement with an 'elsif'
+// branch, e.g.
+//
+//	if $a {
+//	  ...
+//	} else {
+//	  if $b {
+//	    ...
+//	  }
+//	}
+//
+// becomes
+//
+//	if $a {
+//	  ...
+//	} elsif $b {
+//	  ...
+//	}
+//
+// The statements of the nested 'if' statement are indented one level less. No fix is provided when a
+// comment precedes the nested 'if' statement or when it contains a heredoc, since the text of the heredoc
+// would change.
+func elseIfFixes(program *parser.Program, reported issue.Reported, options *FixOptions) []*Fix {
+	nested, path, ok := expressionAt[*parser.IfExpression](program, reported.Location())
+	if !ok || len(path) < 2 || containsHeredoc(nested) {
+		return []*Fix{}
+	}
+	block, ok := path[len(path)-1].(*parser.BlockExpression)
+	if !ok || len(block.Statements()) != 1 {
+		return []*Fix{}
+	}
+	if outer, ok := path[len(path)-2].(*parser.IfExpression); !ok || outer.Else() != block {
+		return []*Fix{}
+	}
+
+	source := nested.Locator().String()
+	ifStart := sourceOffset(nested)
+	before := strings.TrimRight(source[:ifStart], " \t\r\n")
+	if !strings.HasSuffix(before, `{`) {
+		return []*Fix{}
+	}
+	before = strings.TrimRight(before[:len(before)-1], " \t\r\n")
+	if !strings.HasSuffix(before, `else`) {
+		return []*Fix{}
+	}
+	elseStart := len(before) - len(`else`)
+
+	// The closing brace of the 'else' branch is the first one that isn't opened after the nested 'if'
+	closeAt := -1
+	depth := 0
+	l := parser.NewLexer(nested.File(), source[ifStart:], parser.LEXER_ERROR_RECOVERY)
+	for closeAt < 0 && l.NextToken() != parser.TOKEN_END {
+		switch l.CurrentToken() {
+		case parser.TOKEN_LC, parser.TOKEN_SELC:
+			depth++
+		case parser.TOKEN_RC:
+			if depth--; depth < 0 {
+				closeAt = ifStart + l.TokenStartPos()
+			}
+		}
+	}
+	if closeAt < 0 {
+		return []*Fix{}
+	}
+	end := len(strings.TrimRight(source[:closeAt], " \t\r\n"))
+
+	body := source[ifStart+len(`if`) : end]
+	line := source[strings.LastIndexByte(source[:elseStart], '\n')+1 : elseStart]
+	outerIndent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+	if innerIndent := indentAt(source, ifStart); len(innerIndent) > len(outerIndent) && strings.HasPrefix(innerIndent, outerIndent) {
+		body = strings.ReplaceAll(body, "\n"+innerIndent, "\n"+outerIndent)
+	}
+	return []*Fix{{
+		Title:  `Replace 'else { if' with 'elsif'`,
+		Change: Change{Edits: []TextEdit{{File: nested.File(), Offset: elseStart, Length: closeAt + 1 - elseStart, Text: `elsif` + body}}},
+	}}
+}
+
+// removeNot returns an edit that removes the '!' operator of the given expression and the whitespace
+// that follows it
+func removeNot(not *parser.NotExpression) TextEdit {
+	start := sourceOffset(not)
+	return TextEdit{File: not.File(), Offset: start, Length: sourceOffset(not.Expr()) - start}
+}
+
+// isCondition returns true if the value of the expression at the end of the given path is only used as
+// a condition, i.e. as the test of an 'if' or 'unless' statement or as an operand of a logical operator
+func isCondition(e parser.Expression, path []parser.Expression) bool {
+	for i := len(path) - 1; i >= 0; i-- {
+		switch p := path[i].(type) {
+		case *parser.ParenthesizedExpression:
+			e = p
+			continue
+		case *parser.IfExpression:
+			return p.Test() == e
+		case *parser.UnlessExpression:
+			return p.Test() == e
+		case *parser.AndExpression, *parser.OrExpression, *parser.NotExpression:
+			return true
+		}
+		break
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package refactor
+
+import (
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-parser/validator"
+)
+
+func TestNegatedIfFixes(t *testing.T) {
+	expectFixes(t, "if ! $x { notice('a') }", validator.VALIDATE_NEGATED_IF, nil, map[string]string{
+		`Replace 'if !' with 'unless'`: "unless $x { notice('a') }",
+	})
+
+	expectFixes(t, "if !($x and $y) { notice('a') }", validator.VALIDATE_NEGATED_IF, nil, map[string]string{
+		`Replace 'if !' with 'unless'`: "unless ($x and $y) { notice('a') }",
+	})
+}
+
+func TestDoubleNegationFixes(t *testing.T) {
+	expectFixes(t, "unless !$x { notice('a') } else { notice('b') }", validator.VALIDATE_DOUBLE_NEGATION, nil, map[string]string{
+		`Replace 'unless !' with 'if'`: "if $x { notice('a') } else { notice('b') }",
+	})
+
+	expectFixes(t, "if $y and !!$x { notice('a') }", validator.VALIDATE_DOUBLE_NEGATION, nil, map[string]string{
+		`Remove the double negation`: "if $y and $x { notice('a') }",
+	})
+
+	expectFixes(t, "if (!!$x) { notice('a') }", validator.VALIDATE_DOUBLE_NEGATION, nil, map[string]string{
+		`Remove the double negation`: "if ($x) { notice('a') }",
+	})
+
+	expectFixes(t, "$b = !!$x", validator.VALIDATE_DOUBLE_NEGATION, nil, map[string]string{})
+}
+
+func TestElseIfFixes(t *testing.T) {
+	expectFixes(t, issue.Unindent(`
+    class a {
+      if $a {
+        notice('a')
+      } else {
+        if $b {
+          notice('b')
+        } elsif $c {
+          notice({ 'c' => 1 })
+        } else {
+          notice('d') # the rest
+        }
+      }
+    }
+    `), validator.VALIDATE_ELSE_IF, nil, map[string]string{
+		`Replace 'else { if' with 'elsif'`: issue.Unindent(`
+        class a {
+          if $a {
+            notice('a')
+          } elsif $b {
+            notice('b')
+          } elsif $c {
+            notice({ 'c' => 1 })
+          } else {
+            notice('d') # the rest
+          }
+        }
+        `),
+	})
+
+	expectFixes(t, "if $a { 1 } else { if $b { 2 } }", validator.VALIDATE_ELSE_IF, nil, map[string]string{
+		`Replace 'else { if' with 'elsif'`: "if $a { 1 } elsif $b { 2 }",
+	})
+
+	expectFixes(t, "if $a { 1 } else {\n  # b\n  if $b { 2 }\n}", validator.VALIDATE_ELSE_IF, nil, map[string]string{})
+}
@@ -0,0 +1,47 @@
+package refactor
+
+import (
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-parser/parser"
+	"github.com/lyraproj/puppet-parser/validator"
+)
+
+func init() {
+	RegisterFixProvider(validator.VALIDATE_LEGACY_FACT, legacyFactFixes)
+}
+
+// legacyFactFixes replaces a reference to a legacy fact with an access to the structured facts hash, e.g.
+// $osfamily becomes $facts['os']['family']. An interpolation such as "$osfamily" becomes
+// "${facts['os']['family']}".
+func legacyFactFixes(program *parser.Program, reported issue.Reported, options *FixOptions) []*Fix {
+	variable, path, ok := expressionAt[*parser.VariableExpression](program, reported.Location())
+	if !ok {
+		return []*Fix{}
+	}
+	name, _ := variable.Name()
+	replacement, ok := validator.LegacyFactReplacement(name)
+	if !ok {
+		return []*Fix{}
+	}
+
+	// The range of an interpolated variable starts with the text that precedes it in the string
+	text := variable.String()
+	offset := sourceOffset(variable)
+	var edit TextEdit
+	if i := strings.Index(text, `${`+name+`}`); i >= 0 {
+		edit = TextEdit{Offset: offset + i, Length: len(name) + 3, Text: `${` + replacement[1:] + `}`}
+	} else if i = strings.Index(text, `$`+name); i >= 0 {
+		edit = TextEdit{Offset: offset + i, Length: len(name) + 1, Text: replacement}
+		if len(path) > 0 {
+			if _, interpolated := path[len(path)-1].(*parser.TextExpression); interpolated {
+				edit.Text = `${` + replacement[1:] + `}`
+			}
+		}
+	} else {
+		return []*Fix{}
+	}
+	edit.File = variable.File()
+	return []*Fix{{Title: `Replace $` + name + ` with ` + replacement, Change: Change{Edits: []TextEdit{edit}}}}
+}
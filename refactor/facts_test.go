@@ -0,0 +1,25 @@
+package refactor
+
+import (
+	"testing"
+
+	"github.com/lyraproj/puppet-parser/validator"
+)
+
+func TestLegacyFactFixes(t *testing.T) {
+	expectFixes(t, "notice($osfamily)", validator.VALIDATE_LEGACY_FACT, nil, map[string]string{
+		`Replace $osfamily with $facts['os']['family']`: "notice($facts['os']['family'])",
+	})
+
+	expectFixes(t, "class a { $x = $::operatingsystem }", validator.VALIDATE_LEGACY_FACT, nil, map[string]string{
+		`Replace $::operatingsystem with $facts['os']['name']`: "class a { $x = $facts['os']['name'] }",
+	})
+
+	expectFixes(t, `notice("host ${::fqdn}!")`, validator.VALIDATE_LEGACY_FACT, nil, map[string]string{
+		`Replace $::fqdn with $facts['networking']['fqdn']`: `notice("host ${facts['networking']['fqdn']}!")`,
+	})
+
+	expectFixes(t, `notice("host $hostname.")`, validator.VALIDATE_LEGACY_FACT, nil, map[string]string{
+		`Replace $hostname with $facts['networking']['hostname']`: `notice("host ${facts['networking']['hostname']}.")`,
+	})
+}
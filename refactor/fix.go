@@ -0,0 +1,95 @@
+package refactor
+
+import (
+	"sync"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+type (
+	// Fix is a candidate fix of a reported issue
+	Fix struct {
+		// Title describes the fix to the user, e.g. "Rename $v to $_v"
+		Title string
+
+		// Change is the change that fixes the issue
+		Change
+	}
+
+	// FixOptions control the fixes that the fix providers produce
+	FixOptions struct {
+		// UnusedStyle is the naming convention of lambda parameters and variables that are intentionally
+		// unused
+		UnusedStyle UnusedStyle
+
+		// Header is the comment that the fix of a missing header inserts at the top of a manifest, see
+		// validator.RequireHeader. The issue has no fix when it's empty.
+		Header string
+	}
+
+	// FixProvider returns the candidate fixes of an issue that was reported for the given program, most
+	// preferred first, or an empty slice when the issue cannot be fixed
+	FixProvider func(program *parser.Program, reported issue.Reported, options *FixOptions) []*Fix
+
+	// UnusedStyle is a naming convention of lambda parameters and variables that are intentionally
+	// unused. The validator doesn't report unused names that start with an underscore.
+	UnusedStyle int
+)
+
+const (
+	// UNUSED_PREFIX prefixes the name of an unused parameter or variable with an underscore, so $v is
+	// renamed to $_v
+	UNUSED_PREFIX = UnusedStyle(iota)
+
+	// UNUSED_UNDERSCORE renames an unused parameter or variable to $_. UNUSED_PREFIX is used when $_ is
+	// already taken.
+	UNUSED_UNDERSCORE
+)
+
+var fixProvidersLock sync.RWMutex
+var fixProviders = map[issue.Code]FixProvider{}
+
+// RegisterFixProvider makes the given provider provide the fixes of issues with the given code. A
+// provider that was registered for the code before is replaced.
+func RegisterFixProvider(code issue.Code, provider FixProvider) {
+	fixProvidersLock.Lock()
+	fixProviders[code] = provider
+	fixProvidersLock.Unlock()
+}
+
+// Fixes returns the candidate fixes of the given issue, which was reported by a validation of the given
+// program, most preferred first. The options may be nil. An empty slice is returned when no fix
+// provider is registered for the code of the issue.
+func Fixes(program *parser.Program, reported issue.Reported, options *FixOptions) []*Fix {
+	fixProvidersLock.RLock()
+	provider, ok := fixProviders[reported.Code()]
+	fixProvidersLock.RUnlock()
+	if !ok {
+		return []*Fix{}
+	}
+	if options == nil {
+		options = &FixOptions{}
+	}
+	return provider(program, reported, options)
+}
+
+// expressionAt returns the expression of type T that starts at the given location and the path to it
+// from the program that was parsed from the file of the location
+func expressionAt[T parser.Expression](program *parser.Program, location issue.Location) (found T, path []parser.Expression, ok bool) {
+	file, isFile := program.Program(location.File())
+	if !isFile {
+		return
+	}
+	file.AllContents([]parser.Expression{}, func(p []parser.Expression, e parser.Expression) {
+		if t, isT := e.(T); isT && !ok && e.Line() == location.Line() && e.Pos() == location.Pos() {
+			found, path, ok = t, append([]parser.Expression{}, p...), true
+		}
+	})
+	return
+}
+
+// sourceOffset returns the byte offset of the given expression in the source that it was parsed from
+func sourceOffset(e parser.Expression) int {
+	return e.ByteOffset() - e.Locator().HostOffset(0)
+}
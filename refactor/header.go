@@ -0,0 +1,35 @@
+package refactor
+
+import (
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-parser/parser"
+	"github.com/lyraproj/puppet-parser/validator"
+)
+
+func init() {
+	RegisterFixProvider(validator.VALIDATE_MISSING_HEADER, missingHeaderFixes)
+}
+
+// missingHeaderFixes inserts the header of the options at the top of the manifest. The header is
+// separated from a comment that follows it by an empty line so that it doesn't become a part of the doc
+// comment of the first definition.
+func missingHeaderFixes(program *parser.Program, reported issue.Reported, options *FixOptions) []*Fix {
+	if options.Header == `` {
+		return []*Fix{}
+	}
+	file, ok := program.Program(reported.Location().File())
+	if !ok {
+		return []*Fix{}
+	}
+	header := options.Header
+	if !strings.HasSuffix(header, "\n") {
+		header += "\n"
+	}
+	if validator.LeadingComments(file) != `` {
+		header += "\n"
+	}
+	edit := TextEdit{File: file.File(), Offset: 0, Text: header}
+	return []*Fix{{Title: `Insert the required header`, Change: Change{Edits: []TextEdit{edit}}}}
+}
@@ -0,0 +1,51 @@
+package refactor
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/lyraproj/puppet-parser/parser"
+	"github.com/lyraproj/puppet-parser/validator"
+)
+
+func TestMissingHeaderFixes(t *testing.T) {
+	fix := func(source string, options *FixOptions) []*Fix {
+		t.Helper()
+		expr, err := parser.CreateParser().Parse(`x.pp`, source, false)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		v := validator.NewChecker(validator.STRICT_WARNING)
+		validator.RequireHeader(v, regexp.MustCompile(`\A# Copyright`))
+		validator.Validate(v, expr)
+		if len(v.Issues()) != 1 || v.Issues()[0].Code() != validator.VALIDATE_MISSING_HEADER {
+			t.Fatalf("expected a %s issue", validator.VALIDATE_MISSING_HEADER)
+		}
+		return Fixes(expr.(*parser.Program), v.Issues()[0], options)
+	}
+
+	expected := map[string]string{
+		"class a {}":            "# Copyright 2026 Example Inc.\nclass a {}",
+		"# A class\nclass a {}": "# Copyright 2026 Example Inc.\n\n# A class\nclass a {}",
+	}
+	for source, result := range expected {
+		fixes := fix(source, &FixOptions{Header: `# Copyright 2026 Example Inc.`})
+		if len(fixes) != 1 {
+			t.Fatalf("expected 1 fix, got %d", len(fixes))
+		}
+		if fixes[0].Title != `Insert the required header` {
+			t.Errorf("unexpected fix '%s'", fixes[0].Title)
+		}
+		changed, err := fixes[0].Apply(map[string]string{`x.pp`: source})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if changed[`x.pp`] != result {
+			t.Errorf("expected %q, got %q", result, changed[`x.pp`])
+		}
+	}
+
+	if fixes := fix(`class a {}`, nil); len(fixes) != 0 {
+		t.Errorf("expected no fix without a header, got %d", len(fixes))
+	}
+}
@@ -0,0 +1,267 @@
+package refactor
+
+import (
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-parser/parser"
+	"github.com/lyraproj/puppet-parser/printer"
+	"github.com/lyraproj/puppet-parser/validator"
+)
+
+func init() {
+	RegisterFixProvider(validator.VALIDATE_STRING_TOO_LONG, longStringFixes)
+}
+
+// longStringFixes converts a string that is too long to a heredoc, which has its text on lines of its own
+func longStringFixes(program *parser.Program, reported issue.Reported, options *FixOptions) []*Fix {
+	var e parser.Expression
+	if cs, _, ok := expressionAt[*parser.ConcatenatedString](program, reported.Location()); ok {
+		e = cs
+	} else if ls, _, ok := expressionAt[*parser.LiteralString](program, reported.Location()); ok {
+		e = ls
+	}
+	if e != nil {
+		if fix, ok := StringToHeredoc(program, e); ok {
+			return []*Fix{fix}
+		}
+	}
+	return []*Fix{}
+}
+
+// StringToHeredoc returns a fix that converts the given quoted string, a literal or an interpolated
+// string, to a heredoc with the same value, e.g.
+//
+//	$motd = "Welcome to ${facts['fqdn']}\nManaged by Puppet\n" # the banner
+//
+// becomes
+//
+//	$motd = @("END") # the banner
+//	  Welcome to ${$facts['fqdn']}
+//	  Managed by Puppet
+//	  | END
+//
+// The end tag is one that no line of the text conflicts with and the margin is the indentation of the
+// line of the string plus one level. The rest of the line, including comments, is retained. False is
+// returned when the string isn't quoted, when it's in an interpolation or in an EPP template, when
+// another heredoc starts on the line where the string ends, and when the changed file wouldn't parse to
+// the same program with the string in a heredoc.
+func StringToHeredoc(program *parser.Program, e parser.Expression) (*Fix, bool) {
+	switch e.(type) {
+	case *parser.LiteralString, *parser.ConcatenatedString:
+	default:
+		return nil, false
+	}
+	file, ok := program.Program(e.File())
+	if !ok {
+		return nil, false
+	}
+	if _, epp := file.Body().(*parser.LambdaExpression); epp {
+		return nil, false
+	}
+	path, ok := pathTo(program, e)
+	if !ok {
+		return nil, false
+	}
+	for _, p := range path {
+		switch p.(type) {
+		case *parser.ConcatenatedString, *parser.TextExpression, *parser.HeredocExpression:
+			return nil, false
+		}
+	}
+	src := e.String()
+	if len(src) < 2 || !(src[0] == '\'' || src[0] == '"') || src[len(src)-1] != src[0] {
+		return nil, false
+	}
+
+	source := e.Locator().String()
+	start := sourceOffset(e)
+	end := start + len(src)
+	lineEnd := strings.IndexByte(source[end:], '\n')
+	if lineEnd < 0 {
+		lineEnd = len(source)
+	} else {
+		lineEnd += end
+	}
+	if heredocOnLine(file, e, source, lineEnd) {
+		return nil, false
+	}
+
+	heredoc := parser.DefaultFactory().Heredoc(e, ``, parser.NewSyntheticLocator(`refactor`, e), 0, 0)
+	header, body, _ := strings.Cut(printer.String(heredoc), "\n")
+	line := source[strings.LastIndexByte(source[:start], '\n')+1 : start]
+	indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+	lines := strings.SplitAfter(body, "\n")
+	for i, l := range lines {
+		if l != "\n" && l != `` {
+			lines[i] = indent + l
+		}
+	}
+	body = strings.Join(lines, ``)
+
+	insert := TextEdit{File: e.File(), Offset: lineEnd + 1, Text: body}
+	if lineEnd == len(source) {
+		insert = TextEdit{File: e.File(), Offset: lineEnd, Text: "\n" + strings.TrimSuffix(body, "\n")}
+	}
+	fix := &Fix{
+		Title:  `Convert the string to a heredoc`,
+		Change: Change{Edits: []TextEdit{{File: e.File(), Offset: start, Length: len(src), Text: header}, insert}},
+	}
+	if !sameProgram(file, fix) {
+		return nil, false
+	}
+	return fix, true
+}
+
+// HeredocToString returns a fix that converts the given heredoc to a quoted string with the same value.
+// The string is single quoted when the heredoc has no interpolation and a single quoted string needs no
+// more escapes than a double quoted one. The rest of the line of the heredoc declaration, including
+// comments, is retained. False is returned when the heredoc has a syntax, since the syntax would be lost,
+// and when another heredoc starts on the same line.
+func HeredocToString(program *parser.Program, h *parser.HeredocExpression) (*Fix, bool) {
+	if h.Syntax() != `` {
+		return nil, false
+	}
+	file, ok := program.Program(h.File())
+	if !ok {
+		return nil, false
+	}
+	var text string
+	switch t := h.Text().(type) {
+	case *parser.LiteralString:
+		text = printer.Quote(t.StringValue())
+	case *parser.ConcatenatedString:
+		text = printer.String(t)
+	default:
+		return nil, false
+	}
+
+	source := h.Locator().String()
+	start := sourceOffset(h)
+	headerEnd := strings.IndexByte(source[start:], ')')
+	lineEnd := strings.IndexByte(source[start:], '\n')
+	if headerEnd < 0 || lineEnd < headerEnd {
+		return nil, false
+	}
+	headerEnd += start + 1
+	lineEnd += start
+	if heredocOnLine(file, h, source, lineEnd) {
+		return nil, false
+	}
+
+	// The text ends where the line of the end marker starts, or before the newline that precedes it
+	// when the marker is '|-'
+	markerStart := start + h.ByteLength()
+	if strings.HasPrefix(source[markerStart:], "\r\n") {
+		markerStart += 2
+	} else if strings.HasPrefix(source[markerStart:], "\n") {
+		markerStart++
+	}
+	markerEnd := strings.IndexByte(source[markerStart:], '\n')
+	if markerEnd < 0 {
+		markerEnd = len(source)
+	} else {
+		markerEnd += markerStart + 1
+	}
+	return &Fix{
+		Title: `Convert the heredoc to a string`,
+		Change: Change{Edits: []TextEdit{
+			{File: h.File(), Offset: start, Length: headerEnd - start, Text: text},
+			{File: h.File(), Offset: lineEnd + 1, Length: markerEnd - (lineEnd + 1)}}},
+	}, true
+}
+
+// heredocOnLine returns true if a heredoc of the given program other than the given one starts on the
+// line of the given source that ends at the given offset
+func heredocOnLine(program *parser.Program, except parser.Expression, source string, lineEnd int) bool {
+	lineStart := strings.LastIndexByte(source[:lineEnd], '\n') + 1
+	found := false
+	program.AllContents([]parser.Expression{}, func(_ []parser.Expression, e parser.Expression) {
+		if h, ok := e.(*parser.HeredocExpression); ok && e != except {
+			offset := sourceOffset(h)
+			found = found || offset >= lineStart && offset < lineEnd
+		}
+	})
+	return found
+}
+
+// sameProgram returns true if the file of the given program, changed by the given fix, parses to the
+// same program except for one string that the change wraps in a heredoc
+func sameProgram(file *parser.Program, fix *Fix) bool {
+	changed, err := fix.Apply(map[string]string{file.File(): file.Locator().String()})
+	if err != nil {
+		return false
+	}
+	parsed, _ := validator.ParseAuto(file.File(), changed[file.File()])
+	if parsed == nil {
+		return false
+	}
+	wrapped := 0
+	return sameData(file.ToPN().ToData(), parsed.ToPN().ToData(), &wrapped) && wrapped == 1
+}
+
+// sameData returns true if the given PN data of a program is equal to the given PN data of the changed
+// program, where a heredoc in the changed program is equal to its text. The number of such heredocs is
+// added to wrapped.
+func sameData(original, changed interface{}, wrapped *int) bool {
+	before := *wrapped
+	if equalData(original, changed, wrapped) {
+		return true
+	}
+	*wrapped = before
+	if text, ok := heredocText(changed); ok && equalData(original, text, wrapped) {
+		*wrapped++
+		return true
+	}
+	return false
+}
+
+func equalData(original, changed interface{}, wrapped *int) bool {
+	switch o := original.(type) {
+	case []interface{}:
+		c, ok := changed.([]interface{})
+		if !ok || len(o) != len(c) {
+			return false
+		}
+		for i := range o {
+			if !sameData(o[i], c[i], wrapped) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		c, ok := changed.(map[string]interface{})
+		if !ok || len(o) != len(c) {
+			return false
+		}
+		for k, v := range o {
+			if !sameData(v, c[k], wrapped) {
+				return false
+			}
+		}
+		return true
+	default:
+		return original == changed
+	}
+}
+
+// heredocText returns the PN data of the text of the given PN data of a heredoc without a syntax
+func heredocText(data interface{}) (interface{}, bool) {
+	m, ok := data.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	call, ok := m[`^`].([]interface{})
+	if !ok || len(call) != 2 || call[0] != `heredoc` {
+		return nil, false
+	}
+	entries, ok := call[1].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	text, ok := entries[`#`].([]interface{})
+	if !ok || len(text) != 2 || text[0] != `text` {
+		return nil, false
+	}
+	return text[1], true
+}
@@ -0,0 +1,129 @@
+package refactor
+
+import (
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-parser/parser"
+	"github.com/lyraproj/puppet-parser/validator"
+)
+
+func stringToHeredoc(program *parser.Program, e parser.Expression) (*Fix, bool) {
+	switch e.(type) {
+	case *parser.LiteralString, *parser.ConcatenatedString:
+		return StringToHeredoc(program, e)
+	}
+	return nil, false
+}
+
+func heredocToString(program *parser.Program, e parser.Expression) (*Fix, bool) {
+	if h, ok := e.(*parser.HeredocExpression); ok {
+		return HeredocToString(program, h)
+	}
+	return nil, false
+}
+
+func TestStringToHeredoc(t *testing.T) {
+	expectRewrite(t, issue.Unindent(`
+    class a {
+      $motd = "Welcome to ${facts['fqdn']}\nCosts \$5\n" # the banner
+      notice($motd)
+    }
+    `), stringToHeredoc, issue.Unindent(`
+    class a {
+      $motd = @("END"/$) # the banner
+        Welcome to ${$facts['fqdn']}
+        Costs \$5
+        | END
+      notice($motd)
+    }
+    `))
+
+	expectRewrite(t, "notice('C:\\\\Temp', 1)", stringToHeredoc, "notice(@(END), 1)\n  C:\\Temp\n  |- END")
+
+	expectRewrite(t, "$x = 'END\n\nline'\n", stringToHeredoc, "$x = @(END1)\n  END\n\n  line\n  |- END1\n")
+
+	expectRewrite(t, "$x = [@(END), 'a']\n  b\n  | END\n", stringToHeredoc, ``)
+
+	expectRewrite(t, "$x = a\n", stringToHeredoc, ``)
+
+	expectRewrite(t, "Service['apache'] { require +> 'x' }\n", stringToHeredoc,
+		"Service[@(END)] { require +> 'x' }\n  apache\n  |- END\n")
+
+	expectRewrite(t, "File['/tmp/foo'] { mode => 'x' }\n", stringToHeredoc,
+		"File[@(END)] { mode => 'x' }\n  /tmp/foo\n  |- END\n")
+
+	// The rest of the line after the heredoc of 'a.pp' would start a comment
+	expectRewrite(t, "import 'a.pp','b/*.pp'\n", stringToHeredoc, "import 'a.pp',@(END)\n  b/*.pp\n  |- END\n")
+}
+
+func TestHeredocToString(t *testing.T) {
+	expectRewrite(t, issue.Unindent(`
+    class a {
+      $motd = @("END") # the banner
+        Welcome to ${facts['fqdn']}
+        | END
+      notice(@(END), 1)
+        it's
+        |- END
+    }
+    `), heredocToString, issue.Unindent(`
+    class a {
+      $motd = "Welcome to ${$facts['fqdn']}\n" # the banner
+      notice(@(END), 1)
+        it's
+        |- END
+    }
+    `))
+
+	expectRewrite(t, "notice(@(END), 1)\n  it's\n  |- END\n", heredocToString, "notice(\"it's\", 1)\n")
+
+	expectRewrite(t, "$x = @(END:json)\n  {}\n  | END\n", heredocToString, ``)
+}
+
+func TestLongStringFixes(t *testing.T) {
+	program, _ := validate(t, `$x = 'a long string'`)
+	v := validator.NewChecker(validator.STRICT_WARNING)
+	validator.ApplyMaxStringLength(v, 5)
+	validator.Validate(v, program)
+	issues := v.Issues()
+	if len(issues) != 1 {
+		t.Fatalf("expected 1 issue, got %d", len(issues))
+	}
+	fixes := Fixes(program, issues[0], nil)
+	if len(fixes) != 1 || fixes[0].Title != `Convert the string to a heredoc` {
+		t.Fatalf("expected a conversion to a heredoc")
+	}
+	result, err := fixes[0].Apply(map[string]string{`x.pp`: `$x = 'a long string'`})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if expected := "$x = @(END)\n  a long string\n  |- END"; result[`x.pp`] != expected {
+		t.Errorf("expected %q, got %q", expected, result[`x.pp`])
+	}
+
+	source := "import 'a.pp','b/*.pp'\n"
+	program, _ = validate(t, source)
+	v = validator.NewChecker(validator.STRICT_WARNING)
+	validator.ApplyMaxStringLength(v, 3)
+	validator.Validate(v, program)
+	fixed := 0
+	for _, ri := range v.Issues() {
+		if ri.Code() != validator.VALIDATE_STRING_TOO_LONG {
+			continue
+		}
+		for _, fix := range Fixes(program, ri, nil) {
+			result, err := fix.Apply(map[string]string{`x.pp`: source})
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			if expected := "import 'a.pp',@(END)\n  b/*.pp\n  |- END\n"; result[`x.pp`] != expected {
+				t.Errorf("expected %q, got %q", expected, result[`x.pp`])
+			}
+			fixed++
+		}
+	}
+	if fixed != 1 {
+		t.Errorf("expected 1 fix, got %d", fixed)
+	}
+}
@@ -0,0 +1,187 @@
+package refactor
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-parser/parser"
+	"github.com/lyraproj/puppet-parser/printer"
+	"github.com/lyraproj/puppet-parser/validator"
+)
+
+func init() {
+	RegisterFixProvider(validator.VALIDATE_CREATE_RESOURCES, createResourcesFixes)
+	RegisterFixProvider(validator.VALIDATE_RESOURCE_ITERATION, resourceIterationFixes)
+}
+
+var namePattern = regexp.MustCompile(`\A(?:::)?[a-z][a-z0-9_]*(?:::[a-z][a-z0-9_]*)*\z`)
+
+// createResourcesFixes replaces a create_resources statement with an iteration over the hash that
+// declares each resource, e.g. create_resources('file', $files, $defaults) becomes
+//
+//	$files.each |$title, $params| {
+//	  file { $title: * => $defaults + $params }
+//	}
+func createResourcesFixes(program *parser.Program, reported issue.Reported, options *FixOptions) []*Fix {
+	call, path, ok := expressionAt[*parser.CallNamedFunctionExpression](program, reported.Location())
+	if !ok || !isStatement(call, path) || call.Lambda() != nil {
+		return []*Fix{}
+	}
+	args := call.Arguments()
+	if len(args) < 2 || len(args) > 3 {
+		return []*Fix{}
+	}
+	ts, ok := args[0].(*parser.LiteralString)
+	if !ok {
+		return []*Fix{}
+	}
+	typeName := strings.TrimPrefix(strings.ToLower(ts.StringValue()), `::`)
+	if !namePattern.MatchString(typeName) {
+		return []*Fix{}
+	}
+	for _, arg := range args[1:] {
+		if refersTo(arg, `title`, `params`) {
+			return []*Fix{}
+		}
+	}
+
+	var receiver string
+	switch args[1].(type) {
+	case *parser.VariableExpression, *parser.AccessExpression, *parser.LiteralHash, *parser.CallNamedFunctionExpression:
+		receiver = printer.String(args[1]) + `.each`
+	default:
+		receiver = `each(` + printer.String(args[1]) + `)`
+	}
+	params := `$params`
+	if len(args) == 3 {
+		params = printer.String(args[2]) + ` + $params`
+	}
+
+	text := call.String()
+	if !strings.HasSuffix(text, `)`) {
+		return []*Fix{}
+	}
+	source := call.Locator().String()
+	offset := sourceOffset(call)
+	indent := indentAt(source, offset)
+	replacement := receiver + " |$title, $params| {\n" + indent + `  ` + typeName + ` { $title: * => ` + params + " }\n" + indent + `}`
+	return []*Fix{{
+		Title:  `Replace create_resources with an iteration`,
+		Change: Change{Edits: []TextEdit{{File: call.File(), Offset: offset, Length: len(text), Text: replacement}}},
+	}}
+}
+
+// resourceIterationFixes replaces an iteration over a literal hash that declares one resource per entry
+// with a declaration of all the resources. The fix is only provided when every value of the hash is a
+// literal hash of attributes.
+func resourceIterationFixes(program *parser.Program, reported issue.Reported, options *FixOptions) []*Fix {
+	var receiver, lambda parser.Expression
+	var call parser.Expression
+	var path []parser.Expression
+	if mc, p, ok := expressionAt[*parser.CallMethodExpression](program, reported.Location()); ok {
+		if na, ok := mc.Functor().(*parser.NamedAccessExpression); ok {
+			call, path, receiver, lambda = mc, p, na.Lhs(), mc.Lambda()
+		}
+	} else if fc, p, ok := expressionAt[*parser.CallNamedFunctionExpression](program, reported.Location()); ok && len(fc.Arguments()) == 1 {
+		call, path, receiver, lambda = fc, p, fc.Arguments()[0], fc.Lambda()
+	}
+	if call == nil || !isStatement(call, path) {
+		return []*Fix{}
+	}
+	typeName, ok := validator.ResourceIteration(receiver, lambda)
+	if !ok {
+		return []*Fix{}
+	}
+
+	f := parser.DefaultFactory()
+	locator := parser.NewSyntheticLocator(`refactor`, call)
+	entries := receiver.(*parser.LiteralHash).Entries()
+	bodies := make([]parser.Expression, 0, len(entries))
+	for _, entry := range entries {
+		ke := entry.(*parser.KeyedEntry)
+		attrs, ok := ke.Value().(*parser.LiteralHash)
+		if !ok {
+			return []*Fix{}
+		}
+		ops := make([]parser.Expression, 0, len(attrs.Entries()))
+		for _, a := range attrs.Entries() {
+			ae := a.(*parser.KeyedEntry)
+			var name string
+			switch key := ae.Key().(type) {
+			case *parser.LiteralString:
+				name = key.StringValue()
+			case *parser.QualifiedName:
+				name = key.Name()
+			}
+			if !namePattern.MatchString(name) || strings.Contains(name, `::`) {
+				return []*Fix{}
+			}
+			ops = append(ops, f.AttributeOp(`=>`, name, ae.Value(), locator, 0, 0))
+		}
+		bodies = append(bodies, f.ResourceBody(ke.Key(), ops, locator, 0, 0))
+	}
+	if len(bodies) == 0 {
+		return []*Fix{}
+	}
+	resource := f.Resource(parser.REGULAR, f.QualifiedName(typeName, locator, 0, 0), bodies, locator, 0, 0)
+
+	source := call.Locator().String()
+	start := sourceOffset(call)
+	end := sourceOffset(lambda) + len(lambda.String())
+	indent := indentAt(source, start)
+	replacement := strings.ReplaceAll(strings.TrimRight(printer.String(resource), "\n"), "\n", "\n"+indent)
+	return []*Fix{{
+		Title:  `Declare the ` + typeName + ` resources directly`,
+		Change: Change{Edits: []TextEdit{{File: call.File(), Offset: start, Length: end - start, Text: replacement}}},
+	}}
+}
+
+// isStatement returns true if the expression at the end of the given path is a statement of a block, or
+// the only statement of a program
+func isStatement(e parser.Expression, path []parser.Expression) bool {
+	if len(path) == 0 {
+		return false
+	}
+	if program, ok := path[len(path)-1].(*parser.Program); ok {
+		return program.Body() == e
+	}
+	block, ok := path[len(path)-1].(*parser.BlockExpression)
+	if !ok {
+		return false
+	}
+	for _, s := range block.Statements() {
+		if s == e {
+			return true
+		}
+	}
+	return false
+}
+
+// refersTo returns true if the given expression contains a reference to a variable with one of the
+// given names
+func refersTo(e parser.Expression, names ...string) bool {
+	found := false
+	check := func(_ []parser.Expression, e parser.Expression) {
+		if ve, ok := e.(*parser.VariableExpression); ok {
+			if n, ok := ve.Name(); ok {
+				for _, name := range names {
+					found = found || n == name
+				}
+			}
+		}
+	}
+	check(nil, e)
+	e.AllContents([]parser.Expression{}, check)
+	return found
+}
+
+// indentAt returns the whitespace that precedes the given offset on its line, or an empty string when
+// the line has other text before the offset
+func indentAt(source string, offset int) string {
+	lineStart := strings.LastIndexByte(source[:offset], '\n') + 1
+	if indent := source[lineStart:offset]; strings.TrimSpace(indent) == `` {
+		return indent
+	}
+	return ``
+}
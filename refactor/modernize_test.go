@@ -0,0 +1,42 @@
+package refactor
+
+import (
+	"testing"
+
+	"github.com/lyraproj/puppet-parser/validator"
+)
+
+func TestCreateResourcesFixes(t *testing.T) {
+	expectFixes(t, "class a {\n  create_resources('file', $files)\n}\n", validator.VALIDATE_CREATE_RESOURCES, nil, map[string]string{
+		`Replace create_resources with an iteration`: "class a {\n  $files.each |$title, $params| {\n    file { $title: * => $params }\n  }\n}\n",
+	})
+
+	expectFixes(t, "create_resources('::Mymod::Vhost', lookup('vhosts'), { 'port' => 80 })\n", validator.VALIDATE_CREATE_RESOURCES, nil, map[string]string{
+		`Replace create_resources with an iteration`: "lookup('vhosts').each |$title, $params| {\n  mymod::vhost { $title: * => {'port' => 80} + $params }\n}\n",
+	})
+
+	expectFixes(t, "create_resources('user', $a + $b)", validator.VALIDATE_CREATE_RESOURCES, nil, map[string]string{
+		`Replace create_resources with an iteration`: "each($a + $b) |$title, $params| {\n  user { $title: * => $params }\n}",
+	})
+
+	expectFixes(t, "create_resources($type, $files)", validator.VALIDATE_CREATE_RESOURCES, nil, map[string]string{})
+
+	expectFixes(t, "create_resources('file', $params)", validator.VALIDATE_CREATE_RESOURCES, nil, map[string]string{})
+
+	expectFixes(t, "$x = create_resources('file', $files)", validator.VALIDATE_CREATE_RESOURCES, nil, map[string]string{})
+}
+
+func TestResourceIterationFixes(t *testing.T) {
+	source := "class a {\n  {\n    '/a' => { 'ensure' => 'file', mode => '0644' },\n    '/b' => {},\n  }.each |$k, $v| {\n    file { $k: * => $v }\n  }\n}\n"
+	expectFixes(t, source, validator.VALIDATE_RESOURCE_ITERATION, nil, map[string]string{
+		`Declare the file resources directly`: "class a {\n  file {\n    '/a':\n      ensure => 'file',\n      mode   => '0644';\n    '/b':;\n  }\n}\n",
+	})
+
+	expectFixes(t, "each({'x' => {}}) |$k, $v| { user { $k: * => $v } }", validator.VALIDATE_RESOURCE_ITERATION, nil, map[string]string{
+		`Declare the user resources directly`: "user { 'x': }",
+	})
+
+	expectFixes(t, "{'x' => $x}.each |$k, $v| { user { $k: * => $v } }", validator.VALIDATE_RESOURCE_ITERATION, nil, map[string]string{})
+
+	expectFixes(t, "{'x' => {'a::b' => 1}}.each |$k, $v| { user { $k: * => $v } }", validator.VALIDATE_RESOURCE_ITERATION, nil, map[string]string{})
+}
@@ -0,0 +1,222 @@
+package refactor
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+// DefinitionMove is the result of MoveDefinition
+type DefinitionMove struct {
+	// Change moves the definition. It is empty when the definition is already in the right file.
+	Change
+
+	// Name is the name of the moved definition
+	Name string
+
+	// From is the file that contains the definition and To is the file that Puppet autoloads it from
+	From, To string
+
+	// References are the expressions that refer to the definition by name, in the order that they
+	// appear in the program. The name of the definition doesn't change so the references need no
+	// edits, but they tell where the definition is used.
+	References []parser.Expression
+}
+
+// MoveDefinition computes the change that moves the given class or defined type to the file that Puppet
+// autoloads it from, see AutoloadPath, in the module that contains its current file, see ModuleRoot. The
+// given program, typically composed of all files of a module, is used to find out if that file exists
+// and to find the references to the definition.
+//
+// The definition is moved together with the comments that precede it, which typically are its
+// documentation. When nothing else remains in its current file, the file is moved, or deleted when the
+// target file exists. When the target file exists, the definition is appended to it.
+//
+// An error is returned when the definition isn't a class or a defined type, when the module of its file
+// cannot be determined, and when the target file already contains a definition with the same name.
+func MoveDefinition(program *parser.Program, def parser.Definition) (*DefinitionMove, error) {
+	var name string
+	switch d := def.(type) {
+	case *parser.HostClassDefinition:
+		name = d.Name()
+	case *parser.ResourceTypeDefinition:
+		name = d.Name()
+	default:
+		return nil, fmt.Errorf(`only classes and defined types can be moved`)
+	}
+
+	text, r := program.SourceOf(def)
+	if text == `` {
+		return nil, fmt.Errorf(`%s is not defined in the given program`, name)
+	}
+	from := def.File()
+	root, ok := ModuleRoot(from)
+	if !ok {
+		return nil, fmt.Errorf(`cannot determine the module of %s`, from)
+	}
+	_, file, ok := AutoloadPath(def)
+	if !ok {
+		return nil, fmt.Errorf(`%s is not autoloaded`, name)
+	}
+	to := path.Join(root, file)
+
+	move := &DefinitionMove{Name: name, From: from, To: to, References: References(program, def)}
+	if path.Clean(from) == to {
+		return move, nil
+	}
+
+	target, targetExists := program.Program(to)
+	if targetExists {
+		if _, found := target.DefinitionByName(name); found {
+			return nil, fmt.Errorf(`%s is already defined in %s`, name, to)
+		}
+	}
+
+	source := def.Locator().String()
+	start, end := moveRange(source, r.Offset, r.Offset+r.Length)
+	if strings.TrimSpace(source[:start]+source[end:]) == `` {
+		// Nothing but whitespace remains in the file
+		if !targetExists {
+			move.Moves = []FileMove{{from, to}}
+			return move, nil
+		}
+		move.Deletes = []string{from}
+	} else {
+		move.Edits = append(move.Edits, TextEdit{File: from, Offset: start, Length: end - start})
+	}
+
+	moved := strings.TrimRight(source[start:end], " \t\r\n") + "\n"
+	if targetExists {
+		ts := target.Locator().String()
+		sep := "\n"
+		if ts != `` && !strings.HasSuffix(ts, "\n") {
+			sep = "\n\n"
+		}
+		move.Edits = append(move.Edits, TextEdit{File: to, Offset: len(ts), Text: sep + moved})
+	} else {
+		move.Edits = append(move.Edits, TextEdit{File: to, Text: moved})
+	}
+	return move, nil
+}
+
+// moveRange extends the range of a definition in the given source to the start of its line and the
+// comment lines that immediately precede it, and to the end of its line, including the line break. One
+// blank line that separates the definition from the surrounding text is included too. The range is not
+// extended when the definition shares a line with other text.
+func moveRange(source string, start, end int) (int, int) {
+	lineStart := strings.LastIndexByte(source[:start], '\n') + 1
+	if strings.TrimSpace(source[lineStart:start]) == `` {
+		start = lineStart
+		for start > 0 {
+			prev := strings.LastIndexByte(source[:start-1], '\n') + 1
+			if !strings.HasPrefix(strings.TrimSpace(source[prev:start]), `#`) {
+				break
+			}
+			start = prev
+		}
+	}
+	if next := lineEnd(source, end); strings.TrimSpace(source[end:next]) == `` {
+		end = next
+		if start <= lineStart {
+			// Remove one of the blank lines that separate the definition from the surrounding text
+			if next = lineEnd(source, end); next > end && strings.TrimSpace(source[end:next]) == `` {
+				end = next
+			} else if end == len(source) && start > 1 && source[start-2] == '\n' {
+				start--
+			}
+		}
+	}
+	return start, end
+}
+
+// lineEnd returns the offset just after the line break of the line of the given offset, or the length of
+// the source when the line has no line break
+func lineEnd(source string, offset int) int {
+	if i := strings.IndexByte(source[offset:], '\n'); i >= 0 {
+		return offset + i + 1
+	}
+	return len(source)
+}
+
+// References returns the expressions of the given program that refer to the given class or defined type
+// by name, in the order that they appear in the program. References to a class are its names in calls
+// to include, contain, and require, titles of class resources, keys of Class references, and the
+// inherits clauses of other classes. References to a defined type are resource expressions of the type
+// and type references such as Mymod::Vhost['x'].
+func References(program *parser.Program, def parser.Definition) []parser.Expression {
+	refs := make([]parser.Expression, 0)
+	switch d := def.(type) {
+	case *parser.HostClassDefinition:
+		name := d.Name()
+		program.AllContents(nil, func(p []parser.Expression, e parser.Expression) {
+			switch e := e.(type) {
+			case *parser.CallNamedFunctionExpression:
+				if fn, ok := e.Functor().(*parser.QualifiedName); ok && classFunctions[fn.Name()] && anyNames(e.Arguments(), name) {
+					refs = append(refs, e)
+				}
+			case *parser.ResourceExpression:
+				if tn, ok := e.TypeName().(*parser.QualifiedName); ok && tn.Name() == `class` {
+					for _, b := range e.Bodies() {
+						if anyNames([]parser.Expression{b.(*parser.ResourceBody).Title()}, name) {
+							refs = append(refs, b)
+						}
+					}
+				}
+			case *parser.AccessExpression:
+				if qr, ok := e.Operand().(*parser.QualifiedReference); ok && qr.DowncasedName() == `class` && anyNames(e.Keys(), name) {
+					refs = append(refs, e)
+				}
+			case *parser.HostClassDefinition:
+				if e != def && e.ParentClass() != `` && sameName(e.ParentClass(), name) {
+					refs = append(refs, e)
+				}
+			}
+		})
+	case *parser.ResourceTypeDefinition:
+		name := d.Name()
+		program.AllContents(nil, func(p []parser.Expression, e parser.Expression) {
+			switch e := e.(type) {
+			case *parser.ResourceExpression:
+				if tn, ok := e.TypeName().(*parser.QualifiedName); ok && sameName(tn.Name(), name) {
+					refs = append(refs, e)
+				}
+			case *parser.QualifiedReference:
+				if sameName(e.DowncasedName(), name) {
+					refs = append(refs, e)
+				}
+			}
+		})
+	}
+	return refs
+}
+
+// classFunctions are the functions that declare the classes named by their arguments
+var classFunctions = map[string]bool{`include`: true, `contain`: true, `require`: true}
+
+// anyNames returns true if one of the given expressions, or one of the elements of an array among them,
+// is a name or a string that is equal to the given name
+func anyNames(exprs []parser.Expression, name string) bool {
+	for _, e := range exprs {
+		switch e := e.(type) {
+		case *parser.QualifiedName:
+			if sameName(e.Name(), name) {
+				return true
+			}
+		case *parser.LiteralString:
+			if sameName(e.StringValue(), name) {
+				return true
+			}
+		case *parser.LiteralList:
+			if anyNames(e.Elements(), name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func sameName(a, b string) bool {
+	return strings.EqualFold(strings.TrimPrefix(a, `::`), strings.TrimPrefix(b, `::`))
+}
@@ -0,0 +1,137 @@
+package refactor
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+func parseFiles(t *testing.T, files map[string]string) *parser.Program {
+	t.Helper()
+	p := parser.CreateParser()
+	programs := make([]*parser.Program, 0, len(files))
+	for _, file := range []string{`mymod/manifests/init.pp`, `mymod/manifests/vhost.pp`, `mymod/manifests/site.pp`} {
+		if source, ok := files[file]; ok {
+			expr, err := p.Parse(file, source, false)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			programs = append(programs, expr.(*parser.Program))
+		}
+	}
+	return parser.ComposePrograms(programs...)
+}
+
+func TestMoveDefinition(t *testing.T) {
+	files := map[string]string{
+		`mymod/manifests/init.pp`: "class mymod {\n  include mymod::config\n  mymod::vhost { 'a': }\n}\n\n" +
+			"# Configures the module\n#\n# @param p the p\nclass mymod::config($p = 1) {\n  notice($p)\n}\n\n" +
+			"class mymod::other inherits mymod::config {}\n",
+		`mymod/manifests/site.pp`: "class { 'mymod::config': p => 2 }\nMymod::Vhost { owner => root }\nClass['Mymod::Config'] -> Class[mymod]\n",
+	}
+	program := parseFiles(t, files)
+	def, _ := program.DefinitionByName(`mymod::config`)
+	move, err := MoveDefinition(program, def)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if move.From != `mymod/manifests/init.pp` || move.To != `mymod/manifests/config.pp` || len(move.Moves) != 0 || len(move.Deletes) != 0 {
+		t.Errorf("unexpected move %v", move)
+	}
+	result, err := move.Apply(files)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expected := map[string]string{
+		`mymod/manifests/init.pp`:   "class mymod {\n  include mymod::config\n  mymod::vhost { 'a': }\n}\n\nclass mymod::other inherits mymod::config {}\n",
+		`mymod/manifests/config.pp`: "# Configures the module\n#\n# @param p the p\nclass mymod::config($p = 1) {\n  notice($p)\n}\n",
+		`mymod/manifests/site.pp`:   files[`mymod/manifests/site.pp`],
+	}
+	if !reflect.DeepEqual(expected, result) {
+		t.Errorf("expected %q, got %q", expected, result)
+	}
+
+	refs := make([]string, len(move.References))
+	for i, ref := range move.References {
+		refs[i] = ref.File() + `:` + ref.Label()
+	}
+	expectedRefs := []string{
+		`mymod/manifests/init.pp:Function Call`,
+		`mymod/manifests/init.pp:Host Class Definition`,
+		`mymod/manifests/site.pp:Resource Instance Definition`,
+		`mymod/manifests/site.pp:'[]' expression`,
+	}
+	if !reflect.DeepEqual(expectedRefs, refs) {
+		t.Errorf("expected %v, got %v", expectedRefs, refs)
+	}
+}
+
+func TestMoveDefinitionFile(t *testing.T) {
+	files := map[string]string{
+		`mymod/manifests/site.pp`: "\n# A vhost\ndefine mymod::vhost() {\n}\n",
+		`mymod/manifests/init.pp`: "class mymod {\n  mymod::vhost { 'a': }\n}",
+	}
+	program := parseFiles(t, files)
+	move, err := MoveDefinition(program, program.DefinedTypes()[0])
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if !reflect.DeepEqual(move.Change, Change{Moves: []FileMove{{`mymod/manifests/site.pp`, `mymod/manifests/vhost.pp`}}}) {
+		t.Errorf("expected a file move, got %v", move.Change)
+	}
+	if len(move.References) != 1 {
+		t.Errorf("expected one reference, got %d", len(move.References))
+	}
+
+	files[`mymod/manifests/vhost.pp`] = `# vhosts`
+	program = parseFiles(t, files)
+	move, err = MoveDefinition(program, program.DefinedTypes()[0])
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	result, err := move.Apply(files)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expected := map[string]string{
+		`mymod/manifests/init.pp`:  files[`mymod/manifests/init.pp`],
+		`mymod/manifests/vhost.pp`: "# vhosts\n\n# A vhost\ndefine mymod::vhost() {\n}\n",
+	}
+	if !reflect.DeepEqual(expected, result) {
+		t.Errorf("expected %q, got %q", expected, result)
+	}
+}
+
+func TestMoveDefinitionNoop(t *testing.T) {
+	files := map[string]string{`mymod/manifests/init.pp`: "class mymod {}\n"}
+	program := parseFiles(t, files)
+	move, err := MoveDefinition(program, program.Classes()[0])
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if !move.IsEmpty() || move.To != `mymod/manifests/init.pp` {
+		t.Errorf("expected an empty change")
+	}
+}
+
+func TestMoveDefinitionErrors(t *testing.T) {
+	program := parseFiles(t, map[string]string{
+		`mymod/manifests/init.pp`:  "class mymod::vhost {}\nfunction mymod::f() {}\n",
+		`mymod/manifests/vhost.pp`: "define mymod::vhost() {}\n",
+	})
+	if _, err := MoveDefinition(program, program.Classes()[0]); err == nil || err.Error() != `mymod::vhost is already defined in mymod/manifests/vhost.pp` {
+		t.Errorf("unexpected error %v", err)
+	}
+	if _, err := MoveDefinition(program, program.Functions()[0]); err == nil || err.Error() != `only classes and defined types can be moved` {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	other, _ := parser.CreateParser().Parse(`site.pp`, `class a {}`, false)
+	if _, err := MoveDefinition(program, other.(*parser.Program).Classes()[0]); err == nil || err.Error() != `a is not defined in the given program` {
+		t.Errorf("unexpected error %v", err)
+	}
+	if _, err := MoveDefinition(other.(*parser.Program), other.(*parser.Program).Classes()[0]); err == nil || err.Error() != `cannot determine the module of site.pp` {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+package refactor
+
+import (
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-parser/parser"
+	"github.com/lyraproj/puppet-parser/printer"
+	"github.com/lyraproj/puppet-parser/validator"
+)
+
+func init() {
+	RegisterFixProvider(validator.VALIDATE_DOUBLE_QUOTED_STRING, doubleQuotedStringFixes)
+	RegisterFixProvider(validator.VALIDATE_SINGLE_QUOTED_INTERPOLATION, singleQuotedInterpolationFixes)
+}
+
+// doubleQuotedStringFixes replaces a double quoted string that has no interpolation or escape sequences
+// with a single quoted string
+func doubleQuotedStringFixes(program *parser.Program, reported issue.Reported, options *FixOptions) []*Fix {
+	ls, _, ok := expressionAt[*parser.LiteralString](program, reported.Location())
+	if !ok {
+		return []*Fix{}
+	}
+	src := ls.String()
+	if len(src) < 2 || src[0] != '"' || strings.ContainsAny(src, `\'`) {
+		return []*Fix{}
+	}
+	return []*Fix{{
+		Title:  `Use single quotes`,
+		Change: Change{Edits: []TextEdit{{File: ls.File(), Offset: sourceOffset(ls), Length: len(src), Text: printer.SingleQuote(ls.StringValue())}}},
+	}}
+}
+
+// singleQuotedInterpolationFixes replaces a single quoted string that contains text that looks like an
+// interpolation with a double quoted string that interpolates it. Backslashes and double quotes are
+// escaped so that the rest of the text is retained. The fix is only provided when the double quoted
+// string parses and when each interpolation in it is one of the lookalikes in the single quoted string,
+// so a '$' that isn't followed by a variable name never starts an interpolation by accident.
+func singleQuotedInterpolationFixes(program *parser.Program, reported issue.Reported, options *FixOptions) []*Fix {
+	ls, _, ok := expressionAt[*parser.LiteralString](program, reported.Location())
+	if !ok {
+		return []*Fix{}
+	}
+	src := ls.String()
+	if len(src) < 2 || src[0] != '\'' {
+		return []*Fix{}
+	}
+	value := ls.StringValue()
+	text := `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+	e, err := parser.CreateParser().Parse(ls.File(), text, true)
+	if err != nil {
+		return []*Fix{}
+	}
+	cs, ok := e.(*parser.ConcatenatedString)
+	if !ok {
+		return []*Fix{}
+	}
+	interpolations := 0
+	for _, segment := range cs.Segments() {
+		if _, ok := segment.(*parser.TextExpression); ok {
+			interpolations++
+		}
+	}
+	if interpolations != len(validator.INTERPOLATION_LOOKALIKE.FindAllString(value, -1)) {
+		return []*Fix{}
+	}
+	return []*Fix{{
+		Title:  `Use double quotes`,
+		Change: Change{Edits: []TextEdit{{File: ls.File(), Offset: sourceOffset(ls), Length: len(src), Text: text}}},
+	}}
+}
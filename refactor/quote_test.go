@@ -0,0 +1,31 @@
+package refactor
+
+import (
+	"testing"
+
+	"github.com/lyraproj/puppet-parser/validator"
+)
+
+func TestDoubleQuotedStringFixes(t *testing.T) {
+	expectFixes(t, `file { "/tmp/x": ensure => file }`, validator.VALIDATE_DOUBLE_QUOTED_STRING, nil, map[string]string{
+		`Use single quotes`: `file { '/tmp/x': ensure => file }`,
+	})
+
+	expectFixes(t, "$x = @(END)\n  |-END\nnotice(\"a\")\n", validator.VALIDATE_DOUBLE_QUOTED_STRING, nil, map[string]string{
+		`Use single quotes`: "$x = @(END)\n  |-END\nnotice('a')\n",
+	})
+}
+
+func TestSingleQuotedInterpolationFixes(t *testing.T) {
+	expectFixes(t, `notice('The "${name}" in C:\\Temp is $title')`, validator.VALIDATE_SINGLE_QUOTED_INTERPOLATION, nil, map[string]string{
+		`Use double quotes`: `notice("The \"${name}\" in C:\\Temp is $title")`,
+	})
+
+	expectFixes(t, `notice('${facts[\'fqdn\']}')`, validator.VALIDATE_SINGLE_QUOTED_INTERPOLATION, nil, map[string]string{
+		`Use double quotes`: `notice("${facts['fqdn']}")`,
+	})
+
+	expectFixes(t, `notice('$name costs $5')`, validator.VALIDATE_SINGLE_QUOTED_INTERPOLATION, nil, map[string]string{})
+
+	expectFixes(t, `notice('$name costs $ 5')`, validator.VALIDATE_SINGLE_QUOTED_INTERPOLATION, nil, map[string]string{})
+}
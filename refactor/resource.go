@@ -0,0 +1,305 @@
+package refactor
+
+import (
+	"strings"
+
+	"github.com/lyraproj/puppet-parser/parser"
+	"github.com/lyraproj/puppet-parser/printer"
+)
+
+// resourcePart is the source of a body of a resource expression and the comments around it
+type resourcePart struct {
+	// leading are the comments on lines of their own before the body
+	leading []string
+
+	// text is the source from the title to the end of the last attribute operation, without a trailing
+	// comma. Comments between the attribute operations are included.
+	text string
+
+	// trailing are the comments after the body on the line where it ends
+	trailing []string
+}
+
+// SplitResource returns a fix that rewrites the given resource expression with several bodies as one
+// resource expression per body, e.g.
+//
+//	file {
+//	  '/etc/a': ensure => file;
+//	  '/etc/b': ensure => directory;
+//	}
+//
+// becomes
+//
+//	file { '/etc/a': ensure => file }
+//	file { '/etc/b': ensure => directory }
+//
+// The bodies keep their order and comments stay with the bodies they precede or end the line of. False
+// is returned when the resource expression isn't a statement, when it has a body with the title default
+// since its attributes apply to all the other bodies, and when it contains a heredoc since the text of
+// the heredoc would change when the body is indented differently.
+func SplitResource(program *parser.Program, r *parser.ResourceExpression) (*Fix, bool) {
+	if len(r.Bodies()) < 2 || !splittable(program, r) {
+		return nil, false
+	}
+	header, parts, ok := resourceParts(r)
+	if !ok {
+		return nil, false
+	}
+
+	source := r.Locator().String()
+	start := sourceOffset(r)
+	indent := indentAt(source, start)
+	b := &strings.Builder{}
+	for i, part := range parts {
+		writeComments(b, part.leading, indent)
+		if i == len(parts)-1 {
+			break
+		}
+		b.WriteString(indent + header + ` `)
+		if strings.Contains(part.text, "\n") {
+			b.WriteString(reindent(part.text, indent+`  `) + `,`)
+			writeTrailing(b, part.trailing)
+			b.WriteString("\n" + indent + `}`)
+		} else {
+			b.WriteString(part.text + ` }`)
+			writeTrailing(b, part.trailing)
+		}
+		b.WriteString("\n")
+	}
+	return &Fix{
+		Title:  `Split the resource expression into one per title`,
+		Change: Change{Edits: []TextEdit{{File: r.File(), Offset: start, Length: len(r.String()), Text: strings.TrimSuffix(strings.TrimPrefix(b.String(), indent), "\n")}}},
+	}, true
+}
+
+// MergeResources returns a fix that merges the given resource expression, which must have one body, with
+// the resource expressions with one body of the same type and form that directly follow it in the same
+// block into one resource expression with several bodies, e.g.
+//
+//	file { '/etc/a': ensure => file }
+//	# A directory
+//	file { '/etc/b':
+//	  ensure => directory,
+//	}
+//
+// becomes
+//
+//	file {
+//	  '/etc/a': ensure => file;
+//	  # A directory
+//	  '/etc/b':
+//	    ensure => directory;
+//	}
+//
+// The bodies keep their order and comments stay with the bodies they precede or end the line of. False
+// is returned when no resource expression can be merged with the given one.
+func MergeResources(program *parser.Program, r *parser.ResourceExpression) (*Fix, bool) {
+	path, ok := pathTo(program, r)
+	if !ok || len(path) == 0 || len(r.Bodies()) != 1 || !splittable(program, r) {
+		return nil, false
+	}
+	block, ok := path[len(path)-1].(*parser.BlockExpression)
+	if !ok {
+		return nil, false
+	}
+	statements := block.Statements()
+	first := 0
+	for statements[first] != r {
+		first++
+	}
+	run := []*parser.ResourceExpression{r}
+	for _, s := range statements[first+1:] {
+		next, ok := s.(*parser.ResourceExpression)
+		if !ok || len(next.Bodies()) != 1 || next.Form() != r.Form() || !splittable(program, next) ||
+			!strings.EqualFold(printer.String(next.TypeName()), printer.String(r.TypeName())) {
+			break
+		}
+		run = append(run, next)
+	}
+	if len(run) < 2 {
+		return nil, false
+	}
+
+	source := r.Locator().String()
+	start := sourceOffset(r)
+	indent := indentAt(source, start)
+	b := &strings.Builder{}
+	var pending []string
+	for i, re := range run {
+		header, parts, ok := resourceParts(re)
+		if !ok {
+			return nil, false
+		}
+		if i == 0 {
+			b.WriteString(indent + header + "\n")
+		} else {
+			prev := run[i-1]
+			trailing, leading, ok := commentsBetween(re.File(), source, sourceOffset(prev)+len(prev.String()), sourceOffset(re))
+			if !ok {
+				return nil, false
+			}
+			writeTrailing(b, trailing)
+			b.WriteString("\n")
+			writeComments(b, pending, indent+`  `)
+			writeComments(b, leading, indent+`  `)
+		}
+		writeComments(b, parts[0].leading, indent+`  `)
+		b.WriteString(indent + `  ` + reindent(parts[0].text, indent+`    `) + `;`)
+		writeTrailing(b, parts[0].trailing)
+		pending = parts[1].leading
+	}
+	b.WriteString("\n")
+	writeComments(b, pending, indent+`  `)
+	b.WriteString(indent + `}`)
+	last := run[len(run)-1]
+	end := sourceOffset(last) + len(last.String())
+	return &Fix{
+		Title:  `Merge the adjacent ` + printer.String(r.TypeName()) + ` resource expressions`,
+		Change: Change{Edits: []TextEdit{{File: r.File(), Offset: start, Length: end - start, Text: strings.TrimPrefix(b.String(), indent)}}},
+	}, true
+}
+
+// splittable returns true if the given resource expression is a statement without a body with the title
+// default and without heredocs
+func splittable(program *parser.Program, r *parser.ResourceExpression) bool {
+	path, ok := pathTo(program, r)
+	if !ok || !isStatement(r, path) || containsHeredoc(r) {
+		return false
+	}
+	for _, body := range r.Bodies() {
+		if _, ok := body.(*parser.ResourceBody).Title().(*parser.LiteralDefault); ok {
+			return false
+		}
+	}
+	return true
+}
+
+// resourceParts returns the header of the given resource expression, i.e. its form and type name
+// followed by the opening brace, and the parts of its bodies. The comments before the closing brace on
+// lines of their own are the leading comments of an extra last part without text.
+func resourceParts(r *parser.ResourceExpression) (header string, parts []*resourcePart, ok bool) {
+	source := r.Locator().String()
+	start := sourceOffset(r)
+	end := start + len(r.String())
+	brace := strings.IndexByte(source[start:end], '{')
+	if brace < 0 || !strings.HasSuffix(source[:end], `}`) {
+		return
+	}
+	header = strings.TrimSpace(source[start:start+brace]) + ` {`
+	bodies := r.Bodies()
+	parts = make([]*resourcePart, len(bodies)+1)
+	trailing, leading, ok := commentsBetween(r.File(), source, start+brace+1, sourceOffset(bodies[0]))
+	if !ok {
+		return
+	}
+	parts[0] = &resourcePart{leading: append(trailing, leading...)}
+	for i, body := range bodies {
+		from := sourceOffset(body)
+		to := end - 1
+		if i+1 < len(bodies) {
+			to = sourceOffset(bodies[i+1])
+		}
+		codeEnd := from
+		pending := false
+		depth := 0
+		comments := make([]int, 0)
+		texts := make([]string, 0)
+		l := parser.NewLexer(r.File(), source[from:to], parser.LEXER_EMIT_COMMENTS, parser.LEXER_ERROR_RECOVERY)
+		for done := false; !done; {
+			token := l.NextToken()
+			at := from + l.TokenStartPos()
+			if token == parser.TOKEN_END {
+				at = to
+			}
+			if pending {
+				codeEnd = len(strings.TrimRight(source[:at], " \t\r\n"))
+				pending = false
+			}
+			switch token {
+			case parser.TOKEN_END:
+				done = true
+			case parser.TOKEN_COMMENT:
+				comments = append(comments, at)
+				texts = append(texts, l.TokenString())
+			case parser.TOKEN_SEMICOLON:
+				pending = depth > 0
+			case parser.TOKEN_LC, parser.TOKEN_SELC, parser.TOKEN_LB, parser.TOKEN_LISTSTART, parser.TOKEN_LP, parser.TOKEN_WSLP:
+				depth++
+				pending = true
+			case parser.TOKEN_RC, parser.TOKEN_RB, parser.TOKEN_RP:
+				depth--
+				pending = true
+			default:
+				pending = true
+			}
+		}
+		parts[i].text = strings.TrimRight(strings.TrimSuffix(source[from:codeEnd], `,`), " \t\r\n")
+		parts[i+1] = &resourcePart{}
+		for j, at := range comments {
+			switch {
+			case at < codeEnd:
+			case !strings.Contains(source[codeEnd:at], "\n"):
+				parts[i].trailing = append(parts[i].trailing, texts[j])
+			default:
+				parts[i+1].leading = append(parts[i+1].leading, texts[j])
+			}
+		}
+	}
+	ok = true
+	return
+}
+
+// commentsBetween returns the comments between the given offsets of the given source that are on the line
+// of the start offset and the comments on lines of their own. False is returned when there's something
+// other than comments, whitespace, and semicolons between the offsets.
+func commentsBetween(file, source string, start, end int) (trailing, leading []string, ok bool) {
+	l := parser.NewLexer(file, source[start:end], parser.LEXER_EMIT_COMMENTS, parser.LEXER_ERROR_RECOVERY)
+	for l.NextToken() != parser.TOKEN_END {
+		switch l.CurrentToken() {
+		case parser.TOKEN_SEMICOLON:
+		case parser.TOKEN_COMMENT:
+			if at := start + l.TokenStartPos(); strings.Contains(source[start:at], "\n") {
+				leading = append(leading, l.TokenString())
+			} else {
+				trailing = append(trailing, l.TokenString())
+			}
+		default:
+			return nil, nil, false
+		}
+	}
+	return trailing, leading, true
+}
+
+// reindent returns the given text with the indentation of its lines after the first replaced by the
+// given indent. Lines that are indented deeper than the least indented line keep their extra indentation.
+func reindent(text, indent string) string {
+	lines := strings.Split(text, "\n")
+	margin := -1
+	for _, line := range lines[1:] {
+		if trimmed := strings.TrimLeft(line, " \t"); trimmed != `` {
+			if n := len(line) - len(trimmed); margin < 0 || n < margin {
+				margin = n
+			}
+		}
+	}
+	for i, line := range lines[1:] {
+		if strings.TrimSpace(line) == `` {
+			lines[i+1] = ``
+		} else {
+			lines[i+1] = indent + line[margin:]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func writeComments(b *strings.Builder, comments []string, indent string) {
+	for _, c := range comments {
+		b.WriteString(indent + c + "\n")
+	}
+}
+
+func writeTrailing(b *strings.Builder, comments []string) {
+	for _, c := range comments {
+		b.WriteString(` ` + c)
+	}
+}
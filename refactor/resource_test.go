@@ -0,0 +1,95 @@
+package refactor
+
+import (
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+func splitResource(program *parser.Program, e parser.Expression) (*Fix, bool) {
+	if r, ok := e.(*parser.ResourceExpression); ok {
+		return SplitResource(program, r)
+	}
+	return nil, false
+}
+
+func mergeResources(program *parser.Program, e parser.Expression) (*Fix, bool) {
+	if r, ok := e.(*parser.ResourceExpression); ok {
+		return MergeResources(program, r)
+	}
+	return nil, false
+}
+
+func TestSplitResource(t *testing.T) {
+	expectRewrite(t, issue.Unindent(`
+    class a {
+      file { # config files
+        '/etc/a':
+          ensure => file,
+          # readable
+          mode   => '0644'; # a
+        # The b file
+        '/etc/b': ensure => file;
+        # last
+      }
+      notice('done')
+    }
+    `), splitResource, issue.Unindent(`
+    class a {
+      # config files
+      file { '/etc/a':
+        ensure => file,
+        # readable
+        mode   => '0644', # a
+      }
+      # The b file
+      file { '/etc/b': ensure => file }
+      # last
+      notice('done')
+    }
+    `))
+
+	expectRewrite(t, "@@file { 'a': ; 'b': tag => ['x', 'y'] }", splitResource, "@@file { 'a': }\n@@file { 'b': tag => ['x', 'y'] }")
+
+	expectRewrite(t, "file { default: mode => '0644'; 'a': ; 'b': }", splitResource, ``)
+
+	expectRewrite(t, "file { 'a': ; 'b': } -> notify { 'x': }", splitResource, ``)
+}
+
+func TestMergeResources(t *testing.T) {
+	expectRewrite(t, issue.Unindent(`
+    class a {
+      notice('start')
+      file { '/etc/a': ensure => file } # a
+      # A directory
+      file { '/etc/b':
+        ensure => directory,
+        # the mode
+        mode   => '0755',
+        # the end
+      }
+      file { '/etc/c': ensure => file, }
+      @file { '/etc/d': ensure => file }
+    }
+    `), mergeResources, issue.Unindent(`
+    class a {
+      notice('start')
+      file {
+        '/etc/a': ensure => file; # a
+        # A directory
+        '/etc/b':
+          ensure => directory,
+          # the mode
+          mode   => '0755';
+        # the end
+        '/etc/c': ensure => file;
+      }
+      @file { '/etc/d': ensure => file }
+    }
+    `))
+
+	expectRewrite(t, "file { 'a': }\nnotice('x')\nfile { 'b': }\n", mergeResources, ``)
+
+	expectRewrite(t, "file { 'a': }\npackage { 'b': }\n", mergeResources, ``)
+}
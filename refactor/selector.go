@@ -0,0 +1,344 @@
+package refactor
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-parser/parser"
+	"github.com/lyraproj/puppet-parser/printer"
+	"github.com/lyraproj/puppet-parser/validator"
+)
+
+func init() {
+	RegisterFixProvider(validator.VALIDATE_NESTED_SELECTOR, nestedSelectorFixes)
+}
+
+// nestedSelectorFixes rewrites the outermost selector of a nested selector as a case statement with
+// nested case statements
+func nestedSelectorFixes(program *parser.Program, reported issue.Reported, options *FixOptions) []*Fix {
+	selector, path, ok := expressionAt[*parser.SelectorExpression](program, reported.Location())
+	if !ok {
+		return []*Fix{}
+	}
+outer:
+	for i := len(path) - 1; i >= 0; i-- {
+		switch e := path[i].(type) {
+		case *parser.SelectorExpression:
+			selector = e
+		case *parser.SelectorEntry, *parser.ParenthesizedExpression:
+		default:
+			break outer
+		}
+	}
+	if fix, ok := SelectorToCase(program, selector); ok {
+		return []*Fix{fix}
+	}
+	return []*Fix{}
+}
+
+// SelectorToCase returns a fix that rewrites the given selector, which must be the value of an
+// assignment statement, as a case statement that assigns the value of each entry in an option of its
+// own, e.g.
+//
+//	$x = $a ? { 'a' => 1, default => 2 }
+//
+// becomes
+//
+//	case $a {
+//	  'a': { $x = 1 }
+//	  default: { $x = 2 }
+//	}
+//
+// Selectors that are values of the entries become nested case statements. Comments between the entries
+// are kept with the options. The selector must have a default entry since a selector fails when nothing
+// matches while a case statement does nothing, and so must its nested selectors. False is returned when
+// the selector cannot be rewritten.
+func SelectorToCase(program *parser.Program, selector *parser.SelectorExpression) (*Fix, bool) {
+	path, ok := pathTo(program, selector)
+	if !ok || len(path) == 0 {
+		return nil, false
+	}
+	assign, ok := path[len(path)-1].(*parser.AssignmentExpression)
+	if !ok || assign.Operator() != `=` || assign.Rhs() != selector || !isStatement(assign, path[:len(path)-1]) {
+		return nil, false
+	}
+	if !convertibleSelector(selector) {
+		return nil, false
+	}
+
+	source := assign.Locator().String()
+	start := sourceOffset(assign)
+	end := sourceOffset(selector) + len(selector.String())
+	starts := make([]int, len(selector.Selectors()))
+	for i, entry := range selector.Selectors() {
+		starts[i] = sourceOffset(entry)
+	}
+	indent := indentAt(source, start)
+	b := &strings.Builder{}
+	writeCase(b, printed(assign.Lhs(), indent), selector, indent, commentsOf(assign.File(), source, start, end, starts))
+	return &Fix{
+		Title:  `Rewrite the selector as a case statement`,
+		Change: Change{Edits: []TextEdit{{File: assign.File(), Offset: start, Length: end - start, Text: b.String()}}},
+	}, true
+}
+
+// CaseToSelector returns a fix that rewrites the given case statement, whose options all assign a value
+// to the same variable, as an assignment of a selector to the variable, e.g.
+//
+//	case $a {
+//	  'a', 'b': { $x = 1 }
+//	  default: { $x = 2 }
+//	}
+//
+// becomes
+//
+//	$x = $a ? {
+//	  'a' => 1,
+//	  'b' => 1,
+//	  default => 2,
+//	}
+//
+// An option may also consist of a nested case statement that assigns the same variable, which then
+// becomes a nested selector in parentheses. The case statement must have a default option since a
+// selector fails when nothing matches. Comments between the options are kept with the entries. False is
+// returned when the case statement cannot be rewritten.
+func CaseToSelector(program *parser.Program, c *parser.CaseExpression) (*Fix, bool) {
+	path, ok := pathTo(program, c)
+	if !ok || !isStatement(c, path) {
+		return nil, false
+	}
+	target, ok := caseTarget(c)
+	if !ok {
+		return nil, false
+	}
+
+	source := c.Locator().String()
+	start := sourceOffset(c)
+	end := start + len(c.String())
+	starts := make([]int, len(c.Options()))
+	for i, option := range c.Options() {
+		starts[i] = sourceOffset(option)
+	}
+	indent := indentAt(source, start)
+	b := &strings.Builder{}
+	b.WriteString(printed(target, indent) + ` = `)
+	writeSelector(b, c, indent, commentsOf(c.File(), source, start, end, starts))
+	return &Fix{
+		Title:  `Rewrite the case statement as a selector`,
+		Change: Change{Edits: []TextEdit{{File: c.File(), Offset: start, Length: end - start, Text: b.String()}}},
+	}, true
+}
+
+// itemComments are the comments of a selector or a case statement, attributed to its entries or options.
+// A comment that follows other text on its line trails the item that starts before it, and other
+// comments lead the item that starts after them. The last leading comments precede the closing brace.
+type itemComments struct {
+	leading  [][]string
+	trailing [][]string
+}
+
+// commentsOf returns the comments between the start and the end offsets of the given source, attributed
+// to the items that start at the given offsets
+func commentsOf(file, source string, start, end int, starts []int) *itemComments {
+	ic := &itemComments{leading: make([][]string, len(starts)+1), trailing: make([][]string, len(starts))}
+	l := parser.NewLexer(file, source[start:end], parser.LEXER_EMIT_COMMENTS, parser.LEXER_ERROR_RECOVERY)
+	for l.NextToken() != parser.TOKEN_END {
+		if l.CurrentToken() != parser.TOKEN_COMMENT {
+			continue
+		}
+		at := start + l.TokenStartPos()
+		i := sort.SearchInts(starts, at+1)
+		if lineStart := strings.LastIndexByte(source[:at], '\n') + 1; i > 0 && strings.TrimSpace(source[lineStart:at]) != `` {
+			ic.trailing[i-1] = append(ic.trailing[i-1], l.TokenString())
+		} else {
+			ic.leading[i] = append(ic.leading[i], l.TokenString())
+		}
+	}
+	return ic
+}
+
+func (ic *itemComments) writeLeading(b *strings.Builder, i int, indent string) {
+	if ic != nil {
+		for _, c := range ic.leading[i] {
+			b.WriteString(indent + c + "\n")
+		}
+	}
+}
+
+func (ic *itemComments) writeTrailing(b *strings.Builder, i int) {
+	if ic != nil {
+		for _, c := range ic.trailing[i] {
+			b.WriteString(` ` + c)
+		}
+	}
+}
+
+// writeCase writes a case statement that assigns the value of the matching entry of the given selector
+// to the given target
+func writeCase(b *strings.Builder, target string, selector *parser.SelectorExpression, indent string, comments *itemComments) {
+	b.WriteString(`case ` + printed(selector.Lhs(), indent) + " {\n")
+	entries := selector.Selectors()
+	for i, entry := range entries {
+		se := entry.(*parser.SelectorEntry)
+		comments.writeLeading(b, i, indent+`  `)
+		b.WriteString(indent + `  ` + printed(se.Matching(), indent+`  `) + `: {`)
+		if nested, ok := selectorOf(se.Value()); ok {
+			b.WriteString("\n" + indent + `    `)
+			writeCase(b, target, nested, indent+`    `, nil)
+			b.WriteString("\n" + indent + `  }`)
+		} else {
+			b.WriteString(` ` + target + ` = ` + printed(se.Value(), indent+`  `) + ` }`)
+		}
+		comments.writeTrailing(b, i)
+		b.WriteString("\n")
+	}
+	comments.writeLeading(b, len(entries), indent+`  `)
+	b.WriteString(indent + `}`)
+}
+
+// writeSelector writes a selector of the values that the options of the given case statement assign
+func writeSelector(b *strings.Builder, c *parser.CaseExpression, indent string, comments *itemComments) {
+	b.WriteString(printed(c.Test(), indent) + " ? {\n")
+	options := c.Options()
+	for i, option := range options {
+		co := option.(*parser.CaseOption)
+		comments.writeLeading(b, i, indent+`  `)
+		value := statementsOf(co.Then())[0]
+		for j, v := range co.Values() {
+			b.WriteString(indent + `  ` + printed(v, indent+`  `) + ` => `)
+			if nested, ok := value.(*parser.CaseExpression); ok {
+				b.WriteString(`(`)
+				writeSelector(b, nested, indent+`  `, nil)
+				b.WriteString(`)`)
+			} else {
+				b.WriteString(printed(value.(*parser.AssignmentExpression).Rhs(), indent+`  `))
+			}
+			b.WriteString(`,`)
+			if j == len(co.Values())-1 {
+				comments.writeTrailing(b, i)
+			}
+			b.WriteString("\n")
+		}
+	}
+	comments.writeLeading(b, len(options), indent+`  `)
+	b.WriteString(indent + `}`)
+}
+
+// convertibleSelector returns true if the given selector and the selectors that are values of its entries
+// have a default entry and no values that contain heredocs
+func convertibleSelector(selector *parser.SelectorExpression) bool {
+	hasDefault := false
+	for _, entry := range selector.Selectors() {
+		se := entry.(*parser.SelectorEntry)
+		if _, ok := se.Matching().(*parser.LiteralDefault); ok {
+			hasDefault = true
+		}
+		if nested, ok := selectorOf(se.Value()); ok {
+			if !convertibleSelector(nested) {
+				return false
+			}
+		} else if containsHeredoc(se.Value()) {
+			return false
+		}
+	}
+	return hasDefault
+}
+
+// caseTarget returns the variable that each option of the given case statement assigns, or that the
+// options of a nested case statement assign, and true, or false when the options do something else or
+// when the case statement has no default option
+func caseTarget(c *parser.CaseExpression) (parser.Expression, bool) {
+	var target parser.Expression
+	name := ``
+	hasDefault := false
+	for _, option := range c.Options() {
+		co := option.(*parser.CaseOption)
+		for _, v := range co.Values() {
+			if _, ok := v.(*parser.LiteralDefault); ok {
+				hasDefault = true
+			}
+		}
+		statements := statementsOf(co.Then())
+		if len(statements) != 1 {
+			return nil, false
+		}
+		var t parser.Expression
+		switch s := statements[0].(type) {
+		case *parser.AssignmentExpression:
+			if s.Operator() != `=` || containsHeredoc(s.Rhs()) {
+				return nil, false
+			}
+			t = s.Lhs()
+		case *parser.CaseExpression:
+			var ok bool
+			if t, ok = caseTarget(s); !ok {
+				return nil, false
+			}
+		default:
+			return nil, false
+		}
+		ve, ok := t.(*parser.VariableExpression)
+		if !ok {
+			return nil, false
+		}
+		n, _ := ve.Name()
+		if target != nil && n != name {
+			return nil, false
+		}
+		target, name = ve, n
+	}
+	return target, target != nil && hasDefault
+}
+
+// selectorOf returns the given expression, or the expression in the given parentheses, if it's a
+// selector
+func selectorOf(e parser.Expression) (*parser.SelectorExpression, bool) {
+	if pe, ok := e.(*parser.ParenthesizedExpression); ok {
+		e = pe.Expr()
+	}
+	se, ok := e.(*parser.SelectorExpression)
+	return se, ok
+}
+
+// statementsOf returns the statements of the given body of a case option
+func statementsOf(e parser.Expression) []parser.Expression {
+	switch e := e.(type) {
+	case *parser.BlockExpression:
+		return e.Statements()
+	case *parser.Nop:
+		return []parser.Expression{}
+	default:
+		return []parser.Expression{e}
+	}
+}
+
+func containsHeredoc(e parser.Expression) bool {
+	found := false
+	check := func(_ []parser.Expression, e parser.Expression) {
+		_, ok := e.(*parser.HeredocExpression)
+		found = found || ok
+	}
+	check(nil, e)
+	e.AllContents([]parser.Expression{}, check)
+	return found
+}
+
+// printed returns the given expression printed with the given indentation of all lines but the first
+func printed(e parser.Expression, indent string) string {
+	return strings.ReplaceAll(strings.TrimRight(printer.String(e), "\n"), "\n", "\n"+indent)
+}
+
+// pathTo returns the path from the program that the given expression was parsed from to the expression
+func pathTo(program *parser.Program, e parser.Expression) (path []parser.Expression, ok bool) {
+	file, isFile := program.Program(e.File())
+	if !isFile {
+		return
+	}
+	file.AllContents([]parser.Expression{}, func(p []parser.Expression, x parser.Expression) {
+		if x == e && !ok {
+			path, ok = append([]parser.Expression{}, p...), true
+		}
+	})
+	return
+}
@@ -0,0 +1,142 @@
+package refactor
+
+import (
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-parser/parser"
+	"github.com/lyraproj/puppet-parser/validator"
+)
+
+func expectRewrite(t *testing.T, source string, rewrite func(program *parser.Program, e parser.Expression) (*Fix, bool), expected string) {
+	t.Helper()
+	program, _ := validate(t, source)
+	var fix *Fix
+	found := false
+	program.AllContents(nil, func(_ []parser.Expression, e parser.Expression) {
+		if !found {
+			fix, found = rewrite(program, e)
+		}
+	})
+	if !found {
+		if expected != `` {
+			t.Fatalf("no rewrite of %q", source)
+		}
+		return
+	}
+	if expected == `` {
+		t.Fatalf("unexpected rewrite '%s'", fix.Title)
+	}
+	result, err := fix.Apply(map[string]string{`x.pp`: source})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if result[`x.pp`] != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, result[`x.pp`])
+	}
+	if _, err = parser.CreateParser().Parse(`x.pp`, result[`x.pp`], false); err != nil {
+		t.Errorf("the rewrite doesn't parse: %s", err.Error())
+	}
+}
+
+func selectorToCase(program *parser.Program, e parser.Expression) (*Fix, bool) {
+	if selector, ok := e.(*parser.SelectorExpression); ok {
+		return SelectorToCase(program, selector)
+	}
+	return nil, false
+}
+
+func caseToSelector(program *parser.Program, e parser.Expression) (*Fix, bool) {
+	if c, ok := e.(*parser.CaseExpression); ok {
+		return CaseToSelector(program, c)
+	}
+	return nil, false
+}
+
+func TestSelectorToCase(t *testing.T) {
+	expectRewrite(t, issue.Unindent(`
+    class a {
+      # The mode
+      $mode = $facts['os']['family'] ? {
+        'RedHat' => '0644', # readable
+        # Debian family
+        'Debian' => '0640',
+        default  => '0600',
+      }
+    }
+    `), selectorToCase, issue.Unindent(`
+    class a {
+      # The mode
+      case $facts['os']['family'] {
+        'RedHat': { $mode = '0644' } # readable
+        # Debian family
+        'Debian': { $mode = '0640' }
+        default: { $mode = '0600' }
+      }
+    }
+    `))
+
+	expectRewrite(t, "$x = $a ? { 1 => 'one', default => 'other' }", selectorToCase, issue.Unindent(`
+    case $a {
+      1: { $x = 'one' }
+      default: { $x = 'other' }
+    }`))
+
+	expectRewrite(t, `$x = $a ? { 1 => 'one' }`, selectorToCase, ``)
+	expectRewrite(t, `notice($a ? { 1 => 'one', default => 'other' })`, selectorToCase, ``)
+	expectRewrite(t, `$x += $a ? { 1 => 'one', default => 'other' }`, selectorToCase, ``)
+}
+
+func TestNestedSelectorFixes(t *testing.T) {
+	expectFixes(t, "$x = $a ? {\n  'a' => $b ? { 1 => 'p', default => 'q' },\n  default => 'r',\n}\n",
+		validator.VALIDATE_NESTED_SELECTOR, nil, map[string]string{
+			`Rewrite the selector as a case statement`: issue.Unindent(`
+        case $a {
+          'a': {
+            case $b {
+              1: { $x = 'p' }
+              default: { $x = 'q' }
+            }
+          }
+          default: { $x = 'r' }
+        }
+        `),
+		})
+}
+
+func TestCaseToSelector(t *testing.T) {
+	expectRewrite(t, issue.Unindent(`
+    class a {
+      case $facts['os']['family'] {
+        # Red Hat family
+        'RedHat', 'Fedora': { $mode = '0644' }
+        'Debian': {
+          case $facts['os']['release']['major'] {
+            '9': { $mode = '0640' } # stretch
+            default: { $mode = '0600' }
+          }
+        }
+        default: {
+          $mode = '0600'
+        }
+      }
+    }
+    `), caseToSelector, issue.Unindent(`
+    class a {
+      $mode = $facts['os']['family'] ? {
+        # Red Hat family
+        'RedHat' => '0644',
+        'Fedora' => '0644',
+        'Debian' => ($facts['os']['release']['major'] ? {
+          '9' => '0640',
+          default => '0600',
+        }), # stretch
+        default => '0600',
+      }
+    }
+    `))
+
+	expectRewrite(t, `case $a { 1: { $x = 'one' } }`, caseToSelector, ``)
+	expectRewrite(t, `case $a { 1: { $x = 'one' } default: { $y = 'other' } }`, caseToSelector, ``)
+	expectRewrite(t, `case $a { 1: { $x = 'one' notice($x) } default: { $x = 'other' } }`, caseToSelector, ``)
+}
@@ -0,0 +1,196 @@
+package refactor
+
+import (
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-parser/parser"
+	"github.com/lyraproj/puppet-parser/validator"
+)
+
+func init() {
+	RegisterFixProvider(validator.VALIDATE_UNUSED_PARAMETER, unusedParameterFixes)
+	RegisterFixProvider(validator.VALIDATE_UNUSED_VARIABLE, unusedVariableFixes)
+}
+
+// unusedParameterFixes renames an unused lambda parameter according to the UnusedStyle of the options
+// and, when it is the last of several parameters, removes it. The removal is the second alternative
+// since it can change the values that a function passes to the lambda, e.g. each passes key-value pairs
+// to a lambda with one parameter and keys and values to a lambda with two.
+func unusedParameterFixes(program *parser.Program, reported issue.Reported, options *FixOptions) []*Fix {
+	param, path, ok := expressionAt[*parser.Parameter](program, reported.Location())
+	if !ok || len(path) == 0 {
+		return []*Fix{}
+	}
+	lambda, ok := path[len(path)-1].(*parser.LambdaExpression)
+	if !ok {
+		return []*Fix{}
+	}
+
+	name := param.Name()
+	taken := make(map[string]bool)
+	index := 0
+	for i, p := range lambda.Parameters() {
+		taken[p.(*parser.Parameter).Name()] = true
+		if p == param {
+			index = i
+		}
+	}
+	text := param.String()
+	nameOffset := sourceOffset(param) + strings.Index(text, `$`+name) + 1
+	newName := options.UnusedStyle.rename(name, taken)
+	fixes := []*Fix{{
+		Title:  `Rename $` + name + ` to $` + newName,
+		Change: Change{Edits: []TextEdit{{File: param.File(), Offset: nameOffset, Length: len(name), Text: newName}}},
+	}}
+
+	params := lambda.Parameters()
+	if index > 0 && index == len(params)-1 {
+		// Remove everything from the end of the preceding parameter up to the closing pipe
+		start := parameterEnd(params[index-1].(*parser.Parameter))
+		end := parameterEnd(param)
+		if strings.HasSuffix(text, `|`) {
+			end = sourceOffset(param) + len(text) - 1
+		}
+		fixes = append(fixes, &Fix{
+			Title:  `Remove the parameter $` + name,
+			Change: Change{Edits: []TextEdit{{File: param.File(), Offset: start, Length: end - start}}},
+		})
+	}
+	return fixes
+}
+
+// parameterEnd returns the offset just after the last token of the given lambda parameter. The range of
+// a parameter includes the comma or pipe that follows it.
+func parameterEnd(param *parser.Parameter) int {
+	text := param.String()
+	if strings.HasSuffix(text, `,`) || strings.HasSuffix(text, `|`) {
+		text = text[:len(text)-1]
+	}
+	return sourceOffset(param) + len(strings.TrimRight(text, " \t\r\n"))
+}
+
+// unusedVariableFixes renames an unused variable according to the UnusedStyle of the options and, when
+// the variable is assigned a literal value or the value of another variable by a statement of its own,
+// removes that statement
+func unusedVariableFixes(program *parser.Program, reported issue.Reported, options *FixOptions) []*Fix {
+	variable, path, ok := expressionAt[*parser.VariableExpression](program, reported.Location())
+	if !ok {
+		return []*Fix{}
+	}
+	name, _ := variable.Name()
+
+	var scope, body parser.Expression
+	taken := make(map[string]bool)
+	for i := len(path) - 1; i >= 0 && scope == nil; i-- {
+		switch s := path[i].(type) {
+		case *parser.LambdaExpression:
+			scope, body = s, s.Body()
+			for _, p := range s.Parameters() {
+				taken[p.(*parser.Parameter).Name()] = true
+			}
+		case *parser.FunctionDefinition:
+			scope, body = s, s.Body()
+		case *parser.PlanDefinition:
+			scope, body = s, s.Body()
+		}
+	}
+	if body == nil {
+		return []*Fix{}
+	}
+
+	// Find the assignments of the variable in the scope, skipping nested lambdas
+	assignments := make([]*parser.AssignmentExpression, 0)
+	targets := make([]*parser.VariableExpression, 0)
+	var parent parser.Expression
+	body.AllContents([]parser.Expression{}, func(p []parser.Expression, e parser.Expression) {
+		switch e := e.(type) {
+		case *parser.VariableExpression:
+			if n, ok := e.Name(); ok {
+				taken[n] = true
+			}
+		case *parser.AssignmentExpression:
+			for _, a := range p {
+				if _, ok := a.(*parser.LambdaExpression); ok {
+					return
+				}
+			}
+			for _, lhs := range e.AssignedVariables() {
+				if n, _ := lhs.Name(); n == name {
+					assignments = append(assignments, e)
+					targets = append(targets, lhs)
+					parent = body
+					if len(p) > 0 {
+						parent = p[len(p)-1]
+					}
+				}
+			}
+		}
+	})
+
+	newName := options.UnusedStyle.rename(name, taken)
+	edits := make([]TextEdit, len(targets))
+	for i, t := range targets {
+		edits[i] = TextEdit{File: t.File(), Offset: sourceOffset(t) + 1, Length: len(name), Text: newName}
+	}
+	fixes := []*Fix{{Title: `Rename $` + name + ` to $` + newName, Change: Change{Edits: edits}}}
+
+	if len(assignments) == 1 {
+		if edit, ok := removeStatement(assignments[0], parent, scope); ok {
+			fixes = append(fixes, &Fix{Title: `Remove the assignment to $` + name, Change: Change{Edits: []TextEdit{edit}}})
+		}
+	}
+	return fixes
+}
+
+// removeStatement returns the edit that removes the line of the given assignment, which must be a
+// statement of the given block in the given scope. No edit is returned unless the assignment assigns a
+// single line literal or variable and is alone on its line.
+func removeStatement(a *parser.AssignmentExpression, block parser.Expression, scope parser.Expression) (TextEdit, bool) {
+	b, ok := block.(*parser.BlockExpression)
+	if !ok {
+		return TextEdit{}, false
+	}
+	if _, ok = a.Lhs().(*parser.VariableExpression); !ok {
+		return TextEdit{}, false
+	}
+	switch rhs := a.Rhs().(type) {
+	case *parser.LiteralString:
+		if strings.Contains(rhs.StringValue(), "\n") {
+			return TextEdit{}, false
+		}
+	case *parser.LiteralInteger, *parser.LiteralFloat, *parser.LiteralBoolean, *parser.LiteralUndef, *parser.VariableExpression, *parser.QualifiedName:
+	default:
+		return TextEdit{}, false
+	}
+
+	line := a.Line()
+	statements := b.Statements()
+	for i, s := range statements {
+		if s == a && i+1 < len(statements) && statements[i+1].Line() == line {
+			return TextEdit{}, false
+		}
+	}
+	l := a.Locator()
+	scopeEnd := sourceOffset(scope) + len(strings.TrimRight(scope.String(), " \t\r\n")) - 1
+	if l.LineForOffset(scopeEnd) == line {
+		return TextEdit{}, false
+	}
+
+	source := l.String()
+	start := sourceOffset(a)
+	lineStart := strings.LastIndexByte(source[:start], '\n') + 1
+	if strings.TrimSpace(source[lineStart:start]) != `` {
+		return TextEdit{}, false
+	}
+	return TextEdit{File: a.File(), Offset: lineStart, Length: lineEnd(source, start) - lineStart}, true
+}
+
+// rename returns the name that the given unused name is given by this style. The taken names are names
+// that cannot be used.
+func (s UnusedStyle) rename(name string, taken map[string]bool) string {
+	if s == UNUSED_UNDERSCORE && !taken[`_`] {
+		return `_`
+	}
+	return `_` + name
+}
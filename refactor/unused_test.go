@@ -0,0 +1,95 @@
+package refactor
+
+import (
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-parser/parser"
+	"github.com/lyraproj/puppet-parser/validator"
+)
+
+func TestUnusedParameterFixes(t *testing.T) {
+	source := "$h.each |$k, String $v = 'x' | {\n  notice($k)\n}\n"
+	expectFixes(t, source, validator.VALIDATE_UNUSED_PARAMETER, nil, map[string]string{
+		`Rename $v to $_v`:        "$h.each |$k, String $_v = 'x' | {\n  notice($k)\n}\n",
+		`Remove the parameter $v`: "$h.each |$k| {\n  notice($k)\n}\n",
+	})
+
+	expectFixes(t, "$h.each |$k, $v| { notice($v) }", validator.VALIDATE_UNUSED_PARAMETER, &FixOptions{UnusedStyle: UNUSED_UNDERSCORE}, map[string]string{
+		`Rename $k to $_`: "$h.each |$_, $v| { notice($v) }",
+	})
+
+	expectFixes(t, "$h.each |$_, $k, $v| { notice($v) }", validator.VALIDATE_UNUSED_PARAMETER, &FixOptions{UnusedStyle: UNUSED_UNDERSCORE}, map[string]string{
+		`Rename $k to $_k`: "$h.each |$_, $_k, $v| { notice($v) }",
+	})
+}
+
+func TestUnusedVariableFixes(t *testing.T) {
+	expectFixes(t, "function f($a) {\n  $x = 'a'\n  # comment\n  $a\n}\n", validator.VALIDATE_UNUSED_VARIABLE, nil, map[string]string{
+		`Rename $x to $_x`:            "function f($a) {\n  $_x = 'a'\n  # comment\n  $a\n}\n",
+		`Remove the assignment to $x`: "function f($a) {\n  # comment\n  $a\n}\n",
+	})
+
+	expectFixes(t, "$a.map |$v| {\n  if $v {\n    $x = $v\n  } else {\n    $x = 1\n  }\n  $v\n}", validator.VALIDATE_UNUSED_VARIABLE, nil, map[string]string{
+		`Rename $x to $_x`: "$a.map |$v| {\n  if $v {\n    $_x = $v\n  } else {\n    $_x = 1\n  }\n  $v\n}",
+	})
+
+	expectFixes(t, "$a.map |$v| { $x = notice($v) $v }", validator.VALIDATE_UNUSED_VARIABLE, nil, map[string]string{
+		`Rename $x to $_x`: "$a.map |$v| { $_x = notice($v) $v }",
+	})
+
+	expectFixes(t, "$a.map |$v| { [$x, $y] = $v $y }", validator.VALIDATE_UNUSED_VARIABLE, nil, map[string]string{
+		`Rename $x to $_x`: "$a.map |$v| { [$_x, $y] = $v $y }",
+	})
+}
+
+func TestFixesWithoutProvider(t *testing.T) {
+	program, issues := validate(t, `class a { notice($::kernel) }`)
+	if len(issues) != 1 || len(Fixes(program, issues[0], nil)) != 0 {
+		t.Errorf("expected one issue without fixes")
+	}
+}
+
+// expectFixes validates the given source with all lint issues enabled and checks that the first issue
+// with the given code has the expected fixes, a map of fix title to the source after the fix is applied
+func expectFixes(t *testing.T, source string, code issue.Code, options *FixOptions, expected map[string]string) {
+	t.Helper()
+	program, issues := validate(t, source)
+	for _, ri := range issues {
+		if ri.Code() != code {
+			continue
+		}
+		fixes := Fixes(program, ri, options)
+		if len(fixes) != len(expected) {
+			t.Fatalf("expected %d fixes, got %d", len(expected), len(fixes))
+		}
+		for _, fix := range fixes {
+			e, ok := expected[fix.Title]
+			if !ok {
+				t.Errorf("unexpected fix '%s'", fix.Title)
+				continue
+			}
+			result, err := fix.Apply(map[string]string{`x.pp`: source})
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			if result[`x.pp`] != e {
+				t.Errorf("%s: expected %q, got %q", fix.Title, e, result[`x.pp`])
+			}
+		}
+		return
+	}
+	t.Fatalf("no %s issue was reported", code)
+}
+
+func validate(t *testing.T, source string) (*parser.Program, []issue.Reported) {
+	t.Helper()
+	expr, err := parser.CreateParser().Parse(`x.pp`, source, false)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	v := validator.NewChecker(validator.STRICT_WARNING)
+	validator.EnableLint(v)
+	validator.Validate(v, expr)
+	return expr.(*parser.Program), v.Issues()
+}
@@ -0,0 +1,302 @@
+// Package sources expands the arguments of a command into the paths of the Puppet source files to process.
+//
+// An argument is a path to a file, a directory that is searched recursively for .pp and .epp files, a
+// shell style glob pattern, or "-" for stdin. Files found in directories are excluded when they match a
+// .gitignore style pattern of an Ignore, which picks up the .gitignore files of the directories that are
+// searched.
+package sources
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// STDIN is the argument that denotes stdin
+const STDIN = `-`
+
+// Ignore is a list of .gitignore style exclusion patterns
+type Ignore struct {
+	patterns []*pattern
+}
+
+type pattern struct {
+	// base is the directory that the pattern is relative to, in slash form, or "." for the current directory
+	base string
+
+	// segments are the path segments of the pattern. A pattern that doesn't contain a slash, other than a
+	// trailing slash, matches at any depth and starts with a "**" segment.
+	segments []string
+
+	// negate is true for a pattern that starts with "!" and hence includes what a previous pattern excluded
+	negate bool
+
+	// dirOnly is true for a pattern that ends with a slash and hence only matches directories
+	dirOnly bool
+}
+
+// NewIgnore returns an Ignore with the given patterns, which are relative to the current directory
+func NewIgnore(patterns ...string) *Ignore {
+	ig := &Ignore{}
+	ig.Add(`.`, strings.Join(patterns, "\n"))
+	return ig
+}
+
+// Add adds the patterns of the given content of a .gitignore file in the given directory. Blank lines
+// and lines that start with "#" are skipped. A pattern that starts with "!" negates a previous pattern,
+// a pattern that ends with "/" only matches directories, a pattern that contains a "/" is relative to the
+// directory, and "**" matches any number of directories.
+func (ig *Ignore) Add(dir string, content string) {
+	base := path.Clean(filepath.ToSlash(dir))
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, " \r\t")
+		if line == `` || strings.HasPrefix(line, `#`) {
+			continue
+		}
+		p := &pattern{base: base}
+		if strings.HasPrefix(line, `!`) {
+			p.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, `/`) {
+			p.dirOnly = true
+			line = strings.TrimRight(line, `/`)
+		}
+		if line == `` {
+			continue
+		}
+		if !strings.Contains(line, `/`) {
+			line = `**/` + line
+		}
+		p.segments = strings.Split(strings.TrimPrefix(line, `/`), `/`)
+		ig.patterns = append(ig.patterns, p)
+	}
+}
+
+// AddFile adds the patterns of the .gitignore file with the given path. A file that doesn't exist is
+// silently skipped.
+func (ig *Ignore) AddFile(fileName string) error {
+	content, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	ig.Add(filepath.Dir(fileName), string(content))
+	return nil
+}
+
+// Excluded returns true if the file or directory with the given path, or one of the directories that it
+// is in, is excluded. The last pattern that matches a path decides, so a negated pattern can include a
+// file again, but not a file in an excluded directory.
+func (ig *Ignore) Excluded(fileName string, dir bool) bool {
+	if ig == nil || len(ig.patterns) == 0 {
+		return false
+	}
+	segments := strings.Split(path.Clean(filepath.ToSlash(fileName)), `/`)
+	for i := 1; i < len(segments); i++ {
+		if ig.excluded(path.Join(segments[:i]...), true) {
+			return true
+		}
+	}
+	return ig.excluded(fileName, dir)
+}
+
+func (ig *Ignore) excluded(fileName string, dir bool) bool {
+	fileName = path.Clean(filepath.ToSlash(fileName))
+	excluded := false
+	for _, p := range ig.patterns {
+		if excluded == p.negate && p.matches(fileName, dir) {
+			excluded = !p.negate
+		}
+	}
+	return excluded
+}
+
+func (p *pattern) matches(fileName string, dir bool) bool {
+	if p.dirOnly && !dir {
+		return false
+	}
+	rel := fileName
+	if p.base != `.` {
+		if !strings.HasPrefix(fileName, p.base+`/`) {
+			return false
+		}
+		rel = fileName[len(p.base)+1:]
+	}
+	return matchSegments(p.segments, strings.Split(rel, `/`))
+}
+
+// matchSegments returns true if the given path segments match the given pattern segments, where a "**"
+// segment matches any number of path segments
+func matchSegments(pattern []string, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == `**` {
+			if len(pattern) == 1 {
+				return len(segments) > 0
+			}
+			for i := 0; i <= len(segments); i++ {
+				if matchSegments(pattern[1:], segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segments) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+			return false
+		}
+		pattern, segments = pattern[1:], segments[1:]
+	}
+	return len(segments) == 0
+}
+
+// IsSource returns true if the given file name has the extension of a Puppet manifest or template
+func IsSource(fileName string) bool {
+	return strings.HasSuffix(fileName, `.pp`) || strings.HasSuffix(fileName, `.epp`)
+}
+
+// Expand returns the paths of the files that the given arguments denote, in the order of the arguments
+// and without duplicates. A path to a file is returned as is. A directory is searched recursively for
+// .pp and .epp files, skipping the files and directories that the given Ignore excludes and the .git
+// directory. The patterns of the .gitignore files of the searched directories are added to the Ignore.
+// A glob pattern is expanded into the files and directories that it matches, where "**" matches any
+// number of directories, and the matches are treated like arguments but subject to the Ignore. The STDIN
+// argument is returned as is.
+func Expand(args []string, ig *Ignore) ([]string, error) {
+	if ig == nil {
+		ig = &Ignore{}
+	}
+	e := &expander{ignore: ig, seen: make(map[string]bool)}
+	for _, arg := range args {
+		if arg != STDIN && hasMeta(arg) {
+			matches, err := glob(arg)
+			if err != nil {
+				return nil, err
+			}
+			for _, m := range matches {
+				fi, err := os.Stat(m)
+				if err != nil {
+					return nil, err
+				}
+				if !ig.Excluded(m, fi.IsDir()) {
+					if err = e.expand(m, fi); err != nil {
+						return nil, err
+					}
+				}
+			}
+			continue
+		}
+		if arg == STDIN {
+			e.add(arg)
+			continue
+		}
+		fi, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if err = e.expand(arg, fi); err != nil {
+			return nil, err
+		}
+	}
+	return e.files, nil
+}
+
+type expander struct {
+	ignore *Ignore
+	seen   map[string]bool
+	files  []string
+}
+
+func (e *expander) add(fileName string) {
+	if !e.seen[fileName] {
+		e.seen[fileName] = true
+		e.files = append(e.files, fileName)
+	}
+}
+
+func (e *expander) expand(fileName string, fi os.FileInfo) error {
+	if !fi.IsDir() {
+		e.add(fileName)
+		return nil
+	}
+	return e.walk(fileName)
+}
+
+// walk adds the source files of the given directory and its subdirectories in lexical order
+func (e *expander) walk(dir string) error {
+	if err := e.ignore.AddFile(filepath.Join(dir, `.gitignore`)); err != nil {
+		return err
+	}
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, fi := range entries {
+		fileName := filepath.Join(dir, fi.Name())
+		if fi.IsDir() && fi.Name() == `.git` || e.ignore.excluded(fileName, fi.IsDir()) {
+			continue
+		}
+		if fi.IsDir() {
+			if err = e.walk(fileName); err != nil {
+				return err
+			}
+		} else if IsSource(fi.Name()) {
+			e.add(fileName)
+		}
+	}
+	return nil
+}
+
+// hasMeta returns true if the given path contains a glob meta character
+func hasMeta(p string) bool {
+	return strings.ContainsAny(p, `*?[`)
+}
+
+// glob returns the paths that match the given pattern in lexical order. Unlike filepath.Glob, a "**"
+// segment matches any number of directories.
+func glob(pattern string) ([]string, error) {
+	if !strings.Contains(pattern, `**`) {
+		return filepath.Glob(pattern)
+	}
+	segments := strings.Split(filepath.ToSlash(pattern), `/`)
+	i := 0
+	for i < len(segments) && !hasMeta(segments[i]) {
+		i++
+	}
+	root := strings.Join(segments[:i], `/`)
+	if root == `` {
+		root = `.`
+		if strings.HasPrefix(pattern, `/`) {
+			root = `/`
+		}
+	}
+	if _, err := path.Match(strings.Join(segments[i:], `/`), ``); err != nil {
+		return nil, err
+	}
+	matches := make([]string, 0)
+	err := filepath.Walk(root, func(fileName string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, fileName)
+		if err != nil || rel == `.` {
+			return err
+		}
+		if matchSegments(segments[i:], strings.Split(filepath.ToSlash(rel), `/`)) {
+			matches = append(matches, fileName)
+			if fi.IsDir() {
+				return filepath.SkipDir
+			}
+		}
+		return nil
+	})
+	return matches, err
+}
@@ -0,0 +1,113 @@
+package sources
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func tree(t *testing.T, files ...string) string {
+	t.Helper()
+	dir, err := ioutil.TempDir(``, `sources`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	for i := 0; i < len(files); i += 2 {
+		fileName := filepath.Join(dir, filepath.FromSlash(files[i]))
+		if err = os.MkdirAll(filepath.Dir(fileName), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err = ioutil.WriteFile(fileName, []byte(files[i+1]), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func expandIn(t *testing.T, dir string, ig *Ignore, args ...string) []string {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	files, err := Expand(args, ig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, f := range files {
+		files[i] = filepath.ToSlash(f)
+	}
+	return files
+}
+
+func TestExpandDirectory(t *testing.T) {
+	dir := tree(t,
+		`.gitignore`, "# generated\n/pkg/\n*.tmp.pp\n",
+		`manifests/init.pp`, ``,
+		`manifests/config.tmp.pp`, ``,
+		`manifests/README.md`, ``,
+		`templates/config.epp`, ``,
+		`pkg/mod/manifests/init.pp`, ``,
+		`spec/fixtures/.gitignore`, "*\n!keep.pp\n",
+		`spec/fixtures/drop.pp`, ``,
+		`spec/fixtures/keep.pp`, ``,
+		`.git/hooks/x.pp`, ``)
+	expected := []string{`manifests/init.pp`, `spec/fixtures/keep.pp`, `templates/config.epp`}
+	if files := expandIn(t, dir, nil, `.`); !reflect.DeepEqual(expected, files) {
+		t.Errorf("expected %v, got %v", expected, files)
+	}
+}
+
+func TestExpandExcludes(t *testing.T) {
+	dir := tree(t,
+		`manifests/init.pp`, ``,
+		`manifests/params.pp`, ``,
+		`vendor/other/manifests/init.pp`, ``)
+	expected := []string{`manifests/init.pp`, `-`}
+	if files := expandIn(t, dir, NewIgnore(`vendor/`, `params.pp`), `.`, `-`, `manifests/init.pp`); !reflect.DeepEqual(expected, files) {
+		t.Errorf("expected %v, got %v", expected, files)
+	}
+}
+
+func TestExpandGlobs(t *testing.T) {
+	dir := tree(t,
+		`manifests/init.pp`, ``,
+		`manifests/server/config.pp`, ``,
+		`manifests/server/service.pp`, ``,
+		`modules/ntp/manifests/init.pp`, ``,
+		`modules/ntp/templates/ntp.conf.epp`, ``)
+	expected := []string{`manifests/init.pp`, `manifests/server/config.pp`, `manifests/server/service.pp`, `modules/ntp/manifests/init.pp`}
+	if files := expandIn(t, dir, nil, `manifests/*.pp`, `**/manifests/**/*.pp`); !reflect.DeepEqual(expected, files) {
+		t.Errorf("expected %v, got %v", expected, files)
+	}
+
+	expected = []string{`modules/ntp/manifests/init.pp`, `modules/ntp/templates/ntp.conf.epp`}
+	if files := expandIn(t, dir, NewIgnore(`/manifests/`), `modules/*`, `manifests/*.pp`); !reflect.DeepEqual(expected, files) {
+		t.Errorf("expected %v, got %v", expected, files)
+	}
+}
+
+func TestExcluded(t *testing.T) {
+	ig := NewIgnore(`/build`, `**/fixtures/**`, `*.bak.pp`, `!important.bak.pp`, `docs/`)
+	for fileName, excluded := range map[string]bool{
+		`build/init.pp`:              true,
+		`src/build/init.pp`:          false,
+		`spec/fixtures/init.pp`:      true,
+		`spec/fixtures`:              false,
+		`manifests/init.bak.pp`:      true,
+		`manifests/important.bak.pp`: false,
+		`docs/init.pp`:               true,
+		`manifests/docs`:             false,
+	} {
+		if ig.Excluded(fileName, false) != excluded {
+			t.Errorf("expected Excluded(%q) to be %t", fileName, excluded)
+		}
+	}
+}
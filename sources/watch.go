@@ -0,0 +1,100 @@
+package sources
+
+import (
+	"os"
+	"sort"
+	"time"
+)
+
+// Watcher reports the source files that are added, modified, or removed among the files that a list of
+// arguments expands to. It polls the file system, so it needs no support from the operating system and
+// notices files in directories that are created after the watch started.
+type Watcher struct {
+	args   []string
+	ignore *Ignore
+	states map[string]fileState
+}
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// NewWatcher returns a watcher of the files that the given arguments expand to, see Expand. Files in
+// directories are excluded when they match a pattern of the given Ignore, which may be nil. The Ignore
+// isn't modified. The STDIN argument is ignored.
+func NewWatcher(args []string, ignore *Ignore) *Watcher {
+	if ignore == nil {
+		ignore = &Ignore{}
+	}
+	return &Watcher{args: args, ignore: ignore, states: make(map[string]fileState)}
+}
+
+// Poll expands the arguments again and returns the files that were added or modified since the previous
+// call, in the order that Expand returns them, and the files that were removed, in lexical order. The
+// first call returns all files. An argument that doesn't exist is treated as a file that was removed.
+func (w *Watcher) Poll() ([]string, []string, error) {
+	ig := &Ignore{patterns: append([]*pattern{}, w.ignore.patterns...)}
+	states := make(map[string]fileState, len(w.states))
+	changed := make([]string, 0)
+	for _, arg := range w.args {
+		if arg == STDIN {
+			continue
+		}
+		files, err := Expand([]string{arg}, ig)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, nil, err
+		}
+		for _, fileName := range files {
+			if _, ok := states[fileName]; ok {
+				continue
+			}
+			fi, err := os.Stat(fileName)
+			if err != nil {
+				if os.IsNotExist(err) {
+					continue
+				}
+				return nil, nil, err
+			}
+			state := fileState{fi.ModTime(), fi.Size()}
+			states[fileName] = state
+			if prev, ok := w.states[fileName]; !ok || prev != state {
+				changed = append(changed, fileName)
+			}
+		}
+	}
+	removed := make([]string, 0)
+	for fileName := range w.states {
+		if _, ok := states[fileName]; !ok {
+			removed = append(removed, fileName)
+		}
+	}
+	sort.Strings(removed)
+	w.states = states
+	return changed, removed, nil
+}
+
+// Watch polls the files at the given interval and calls the given function when files were added,
+// modified, or removed, until the stop channel is closed or polling fails. The function is called with
+// all files on the first poll.
+func (w *Watcher) Watch(interval time.Duration, stop <-chan struct{}, changes func(changed []string, removed []string)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		changed, removed, err := w.Poll()
+		if err != nil {
+			return err
+		}
+		if len(changed) > 0 || len(removed) > 0 {
+			changes(changed, removed)
+		}
+		select {
+		case <-stop:
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
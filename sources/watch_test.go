@@ -0,0 +1,48 @@
+package sources
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWatcherPoll(t *testing.T) {
+	dir := tree(t,
+		`manifests/init.pp`, `class ntp {}`,
+		`manifests/config.pp`, `class ntp::config {}`,
+		`manifests/generated.pp`, ``)
+	w := NewWatcher([]string{dir, filepath.Join(dir, `site.pp`)}, NewIgnore(`generated.pp`))
+	rel := func(files []string) []string {
+		for i, f := range files {
+			files[i], _ = filepath.Rel(dir, f)
+			files[i] = filepath.ToSlash(files[i])
+		}
+		return files
+	}
+	poll := func(expectedChanged, expectedRemoved []string) {
+		t.Helper()
+		changed, removed, err := w.Poll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(expectedChanged, rel(changed)) || !reflect.DeepEqual(expectedRemoved, rel(removed)) {
+			t.Errorf("expected %v and %v, got %v and %v", expectedChanged, expectedRemoved, changed, removed)
+		}
+	}
+
+	poll([]string{`manifests/config.pp`, `manifests/init.pp`}, []string{})
+	poll([]string{}, []string{})
+
+	if err := ioutil.WriteFile(filepath.Join(dir, `manifests`, `init.pp`), []byte(`class ntp { include ntp::config }`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, `site.pp`), []byte(`include ntp`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(dir, `manifests`, `config.pp`)); err != nil {
+		t.Fatal(err)
+	}
+	poll([]string{`manifests/init.pp`, `site.pp`}, []string{`manifests/config.pp`})
+}
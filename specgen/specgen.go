@@ -0,0 +1,282 @@
+// Package specgen generates skeleton rspec-puppet spec files for classes and defined types.
+//
+// Each spec declares the parameters that the definition requires, with sample values derived from
+// their types, lists the optional parameters and their defaults as comments, and expects the catalog
+// to compile on all supported operating systems. The generated part of a spec is delimited by marker
+// lines so that a spec can be regenerated with Merge when the parameters change without losing the
+// examples that were added outside of the markers.
+package specgen
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lyraproj/puppet-parser/analysis"
+	"github.com/lyraproj/puppet-parser/literal"
+	"github.com/lyraproj/puppet-parser/parser"
+	"github.com/lyraproj/puppet-parser/printer"
+)
+
+const (
+	// BEGIN_MARKER is the comment that precedes the generated part of a spec
+	BEGIN_MARKER = `# BEGIN GENERATED by specgen. Edits between BEGIN and END are lost when the spec is regenerated.`
+
+	// END_MARKER is the comment that follows the generated part of a spec
+	END_MARKER = `# END GENERATED`
+)
+
+// Spec is a generated spec file
+type Spec struct {
+	// Path is the path of the spec file relative to the root of the module, e.g.
+	// "spec/classes/ntp_spec.rb" or "spec/defines/peer_spec.rb"
+	Path string
+
+	// Schema describes the class or defined type that the spec is for
+	Schema *analysis.DefinitionSchema
+
+	// Source is the content of the spec file
+	Source string
+}
+
+// Generate returns a spec for each class and defined type in the tree rooted at the given expression,
+// in the order that they appear
+func Generate(root parser.Expression) []*Spec {
+	specs := make([]*Spec, 0)
+	for _, s := range analysis.ParameterSchemas(root) {
+		if s.Kind == `plan` {
+			continue
+		}
+		b := bytes.NewBufferString(``)
+		writeSpec(b, s)
+		specs = append(specs, &Spec{Path: SpecPath(s.Kind, s.Name), Schema: s, Source: b.String()})
+	}
+	return specs
+}
+
+// SpecPath returns the conventional path of the spec file of a class or defined type with the given name.
+// The first segment of the name is the module name. It's dropped unless the name has no other segments,
+// and the remaining segments are joined with a double underscore, e.g. "spec/classes/ntp_spec.rb" for
+// the class ntp and "spec/defines/peer__server_spec.rb" for the defined type ntp::peer::server.
+func SpecPath(kind string, name string) string {
+	dir := `spec/classes/`
+	if kind == `define` {
+		dir = `spec/defines/`
+	}
+	segments := strings.Split(strings.TrimPrefix(name, `::`), `::`)
+	if len(segments) > 1 {
+		segments = segments[1:]
+	}
+	return dir + strings.Join(segments, `__`) + `_spec.rb`
+}
+
+// Merge replaces the generated part of the existing content of a spec file with the generated part of
+// the given spec and returns the result and true. The existing content is returned unchanged together
+// with false when it has no generated part.
+func Merge(existing string, spec *Spec) (string, bool) {
+	eb, ee, ok := generatedPart(existing)
+	if !ok {
+		return existing, false
+	}
+	gb, ge, ok := generatedPart(spec.Source)
+	if !ok {
+		return existing, false
+	}
+	return existing[:eb] + spec.Source[gb:ge] + existing[ee:], true
+}
+
+// generatedPart returns the offsets of the start of the line of the BEGIN_MARKER and of the end of the
+// line of the END_MARKER in the given content
+func generatedPart(content string) (int, int, bool) {
+	begin := strings.Index(content, BEGIN_MARKER)
+	if begin < 0 {
+		return 0, 0, false
+	}
+	end := strings.Index(content[begin:], END_MARKER)
+	if end < 0 {
+		return 0, 0, false
+	}
+	end += begin + len(END_MARKER)
+	begin = strings.LastIndexByte(content[:begin], '\n') + 1
+	if nl := strings.IndexByte(content[end:], '\n'); nl >= 0 {
+		end += nl + 1
+	} else {
+		end = len(content)
+	}
+	return begin, end, true
+}
+
+func writeSpec(w io.Writer, s *analysis.DefinitionSchema) {
+	fmt.Fprintf(w, "# frozen_string_literal: true\n\nrequire 'spec_helper'\n\ndescribe %s do\n", rubyString(s.Name))
+	fmt.Fprintf(w, "  %s\n", BEGIN_MARKER)
+	fmt.Fprintln(w, `  on_supported_os.each do |os, os_facts|`)
+	fmt.Fprintln(w, `    context "on #{os}" do`)
+	fmt.Fprintln(w, `      let(:facts) { os_facts }`)
+	if s.Kind == `define` {
+		fmt.Fprintln(w, `      let(:title) { 'namevar' }`)
+	}
+	params := s.Definition.(parser.NamedDefinition).Parameters()
+	if len(params) > 0 {
+		fmt.Fprintln(w, `      let(:params) do`)
+		fmt.Fprintln(w, `        {`)
+		for i, ps := range s.Parameters {
+			if ps.Default == nil {
+				value, known := sampleValue(params[i].(*parser.Parameter).Type())
+				if known {
+					fmt.Fprintf(w, "          %s: %s,\n", ps.Name, value)
+				} else {
+					fmt.Fprintf(w, "          %s: %s, # TODO: a value of type %s\n", ps.Name, value, ps.Type)
+				}
+			}
+		}
+		for _, ps := range s.Parameters {
+			if ps.Default != nil {
+				if value, ok := rubyLiteral(ps.Default); ok {
+					fmt.Fprintf(w, "          # %s: %s,\n", ps.Name, value)
+				} else {
+					fmt.Fprintf(w, "          # %s: defaults to %s\n", ps.Name, printer.String(ps.Default))
+				}
+			}
+		}
+		fmt.Fprintln(w, `        }`)
+		fmt.Fprintln(w, `      end`)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, `      it { is_expected.to compile.with_all_deps }`)
+	if s.Kind == `define` {
+		fmt.Fprintf(w, "      it { is_expected.to contain_%s('namevar') }\n", strings.ReplaceAll(parser.ResolveReference(s.Name), `::`, `__`))
+	} else {
+		fmt.Fprintf(w, "      it { is_expected.to contain_class(%s) }\n", rubyString(parser.ResolveReference(s.Name)))
+	}
+	fmt.Fprintln(w, `    end`)
+	fmt.Fprintln(w, `  end`)
+	fmt.Fprintf(w, "  %s\n", END_MARKER)
+	fmt.Fprintln(w, `end`)
+}
+
+// sampleValue returns the Ruby source of a value of the given type and true, or a placeholder string and
+// false when no such value can be derived from the type
+func sampleValue(t parser.Expression) (string, bool) {
+	var name string
+	var keys []parser.Expression
+	switch t := t.(type) {
+	case *parser.QualifiedReference:
+		name = t.DowncasedName()
+	case *parser.AccessExpression:
+		qr, ok := t.Operand().(*parser.QualifiedReference)
+		if !ok {
+			return `'value'`, false
+		}
+		name = qr.DowncasedName()
+		keys = t.Keys()
+	default:
+		return `'value'`, false
+	}
+
+	switch name {
+	case `string`, `scalar`, `scalardata`, `data`, `any`:
+		return `'value'`, true
+	case `integer`, `numeric`:
+		if len(keys) > 0 {
+			if v, ok := literal.ToLiteral(keys[0]); ok {
+				if i, ok := v.(int64); ok {
+					return strconv.FormatInt(i, 10), true
+				}
+			}
+		}
+		return `1`, true
+	case `float`:
+		return `1.0`, true
+	case `boolean`:
+		return `true`, true
+	case `array`, `tuple`:
+		return `[]`, true
+	case `hash`, `struct`:
+		return `{}`, true
+	case `optional`, `undef`:
+		return `nil`, true
+	case `enum`:
+		if len(keys) > 0 {
+			if v, ok := rubyLiteral(keys[0]); ok {
+				return v, true
+			}
+		}
+	case `variant`:
+		if len(keys) > 0 {
+			return sampleValue(keys[0])
+		}
+	case `stdlib::absolutepath`, `stdlib::unixpath`:
+		return `'/tmp/value'`, true
+	case `stdlib::port`:
+		return `8080`, true
+	case `stdlib::host`, `stdlib::fqdn`:
+		return `'host.example.com'`, true
+	}
+	return `'value'`, false
+}
+
+// rubyLiteral returns the Ruby source of the value of the given literal expression
+func rubyLiteral(e parser.Expression) (string, bool) {
+	if _, ok := e.(*parser.LiteralDefault); ok {
+		return ``, false
+	}
+	if qn, ok := e.(*parser.QualifiedName); ok {
+		return rubyString(qn.Name()), true
+	}
+	v, ok := literal.ToLiteral(e)
+	if !ok {
+		return ``, false
+	}
+	return rubyValue(v)
+}
+
+func rubyValue(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case nil:
+		return `nil`, true
+	case string:
+		return rubyString(v), true
+	case bool:
+		return strconv.FormatBool(v), true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), true
+	case []interface{}:
+		elems := make([]string, len(v))
+		for i, e := range v {
+			var ok bool
+			if elems[i], ok = rubyValue(e); !ok {
+				return ``, false
+			}
+		}
+		return `[` + strings.Join(elems, `, `) + `]`, true
+	case map[interface{}]interface{}:
+		entries := make([]string, 0, len(v))
+		for k, e := range v {
+			ks, ok := rubyValue(k)
+			if !ok {
+				return ``, false
+			}
+			es, ok := rubyValue(e)
+			if !ok {
+				return ``, false
+			}
+			entries = append(entries, ks+` => `+es)
+		}
+		sort.Strings(entries)
+		if len(entries) == 0 {
+			return `{}`, true
+		}
+		return `{ ` + strings.Join(entries, `, `) + ` }`, true
+	}
+	return ``, false
+}
+
+// rubyString returns the given string as a single quoted Ruby string
+func rubyString(s string) string {
+	return `'` + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + `'`
+}
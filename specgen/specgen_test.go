@@ -0,0 +1,175 @@
+package specgen
+
+import (
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+const manifest = `
+class ntp(
+  Array[String] $servers = ['pool.ntp.org'],
+  Enum['client', 'server'] $mode,
+  Integer[1, 10] $retries,
+  Mymod::Config $config,
+  Hash $opts = { 'b' => 2, 'a' => 'x' },
+  String $file = "${name}.conf",
+) {
+}
+
+define ntp::peer::server(Variant[String, Integer] $host) {}
+
+class ntp::config {}
+
+plan ntp::check() {}`
+
+func TestGenerate(t *testing.T) {
+	specs := generate(t, manifest)
+	if len(specs) != 3 {
+		t.Fatalf("expected 3 specs, got %d", len(specs))
+	}
+
+	expectSpec(t, specs[0], `spec/classes/ntp_spec.rb`, `# frozen_string_literal: true
+
+require 'spec_helper'
+
+describe 'ntp' do
+  `+BEGIN_MARKER+`
+  on_supported_os.each do |os, os_facts|
+    context "on #{os}" do
+      let(:facts) { os_facts }
+      let(:params) do
+        {
+          mode: 'client',
+          retries: 1,
+          config: 'value', # TODO: a value of type Mymod::Config
+          # servers: ['pool.ntp.org'],
+          # opts: { 'a' => 'x', 'b' => 2 },
+          # file: defaults to "${name}.conf"
+        }
+      end
+
+      it { is_expected.to compile.with_all_deps }
+      it { is_expected.to contain_class('ntp') }
+    end
+  end
+  `+END_MARKER+`
+end
+`)
+
+	expectSpec(t, specs[1], `spec/defines/peer__server_spec.rb`, `# frozen_string_literal: true
+
+require 'spec_helper'
+
+describe 'ntp::peer::server' do
+  `+BEGIN_MARKER+`
+  on_supported_os.each do |os, os_facts|
+    context "on #{os}" do
+      let(:facts) { os_facts }
+      let(:title) { 'namevar' }
+      let(:params) do
+        {
+          host: 'value',
+        }
+      end
+
+      it { is_expected.to compile.with_all_deps }
+      it { is_expected.to contain_ntp__peer__server('namevar') }
+    end
+  end
+  `+END_MARKER+`
+end
+`)
+
+	expectSpec(t, specs[2], `spec/classes/config_spec.rb`, `# frozen_string_literal: true
+
+require 'spec_helper'
+
+describe 'ntp::config' do
+  `+BEGIN_MARKER+`
+  on_supported_os.each do |os, os_facts|
+    context "on #{os}" do
+      let(:facts) { os_facts }
+
+      it { is_expected.to compile.with_all_deps }
+      it { is_expected.to contain_class('ntp::config') }
+    end
+  end
+  `+END_MARKER+`
+end
+`)
+}
+
+func TestMerge(t *testing.T) {
+	spec := generate(t, `class ntp::config(String $x) {}`)[0]
+	existing := issue.Unindent(`
+    require 'spec_helper'
+
+    describe 'ntp::config' do
+      ` + BEGIN_MARKER + `
+      it { is_expected.to compile }
+      ` + END_MARKER + `
+
+      context 'with custom examples' do
+        it { is_expected.to contain_file('/etc/ntp.conf') }
+      end
+    end
+    `)
+
+	merged, ok := Merge(existing, spec)
+	if !ok {
+		t.Fatal(`expected a merge`)
+	}
+	expected := issue.Unindent(`
+    require 'spec_helper'
+
+    describe 'ntp::config' do
+      ` + BEGIN_MARKER + `
+      on_supported_os.each do |os, os_facts|
+        context "on #{os}" do
+          let(:facts) { os_facts }
+          let(:params) do
+            {
+              x: 'value',
+            }
+          end
+
+          it { is_expected.to compile.with_all_deps }
+          it { is_expected.to contain_class('ntp::config') }
+        end
+      end
+      ` + END_MARKER + `
+
+      context 'with custom examples' do
+        it { is_expected.to contain_file('/etc/ntp.conf') }
+      end
+    end
+    `)
+	if merged != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, merged)
+	}
+
+	if _, ok := Merge(`describe 'ntp::config' do; end`, spec); ok {
+		t.Error(`expected no merge of a spec without generated part`)
+	}
+}
+
+func generate(t *testing.T, source string) []*Spec {
+	t.Helper()
+	expr, err := parser.CreateParser(parser.PARSER_TASKS_ENABLED).Parse(`test.pp`, source, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Generate(expr)
+}
+
+func expectSpec(t *testing.T, spec *Spec, path string, source string) {
+	t.Helper()
+	if spec.Path != path {
+		t.Errorf("expected path %s, got %s", path, spec.Path)
+	}
+	if spec.Source != source {
+		t.Errorf("expected:\n%s\ngot:\n%s", source, spec.Source)
+	}
+}
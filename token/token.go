@@ -0,0 +1,259 @@
+// Package token defines the tokens that the lexer of the parser package produces
+package token
+
+const (
+	END = 0
+
+	// Binary ops
+	ASSIGN          = 1
+	ADD_ASSIGN      = 2
+	SUBTRACT_ASSIGN = 3
+
+	MULTIPLY  = 10
+	DIVIDE    = 11
+	REMAINDER = 12
+	SUBTRACT  = 13
+	ADD       = 14
+
+	LSHIFT = 20
+	RSHIFT = 21
+
+	EQUAL         = 30
+	NOT_EQUAL     = 31
+	LESS          = 32
+	LESS_EQUAL    = 33
+	GREATER       = 34
+	GREATER_EQUAL = 35
+
+	MATCH     = 40
+	NOT_MATCH = 41
+
+	LCOLLECT  = 50
+	LLCOLLECT = 51
+
+	RCOLLECT  = 60
+	RRCOLLECT = 61
+
+	FARROW = 70
+	PARROW = 71
+
+	IN_EDGE      = 72
+	IN_EDGE_SUB  = 73
+	OUT_EDGE     = 74
+	OUT_EDGE_SUB = 75
+
+	// Unary ops
+	NOT  = 80
+	AT   = 81
+	ATAT = 82
+
+	// ()
+	LP   = 90
+	WSLP = 91
+	RP   = 92
+
+	// []
+	LB        = 100
+	LISTSTART = 101
+	RB        = 102
+
+	// {}
+	LC   = 110
+	SELC = 111
+	RC   = 112
+
+	// | |
+	PIPE     = 120
+	PIPE_END = 121
+
+	// EPP
+	EPP_END       = 130
+	EPP_END_TRIM  = 131
+	RENDER_EXPR   = 132
+	RENDER_STRING = 133
+
+	// Separators
+	COMMA     = 140
+	DOT       = 141
+	QMARK     = 142
+	COLON     = 143
+	SEMICOLON = 144
+
+	// Strings with semantics
+	IDENTIFIER          = 150
+	STRING              = 151
+	INTEGER             = 152
+	FLOAT               = 153
+	BOOLEAN             = 154
+	CONCATENATED_STRING = 155
+	HEREDOC             = 156
+	VARIABLE            = 157
+	REGEXP              = 158
+	TYPE_NAME           = 159
+
+	// Comments, only produced by a lexer that emits comments
+	COMMENT = 160
+
+	// Source that cannot be lexed, only produced by a lexer that recovers from errors
+	ERROR = 170
+
+	// Keywords
+	AND         = 200
+	APPLICATION = 201
+	ATTR        = 202
+	CASE        = 203
+	CLASS       = 204
+	CONSUMES    = 205
+	DEFAULT     = 206
+	DEFINE      = 207
+	FUNCTION    = 208
+	IF          = 209
+	IN          = 210
+	INHERITS    = 211
+	ELSE        = 212
+	ELSIF       = 213
+	NODE        = 214
+	OR          = 215
+	PLAN        = 216
+	PRIVATE     = 217
+	PRODUCES    = 218
+	SITE        = 219
+	TYPE        = 220
+	UNDEF       = 221
+	UNLESS      = 222
+)
+
+// names is indexed by token
+var names = [...]string{
+	END:   `EOF`,
+	ERROR: `error`,
+
+	// Binary ops
+	ASSIGN:          `=`,
+	ADD_ASSIGN:      `+=`,
+	SUBTRACT_ASSIGN: `-=`,
+
+	MULTIPLY:  `*`,
+	DIVIDE:    `/`,
+	REMAINDER: `%`,
+	SUBTRACT:  `-`,
+	ADD:       `+`,
+
+	LSHIFT: `<<`,
+	RSHIFT: `>>`,
+
+	EQUAL:         `==`,
+	NOT_EQUAL:     `!=`,
+	LESS:          `<`,
+	LESS_EQUAL:    `<=`,
+	GREATER:       `>`,
+	GREATER_EQUAL: `>=`,
+
+	MATCH:     `=~`,
+	NOT_MATCH: `!~`,
+
+	LCOLLECT:  `<|`,
+	LLCOLLECT: `<<|`,
+
+	RCOLLECT:  `|>`,
+	RRCOLLECT: `|>>`,
+
+	FARROW: `=>`,
+	PARROW: `+>`,
+
+	IN_EDGE:      `->`,
+	IN_EDGE_SUB:  `~>`,
+	OUT_EDGE:     `<-`,
+	OUT_EDGE_SUB: `<~`,
+
+	// Unary ops
+	NOT:  `!`,
+	AT:   `@`,
+	ATAT: `@@`,
+
+	COMMA: `,`,
+
+	// ()
+	LP:   `(`,
+	WSLP: `(`,
+	RP:   `)`,
+
+	// []
+	LB:        `[`,
+	LISTSTART: `[`,
+	RB:        `]`,
+
+	// {}
+	LC:   `{`,
+	SELC: `{`,
+	RC:   `}`,
+
+	// | |
+	PIPE:     `|`,
+	PIPE_END: `|`,
+
+	// EPP
+	EPP_END:       `%>`,
+	EPP_END_TRIM:  `-%>`,
+	RENDER_EXPR:   `<%=`,
+	RENDER_STRING: `epp text`,
+
+	// Separators
+	DOT:       `.`,
+	QMARK:     `?`,
+	COLON:     `:`,
+	SEMICOLON: `;`,
+
+	// Strings with semantics
+	IDENTIFIER:          `identifier`,
+	STRING:              `string literal`,
+	INTEGER:             `integer literal`,
+	FLOAT:               `float literal`,
+	BOOLEAN:             `boolean literal`,
+	CONCATENATED_STRING: `dq string literal`,
+	HEREDOC:             `heredoc`,
+	VARIABLE:            `variable`,
+	REGEXP:              `regexp`,
+	TYPE_NAME:           `type name`,
+
+	COMMENT: `comment`,
+
+	// Keywords
+	AND:         `and`,
+	APPLICATION: `application`,
+	ATTR:        `attr`,
+	CASE:        `case`,
+	CLASS:       `class`,
+	CONSUMES:    `consumes`,
+	DEFAULT:     `default`,
+	DEFINE:      `define`,
+	FUNCTION:    `function`,
+	IF:          `if`,
+	IN:          `in`,
+	INHERITS:    `inherits`,
+	ELSE:        `else`,
+	ELSIF:       `elsif`,
+	NODE:        `node`,
+	OR:          `or`,
+	PLAN:        `plan`,
+	PRIVATE:     `private`,
+	PRODUCES:    `produces`,
+	SITE:        `site`,
+	TYPE:        `type`,
+	UNDEF:       `undef`,
+	UNLESS:      `unless`,
+}
+
+// String returns the string representation of the given token, e.g. "=>" for FARROW, "if" for IF, and
+// "variable" for VARIABLE. An empty string is returned for a value that isn't a token.
+func String(token int) string {
+	if token < 0 || token >= len(names) {
+		return ``
+	}
+	return names[token]
+}
+
+// IsKeyword returns true if the given token is a keyword
+func IsKeyword(token int) bool {
+	return token >= AND && token <= UNLESS
+}
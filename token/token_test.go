@@ -0,0 +1,17 @@
+package token
+
+import "testing"
+
+func TestString(t *testing.T) {
+	for tk, s := range map[int]string{FARROW: `=>`, IF: `if`, VARIABLE: `variable`, END: `EOF`, 999: ``} {
+		if String(tk) != s {
+			t.Errorf(`expected String(%d) to be %q, got %q`, tk, s, String(tk))
+		}
+	}
+}
+
+func TestIsKeyword(t *testing.T) {
+	if !IsKeyword(AND) || !IsKeyword(UNLESS) || IsKeyword(IDENTIFIER) || IsKeyword(BOOLEAN) {
+		t.Error(`unexpected keyword classification`)
+	}
+}
@@ -0,0 +1,296 @@
+// Package typegen generates Go type declarations from Puppet type aliases.
+//
+// A type alias that is a Struct or an Object becomes a Go struct with JSON tags that match the
+// keys of the Struct or the attributes of the Object. Other aliases become Go types with the
+// underlying Go type that best represents the aliased Puppet type.
+package typegen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"strings"
+
+	"github.com/lyraproj/puppet-parser/literal"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+type (
+	// Generator collects type aliases from parsed programs and generates Go source for them
+	Generator struct {
+		pkg     string
+		aliases map[string]*parser.TypeAlias
+		order   []string
+		decls   []string
+		emitted map[string]bool
+	}
+
+	field struct {
+		name     string
+		goType   string
+		optional bool
+	}
+)
+
+// NewGenerator creates a Generator for a Go package with the given name
+func NewGenerator(pkg string) *Generator {
+	return &Generator{pkg: pkg, aliases: make(map[string]*parser.TypeAlias)}
+}
+
+// Add adds all type aliases found in the given expression
+func (g *Generator) Add(e parser.Expression) {
+	add := func(path []parser.Expression, e parser.Expression) {
+		if ta, ok := e.(*parser.TypeAlias); ok {
+			if _, found := g.aliases[ta.Name()]; !found {
+				g.order = append(g.order, ta.Name())
+			}
+			g.aliases[ta.Name()] = ta
+		}
+	}
+	add(nil, e)
+	e.AllContents(nil, add)
+}
+
+// Generate writes formatted Go source with declarations for all added type aliases to the given writer
+func (g *Generator) Generate(w io.Writer) error {
+	g.decls = make([]string, 0, len(g.order))
+	g.emitted = make(map[string]bool, len(g.order))
+	for _, name := range g.order {
+		g.declare(GoName(name), g.aliases[name].Type())
+	}
+
+	b := bytes.NewBufferString(``)
+	fmt.Fprintf(b, "// Code generated by pp2go. DO NOT EDIT.\n\npackage %s\n", g.pkg)
+	for _, decl := range g.decls {
+		b.WriteString("\n")
+		b.WriteString(decl)
+	}
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+// GoName returns the name of the Go type that represents a Puppet type with the given name,
+// e.g. 'Mymodule::Config' becomes 'MymoduleConfig'
+func GoName(name string) string {
+	b := bytes.NewBufferString(``)
+	for _, segment := range strings.Split(name, `::`) {
+		b.WriteString(exported(segment))
+	}
+	return b.String()
+}
+
+// exported returns the given name in camel case with an initial upper case letter,
+// e.g. 'listen_port' becomes 'ListenPort'
+func exported(name string) string {
+	b := bytes.NewBufferString(``)
+	for _, part := range strings.FieldsFunc(name, func(c rune) bool { return c == '_' || c == '-' }) {
+		b.WriteString(strings.ToUpper(part[:1]))
+		b.WriteString(part[1:])
+	}
+	return b.String()
+}
+
+func (g *Generator) declare(name string, t parser.Expression) {
+	if g.emitted[name] {
+		return
+	}
+	g.emitted[name] = true
+
+	// Reserve the position of this declaration so that it precedes nested declarations
+	idx := len(g.decls)
+	g.decls = append(g.decls, ``)
+	if fields, parent, ok := g.structFields(name, t); ok {
+		g.decls[idx] = structDecl(name, parent, fields)
+	} else {
+		g.decls[idx] = fmt.Sprintf("type %s %s\n", name, g.goType(name, t))
+	}
+}
+
+func structDecl(name string, parent string, fields []*field) string {
+	b := bytes.NewBufferString(``)
+	fmt.Fprintf(b, "type %s struct {\n", name)
+	if parent != `` {
+		fmt.Fprintf(b, "%s\n", parent)
+	}
+	for _, f := range fields {
+		tag := f.name
+		if f.optional {
+			tag += `,omitempty`
+		}
+		fmt.Fprintf(b, "%s %s `json:\"%s\"`\n", exported(f.name), f.goType, tag)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// structFields returns the fields of a Struct or Object type and the name of the parent struct in
+// case of an Object that has a parent.
+func (g *Generator) structFields(name string, t parser.Expression) (fields []*field, parent string, ok bool) {
+	ae, ok := t.(*parser.AccessExpression)
+	if !ok || len(ae.Keys()) != 1 {
+		return nil, ``, false
+	}
+	hash, ok := ae.Keys()[0].(*parser.LiteralHash)
+	if !ok {
+		return nil, ``, false
+	}
+
+	switch typeName(ae.Operand()) {
+	case `Struct`:
+		fields = make([]*field, 0, len(hash.Entries()))
+		for _, entry := range hash.Entries() {
+			ke := entry.(*parser.KeyedEntry)
+			key, optional := structKey(ke.Key())
+			if key == `` {
+				continue
+			}
+			fields = append(fields, g.field(name, key, ke.Value(), optional))
+		}
+		return fields, ``, true
+
+	case `Object`:
+		if p := hash.Get(`parent`); p != nil {
+			if pn := typeName(p); g.aliases[pn] != nil {
+				parent = GoName(pn)
+			}
+		}
+		fields = make([]*field, 0)
+		if attrs, ok := hash.Get(`attributes`).(*parser.LiteralHash); ok {
+			for _, entry := range attrs.Entries() {
+				ke := entry.(*parser.KeyedEntry)
+				key := stringValue(ke.Key())
+				if key == `` {
+					continue
+				}
+				at := ke.Value()
+				optional := false
+				if ah, ok := at.(*parser.LiteralHash); ok {
+					// Attribute declared using a hash with type, value, kind etc.
+					optional = ah.Get(`value`) != nil
+					if at = ah.Get(`type`); at == nil {
+						at = ah
+					}
+				}
+				fields = append(fields, g.field(name, key, at, optional))
+			}
+		}
+		return fields, parent, true
+	}
+	return nil, ``, false
+}
+
+func (g *Generator) field(structName string, key string, t parser.Expression, optional bool) *field {
+	goType := g.goType(structName+exported(key), t)
+	if optional {
+		goType = pointer(goType)
+	}
+	return &field{key, goType, optional || isOptional(t)}
+}
+
+// goType returns the Go type that represents the given type. The given name is used when a
+// declaration must be generated for a Struct or Object that is nested in another type.
+func (g *Generator) goType(name string, t parser.Expression) string {
+	if ae, ok := t.(*parser.AccessExpression); ok {
+		keys := ae.Keys()
+		switch tn := typeName(ae.Operand()); tn {
+		case `Struct`, `Object`:
+			g.declare(name, t)
+			return name
+		case `Optional`:
+			if len(keys) == 1 {
+				if _, ok := keys[0].(*parser.LiteralString); ok {
+					return `*string`
+				}
+				return pointer(g.goType(name, keys[0]))
+			}
+		case `NotUndef`, `Sensitive`:
+			if len(keys) == 1 {
+				return g.goType(name, keys[0])
+			}
+		case `Array`:
+			if len(keys) > 0 {
+				return `[]` + g.goType(name+`Element`, keys[0])
+			}
+		case `Hash`:
+			if len(keys) > 1 {
+				return `map[string]` + g.goType(name+`Value`, keys[1])
+			}
+		}
+		return g.goType(name, ae.Operand())
+	}
+
+	switch tn := typeName(t); tn {
+	case `String`, `Pattern`, `Enum`, `Timestamp`, `Timespan`:
+		return `string`
+	case `Integer`:
+		return `int64`
+	case `Float`, `Numeric`:
+		return `float64`
+	case `Boolean`:
+		return `bool`
+	case `Array`, `Tuple`:
+		return `[]interface{}`
+	case `Hash`, `Struct`:
+		return `map[string]interface{}`
+	case ``:
+		if _, ok := t.(*parser.LiteralString); ok {
+			// Shorthand for Enum with one value
+			return `string`
+		}
+	default:
+		if g.aliases[tn] != nil {
+			return GoName(tn)
+		}
+	}
+	return `interface{}`
+}
+
+// pointer returns a pointer to the given type unless the type already can be nil
+func pointer(goType string) string {
+	if strings.HasPrefix(goType, `*`) || strings.HasPrefix(goType, `[]`) || strings.HasPrefix(goType, `map[`) || goType == `interface{}` {
+		return goType
+	}
+	return `*` + goType
+}
+
+func isOptional(t parser.Expression) bool {
+	if ae, ok := t.(*parser.AccessExpression); ok {
+		return typeName(ae.Operand()) == `Optional`
+	}
+	return false
+}
+
+// structKey returns the name of a Struct key and whether or not it's optional
+func structKey(k parser.Expression) (string, bool) {
+	if ae, ok := k.(*parser.AccessExpression); ok && len(ae.Keys()) == 1 {
+		switch typeName(ae.Operand()) {
+		case `Optional`:
+			return stringValue(ae.Keys()[0]), true
+		case `NotUndef`:
+			return stringValue(ae.Keys()[0]), false
+		}
+		return ``, false
+	}
+	return stringValue(k), false
+}
+
+func typeName(e parser.Expression) string {
+	if qr, ok := e.(*parser.QualifiedReference); ok {
+		return qr.Name()
+	}
+	return ``
+}
+
+func stringValue(e parser.Expression) string {
+	if v, ok := literal.ToLiteral(e); ok {
+		if s, ok := v.(string); ok {
+			return s
+		}
+	}
+	return ``
+}
@@ -0,0 +1,84 @@
+package typegen
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+func TestStruct(t *testing.T) {
+	expectGo(t, `
+type Mymodule::Config = Struct[{
+  name => String,
+  listen_port => Integer[1, 65535],
+  Optional[ratio] => Float,
+  tags => Array[String],
+  labels => Hash[String, Mymodule::Label],
+  tls => Struct[{ cert => String, 'verify' => Optional[Boolean] }],
+  other => Variant[String, Integer]
+}]
+
+type Mymodule::Label = String[1]`, `// Code generated by pp2go. DO NOT EDIT.
+
+package config
+
+type MymoduleConfig struct {
+	Name       string                   `+"`"+`json:"name"`+"`"+`
+	ListenPort int64                    `+"`"+`json:"listen_port"`+"`"+`
+	Ratio      *float64                 `+"`"+`json:"ratio,omitempty"`+"`"+`
+	Tags       []string                 `+"`"+`json:"tags"`+"`"+`
+	Labels     map[string]MymoduleLabel `+"`"+`json:"labels"`+"`"+`
+	Tls        MymoduleConfigTls        `+"`"+`json:"tls"`+"`"+`
+	Other      interface{}              `+"`"+`json:"other"`+"`"+`
+}
+
+type MymoduleConfigTls struct {
+	Cert   string `+"`"+`json:"cert"`+"`"+`
+	Verify *bool  `+"`"+`json:"verify,omitempty"`+"`"+`
+}
+
+type MymoduleLabel string
+`)
+}
+
+func TestObject(t *testing.T) {
+	expectGo(t, `
+type Mymodule::Base = Object[{ attributes => { id => String } }]
+type Mymodule::Service = Mymodule::Base {
+  attributes => {
+    port => Integer,
+    enabled => { type => Boolean, value => true }
+  }
+}`, `// Code generated by pp2go. DO NOT EDIT.
+
+package config
+
+type MymoduleBase struct {
+	Id string `+"`"+`json:"id"`+"`"+`
+}
+
+type MymoduleService struct {
+	MymoduleBase
+	Port    int64 `+"`"+`json:"port"`+"`"+`
+	Enabled *bool `+"`"+`json:"enabled,omitempty"`+"`"+`
+}
+`)
+}
+
+func expectGo(t *testing.T, source string, expected string) {
+	t.Helper()
+	expr, err := parser.CreateParser().Parse(`test.pp`, source, false)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	g := NewGenerator(`config`)
+	g.Add(expr)
+	b := bytes.NewBufferString(``)
+	if err = g.Generate(b); err != nil {
+		t.Fatal(err.Error())
+	}
+	if actual := b.String(); actual != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, actual)
+	}
+}
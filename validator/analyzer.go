@@ -0,0 +1,29 @@
+package validator
+
+import (
+	"github.com/lyraproj/puppet-parser/analysis"
+)
+
+// NewAnalyzer returns an analyzer with the given name and documentation that validates each program
+// of a module using a validator created by the given function, and reports the issues that the
+// validator finds. This makes the validators composable with other analyzers run by
+// analysis.RunAnalyzers, e.g.
+//
+//	puppet := NewAnalyzer(`puppet`, `validates Puppet manifests`, func() Validator { return NewChecker(STRICT_WARNING) })
+//	issues, err := analysis.RunAnalyzers([]*analysis.Analyzer{puppet, myCheck}, modules)
+func NewAnalyzer(name, doc string, create func() Validator) *analysis.Analyzer {
+	return &analysis.Analyzer{
+		Name: name,
+		Doc:  doc,
+		Run: func(pass *analysis.Pass) (interface{}, error) {
+			v := create()
+			for _, program := range pass.Module.Programs {
+				Validate(v, program)
+				for _, i := range v.Issues() {
+					pass.ReportIssue(i)
+				}
+			}
+			return nil, nil
+		},
+	}
+}
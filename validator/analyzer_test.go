@@ -0,0 +1,23 @@
+package validator
+
+import (
+	"testing"
+
+	"github.com/lyraproj/puppet-parser/analysis"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+func TestNewAnalyzer(t *testing.T) {
+	program, err := parser.CreateParser().Parse(`test.pp`, `$x::z = 1`, false)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	puppet := NewAnalyzer(`puppet`, `validates Puppet manifests`, func() Validator { return NewChecker(STRICT_ERROR) })
+	issues, err := analysis.RunAnalyzers([]*analysis.Analyzer{puppet}, []*analysis.Module{{Name: `m`, Programs: []*parser.Program{program.(*parser.Program)}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 1 || issues[0].Code() != VALIDATE_CROSS_SCOPE_ASSIGNMENT {
+		t.Errorf("unexpected issues %v", issues)
+	}
+}
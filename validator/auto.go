@@ -0,0 +1,49 @@
+package validator
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+// ParserValidatorFor returns a ParserValidator that parses and validates the file at the given path
+// the way that the conventions of a Puppet module call for:
+//
+//   - Files with an .epp extension are parsed as EPP templates and validated as manifests
+//   - Files under a plans directory are parsed and validated as plans, i.e. with tasks enabled
+//   - All other files are parsed and validated as manifests
+//
+// The given parser options are used in addition to the options that the path calls for.
+func ParserValidatorFor(path string, parserOptions ...parser.Option) ParserValidator {
+	return ParserValidatorWith(path, nil, parserOptions...)
+}
+
+// ParserValidatorWith is like ParserValidatorFor but also passes the validator to the given function,
+// unless it is nil, so that the function can configure it, e.g. enable lint or change the severities
+// of issues, before it is used
+func ParserValidatorWith(path string, configure func(Validator), parserOptions ...parser.Option) ParserValidator {
+	path = filepath.ToSlash(path)
+	var v Validator
+	switch {
+	case strings.HasSuffix(path, `.epp`):
+		parserOptions = append(parserOptions, parser.PARSER_EPP_MODE)
+		v = NewChecker(STRICT_WARNING)
+	case strings.HasPrefix(path, `plans/`) || strings.Contains(path, `/plans/`):
+		parserOptions = append(parserOptions, parser.PARSER_TASKS_ENABLED)
+		v = NewTasksChecker()
+	default:
+		v = NewChecker(STRICT_WARNING)
+	}
+	if configure != nil {
+		configure(v)
+	}
+	return NewParserValidator(parser.CreateParser(parserOptions...), v)
+}
+
+// ParseAuto parses and validates the given source using the ParserValidator that ParserValidatorFor
+// returns for the given path
+func ParseAuto(path, source string) (parser.Expression, issue.Result) {
+	return ParserValidatorFor(path).Parse(path, source)
+}
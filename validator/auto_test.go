@@ -0,0 +1,44 @@
+package validator
+
+import (
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+func TestParseAuto(t *testing.T) {
+	expr, result := ParseAuto(`mymod/templates/a.epp`, `Hello <%= $x %>`)
+	if result != nil {
+		t.Fatal(result.Issues()[0].Error())
+	}
+	if _, ok := expr.(*parser.Program).Body().(*parser.LambdaExpression); !ok {
+		t.Errorf("expected template to be parsed in EPP mode")
+	}
+
+	_, result = ParseAuto(`mymod/plans/deploy.pp`, `plan mymod::deploy() { notify { x: } }`)
+	if result == nil || result.Issues()[0].Code() != VALIDATE_CATALOG_OPERATION_NOT_SUPPORTED {
+		t.Errorf("expected plan to be validated with tasks enabled")
+	}
+
+	_, result = ParseAuto(`plans/deploy.pp`, `plan mymod::deploy() {}`)
+	if result != nil {
+		t.Errorf("expected plan to be accepted")
+	}
+
+	_, result = ParseAuto(`mymod/manifests/plans.pp`, `plan mymod::deploy() {}`)
+	if result == nil {
+		t.Errorf("expected plan in manifest to be rejected")
+	}
+}
+
+func TestParserValidatorWith(t *testing.T) {
+	pv := ParserValidatorWith(`mymod/manifests/init.pp`, func(v Validator) {
+		EnableLint(v)
+		WarningsAsErrors(v, VALIDATE_EMPTY_BODY)
+	})
+	_, result := pv.Parse(`mymod/manifests/init.pp`, `class mymod {}`)
+	if result == nil || result.Issues()[0].Code() != VALIDATE_EMPTY_BODY || result.Issues()[0].Severity() != issue.SEVERITY_ERROR {
+		t.Errorf("expected the validator to be configured")
+	}
+}
@@ -0,0 +1,207 @@
+package validator
+
+import (
+	"runtime"
+	"sort"
+	"sync"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-parser/parser"
+	"github.com/lyraproj/puppet-parser/pn"
+)
+
+type (
+	// BatchOptions controls how ValidateAll processes the files
+	BatchOptions struct {
+		// ParserOptions are used in addition to the options that the path of a file calls for. See
+		// ParserValidatorFor.
+		ParserOptions []parser.Option
+
+		// Configure, unless nil, is called with the validator of each file before the file is validated.
+		// See ParserValidatorWith.
+		Configure func(Validator)
+
+		// Jobs is the maximum number of files that are processed at the same time. The default is the
+		// value of runtime.GOMAXPROCS.
+		Jobs int
+	}
+
+	// Report is the result of ValidateAll
+	Report struct {
+		// Files holds one entry per file, sorted by path
+		Files []*FileReport
+
+		// Codes holds one entry per issue code that was reported, sorted by code
+		Codes []*CodeSummary
+
+		severities map[issue.Severity]int
+	}
+
+	// FileReport contains the issues of one file in the order that they were reported. Issues that are
+	// reported more than once with the same message are included once.
+	FileReport struct {
+		Path   string
+		Issues []issue.Reported
+	}
+
+	// CodeSummary tells how many times an issue code was reported and in how many files
+	CodeSummary struct {
+		Code     issue.Code
+		Severity issue.Severity
+		Count    int
+		Files    int
+	}
+)
+
+// ValidateAll parses and validates the given files, a map of path to source, in parallel. Each file is
+// parsed and validated the way that ParserValidatorFor describes.
+func ValidateAll(files map[string]string, options BatchOptions) *Report {
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	jobs := options.Jobs
+	if jobs <= 0 {
+		jobs = runtime.GOMAXPROCS(0)
+	}
+
+	report := &Report{Files: make([]*FileReport, len(paths))}
+	next := make(chan int)
+	wg := sync.WaitGroup{}
+	for j := 0; j < min(jobs, len(paths)); j++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				report.Files[i] = validateFile(paths[i], files[paths[i]], options)
+			}
+		}()
+	}
+	for i := range paths {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	report.summarize()
+	return report
+}
+
+func validateFile(path, source string, options BatchOptions) *FileReport {
+	fr := &FileReport{Path: path, Issues: make([]issue.Reported, 0)}
+	_, result := ParserValidatorWith(path, options.Configure, options.ParserOptions...).Parse(path, source)
+	if result == nil {
+		return fr
+	}
+	seen := make(map[string]bool, len(result.Issues()))
+	for _, ri := range result.Issues() {
+		key := string(ri.Code()) + ` ` + ri.Error()
+		if !seen[key] {
+			seen[key] = true
+			fr.Issues = append(fr.Issues, ri)
+		}
+	}
+	return fr
+}
+
+// summarize computes the code and severity summaries from the file reports
+func (r *Report) summarize() {
+	r.severities = make(map[issue.Severity]int, 4)
+	codes := make(map[issue.Code]*CodeSummary)
+	for _, fr := range r.Files {
+		inFile := make(map[issue.Code]bool)
+		for _, ri := range fr.Issues {
+			r.severities[ri.Severity()]++
+			cs, ok := codes[ri.Code()]
+			if !ok {
+				cs = &CodeSummary{Code: ri.Code(), Severity: ri.Severity()}
+				codes[ri.Code()] = cs
+			}
+			cs.Count++
+			if ri.Severity() > cs.Severity {
+				cs.Severity = ri.Severity()
+			}
+			if !inFile[ri.Code()] {
+				inFile[ri.Code()] = true
+				cs.Files++
+			}
+		}
+	}
+	r.Codes = make([]*CodeSummary, 0, len(codes))
+	for _, cs := range codes {
+		r.Codes = append(r.Codes, cs)
+	}
+	sort.Slice(r.Codes, func(i, j int) bool { return r.Codes[i].Code < r.Codes[j].Code })
+}
+
+// Count returns the number of issues with the given severity in all files
+func (r *Report) Count(severity issue.Severity) int {
+	return r.severities[severity]
+}
+
+// Failed returns true if an error was reported for at least one file. A CI gate fails when it does.
+func (r *Report) Failed() bool {
+	return r.Count(issue.SEVERITY_ERROR) > 0
+}
+
+// Count returns the number of issues with the given severity in the file
+func (fr *FileReport) Count(severity issue.Severity) int {
+	n := 0
+	for _, ri := range fr.Issues {
+		if ri.Severity() == severity {
+			n++
+		}
+	}
+	return n
+}
+
+// ToData returns the report as a map that can be encoded as JSON. The map has the keys "summary", with the
+// number of files and the number of issues per severity, "codes", with a CodeSummary and the documentation
+// URL per code, and "files", with the path, the number of issues per severity, and the issues of each file.
+// See pn.DocURL.
+func (r *Report) ToData() map[string]interface{} {
+	files := make([]interface{}, len(r.Files))
+	failed := 0
+	for i, fr := range r.Files {
+		issues := make([]interface{}, len(fr.Issues))
+		for j, ri := range fr.Issues {
+			issues[j] = pn.ReportedToPN(ri).ToData()
+		}
+		fd := severityCounts(fr.Count)
+		fd[`path`] = fr.Path
+		fd[`issues`] = issues
+		files[i] = fd
+		if fr.Count(issue.SEVERITY_ERROR) > 0 {
+			failed++
+		}
+	}
+
+	codes := make([]interface{}, len(r.Codes))
+	for i, cs := range r.Codes {
+		cd := map[string]interface{}{
+			`code`:     string(cs.Code),
+			`severity`: cs.Severity.String(),
+			`count`:    cs.Count,
+			`files`:    cs.Files,
+		}
+		if url := pn.DocURL(cs.Code); url != `` {
+			cd[`url`] = url
+		}
+		codes[i] = cd
+	}
+
+	summary := severityCounts(r.Count)
+	summary[`files`] = len(r.Files)
+	summary[`failed_files`] = failed
+	return map[string]interface{}{`summary`: summary, `codes`: codes, `files`: files}
+}
+
+func severityCounts(count func(issue.Severity) int) map[string]interface{} {
+	return map[string]interface{}{
+		`error`:       count(issue.SEVERITY_ERROR),
+		`warning`:     count(issue.SEVERITY_WARNING),
+		`deprecation`: count(issue.SEVERITY_DEPRECATION),
+	}
+}
@@ -0,0 +1,71 @@
+package validator
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-parser/json"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+func TestValidateAll(t *testing.T) {
+	report := ValidateAll(map[string]string{
+		`mymod/manifests/init.pp`:    `$a::b = 1 $c::d = 2`,
+		`mymod/manifests/ok.pp`:      `class mymod::ok {}`,
+		`mymod/manifests/broken.pp`:  `$x = `,
+		`mymod/templates/motd.epp`:   `<%= $x %>`,
+		`mymod/plans/deploy.pp`:      `plan mymod::deploy() { notify { x: } }`,
+		`mymod/manifests/another.pp`: `$e::f = 3`,
+	}, BatchOptions{Jobs: 2})
+
+	paths := make([]string, len(report.Files))
+	for i, fr := range report.Files {
+		paths[i] = fr.Path
+	}
+	expected := []string{
+		`mymod/manifests/another.pp`,
+		`mymod/manifests/broken.pp`,
+		`mymod/manifests/init.pp`,
+		`mymod/manifests/ok.pp`,
+		`mymod/plans/deploy.pp`,
+		`mymod/templates/motd.epp`,
+	}
+	if !reflect.DeepEqual(expected, paths) {
+		t.Errorf("expected %v, got %v", expected, paths)
+	}
+
+	if !report.Failed() || report.Count(issue.SEVERITY_ERROR) != 5 || report.Count(issue.SEVERITY_WARNING) != 0 {
+		t.Errorf("unexpected counts %d errors, %d warnings", report.Count(issue.SEVERITY_ERROR), report.Count(issue.SEVERITY_WARNING))
+	}
+	if n := report.Files[2].Count(issue.SEVERITY_ERROR); n != 2 {
+		t.Errorf("expected 2 errors in init.pp, got %d", n)
+	}
+
+	codes := make(map[issue.Code][2]int)
+	for _, cs := range report.Codes {
+		codes[cs.Code] = [2]int{cs.Count, cs.Files}
+	}
+	expectedCodes := map[issue.Code][2]int{
+		VALIDATE_CROSS_SCOPE_ASSIGNMENT:          {3, 2},
+		VALIDATE_CATALOG_OPERATION_NOT_SUPPORTED: {1, 1},
+		parser.LEX_UNEXPECTED_TOKEN:              {1, 1},
+	}
+	if !reflect.DeepEqual(expectedCodes, codes) {
+		t.Errorf("expected %v, got %v", expectedCodes, codes)
+	}
+
+	b := bytes.NewBufferString(``)
+	json.ToJson(report.ToData()[`summary`], b)
+	if b.String() != "{\"deprecation\":0,\"error\":5,\"failed_files\":4,\"files\":6,\"warning\":0}\n" {
+		t.Errorf("unexpected summary %s", b.String())
+	}
+}
+
+func TestValidateAllEmpty(t *testing.T) {
+	report := ValidateAll(map[string]string{}, BatchOptions{})
+	if report.Failed() || len(report.Files) != 0 || len(report.Codes) != 0 {
+		t.Errorf("expected an empty report")
+	}
+}
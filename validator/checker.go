@@ -1,10 +1,13 @@
 package validator
 
 import (
+	"fmt"
 	"regexp"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-parser/analysis"
 	"github.com/lyraproj/puppet-parser/literal"
 	"github.com/lyraproj/puppet-parser/parser"
 )
@@ -22,6 +25,9 @@ var CLASSREF_EXT_DECL = regexp.MustCompile(`\A[A-Z][\w]*(?:::[A-Z][\w]*)*\z`)
 // model (i.e. in lower case).
 var CLASSREF_DECL = regexp.MustCompile(`\A[a-z][\w]*(?:::[a-z][\w]*)*\z`)
 
+// ISSUE_LOCATION matches the locations at the end of the message of a reported issue
+var ISSUE_LOCATION = regexp.MustCompile(`(?: \((?:file: [^,]*, )?line: \d+, column: \d+\))+\z`)
+
 // ILLEGAL_P3_1_HOSTNAME matches if a hostname contains illegal characters.
 // This check does not prevent pathological names like 'a....b', '.....', "---". etc.
 var ILLEGAL_HOSTNAME_CHARS = regexp.MustCompile(`[^-\w.]`)
@@ -31,6 +37,14 @@ var PARAM_NAME = regexp.MustCompile(`\A[a-z_]\w*\z`)
 
 var STARTS_WITH_NUMBER = regexp.MustCompile(`\A[0-9]`)
 
+// QUOTED_NUMBER matches a decimal integer or float without leading zeroes
+var QUOTED_NUMBER = regexp.MustCompile(`\A-?(?:0|[1-9][0-9]*)(?:\.[0-9]+)?(?:[eE][-+]?[0-9]+)?\z`)
+
+// INTERPOLATION_LOOKALIKE matches text in a single quoted string that looks like it was intended
+// as an interpolation, i.e. '${' or a '$' followed by a variable name that starts with a lowercase
+// letter, an underscore, or '::'.
+var INTERPOLATION_LOOKALIKE = regexp.MustCompile(`\$\{|\$(?:::)?[a-z_]\w*(?:::[a-z_]\w*)*`)
+
 var RESERVED_TYPE_NAMES = map[string]bool{
 	`type`:       true,
 	`any`:        true,
@@ -74,6 +88,39 @@ var RESERVED_PARAMETERS = map[string]bool{
 	`title`: true,
 }
 
+// BUILTIN_VARIABLES are the variables that are always available in a class or define and hence
+// are not reported as top scope references when used without qualification.
+var BUILTIN_VARIABLES = map[string]bool{
+	`caller_module_name`: true,
+	`facts`:              true,
+	`module_name`:        true,
+	`name`:               true,
+	`server_facts`:       true,
+	`title`:              true,
+	`trusted`:            true,
+}
+
+// STATEMENT_CALL_ARITY are the allowed number of arguments, min and max, for functions that can be
+// called without parentheses. A max of -1 means that there is no upper limit.
+var STATEMENT_CALL_ARITY = map[string][2]int{
+	`break`:   {0, 0},
+	`contain`: {1, -1},
+	`include`: {1, -1},
+	`next`:    {0, 1},
+	`realize`: {1, -1},
+	`require`: {1, -1},
+	`return`:  {0, 1},
+	`tag`:     {1, -1},
+}
+
+// NO_VALUE_CALLS are the functions that never return and hence don't produce a value
+var NO_VALUE_CALLS = map[string]bool{
+	`break`:  true,
+	`fail`:   true,
+	`next`:   true,
+	`return`: true,
+}
+
 type basicChecker struct {
 	AbstractValidator
 }
@@ -88,23 +135,30 @@ type Checker interface {
 	check_AttributesOperation(e *parser.AttributesOperation)
 	check_BinaryExpression(e parser.BinaryExpression)
 	check_BlockExpression(e *parser.BlockExpression)
+	check_CallMethodExpression(e *parser.CallMethodExpression)
 	check_CallNamedFunctionExpression(e *parser.CallNamedFunctionExpression)
 	check_CapabilityMapping(e *parser.CapabilityMapping)
 	check_CaseExpression(e *parser.CaseExpression)
 	check_CaseOption(e *parser.CaseOption)
 	check_CollectExpression(e *parser.CollectExpression)
+	check_ConcatenatedString(e *parser.ConcatenatedString)
 	check_EppExpression(e *parser.EppExpression)
 	check_FunctionDefinition(e *parser.FunctionDefinition)
 	check_HostClassDefinition(e *parser.HostClassDefinition)
 	check_IfExpression(e *parser.IfExpression)
+	check_ImportExpression(e *parser.ImportExpression)
 	check_KeyedEntry(e *parser.KeyedEntry)
 	check_LambdaExpression(e *parser.LambdaExpression)
 	check_LiteralHash(e *parser.LiteralHash)
 	check_LiteralList(e *parser.LiteralList)
+	check_LiteralString(e *parser.LiteralString)
 	check_NamedAccessExpression(e *parser.NamedAccessExpression)
 	check_NamedDefinition(e parser.NamedDefinition)
 	check_NodeDefinition(e *parser.NodeDefinition)
 	check_Parameter(e *parser.Parameter)
+	check_PlanDefinition(e *parser.PlanDefinition)
+	check_Program(e *parser.Program)
+	check_QualifiedReference(e *parser.QualifiedReference)
 	check_QueryExpression(e parser.QueryExpression)
 	check_RelationshipExpression(e *parser.RelationshipExpression)
 	check_ReservedWord(e *parser.ReservedWord)
@@ -142,6 +196,8 @@ func Check(v Checker, e parser.Expression) {
 		v.check_AttributesOperation(e.(*parser.AttributesOperation))
 	case *parser.BlockExpression:
 		v.check_BlockExpression(e.(*parser.BlockExpression))
+	case *parser.CallMethodExpression:
+		v.check_CallMethodExpression(e.(*parser.CallMethodExpression))
 	case *parser.CallNamedFunctionExpression:
 		v.check_CallNamedFunctionExpression(e.(*parser.CallNamedFunctionExpression))
 	case *parser.CapabilityMapping:
@@ -152,6 +208,8 @@ func Check(v Checker, e parser.Expression) {
 		v.check_CaseOption(e.(*parser.CaseOption))
 	case *parser.CollectExpression:
 		v.check_CollectExpression(e.(*parser.CollectExpression))
+	case *parser.ConcatenatedString:
+		v.check_ConcatenatedString(e.(*parser.ConcatenatedString))
 	case *parser.EppExpression:
 		v.check_EppExpression(e.(*parser.EppExpression))
 	case *parser.FunctionDefinition:
@@ -160,6 +218,8 @@ func Check(v Checker, e parser.Expression) {
 		v.check_HostClassDefinition(e.(*parser.HostClassDefinition))
 	case *parser.IfExpression:
 		v.check_IfExpression(e.(*parser.IfExpression))
+	case *parser.ImportExpression:
+		v.check_ImportExpression(e.(*parser.ImportExpression))
 	case *parser.KeyedEntry:
 		v.check_KeyedEntry(e.(*parser.KeyedEntry))
 	case *parser.LambdaExpression:
@@ -168,12 +228,20 @@ func Check(v Checker, e parser.Expression) {
 		v.check_LiteralHash(e.(*parser.LiteralHash))
 	case *parser.LiteralList:
 		v.check_LiteralList(e.(*parser.LiteralList))
+	case *parser.LiteralString:
+		v.check_LiteralString(e.(*parser.LiteralString))
 	case *parser.NamedAccessExpression:
 		v.check_NamedAccessExpression(e.(*parser.NamedAccessExpression))
 	case *parser.NodeDefinition:
 		v.check_NodeDefinition(e.(*parser.NodeDefinition))
 	case *parser.Parameter:
 		v.check_Parameter(e.(*parser.Parameter))
+	case *parser.PlanDefinition:
+		v.check_PlanDefinition(e.(*parser.PlanDefinition))
+	case *parser.Program:
+		v.check_Program(e.(*parser.Program))
+	case *parser.QualifiedReference:
+		v.check_QualifiedReference(e.(*parser.QualifiedReference))
 	case *parser.RelationshipExpression:
 		v.check_RelationshipExpression(e.(*parser.RelationshipExpression))
 	case *parser.ReservedWord:
@@ -218,8 +286,19 @@ func (v *basicChecker) Validate(e parser.Expression) {
 func (v *basicChecker) initialize(strict Strictness) {
 	v.severities = make(map[issue.Code]issue.Severity, 5)
 	v.Demote(VALIDATE_FUTURE_RESERVED_WORD, issue.SEVERITY_DEPRECATION)
-	v.Demote(VALIDATE_DUPLICATE_KEY, issue.Severity(strict))
-	v.Demote(VALIDATE_IDEM_EXPRESSION_NOT_LAST, issue.Severity(strict))
+	v.Demote(VALIDATE_MISSING_DEFAULT, issue.SEVERITY_WARNING)
+	v.Demote(VALIDATE_DEFAULT_NOT_LAST, issue.SEVERITY_WARNING)
+	v.Demote(VALIDATE_DEFAULT_TYPE_MISMATCH, issue.SEVERITY_WARNING)
+	v.Demote(VALIDATE_CALL_NOT_RVALUE, issue.SEVERITY_WARNING)
+	v.Demote(VALIDATE_UNRESTRICTED_COLLECTOR_OVERRIDE, issue.SEVERITY_WARNING)
+	v.Demote(VALIDATE_NESTED_SELECTOR, issue.SEVERITY_WARNING)
+	v.Demote(VALIDATE_KEYWORD_CASE, issue.SEVERITY_WARNING)
+	for _, code := range LINT_ISSUES {
+		v.Demote(code, issue.SEVERITY_IGNORE)
+	}
+	ApplyStrictness(v, strict)
+	ApplyLanguageVersion(v, DEFAULT_LANGUAGE_VERSION)
+	ApplyStoreconfigs(v, true)
 }
 
 func (v *basicChecker) illegalWorkflowOperation(e parser.Expression) {
@@ -233,6 +312,10 @@ func (v *basicChecker) check_ActivityExpression(e *parser.ActivityExpression) {
 func (v *basicChecker) check_AssignmentExpression(e *parser.AssignmentExpression) {
 	switch e.Operator() {
 	case `=`:
+		if _, ok := e.Rhs().(*parser.LiteralHash); ok && isResourceReference(e.Lhs()) {
+			v.Accept(VALIDATE_OVERRIDE_WITH_HASH, e, issue.H{`reference`: e.Lhs()})
+			return
+		}
 		v.checkAssign(e.Lhs())
 	default:
 		v.Accept(VALIDATE_APPENDS_DELETES_NO_LONGER_SUPPORTED, e, issue.H{`operator`: e.Operator()})
@@ -240,6 +323,7 @@ func (v *basicChecker) check_AssignmentExpression(e *parser.AssignmentExpression
 }
 
 func (v *basicChecker) check_Application(e *parser.Application) {
+	v.Accept(VALIDATE_APP_ORCHESTRATION_DEPRECATED, e, issue.H{`expression`: e})
 }
 
 func (v *basicChecker) check_AttributeOperation(e *parser.AttributeOperation) {
@@ -252,6 +336,7 @@ func (v *basicChecker) check_AttributeOperation(e *parser.AttributeOperation) {
 			v.Accept(VALIDATE_ILLEGAL_ATTRIBUTE_APPEND, e, issue.H{`attr`: e.Name(), `expression`: p})
 		}
 	}
+	v.checkQuotedValue(e)
 }
 
 func (v *basicChecker) check_AttributesOperation(e *parser.AttributesOperation) {
@@ -269,6 +354,9 @@ func (v *basicChecker) check_BinaryExpression(e parser.BinaryExpression) {
 }
 
 func (v *basicChecker) check_BlockExpression(e *parser.BlockExpression) {
+	if p, ok := v.Container().(*parser.Program); ok {
+		v.checkLegacyFacts(p)
+	}
 	last := len(e.Statements()) - 1
 	for idx, statement := range e.Statements() {
 		if idx != last && v.isIdem(statement) {
@@ -278,9 +366,33 @@ func (v *basicChecker) check_BlockExpression(e *parser.BlockExpression) {
 	}
 }
 
+func (v *basicChecker) check_CallMethodExpression(e *parser.CallMethodExpression) {
+	if na, ok := e.Functor().(*parser.NamedAccessExpression); ok {
+		if fn, ok := na.Rhs().(*parser.QualifiedName); ok && fn.Name() == `each` {
+			v.checkResourceIteration(e, na.Lhs(), e.Lambda())
+		}
+	}
+}
+
 func (v *basicChecker) check_CallNamedFunctionExpression(e *parser.CallNamedFunctionExpression) {
-	switch e.Functor().(type) {
+	switch f := e.Functor().(type) {
 	case *parser.QualifiedName:
+		switch f.Name() {
+		case `import`:
+			v.Accept(VALIDATE_DISCONTINUED_IMPORT, e, issue.NO_ARGS)
+			return
+		case `create_resources`:
+			v.Accept(VALIDATE_CREATE_RESOURCES, e, issue.NO_ARGS)
+		case `each`:
+			if len(e.Arguments()) == 1 {
+				v.checkResourceIteration(e, e.Arguments()[0], e.Lambda())
+			}
+		case `inline_epp`:
+			if len(e.Arguments()) > 0 {
+				v.checkInlineEpp(e.Arguments()[0])
+			}
+		}
+		v.checkStatementCall(e, f.Name())
 		return
 	case *parser.QualifiedReference:
 		// Call to type
@@ -296,7 +408,31 @@ func (v *basicChecker) check_CallNamedFunctionExpression(e *parser.CallNamedFunc
 		issue.H{`expression`: e.Functor(), `feature`: `function name`, `container`: e})
 }
 
+// checkInlineEpp parses the given template argument of an inline_epp call in EPP mode when it's a literal
+// string or a heredoc without interpolation, and reports the syntax error of the template, if any
+func (v *basicChecker) checkInlineEpp(template parser.Expression) {
+	if heredoc, ok := template.(*parser.HeredocExpression); ok {
+		if heredoc.Template() != nil {
+			// A heredoc with the syntax epp that has already been parsed without errors
+			return
+		}
+		template = heredoc.Text()
+	}
+	ls, ok := template.(*parser.LiteralString)
+	if !ok {
+		return
+	}
+	_, err := parser.CreateParser(parser.PARSER_EPP_MODE).Parse(``, ls.StringValue(), false)
+	reported, ok := err.(issue.Reported)
+	if !ok || reported.Location() == nil {
+		return
+	}
+	message := ISSUE_LOCATION.ReplaceAllString(reported.Error(), ``)
+	v.Accept(VALIDATE_INLINE_EPP_ERROR, ls, issue.H{`line`: reported.Location().Line(), `column`: reported.Location().Pos(), `message`: message})
+}
+
 func (v *basicChecker) check_CapabilityMapping(e *parser.CapabilityMapping) {
+	v.Accept(VALIDATE_APP_ORCHESTRATION_DEPRECATED, e, issue.H{`expression`: e})
 	exprOk := false
 	switch e.Component().(type) {
 	case *parser.QualifiedReference:
@@ -329,6 +465,7 @@ func (v *basicChecker) check_CapabilityMapping(e *parser.CapabilityMapping) {
 func (v *basicChecker) check_CaseExpression(e *parser.CaseExpression) {
 	v.checkRValue(e.Test())
 	foundDefault := false
+	unique := make(map[interface{}]bool)
 	for _, option := range e.Options() {
 		co := option.(*parser.CaseOption)
 		for _, value := range co.Values() {
@@ -337,9 +474,14 @@ func (v *basicChecker) check_CaseExpression(e *parser.CaseExpression) {
 					v.Accept(VALIDATE_DUPLICATE_DEFAULT, value, issue.H{`container`: e})
 				}
 				foundDefault = true
+			} else {
+				v.checkDuplicateMatch(e, value, unique)
 			}
 		}
 	}
+	if !foundDefault {
+		v.Accept(VALIDATE_MISSING_DEFAULT, e, issue.H{`container`: e})
+	}
 }
 
 func (v *basicChecker) check_CaseOption(e *parser.CaseOption) {
@@ -349,12 +491,26 @@ func (v *basicChecker) check_CaseOption(e *parser.CaseOption) {
 }
 
 func (v *basicChecker) check_CollectExpression(e *parser.CollectExpression) {
-	if _, ok := e.ResourceType().(*parser.QualifiedReference); !ok {
+	typeName, ok := e.ResourceType().(*parser.QualifiedReference)
+	if !ok {
 		v.Accept(VALIDATE_ILLEGAL_EXPRESSION, e.ResourceType(),
 			issue.H{`expression`: e.ResourceType(), `feature`: `type name`, `container`: e})
+		return
+	}
+	form := parser.VIRTUAL
+	if _, ok := e.Query().(*parser.ExportedQuery); ok {
+		form = parser.EXPORTED
+		v.Accept(VALIDATE_STORECONFIGS_DISABLED, e.Query(), issue.H{`expression`: e.Query()})
+	}
+	if q, ok := e.Query().(parser.QueryExpression); ok && (q.Expr() == nil || q.Expr().IsNop()) && len(e.Operations()) > 0 {
+		v.Accept(VALIDATE_UNRESTRICTED_COLLECTOR_OVERRIDE, e, issue.H{`form`: form, `type`: typeName.Name()})
 	}
 }
 
+func (v *basicChecker) check_ConcatenatedString(e *parser.ConcatenatedString) {
+	v.checkStringLength(e)
+}
+
 func (v *basicChecker) check_EppExpression(e *parser.EppExpression) {
 	p := v.Container()
 	if lambda, ok := p.(*parser.LambdaExpression); ok {
@@ -367,6 +523,7 @@ func (v *basicChecker) check_FunctionDefinition(e *parser.FunctionDefinition) {
 	v.check_NamedDefinition(e)
 	v.checkCaptureLast(e, e.Parameters())
 	v.checkReturnType(e, e.ReturnType())
+	v.checkUnusedVariables(e, nil, e.Body())
 }
 
 func (v *basicChecker) check_HostClassDefinition(e *parser.HostClassDefinition) {
@@ -374,10 +531,20 @@ func (v *basicChecker) check_HostClassDefinition(e *parser.HostClassDefinition)
 	v.checkNoCapture(e, e.Parameters())
 	v.checkReservedParams(e, e.Parameters())
 	v.checkNoIdemLast(e, e.Body())
+	v.checkEmptyBody(e, e.Body())
+	v.checkTopScopeVariables(e, e.ParentClass() == ``)
+	v.checkClassInheritance(e)
 }
 
 func (v *basicChecker) check_IfExpression(e *parser.IfExpression) {
 	v.checkRValue(e.Test())
+	v.checkEmptyBranches(e, e.Then(), e.Else())
+	v.checkNegatedIf(e)
+	v.checkElseIf(e.Else())
+}
+
+func (v *basicChecker) check_ImportExpression(e *parser.ImportExpression) {
+	v.Accept(VALIDATE_DISCONTINUED_IMPORT, e, issue.NO_ARGS)
 }
 
 func (v *basicChecker) check_KeyedEntry(e *parser.KeyedEntry) {
@@ -388,6 +555,7 @@ func (v *basicChecker) check_KeyedEntry(e *parser.KeyedEntry) {
 func (v *basicChecker) check_LambdaExpression(e *parser.LambdaExpression) {
 	v.checkCaptureLast(e, e.Parameters())
 	v.checkReturnType(e, e.ReturnType())
+	v.checkUnusedVariables(e, e.Parameters(), e.Body())
 }
 
 func (v *basicChecker) check_LiteralHash(e *parser.LiteralHash) {
@@ -414,6 +582,38 @@ func (v *basicChecker) check_LiteralList(e *parser.LiteralList) {
 	}
 }
 
+func (v *basicChecker) check_LiteralString(e *parser.LiteralString) {
+	if _, ok := v.Container().(*parser.ConcatenatedString); ok {
+		return
+	}
+	v.checkStringLength(e)
+	if v.ignored(VALIDATE_SINGLE_QUOTED_INTERPOLATION) && v.ignored(VALIDATE_DOUBLE_QUOTED_STRING) {
+		return
+	}
+	src := e.String()
+	if len(src) < 2 || src[0] != src[len(src)-1] {
+		return
+	}
+	switch src[0] {
+	case '\'':
+		if v.ignored(VALIDATE_SINGLE_QUOTED_INTERPOLATION) {
+			return
+		}
+		if m := INTERPOLATION_LOOKALIKE.FindString(e.StringValue()); m != `` {
+			v.Accept(VALIDATE_SINGLE_QUOTED_INTERPOLATION, e, issue.H{`text`: m})
+		}
+	case '"':
+		if v.ignored(VALIDATE_DOUBLE_QUOTED_STRING) {
+			return
+		}
+		// A double quoted string with interpolation is a concatenated string, and one with a '$' that
+		// isn't interpolated must escape it
+		if !strings.ContainsAny(src, `\'`) {
+			v.Accept(VALIDATE_DOUBLE_QUOTED_STRING, e, issue.NO_ARGS)
+		}
+	}
+}
+
 func (v *basicChecker) check_NamedAccessExpression(e *parser.NamedAccessExpression) {
 	if _, ok := e.Rhs().(*parser.QualifiedName); !ok {
 		v.Accept(VALIDATE_ILLEGAL_EXPRESSION, e.Rhs(),
@@ -435,6 +635,10 @@ func (v *basicChecker) check_NodeDefinition(e *parser.NodeDefinition) {
 	v.checkHostname(e, e.HostMatches())
 	v.checkTop(e, v.Container())
 	v.checkNoIdemLast(e, e.Body())
+	v.checkLegacyFacts(e)
+	if e.Parent() != nil {
+		v.Accept(VALIDATE_NODE_INHERITANCE, e.Parent(), issue.NO_ARGS)
+	}
 }
 
 func (v *basicChecker) check_Parameter(e *parser.Parameter) {
@@ -445,11 +649,30 @@ func (v *basicChecker) check_Parameter(e *parser.Parameter) {
 	}
 	if e.Value() != nil {
 		v.checkIllegalAssignment(e.Value())
+		if e.Type() != nil && !analysis.MayBeInstance(e.Type(), e.Value()) {
+			v.Accept(VALIDATE_DEFAULT_TYPE_MISMATCH, e.Value(), issue.H{`param`: e.Name(), `actual`: analysis.InferType(e.Value()), `expected`: e.Type()})
+		}
+	}
+}
+
+func (v *basicChecker) check_PlanDefinition(e *parser.PlanDefinition) {
+	v.checkEmptyBody(e, e.Body())
+	v.checkUnusedVariables(e, nil, e.Body())
+}
+
+func (v *basicChecker) check_Program(e *parser.Program) {
+	v.checkHeader(e)
+	v.checkLineLength(e)
+}
+
+func (v *basicChecker) check_QualifiedReference(e *parser.QualifiedReference) {
+	if keyword, ok := parser.KeywordLookalike(e.Name()); ok {
+		v.Accept(VALIDATE_KEYWORD_CASE, e, issue.H{`name`: e.Name(), `keyword`: keyword})
 	}
 }
 
 func (v *basicChecker) check_QueryExpression(e parser.QueryExpression) {
-	if e.Expr() != nil {
+	if e.Expr() != nil && !e.Expr().IsNop() {
 		v.checkQuery(e.Expr())
 	}
 }
@@ -468,6 +691,9 @@ func (v *basicChecker) check_ReservedWord(e *parser.ReservedWord) {
 }
 
 func (v *basicChecker) check_ResourceBody(e *parser.ResourceBody) {
+	if len(e.Operations()) == 0 {
+		v.Accept(VALIDATE_EMPTY_RESOURCE_BODY, e, issue.NO_ARGS)
+	}
 	seenUnfolding := false
 	for _, ao := range e.Operations() {
 		if _, ok := ao.(*parser.AttributesOperation); ok {
@@ -478,12 +704,18 @@ func (v *basicChecker) check_ResourceBody(e *parser.ResourceBody) {
 			}
 		}
 	}
+	v.checkEnsureFirst(e.Operations())
+	v.checkArrowAlignment(e.Operations())
 }
 
 func (v *basicChecker) check_ResourceDefaultsExpression(e *parser.ResourceDefaultsExpression) {
 	if e.Form() != parser.REGULAR {
 		v.Accept(VALIDATE_NOT_VIRTUALIZABLE, e, issue.NO_ARGS)
 	}
+	if qr, ok := e.TypeRef().(*parser.QualifiedReference); ok && qr.DowncasedName() == `resource` {
+		v.Accept(VALIDATE_ILLEGAL_DEFAULTS_TYPE, e.TypeRef(), issue.H{`type`: qr})
+	}
+	v.checkArrowAlignment(e.Operations())
 }
 
 func (v *basicChecker) check_ResourceExpression(e *parser.ResourceExpression) {
@@ -495,6 +727,9 @@ func (v *basicChecker) check_ResourceExpression(e *parser.ResourceExpression) {
 		if typeName, ok := e.TypeName().(*parser.QualifiedName); ok && typeName.Name() == `class` {
 			v.Accept(VALIDATE_NOT_VIRTUALIZABLE, e, issue.NO_ARGS)
 		}
+		if e.Form() == parser.EXPORTED {
+			v.Accept(VALIDATE_STORECONFIGS_DISABLED, e, issue.H{`expression`: e})
+		}
 	}
 }
 
@@ -502,6 +737,8 @@ func (v *basicChecker) check_ResourceOverrideExpression(e *parser.ResourceOverri
 	if e.Form() != parser.REGULAR {
 		v.Accept(VALIDATE_NOT_VIRTUALIZABLE, e, issue.NO_ARGS)
 	}
+	v.checkOverrideReference(e.Resources())
+	v.checkArrowAlignment(e.Operations())
 }
 
 func (v *basicChecker) check_ResourceTypeDefinition(e *parser.ResourceTypeDefinition) {
@@ -509,6 +746,8 @@ func (v *basicChecker) check_ResourceTypeDefinition(e *parser.ResourceTypeDefini
 	v.checkNoCapture(e, e.Parameters())
 	v.checkReservedParams(e, e.Parameters())
 	v.checkNoIdemLast(e, e.Body())
+	v.checkEmptyBody(e, e.Body())
+	v.checkTopScopeVariables(e, true)
 }
 
 func (v *basicChecker) check_SelectorEntry(e *parser.SelectorEntry) {
@@ -517,20 +756,38 @@ func (v *basicChecker) check_SelectorEntry(e *parser.SelectorEntry) {
 
 func (v *basicChecker) check_SelectorExpression(e *parser.SelectorExpression) {
 	v.checkRValue(e.Lhs())
-	seenDefault := false
-	for _, entry := range e.Selectors() {
+	v.checkNestedSelector(e.Lhs())
+	var firstDefault *parser.SelectorEntry
+	unique := make(map[interface{}]bool)
+	entries := e.Selectors()
+	for _, entry := range entries {
 		se := entry.(*parser.SelectorEntry)
 		if _, ok := se.Matching().(*parser.LiteralDefault); ok {
-			if seenDefault {
+			if firstDefault != nil {
 				v.Accept(VALIDATE_DUPLICATE_DEFAULT, se, issue.H{`container`: e})
 			} else {
-				seenDefault = true
+				firstDefault = se
 			}
+		} else {
+			v.checkDuplicateMatch(e, se.Matching(), unique)
 		}
+		v.checkNestedSelector(se.Value())
+	}
+	if firstDefault != nil && firstDefault != entries[len(entries)-1] {
+		v.Accept(VALIDATE_DEFAULT_NOT_LAST, firstDefault, issue.H{`container`: e})
+	}
+}
+
+// checkNestedSelector reports the given expression if it is a selector that is not enclosed in
+// parentheses
+func (v *basicChecker) checkNestedSelector(e parser.Expression) {
+	if _, ok := e.(*parser.SelectorExpression); ok {
+		v.Accept(VALIDATE_NESTED_SELECTOR, e, issue.H{})
 	}
 }
 
 func (v *basicChecker) check_SiteDefinition(e *parser.SiteDefinition) {
+	v.Accept(VALIDATE_APP_ORCHESTRATION_DEPRECATED, e, issue.H{`expression`: e})
 }
 
 func (v *basicChecker) check_TypeAlias(e *parser.TypeAlias) {
@@ -581,10 +838,19 @@ func (v *basicChecker) check_TypeMapping(e *parser.TypeMapping) {
 
 func (v *basicChecker) check_UnaryExpression(e parser.UnaryExpression) {
 	v.checkRValue(e.Expr())
+	if _, ok := e.(*parser.NotExpression); ok {
+		if _, ok := unparenthesized(e.Expr()).(*parser.NotExpression); ok {
+			v.Accept(VALIDATE_DOUBLE_NEGATION, e, issue.H{`container`: e})
+		}
+	}
 }
 
 func (v *basicChecker) check_UnlessExpression(e *parser.UnlessExpression) {
 	v.checkRValue(e.Test())
+	v.checkEmptyBranches(e, e.Then(), e.Else())
+	if _, ok := e.Test().(*parser.NotExpression); ok {
+		v.Accept(VALIDATE_DOUBLE_NEGATION, e, issue.H{`container`: e})
+	}
 }
 
 // TODO: Add more validations here
@@ -622,6 +888,329 @@ func (v *basicChecker) checkCaptureLast(container parser.Expression, parameters
 	}
 }
 
+// checkClassInheritance reports a class that inherits a params class, i.e. a class with a name that ends
+// with ::params, and a class that inherits a class in another module
+func (v *basicChecker) checkClassInheritance(e *parser.HostClassDefinition) {
+	parent := strings.TrimPrefix(strings.ToLower(e.ParentClass()), `::`)
+	if parent == `` || parent == `default` {
+		return
+	}
+	name := strings.TrimPrefix(strings.ToLower(e.Name()), `::`)
+	if strings.HasSuffix(parent, `::params`) {
+		v.Accept(VALIDATE_PARAMS_CLASS_INHERITANCE, e, issue.H{`name`: name, `parent`: parent})
+	}
+	if module := strings.SplitN(name, `::`, 2)[0]; module != strings.SplitN(parent, `::`, 2)[0] {
+		v.Accept(VALIDATE_CROSS_MODULE_INHERITANCE, e, issue.H{`name`: name, `parent`: parent, `module`: module})
+	}
+}
+
+// checkEmptyBody reports the body of the given definition if it contains no statements
+func (v *basicChecker) checkEmptyBody(e parser.Expression, body parser.Expression) {
+	if isEmptyBlock(body) {
+		v.Accept(VALIDATE_EMPTY_BODY, e, issue.H{`container`: e})
+	}
+}
+
+// checkEmptyBranches reports the branches of the given 'if' or 'unless' expression that contain
+// no statements. An absent 'else' branch is not reported, and neither is an 'elsif' branch since
+// it is an 'if' expression in its own right.
+func (v *basicChecker) checkEmptyBranches(e, thenPart, elsePart parser.Expression) {
+	if isEmptyBlock(thenPart) {
+		v.Accept(VALIDATE_EMPTY_BRANCH, e, issue.H{`branch`: `then`, `container`: e})
+	}
+	if _, ok := elsePart.(*parser.BlockExpression); ok && isEmptyBlock(elsePart) {
+		v.Accept(VALIDATE_EMPTY_BRANCH, e, issue.H{`branch`: `else`, `container`: e})
+	}
+}
+
+// checkElseIf reports an 'if' statement that is the only statement of the given 'else' branch of an 'if'
+// statement, since it can be written as an 'elsif' branch
+func (v *basicChecker) checkElseIf(elsePart parser.Expression) {
+	if block, ok := elsePart.(*parser.BlockExpression); ok && len(block.Statements()) == 1 {
+		if nested, ok := block.Statements()[0].(*parser.IfExpression); ok {
+			v.Accept(VALIDATE_ELSE_IF, nested, issue.NO_ARGS)
+		}
+	}
+}
+
+// checkNegatedIf reports an 'if' statement with a negated condition that has neither an 'elsif' nor
+// an 'else' branch and isn't an 'elsif' branch itself, since it can be written as an 'unless' statement.
+// A condition that negates a negation is reported as a double negation instead.
+func (v *basicChecker) checkNegatedIf(e *parser.IfExpression) {
+	not, ok := e.Test().(*parser.NotExpression)
+	if !ok {
+		return
+	}
+	if _, ok = unparenthesized(not.Expr()).(*parser.NotExpression); ok {
+		return
+	}
+	if _, ok = e.Else().(*parser.Nop); !ok {
+		return
+	}
+	if c, ok := v.Container().(*parser.IfExpression); ok && c.Else() == e {
+		return
+	}
+	v.Accept(VALIDATE_NEGATED_IF, e, issue.NO_ARGS)
+}
+
+// unparenthesized returns the expression that the given expression encloses in parentheses, or the given
+// expression when it isn't parenthesized
+func unparenthesized(e parser.Expression) parser.Expression {
+	for {
+		pe, ok := e.(*parser.ParenthesizedExpression)
+		if !ok {
+			return e
+		}
+		e = pe.Expr()
+	}
+}
+
+// checkEnsureFirst reports an 'ensure' attribute that isn't the first of the given attribute operations
+func (v *basicChecker) checkEnsureFirst(operations []parser.Expression) {
+	for i, op := range operations {
+		if ao, ok := op.(*parser.AttributeOperation); ok && ao.Name() == `ensure` {
+			if i > 0 {
+				v.Accept(VALIDATE_ENSURE_NOT_FIRST, ao, issue.NO_ARGS)
+			}
+			return
+		}
+	}
+}
+
+// checkArrowAlignment reports the attribute operations whose operator isn't aligned one space after
+// the longest attribute name. Operations that share a line with other operations are not aligned
+// by convention so the check is skipped when a line has more than one of the given operations.
+func (v *basicChecker) checkArrowAlignment(operations []parser.Expression) {
+	if len(operations) < 2 {
+		return
+	}
+	type arrow struct {
+		op     parser.Expression
+		name   string
+		column int
+	}
+	arrows := make([]arrow, 0, len(operations))
+	lines := make(map[int]bool, len(operations))
+	expected := 0
+	for _, op := range operations {
+		var name, operator string
+		switch op := op.(type) {
+		case *parser.AttributeOperation:
+			name, operator = op.Name(), op.Operator()
+		case *parser.AttributesOperation:
+			name, operator = `*`, `=>`
+		default:
+			return
+		}
+		if lines[op.Line()] {
+			return
+		}
+		lines[op.Line()] = true
+
+		ix := strings.Index(op.String()[len(name):], operator)
+		if ix < 0 {
+			return
+		}
+		locator := op.Locator()
+		column := locator.PosOnLine(op.ByteOffset() - locator.HostOffset(0) + len(name) + ix)
+		if end := op.Pos() + utf8.RuneCountInString(name) + 1; end > expected {
+			expected = end
+		}
+		arrows = append(arrows, arrow{op, name, column})
+	}
+	for _, a := range arrows {
+		if a.column != expected {
+			v.Accept(VALIDATE_ARROW_ALIGNMENT, a.op, issue.H{`attr`: a.name, `column`: a.column, `expected`: expected})
+		}
+	}
+}
+
+// checkOverrideReference reports the given resources of an override if they are a type reference
+// that lacks a title
+func (v *basicChecker) checkOverrideReference(resources parser.Expression) {
+	if r, ok := resources.(*parser.AccessExpression); ok {
+		if qr, ok := r.Operand().(*parser.QualifiedReference); ok {
+			titleCount := len(r.Keys())
+			if qr.DowncasedName() == `resource` {
+				// First key is the type
+				titleCount--
+			}
+			if titleCount < 1 {
+				v.Accept(VALIDATE_ILLEGAL_OVERRIDE_REFERENCE, r, issue.H{`reference`: r})
+			}
+		}
+	}
+}
+
+// checkQuotedValue reports the value of the given attribute operation if it is a string that
+// contains a boolean or a number. Numbers with a leading zero, such as a file mode, are not
+// reported since they would change meaning if unquoted.
+func (v *basicChecker) checkQuotedValue(e *parser.AttributeOperation) {
+	str, ok := e.Value().(*parser.LiteralString)
+	if !ok {
+		return
+	}
+	switch value := str.StringValue(); {
+	case value == `true` || value == `false`:
+		v.Accept(VALIDATE_QUOTED_BOOLEAN, str, issue.H{`attr`: e.Name(), `value`: str.String(), `suggestion`: value})
+	case QUOTED_NUMBER.MatchString(value):
+		v.Accept(VALIDATE_QUOTED_NUMBER, str, issue.H{`attr`: e.Name(), `value`: str.String(), `suggestion`: value})
+	}
+}
+
+// checkTopScopeVariables reports references to top scope variables made from within the given
+// class or define. A reference such as $::x is always reported. An unqualified reference is
+// reported when checkUnqualified is true and the variable is neither a parameter, a builtin, nor
+// assigned anywhere in the definition. References to legacy facts are reported as such, see
+// checkLegacyFacts. Nested definitions are checked separately and are skipped.
+func (v *basicChecker) checkTopScopeVariables(e parser.Expression, checkUnqualified bool) {
+	local, refs := scopeVariables(e)
+	for _, ref := range refs {
+		name, ok := ref.Name()
+		if !ok || v.checkLegacyFact(ref, name, local) {
+			continue
+		}
+		if strings.HasPrefix(name, `::`) {
+			if top := name[2:]; !strings.Contains(top, `::`) && !BUILTIN_VARIABLES[top] {
+				v.Accept(VALIDATE_TOP_SCOPE_VARIABLE, ref, issue.H{`name`: top})
+			}
+		} else if checkUnqualified && !strings.Contains(name, `::`) && !local[name] && !BUILTIN_VARIABLES[name] {
+			v.Accept(VALIDATE_UNQUALIFIED_TOP_SCOPE_VARIABLE, ref, issue.H{`name`: name, `container`: e})
+		}
+	}
+}
+
+// checkLegacyFacts reports the references to legacy facts made from the given node definition or
+// program, i.e. from code that is evaluated in the top scope or in a node scope. Nested definitions are
+// checked separately and are skipped.
+func (v *basicChecker) checkLegacyFacts(e parser.Expression) {
+	local, refs := scopeVariables(e)
+	for _, ref := range refs {
+		if name, ok := ref.Name(); ok {
+			v.checkLegacyFact(ref, name, local)
+		}
+	}
+}
+
+// checkLegacyFact reports the given reference if it is a reference to a legacy fact, i.e. a top scope
+// variable such as $::osfamily or an unqualified variable such as $osfamily that isn't local, and
+// returns true if it was reported
+func (v *basicChecker) checkLegacyFact(ref *parser.VariableExpression, name string, local map[string]bool) bool {
+	if !strings.HasPrefix(name, `::`) && local[name] {
+		return false
+	}
+	if replacement, ok := LegacyFactReplacement(name); ok {
+		v.Accept(VALIDATE_LEGACY_FACT, ref, issue.H{`name`: name, `replacement`: replacement})
+		return true
+	}
+	return false
+}
+
+// scopeVariables returns the names of the parameters and the assigned variables of the given scope and
+// the variable references made from it. Nested definitions are skipped.
+func scopeVariables(e parser.Expression) (map[string]bool, []*parser.VariableExpression) {
+	local := make(map[string]bool)
+	refs := make([]*parser.VariableExpression, 0)
+	e.AllContents([]parser.Expression{}, func(path []parser.Expression, expr parser.Expression) {
+		for _, p := range path {
+			switch p.(type) {
+			case *parser.HostClassDefinition, *parser.ResourceTypeDefinition, *parser.FunctionDefinition, *parser.NodeDefinition, *parser.PlanDefinition, *parser.Program:
+				if p != e {
+					return
+				}
+			}
+		}
+		switch expr := expr.(type) {
+		case *parser.Parameter:
+			local[expr.Name()] = true
+		case *parser.AssignmentExpression:
+			for _, lhs := range expr.AssignedVariables() {
+				if name, ok := lhs.Name(); ok {
+					local[name] = true
+				}
+			}
+		case *parser.VariableExpression:
+			refs = append(refs, expr)
+		}
+	})
+	return local, refs
+}
+
+// checkUnusedVariables reports the given lambda parameters and the variables assigned in the given body
+// of a lambda, function, or plan that are never referenced in the body. Variables assigned in a nested
+// lambda are local to that lambda and are checked with it. Names that start with an underscore are
+// intentionally unused and are not reported.
+func (v *basicChecker) checkUnusedVariables(e parser.Expression, parameters []parser.Expression, body parser.Expression) {
+	if body == nil {
+		return
+	}
+	assigned := make(map[*parser.VariableExpression]bool)
+	local := make([]*parser.VariableExpression, 0)
+	used := make(map[string]bool)
+	body.AllContents([]parser.Expression{}, func(path []parser.Expression, expr parser.Expression) {
+		switch expr := expr.(type) {
+		case *parser.AssignmentExpression:
+			for _, lhs := range expr.AssignedVariables() {
+				assigned[lhs] = true
+				if !inLambda(path) {
+					local = append(local, lhs)
+				}
+			}
+		case *parser.VariableExpression:
+			if name, ok := expr.Name(); ok && !assigned[expr] {
+				used[name] = true
+			}
+		}
+	})
+
+	for _, p := range parameters {
+		if p, ok := p.(*parser.Parameter); ok && !used[p.Name()] && !strings.HasPrefix(p.Name(), `_`) {
+			v.Accept(VALIDATE_UNUSED_PARAMETER, p, issue.H{`name`: p.Name()})
+		}
+	}
+	reported := make(map[string]bool)
+	for _, lhs := range local {
+		if name, ok := lhs.Name(); ok && !used[name] && !reported[name] && !strings.HasPrefix(name, `_`) {
+			reported[name] = true
+			v.Accept(VALIDATE_UNUSED_VARIABLE, lhs, issue.H{`name`: name, `container`: e})
+		}
+	}
+}
+
+// inLambda returns true if the given path contains a lambda
+func inLambda(path []parser.Expression) bool {
+	for _, p := range path {
+		if _, ok := p.(*parser.LambdaExpression); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// checkDuplicateMatch reports the given match value of the given case or selector expression if it
+// is a literal value that is present in the given set of unique values. Otherwise the value is added
+// to the set.
+func (v *basicChecker) checkDuplicateMatch(e parser.Expression, value parser.Expression, unique map[interface{}]bool) {
+	literalValue, ok := literal.ToLiteral(value)
+	if !ok {
+		if re, isRegexp := value.(*parser.RegexpExpression); isRegexp {
+			literalValue, ok = re.ToPN().String(), true
+		}
+	}
+	if !ok {
+		return
+	}
+	switch value.(type) {
+	case *parser.LiteralList, *parser.LiteralHash:
+		literalValue = value.ToPN().String()
+	}
+	if unique[literalValue] {
+		v.Accept(VALIDATE_DUPLICATE_MATCH, value, issue.H{`value`: value.String(), `container`: e})
+	} else {
+		unique[literalValue] = true
+	}
+}
+
 func (v *basicChecker) checkFutureReservedWord(e parser.Expression, w string) {
 	if _, ok := FUTURE_RESERVED_WORDS[w]; ok {
 		v.Accept(VALIDATE_FUTURE_RESERVED_WORD, e, issue.H{`word`: w})
@@ -695,9 +1284,15 @@ func (v *basicChecker) checkParameterNameUniqueness(container parser.Expression,
 func (v *basicChecker) checkQuery(e parser.Expression) {
 	switch e.(type) {
 	case *parser.ComparisonExpression:
-		switch e.(*parser.ComparisonExpression).Operator() {
+		ce := e.(*parser.ComparisonExpression)
+		switch ce.Operator() {
 		case `==`, `!=`:
-			// OK
+			switch ce.Lhs().(type) {
+			case *parser.QualifiedName, *parser.LiteralString:
+				// OK
+			default:
+				v.Accept(VALIDATE_ILLEGAL_QUERY_ATTRIBUTE, ce.Lhs(), issue.H{`expression`: ce.Lhs()})
+			}
 		default:
 			v.Accept(VALIDATE_ILLEGAL_QUERY_EXPRESSION, e, issue.H{`expression`: e})
 		}
@@ -753,6 +1348,90 @@ func (v *basicChecker) checkRValue(e parser.Expression) {
 	}
 }
 
+// checkResourceIteration reports an iteration over a literal hash with a lambda that does nothing but
+// declare one resource per entry, using the key as the title and the value as the attributes, i.e. a
+// create_resources call in disguise
+func (v *basicChecker) checkResourceIteration(e parser.Expression, receiver parser.Expression, lambda parser.Expression) {
+	if typeName, ok := ResourceIteration(receiver, lambda); ok {
+		v.Accept(VALIDATE_RESOURCE_ITERATION, e, issue.H{`type`: typeName})
+	}
+}
+
+// ResourceIteration returns the name of the resource type if the given receiver of an each call is a
+// literal hash and the given lambda has a key and a value parameter and a body that consists of one
+// regular resource declaration with the key as its title and the value as its attributes, e.g.
+//
+//	{'a' => {'ensure' => 'file'}}.each |$k, $v| { file { $k: * => $v } }
+func ResourceIteration(receiver parser.Expression, lambda parser.Expression) (string, bool) {
+	if _, ok := receiver.(*parser.LiteralHash); !ok {
+		return ``, false
+	}
+	l, ok := lambda.(*parser.LambdaExpression)
+	if !ok || len(l.Parameters()) != 2 {
+		return ``, false
+	}
+	block, ok := l.Body().(*parser.BlockExpression)
+	if !ok || len(block.Statements()) != 1 {
+		return ``, false
+	}
+	re, ok := block.Statements()[0].(*parser.ResourceExpression)
+	if !ok || re.Form() != parser.REGULAR || len(re.Bodies()) != 1 {
+		return ``, false
+	}
+	tn, ok := re.TypeName().(*parser.QualifiedName)
+	if !ok || tn.Name() == `class` {
+		return ``, false
+	}
+	body := re.Bodies()[0].(*parser.ResourceBody)
+	if len(body.Operations()) != 1 {
+		return ``, false
+	}
+	ao, ok := body.Operations()[0].(*parser.AttributesOperation)
+	if !ok {
+		return ``, false
+	}
+	if !isVariable(body.Title(), l.Parameters()[0].(*parser.Parameter).Name()) ||
+		!isVariable(ao.Expr(), l.Parameters()[1].(*parser.Parameter).Name()) {
+		return ``, false
+	}
+	return tn.Name(), true
+}
+
+func isVariable(e parser.Expression, name string) bool {
+	if ve, ok := e.(*parser.VariableExpression); ok {
+		n, _ := ve.Name()
+		return n == name
+	}
+	return false
+}
+
+// checkStatementCall checks the number of arguments given to functions that can be called without
+// parentheses and that such calls aren't used as values when they don't produce one. A call that is the
+// value of a selector entry is not considered to be used as a value since the selector is what decides
+// if the call is made.
+func (v *basicChecker) checkStatementCall(e *parser.CallNamedFunctionExpression, name string) {
+	if arity, ok := STATEMENT_CALL_ARITY[name]; ok {
+		argc := len(e.Arguments())
+		var expected string
+		switch {
+		case argc < arity[0]:
+			expected = fmt.Sprintf(`at least %d argument`, arity[0])
+		case arity[1] == 0 && argc > 0:
+			expected = `no arguments`
+		case arity[1] >= 0 && argc > arity[1]:
+			expected = fmt.Sprintf(`at most %d argument`, arity[1])
+		}
+		if expected != `` {
+			v.Accept(VALIDATE_ILLEGAL_ARGUMENT_COUNT, e, issue.H{`name`: name, `expected`: expected, `actual`: argc})
+		}
+	}
+	if NO_VALUE_CALLS[name] && e.RvalRequired() {
+		if se, ok := v.Container().(*parser.SelectorEntry); !ok || se.Value() != e {
+			v.Accept(VALIDATE_CALL_NOT_RVALUE, e, issue.H{`name`: name})
+		}
+	}
+}
+
 func (v *basicChecker) checkTop(e parser.Expression, c parser.Expression) {
 	if c == nil {
 		return
@@ -846,6 +1525,15 @@ func isPatternWithReplacement(e parser.Expression) bool {
 	return false
 }
 
+// isResourceReference returns true if the given expression is a type reference with a title, e.g. File['/tmp']
+func isResourceReference(e parser.Expression) bool {
+	if ae, ok := e.(*parser.AccessExpression); ok && len(ae.Keys()) > 0 {
+		_, ok = ae.Operand().(*parser.QualifiedReference)
+		return ok
+	}
+	return false
+}
+
 func isTypeRef(e parser.Expression) bool {
 	n := e
 	if ae, ok := e.(*parser.AccessExpression); ok {
@@ -888,3 +1576,14 @@ func (v *basicChecker) idem_CaseOption(e *parser.CaseOption) bool {
 func (v *basicChecker) idem_IfExpression(e *parser.IfExpression) bool {
 	return v.isIdem(e.Test()) && v.isIdem(e.Then()) && v.isIdem(e.Else())
 }
+
+// isEmptyBlock returns true if the given expression is absent or a block without statements
+func isEmptyBlock(e parser.Expression) bool {
+	switch e := e.(type) {
+	case nil, *parser.Nop:
+		return true
+	case *parser.BlockExpression:
+		return len(e.Statements()) == 0
+	}
+	return false
+}
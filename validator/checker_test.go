@@ -1,6 +1,7 @@
 package validator
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/lyraproj/issue/issue"
@@ -9,6 +10,7 @@ import (
 
 var PuppetTasks = false
 var PuppetWorkflow = false
+var PuppetLint = false
 
 func TestVariableAssignValidation(t *testing.T) {
 	expectNoIssues(t, `$x = 'y'`)
@@ -85,6 +87,62 @@ func TestAttributesOpValidation(t *testing.T) {
 		VALIDATE_NOT_TOP_LEVEL, VALIDATE_NOT_RVALUE)
 }
 
+func TestResourceDefaultsAndOverrideValidation(t *testing.T) {
+	expectNoIssues(t,
+		issue.Unindent(`
+      File { mode => '0644' }
+      Resource['file'] { mode => '0644' }
+      File['/tmp/a'] { mode +> '0644' }
+      Resource['file', '/tmp/a'] { mode => '0644' }`))
+
+	expectIssues(t, `Package { ensure +> present }`, VALIDATE_ILLEGAL_ATTRIBUTE_APPEND)
+
+	expectIssues(t, `Resource { ensure => present }`, VALIDATE_ILLEGAL_DEFAULTS_TYPE)
+
+	expectIssues(t, `File[] { mode => '0644' }`, VALIDATE_ILLEGAL_OVERRIDE_REFERENCE)
+
+	expectIssues(t, `Resource['file'] { mode => '0644' }`)
+
+	expectIssues(t, `Resource[] { mode => '0644' }`, VALIDATE_ILLEGAL_OVERRIDE_REFERENCE)
+
+	expectIssues(t, `File['/tmp/a'] = { mode => '0644' }`, VALIDATE_OVERRIDE_WITH_HASH)
+
+	expectIssues(t, `$x['a'] = { mode => '0644' }`, VALIDATE_ILLEGAL_ASSIGNMENT_VIA_INDEX)
+
+	issues := parseAndValidate(t, `File['/tmp/a'] = { mode => '0644' }`)
+	if len(issues) != 1 {
+		t.Fatalf("expected 1 issue, got %d", len(issues))
+	}
+	if issues[0].Error() != `Attributes of File['/tmp/a'] can not be set by assigning a Hash. Use a resource override such as File['/tmp/a'] { attribute => value } (line: 1, column: 1)` {
+		t.Errorf("unexpected issue %s", issues[0])
+	}
+}
+
+func TestCollectorValidation(t *testing.T) {
+	expectNoIssues(t,
+		issue.Unindent(`
+      File <| tag == 'a' and (title != '/tmp' or 'mode' == '0644') |> { owner => 'root' }
+      File <<| |>>
+      File <| |>`))
+
+	expectIssues(t, `File <| mode =~ /^06/ |>`, VALIDATE_ILLEGAL_QUERY_EXPRESSION)
+
+	expectIssues(t, `File <| mode > 1 |>`, VALIDATE_ILLEGAL_QUERY_EXPRESSION)
+
+	expectIssues(t, `File <| $mode == 1 |>`, VALIDATE_ILLEGAL_QUERY_ATTRIBUTE)
+
+	expectIssues(t, `File <| |> { owner => 'root' }`, VALIDATE_UNRESTRICTED_COLLECTOR_OVERRIDE)
+
+	issues := parseAndValidate(t, `File <<| |>> { owner => 'root' }`)
+	if len(issues) != 1 {
+		t.Fatalf("expected 1 issue, got %d", len(issues))
+	}
+	if issues[0].Severity() != issue.SEVERITY_WARNING || issues[0].Error() !=
+		`This collector has an empty query, so its attribute overrides apply to every exported File resource (line: 1, column: 1)` {
+		t.Errorf("unexpected issue %s", issues[0])
+	}
+}
+
 func TestCallNamedFunctionValidation(t *testing.T) {
 	expectNoIssues(t,
 		issue.Unindent(`
@@ -268,6 +326,47 @@ func TestCaseExpressionValidation(t *testing.T) {
         default: { false }
       }`),
 		VALIDATE_NOT_TOP_LEVEL, VALIDATE_NOT_RVALUE)
+
+	expectIssues(t,
+		issue.Unindent(`
+      case $x {
+        'a': { true }
+      }`),
+		VALIDATE_MISSING_DEFAULT)
+
+	expectIssues(t,
+		issue.Unindent(`
+      case $x {
+        'a', /b/, [1]: { true }
+        "a", $y, $y: { true }
+        /b/, [1], 'c': { true }
+        default: { false }
+      }`),
+		VALIDATE_DUPLICATE_MATCH, VALIDATE_DUPLICATE_MATCH, VALIDATE_DUPLICATE_MATCH)
+
+	// A hash with a list as a key is not a literal value
+	expectNoIssues(t,
+		issue.Unindent(`
+      case $x {
+        {[1] => 2}: { true }
+        {[1] => 2}: { true }
+        default: { false }
+      }`))
+
+	issues := parseAndValidate(t, issue.Unindent(`
+      case $x {
+        'a': { true }
+        'a': { false }
+      }`))
+	if len(issues) != 2 {
+		t.Fatalf("expected 2 issues, got %d", len(issues))
+	}
+	if issues[0].Code() != VALIDATE_DUPLICATE_MATCH || issues[0].Location().Line() != 3 || issues[0].Error() != `The value ''a'' is matched more than once in this 'case' statement (line: 3, column: 3)` {
+		t.Errorf("unexpected issue %s", issues[0])
+	}
+	if issues[1].Code() != VALIDATE_MISSING_DEFAULT || issues[1].Severity() != issue.SEVERITY_WARNING {
+		t.Errorf("unexpected issue %s", issues[1])
+	}
 }
 
 func TestCollectValidation(t *testing.T) {
@@ -353,6 +452,28 @@ func TestEppValidation(t *testing.T) {
 		VALIDATE_CAPTURES_REST_NOT_SUPPORTED)
 }
 
+func TestInlineEppValidation(t *testing.T) {
+	expectNoIssues(t,
+		issue.Unindent(`
+      $x = inline_epp('<%- | $a | -%>This is <%= $a %>', { 'a' => 1 })
+      $y = inline_epp("<%= ${z}")
+      `))
+
+	issues := parseAndValidate(t, issue.Unindent(`
+    $x = inline_epp(@(END))
+      <%- | $a | -%>
+      This is <%= $a ) %>
+      | END
+    `))
+	if len(issues) != 1 || issues[0].Code() != VALIDATE_INLINE_EPP_ERROR {
+		t.Fatalf("expected %s, got %v", VALIDATE_INLINE_EPP_ERROR, issues)
+	}
+	expected := `The inline EPP template has an error at line 2, column 16 of the template: unexpected token ')'`
+	if !strings.HasPrefix(issues[0].Error(), expected+` (line: 2,`) || issues[0].Location().Line() != 2 {
+		t.Errorf("expected '%s', got '%s'", expected, issues[0].Error())
+	}
+}
+
 func TestFunctionDefinitionValidation(t *testing.T) {
 	expectNoIssues(t,
 		issue.Unindent(`
@@ -483,6 +604,13 @@ func TestLiteralHashValidation(t *testing.T) {
       }`),
 		VALIDATE_DUPLICATE_KEY)
 
+	expectNoIssues(t,
+		issue.Unindent(`
+      $x = {
+        {[1] => 2} => 'one',
+        {[1] => 2} => 'two'
+      }`))
+
 	expectIssues(t,
 		issue.Unindent(`
       $x = {
@@ -677,7 +805,115 @@ func TestSelectorExpressionValidation(t *testing.T) {
         default             => role::generic,
         'RedHat'            => role::redhat,
         default             => role::generic,
-      }`), VALIDATE_DUPLICATE_DEFAULT)
+      }`), VALIDATE_DUPLICATE_DEFAULT, VALIDATE_DEFAULT_NOT_LAST)
+
+	expectIssues(t,
+		issue.Unindent(`
+      $role = $facts['os']['name'] ? {
+        'Solaris'           => role::solaris,
+        'RedHat'            => role::redhat,
+        /^(Debian|Ubuntu)$/ => role::debian,
+        "RedHat"            => role::redhat,
+        /^(Debian|Ubuntu)$/ => role::debian,
+        default             => role::generic,
+      }`), VALIDATE_DUPLICATE_MATCH, VALIDATE_DUPLICATE_MATCH)
+
+	expectIssues(t,
+		issue.Unindent(`
+      $role = $facts['os']['name'] ? {
+        'Solaris' => $facts['os']['release']['major'] ? {
+          '11'    => role::solaris11,
+          default => role::solaris,
+        },
+        default   => role::generic,
+      }`), VALIDATE_NESTED_SELECTOR)
+
+	expectNoIssues(t,
+		issue.Unindent(`
+      $role = $facts['os']['name'] ? {
+        'Solaris' => ($facts['os']['release']['major'] ? {
+          '11'    => role::solaris11,
+          default => role::solaris,
+        }),
+        default   => role::generic,
+      }`))
+
+	issues := parseAndValidate(t,
+		issue.Unindent(`
+      $role = $facts['os']['name'] ? {
+        default  => role::generic,
+        'RedHat' => role::redhat,
+      }`))
+	if len(issues) != 1 {
+		t.Fatalf("expected 1 issue, got %d", len(issues))
+	}
+	if issues[0].Code() != VALIDATE_DEFAULT_NOT_LAST || issues[0].Severity() != issue.SEVERITY_WARNING || issues[0].Location().Line() != 2 {
+		t.Errorf("unexpected issue %s", issues[0])
+	}
+}
+
+func TestParameterDefaultType(t *testing.T) {
+	expectNoIssues(t,
+		issue.Unindent(`
+      class foo(
+        Integer[1, 10] $a = 5,
+        Optional[String] $b = undef,
+        Array[Integer] $c = [],
+        Hash[String, Variant[Integer, Boolean]] $d = { 'x' => 1, 'y' => true },
+        Float $e = $x,
+        MyAlias $f = 'foo',
+        Enum['a', 'b'] $g = 'a',
+        Stdlib::Port $h = 80,
+      ) {}`))
+
+	expectIssues(t, `class foo(Integer $x = 'foo') {}`, VALIDATE_DEFAULT_TYPE_MISMATCH)
+
+	expectIssues(t, `define foo(Integer[1, 10] $x = 11) {}`, VALIDATE_DEFAULT_TYPE_MISMATCH)
+
+	expectIssues(t, `function foo(String $x = undef) {}`, VALIDATE_DEFAULT_TYPE_MISMATCH)
+
+	expectIssues(t, `class foo(Array[String, 1] $x = []) {}`, VALIDATE_DEFAULT_TYPE_MISMATCH)
+
+	expectIssues(t, `class foo(Hash[String, Integer] $x = { 'a' => 'b' }) {}`, VALIDATE_DEFAULT_TYPE_MISMATCH)
+
+	issues := parseAndValidate(t, `class foo(Boolean $x = 'true') {}`)
+	if len(issues) != 1 {
+		t.Fatalf("expected 1 issue, got %d", len(issues))
+	}
+	if issues[0].Severity() != issue.SEVERITY_WARNING || issues[0].Error() !=
+		`The default value of parameter $x has type String, which can never match the declared type Boolean (line: 1, column: 24)` {
+		t.Errorf("unexpected issue %s", issues[0])
+	}
+}
+
+func TestStatementCalls(t *testing.T) {
+	expectNoIssues(t,
+		issue.Unindent(`
+      include foo, bar
+      contain(foo)
+      tag 'a'
+      fail('x')
+      $x = $y ? { 'a' => 1, default => fail('unsupported') }
+      [1].each |$x| { if $x == 1 { next() } else { break() } }
+      function foo() { return(1) }`))
+
+	expectIssues(t, `include()`, VALIDATE_ILLEGAL_ARGUMENT_COUNT)
+
+	expectIssues(t, `[1].each |$x| { next(1, 2) }`, VALIDATE_ILLEGAL_ARGUMENT_COUNT)
+
+	expectIssues(t, `[1].each |$x| { break(1) }`, VALIDATE_ILLEGAL_ARGUMENT_COUNT)
+
+	expectIssues(t, `$x = fail('x')`, VALIDATE_CALL_NOT_RVALUE)
+
+	expectIssues(t, `notice(return(1))`, VALIDATE_CALL_NOT_RVALUE)
+
+	issues := parseAndValidate(t, `notice('a'); include()`)
+	if len(issues) != 1 {
+		t.Fatalf("expected 1 issue, got %d", len(issues))
+	}
+	if issues[0].Error() != `'include' expects at least 1 argument, got 0 (line: 1, column: 14)` {
+		t.Errorf("unexpected issue %s", issues[0])
+	}
 }
 
 func TestTypeAliasValidation(t *testing.T) {
@@ -772,27 +1008,30 @@ func parseAndValidate(t *testing.T, str string, parserOptions ...parser.Option)
 		parserOptions = append([]parser.Option{parser.PARSER_WORKFLOW_ENABLED}, parserOptions...)
 	}
 
+	expr := parse(t, str, parserOptions...)
+	if expr == nil {
+		return nil
+	}
 	var v Validator
-	if PuppetTasks || PuppetWorkflow {
-		if expr := parse(t, str, parserOptions...); expr != nil {
-			if PuppetWorkflow {
-				v = ValidateWorkflow(expr)
-			} else {
-				v = ValidateTasks(expr)
-			}
-			return v.Issues()
-		}
-	} else if expr := parse(t, str, parserOptions...); expr != nil {
-		v = ValidatePuppet(expr, STRICT_ERROR)
-		return v.Issues()
+	switch {
+	case PuppetWorkflow:
+		v = NewWorkflowChecker()
+	case PuppetTasks:
+		v = NewTasksChecker()
+	default:
+		v = NewChecker(STRICT_ERROR)
 	}
-	return nil
+	if PuppetLint {
+		EnableLint(v)
+	}
+	Validate(v, expr)
+	return v.Issues()
 }
 
 func parse(t *testing.T, str string, parserOptions ...parser.Option) *parser.Program {
 	expr, err := parser.CreateParser(parserOptions...).Parse(``, str, false)
 	if err != nil {
-		t.Errorf(err.Error())
+		t.Error(err.Error())
 		return nil
 	}
 	block, ok := expr.(*parser.Program)
@@ -802,3 +1041,15 @@ func parse(t *testing.T, str string, parserOptions ...parser.Option) *parser.Pro
 	}
 	return block
 }
+
+func TestKeywordCaseValidation(t *testing.T) {
+	expectIssues(t, `$x = If`, VALIDATE_KEYWORD_CASE)
+	expectIssues(t, `$x = CLASS`, VALIDATE_KEYWORD_CASE)
+	expectNoIssues(t, `$x = Class['a']`)
+	expectNoIssues(t, `$x = Iffy`)
+
+	issues := parseAndValidate(t, `$x = Else`)
+	if len(issues) != 1 || issues[0].Severity() != issue.SEVERITY_WARNING {
+		t.Errorf(`expected a warning`)
+	}
+}
@@ -0,0 +1,90 @@
+package validator
+
+import (
+	"strings"
+)
+
+// LEGACY_FACTS maps the names of the legacy facts that Facter no longer provides as top scope variables
+// to the dot separated path of the equivalent value in the structured facts hash
+var LEGACY_FACTS = map[string]string{
+	`architecture`:                `os.architecture`,
+	`bios_release_date`:           `dmi.bios.release_date`,
+	`bios_vendor`:                 `dmi.bios.vendor`,
+	`bios_version`:                `dmi.bios.version`,
+	`boardmanufacturer`:           `dmi.board.manufacturer`,
+	`boardproductname`:            `dmi.board.product`,
+	`boardserialnumber`:           `dmi.board.serial_number`,
+	`domain`:                      `networking.domain`,
+	`fqdn`:                        `networking.fqdn`,
+	`gid`:                         `identity.group`,
+	`hardwaremodel`:               `os.hardware`,
+	`hostname`:                    `networking.hostname`,
+	`id`:                          `identity.user`,
+	`ipaddress`:                   `networking.ip`,
+	`ipaddress6`:                  `networking.ip6`,
+	`lsbdistcodename`:             `os.distro.codename`,
+	`lsbdistdescription`:          `os.distro.description`,
+	`lsbdistid`:                   `os.distro.id`,
+	`lsbdistrelease`:              `os.distro.release.full`,
+	`lsbmajdistrelease`:           `os.distro.release.major`,
+	`lsbminordistrelease`:         `os.distro.release.minor`,
+	`macaddress`:                  `networking.mac`,
+	`macosx_buildversion`:         `os.macosx.build`,
+	`macosx_productname`:          `os.macosx.product`,
+	`macosx_productversion`:       `os.macosx.version.full`,
+	`macosx_productversion_major`: `os.macosx.version.major`,
+	`macosx_productversion_minor`: `os.macosx.version.minor`,
+	`manufacturer`:                `dmi.manufacturer`,
+	`memoryfree`:                  `memory.system.available`,
+	`memorysize`:                  `memory.system.total`,
+	`netmask`:                     `networking.netmask`,
+	`netmask6`:                    `networking.netmask6`,
+	`network`:                     `networking.network`,
+	`network6`:                    `networking.network6`,
+	`operatingsystem`:             `os.name`,
+	`operatingsystemmajrelease`:   `os.release.major`,
+	`operatingsystemrelease`:      `os.release.full`,
+	`osfamily`:                    `os.family`,
+	`physicalprocessorcount`:      `processors.physicalcount`,
+	`processorcount`:              `processors.count`,
+	`productname`:                 `dmi.product.name`,
+	`rubyplatform`:                `ruby.platform`,
+	`rubysitedir`:                 `ruby.sitedir`,
+	`rubyversion`:                 `ruby.version`,
+	`selinux`:                     `os.selinux.enabled`,
+	`selinux_config_mode`:         `os.selinux.config_mode`,
+	`selinux_current_mode`:        `os.selinux.current_mode`,
+	`selinux_enforced`:            `os.selinux.enforced`,
+	`selinux_policyversion`:       `os.selinux.policy_version`,
+	`serialnumber`:                `dmi.product.serial_number`,
+	`sshdsakey`:                   `ssh.dsa.key`,
+	`sshecdsakey`:                 `ssh.ecdsa.key`,
+	`sshed25519key`:               `ssh.ed25519.key`,
+	`sshrsakey`:                   `ssh.rsa.key`,
+	`swapfree`:                    `memory.swap.available`,
+	`swapsize`:                    `memory.swap.total`,
+	`system32`:                    `os.windows.system32`,
+	`uptime`:                      `system_uptime.uptime`,
+	`uptime_days`:                 `system_uptime.days`,
+	`uptime_hours`:                `system_uptime.hours`,
+	`uptime_seconds`:              `system_uptime.seconds`,
+	`uuid`:                        `dmi.product.uuid`,
+}
+
+// LegacyFactReplacement returns the access to the structured facts hash that replaces the given legacy
+// fact variable, e.g. $facts['os']['family'] for $osfamily or $::osfamily. The last return value is
+// false when the name isn't the name of a legacy fact.
+func LegacyFactReplacement(name string) (string, bool) {
+	path, ok := LEGACY_FACTS[strings.TrimPrefix(name, `::`)]
+	if !ok {
+		return ``, false
+	}
+	b := strings.Builder{}
+	b.WriteString(`$facts`)
+	for _, key := range strings.Split(path, `.`) {
+		b.WriteString(`['`)
+		b.WriteString(key)
+		b.WriteString(`']`)
+	}
+	return b.String(), true
+}
@@ -0,0 +1,50 @@
+package validator
+
+import (
+	"regexp"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+// RequireHeader makes the given validator warn about manifests whose leading comments don't match the
+// given pattern, e.g. a license header:
+//
+//	RequireHeader(v, regexp.MustCompile(`\A# Copyright \d{4} Example Inc\.\n# SPDX-License-Identifier: Apache-2\.0`))
+//
+// The pattern is matched against the text that LeadingComments returns, so a pattern that isn't anchored
+// matches a header anywhere among the leading comments. EPP templates are not checked. The issue is a
+// lint issue, so it can be turned off again by demoting VALIDATE_MISSING_HEADER to issue.SEVERITY_IGNORE.
+func RequireHeader(v Validator, pattern *regexp.Regexp) {
+	v.setHeader(pattern)
+	v.Demote(VALIDATE_MISSING_HEADER, issue.SEVERITY_WARNING)
+}
+
+// LeadingComments returns the source text of the given program from its start to the end of the last
+// comment that precedes the first token, or an empty string when the program doesn't start with a comment
+func LeadingComments(program *parser.Program) string {
+	locator := program.Locator()
+	source := locator.String()
+	l := parser.NewLexer(locator.File(), source, parser.LEXER_EMIT_COMMENTS, parser.LEXER_ERROR_RECOVERY)
+	end := 0
+	for l.NextToken() == parser.TOKEN_COMMENT {
+		end = l.TokenStartPos() + len(l.TokenString())
+	}
+	return source[:end]
+}
+
+func (v *AbstractValidator) setHeader(pattern *regexp.Regexp) {
+	v.header = pattern
+}
+
+func (v *basicChecker) checkHeader(e *parser.Program) {
+	if v.header == nil {
+		return
+	}
+	if _, epp := e.Body().(*parser.LambdaExpression); epp {
+		return
+	}
+	if !v.header.MatchString(LeadingComments(e)) {
+		v.Accept(VALIDATE_MISSING_HEADER, e, issue.NO_ARGS)
+	}
+}
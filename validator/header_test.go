@@ -0,0 +1,74 @@
+package validator
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+func TestRequireHeader(t *testing.T) {
+	header := regexp.MustCompile(`\A# Copyright \d{4} Example Inc\.\n# SPDX-License-Identifier: Apache-2\.0\n`)
+	validate := func(source string) []issue.Reported {
+		v := NewChecker(STRICT_ERROR)
+		RequireHeader(v, header)
+		Validate(v, parse(t, source))
+		return v.Issues()
+	}
+
+	good := issue.Unindent(`
+    # Copyright 2026 Example Inc.
+    # SPDX-License-Identifier: Apache-2.0
+
+    # A class
+    class a {}
+    `)
+	if issues := validate(good); len(issues) != 0 {
+		t.Errorf("unexpected issue %s", issues[0])
+	}
+
+	issues := validate("# A class\nclass a {}\n")
+	if len(issues) != 1 {
+		t.Fatalf("expected 1 issue, got %d", len(issues))
+	}
+	if issues[0].Code() != VALIDATE_MISSING_HEADER || issues[0].Severity() != issue.SEVERITY_WARNING ||
+		issues[0].Error() != `The manifest does not start with the required header comment (line: 1, column: 1)` {
+		t.Errorf("unexpected issue %s", issues[0])
+	}
+
+	if issues := validate(`class a {}`); len(issues) != 1 {
+		t.Errorf("expected 1 issue, got %d", len(issues))
+	}
+
+	expectNoIssues(t, `class a {}`)
+
+	v := NewChecker(STRICT_ERROR)
+	RequireHeader(v, header)
+	v.Demote(VALIDATE_MISSING_HEADER, issue.SEVERITY_IGNORE)
+	Validate(v, parse(t, `class a {}`))
+	if len(v.Issues()) != 0 {
+		t.Errorf("unexpected issue %s", v.Issues()[0])
+	}
+
+	template, err := parser.CreateParser(parser.PARSER_EPP_MODE).Parse(``, `<%= 'x' %>`, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v = NewChecker(STRICT_ERROR)
+	RequireHeader(v, header)
+	Validate(v, template)
+	if len(v.Issues()) != 0 {
+		t.Errorf("unexpected issue %s", v.Issues()[0])
+	}
+}
+
+func TestLeadingComments(t *testing.T) {
+	source := "# one\n/* two\n */\n# three\n$x = 1 # four\n"
+	if actual := LeadingComments(parse(t, source)); actual != "# one\n/* two\n */\n# three" {
+		t.Errorf("unexpected leading comments %q", actual)
+	}
+	if actual := LeadingComments(parse(t, `$x = 1 # one`)); actual != `` {
+		t.Errorf("unexpected leading comments %q", actual)
+	}
+}
@@ -6,47 +6,100 @@ import (
 
 const (
 	VALIDATE_APPENDS_DELETES_NO_LONGER_SUPPORTED = `VALIDATE_APPENDS_DELETES_NO_LONGER_SUPPORTED`
+	VALIDATE_APP_ORCHESTRATION_DEPRECATED        = `VALIDATE_APP_ORCHESTRATION_DEPRECATED`
+	VALIDATE_ARROW_ALIGNMENT                     = `VALIDATE_ARROW_ALIGNMENT`
+	VALIDATE_CALL_NOT_RVALUE                     = `VALIDATE_CALL_NOT_RVALUE`
 	VALIDATE_CAPTURES_REST_NOT_LAST              = `VALIDATE_CAPTURES_REST_NOT_LAST`
 	VALIDATE_CAPTURES_REST_NOT_SUPPORTED         = `VALIDATE_CAPTURES_REST_NOT_SUPPORTED`
 	VALIDATE_CATALOG_OPERATION_NOT_SUPPORTED     = `VALIDATE_CATALOG_OPERATION_NOT_SUPPORTED`
+	VALIDATE_CREATE_RESOURCES                    = `VALIDATE_CREATE_RESOURCES`
+	VALIDATE_CROSS_MODULE_INHERITANCE            = `VALIDATE_CROSS_MODULE_INHERITANCE`
 	VALIDATE_CROSS_SCOPE_ASSIGNMENT              = `VALIDATE_CROSS_SCOPE_ASSIGNMENT`
+	VALIDATE_DEFAULT_NOT_LAST                    = `VALIDATE_DEFAULT_NOT_LAST`
+	VALIDATE_DEFAULT_TYPE_MISMATCH               = `VALIDATE_DEFAULT_TYPE_MISMATCH`
+	VALIDATE_DISCONTINUED_IMPORT                 = `VALIDATE_DISCONTINUED_IMPORT`
+	VALIDATE_DOUBLE_NEGATION                     = `VALIDATE_DOUBLE_NEGATION`
+	VALIDATE_DOUBLE_QUOTED_STRING                = `VALIDATE_DOUBLE_QUOTED_STRING`
 	VALIDATE_DUPLICATE_DEFAULT                   = `VALIDATE_DUPLICATE_DEFAULT`
 	VALIDATE_DUPLICATE_KEY                       = `VALIDATE_DUPLICATE_KEY`
+	VALIDATE_DUPLICATE_MATCH                     = `VALIDATE_DUPLICATE_MATCH`
 	VALIDATE_DUPLICATE_PARAMETER                 = `VALIDATE_DUPLICATE_PARAMETER`
+	VALIDATE_ELSE_IF                             = `VALIDATE_ELSE_IF`
+	VALIDATE_EMPTY_BODY                          = `VALIDATE_EMPTY_BODY`
+	VALIDATE_EMPTY_BRANCH                        = `VALIDATE_EMPTY_BRANCH`
+	VALIDATE_EMPTY_RESOURCE_BODY                 = `VALIDATE_EMPTY_RESOURCE_BODY`
+	VALIDATE_ENSURE_NOT_FIRST                    = `VALIDATE_ENSURE_NOT_FIRST`
 	VALIDATE_FUTURE_RESERVED_WORD                = `VALIDATE_FUTURE_RESERVED_WORD`
 	VALIDATE_IDEM_EXPRESSION_NOT_LAST            = `VALIDATE_IDEM_EXPRESSION_NOT_LAST`
 	VALIDATE_IDEM_NOT_ALLOWED_LAST               = `VALIDATE_IDEM_NOT_ALLOWED_LAST`
+	VALIDATE_ILLEGAL_ARGUMENT_COUNT              = `VALIDATE_ILLEGAL_ARGUMENT_COUNT`
 	VALIDATE_ILLEGAL_ASSIGNMENT_CONTEXT          = `VALIDATE_ILLEGAL_ASSIGNMENT_CONTEXT`
 	VALIDATE_ILLEGAL_ASSIGNMENT_VIA_INDEX        = `VALIDATE_ILLEGAL_ASSIGNMENT_VIA_INDEX`
 	VALIDATE_ILLEGAL_ATTRIBUTE_APPEND            = `VALIDATE_ILLEGAL_ATTRIBUTE_APPEND`
 	VALIDATE_ILLEGAL_CLASSREF                    = `VALIDATE_ILLEGAL_CLASSREF`
+	VALIDATE_ILLEGAL_DEFAULTS_TYPE               = `VALIDATE_ILLEGAL_DEFAULTS_TYPE`
 	VALIDATE_ILLEGAL_DEFINITION_NAME             = `VALIDATE_ILLEGAL_DEFINITION_NAME`
 	VALIDATE_ILLEGAL_EXPRESSION                  = `VALIDATE_ILLEGAL_EXPRESSION`
 	VALIDATE_ILLEGAL_HOSTNAME_CHARS              = `VALIDATE_ILLEGAL_HOSTNAME_CHARS`
 	VALIDATE_ILLEGAL_HOSTNAME_INTERPOLATION      = `VALIDATE_ILLEGAL_HOSTNAME_INTERPOLATION`
 	VALIDATE_ILLEGAL_NUMERIC_ASSIGNMENT          = `VALIDATE_ILLEGAL_NUMERIC_ASSIGNMENT`
 	VALIDATE_ILLEGAL_NUMERIC_PARAMETER           = `VALIDATE_ILLEGAL_NUMERIC_PARAMETER`
+	VALIDATE_ILLEGAL_OVERRIDE_REFERENCE          = `VALIDATE_ILLEGAL_OVERRIDE_REFERENCE`
 	VALIDATE_ILLEGAL_PARAMETER_NAME              = `VALIDATE_ILLEGAL_PARAMETER_NAME`
+	VALIDATE_ILLEGAL_QUERY_ATTRIBUTE             = `VALIDATE_ILLEGAL_QUERY_ATTRIBUTE`
 	VALIDATE_ILLEGAL_QUERY_EXPRESSION            = `VALIDATE_ILLEGAL_QUERY_EXPRESSION`
 	VALIDATE_ILLEGAL_REGEXP_TYPE_MAPPING         = `VALIDATE_ILLEGAL_REGEXP_TYPE_MAPPING`
 	VALIDATE_ILLEGAL_SINGLE_TYPE_MAPPING         = `VALIDATE_ILLEGAL_SINGLE_TYPE_MAPPING`
+	VALIDATE_INLINE_EPP_ERROR                    = `VALIDATE_INLINE_EPP_ERROR`
 	VALIDATE_INVALID_ACTIVITY_STYLE              = `VALIDATE_INVALID_ACTIVITY_STYLE`
+	VALIDATE_KEYWORD_CASE                        = `VALIDATE_KEYWORD_CASE`
+	VALIDATE_LEGACY_FACT                         = `VALIDATE_LEGACY_FACT`
+	VALIDATE_LINE_TOO_LONG                       = `VALIDATE_LINE_TOO_LONG`
+	VALIDATE_METADATA_MISSING_PARAMETER          = `VALIDATE_METADATA_MISSING_PARAMETER`
+	VALIDATE_METADATA_TYPE_MISMATCH              = `VALIDATE_METADATA_TYPE_MISMATCH`
+	VALIDATE_METADATA_UNKNOWN_PARAMETER          = `VALIDATE_METADATA_UNKNOWN_PARAMETER`
+	VALIDATE_MISSING_DEFAULT                     = `VALIDATE_MISSING_DEFAULT`
+	VALIDATE_MISSING_HEADER                      = `VALIDATE_MISSING_HEADER`
 	VALIDATE_MULTIPLE_ATTRIBUTES_UNFOLD          = `VALIDATE_MULTIPLE_ATTRIBUTES_UNFOLD`
+	VALIDATE_NEGATED_IF                          = `VALIDATE_NEGATED_IF`
+	VALIDATE_NESTED_SELECTOR                     = `VALIDATE_NESTED_SELECTOR`
+	VALIDATE_NODE_INHERITANCE                    = `VALIDATE_NODE_INHERITANCE`
 	VALIDATE_NOT_ABSOLUTE_TOP_LEVEL              = `VALIDATE_NOT_ABSOLUTE_TOP_LEVEL`
 	VALIDATE_NOT_RVALUE                          = `VALIDATE_NOT_RVALUE`
 	VALIDATE_NOT_TOP_LEVEL                       = `VALIDATE_NOT_TOP_LEVEL`
 	VALIDATE_NOT_VIRTUALIZABLE                   = `VALIDATE_NOT_VIRTUALIZABLE`
+	VALIDATE_OVERRIDE_WITH_HASH                  = `VALIDATE_OVERRIDE_WITH_HASH`
+	VALIDATE_PARAMS_CLASS_INHERITANCE            = `VALIDATE_PARAMS_CLASS_INHERITANCE`
+	VALIDATE_QUOTED_BOOLEAN                      = `VALIDATE_QUOTED_BOOLEAN`
+	VALIDATE_QUOTED_NUMBER                       = `VALIDATE_QUOTED_NUMBER`
 	VALIDATE_RESERVED_PARAMETER                  = `VALIDATE_RESERVED_PARAMETER`
 	VALIDATE_RESERVED_TYPE_NAME                  = `VALIDATE_RESERVED_TYPE_NAME`
 	VALIDATE_RESERVED_WORD                       = `VALIDATE_RESERVED_WORD`
+	VALIDATE_RESOURCE_ITERATION                  = `VALIDATE_RESOURCE_ITERATION`
+	VALIDATE_SINGLE_QUOTED_INTERPOLATION         = `VALIDATE_SINGLE_QUOTED_INTERPOLATION`
+	VALIDATE_STORECONFIGS_DISABLED               = `VALIDATE_STORECONFIGS_DISABLED`
+	VALIDATE_STRING_TOO_LONG                     = `VALIDATE_STRING_TOO_LONG`
+	VALIDATE_TOP_SCOPE_VARIABLE                  = `VALIDATE_TOP_SCOPE_VARIABLE`
+	VALIDATE_UNQUALIFIED_TOP_SCOPE_VARIABLE      = `VALIDATE_UNQUALIFIED_TOP_SCOPE_VARIABLE`
+	VALIDATE_UNRESTRICTED_COLLECTOR_OVERRIDE     = `VALIDATE_UNRESTRICTED_COLLECTOR_OVERRIDE`
 	VALIDATE_UNSUPPORTED_EXPRESSION              = `VALIDATE_UNSUPPORTED_EXPRESSION`
 	VALIDATE_UNSUPPORTED_OPERATOR_IN_CONTEXT     = `VALIDATE_UNSUPPORTED_OPERATOR_IN_CONTEXT`
+	VALIDATE_UNUSED_PARAMETER                    = `VALIDATE_UNUSED_PARAMETER`
+	VALIDATE_UNUSED_VARIABLE                     = `VALIDATE_UNUSED_VARIABLE`
 	VALIDATE_WORKFLOW_OPERATION_NOT_SUPPORTED    = `VALIDATE_WORKFLOW_OPERATION_NOT_SUPPORTED`
 )
 
 func init() {
 	issue.Hard(VALIDATE_APPENDS_DELETES_NO_LONGER_SUPPORTED, `The operator '%{operator}' is no longer supported. See http://links.puppet.com/remove-plus-equals`)
 
+	issue.Soft2(VALIDATE_APP_ORCHESTRATION_DEPRECATED,
+		`%{expression} is part of application orchestration, which is deprecated since Puppet 6 and removed in Puppet 7`,
+		issue.HF{`expression`: issue.A_anUc})
+
+	issue.Soft(VALIDATE_ARROW_ALIGNMENT, `The arrow of attribute '%{attr}' is at column %{column} but should be at column %{expected} to align with the other arrows`)
+
+	issue.Soft(VALIDATE_CALL_NOT_RVALUE, `Invalid use of '%{name}'. It does not produce a value`)
+
 	issue.Hard(VALIDATE_CAPTURES_REST_NOT_LAST, `Parameter $%{param} is not last, and has 'captures rest'`)
 
 	issue.Hard2(VALIDATE_CAPTURES_REST_NOT_SUPPORTED,
@@ -55,16 +108,54 @@ func init() {
 
 	issue.Hard(VALIDATE_CATALOG_OPERATION_NOT_SUPPORTED, `The catalog operation '%{operation}' is only available when compiling a catalog`)
 
+	issue.Soft(VALIDATE_CREATE_RESOURCES,
+		`The create_resources function is a legacy way to declare resources. Iterate over the hash and declare each resource instead`)
+
+	issue.Soft(VALIDATE_CROSS_MODULE_INHERITANCE,
+		`The class '%{name}' inherits '%{parent}', which is not in the module '%{module}'. Use include or class parameters instead of inheriting across modules`)
+
 	issue.Hard(VALIDATE_CROSS_SCOPE_ASSIGNMENT, `Illegal attempt to assign to '%{name}'. Cannot assign to variables in other namespaces`)
 
+	issue.Soft2(VALIDATE_DEFAULT_NOT_LAST,
+		`The 'default' entry of this %{container} is not last. It is only selected when no other entry matches`,
+		issue.HF{`container`: issue.Label})
+
+	issue.Soft(VALIDATE_DEFAULT_TYPE_MISMATCH, `The default value of parameter $%{param} has type %{actual}, which can never match the declared type %{expected}`)
+
+	issue.Hard(VALIDATE_DISCONTINUED_IMPORT, `Use of 'import' has been discontinued in favor of a manifest directory. See http://links.puppet.com/puppet-import-deprecation`)
+
+	issue.Soft2(VALIDATE_DOUBLE_NEGATION,
+		`This %{container} negates a negated condition, which is hard to read`,
+		issue.HF{`container`: issue.Label})
+
+	issue.Soft(VALIDATE_DOUBLE_QUOTED_STRING, `The double quoted string contains no interpolation or escape sequences. Use single quotes`)
+
 	issue.Hard2(VALIDATE_DUPLICATE_DEFAULT,
 		`This %{container} already has a 'default' entry - this is a duplicate`,
 		issue.HF{`container`: issue.Label})
 
 	issue.Soft(VALIDATE_DUPLICATE_KEY, `The key '%{key}' is declared more than once`)
 
+	issue.Soft2(VALIDATE_DUPLICATE_MATCH,
+		`The value '%{value}' is matched more than once in this %{container}`,
+		issue.HF{`container`: issue.Label})
+
 	issue.Hard(VALIDATE_DUPLICATE_PARAMETER, `The parameter '%{param}' is declared more than once in the parameter list`)
 
+	issue.Soft(VALIDATE_ELSE_IF, `The 'else' branch contains nothing but an 'if' statement. Use 'elsif' instead`)
+
+	issue.Soft2(VALIDATE_EMPTY_BODY,
+		`The body of this %{container} is empty`,
+		issue.HF{`container`: issue.Label})
+
+	issue.Soft2(VALIDATE_EMPTY_BRANCH,
+		`The '%{branch}' branch of this %{container} is empty`,
+		issue.HF{`container`: issue.Label})
+
+	issue.Soft(VALIDATE_EMPTY_RESOURCE_BODY, `The resource body has no attributes`)
+
+	issue.Soft(VALIDATE_ENSURE_NOT_FIRST, `The 'ensure' attribute should be the first attribute of the resource body`)
+
 	issue.Soft(VALIDATE_FUTURE_RESERVED_WORD, `Use of future reserved word: '%{word}'`)
 
 	issue.Soft2(VALIDATE_IDEM_EXPRESSION_NOT_LAST,
@@ -75,6 +166,8 @@ func init() {
 		`This %{expression} has no effect. %{container} can not end with a value-producing expression without other effect`,
 		issue.HF{`expression`: issue.Label, `container`: issue.A_anUc})
 
+	issue.Hard(VALIDATE_ILLEGAL_ARGUMENT_COUNT, `'%{name}' expects %{expected}, got %{actual}`)
+
 	issue.Hard(VALIDATE_ILLEGAL_ASSIGNMENT_CONTEXT, `Assignment not allowed here`)
 
 	issue.Hard(VALIDATE_ILLEGAL_ASSIGNMENT_VIA_INDEX, `Illegal attempt to assign via [index/key]. Not an assignable reference`)
@@ -85,6 +178,8 @@ func init() {
 
 	issue.Hard(VALIDATE_ILLEGAL_CLASSREF, `Illegal type reference. The given name '%{name}' does not conform to the naming rule`)
 
+	issue.Hard(VALIDATE_ILLEGAL_DEFAULTS_TYPE, `Resource defaults must be given for a specific resource type such as File or Resource['file'], not for %{type}`)
+
 	issue.Hard2(VALIDATE_ILLEGAL_DEFINITION_NAME,
 		`Unacceptable name. The name '%{name}' is unacceptable as the name of %{value}`,
 		issue.HF{`value`: issue.A_an})
@@ -102,8 +197,14 @@ func init() {
 
 	issue.Hard(VALIDATE_ILLEGAL_NUMERIC_PARAMETER, `The numeric parameter name '$%{name}' cannot be used (clashes with numeric match result variables)`)
 
+	issue.Hard(VALIDATE_ILLEGAL_OVERRIDE_REFERENCE, `%{reference} does not reference a specific resource. A resource override needs a type and at least one title`)
+
 	issue.Hard(VALIDATE_ILLEGAL_PARAMETER_NAME, `Illegal parameter name. The given name '%{name}' does not conform to the naming rule /^[a-z_]\w*$/`)
 
+	issue.Hard2(VALIDATE_ILLEGAL_QUERY_ATTRIBUTE,
+		`Illegal query expression. The left side of a comparison must be an attribute name, not %{expression}`,
+		issue.HF{`expression`: issue.A_an})
+
 	issue.Hard2(VALIDATE_ILLEGAL_QUERY_EXPRESSION,
 		`Illegal query expression. %{expression} cannot be used in a query`,
 		issue.HF{`expression`: issue.A_anUc})
@@ -116,22 +217,60 @@ func init() {
 		`Illegal type mapping. Expected a Type on the left side, got %{expression}`,
 		issue.HF{`expression`: issue.A_an})
 
+	issue.Hard(VALIDATE_INLINE_EPP_ERROR, `The inline EPP template has an error at line %{line}, column %{column} of the template: %{message}`)
+
 	issue.Hard(VALIDATE_INVALID_ACTIVITY_STYLE, `Expected one of 'for', 'function', 'guard', 'resource', or 'workflow'. Got '%{style}'`)
 
+	issue.Soft(VALIDATE_KEYWORD_CASE, `'%{name}' is a type name and not a keyword. Did you mean '%{keyword}'?`)
+
+	issue.Soft(VALIDATE_LEGACY_FACT, `The variable '$%{name}' refers to a legacy fact. Use %{replacement} instead`)
+
+	issue.Soft(VALIDATE_LINE_TOO_LONG, `This line is %{length} characters long, which exceeds the maximum of %{max}`)
+
+	issue.Soft(VALIDATE_METADATA_MISSING_PARAMETER, `Parameter $%{param} of plan '%{plan}' is not declared in %{file}`)
+
+	issue.Soft(VALIDATE_METADATA_TYPE_MISMATCH, `Parameter $%{param} of plan '%{plan}' has type %{type} but %{file} declares type %{metadata_type}`)
+
+	issue.Soft(VALIDATE_METADATA_UNKNOWN_PARAMETER, `Parameter '%{param}' declared in %{file} is not a parameter of plan '%{plan}'`)
+
+	issue.Soft2(VALIDATE_MISSING_DEFAULT,
+		`This %{container} has no 'default' option`,
+		issue.HF{`container`: issue.Label})
+
+	issue.Soft(VALIDATE_MISSING_HEADER, `The manifest does not start with the required header comment`)
+
 	issue.Hard(VALIDATE_MULTIPLE_ATTRIBUTES_UNFOLD, `Unfolding of attributes from Hash can only be used once per resource body`)
 
+	issue.Soft(VALIDATE_NEGATED_IF, `The condition of an 'if' statement without an 'else' branch is negated. Use 'unless' instead`)
+
+	issue.Soft(VALIDATE_NESTED_SELECTOR, `A selector nested in another selector is hard to read. Enclose it in parentheses or assign it to a variable`)
+
+	issue.Soft(VALIDATE_NODE_INHERITANCE,
+		`Node inheritance is not supported since Puppet 4. Use classes, e.g. roles and profiles, to share configuration between nodes`)
+
 	issue.Hard2(VALIDATE_NOT_ABSOLUTE_TOP_LEVEL,
 		`%{value} may only appear at top level`,
 		issue.HF{`value`: issue.A_anUc})
 
-	issue.Hard(VALIDATE_NOT_TOP_LEVEL, `Classes, definitions, and nodes may only appear at top level or inside other classes`)
-
 	issue.Hard2(VALIDATE_NOT_RVALUE,
 		`Invalid use of expression. %{value} does not produce a value`,
 		issue.HF{`value`: issue.A_anUc})
 
+	issue.Hard(VALIDATE_NOT_TOP_LEVEL, `Classes, definitions, and nodes may only appear at top level or inside other classes`)
+
 	issue.Hard(VALIDATE_NOT_VIRTUALIZABLE, `Resource Defaults/Overrides are not virtualizable`)
 
+	issue.Hard(VALIDATE_OVERRIDE_WITH_HASH, `Attributes of %{reference} can not be set by assigning a Hash. Use a resource override such as %{reference} { attribute => value }`)
+
+	issue.Soft(VALIDATE_PARAMS_CLASS_INHERITANCE,
+		`The class '%{name}' inherits '%{parent}' to get the default values of its parameters. Use module data instead of a params class`)
+
+	issue.Soft(VALIDATE_QUOTED_BOOLEAN,
+		`The value of attribute '%{attr}' is the quoted boolean %{value}. Use %{suggestion} without quotes if a Boolean is intended`)
+
+	issue.Soft(VALIDATE_QUOTED_NUMBER,
+		`The value of attribute '%{attr}' is the quoted number %{value}. Use %{suggestion} without quotes if a number is intended`)
+
 	issue.Hard2(VALIDATE_RESERVED_PARAMETER,
 		`The parameter $%{param} redefines a built in parameter in %{container}`,
 		issue.HF{`container`: issue.A_an})
@@ -142,6 +281,28 @@ func init() {
 
 	issue.Hard(VALIDATE_RESERVED_WORD, `Use of reserved word: %{word}, must be quoted if intended to be a String value`)
 
+	issue.Soft(VALIDATE_RESOURCE_ITERATION,
+		`This iteration over a literal hash declares one '%{type}' resource per entry. Declare the resources directly instead`)
+
+	issue.Soft(VALIDATE_SINGLE_QUOTED_INTERPOLATION,
+		`The single quoted string contains '%{text}', which looks like an interpolation. Use double quotes if interpolation is intended`)
+
+	issue.Soft2(VALIDATE_STORECONFIGS_DISABLED,
+		`This %{expression} has no effect since exported resources require storeconfigs, which is disabled`,
+		issue.HF{`expression`: issue.Label})
+
+	issue.Soft(VALIDATE_STRING_TOO_LONG, `This string is %{length} characters long, which exceeds the maximum of %{max}`)
+
+	issue.Soft(VALIDATE_TOP_SCOPE_VARIABLE,
+		`Reference to the top scope variable '$::%{name}'. Use an explicit parameter or, if it is a fact, the facts hash ($facts['%{name}']) instead`)
+
+	issue.Soft2(VALIDATE_UNQUALIFIED_TOP_SCOPE_VARIABLE,
+		`The variable '$%{name}' is neither a parameter nor assigned in this %{container}. Use an explicit parameter or, if it is a fact, the facts hash ($facts['%{name}']) instead`,
+		issue.HF{`container`: issue.Label})
+
+	issue.Soft(VALIDATE_UNRESTRICTED_COLLECTOR_OVERRIDE,
+		`This collector has an empty query, so its attribute overrides apply to every %{form} %{type} resource`)
+
 	issue.Hard2(VALIDATE_UNSUPPORTED_EXPRESSION,
 		`Expressions of type %{expression} are not supported in this version of Puppet`,
 		issue.HF{`expression`: issue.A_an})
@@ -150,5 +311,12 @@ func init() {
 		`The operator '%{operator}' in %{value} is not supported`,
 		issue.HF{`value`: issue.A_an})
 
+	issue.Soft(VALIDATE_UNUSED_PARAMETER,
+		`The lambda parameter $%{name} is never used. Prefix its name with an underscore if it is intentionally unused`)
+
+	issue.Soft2(VALIDATE_UNUSED_VARIABLE,
+		`The variable $%{name} is assigned but never used in this %{container}`,
+		issue.HF{`container`: issue.Label})
+
 	issue.Hard(VALIDATE_WORKFLOW_OPERATION_NOT_SUPPORTED, `The workflow operation '%{operation}' is only available when compiling workflows`)
 }
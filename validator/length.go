@@ -0,0 +1,111 @@
+package validator
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+// ApplyMaxLineLength makes the given validator warn about the lines of a manifest that are longer than
+// the given number of characters. The issue is reported for the characters beyond the maximum, so an
+// editor can mark exactly the part of the line that should be wrapped. EPP templates are not checked.
+func ApplyMaxLineLength(v Validator, max int) {
+	v.setLimit(VALIDATE_LINE_TOO_LONG, max)
+	v.Demote(VALIDATE_LINE_TOO_LONG, issue.SEVERITY_WARNING)
+}
+
+// ApplyMaxStringLength makes the given validator warn about quoted strings, e.g. attribute values, whose
+// text between the quotes is longer than the given number of characters. The issue is reported for the
+// whole string. Heredocs are not checked.
+func ApplyMaxStringLength(v Validator, max int) {
+	v.setLimit(VALIDATE_STRING_TOO_LONG, max)
+	v.Demote(VALIDATE_STRING_TOO_LONG, issue.SEVERITY_WARNING)
+}
+
+func (v *AbstractValidator) setLimit(code issue.Code, limit int) {
+	if v.limits == nil {
+		v.limits = make(map[issue.Code]int)
+	}
+	v.limits[code] = limit
+}
+
+// lineRange is the range of a part of a line that an issue is reported for. Like an expression, it has a
+// byte offset and length, so diagnostics that are created from the issue get its exact end.
+type lineRange struct {
+	locator *parser.Locator
+	offset  int
+	length  int
+}
+
+func (r *lineRange) File() string {
+	return r.locator.File()
+}
+
+func (r *lineRange) Line() int {
+	return r.locator.LineForOffset(r.offset)
+}
+
+func (r *lineRange) Pos() int {
+	return r.locator.PosOnLine(r.offset)
+}
+
+func (r *lineRange) ByteOffset() int {
+	return r.offset
+}
+
+func (r *lineRange) ByteLength() int {
+	return r.length
+}
+
+func (r *lineRange) Locator() *parser.Locator {
+	return r.locator
+}
+
+func (v *basicChecker) checkLineLength(e *parser.Program) {
+	max, ok := v.limits[VALIDATE_LINE_TOO_LONG]
+	if !ok {
+		return
+	}
+	if _, epp := e.Body().(*parser.LambdaExpression); epp {
+		return
+	}
+	locator := e.Locator()
+	source := locator.String()
+	for start := 0; start < len(source); {
+		end := strings.IndexByte(source[start:], '\n')
+		if end < 0 {
+			end = len(source)
+		} else {
+			end += start
+		}
+		line := strings.TrimSuffix(source[start:end], "\r")
+		if length := utf8.RuneCountInString(line); length > max {
+			// The byte offset of the first character beyond the maximum
+			over := start
+			for i := 0; i < max; i++ {
+				_, sz := utf8.DecodeRuneInString(source[over:])
+				over += sz
+			}
+			v.acceptAt(VALIDATE_LINE_TOO_LONG, &lineRange{locator, over, start + len(line) - over},
+				issue.H{`length`: length, `max`: max})
+		}
+		start = end + 1
+	}
+}
+
+func (v *basicChecker) checkStringLength(e parser.Expression) {
+	max, ok := v.limits[VALIDATE_STRING_TOO_LONG]
+	if !ok || v.ignored(VALIDATE_STRING_TOO_LONG) {
+		return
+	}
+	src := e.String()
+	if len(src) < 2 || !(src[0] == '\'' || src[0] == '"') {
+		// Heredoc text and bare words
+		return
+	}
+	if length := utf8.RuneCountInString(src) - 2; length > max {
+		v.Accept(VALIDATE_STRING_TOO_LONG, e, issue.H{`length`: length, `max`: max})
+	}
+}
@@ -0,0 +1,74 @@
+package validator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+)
+
+func TestMaxLineLength(t *testing.T) {
+	source := "$a = 'short'\n$b = 'a line that is too long'\r\n$c = 'ü is one character'\n"
+	expectNoIssues(t, source)
+
+	v := NewChecker(STRICT_ERROR)
+	ApplyMaxLineLength(v, 24)
+	Validate(v, parse(t, source))
+	issues := v.Issues()
+	if len(issues) != 2 {
+		t.Fatalf("expected 2 issues, got %d", len(issues))
+	}
+	expected := []string{
+		`This line is 30 characters long, which exceeds the maximum of 24 (line: 2, column: 25)`,
+		`This line is 25 characters long, which exceeds the maximum of 24 (line: 3, column: 25)`,
+	}
+	for i, ri := range issues {
+		if ri.Code() != VALIDATE_LINE_TOO_LONG || ri.Severity() != issue.SEVERITY_WARNING || ri.Error() != expected[i] {
+			t.Errorf("unexpected issue %s", ri)
+		}
+	}
+	if r, ok := issues[0].Location().(*lineRange); !ok || source[r.offset:r.offset+r.length] != ` long'` {
+		t.Errorf("unexpected range of %s", issues[0])
+	}
+	if r, ok := issues[1].Location().(*lineRange); !ok || source[r.offset:r.offset+r.length] != `'` {
+		t.Errorf("unexpected range of %s", issues[1])
+	}
+}
+
+func TestMaxStringLength(t *testing.T) {
+	source := issue.Unindent(`
+    file { '/etc/motd':
+      content => "Welcome to ${facts['fqdn']}",
+      owner   => 'a-rather-long-owner',
+      group   => 'root',
+    }
+    $x = @(END)
+      A heredoc with a rather long text
+      | END
+    $y = @(END)
+      |-END
+    `)
+	expectNoIssues(t, source)
+
+	v := NewChecker(STRICT_ERROR)
+	ApplyMaxStringLength(v, 12)
+	Validate(v, parse(t, source))
+	messages := make([]string, 0)
+	for _, ri := range v.Issues() {
+		messages = append(messages, ri.Error())
+	}
+	expected := issue.Unindent(`
+    This string is 27 characters long, which exceeds the maximum of 12 (line: 2, column: 14)
+    This string is 19 characters long, which exceeds the maximum of 12 (line: 3, column: 14)`)
+	if actual := strings.Join(messages, "\n"); actual != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, actual)
+	}
+
+	v = NewChecker(STRICT_ERROR)
+	ApplyMaxStringLength(v, 12)
+	v.Demote(VALIDATE_STRING_TOO_LONG, issue.SEVERITY_IGNORE)
+	Validate(v, parse(t, source))
+	if len(v.Issues()) != 0 {
+		t.Errorf("expected no issues, got %v", v.Issues())
+	}
+}
@@ -0,0 +1,48 @@
+package validator
+
+import (
+	"github.com/lyraproj/issue/issue"
+)
+
+// LINT_ISSUES are the codes of the issues that report constructs that are valid but usually
+// unintended, such as leftovers from refactoring. These issues are ignored unless they are
+// enabled using EnableLint.
+var LINT_ISSUES = []issue.Code{
+	VALIDATE_ARROW_ALIGNMENT,
+	VALIDATE_CREATE_RESOURCES,
+	VALIDATE_CROSS_MODULE_INHERITANCE,
+	VALIDATE_DOUBLE_NEGATION,
+	VALIDATE_DOUBLE_QUOTED_STRING,
+	VALIDATE_ELSE_IF,
+	VALIDATE_EMPTY_BODY,
+	VALIDATE_EMPTY_BRANCH,
+	VALIDATE_EMPTY_RESOURCE_BODY,
+	VALIDATE_ENSURE_NOT_FIRST,
+	VALIDATE_LEGACY_FACT,
+	VALIDATE_LINE_TOO_LONG,
+	VALIDATE_MISSING_HEADER,
+	VALIDATE_NEGATED_IF,
+	VALIDATE_NODE_INHERITANCE,
+	VALIDATE_PARAMS_CLASS_INHERITANCE,
+	VALIDATE_QUOTED_BOOLEAN,
+	VALIDATE_QUOTED_NUMBER,
+	VALIDATE_RESOURCE_ITERATION,
+	VALIDATE_SINGLE_QUOTED_INTERPOLATION,
+	VALIDATE_STRING_TOO_LONG,
+	VALIDATE_TOP_SCOPE_VARIABLE,
+	VALIDATE_UNQUALIFIED_TOP_SCOPE_VARIABLE,
+	VALIDATE_UNUSED_PARAMETER,
+	VALIDATE_UNUSED_VARIABLE,
+}
+
+// EnableLint makes the given validator report all lint issues as warnings. Individual lint issues
+// can then be turned off again by demoting them to issue.SEVERITY_IGNORE, e.g. the reporting of
+// unqualified variables that may refer to top scope variables:
+//
+//	EnableLint(v)
+//	v.Demote(VALIDATE_UNQUALIFIED_TOP_SCOPE_VARIABLE, issue.SEVERITY_IGNORE)
+func EnableLint(v Validator) {
+	for _, code := range LINT_ISSUES {
+		v.Demote(code, issue.SEVERITY_WARNING)
+	}
+}
@@ -0,0 +1,387 @@
+package validator
+
+import (
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+)
+
+func TestEmptyBodyLint(t *testing.T) {
+	expectNoIssues(t, `class a {}`)
+
+	PuppetLint = true
+	defer func() { PuppetLint = false }()
+
+	expectIssues(t, `class a {}`, VALIDATE_EMPTY_BODY)
+
+	expectIssues(t, `define a() {}`, VALIDATE_EMPTY_BODY)
+
+	expectNoIssues(t, `define a() { notice('a') }`)
+
+	expectIssues(t, `if $x {} else { notice('a') }`, VALIDATE_EMPTY_BRANCH)
+
+	expectIssues(t, `unless $x { notice('a') } else {}`, VALIDATE_EMPTY_BRANCH)
+
+	expectIssues(t, `if $x { notice('a') } elsif $y {}`, VALIDATE_EMPTY_BRANCH)
+
+	expectNoIssues(t, `if $x { notice('a') } elsif $y { notice('b') }`)
+
+	expectIssues(t, `file { '/tmp/x': }`, VALIDATE_EMPTY_RESOURCE_BODY)
+
+	expectNoIssues(t, `file { '/tmp/x': ensure => file }`)
+
+	issues := parseAndValidate(t, issue.Unindent(`
+    if $x {
+      notice('a')
+    } else {
+    }`))
+	if len(issues) != 1 {
+		t.Fatalf("expected 1 issue, got %d", len(issues))
+	}
+	if issues[0].Severity() != issue.SEVERITY_WARNING || issues[0].Location().Line() != 1 ||
+		issues[0].Error() != `The 'else' branch of this 'if' statement is empty (line: 1, column: 1)` {
+		t.Errorf("unexpected issue %s", issues[0])
+	}
+}
+
+func TestEmptyPlanBodyLint(t *testing.T) {
+	PuppetTasks = true
+	PuppetLint = true
+	defer func() {
+		PuppetTasks = false
+		PuppetLint = false
+	}()
+
+	expectIssues(t, `plan a() {}`, VALIDATE_EMPTY_BODY)
+}
+
+func TestTopScopeVariableLint(t *testing.T) {
+	expectNoIssues(t, `class a { notice($::kernel, $x) }`)
+
+	PuppetLint = true
+	defer func() { PuppetLint = false }()
+
+	expectNoIssues(t, `notice($::kernel, $x)`)
+
+	expectIssues(t, `class a { notice($::kernel) }`, VALIDATE_TOP_SCOPE_VARIABLE)
+
+	expectIssues(t, `class a($x = $::kernel) { notice($x) }`, VALIDATE_TOP_SCOPE_VARIABLE)
+
+	expectNoIssues(t, `class a { notice($::facts['kernel'], $::a::x) }`)
+
+	expectIssues(t, `define a() { notice($kernel) }`, VALIDATE_UNQUALIFIED_TOP_SCOPE_VARIABLE)
+
+	expectNoIssues(t, issue.Unindent(`
+    class a(String $x) {
+      [$y, $z] = [$x, $title]
+      $w = 1
+      [1].each |$v| { notice($v, $w, $y, $z, $facts, $a::b::c) }
+    }`))
+
+	expectNoIssues(t, `class a::b inherits a { notice($x) }`)
+
+	expectIssues(t, issue.Unindent(`
+    class a {
+      $x = 1
+      define b() { notice($x) }
+    }`), VALIDATE_UNQUALIFIED_TOP_SCOPE_VARIABLE)
+
+	issues := parseAndValidate(t, `class a { notice($kernel) }`)
+	if len(issues) != 1 {
+		t.Fatalf("expected 1 issue, got %d", len(issues))
+	}
+	if issues[0].Severity() != issue.SEVERITY_WARNING ||
+		issues[0].Error() != `The variable '$kernel' is neither a parameter nor assigned in this Host Class Definition. Use an explicit parameter or, if it is a fact, the facts hash ($facts['kernel']) instead (line: 1, column: 18)` {
+		t.Errorf("unexpected issue %s", issues[0])
+	}
+}
+
+func TestLegacyFactLint(t *testing.T) {
+	expectNoIssues(t, `notice($osfamily)`)
+
+	PuppetLint = true
+	defer func() { PuppetLint = false }()
+
+	expectIssues(t, `notice($osfamily, $::operatingsystem)`, VALIDATE_LEGACY_FACT, VALIDATE_LEGACY_FACT)
+
+	expectIssues(t, `class a { notice("${::fqdn}") }`, VALIDATE_LEGACY_FACT)
+
+	expectIssues(t, `node default { notice($hostname) }`, VALIDATE_LEGACY_FACT)
+
+	expectNoIssues(t, `$osfamily = 'x' notice($osfamily)`)
+
+	expectNoIssues(t, `class a(String $domain) { notice($domain) }`)
+
+	expectNoIssues(t, `class a { notice($facts['os']['family']) } notice($kernel, $::kernel)`)
+
+	issues := parseAndValidate(t, `class a { notice($::lsbdistcodename) }`)
+	if len(issues) != 1 {
+		t.Fatalf("expected 1 issue, got %d", len(issues))
+	}
+	if issues[0].Error() != `The variable '$::lsbdistcodename' refers to a legacy fact. Use $facts['os']['distro']['codename'] instead (line: 1, column: 18)` {
+		t.Errorf("unexpected issue %s", issues[0])
+	}
+}
+
+func TestInheritanceLint(t *testing.T) {
+	expectNoIssues(t, `class a inherits a::params {} node a inherits b {}`)
+
+	PuppetLint = true
+	defer func() { PuppetLint = false }()
+
+	expectIssues(t, `class a($x = $a::params::x) inherits a::params { notice($x) }`, VALIDATE_PARAMS_CLASS_INHERITANCE)
+
+	expectIssues(t, `class a inherits b { notice('a') }`, VALIDATE_CROSS_MODULE_INHERITANCE)
+
+	expectIssues(t, `class a::b inherits c::params { notice('a') }`, VALIDATE_PARAMS_CLASS_INHERITANCE, VALIDATE_CROSS_MODULE_INHERITANCE)
+
+	expectNoIssues(t, `class a::b inherits ::A::C { notice('a') }`)
+
+	issues := parseAndValidate(t, `node 'a.example.com' inherits base {}`)
+	if len(issues) != 1 {
+		t.Fatalf("expected 1 issue, got %d", len(issues))
+	}
+	if issues[0].Code() != VALIDATE_NODE_INHERITANCE || issues[0].Location().Pos() != 31 {
+		t.Errorf("unexpected issue %s", issues[0])
+	}
+}
+
+func TestUnusedVariableLint(t *testing.T) {
+	expectNoIssues(t, `$a.each |$k, $v| { notice($k) }`)
+
+	PuppetLint = true
+	defer func() { PuppetLint = false }()
+
+	expectIssues(t, `$a.each |$k, $v| { notice($k) }`, VALIDATE_UNUSED_PARAMETER)
+
+	expectNoIssues(t, `$a.each |$_k, $v| { notice("${v}") }`)
+
+	expectNoIssues(t, `$a.each |$k, $v| { $a.each |$x| { notice($k, $v, $x) } }`)
+
+	expectIssues(t, `$a.map |$v| { $x = $v $x }`)
+
+	expectIssues(t, `$a.map |$v| { $x = $v $v }`, VALIDATE_UNUSED_VARIABLE)
+
+	expectIssues(t, `function f() { [$x, $y] = [1, 2] $x }`, VALIDATE_UNUSED_VARIABLE)
+
+	expectIssues(t, `function f() { $x = 1 [1].each |$v| { $y = $v notice($x) } }`, VALIDATE_UNUSED_VARIABLE)
+
+	expectNoIssues(t, `class a { $x = 1 }`)
+
+	issues := parseAndValidate(t, `function f() { $x = 1 $_y = 2 }`)
+	if len(issues) != 1 {
+		t.Fatalf("expected 1 issue, got %d", len(issues))
+	}
+	if issues[0].Error() != `The variable $x is assigned but never used in this Function Definition (line: 1, column: 16)` {
+		t.Errorf("unexpected issue %s", issues[0])
+	}
+}
+
+func TestResourceCreationLint(t *testing.T) {
+	expectNoIssues(t, `create_resources('file', $files)`)
+
+	PuppetLint = true
+	defer func() { PuppetLint = false }()
+
+	expectIssues(t, `create_resources('file', $files)`, VALIDATE_CREATE_RESOURCES)
+
+	expectIssues(t, `{'/a' => {}}.each |$k, $v| { file { $k: * => $v } }`, VALIDATE_RESOURCE_ITERATION)
+
+	expectIssues(t, `each({'/a' => {}}) |$k, $v| { file { $k: * => $v } }`, VALIDATE_RESOURCE_ITERATION)
+
+	expectNoIssues(t, `$files.each |$k, $v| { file { $k: * => $v } }`)
+
+	expectNoIssues(t, `{'/a' => {}}.each |$k, $v| { file { $k: mode => '0644', * => $v } }`)
+
+	expectNoIssues(t, `{'/a' => {}}.each |$k, $v| { @file { $k: * => $v } }`)
+
+	expectNoIssues(t, `{'/a' => {}}.each |$k, $v| { file { $v: * => $k } }`)
+
+	issues := parseAndValidate(t, `{'/a' => {}}.each |$k, $v| { file { $k: * => $v } }`)
+	if len(issues) != 1 {
+		t.Fatalf("expected 1 issue, got %d", len(issues))
+	}
+	if issues[0].Error() != `This iteration over a literal hash declares one 'file' resource per entry. Declare the resources directly instead (line: 1, column: 1)` {
+		t.Errorf("unexpected issue %s", issues[0])
+	}
+}
+
+func TestSingleQuotedInterpolationLint(t *testing.T) {
+	expectNoIssues(t, `notice('hello ${name}')`)
+
+	PuppetLint = true
+	defer func() { PuppetLint = false }()
+
+	expectIssues(t, `notice('hello ${name}')`, VALIDATE_SINGLE_QUOTED_INTERPOLATION)
+
+	expectIssues(t, `notice('hello $name')`, VALIDATE_SINGLE_QUOTED_INTERPOLATION)
+
+	expectIssues(t, `notice('hello $::a::name')`, VALIDATE_SINGLE_QUOTED_INTERPOLATION)
+
+	expectNoIssues(t, `notice('costs $5 or $HOME')`)
+
+	expectNoIssues(t, `notice("hello ${name}", "a \$b", "${name}'\$x'")`)
+
+	expectNoIssues(t, issue.Unindent(`
+    notice(@(END))
+      hello ${name}
+      | END`))
+
+	issues := parseAndValidate(t, `notice('hello $name')`)
+	if len(issues) != 1 {
+		t.Fatalf("expected 1 issue, got %d", len(issues))
+	}
+	if issues[0].Severity() != issue.SEVERITY_WARNING ||
+		issues[0].Error() != `The single quoted string contains '$name', which looks like an interpolation. Use double quotes if interpolation is intended (line: 1, column: 8)` {
+		t.Errorf("unexpected issue %s", issues[0])
+	}
+}
+
+func TestQuotedValueLint(t *testing.T) {
+	expectNoIssues(t, `service { 'x': enable => 'true' }`)
+
+	PuppetLint = true
+	defer func() { PuppetLint = false }()
+
+	expectIssues(t, `service { 'x': enable => 'true' }`, VALIDATE_QUOTED_BOOLEAN)
+
+	expectIssues(t, `Service { enable => "false" }`, VALIDATE_QUOTED_BOOLEAN, VALIDATE_DOUBLE_QUOTED_STRING)
+
+	expectIssues(t, `user { 'x': uid => '1001' }`, VALIDATE_QUOTED_NUMBER)
+
+	expectIssues(t, `foo { 'x': ratio => '-0.5' }`, VALIDATE_QUOTED_NUMBER)
+
+	expectNoIssues(t, `file { 'x': mode => '0644', owner => '1001a', replace => true }`)
+
+	expectNoIssues(t, `notice('true', '1')`)
+
+	issues := parseAndValidate(t, `service { 'x': enable => 'true' }`)
+	if len(issues) != 1 {
+		t.Fatalf("expected 1 issue, got %d", len(issues))
+	}
+	if issues[0].Severity() != issue.SEVERITY_WARNING ||
+		issues[0].Error() != `The value of attribute 'enable' is the quoted boolean 'true'. Use true without quotes if a Boolean is intended (line: 1, column: 26)` {
+		t.Errorf("unexpected issue %s", issues[0])
+	}
+}
+
+func TestResourceStyleLint(t *testing.T) {
+	PuppetLint = true
+	defer func() { PuppetLint = false }()
+
+	expectNoIssues(t, issue.Unindent(`
+    file { '/tmp/x':
+      ensure  => file,
+      mode    => '0644',
+      *       => $h,
+      content => 'x',
+    }`))
+
+	expectNoIssues(t, `file { '/tmp/x': ensure => file, mode => '0644' }`)
+
+	expectIssues(t, issue.Unindent(`
+    file { '/tmp/x':
+      mode   => '0644',
+      ensure => file,
+    }`), VALIDATE_ENSURE_NOT_FIRST)
+
+	expectIssues(t, issue.Unindent(`
+    File {
+      ensure => file,
+      mode    => '0644',
+    }`), VALIDATE_ARROW_ALIGNMENT, VALIDATE_ARROW_ALIGNMENT)
+
+	issues := parseAndValidate(t, issue.Unindent(`
+    file { '/tmp/x':
+      ensure => file,
+      mode   => '0644',
+      owner => 'root',
+    }`))
+	if len(issues) != 1 {
+		t.Fatalf("expected 1 issue, got %d", len(issues))
+	}
+	if issues[0].Severity() != issue.SEVERITY_WARNING ||
+		issues[0].Error() != `The arrow of attribute 'owner' is at column 9 but should be at column 10 to align with the other arrows (line: 4, column: 3)` {
+		t.Errorf("unexpected issue %s", issues[0])
+	}
+}
+
+func TestConditionalLint(t *testing.T) {
+	expectNoIssues(t, `if !$x { notice('a') }`)
+
+	PuppetLint = true
+	defer func() { PuppetLint = false }()
+
+	expectIssues(t, `if !$x { notice('a') }`, VALIDATE_NEGATED_IF)
+
+	expectNoIssues(t, `if !$x { notice('a') } else { notice('b') }`)
+
+	expectNoIssues(t, `if $x { notice('a') } elsif !$y { notice('b') }`)
+
+	expectNoIssues(t, `unless $x and $y { notice('a') }`)
+
+	expectIssues(t, `if !!$x { notice('a') }`, VALIDATE_DOUBLE_NEGATION)
+
+	expectIssues(t, `notice(!(!$x))`, VALIDATE_DOUBLE_NEGATION)
+
+	expectIssues(t, `unless !$x { notice('a') }`, VALIDATE_DOUBLE_NEGATION)
+
+	expectIssues(t, `if $x { notice('a') } else { if $y { notice('b') } }`, VALIDATE_ELSE_IF)
+
+	expectNoIssues(t, `unless $x { notice('a') } else { if $y { notice('b') } }`)
+
+	expectNoIssues(t, `if $x { notice('a') } else { if $y { notice('b') } notice('c') }`)
+
+	expectNoIssues(t, `if $x { notice('a') } else { unless $y { notice('b') } }`)
+
+	issues := parseAndValidate(t, `unless !$x { notice('a') }`)
+	if len(issues) != 1 {
+		t.Fatalf("expected 1 issue, got %d", len(issues))
+	}
+	if issues[0].Severity() != issue.SEVERITY_WARNING ||
+		issues[0].Error() != `This 'unless' statement negates a negated condition, which is hard to read (line: 1, column: 1)` {
+		t.Errorf("unexpected issue %s", issues[0])
+	}
+}
+
+func TestStringQuotingLint(t *testing.T) {
+	expectNoIssues(t, `notice("a")`)
+
+	PuppetLint = true
+	defer func() { PuppetLint = false }()
+
+	expectIssues(t, `notice("a")`, VALIDATE_DOUBLE_QUOTED_STRING)
+
+	expectNoIssues(t, `notice("a\n", "it's", "\$x", "${x}", "a ${x} b", @("END"/L)
+      text\
+      | END
+    )`)
+
+	expectIssues(t, `notice('a ${x} b')`, VALIDATE_SINGLE_QUOTED_INTERPOLATION)
+
+	// The text of an empty heredoc that ends with '|-' has no source
+	expectNoIssues(t, "$x = @(END)\n  |-END\n")
+}
+
+func TestStringQuotingLintIgnored(t *testing.T) {
+	source := `notice("a", '${x}')`
+	v := NewChecker(STRICT_ERROR)
+	EnableLint(v)
+	v.Demote(VALIDATE_DOUBLE_QUOTED_STRING, issue.SEVERITY_IGNORE)
+	Validate(v, parse(t, source))
+	if issues := v.Issues(); len(issues) != 1 || issues[0].Code() != VALIDATE_SINGLE_QUOTED_INTERPOLATION {
+		t.Errorf("expected one %s issue, got %v", VALIDATE_SINGLE_QUOTED_INTERPOLATION, issues)
+	}
+
+	v = NewChecker(STRICT_ERROR)
+	EnableLint(v)
+	v.Demote(VALIDATE_SINGLE_QUOTED_INTERPOLATION, issue.SEVERITY_IGNORE)
+	Validate(v, parse(t, source))
+	if issues := v.Issues(); len(issues) != 1 || issues[0].Code() != VALIDATE_DOUBLE_QUOTED_STRING {
+		t.Errorf("expected one %s issue, got %v", VALIDATE_DOUBLE_QUOTED_STRING, issues)
+	}
+}
+
+func TestEmptyHeredocWithoutLint(t *testing.T) {
+	expectNoIssues(t, "$x = @(END)\n  |-END\n")
+}
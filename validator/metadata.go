@@ -0,0 +1,68 @@
+package validator
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-parser/analysis"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+// CheckPlanMetadata compares the parameters of the given plan with the parameters declared in the given
+// metadata, which is the content of a task metadata file in JSON format, typically the metadata of a
+// task that the plan wraps. The given file name is used in the messages of the returned issues.
+//
+// A warning is returned for each plan parameter that is not declared in the metadata, for each
+// parameter in the metadata that is not a plan parameter, and for each parameter with a type that
+// differs from the type in the metadata. Types are compared in their string form without whitespace,
+// and a missing type in the metadata isn't compared. An error is returned when the metadata can't be
+// parsed.
+func CheckPlanMetadata(plan *parser.PlanDefinition, file string, metadata []byte) ([]issue.Reported, error) {
+	var md struct {
+		Parameters map[string]struct {
+			Type string `json:"type"`
+		} `json:"parameters"`
+	}
+	if err := json.Unmarshal(metadata, &md); err != nil {
+		return nil, err
+	}
+
+	issues := make([]issue.Reported, 0)
+	report := func(code issue.Code, e parser.Expression, args issue.H) {
+		args[`plan`] = plan.Name()
+		args[`file`] = file
+		issues = append(issues, issue.NewReported(code, issue.SEVERITY_WARNING, args, e))
+	}
+
+	declared := make(map[string]bool, len(plan.Parameters()))
+	schema := analysis.ParameterSchemas(plan)[0]
+	for i, ps := range schema.Parameters {
+		param := plan.Parameters()[i]
+		declared[ps.Name] = true
+		mp, ok := md.Parameters[ps.Name]
+		switch {
+		case !ok:
+			report(VALIDATE_METADATA_MISSING_PARAMETER, param, issue.H{`param`: ps.Name})
+		case mp.Type != `` && stripSpace(mp.Type) != stripSpace(ps.Type):
+			report(VALIDATE_METADATA_TYPE_MISMATCH, param, issue.H{`param`: ps.Name, `type`: ps.Type, `metadata_type`: mp.Type})
+		}
+	}
+
+	unknown := make([]string, 0)
+	for name := range md.Parameters {
+		if !declared[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		report(VALIDATE_METADATA_UNKNOWN_PARAMETER, plan, issue.H{`param`: name})
+	}
+	return issues, nil
+}
+
+func stripSpace(s string) string {
+	return strings.Join(strings.Fields(s), ``)
+}
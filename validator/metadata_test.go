@@ -0,0 +1,55 @@
+package validator
+
+import (
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+func TestCheckPlanMetadata(t *testing.T) {
+	program, err := parser.CreateParser(parser.PARSER_TASKS_ENABLED).Parse(`plans/init.pp`, issue.Unindent(`
+    plan mymod(
+      TargetSpec $targets,
+      Optional[String[1]] $message = undef,
+      Integer $count = 1,
+      $extra = 0,
+    ) {
+    }`), false)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	plan := program.(*parser.Program).Definitions()[0].(*parser.PlanDefinition)
+
+	issues, err := CheckPlanMetadata(plan, `tasks/init.json`, []byte(`{
+    "description": "Does things",
+    "parameters": {
+      "targets": { "type": "TargetSpec" },
+      "message": { "type": "Optional[ String[1] ]" },
+      "count": { "type": "Integer[1]" },
+      "verbose": { "type": "Boolean" },
+      "debug": {}
+    }
+  }`))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expected := []string{
+		`Parameter $count of plan 'mymod' has type Integer but tasks/init.json declares type Integer[1] (file: plans/init.pp, line: 4, column: 3)`,
+		`Parameter $extra of plan 'mymod' is not declared in tasks/init.json (file: plans/init.pp, line: 5, column: 3)`,
+		`Parameter 'debug' declared in tasks/init.json is not a parameter of plan 'mymod' (file: plans/init.pp, line: 1, column: 1)`,
+		`Parameter 'verbose' declared in tasks/init.json is not a parameter of plan 'mymod' (file: plans/init.pp, line: 1, column: 1)`,
+	}
+	if len(issues) != len(expected) {
+		t.Fatalf("expected %d issues, got %d", len(expected), len(issues))
+	}
+	for i, ri := range issues {
+		if ri.Error() != expected[i] || ri.Severity() != issue.SEVERITY_WARNING {
+			t.Errorf("expected %s, got %s", expected[i], ri.Error())
+		}
+	}
+
+	if _, err = CheckPlanMetadata(plan, `tasks/init.json`, []byte(`{`)); err == nil {
+		t.Errorf("expected malformed metadata to be rejected")
+	}
+}
@@ -0,0 +1,31 @@
+package validator
+
+import (
+	"github.com/lyraproj/issue/issue"
+)
+
+// promotedIssue is an issue that is reported as an error although it was issued as a warning
+type promotedIssue struct {
+	issue.Reported
+}
+
+func (pi *promotedIssue) Severity() issue.Severity {
+	return issue.SEVERITY_ERROR
+}
+
+func (pi *promotedIssue) OffsetByLocation(location issue.Location) issue.Reported {
+	return &promotedIssue{pi.Reported.OffsetByLocation(location)}
+}
+
+// WarningsAsErrors makes the given validator report the issues with the given codes as errors when
+// they would otherwise be reported as warnings or deprecations. All such issues are reported as errors
+// when no codes are given. This lets a strict pipeline fail on e.g. deprecations:
+//
+//	v := NewChecker(STRICT_WARNING)
+//	WarningsAsErrors(v, VALIDATE_FUTURE_RESERVED_WORD, VALIDATE_APP_ORCHESTRATION_DEPRECATED)
+//
+// Issues that are ignored remain ignored. A ParserValidator that uses the validator also reports the
+// warnings of its parser according to this policy. Calls are cumulative.
+func WarningsAsErrors(v Validator, codes ...issue.Code) {
+	v.setWarningsAsErrors(codes)
+}
@@ -0,0 +1,60 @@
+package validator
+
+import (
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+func TestWarningsAsErrors(t *testing.T) {
+	source := issue.Unindent(`
+    $x = {a => 1, a => 2}
+    case $x {
+      'a': { true }
+    }`)
+
+	severities := func(v Validator) map[issue.Code]issue.Severity {
+		Validate(v, parse(t, source))
+		result := make(map[issue.Code]issue.Severity)
+		for _, i := range v.Issues() {
+			result[i.Code()] = i.Severity()
+		}
+		return result
+	}
+
+	v := NewChecker(STRICT_WARNING)
+	WarningsAsErrors(v)
+	if s := severities(v); len(s) != 2 || s[VALIDATE_DUPLICATE_KEY] != issue.SEVERITY_ERROR || s[VALIDATE_MISSING_DEFAULT] != issue.SEVERITY_ERROR {
+		t.Errorf("unexpected severities %v", s)
+	}
+
+	v = NewChecker(STRICT_WARNING)
+	WarningsAsErrors(v, VALIDATE_DUPLICATE_KEY)
+	if s := severities(v); len(s) != 2 || s[VALIDATE_DUPLICATE_KEY] != issue.SEVERITY_ERROR || s[VALIDATE_MISSING_DEFAULT] != issue.SEVERITY_WARNING {
+		t.Errorf("unexpected severities %v", s)
+	}
+
+	v = NewChecker(STRICT_OFF)
+	WarningsAsErrors(v, VALIDATE_DUPLICATE_KEY)
+	WarningsAsErrors(v)
+	if s := severities(v); len(s) != 1 || s[VALIDATE_MISSING_DEFAULT] != issue.SEVERITY_ERROR {
+		t.Errorf("expected ignored issues to remain ignored, got %v", s)
+	}
+}
+
+func TestWarningsAsErrorsForParserWarnings(t *testing.T) {
+	v := NewChecker(STRICT_WARNING)
+	WarningsAsErrors(v, parser.PARSE_EXTRANEOUS_COMMA)
+	_, result := NewParserValidator(parser.CreateParser(parser.PARSER_LENIENT_COMMAS), v).Parse(``, `$a = 1, $b = 2`)
+	if result == nil || len(result.Issues()) != 1 {
+		t.Fatalf("expected one issue")
+	}
+	i := result.Issues()[0]
+	if i.Code() != parser.PARSE_EXTRANEOUS_COMMA || i.Severity() != issue.SEVERITY_ERROR || i.Error() != `Extraneous comma between statements (line: 1, column: 8)` {
+		t.Errorf("unexpected issue %s with severity %s", i, i.Severity())
+	}
+	if !result.Error() {
+		t.Errorf("expected the result to have errors")
+	}
+}
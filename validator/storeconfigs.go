@@ -0,0 +1,17 @@
+package validator
+
+import (
+	"github.com/lyraproj/issue/issue"
+)
+
+// ApplyStoreconfigs tells the given validator if storeconfigs is enabled in the environment that the
+// validated manifests are compiled in. Exported resources are neither stored nor collected when it is
+// disabled, so the validator then warns about exported resource declarations and exported collectors.
+// Storeconfigs is assumed to be enabled unless it is disabled using this function.
+func ApplyStoreconfigs(v Validator, enabled bool) {
+	if enabled {
+		v.Demote(VALIDATE_STORECONFIGS_DISABLED, issue.SEVERITY_IGNORE)
+	} else {
+		v.Demote(VALIDATE_STORECONFIGS_DISABLED, issue.SEVERITY_WARNING)
+	}
+}
@@ -0,0 +1,44 @@
+package validator
+
+import (
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+)
+
+func TestStoreconfigs(t *testing.T) {
+	source := issue.Unindent(`
+    @@file { '/tmp/a': ensure => file }
+    File <<| tag == 'a' |>>
+    File <| tag == 'a' |>
+    @file { '/tmp/b': ensure => file }`)
+
+	expectNoIssues(t, source)
+
+	v := NewChecker(STRICT_ERROR)
+	ApplyStoreconfigs(v, false)
+	Validate(v, parse(t, source))
+	issues := v.Issues()
+	if len(issues) != 2 {
+		t.Fatalf("expected 2 issues, got %d", len(issues))
+	}
+	for _, i := range issues {
+		if i.Code() != VALIDATE_STORECONFIGS_DISABLED || i.Severity() != issue.SEVERITY_WARNING {
+			t.Errorf("unexpected issue %s", i)
+		}
+	}
+	if issues[0].Error() != `This Resource Statement has no effect since exported resources require storeconfigs, which is disabled (line: 1, column: 1)` {
+		t.Errorf("unexpected message %s", issues[0].Error())
+	}
+	if issues[1].Location().Line() != 2 {
+		t.Errorf("unexpected location of %s", issues[1])
+	}
+
+	v = NewChecker(STRICT_ERROR)
+	ApplyStoreconfigs(v, false)
+	ApplyStoreconfigs(v, true)
+	Validate(v, parse(t, source))
+	if len(v.Issues()) != 0 {
+		t.Errorf("expected no issues, got %d", len(v.Issues()))
+	}
+}
@@ -2,6 +2,7 @@ package validator
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
 
 	"github.com/lyraproj/issue/issue"
@@ -24,6 +25,20 @@ type (
 		// Return all reported issues (should be called after validation)
 		Issues() []issue.Reported
 
+		// Demote changes the severity used when reporting the soft issue with the given code
+		Demote(code issue.Code, severity issue.Severity)
+
+		// Promoted returns the given issue with the severity error when it is a warning or a deprecation
+		// that the validator reports as an error, see WarningsAsErrors. The given issue is returned in all
+		// other cases.
+		Promoted(reported issue.Reported) issue.Reported
+
+		setWarningsAsErrors(codes []issue.Code)
+
+		setHeader(pattern *regexp.Regexp)
+
+		setLimit(code issue.Code, limit int)
+
 		setPathAndSubject(path []parser.Expression, expr parser.Expression)
 	}
 
@@ -39,6 +54,15 @@ type (
 		subject    parser.Expression
 		issues     []issue.Reported
 		severities map[issue.Code]issue.Severity
+
+		// Codes of the warnings that are reported as errors, nil when no warnings are, and empty when all are
+		warningsAsErrors map[issue.Code]bool
+
+		// The pattern that the leading comments of a manifest must match, nil when no header is required
+		header *regexp.Regexp
+
+		// The limits of the issues that report a length that exceeds a limit, keyed by issue code
+		limits map[issue.Code]int
 	}
 
 	Strictness int
@@ -62,6 +86,14 @@ func Strict(str string) Strictness {
 	}
 }
 
+// ApplyStrictness sets the severity of the issues that the given strictness controls, i.e. duplicate
+// hash keys, duplicate case and selector matches, and idempotent expressions that are not last in a block
+func ApplyStrictness(v Validator, strict Strictness) {
+	v.Demote(VALIDATE_DUPLICATE_KEY, issue.Severity(strict))
+	v.Demote(VALIDATE_DUPLICATE_MATCH, issue.Severity(strict))
+	v.Demote(VALIDATE_IDEM_EXPRESSION_NOT_LAST, issue.Severity(strict))
+}
+
 func (s Strictness) String() string {
 	switch s {
 	case STRICT_OFF:
@@ -86,12 +118,55 @@ func (v *AbstractValidator) Demote(code issue.Code, severity issue.Severity) {
 
 // Accept an issue during validation
 func (v *AbstractValidator) Accept(code issue.Code, e parser.Expression, args issue.H) {
+	v.acceptAt(code, e, args)
+}
+
+// acceptAt accepts an issue for a location that isn't an expression, such as a range of a line
+func (v *AbstractValidator) acceptAt(code issue.Code, location issue.Location, args issue.H) {
 	severity, ok := v.severities[code]
 	if !ok {
 		severity = issue.SEVERITY_ERROR
 	}
+	if v.isPromoted(code, severity) {
+		severity = issue.SEVERITY_ERROR
+	}
 	if severity != issue.SEVERITY_IGNORE {
-		v.issues = append(v.issues, issue.NewReported(code, severity, args, e))
+		v.issues = append(v.issues, issue.NewReported(code, severity, args, location))
+	}
+}
+
+// ignored returns true if issues with the given code are ignored, so a check that only reports them can be
+// skipped
+func (v *AbstractValidator) ignored(code issue.Code) bool {
+	severity, ok := v.severities[code]
+	return ok && severity == issue.SEVERITY_IGNORE
+}
+
+func (v *AbstractValidator) Promoted(reported issue.Reported) issue.Reported {
+	if v.isPromoted(reported.Code(), reported.Severity()) {
+		return &promotedIssue{reported}
+	}
+	return reported
+}
+
+func (v *AbstractValidator) isPromoted(code issue.Code, severity issue.Severity) bool {
+	if v.warningsAsErrors == nil || !(severity == issue.SEVERITY_WARNING || severity == issue.SEVERITY_DEPRECATION) {
+		return false
+	}
+	return len(v.warningsAsErrors) == 0 || v.warningsAsErrors[code]
+}
+
+func (v *AbstractValidator) setWarningsAsErrors(codes []issue.Code) {
+	if v.warningsAsErrors == nil {
+		v.warningsAsErrors = make(map[issue.Code]bool, len(codes))
+	} else if len(v.warningsAsErrors) == 0 {
+		// All warnings are already reported as errors
+		return
+	} else if len(codes) == 0 {
+		v.warningsAsErrors = make(map[issue.Code]bool)
+	}
+	for _, code := range codes {
+		v.warningsAsErrors[code] = true
 	}
 }
 
@@ -171,7 +246,14 @@ func NewParserValidator(parser parser.ExpressionParser, validator Validator) Par
 }
 
 func (pv *parserValidator) Parse(filename string, source string) (parser.Expression, issue.Result) {
-	expr, err := pv.parser.Parse(filename, source, false)
+	var expr parser.Expression
+	var warnings []issue.Reported
+	var err error
+	if wp, ok := pv.parser.(parser.WarningsParser); ok {
+		expr, warnings, err = wp.ParseWithWarnings(filename, source, false)
+	} else {
+		expr, err = pv.parser.Parse(filename, source, false)
+	}
 	if err != nil {
 		if i, ok := err.(issue.Reported); ok {
 			return nil, issue.NewResult([]issue.Reported{i})
@@ -179,7 +261,11 @@ func (pv *parserValidator) Parse(filename string, source string) (parser.Express
 		panic(err.Error())
 	}
 	Validate(pv.validator, expr)
-	issues := pv.validator.Issues()
+	issues := make([]issue.Reported, 0, len(warnings)+len(pv.validator.Issues()))
+	for _, warning := range warnings {
+		issues = append(issues, pv.validator.Promoted(warning))
+	}
+	issues = append(issues, pv.validator.Issues()...)
 	if len(issues) == 0 {
 		return expr, nil
 	}
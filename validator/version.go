@@ -0,0 +1,53 @@
+package validator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+)
+
+// LanguageVersion is the major version of the Puppet language that a validated manifest targets
+type LanguageVersion int
+
+const (
+	PUPPET_5 = LanguageVersion(5)
+	PUPPET_6 = LanguageVersion(6)
+	PUPPET_7 = LanguageVersion(7)
+)
+
+// DEFAULT_LANGUAGE_VERSION is the version that the validators created by NewChecker target unless
+// another version is applied using ApplyLanguageVersion
+const DEFAULT_LANGUAGE_VERSION = PUPPET_5
+
+// ParseLanguageVersion returns the language version that corresponds to the given string, e.g. "6"
+func ParseLanguageVersion(str string) LanguageVersion {
+	switch strings.TrimSpace(str) {
+	case `5`:
+		return PUPPET_5
+	case `6`:
+		return PUPPET_6
+	case `7`:
+		return PUPPET_7
+	default:
+		panic(fmt.Sprintf(`Invalid LanguageVersion value '%s'`, str))
+	}
+}
+
+func (lv LanguageVersion) String() string {
+	return fmt.Sprintf(`%d`, int(lv))
+}
+
+// ApplyLanguageVersion makes the given validator report the constructs that are deprecated or removed
+// in the given language version. Application orchestration (applications, sites, capability mappings)
+// is silently accepted by Puppet 5, deprecated in Puppet 6, and an error in Puppet 7.
+func ApplyLanguageVersion(v Validator, version LanguageVersion) {
+	switch {
+	case version >= PUPPET_7:
+		v.Demote(VALIDATE_APP_ORCHESTRATION_DEPRECATED, issue.SEVERITY_ERROR)
+	case version >= PUPPET_6:
+		v.Demote(VALIDATE_APP_ORCHESTRATION_DEPRECATED, issue.SEVERITY_DEPRECATION)
+	default:
+		v.Demote(VALIDATE_APP_ORCHESTRATION_DEPRECATED, issue.SEVERITY_IGNORE)
+	}
+}
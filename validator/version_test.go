@@ -0,0 +1,52 @@
+package validator
+
+import (
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+func TestDiscontinuedImport(t *testing.T) {
+	expectIssues(t, `import 'nodes/*.pp'`, VALIDATE_DISCONTINUED_IMPORT)
+
+	expectNoIssues(t, `$x = import`)
+
+	expectIssuesX(t, `import 'nodes/*.pp'`, []parser.Option{parser.PARSER_IMPORT_COMPAT}, VALIDATE_DISCONTINUED_IMPORT)
+}
+
+func TestLanguageVersion(t *testing.T) {
+	source := issue.Unindent(`
+    application a() {}
+    site {}
+    Something produces Foo {}`)
+
+	expectNoIssues(t, source)
+
+	for _, tc := range []struct {
+		version  string
+		severity issue.Severity
+	}{{`5`, issue.SEVERITY_IGNORE}, {`6`, issue.SEVERITY_DEPRECATION}, {`7`, issue.SEVERITY_ERROR}} {
+		v := NewChecker(STRICT_ERROR)
+		ApplyLanguageVersion(v, ParseLanguageVersion(tc.version))
+		Validate(v, parse(t, source))
+		issues := v.Issues()
+		if tc.severity == issue.SEVERITY_IGNORE {
+			if len(issues) != 0 {
+				t.Errorf("Puppet %s: expected no issues, got %d", tc.version, len(issues))
+			}
+			continue
+		}
+		if len(issues) != 3 {
+			t.Fatalf("Puppet %s: expected 3 issues, got %d", tc.version, len(issues))
+		}
+		for _, i := range issues {
+			if i.Code() != VALIDATE_APP_ORCHESTRATION_DEPRECATED || i.Severity() != tc.severity {
+				t.Errorf("Puppet %s: unexpected issue %s", tc.version, i)
+			}
+		}
+		if issues[0].Error() != `An Application is part of application orchestration, which is deprecated since Puppet 6 and removed in Puppet 7 (line: 1, column: 1)` {
+			t.Errorf("Puppet %s: unexpected message %s", tc.version, issues[0].Error())
+		}
+	}
+}